@@ -2,6 +2,7 @@ package apis
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -291,7 +292,8 @@ func processInternalRequest(
 		return nil, err
 	}
 
-	r, err := http.NewRequest(strings.ToUpper(ir.Method), ir.URL, buf)
+	// inherit the batch request context values (e.g. tracing) but not its cancellation
+	r, err := http.NewRequestWithContext(context.WithoutCancel(baseEvent.Request.Context()), strings.ToUpper(ir.Method), ir.URL, buf)
 	if err != nil {
 		return nil, err
 	}
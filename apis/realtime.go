@@ -553,7 +553,7 @@ func realtimeBroadcastRecord(app core.App, action string, record *core.Record, d
 							// apply expand
 							rawExpand := options.Query[expandQueryParam]
 							if rawExpand != "" {
-								expandErrs := app.ExpandRecord(cleanRecord, strings.Split(rawExpand, ","), expandFetch(app, requestInfo))
+								expandErrs := app.ExpandRecord(cleanRecord, strings.Split(rawExpand, ","), expandFetch(context.Background(), app, requestInfo))
 								if len(expandErrs) > 0 {
 									app.Logger().Debug(
 										"[broadcastRecord] expand errors",
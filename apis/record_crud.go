@@ -1,6 +1,7 @@
 package apis
 
 import (
+	"context"
 	cryptoRand "crypto/rand"
 	"errors"
 	"fmt"
@@ -60,7 +61,7 @@ func recordsList(e *core.RequestEvent) error {
 		return err
 	}
 
-	query := e.App.RecordQuery(collection)
+	query := e.App.RecordQuery(collection).WithContext(detachedRequestContext(e))
 
 	fieldsResolver := core.NewRecordFieldResolver(e.App, collection, requestInfo, true)
 
@@ -170,6 +171,8 @@ func recordView(e *core.RequestEvent) error {
 	}
 
 	ruleFunc := func(q *dbx.SelectQuery) error {
+		q.WithContext(detachedRequestContext(e))
+
 		if !requestInfo.HasSuperuserAuth() && collection.ViewRule != nil && *collection.ViewRule != "" {
 			resolver := core.NewRecordFieldResolver(e.App, collection, requestInfo, true)
 
@@ -257,6 +260,7 @@ func recordCreate(responseWriteAfterTx bool, optFinalizer func(data any) error)
 		requestInfo.Body = data
 
 		form := forms.NewRecordUpsert(e.App, record)
+		form.SetContext(detachedRequestContext(e))
 		if hasSuperuserAuth {
 			form.GrantSuperuserAccess()
 		}
@@ -480,6 +484,7 @@ func recordUpdate(responseWriteAfterTx bool, optFinalizer func(data any) error)
 		}
 
 		form := forms.NewRecordUpsert(e.App, record)
+		form.SetContext(detachedRequestContext(e))
 		if hasSuperuserAuth {
 			form.GrantSuperuserAccess()
 		}
@@ -608,7 +613,7 @@ func recordDelete(responseWriteAfterTx bool, optFinalizer func(data any) error)
 		event.Record = record
 
 		hookErr := e.App.OnRecordDeleteRequest().Trigger(event, func(e *core.RecordRequestEvent) error {
-			if err := e.App.Delete(e.Record); err != nil {
+			if err := e.App.DeleteWithContext(detachedRequestContext(e.RequestEvent), e.Record); err != nil {
 				return firstApiError(err, e.BadRequestError("Failed to delete record. Make sure that the record is not part of a required relation reference.", err))
 			}
 
@@ -842,3 +847,9 @@ func pgTypeForValue(v any, field core.Field) string {
 		return "::text"
 	}
 }
+
+// detachedRequestContext 返回保留请求 context 值（如追踪信息）但不随请求取消的 context，
+// 使数据库操作能关联到请求的追踪，同时不会因客户端断开而中断已开始的写入。
+func detachedRequestContext(e *core.RequestEvent) context.Context {
+	return context.WithoutCancel(e.Request.Context())
+}
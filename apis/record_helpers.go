@@ -1,6 +1,7 @@
 package apis
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -99,7 +100,7 @@ func recordAuthResponse(e *core.RequestEvent, authRecord *core.Record, token str
 			// expand record relations
 			expands := strings.Split(e.Request.URL.Query().Get(expandQueryParam), ",")
 			if len(expands) > 0 {
-				failed := e.App.ExpandRecord(e.Record, expands, expandFetch(e.App, &requestInfo))
+				failed := e.App.ExpandRecord(e.Record, expands, expandFetch(detachedRequestContext(e.RequestEvent), e.App, &requestInfo))
 				if len(failed) > 0 {
 					e.App.Logger().Warn("[recordAuthResponse] Failed to expand relations", "error", failed)
 				}
@@ -278,7 +279,7 @@ func EnrichRecords(e *core.RequestEvent, records []*core.Record, defaultExpands
 			expands = append(expands, strings.Split(param, ",")...)
 		}
 
-		err := defaultEnrichRecords(e.App, info, records, expands...)
+		err := defaultEnrichRecords(detachedRequestContext(e), e.App, info, records, expands...)
 		if err != nil {
 			// only log because it is not critical
 			e.App.Logger().Warn("failed to apply default enriching", "error", err)
@@ -345,14 +346,14 @@ func triggerRecordEnrichHooks(app core.App, requestInfo *core.RequestInfo, recor
 	return iterate(it.next())
 }
 
-func defaultEnrichRecords(app core.App, requestInfo *core.RequestInfo, records []*core.Record, expands ...string) error {
+func defaultEnrichRecords(ctx context.Context, app core.App, requestInfo *core.RequestInfo, records []*core.Record, expands ...string) error {
 	err := autoResolveRecordsFlags(app, records, requestInfo)
 	if err != nil {
 		return fmt.Errorf("failed to resolve records flags: %w", err)
 	}
 
 	if len(expands) > 0 {
-		expandErrs := app.ExpandRecords(records, expands, expandFetch(app, requestInfo))
+		expandErrs := app.ExpandRecords(records, expands, expandFetch(ctx, app, requestInfo))
 		if len(expandErrs) > 0 {
 			errsSlice := make([]error, 0, len(expandErrs))
 			for key, err := range expandErrs {
@@ -366,7 +367,9 @@ func defaultEnrichRecords(app core.App, requestInfo *core.RequestInfo, records [
 }
 
 // expandFetch is the records fetch function that is used to expand related records.
-func expandFetch(app core.App, originalRequestInfo *core.RequestInfo) core.ExpandFetchFunc {
+//
+// The relation queries are executed with ctx (e.g. to associate them with the request trace).
+func expandFetch(ctx context.Context, app core.App, originalRequestInfo *core.RequestInfo) core.ExpandFetchFunc {
 	// shallow clone the provided request info to set an "expand" context
 	requestInfoClone := *originalRequestInfo
 	requestInfoPtr := &requestInfoClone
@@ -374,6 +377,8 @@ func expandFetch(app core.App, originalRequestInfo *core.RequestInfo) core.Expan
 
 	return func(relCollection *core.Collection, relIds []string) ([]*core.Record, error) {
 		records, findErr := app.FindRecordsByIds(relCollection.Id, relIds, func(q *dbx.SelectQuery) error {
+			q.WithContext(ctx)
+
 			if requestInfoPtr.Auth != nil && requestInfoPtr.Auth.IsSuperuser() {
 				return nil // superusers can access everything
 			}
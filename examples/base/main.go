@@ -161,7 +161,10 @@ func main() {
 	// Gateway 插件 - API 网关代理转发
 	// 支持代理 LLM API（OpenAI、Claude 等）和本地 Sidecar
 	// 使用 ReverseProxy + "暴力归一化" 策略解决协议兼容问题
-	gateway.MustRegister(app, gateway.Config{})
+	// 可通过 pb_gateway.yaml 声明式管理代理，`gateway apply --dry-run` 预览差异
+	gatewayConfig := gateway.Config{}
+	gateway.MustRegister(app, gatewayConfig)
+	app.RootCmd.AddCommand(gateway.NewCommand(app, gatewayConfig))
 
 	// Secrets 插件 - 系统级密钥管理
 	// 提供 _secrets 系统表，通过 AES-256-GCM 加密存储敏感信息
@@ -1,4 +1,5 @@
 import { BaseService } from "@/services/BaseService";
+import { UnsubscribeFunc } from "@/services/RealtimeService";
 
 /**
  * 事件数据接口（发送到服务端）
@@ -8,6 +9,8 @@ export interface AnalyticsEvent {
     event: string;
     /** 页面路径 */
     path?: string;
+    /** 查询字符串（不含 "?"，用于 UTM 营销活动归因） */
+    query?: string;
     /** 来源页面 */
     referrer?: string;
     /** 页面标题 */
@@ -123,6 +126,29 @@ export interface RawLogsResponse {
     dates: string[];
 }
 
+/**
+ * 实时分析快照（最近 30 分钟的滚动窗口）
+ */
+export interface RealtimeAnalyticsResponse {
+    timestamp: string;
+    /** 最近 5 分钟内活跃的会话数 */
+    active5m: number;
+    /** 最近 30 分钟内活跃的会话数 */
+    active30m: number;
+    topPages: Array<{
+        path: string;
+        visitors: number;
+    }>;
+    perMinute: Array<{
+        minute: string;
+        events: number;
+        pageViews: number;
+    }>;
+}
+
+/** 实时分析的订阅主题 */
+const REALTIME_TOPIC = "analytics/realtime";
+
 const STORAGE_KEY_OPT_OUT = "pb_analytics_opt_out";
 const STORAGE_KEY_SESSION_ID = "pb_analytics_session_id";
 
@@ -230,6 +256,7 @@ export class AnalyticsService extends BaseService {
         const analyticsEvent: AnalyticsEvent = {
             event,
             path: typeof window !== "undefined" ? window.location.pathname : "",
+            query: typeof window !== "undefined" ? (window.location.search || "").replace(/^\?/, "") : "",
             referrer: typeof document !== "undefined" ? document.referrer : "",
             title: typeof document !== "undefined" ? document.title : "",
             sessionId: this.sessionId,
@@ -333,6 +360,7 @@ export class AnalyticsService extends BaseService {
             events: events.map(e => ({
                 event: e.event,
                 path: e.path,
+                query: e.query,
                 referrer: e.referrer,
                 title: e.title,
                 sessionId: e.sessionId || this.sessionId,
@@ -420,6 +448,29 @@ export class AnalyticsService extends BaseService {
         });
     }
 
+    /**
+     * 获取实时分析快照（当前在线会话、热门页面、每分钟事件数）。
+     * 需要 Superuser 认证。
+     */
+    async getRealtime(): Promise<RealtimeAnalyticsResponse> {
+        return this.client.send("/api/analytics/realtime", {
+            method: "GET",
+        });
+    }
+
+    /**
+     * 订阅实时分析快照，服务端每隔几秒推送一次。
+     * 需要 Superuser 认证。
+     *
+     * @param callback 接收快照的回调
+     * @returns 取消订阅函数
+     */
+    async subscribeRealtime(
+        callback: (data: RealtimeAnalyticsResponse) => void,
+    ): Promise<UnsubscribeFunc> {
+        return this.client.realtime.subscribe(REALTIME_TOPIC, callback);
+    }
+
     /**
      * 获取可下载的原始日志日期列表。
      * 需要 Superuser 认证。
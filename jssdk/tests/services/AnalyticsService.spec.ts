@@ -58,7 +58,7 @@ describe("AnalyticsService", function () {
         // Set up mocks
         (global as any).localStorage = localStorageMock;
         (global as any).window = {
-            location: { pathname: "/test", href: "http://test.local/test" },
+            location: { pathname: "/test", search: "?utm_source=newsletter", href: "http://test.local/test" },
             addEventListener: mock(() => {}),
         };
         (global as any).document = {
@@ -106,11 +106,12 @@ describe("AnalyticsService", function () {
             assert.deepEqual(queue[0].props, { key: "value" });
         });
 
-        test("Should include path, referrer, title and timestamp", function () {
+        test("Should include path, query, referrer, title and timestamp", function () {
             service.track("click");
 
             const queue = (service as any).eventQueue;
             assert.equal(queue[0].path, "/test");
+            assert.equal(queue[0].query, "utm_source=newsletter");
             assert.equal(queue[0].referrer, "http://referrer.local");
             assert.equal(queue[0].title, "Test Page");
             assert.equal(typeof queue[0].timestamp, "number");
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/gateway"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return addGatewayTransformField(txApp)
+	}, func(txApp core.App) error {
+		return removeGatewayTransformField(txApp)
+	}, "20260310000000_gateway_transform.go")
+}
+
+// addGatewayTransformField 为 _proxies 表添加请求/响应变换配置字段
+//
+// 新增字段:
+// - transform: 变换配置 JSON（头部、查询参数、Body、路径重写、状态码映射）
+func addGatewayTransformField(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		// Collection 不存在，可能是首次启动前的状态
+		return nil
+	}
+
+	if col.Fields.GetByName(gateway.ProxyFieldTransform) == nil {
+		col.Fields.Add(&core.JSONField{
+			Name:    gateway.ProxyFieldTransform,
+			System:  true,
+			MaxSize: 20000, // 20KB
+		})
+	}
+
+	return txApp.Save(col)
+}
+
+// removeGatewayTransformField 回滚：移除变换配置字段
+func removeGatewayTransformField(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		return nil // Collection 不存在，无需回滚
+	}
+
+	col.Fields.RemoveByName(gateway.ProxyFieldTransform)
+
+	return txApp.Save(col)
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/gateway"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return addGatewayUpstreamAuthField(txApp)
+	}, func(txApp core.App) error {
+		return removeGatewayUpstreamAuthField(txApp)
+	}, "20260310000100_gateway_upstream_auth.go")
+}
+
+// addGatewayUpstreamAuthField 为 _proxies 表添加上游认证配置字段
+//
+// 新增字段:
+// - upstreamAuth: 上游认证配置 JSON（OAuth2 Client Credentials、HMAC 签名、mTLS）
+func addGatewayUpstreamAuthField(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		// Collection 不存在，可能是首次启动前的状态
+		return nil
+	}
+
+	if col.Fields.GetByName(gateway.ProxyFieldUpstreamAuth) == nil {
+		col.Fields.Add(&core.JSONField{
+			Name:    gateway.ProxyFieldUpstreamAuth,
+			System:  true,
+			MaxSize: 50000, // 50KB（可能包含 PEM 证书）
+		})
+	}
+
+	return txApp.Save(col)
+}
+
+// removeGatewayUpstreamAuthField 回滚：移除上游认证配置字段
+func removeGatewayUpstreamAuthField(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		return nil // Collection 不存在，无需回滚
+	}
+
+	col.Fields.RemoveByName(gateway.ProxyFieldUpstreamAuth)
+
+	return txApp.Save(col)
+}
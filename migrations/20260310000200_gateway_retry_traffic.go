@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/gateway"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return addGatewayRetryTrafficFields(txApp)
+	}, func(txApp core.App) error {
+		return removeGatewayRetryTrafficFields(txApp)
+	}, "20260310000200_gateway_retry_traffic.go")
+}
+
+// addGatewayRetryTrafficFields 为 _proxies 表添加重试与流量管理字段
+//
+// 新增字段:
+// - retryPolicy: 重试/对冲配置 JSON
+// - traffic: 流量拆分/镜像配置 JSON
+func addGatewayRetryTrafficFields(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		// Collection 不存在，可能是首次启动前的状态
+		return nil
+	}
+
+	if col.Fields.GetByName(gateway.ProxyFieldRetryPolicy) == nil {
+		col.Fields.Add(&core.JSONField{
+			Name:    gateway.ProxyFieldRetryPolicy,
+			System:  true,
+			MaxSize: 2000, // 2KB 足够
+		})
+	}
+
+	if col.Fields.GetByName(gateway.ProxyFieldTraffic) == nil {
+		col.Fields.Add(&core.JSONField{
+			Name:    gateway.ProxyFieldTraffic,
+			System:  true,
+			MaxSize: 5000, // 5KB
+		})
+	}
+
+	return txApp.Save(col)
+}
+
+// removeGatewayRetryTrafficFields 回滚：移除重试与流量管理字段
+func removeGatewayRetryTrafficFields(txApp core.App) error {
+	col, err := txApp.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		return nil // Collection 不存在，无需回滚
+	}
+
+	col.Fields.RemoveByName(gateway.ProxyFieldRetryPolicy)
+	col.Fields.RemoveByName(gateway.ProxyFieldTraffic)
+
+	return txApp.Save(col)
+}
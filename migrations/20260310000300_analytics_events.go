@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return createAnalyticsEventsTable(txApp)
+	}, func(txApp core.App) error {
+		return dropAnalyticsEventsTable(txApp)
+	}, "20260310000300_analytics_events.go")
+}
+
+// createAnalyticsEventsTable 创建自定义事件统计表
+//
+// 每行为某天某个事件的总量（prop_key 为空），或按属性值拆分的明细：
+// - count: 事件次数
+// - hll: 访客 HLL Sketch，用于跨天 UV 合并
+// - visitors: 估算的 UV 值
+func createAnalyticsEventsTable(txApp core.App) error {
+	var sql string
+	var db = txApp.AuxDB() // 默认使用辅助数据库
+
+	if txApp.IsPostgres() {
+		db = txApp.DB() // PostgreSQL 模式使用主数据库
+		sql = `
+			CREATE UNLOGGED TABLE IF NOT EXISTS "_analytics_events" (
+				"id"         TEXT PRIMARY KEY NOT NULL,
+				"date"       TEXT NOT NULL,
+				"event"      TEXT NOT NULL,
+				"prop_key"   TEXT DEFAULT '' NOT NULL,
+				"prop_value" TEXT DEFAULT '' NOT NULL,
+				"count"      BIGINT DEFAULT 0 NOT NULL,
+				"hll"        BYTEA,
+				"visitors"   BIGINT DEFAULT 0 NOT NULL,
+				"created"    TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				"updated"    TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_events_date_event
+			ON "_analytics_events" ("date", "event", "prop_key");
+		`
+	} else {
+		sql = `
+			CREATE TABLE IF NOT EXISTS {{_analytics_events}} (
+				[[id]]         TEXT PRIMARY KEY NOT NULL,
+				[[date]]       TEXT NOT NULL,
+				[[event]]      TEXT NOT NULL,
+				[[prop_key]]   TEXT DEFAULT '' NOT NULL,
+				[[prop_value]] TEXT DEFAULT '' NOT NULL,
+				[[count]]      INTEGER DEFAULT 0 NOT NULL,
+				[[hll]]        BLOB,
+				[[visitors]]   INTEGER DEFAULT 0 NOT NULL,
+				[[created]]    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+				[[updated]]    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_events_date_event
+			ON {{_analytics_events}} ([[date]], [[event]], [[prop_key]]);
+		`
+	}
+
+	_, err := db.NewQuery(sql).Execute()
+	return err
+}
+
+// dropAnalyticsEventsTable 删除自定义事件统计表
+func dropAnalyticsEventsTable(txApp core.App) error {
+	var db = txApp.AuxDB()
+	if txApp.IsPostgres() {
+		db = txApp.DB()
+	}
+
+	_, err := db.DropTable("_analytics_events").Execute()
+	return err
+}
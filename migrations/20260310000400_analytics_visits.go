@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return createAnalyticsVisitsTable(txApp)
+	}, func(txApp core.App) error {
+		return dropAnalyticsVisitsTable(txApp)
+	}, "20260310000400_analytics_visits.go")
+}
+
+// createAnalyticsVisitsTable 创建访客访问记录表（漏斗与留存分析）
+//
+// 每个访客每天每个 (事件, 路径) 一行：
+// - first_ts / last_ts: 当天首次/最后发生时间（毫秒时间戳）
+// - hits: 当天发生次数
+func createAnalyticsVisitsTable(txApp core.App) error {
+	var sql string
+	var db = txApp.AuxDB() // 默认使用辅助数据库
+
+	if txApp.IsPostgres() {
+		db = txApp.DB() // PostgreSQL 模式使用主数据库
+		sql = `
+			CREATE UNLOGGED TABLE IF NOT EXISTS "_analytics_visits" (
+				"id"       TEXT PRIMARY KEY NOT NULL,
+				"date"     TEXT NOT NULL,
+				"sid"      TEXT NOT NULL,
+				"event"    TEXT NOT NULL,
+				"path"     TEXT NOT NULL,
+				"first_ts" BIGINT DEFAULT 0 NOT NULL,
+				"last_ts"  BIGINT DEFAULT 0 NOT NULL,
+				"hits"     BIGINT DEFAULT 0 NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_visits_date_event
+			ON "_analytics_visits" ("date", "event");
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_visits_sid
+			ON "_analytics_visits" ("sid");
+		`
+	} else {
+		sql = `
+			CREATE TABLE IF NOT EXISTS {{_analytics_visits}} (
+				[[id]]       TEXT PRIMARY KEY NOT NULL,
+				[[date]]     TEXT NOT NULL,
+				[[sid]]      TEXT NOT NULL,
+				[[event]]    TEXT NOT NULL,
+				[[path]]     TEXT NOT NULL,
+				[[first_ts]] INTEGER DEFAULT 0 NOT NULL,
+				[[last_ts]]  INTEGER DEFAULT 0 NOT NULL,
+				[[hits]]     INTEGER DEFAULT 0 NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_visits_date_event
+			ON {{_analytics_visits}} ([[date]], [[event]]);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_visits_sid
+			ON {{_analytics_visits}} ([[sid]]);
+		`
+	}
+
+	_, err := db.NewQuery(sql).Execute()
+	return err
+}
+
+// dropAnalyticsVisitsTable 删除访客访问记录表
+func dropAnalyticsVisitsTable(txApp core.App) error {
+	var db = txApp.AuxDB()
+	if txApp.IsPostgres() {
+		db = txApp.DB()
+	}
+
+	_, err := db.DropTable("_analytics_visits").Execute()
+	return err
+}
@@ -0,0 +1,115 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return createAnalyticsCampaignsSessionsTables(txApp)
+	}, func(txApp core.App) error {
+		return dropAnalyticsCampaignsSessionsTables(txApp)
+	}, "20260310000500_analytics_campaigns_sessions.go")
+}
+
+// createAnalyticsCampaignsSessionsTables 创建 UTM 营销活动统计表和会话页面统计表
+//
+// _analytics_campaigns 每行为某天某个 (utm_source, utm_medium, utm_campaign) 组合：
+// - count: 带 UTM 参数的事件数
+// - hll: 访客 HLL Sketch，用于跨天 UV 合并
+// - visitors: 估算的 UV 值
+//
+// _analytics_session_pages 每行为某天（会话开始日期）某个页面的会话统计：
+// - entries/bounces/duration: 以该页面为入口的会话数、跳出数、总时长（毫秒）
+// - exits: 以该页面为出口的会话数
+func createAnalyticsCampaignsSessionsTables(txApp core.App) error {
+	var sql string
+	var db = txApp.AuxDB() // 默认使用辅助数据库
+
+	if txApp.IsPostgres() {
+		db = txApp.DB() // PostgreSQL 模式使用主数据库
+		sql = `
+			CREATE UNLOGGED TABLE IF NOT EXISTS "_analytics_campaigns" (
+				"id"       TEXT PRIMARY KEY NOT NULL,
+				"date"     TEXT NOT NULL,
+				"source"   TEXT DEFAULT '' NOT NULL,
+				"medium"   TEXT DEFAULT '' NOT NULL,
+				"campaign" TEXT DEFAULT '' NOT NULL,
+				"count"    BIGINT DEFAULT 0 NOT NULL,
+				"hll"      BYTEA,
+				"visitors" BIGINT DEFAULT 0 NOT NULL,
+				"created"  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				"updated"  TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_campaigns_date
+			ON "_analytics_campaigns" ("date");
+
+			CREATE UNLOGGED TABLE IF NOT EXISTS "_analytics_session_pages" (
+				"id"       TEXT PRIMARY KEY NOT NULL,
+				"date"     TEXT NOT NULL,
+				"path"     TEXT NOT NULL,
+				"entries"  BIGINT DEFAULT 0 NOT NULL,
+				"exits"    BIGINT DEFAULT 0 NOT NULL,
+				"bounces"  BIGINT DEFAULT 0 NOT NULL,
+				"duration" BIGINT DEFAULT 0 NOT NULL,
+				"created"  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				"updated"  TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_session_pages_date
+			ON "_analytics_session_pages" ("date");
+		`
+	} else {
+		sql = `
+			CREATE TABLE IF NOT EXISTS {{_analytics_campaigns}} (
+				[[id]]       TEXT PRIMARY KEY NOT NULL,
+				[[date]]     TEXT NOT NULL,
+				[[source]]   TEXT DEFAULT '' NOT NULL,
+				[[medium]]   TEXT DEFAULT '' NOT NULL,
+				[[campaign]] TEXT DEFAULT '' NOT NULL,
+				[[count]]    INTEGER DEFAULT 0 NOT NULL,
+				[[hll]]      BLOB,
+				[[visitors]] INTEGER DEFAULT 0 NOT NULL,
+				[[created]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+				[[updated]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_campaigns_date
+			ON {{_analytics_campaigns}} ([[date]]);
+
+			CREATE TABLE IF NOT EXISTS {{_analytics_session_pages}} (
+				[[id]]       TEXT PRIMARY KEY NOT NULL,
+				[[date]]     TEXT NOT NULL,
+				[[path]]     TEXT NOT NULL,
+				[[entries]]  INTEGER DEFAULT 0 NOT NULL,
+				[[exits]]    INTEGER DEFAULT 0 NOT NULL,
+				[[bounces]]  INTEGER DEFAULT 0 NOT NULL,
+				[[duration]] INTEGER DEFAULT 0 NOT NULL,
+				[[created]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+				[[updated]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_session_pages_date
+			ON {{_analytics_session_pages}} ([[date]]);
+		`
+	}
+
+	_, err := db.NewQuery(sql).Execute()
+	return err
+}
+
+// dropAnalyticsCampaignsSessionsTables 删除 UTM 营销活动统计表和会话页面统计表
+func dropAnalyticsCampaignsSessionsTables(txApp core.App) error {
+	var db = txApp.AuxDB()
+	if txApp.IsPostgres() {
+		db = txApp.DB()
+	}
+
+	if _, err := db.DropTable("_analytics_campaigns").Execute(); err != nil {
+		return err
+	}
+
+	_, err := db.DropTable("_analytics_session_pages").Execute()
+	return err
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Register(func(txApp core.App) error {
+		return createAnalyticsGeoTable(txApp)
+	}, func(txApp core.App) error {
+		return dropAnalyticsGeoTable(txApp)
+	}, "20260310000600_analytics_geo.go")
+}
+
+// createAnalyticsGeoTable 创建地理位置统计表
+//
+// 每行为某天某个 (country, region, city) 组合：
+// - count: 事件数
+// - hll: 访客 HLL Sketch，用于跨天 UV 合并
+// - visitors: 估算的 UV 值
+func createAnalyticsGeoTable(txApp core.App) error {
+	var sql string
+	var db = txApp.AuxDB() // 默认使用辅助数据库
+
+	if txApp.IsPostgres() {
+		db = txApp.DB() // PostgreSQL 模式使用主数据库
+		sql = `
+			CREATE UNLOGGED TABLE IF NOT EXISTS "_analytics_geo" (
+				"id"       TEXT PRIMARY KEY NOT NULL,
+				"date"     TEXT NOT NULL,
+				"country"  TEXT NOT NULL,
+				"region"   TEXT DEFAULT '' NOT NULL,
+				"city"     TEXT DEFAULT '' NOT NULL,
+				"count"    BIGINT DEFAULT 0 NOT NULL,
+				"hll"      BYTEA,
+				"visitors" BIGINT DEFAULT 0 NOT NULL,
+				"created"  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				"updated"  TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_geo_date_country
+			ON "_analytics_geo" ("date", "country");
+		`
+	} else {
+		sql = `
+			CREATE TABLE IF NOT EXISTS {{_analytics_geo}} (
+				[[id]]       TEXT PRIMARY KEY NOT NULL,
+				[[date]]     TEXT NOT NULL,
+				[[country]]  TEXT NOT NULL,
+				[[region]]   TEXT DEFAULT '' NOT NULL,
+				[[city]]     TEXT DEFAULT '' NOT NULL,
+				[[count]]    INTEGER DEFAULT 0 NOT NULL,
+				[[hll]]      BLOB,
+				[[visitors]] INTEGER DEFAULT 0 NOT NULL,
+				[[created]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+				[[updated]]  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_analytics_geo_date_country
+			ON {{_analytics_geo}} ([[date]], [[country]]);
+		`
+	}
+
+	_, err := db.NewQuery(sql).Execute()
+	return err
+}
+
+// dropAnalyticsGeoTable 删除地理位置统计表
+func dropAnalyticsGeoTable(txApp core.App) error {
+	var db = txApp.AuxDB()
+	if txApp.IsPostgres() {
+		db = txApp.DB()
+	}
+
+	_, err := db.DropTable("_analytics_geo").Execute()
+	return err
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Add(&core.Migration{
+		Up: func(txApp core.App) error {
+			// 每行为一个采集周期内某个 (method, route) 的请求统计
+			// latency_buckets 保存非累计的耗时直方图（键为分桶上界 ms），用于跨周期合并计算分位数
+			var sql string
+			if txApp.IsPostgres() {
+				sql = `
+					CREATE UNLOGGED TABLE IF NOT EXISTS {{_metrics_routes}} (
+						[[id]]              TEXT PRIMARY KEY DEFAULT ('r'||lower(encode(gen_random_bytes(7), 'hex'))) NOT NULL,
+						[[timestamp]]       TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+						[[method]]          TEXT DEFAULT '' NOT NULL,
+						[[route]]           TEXT DEFAULT '' NOT NULL,
+						[[request_count]]   INTEGER DEFAULT 0 NOT NULL,
+						[[error_count]]     INTEGER DEFAULT 0 NOT NULL,
+						[[avg_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[max_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p50_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p95_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p99_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[latency_buckets]] JSONB DEFAULT '{}' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_routes_timestamp
+					ON {{_metrics_routes}} ([[timestamp]]);
+				`
+			} else {
+				sql = `
+					CREATE TABLE IF NOT EXISTS {{_metrics_routes}} (
+						[[id]]              TEXT PRIMARY KEY DEFAULT ('r'||lower(hex(randomblob(7)))) NOT NULL,
+						[[timestamp]]       TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+						[[method]]          TEXT DEFAULT '' NOT NULL,
+						[[route]]           TEXT DEFAULT '' NOT NULL,
+						[[request_count]]   INTEGER DEFAULT 0 NOT NULL,
+						[[error_count]]     INTEGER DEFAULT 0 NOT NULL,
+						[[avg_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[max_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p50_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p95_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[p99_latency_ms]]  REAL DEFAULT 0 NOT NULL,
+						[[latency_buckets]] JSON DEFAULT '{}' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_routes_timestamp
+					ON {{_metrics_routes}} ([[timestamp]]);
+				`
+			}
+			_, execErr := txApp.AuxDB().NewQuery(sql).Execute()
+			return execErr
+		},
+		Down: func(txApp core.App) error {
+			_, err := txApp.AuxDB().DropTable("_metrics_routes").Execute()
+			return err
+		},
+		ReapplyCondition: func(txApp core.App, runner *core.MigrationsRunner, fileName string) (bool, error) {
+			// 仅当 _metrics_routes 表不存在时重新应用
+			exists := txApp.AuxHasTable("_metrics_routes")
+			return !exists, nil
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Add(&core.Migration{
+		Up: func(txApp core.App) error {
+			// 每条告警规则一行，记录当前状态（pending/firing/resolved）及状态变更时间
+			var sql string
+			if txApp.IsPostgres() {
+				// 告警状态需要在重启/崩溃后保留（避免重复通知），不使用 UNLOGGED
+				sql = `
+					CREATE TABLE IF NOT EXISTS {{_metrics_alerts}} (
+						[[id]]           TEXT PRIMARY KEY DEFAULT ('r'||lower(encode(gen_random_bytes(7), 'hex'))) NOT NULL,
+						[[rule]]         TEXT NOT NULL,
+						[[field]]        TEXT DEFAULT '' NOT NULL,
+						[[severity]]     TEXT DEFAULT '' NOT NULL,
+						[[state]]        TEXT DEFAULT '' NOT NULL,
+						[[value]]        REAL DEFAULT 0 NOT NULL,
+						[[threshold]]    REAL DEFAULT 0 NOT NULL,
+						[[message]]      TEXT DEFAULT '' NOT NULL,
+						[[active_at]]    TIMESTAMPTZ NULL,
+						[[fired_at]]     TIMESTAMPTZ NULL,
+						[[resolved_at]]  TIMESTAMPTZ NULL,
+						[[evaluated_at]] TIMESTAMPTZ NULL
+					);
+
+					CREATE UNIQUE INDEX IF NOT EXISTS idx_metrics_alerts_rule
+					ON {{_metrics_alerts}} ([[rule]]);
+				`
+			} else {
+				sql = `
+					CREATE TABLE IF NOT EXISTS {{_metrics_alerts}} (
+						[[id]]           TEXT PRIMARY KEY DEFAULT ('r'||lower(hex(randomblob(7)))) NOT NULL,
+						[[rule]]         TEXT NOT NULL,
+						[[field]]        TEXT DEFAULT '' NOT NULL,
+						[[severity]]     TEXT DEFAULT '' NOT NULL,
+						[[state]]        TEXT DEFAULT '' NOT NULL,
+						[[value]]        REAL DEFAULT 0 NOT NULL,
+						[[threshold]]    REAL DEFAULT 0 NOT NULL,
+						[[message]]      TEXT DEFAULT '' NOT NULL,
+						[[active_at]]    TEXT DEFAULT NULL,
+						[[fired_at]]     TEXT DEFAULT NULL,
+						[[resolved_at]]  TEXT DEFAULT NULL,
+						[[evaluated_at]] TEXT DEFAULT NULL
+					);
+
+					CREATE UNIQUE INDEX IF NOT EXISTS idx_metrics_alerts_rule
+					ON {{_metrics_alerts}} ([[rule]]);
+				`
+			}
+			_, execErr := txApp.AuxDB().NewQuery(sql).Execute()
+			return execErr
+		},
+		Down: func(txApp core.App) error {
+			_, err := txApp.AuxDB().DropTable("_metrics_alerts").Execute()
+			return err
+		},
+		ReapplyCondition: func(txApp core.App, runner *core.MigrationsRunner, fileName string) (bool, error) {
+			// 仅当 _metrics_alerts 表不存在时重新应用
+			exists := txApp.AuxHasTable("_metrics_alerts")
+			return !exists, nil
+		},
+	})
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// metricsRollupTables 系统监控指标的降采样表（5 分钟、小时、天）
+var metricsRollupTables = []string{"_metrics_5m", "_metrics_1h", "_metrics_1d"}
+
+func init() {
+	core.SystemMigrations.Add(&core.Migration{
+		Up: func(txApp core.App) error {
+			// 每行为一个时间桶（timestamp 为桶起点）内原始样本的汇总
+			// stats 保存每个字段的 min/max/avg/p95
+			// 降采样数据用于长期对比，需在崩溃后保留，PostgreSQL 下不使用 UNLOGGED
+			var template string
+			if txApp.IsPostgres() {
+				template = `
+					CREATE TABLE IF NOT EXISTS {{__table__}} (
+						[[id]]           TEXT PRIMARY KEY DEFAULT ('r'||lower(encode(gen_random_bytes(7), 'hex'))) NOT NULL,
+						[[timestamp]]    TIMESTAMPTZ NOT NULL,
+						[[sample_count]] INTEGER DEFAULT 0 NOT NULL,
+						[[stats]]        JSONB DEFAULT '{}' NOT NULL
+					);
+
+					CREATE UNIQUE INDEX IF NOT EXISTS idx__table___timestamp
+					ON {{__table__}} ([[timestamp]]);
+				`
+			} else {
+				template = `
+					CREATE TABLE IF NOT EXISTS {{__table__}} (
+						[[id]]           TEXT PRIMARY KEY DEFAULT ('r'||lower(hex(randomblob(7)))) NOT NULL,
+						[[timestamp]]    TEXT NOT NULL,
+						[[sample_count]] INTEGER DEFAULT 0 NOT NULL,
+						[[stats]]        JSON DEFAULT '{}' NOT NULL
+					);
+
+					CREATE UNIQUE INDEX IF NOT EXISTS idx__table___timestamp
+					ON {{__table__}} ([[timestamp]]);
+				`
+			}
+
+			for _, table := range metricsRollupTables {
+				sql := strings.ReplaceAll(template, "__table__", table)
+				if _, err := txApp.AuxDB().NewQuery(sql).Execute(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(txApp core.App) error {
+			for _, table := range metricsRollupTables {
+				if _, err := txApp.AuxDB().DropTable(table).Execute(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		ReapplyCondition: func(txApp core.App, runner *core.MigrationsRunner, fileName string) (bool, error) {
+			// 任一降采样表不存在时重新应用
+			for _, table := range metricsRollupTables {
+				if !txApp.AuxHasTable(table) {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+	})
+}
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Add(&core.Migration{
+		Up: func(txApp core.App) error {
+			// 每行为一个采集周期内某个自定义指标序列（name + labels）的数据
+			// counter 为周期内增量，gauge 为当前值，histogram 为周期内观测值之和、次数与非累计分桶
+			var sql string
+			if txApp.IsPostgres() {
+				sql = `
+					CREATE UNLOGGED TABLE IF NOT EXISTS {{_metrics_custom}} (
+						[[id]]        TEXT PRIMARY KEY DEFAULT ('r'||lower(encode(gen_random_bytes(7), 'hex'))) NOT NULL,
+						[[timestamp]] TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+						[[name]]      TEXT DEFAULT '' NOT NULL,
+						[[type]]      TEXT DEFAULT '' NOT NULL,
+						[[labels]]    JSONB DEFAULT '{}' NOT NULL,
+						[[value]]     DOUBLE PRECISION DEFAULT 0 NOT NULL,
+						[[count]]     BIGINT DEFAULT 0 NOT NULL,
+						[[buckets]]   JSONB DEFAULT '{}' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_custom_name_timestamp
+					ON {{_metrics_custom}} ([[name]], [[timestamp]]);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_custom_timestamp
+					ON {{_metrics_custom}} ([[timestamp]]);
+				`
+			} else {
+				sql = `
+					CREATE TABLE IF NOT EXISTS {{_metrics_custom}} (
+						[[id]]        TEXT PRIMARY KEY DEFAULT ('r'||lower(hex(randomblob(7)))) NOT NULL,
+						[[timestamp]] TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+						[[name]]      TEXT DEFAULT '' NOT NULL,
+						[[type]]      TEXT DEFAULT '' NOT NULL,
+						[[labels]]    JSON DEFAULT '{}' NOT NULL,
+						[[value]]     REAL DEFAULT 0 NOT NULL,
+						[[count]]     INTEGER DEFAULT 0 NOT NULL,
+						[[buckets]]   JSON DEFAULT '{}' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_custom_name_timestamp
+					ON {{_metrics_custom}} ([[name]], [[timestamp]]);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_custom_timestamp
+					ON {{_metrics_custom}} ([[timestamp]]);
+				`
+			}
+			_, execErr := txApp.AuxDB().NewQuery(sql).Execute()
+			return execErr
+		},
+		Down: func(txApp core.App) error {
+			_, err := txApp.AuxDB().DropTable("_metrics_custom").Execute()
+			return err
+		},
+		ReapplyCondition: func(txApp core.App, runner *core.MigrationsRunner, fileName string) (bool, error) {
+			// 仅当 _metrics_custom 表不存在时重新应用
+			exists := txApp.AuxHasTable("_metrics_custom")
+			return !exists, nil
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func init() {
+	core.SystemMigrations.Add(&core.Migration{
+		Up: func(txApp core.App) error {
+			// 每行为一条耗时超过阈值的 SQLite 语句，sql 中的字面量已替换为 ?
+			// params 为各字面量的类型与长度，plan 为 EXPLAIN QUERY PLAN 的输出
+			var sql string
+			if txApp.IsPostgres() {
+				sql = `
+					CREATE UNLOGGED TABLE IF NOT EXISTS {{_metrics_slow_queries}} (
+						[[id]]          TEXT PRIMARY KEY DEFAULT ('r'||lower(encode(gen_random_bytes(7), 'hex'))) NOT NULL,
+						[[timestamp]]   TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+						[[sql]]         TEXT DEFAULT '' NOT NULL,
+						[[params]]      JSONB DEFAULT '[]' NOT NULL,
+						[[duration_ms]] DOUBLE PRECISION DEFAULT 0 NOT NULL,
+						[[error]]       TEXT DEFAULT '' NOT NULL,
+						[[plan]]        JSONB DEFAULT '[]' NOT NULL,
+						[[plan_error]]  TEXT DEFAULT '' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_slow_queries_timestamp
+					ON {{_metrics_slow_queries}} ([[timestamp]]);
+				`
+			} else {
+				sql = `
+					CREATE TABLE IF NOT EXISTS {{_metrics_slow_queries}} (
+						[[id]]          TEXT PRIMARY KEY DEFAULT ('r'||lower(hex(randomblob(7)))) NOT NULL,
+						[[timestamp]]   TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+						[[sql]]         TEXT DEFAULT '' NOT NULL,
+						[[params]]      JSON DEFAULT '[]' NOT NULL,
+						[[duration_ms]] REAL DEFAULT 0 NOT NULL,
+						[[error]]       TEXT DEFAULT '' NOT NULL,
+						[[plan]]        JSON DEFAULT '[]' NOT NULL,
+						[[plan_error]]  TEXT DEFAULT '' NOT NULL
+					);
+
+					CREATE INDEX IF NOT EXISTS idx_metrics_slow_queries_timestamp
+					ON {{_metrics_slow_queries}} ([[timestamp]]);
+				`
+			}
+			_, execErr := txApp.AuxDB().NewQuery(sql).Execute()
+			return execErr
+		},
+		Down: func(txApp core.App) error {
+			_, err := txApp.AuxDB().DropTable("_metrics_slow_queries").Execute()
+			return err
+		},
+		ReapplyCondition: func(txApp core.App, runner *core.MigrationsRunner, fileName string) (bool, error) {
+			// 仅当 _metrics_slow_queries 表不存在时重新应用
+			exists := txApp.AuxHasTable("_metrics_slow_queries")
+			return !exists, nil
+		},
+	})
+}
@@ -1,7 +1,10 @@
 package analytics
 
 import (
+	"container/list"
+	"strconv"
 	"sync"
+	"time"
 )
 
 // Aggregation 内存中的聚合数据（带 HLL 实例）
@@ -22,6 +25,27 @@ type deviceAggregationWithHLL struct {
 	hll *HLL
 }
 
+// EventAggregation 内存中的自定义事件聚合数据（带 HLL 实例）
+type eventAggregationWithHLL struct {
+	*EventAggregation
+	hll *HLL
+}
+
+// CampaignAggregation 内存中的 UTM 营销活动聚合数据（带 HLL 实例）
+type campaignAggregationWithHLL struct {
+	*CampaignAggregation
+	hll *HLL
+}
+
+// GeoAggregation 内存中的地理位置聚合数据（带 HLL 实例）
+type geoAggregationWithHLL struct {
+	*GeoAggregation
+	hll *HLL
+}
+
+// maxEventPropValueLength 属性值参与聚合时的最大长度（字符数），超出部分截断
+const maxEventPropValueLength = 100
+
 // Buffer 是分析事件的内存缓冲区。
 // 它实现了 Fork & Flush 架构中的 Fork 部分：
 // - Raw Buffer: 存储原始事件，用于写入 Parquet
@@ -44,6 +68,36 @@ type Buffer struct {
 
 	// deviceAggregations 存储按 date+browser+os 聚合的数据
 	deviceAggregations map[string]*deviceAggregationWithHLL
+
+	// eventAggregations 存储按 date+event(+prop_key+prop_value) 聚合的数据
+	// key: "2026-01-09|click_buy||"（总量）或 "2026-01-09|click_buy|plan|pro"
+	eventAggregations map[string]*eventAggregationWithHLL
+
+	// eventProps 需要按属性值拆分统计的属性名
+	eventProps []string
+
+	// visitAggregations 存储按 date+sid+event+path 聚合的访问记录（漏斗与留存分析）
+	visitAggregations map[string]*VisitAggregation
+
+	// campaignAggregations 存储按 date+utm_source+utm_medium+utm_campaign 聚合的数据
+	campaignAggregations map[string]*campaignAggregationWithHLL
+
+	// geoAggregations 存储按 date+country+region+city 聚合的数据
+	geoAggregations map[string]*geoAggregationWithHLL
+
+	// sessions 进行中的会话（按 SessionID），会话结束后计入 sessionAggregations
+	sessions       map[string]*sessionState
+	sessionTimeout time.Duration
+
+	// sessionOrder 按最近活动排序的进行中会话，队首为最久未活动的会话
+	sessionOrder *list.List
+	maxSessions  int
+
+	// sessionAggregations 存储按 date+path 聚合的已结束会话数据（入口页/退出页）
+	sessionAggregations map[string]*SessionAggregation
+
+	// realtime 实时分析滚动窗口（可选）
+	realtime *Realtime
 }
 
 // NewBuffer 创建一个新的缓冲区。
@@ -58,9 +112,33 @@ func NewBuffer(maxRawSize int) *Buffer {
 		aggregations:       make(map[string]*aggregationWithHLL),
 		sourceAggregations: make(map[string]*sourceAggregationWithHLL),
 		deviceAggregations: make(map[string]*deviceAggregationWithHLL),
+		eventAggregations:  make(map[string]*eventAggregationWithHLL),
+		visitAggregations:  make(map[string]*VisitAggregation),
+
+		campaignAggregations: make(map[string]*campaignAggregationWithHLL),
+		geoAggregations:      make(map[string]*geoAggregationWithHLL),
+		sessions:             make(map[string]*sessionState),
+		sessionTimeout:       DefaultSessionTimeout,
+		sessionOrder:         list.New(),
+		maxSessions:          DefaultMaxSessions,
+		sessionAggregations:  make(map[string]*SessionAggregation),
 	}
 }
 
+// SetEventProps 设置需要按属性值拆分统计的属性名。
+func (b *Buffer) SetEventProps(props []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.eventProps = props
+}
+
+// SetRealtime 设置实时分析滚动窗口，推入的事件会同时计入该窗口。
+func (b *Buffer) SetRealtime(realtime *Realtime) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.realtime = realtime
+}
+
 // Push 将事件推入缓冲区。
 // 事件会同时进入 Raw Buffer 和 Aggregation Map（Fork）。
 func (b *Buffer) Push(event *Event) error {
@@ -79,6 +157,16 @@ func (b *Buffer) Push(event *Event) error {
 	b.updateAggregation(event)
 	b.updateSourceAggregation(event)
 	b.updateDeviceAggregation(event)
+	b.updateEventAggregations(event)
+	b.updateVisitAggregation(event)
+	b.updateCampaignAggregation(event)
+	b.updateGeoAggregation(event)
+	b.trackSession(event)
+
+	// 实时分析滚动窗口
+	if b.realtime != nil {
+		b.realtime.Add(event)
+	}
 
 	return nil
 }
@@ -116,6 +204,31 @@ func (b *Buffer) DrainRaw() []*Event {
 	return events
 }
 
+// RestoreRaw 将原始事件放回 Raw Buffer（用于归档写入失败时重试）。
+// 为避免存储持续不可用导致内存无限增长，超过 maxRawSize 的部分会被丢弃。
+func (b *Buffer) RestoreRaw(events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	restored := make([]*Event, 0, len(events)+len(b.rawBuffer))
+	size := 0
+	for _, event := range events {
+		eventSize := b.estimateEventSize(event)
+		if size+eventSize > b.maxRawSize {
+			break
+		}
+		restored = append(restored, event)
+		size += eventSize
+	}
+
+	b.rawBuffer = append(restored, b.rawBuffer...)
+	b.rawSize += size
+}
+
 // DrainAggregations 取出并清空 Aggregation Map。
 // 在返回前，将内存中的 HLL 序列化到 Aggregation.HLL 字段。
 func (b *Buffer) DrainAggregations() map[string]*Aggregation {
@@ -183,6 +296,83 @@ func (b *Buffer) DrainDeviceAggregations() map[string]*DeviceAggregation {
 	return result
 }
 
+// DrainEventAggregations 取出并清空 Event Aggregation Map。
+func (b *Buffer) DrainEventAggregations() map[string]*EventAggregation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]*EventAggregation, len(b.eventAggregations))
+	for key, aggWithHLL := range b.eventAggregations {
+		// 序列化 HLL 到字节数组
+		if aggWithHLL.hll != nil {
+			hllBytes, err := aggWithHLL.hll.Bytes()
+			if err == nil {
+				aggWithHLL.EventAggregation.HLL = hllBytes
+			}
+		}
+		result[key] = aggWithHLL.EventAggregation
+	}
+
+	b.eventAggregations = make(map[string]*eventAggregationWithHLL)
+
+	return result
+}
+
+// DrainVisitAggregations 取出并清空 Visit Aggregation Map。
+func (b *Buffer) DrainVisitAggregations() map[string]*VisitAggregation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := b.visitAggregations
+	b.visitAggregations = make(map[string]*VisitAggregation)
+
+	return result
+}
+
+// DrainCampaignAggregations 取出并清空 Campaign Aggregation Map。
+func (b *Buffer) DrainCampaignAggregations() map[string]*CampaignAggregation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]*CampaignAggregation, len(b.campaignAggregations))
+	for key, aggWithHLL := range b.campaignAggregations {
+		// 序列化 HLL 到字节数组
+		if aggWithHLL.hll != nil {
+			hllBytes, err := aggWithHLL.hll.Bytes()
+			if err == nil {
+				aggWithHLL.CampaignAggregation.HLL = hllBytes
+			}
+		}
+		result[key] = aggWithHLL.CampaignAggregation
+	}
+
+	b.campaignAggregations = make(map[string]*campaignAggregationWithHLL)
+
+	return result
+}
+
+// DrainGeoAggregations 取出并清空 Geo Aggregation Map。
+func (b *Buffer) DrainGeoAggregations() map[string]*GeoAggregation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]*GeoAggregation, len(b.geoAggregations))
+	for key, aggWithHLL := range b.geoAggregations {
+		// 序列化 HLL 到字节数组
+		if aggWithHLL.hll != nil {
+			hllBytes, err := aggWithHLL.hll.Bytes()
+			if err == nil {
+				aggWithHLL.GeoAggregation.HLL = hllBytes
+			}
+		}
+		result[key] = aggWithHLL.GeoAggregation
+	}
+
+	b.geoAggregations = make(map[string]*geoAggregationWithHLL)
+
+	return result
+}
+
 // AggregationCount 返回聚合条目数量。
 func (b *Buffer) AggregationCount() int {
 	b.mu.RLock()
@@ -292,6 +482,180 @@ func (b *Buffer) updateDeviceAggregation(event *Event) {
 	}
 }
 
+// updateEventAggregations 更新自定义事件聚合数据。
+// 每个事件计入一行总量，并为每个已配置且存在的属性计入一行拆分明细。
+func (b *Buffer) updateEventAggregations(event *Event) {
+	if event.Event == "" {
+		return
+	}
+
+	date := event.Timestamp.Format("2006-01-02")
+	b.updateEventAggregation(date, event, "", "")
+
+	for _, prop := range b.eventProps {
+		value, ok := eventPropValue(event.Props[prop])
+		if !ok {
+			continue
+		}
+		b.updateEventAggregation(date, event, prop, value)
+	}
+}
+
+// updateEventAggregation 更新单个事件聚合条目。
+func (b *Buffer) updateEventAggregation(date string, event *Event, propKey, propValue string) {
+	key := date + "|" + event.Event + "|" + propKey + "|" + propValue
+
+	agg, exists := b.eventAggregations[key]
+	if !exists {
+		agg = &eventAggregationWithHLL{
+			EventAggregation: &EventAggregation{
+				Date:      date,
+				Event:     event.Event,
+				PropKey:   propKey,
+				PropValue: propValue,
+			},
+			hll: NewHLL(),
+		}
+		b.eventAggregations[key] = agg
+	}
+
+	agg.Count++
+
+	// 添加 SessionID 到 HLL 用于 UV 去重
+	if event.SessionID != "" {
+		if agg.hll == nil {
+			agg.hll = NewHLL()
+		}
+		agg.hll.Add(event.SessionID)
+	}
+}
+
+// updateVisitAggregation 更新访客访问记录。
+func (b *Buffer) updateVisitAggregation(event *Event) {
+	if event.SessionID == "" || event.Event == "" {
+		return
+	}
+
+	date := event.Timestamp.Format("2006-01-02")
+	key := date + "|" + event.SessionID + "|" + event.Event + "|" + event.Path
+	ts := event.Timestamp.UnixMilli()
+
+	agg, exists := b.visitAggregations[key]
+	if !exists {
+		b.visitAggregations[key] = &VisitAggregation{
+			Date:      date,
+			SessionID: event.SessionID,
+			Event:     event.Event,
+			Path:      event.Path,
+			FirstTs:   ts,
+			LastTs:    ts,
+			Hits:      1,
+		}
+		return
+	}
+
+	agg.FirstTs = min(agg.FirstTs, ts)
+	agg.LastTs = max(agg.LastTs, ts)
+	agg.Hits++
+}
+
+// updateCampaignAggregation 更新 UTM 营销活动聚合数据。
+// 只统计 Query 中带有 utm_source 或 utm_campaign 的事件。
+func (b *Buffer) updateCampaignAggregation(event *Event) {
+	utm, ok := ParseUTM(event.Query)
+	if !ok {
+		return
+	}
+
+	date := event.Timestamp.Format("2006-01-02")
+	key := date + "|" + utm.Source + "|" + utm.Medium + "|" + utm.Campaign
+
+	agg, exists := b.campaignAggregations[key]
+	if !exists {
+		agg = &campaignAggregationWithHLL{
+			CampaignAggregation: &CampaignAggregation{
+				Date:     date,
+				Source:   utm.Source,
+				Medium:   utm.Medium,
+				Campaign: utm.Campaign,
+			},
+			hll: NewHLL(),
+		}
+		b.campaignAggregations[key] = agg
+	}
+
+	agg.Count++
+
+	// 添加 SessionID 到 HLL 用于 UV 去重
+	if event.SessionID != "" {
+		if agg.hll == nil {
+			agg.hll = NewHLL()
+		}
+		agg.hll.Add(event.SessionID)
+	}
+}
+
+// updateGeoAggregation 更新地理位置聚合数据。
+// 只统计经过 GeoIP 解析（Country 非空）的事件。
+func (b *Buffer) updateGeoAggregation(event *Event) {
+	if event.Country == "" {
+		return
+	}
+
+	date := event.Timestamp.Format("2006-01-02")
+	key := date + "|" + event.Country + "|" + event.Region + "|" + event.City
+
+	agg, exists := b.geoAggregations[key]
+	if !exists {
+		agg = &geoAggregationWithHLL{
+			GeoAggregation: &GeoAggregation{
+				Date:    date,
+				Country: event.Country,
+				Region:  event.Region,
+				City:    event.City,
+			},
+			hll: NewHLL(),
+		}
+		b.geoAggregations[key] = agg
+	}
+
+	agg.Count++
+
+	// 添加 SessionID 到 HLL 用于 UV 去重
+	if event.SessionID != "" {
+		if agg.hll == nil {
+			agg.hll = NewHLL()
+		}
+		agg.hll.Add(event.SessionID)
+	}
+}
+
+// eventPropValue 将属性值转换为聚合使用的字符串。
+// 只支持标量值（字符串、数字、布尔），对象和数组不参与拆分。
+func eventPropValue(value any) (string, bool) {
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case bool:
+		str = strconv.FormatBool(v)
+	case float64:
+		str = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		str = strconv.Itoa(v)
+	case int64:
+		str = strconv.FormatInt(v, 10)
+	default:
+		return "", false
+	}
+
+	if runes := []rune(str); len(runes) > maxEventPropValueLength {
+		str = string(runes[:maxEventPropValueLength])
+	}
+
+	return str, true
+}
+
 // estimateEventSize 估算事件的内存占用（字节）。
 func (b *Buffer) estimateEventSize(event *Event) int {
 	// 粗略估算：固定开销 + 字符串长度
@@ -310,6 +674,7 @@ func (b *Buffer) estimateEventSize(event *Event) int {
 	size += len(event.OS)
 	size += len(event.Device)
 	size += len(event.Language)
+	size += len(event.Country) + len(event.Region) + len(event.City)
 	// Props 的估算较复杂，简单按 100 字节计算
 	if len(event.Props) > 0 {
 		size += 100
@@ -475,3 +840,146 @@ func (b *Buffer) RestoreDeviceAggregations(aggs map[string]*DeviceAggregation) {
 		}
 	}
 }
+
+// RestoreEventAggregations 将自定义事件聚合数据放回 buffer。
+func (b *Buffer) RestoreEventAggregations(aggs map[string]*EventAggregation) {
+	if len(aggs) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, agg := range aggs {
+		if existing, ok := b.eventAggregations[key]; ok {
+			existing.Count += agg.Count
+
+			// 合并 HLL
+			if len(agg.HLL) > 0 {
+				if existing.hll == nil {
+					existing.hll = NewHLL()
+				}
+				_ = existing.hll.MergeBytes(agg.HLL)
+			}
+		} else {
+			// 从字节数组恢复 HLL
+			var hll *HLL
+			if len(agg.HLL) > 0 {
+				var err error
+				hll, err = NewHLLFromBytes(agg.HLL)
+				if err != nil {
+					hll = NewHLL()
+				}
+			} else {
+				hll = NewHLL()
+			}
+
+			b.eventAggregations[key] = &eventAggregationWithHLL{
+				EventAggregation: agg,
+				hll:              hll,
+			}
+		}
+	}
+}
+
+// RestoreVisitAggregations 将访客访问聚合数据放回 buffer。
+func (b *Buffer) RestoreVisitAggregations(aggs map[string]*VisitAggregation) {
+	if len(aggs) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, agg := range aggs {
+		if existing, ok := b.visitAggregations[key]; ok {
+			existing.FirstTs = min(existing.FirstTs, agg.FirstTs)
+			existing.LastTs = max(existing.LastTs, agg.LastTs)
+			existing.Hits += agg.Hits
+		} else {
+			b.visitAggregations[key] = agg
+		}
+	}
+}
+
+// RestoreCampaignAggregations 将 UTM 营销活动聚合数据放回 buffer。
+func (b *Buffer) RestoreCampaignAggregations(aggs map[string]*CampaignAggregation) {
+	if len(aggs) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, agg := range aggs {
+		if existing, ok := b.campaignAggregations[key]; ok {
+			existing.Count += agg.Count
+
+			// 合并 HLL
+			if len(agg.HLL) > 0 {
+				if existing.hll == nil {
+					existing.hll = NewHLL()
+				}
+				_ = existing.hll.MergeBytes(agg.HLL)
+			}
+		} else {
+			// 从字节数组恢复 HLL
+			var hll *HLL
+			if len(agg.HLL) > 0 {
+				var err error
+				hll, err = NewHLLFromBytes(agg.HLL)
+				if err != nil {
+					hll = NewHLL()
+				}
+			} else {
+				hll = NewHLL()
+			}
+
+			b.campaignAggregations[key] = &campaignAggregationWithHLL{
+				CampaignAggregation: agg,
+				hll:                 hll,
+			}
+		}
+	}
+}
+
+// RestoreGeoAggregations 将地理位置聚合数据放回 buffer。
+func (b *Buffer) RestoreGeoAggregations(aggs map[string]*GeoAggregation) {
+	if len(aggs) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, agg := range aggs {
+		if existing, ok := b.geoAggregations[key]; ok {
+			existing.Count += agg.Count
+
+			// 合并 HLL
+			if len(agg.HLL) > 0 {
+				if existing.hll == nil {
+					existing.hll = NewHLL()
+				}
+				_ = existing.hll.MergeBytes(agg.HLL)
+			}
+		} else {
+			// 从字节数组恢复 HLL
+			var hll *HLL
+			if len(agg.HLL) > 0 {
+				var err error
+				hll, err = NewHLLFromBytes(agg.HLL)
+				if err != nil {
+					hll = NewHLL()
+				}
+			} else {
+				hll = NewHLL()
+			}
+
+			b.geoAggregations[key] = &geoAggregationWithHLL{
+				GeoAggregation: agg,
+				hll:            hll,
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package analytics
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -763,6 +764,65 @@ func TestBufferRestoreAggregations_MergeHLL_Proper(t *testing.T) {
 	}
 }
 
+func TestBufferEventAggregations(t *testing.T) {
+	buf := NewBuffer(0)
+	buf.SetEventProps([]string{"plan", "seats"})
+
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	buf.Push(&Event{Event: "click_buy", Path: "/pricing", SessionID: "s1", Timestamp: now, Props: map[string]any{"plan": "pro", "seats": float64(5), "ignored": "x"}})
+	buf.Push(&Event{Event: "click_buy", Path: "/pricing", SessionID: "s1", Timestamp: now, Props: map[string]any{"plan": "pro"}})
+	buf.Push(&Event{Event: "click_buy", Path: "/pricing", SessionID: "s2", Timestamp: now, Props: map[string]any{"plan": map[string]any{"nested": true}}})
+
+	aggs := buf.DrainEventAggregations()
+
+	total := aggs["2026-01-10|click_buy||"]
+	if total == nil || total.Count != 3 {
+		t.Fatalf("total aggregation = %+v", total)
+	}
+	if hll, err := NewHLLFromBytes(total.HLL); err != nil || hll.Count() != 2 {
+		t.Errorf("total visitors = %v, %v, want 2", hll, err)
+	}
+
+	if pro := aggs["2026-01-10|click_buy|plan|pro"]; pro == nil || pro.Count != 2 {
+		t.Errorf("plan=pro aggregation = %+v", pro)
+	}
+	if seats := aggs["2026-01-10|click_buy|seats|5"]; seats == nil || seats.Count != 1 {
+		t.Errorf("seats=5 aggregation = %+v", seats)
+	}
+
+	// 未配置的属性和非标量值不参与拆分
+	if len(aggs) != 3 {
+		t.Errorf("len(aggs) = %d, want 3", len(aggs))
+	}
+
+	if len(buf.DrainEventAggregations()) != 0 {
+		t.Error("event aggregations should be drained")
+	}
+}
+
+func TestEventPropValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+		ok    bool
+	}{
+		{"pro", "pro", true},
+		{true, "true", true},
+		{float64(9.5), "9.5", true},
+		{int64(3), "3", true},
+		{nil, "", false},
+		{[]any{"a"}, "", false},
+		{strings.Repeat("长", 150), strings.Repeat("长", maxEventPropValueLength), true},
+	}
+
+	for _, tt := range tests {
+		got, ok := eventPropValue(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("eventPropValue(%v) = (%q, %v), want (%q, %v)", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
 func BenchmarkBufferPush(b *testing.B) {
 	buf := NewBuffer(16 * 1024 * 1024)
 	event := &Event{
@@ -780,3 +840,63 @@ func BenchmarkBufferPush(b *testing.B) {
 		buf.Push(event)
 	}
 }
+
+func TestBufferCampaignAggregations(t *testing.T) {
+	buffer := NewBuffer(0)
+	ts := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	buffer.Push(&Event{Event: "page_view", Path: "/", Query: "utm_source=Newsletter&utm_medium=email&utm_campaign=launch", SessionID: "s1", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", Query: "utm_source=newsletter&utm_medium=email&utm_campaign=launch", SessionID: "s1", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", Query: "utm_source=newsletter&utm_medium=email&utm_campaign=launch", SessionID: "s2", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", Query: "page=2", SessionID: "s3", Timestamp: ts})
+
+	aggs := buffer.DrainCampaignAggregations()
+	if len(aggs) != 1 {
+		t.Fatalf("Expected 1 campaign aggregation, got %d", len(aggs))
+	}
+
+	agg := aggs["2026-01-09|newsletter|email|launch"]
+	if agg == nil || agg.Count != 3 {
+		t.Fatalf("Unexpected campaign aggregation: %+v", agg)
+	}
+
+	hll, err := NewHLLFromBytes(agg.HLL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hll.Count() != 2 {
+		t.Fatalf("Expected 2 visitors, got %d", hll.Count())
+	}
+
+	buffer.RestoreCampaignAggregations(aggs)
+	buffer.RestoreCampaignAggregations(aggs)
+	if restored := buffer.DrainCampaignAggregations(); restored["2026-01-09|newsletter|email|launch"].Count != 6 {
+		t.Fatalf("Expected restored count 6, got %d", restored["2026-01-09|newsletter|email|launch"].Count)
+	}
+}
+
+func TestBufferGeoAggregations(t *testing.T) {
+	buffer := NewBuffer(0)
+	ts := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	buffer.Push(&Event{Event: "page_view", Path: "/", Country: "US", Region: "California", City: "Mountain View", SessionID: "s1", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", Country: "US", Region: "California", City: "Mountain View", SessionID: "s2", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", Country: "DE", SessionID: "s3", Timestamp: ts})
+	buffer.Push(&Event{Event: "page_view", Path: "/", SessionID: "s4", Timestamp: ts})
+
+	aggs := buffer.DrainGeoAggregations()
+	if len(aggs) != 2 {
+		t.Fatalf("Expected 2 geo aggregations, got %d", len(aggs))
+	}
+
+	agg := aggs["2026-01-09|US|California|Mountain View"]
+	if agg == nil || agg.Count != 2 {
+		t.Fatalf("Unexpected geo aggregation: %+v", agg)
+	}
+
+	buffer.RestoreGeoAggregations(aggs)
+	buffer.RestoreGeoAggregations(aggs)
+	if restored := buffer.DrainGeoAggregations(); restored["2026-01-09|DE||"].Count != 2 {
+		t.Fatalf("Expected restored count 2, got %d", restored["2026-01-09|DE||"].Count)
+	}
+}
@@ -38,8 +38,43 @@ type Config struct {
 
 	// MaxRawSize Raw Buffer 最大容量（字节）
 	MaxRawSize int64
+
+	// SessionTimeout 会话超时时间，同一会话超过该时长无新事件即视为结束（默认 30 分钟）
+	SessionTimeout time.Duration
+
+	// MaxSessions 进行中会话数上限，达到上限时提前结束最久未活动的会话（默认 100000）
+	MaxSessions int
+
+	// ConsentMode 访客标识方式（standard/cookieless），默认 standard
+	ConsentMode ConsentMode
+
+	// IPMode IP 地址进入缓冲区前的处理方式（truncate/hash/full），默认 truncate
+	IPMode IPMode
+
+	// IgnoreDNT 是否忽略 DNT / Global Privacy Control 请求头（默认尊重，丢弃这些请求的事件）
+	IgnoreDNT bool
+
+	// StoreUserAgent 是否在原始事件中保留完整 User-Agent（默认只保留解析后的浏览器/系统/设备）
+	StoreUserAgent bool
+
+	// MaxSessionEventsPerMinute 单个会话每分钟最大事件数，超过时当天丢弃该会话（默认 60，负数表示不限制）
+	MaxSessionEventsPerMinute int
+
+	// GeoIPPath 本地 MaxMind 格式（MMDB）GeoIP 数据库文件路径，如 GeoLite2-City.mmdb
+	// 为空时不做地理位置解析；查询完全离线，不发起网络请求
+	GeoIPPath string
+
+	// EventProps 自定义事件按属性拆分统计的属性名（如 "plan"），最多 MaxEventProps 个
+	// 未配置的属性只计入事件总量，避免高基数属性撑大统计表
+	EventProps []string
+
+	// Funnels 预定义的漏斗，可通过 GET /api/analytics/funnels/{name} 查询
+	Funnels []Funnel
 }
 
+// MaxEventProps 可拆分统计的属性名数量上限
+const MaxEventProps = 5
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() Config {
 	return Config{
@@ -48,6 +83,13 @@ func DefaultConfig() Config {
 		Retention:     90,
 		FlushInterval: 10 * time.Second,
 		MaxRawSize:    16 * 1024 * 1024, // 16MB
+
+		SessionTimeout: DefaultSessionTimeout,
+		MaxSessions:    DefaultMaxSessions,
+
+		ConsentMode:               ConsentStandard,
+		IPMode:                    IPTruncate,
+		MaxSessionEventsPerMinute: DefaultMaxSessionEventsPerMinute,
 	}
 }
 
@@ -65,9 +107,46 @@ func applyDefaults(c Config) Config {
 	if c.MaxRawSize <= 0 {
 		c.MaxRawSize = 16 * 1024 * 1024
 	}
+	if c.SessionTimeout <= 0 {
+		c.SessionTimeout = DefaultSessionTimeout
+	}
+	if c.MaxSessions <= 0 {
+		c.MaxSessions = DefaultMaxSessions
+	}
+	if c.ConsentMode == "" {
+		c.ConsentMode = ConsentStandard
+	}
+	if c.IPMode == "" {
+		c.IPMode = IPTruncate
+	}
+	if c.MaxSessionEventsPerMinute == 0 {
+		c.MaxSessionEventsPerMinute = DefaultMaxSessionEventsPerMinute
+	}
+	c.EventProps = normalizeEventProps(c.EventProps)
 	return c
 }
 
+// normalizeEventProps 去除空白与重复的属性名，并截断到 MaxEventProps 个
+func normalizeEventProps(props []string) []string {
+	result := make([]string, 0, len(props))
+	seen := make(map[string]struct{}, len(props))
+	for _, prop := range props {
+		prop = strings.TrimSpace(prop)
+		if prop == "" {
+			continue
+		}
+		if _, ok := seen[prop]; ok {
+			continue
+		}
+		seen[prop] = struct{}{}
+		result = append(result, prop)
+		if len(result) == MaxEventProps {
+			break
+		}
+	}
+	return result
+}
+
 // applyEnvOverrides 应用环境变量覆盖
 func applyEnvOverrides(c Config) Config {
 	// PB_ANALYTICS_MODE
@@ -101,6 +180,52 @@ func applyEnvOverrides(c Config) Config {
 		}
 	}
 
+	// PB_ANALYTICS_SESSION_TIMEOUT (分钟)
+	if timeout := os.Getenv("PB_ANALYTICS_SESSION_TIMEOUT"); timeout != "" {
+		if t, err := strconv.Atoi(timeout); err == nil {
+			c.SessionTimeout = time.Duration(t) * time.Minute
+		}
+	}
+
+	// PB_ANALYTICS_MAX_SESSIONS
+	if maxSessions := os.Getenv("PB_ANALYTICS_MAX_SESSIONS"); maxSessions != "" {
+		if n, err := strconv.Atoi(maxSessions); err == nil {
+			c.MaxSessions = n
+		}
+	}
+
+	// PB_ANALYTICS_CONSENT_MODE
+	if mode := os.Getenv("PB_ANALYTICS_CONSENT_MODE"); mode != "" {
+		c.ConsentMode = ConsentMode(mode)
+	}
+
+	// PB_ANALYTICS_IP_MODE
+	if mode := os.Getenv("PB_ANALYTICS_IP_MODE"); mode != "" {
+		c.IPMode = IPMode(mode)
+	}
+
+	// PB_ANALYTICS_IGNORE_DNT
+	if ignore := os.Getenv("PB_ANALYTICS_IGNORE_DNT"); ignore != "" {
+		c.IgnoreDNT = strings.ToLower(ignore) == "true" || ignore == "1"
+	}
+
+	// PB_ANALYTICS_MAX_SESSION_EVENTS（每分钟）
+	if maxEvents := os.Getenv("PB_ANALYTICS_MAX_SESSION_EVENTS"); maxEvents != "" {
+		if n, err := strconv.Atoi(maxEvents); err == nil {
+			c.MaxSessionEventsPerMinute = n
+		}
+	}
+
+	// PB_ANALYTICS_GEOIP_PATH
+	if path := os.Getenv("PB_ANALYTICS_GEOIP_PATH"); path != "" {
+		c.GeoIPPath = path
+	}
+
+	// PB_ANALYTICS_EVENT_PROPS（逗号分隔）
+	if props := os.Getenv("PB_ANALYTICS_EVENT_PROPS"); props != "" {
+		c.EventProps = strings.Split(props, ",")
+	}
+
 	// S3 配置
 	if bucket := os.Getenv("PB_ANALYTICS_S3_BUCKET"); bucket != "" {
 		c.S3Bucket = bucket
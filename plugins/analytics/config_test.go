@@ -73,6 +73,22 @@ func TestApplyDefaults_PreservesValues(t *testing.T) {
 	}
 }
 
+func TestApplyDefaults_EventProps(t *testing.T) {
+	cfg := applyDefaults(Config{
+		EventProps: []string{" plan ", "", "plan", "a", "b", "c", "d", "e"},
+	})
+
+	want := []string{"plan", "a", "b", "c", "d"}
+	if len(cfg.EventProps) != len(want) {
+		t.Fatalf("applyDefaults().EventProps = %v, want %v", cfg.EventProps, want)
+	}
+	for i := range want {
+		if cfg.EventProps[i] != want[i] {
+			t.Errorf("applyDefaults().EventProps[%d] = %q, want %q", i, cfg.EventProps[i], want[i])
+		}
+	}
+}
+
 func TestApplyEnvOverrides(t *testing.T) {
 	// 设置环境变量
 	os.Setenv("PB_ANALYTICS_MODE", "full")
@@ -116,6 +132,37 @@ func TestApplyEnvOverrides(t *testing.T) {
 	}
 }
 
+func TestApplyEnvOverrides_Privacy(t *testing.T) {
+	t.Setenv("PB_ANALYTICS_CONSENT_MODE", "cookieless")
+	t.Setenv("PB_ANALYTICS_IP_MODE", "hash")
+	t.Setenv("PB_ANALYTICS_IGNORE_DNT", "true")
+	t.Setenv("PB_ANALYTICS_MAX_SESSION_EVENTS", "30")
+	t.Setenv("PB_ANALYTICS_GEOIP_PATH", "/data/GeoLite2-City.mmdb")
+
+	cfg := applyEnvOverrides(Config{})
+
+	if cfg.ConsentMode != ConsentCookieless {
+		t.Errorf("applyEnvOverrides().ConsentMode = %v, want %v", cfg.ConsentMode, ConsentCookieless)
+	}
+	if cfg.IPMode != IPHash {
+		t.Errorf("applyEnvOverrides().IPMode = %v, want %v", cfg.IPMode, IPHash)
+	}
+	if !cfg.IgnoreDNT {
+		t.Error("applyEnvOverrides().IgnoreDNT should be true")
+	}
+	if cfg.MaxSessionEventsPerMinute != 30 {
+		t.Errorf("applyEnvOverrides().MaxSessionEventsPerMinute = %v, want 30", cfg.MaxSessionEventsPerMinute)
+	}
+	if cfg.GeoIPPath != "/data/GeoLite2-City.mmdb" {
+		t.Errorf("applyEnvOverrides().GeoIPPath = %v", cfg.GeoIPPath)
+	}
+
+	cfg = applyDefaults(Config{})
+	if cfg.ConsentMode != ConsentStandard || cfg.IPMode != IPTruncate || cfg.MaxSessionEventsPerMinute != DefaultMaxSessionEventsPerMinute {
+		t.Errorf("applyDefaults() privacy = %v/%v/%v", cfg.ConsentMode, cfg.IPMode, cfg.MaxSessionEventsPerMinute)
+	}
+}
+
 func TestApplyEnvOverrides_InvalidValues(t *testing.T) {
 	// 设置无效的环境变量值
 	os.Setenv("PB_ANALYTICS_RETENTION", "invalid")
@@ -51,6 +51,15 @@ type Event struct {
 	// Language 是浏览器语言
 	Language string `json:"lang,omitempty"`
 
+	// Country 是 GeoIP 解析出的国家代码（ISO 3166-1，如 "US"）
+	Country string `json:"country,omitempty"`
+
+	// Region 是 GeoIP 解析出的一级行政区名称
+	Region string `json:"region,omitempty"`
+
+	// City 是 GeoIP 解析出的城市名称
+	City string `json:"city,omitempty"`
+
 	// Props 是业务自定义属性（JSON 格式）
 	Props map[string]any `json:"props,omitempty"`
 
@@ -85,6 +94,7 @@ type EventInput struct {
 	SessionID string         `json:"sid"`
 	Path      string         `json:"path"`
 	Query     string         `json:"query,omitempty"`
+	URL       string         `json:"url,omitempty"` // 完整页面 URL（可选），Query 为空时从中提取查询字符串
 	Referrer  string         `json:"referrer,omitempty"`
 	Title     string         `json:"title,omitempty"`
 	Language  string         `json:"lang,omitempty"`
@@ -15,6 +15,11 @@ type Flusher struct {
 	buffer     *Buffer
 	repository Repository
 	config     *Config
+	archive    *RawArchive // 原始事件归档（可选）
+
+	// lastRawFlush 上次写入原始事件归档的时间
+	// 低流量时 Raw Buffer 难以达到 MaxRawSize，按 DefaultRawFlushInterval 定期落盘
+	lastRawFlush time.Time
 
 	mu      sync.Mutex
 	running bool
@@ -23,16 +28,25 @@ type Flusher struct {
 	ticker  *time.Ticker
 }
 
+// DefaultRawFlushInterval Raw Buffer 未满时写入归档的最长间隔
+const DefaultRawFlushInterval = 5 * time.Minute
+
 // NewFlusher 创建一个新的 Flusher 实例。
 func NewFlusher(app core.App, buffer *Buffer, repo Repository, config *Config) *Flusher {
 	return &Flusher{
-		app:        app,
-		buffer:     buffer,
-		repository: repo,
-		config:     config,
+		app:          app,
+		buffer:       buffer,
+		repository:   repo,
+		config:       config,
+		lastRawFlush: time.Now(),
 	}
 }
 
+// SetRawArchive 设置原始事件归档，未设置时原始事件在刷新时被丢弃。
+func (f *Flusher) SetRawArchive(archive *RawArchive) {
+	f.archive = archive
+}
+
 // Start 启动定时刷新任务。
 func (f *Flusher) Start(ctx context.Context) error {
 	f.mu.Lock()
@@ -71,8 +85,16 @@ func (f *Flusher) Stop(ctx context.Context) error {
 	// 等待运行循环结束
 	<-f.doneCh
 
-	// 执行最后一次刷新
-	return f.Flush(ctx)
+	// 执行最后一次刷新（包括未满的 Raw Buffer 和进行中的会话）
+	if f.buffer != nil {
+		f.buffer.CloseAllSessions()
+	}
+	err := f.Flush(ctx)
+	if rawErr := f.flushRawEvents(ctx); rawErr != nil && f.app != nil {
+		f.app.Logger().Error("Failed to flush raw analytics events", "error", rawErr)
+	}
+
+	return err
 }
 
 // Flush 立即执行一次刷新操作。
@@ -86,13 +108,13 @@ func (f *Flusher) Flush(ctx context.Context) error {
 		return err
 	}
 
-	// 检查是否需要刷新原始日志到 Parquet
-	if f.buffer.ShouldFlushRaw() {
-		if err := f.flushRawToParquet(ctx); err != nil {
+	// 检查是否需要刷新原始日志到归档
+	if f.shouldFlushRaw() {
+		if err := f.flushRawEvents(ctx); err != nil {
 			// 原始日志刷新失败不应阻塞聚合数据刷新
 			// 记录错误但继续
 			if f.app != nil {
-				f.app.Logger().Error("Failed to flush raw events to Parquet", "error", err)
+				f.app.Logger().Error("Failed to flush raw analytics events", "error", err)
 			}
 		}
 	}
@@ -110,13 +132,22 @@ func (f *Flusher) FlushWithRetry(ctx context.Context, maxRetries int, retryDelay
 		return nil
 	}
 
+	// 结束超时的会话，使其计入会话聚合数据
+	f.buffer.CloseIdleSessions(time.Now())
+
 	// 先获取数据（会清空 buffer）
 	dailyAggs := f.buffer.DrainAggregations()
 	sourceAggs := f.buffer.DrainSourceAggregations()
 	deviceAggs := f.buffer.DrainDeviceAggregations()
+	eventAggs := f.buffer.DrainEventAggregations()
+	visitAggs := f.buffer.DrainVisitAggregations()
+	campaignAggs := f.buffer.DrainCampaignAggregations()
+	geoAggs := f.buffer.DrainGeoAggregations()
+	sessionAggs := f.buffer.DrainSessionAggregations()
 
 	// 如果没有数据，直接返回
-	if len(dailyAggs) == 0 && len(sourceAggs) == 0 && len(deviceAggs) == 0 {
+	if len(dailyAggs) == 0 && len(sourceAggs) == 0 && len(deviceAggs) == 0 && len(eventAggs) == 0 &&
+		len(visitAggs) == 0 && len(campaignAggs) == 0 && len(geoAggs) == 0 && len(sessionAggs) == 0 {
 		return nil
 	}
 
@@ -126,19 +157,19 @@ func (f *Flusher) FlushWithRetry(ctx context.Context, maxRetries int, retryDelay
 		select {
 		case <-ctx.Done():
 			// 将数据放回 buffer
-			f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs)
+			f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs, eventAggs, visitAggs, campaignAggs, geoAggs, sessionAggs)
 			return ctx.Err()
 		default:
 		}
 
 		// 尝试写入
-		err := f.writeAggregationsFromMaps(ctx, dailyAggs, sourceAggs, deviceAggs)
+		err := f.writeAggregationsFromMaps(ctx, dailyAggs, sourceAggs, deviceAggs, eventAggs, visitAggs, campaignAggs, geoAggs, sessionAggs)
 		if err == nil {
 			// 成功，处理原始日志
-			if f.buffer.ShouldFlushRaw() {
-				if rawErr := f.flushRawToParquet(ctx); rawErr != nil {
+			if f.shouldFlushRaw() {
+				if rawErr := f.flushRawEvents(ctx); rawErr != nil {
 					if f.app != nil {
-						f.app.Logger().Error("Failed to flush raw events to Parquet", "error", rawErr)
+						f.app.Logger().Error("Failed to flush raw analytics events", "error", rawErr)
 					}
 				}
 			}
@@ -162,7 +193,7 @@ func (f *Flusher) FlushWithRetry(ctx context.Context, maxRetries int, retryDelay
 			select {
 			case <-ctx.Done():
 				// 将数据放回 buffer
-				f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs)
+				f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs, eventAggs, visitAggs, campaignAggs, geoAggs, sessionAggs)
 				return ctx.Err()
 			case <-time.After(delay):
 				// 继续重试
@@ -171,7 +202,7 @@ func (f *Flusher) FlushWithRetry(ctx context.Context, maxRetries int, retryDelay
 	}
 
 	// 所有重试都失败，将数据放回 buffer 以便下次重试
-	f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs)
+	f.restoreAggregations(dailyAggs, sourceAggs, deviceAggs, eventAggs, visitAggs, campaignAggs, geoAggs, sessionAggs)
 
 	if f.app != nil {
 		f.app.Logger().Error("Analytics flush failed after all retries",
@@ -182,7 +213,7 @@ func (f *Flusher) FlushWithRetry(ctx context.Context, maxRetries int, retryDelay
 }
 
 // writeAggregationsFromMaps 将聚合数据写入数据库。
-func (f *Flusher) writeAggregationsFromMaps(ctx context.Context, dailyAggs map[string]*Aggregation, sourceAggs map[string]*SourceAggregation, deviceAggs map[string]*DeviceAggregation) error {
+func (f *Flusher) writeAggregationsFromMaps(ctx context.Context, dailyAggs map[string]*Aggregation, sourceAggs map[string]*SourceAggregation, deviceAggs map[string]*DeviceAggregation, eventAggs map[string]*EventAggregation, visitAggs map[string]*VisitAggregation, campaignAggs map[string]*CampaignAggregation, geoAggs map[string]*GeoAggregation, sessionAggs map[string]*SessionAggregation) error {
 	// 刷新每日统计
 	for _, agg := range dailyAggs {
 		stat := &DailyStat{
@@ -226,14 +257,73 @@ func (f *Flusher) writeAggregationsFromMaps(ctx context.Context, dailyAggs map[s
 		}
 	}
 
+	// 刷新自定义事件统计
+	for _, agg := range eventAggs {
+		if err := f.repository.UpsertEvent(ctx, newEventStat(agg)); err != nil {
+			return err
+		}
+	}
+
+	// 刷新访客访问记录
+	for _, agg := range visitAggs {
+		stat := &VisitStat{
+			ID:        generateID(agg.Date, agg.SessionID+"|"+agg.Event+"|"+agg.Path),
+			Date:      agg.Date,
+			SessionID: agg.SessionID,
+			Event:     agg.Event,
+			Path:      agg.Path,
+			FirstTs:   agg.FirstTs,
+			LastTs:    agg.LastTs,
+			Hits:      agg.Hits,
+		}
+		if err := f.repository.UpsertVisit(ctx, stat); err != nil {
+			return err
+		}
+	}
+
+	// 刷新 UTM 营销活动统计
+	for _, agg := range campaignAggs {
+		if err := f.repository.UpsertCampaign(ctx, newCampaignStat(agg)); err != nil {
+			return err
+		}
+	}
+
+	// 刷新地理位置统计
+	for _, agg := range geoAggs {
+		if err := f.repository.UpsertGeo(ctx, newGeoStat(agg)); err != nil {
+			return err
+		}
+	}
+
+	// 刷新会话页面统计
+	for _, agg := range sessionAggs {
+		stat := &SessionPageStat{
+			ID:       generateID(agg.Date, agg.Path),
+			Date:     agg.Date,
+			Path:     agg.Path,
+			Entries:  agg.Entries,
+			Exits:    agg.Exits,
+			Bounces:  agg.Bounces,
+			Duration: agg.Duration,
+		}
+		if err := f.repository.UpsertSessionPage(ctx, stat); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // restoreAggregations 将聚合数据放回 buffer。
-func (f *Flusher) restoreAggregations(dailyAggs map[string]*Aggregation, sourceAggs map[string]*SourceAggregation, deviceAggs map[string]*DeviceAggregation) {
+func (f *Flusher) restoreAggregations(dailyAggs map[string]*Aggregation, sourceAggs map[string]*SourceAggregation, deviceAggs map[string]*DeviceAggregation, eventAggs map[string]*EventAggregation, visitAggs map[string]*VisitAggregation, campaignAggs map[string]*CampaignAggregation, geoAggs map[string]*GeoAggregation, sessionAggs map[string]*SessionAggregation) {
 	f.buffer.RestoreAggregations(dailyAggs)
 	f.buffer.RestoreSourceAggregations(sourceAggs)
 	f.buffer.RestoreDeviceAggregations(deviceAggs)
+	f.buffer.RestoreEventAggregations(eventAggs)
+	f.buffer.RestoreVisitAggregations(visitAggs)
+	f.buffer.RestoreCampaignAggregations(campaignAggs)
+	f.buffer.RestoreGeoAggregations(geoAggs)
+	f.buffer.RestoreSessionAggregations(sessionAggs)
 }
 
 // run 是定时刷新的主循环。
@@ -263,69 +353,109 @@ func (f *Flusher) flushAggregations(ctx context.Context) error {
 		return nil
 	}
 
+	// 结束超时的会话，使其计入会话聚合数据
+	f.buffer.CloseIdleSessions(time.Now())
+
 	// 获取并清空聚合数据
 	dailyAggs := f.buffer.DrainAggregations()
 	sourceAggs := f.buffer.DrainSourceAggregations()
 	deviceAggs := f.buffer.DrainDeviceAggregations()
+	eventAggs := f.buffer.DrainEventAggregations()
+	visitAggs := f.buffer.DrainVisitAggregations()
+	campaignAggs := f.buffer.DrainCampaignAggregations()
+	geoAggs := f.buffer.DrainGeoAggregations()
+	sessionAggs := f.buffer.DrainSessionAggregations()
 
-	// 刷新每日统计
-	for _, agg := range dailyAggs {
-		stat := &DailyStat{
-			ID:       generateID(agg.Date, agg.Path),
-			Date:     agg.Date,
-			Path:     agg.Path,
-			TotalPV:  agg.PV,
-			TotalUV:  agg.HLL,
-			Visitors: agg.Count,
-			AvgDur:   0,
-		}
-		if err := f.repository.UpsertDaily(ctx, stat); err != nil {
-			return err
-		}
+	return f.writeAggregationsFromMaps(ctx, dailyAggs, sourceAggs, deviceAggs, eventAggs, visitAggs, campaignAggs, geoAggs, sessionAggs)
+}
+
+// shouldFlushRaw 返回是否应该将 Raw Buffer 写入归档：
+// 达到 MaxRawSize，或距上次写入超过 DefaultRawFlushInterval。
+func (f *Flusher) shouldFlushRaw() bool {
+	if f.buffer.ShouldFlushRaw() {
+		return true
 	}
+	return f.buffer.Len() > 0 && time.Since(f.lastRawFlush) >= DefaultRawFlushInterval
+}
 
-	// 刷新来源统计
-	for _, agg := range sourceAggs {
-		stat := &SourceStat{
-			ID:       generateID(agg.Date, agg.Source),
-			Date:     agg.Date,
-			Source:   agg.Source,
-			Visitors: agg.Count,
-		}
-		if err := f.repository.UpsertSource(ctx, stat); err != nil {
-			return err
-		}
+// flushRawEvents 将原始事件写入按天归档的压缩文件。
+// 写入失败时将事件放回 Raw Buffer，等待下次重试。
+func (f *Flusher) flushRawEvents(ctx context.Context) error {
+	events := f.buffer.DrainRaw()
+	f.lastRawFlush = time.Now()
+	if len(events) == 0 || f.archive == nil {
+		return nil
 	}
 
-	// 刷新设备统计
-	for _, agg := range deviceAggs {
-		stat := &DeviceStat{
-			ID:       generateID(agg.Date, agg.Browser+"|"+agg.OS),
-			Date:     agg.Date,
-			Browser:  agg.Browser,
-			OS:       agg.OS,
-			Visitors: agg.Count,
-		}
-		if err := f.repository.UpsertDevice(ctx, stat); err != nil {
-			return err
-		}
+	if err := f.archive.Write(ctx, events); err != nil {
+		f.buffer.RestoreRaw(events)
+		return err
 	}
 
 	return nil
 }
 
-// flushRawToParquet 将原始事件刷新到 Parquet 文件。
-func (f *Flusher) flushRawToParquet(ctx context.Context) error {
-	events := f.buffer.DrainRaw()
-	if len(events) == 0 {
-		return nil
+// newEventStat 将事件聚合转换为统计行，Visitors 使用 HLL 估算。
+func newEventStat(agg *EventAggregation) *EventStat {
+	visitors := agg.Count
+	if len(agg.HLL) > 0 {
+		if hll, err := NewHLLFromBytes(agg.HLL); err == nil {
+			visitors = int64(hll.Count())
+		}
 	}
 
-	// TODO: 实现 Parquet 写入
-	// - SQLite 模式：写入本地文件
-	// - PostgreSQL 模式：写入 S3
+	return &EventStat{
+		ID:        generateID(agg.Date, agg.Event+"|"+agg.PropKey+"|"+agg.PropValue),
+		Date:      agg.Date,
+		Event:     agg.Event,
+		PropKey:   agg.PropKey,
+		PropValue: agg.PropValue,
+		Count:     agg.Count,
+		HLL:       agg.HLL,
+		Visitors:  visitors,
+	}
+}
 
-	return nil
+// newCampaignStat 将营销活动聚合转换为统计行，Visitors 使用 HLL 估算。
+func newCampaignStat(agg *CampaignAggregation) *CampaignStat {
+	visitors := agg.Count
+	if len(agg.HLL) > 0 {
+		if hll, err := NewHLLFromBytes(agg.HLL); err == nil {
+			visitors = int64(hll.Count())
+		}
+	}
+
+	return &CampaignStat{
+		ID:       generateID(agg.Date, agg.Source+"|"+agg.Medium+"|"+agg.Campaign),
+		Date:     agg.Date,
+		Source:   agg.Source,
+		Medium:   agg.Medium,
+		Campaign: agg.Campaign,
+		Count:    agg.Count,
+		HLL:      agg.HLL,
+		Visitors: visitors,
+	}
+}
+
+// newGeoStat 将地理位置聚合转换为统计行，Visitors 使用 HLL 估算。
+func newGeoStat(agg *GeoAggregation) *GeoStat {
+	visitors := agg.Count
+	if len(agg.HLL) > 0 {
+		if hll, err := NewHLLFromBytes(agg.HLL); err == nil {
+			visitors = int64(hll.Count())
+		}
+	}
+
+	return &GeoStat{
+		ID:       generateID(agg.Date, agg.Country+"|"+agg.Region+"|"+agg.City),
+		Date:     agg.Date,
+		Country:  agg.Country,
+		Region:   agg.Region,
+		City:     agg.City,
+		Count:    agg.Count,
+		HLL:      agg.HLL,
+		Visitors: visitors,
+	}
 }
 
 // generateID 生成分析数据的唯一 ID。
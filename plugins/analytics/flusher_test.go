@@ -15,6 +15,10 @@ type mockRepository struct {
 	dailyStats      map[string]*DailyStat
 	sourceStats     map[string]*SourceStat
 	deviceStats     map[string]*DeviceStat
+	eventStats      map[string]*EventStat
+	campaignStats   map[string]*CampaignStat
+	geoStats        map[string]*GeoStat
+	sessionStats    map[string]*SessionPageStat
 	upsertDailyErr  error
 	upsertSourceErr error
 	upsertDeviceErr error
@@ -26,6 +30,11 @@ func newMockRepository() *mockRepository {
 		dailyStats:  make(map[string]*DailyStat),
 		sourceStats: make(map[string]*SourceStat),
 		deviceStats: make(map[string]*DeviceStat),
+		eventStats:  make(map[string]*EventStat),
+
+		campaignStats: make(map[string]*CampaignStat),
+		geoStats:      make(map[string]*GeoStat),
+		sessionStats:  make(map[string]*SessionPageStat),
 	}
 }
 
@@ -62,6 +71,71 @@ func (m *mockRepository) UpsertDevice(ctx context.Context, stat *DeviceStat) err
 	return nil
 }
 
+func (m *mockRepository) UpsertEvent(ctx context.Context, stat *EventStat) error {
+	m.callCount.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventStats[stat.ID] = stat
+	return nil
+}
+
+func (m *mockRepository) GetEventStats(ctx context.Context, startDate, endDate, event, propKey string) ([]*EventStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) UpsertVisit(ctx context.Context, stat *VisitStat) error {
+	m.callCount.Add(1)
+	return nil
+}
+
+func (m *mockRepository) GetVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) GetFirstVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) UpsertCampaign(ctx context.Context, stat *CampaignStat) error {
+	m.callCount.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.campaignStats[stat.ID] = stat
+	return nil
+}
+
+func (m *mockRepository) GetTopCampaigns(ctx context.Context, startDate, endDate string, limit int) ([]*CampaignStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) UpsertGeo(ctx context.Context, stat *GeoStat) error {
+	m.callCount.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.geoStats[stat.ID] = stat
+	return nil
+}
+
+func (m *mockRepository) GetTopGeo(ctx context.Context, startDate, endDate, country string, limit int) ([]*GeoStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) UpsertSessionPage(ctx context.Context, stat *SessionPageStat) error {
+	m.callCount.Add(1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessionStats[stat.ID] = stat
+	return nil
+}
+
+func (m *mockRepository) GetTopSessionPages(ctx context.Context, startDate, endDate, orderBy string, limit int) ([]*SessionPageStat, error) {
+	return nil, nil
+}
+
+func (m *mockRepository) GetSessionTotals(ctx context.Context, startDate, endDate string) (*SessionPageStat, error) {
+	return &SessionPageStat{}, nil
+}
+
 func (m *mockRepository) GetDailyStats(ctx context.Context, startDate, endDate string) ([]*DailyStat, error) {
 	return nil, nil
 }
@@ -584,8 +658,8 @@ func TestFlusherRunContextCancel(t *testing.T) {
 	}
 }
 
-// TestFlusherFlushRawToParquet 测试 Parquet 刷新（目前是 TODO，只验证不 panic）
-func TestFlusherFlushRawToParquet(t *testing.T) {
+// TestFlusherFlushRawEvents 测试未设置归档时的原始事件刷新（事件被丢弃）
+func TestFlusherFlushRawEvents(t *testing.T) {
 	buffer := NewBuffer(1024)
 	repo := newMockRepository()
 	config := DefaultConfig()
@@ -601,10 +675,9 @@ func TestFlusherFlushRawToParquet(t *testing.T) {
 	}
 	buffer.Push(event)
 
-	// 调用 flushRawToParquet（私有方法，通过反射测试或直接调用）
-	err := flusher.flushRawToParquet(ctx)
+	err := flusher.flushRawEvents(ctx)
 	if err != nil {
-		t.Fatalf("flushRawToParquet failed: %v", err)
+		t.Fatalf("flushRawEvents failed: %v", err)
 	}
 
 	// 验证 buffer 被清空
@@ -613,8 +686,8 @@ func TestFlusherFlushRawToParquet(t *testing.T) {
 	}
 }
 
-// TestFlusherFlushRawToParquetEmpty 测试空 buffer 的 Parquet 刷新
-func TestFlusherFlushRawToParquetEmpty(t *testing.T) {
+// TestFlusherFlushRawEventsEmpty 测试空 buffer 的原始事件刷新
+func TestFlusherFlushRawEventsEmpty(t *testing.T) {
 	buffer := NewBuffer(1024)
 	repo := newMockRepository()
 	config := DefaultConfig()
@@ -623,8 +696,8 @@ func TestFlusherFlushRawToParquetEmpty(t *testing.T) {
 	ctx := context.Background()
 
 	// 空 buffer 调用应该成功
-	err := flusher.flushRawToParquet(ctx)
+	err := flusher.flushRawEvents(ctx)
 	if err != nil {
-		t.Fatalf("flushRawToParquet with empty buffer failed: %v", err)
+		t.Fatalf("flushRawEvents with empty buffer failed: %v", err)
 	}
 }
@@ -0,0 +1,253 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFunnelWindow 漏斗默认转化窗口
+const DefaultFunnelWindow = 7 * 24 * time.Hour
+
+// MaxFunnelSteps 漏斗最大步骤数
+const MaxFunnelSteps = 10
+
+// CookielessFunnelWindow 无 Cookie 模式下漏斗的默认及最大转化窗口
+// 该模式下访客 ID 由每日轮换的盐生成，跨天无法关联同一访客，更长的窗口只会低估转化
+const CookielessFunnelWindow = 24 * time.Hour
+
+// FunnelStep 漏斗步骤，按事件名称和/或路径匹配访问记录
+type FunnelStep struct {
+	// Name 步骤显示名称（可选）
+	Name string `json:"name,omitempty"`
+
+	// Event 匹配的事件名称（可选）
+	Event string `json:"event,omitempty"`
+
+	// Path 匹配的路径（可选），以 "*" 结尾时按前缀匹配
+	Path string `json:"path,omitempty"`
+}
+
+// filter 返回步骤对应的访问过滤条件
+func (s FunnelStep) filter() VisitFilter {
+	return VisitFilter{Event: s.Event, Path: s.Path}
+}
+
+// Funnel 漏斗定义：按顺序完成的步骤，以及从第一步开始计算的转化窗口
+type Funnel struct {
+	// Name 漏斗名称，用于 GET /api/analytics/funnels/{name}
+	Name string `json:"name,omitempty"`
+
+	// Steps 有序步骤（2 - MaxFunnelSteps 个）
+	Steps []FunnelStep `json:"steps"`
+
+	// Window 转化窗口，如 "7d"、"12h"，默认 7 天
+	Window string `json:"window,omitempty"`
+}
+
+// Validate 校验漏斗定义
+func (f *Funnel) Validate() error {
+	if len(f.Steps) < 2 {
+		return errors.New("a funnel requires at least 2 steps")
+	}
+	if len(f.Steps) > MaxFunnelSteps {
+		return fmt.Errorf("a funnel can have at most %d steps", MaxFunnelSteps)
+	}
+
+	for i, step := range f.Steps {
+		if step.Event == "" && step.Path == "" {
+			return fmt.Errorf("steps[%d]: event or path is required", i)
+		}
+	}
+
+	if _, err := f.window(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// window 解析转化窗口
+func (f *Funnel) window() (time.Duration, error) {
+	if f.Window == "" {
+		return DefaultFunnelWindow, nil
+	}
+
+	window, err := parseWindow(f.Window)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid window %q, expected a duration like 7d or 12h", f.Window)
+	}
+
+	return window, nil
+}
+
+// windowFor 返回漏斗在指定访客标识方式下的转化窗口
+// 无 Cookie 模式下未设置窗口时使用 CookielessFunnelWindow，设置的窗口超过该值时返回错误
+func (f *Funnel) windowFor(mode ConsentMode) (time.Duration, error) {
+	if mode != ConsentCookieless {
+		return f.window()
+	}
+	if f.Window == "" {
+		return CookielessFunnelWindow, nil
+	}
+
+	window, err := f.window()
+	if err != nil {
+		return 0, err
+	}
+	if window > CookielessFunnelWindow {
+		return 0, fmt.Errorf("window %q exceeds 1d, visitors cannot be linked across days in cookieless mode", f.Window)
+	}
+
+	return window, nil
+}
+
+// filters 返回所有步骤的访问过滤条件
+func (f *Funnel) filters() []VisitFilter {
+	filters := make([]VisitFilter, 0, len(f.Steps))
+	for _, step := range f.Steps {
+		filters = append(filters, step.filter())
+	}
+	return filters
+}
+
+// parseWindow 解析时长，在 time.ParseDuration 的基础上支持 "d"（天）单位
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// FunnelStepResult 漏斗单个步骤的转化结果
+type FunnelStepResult struct {
+	FunnelStep
+
+	// Visitors 到达该步骤的访客数
+	Visitors int64 `json:"visitors"`
+
+	// Conversion 相对第一步的转化率（0-1）
+	Conversion float64 `json:"conversion"`
+
+	// StepConversion 相对上一步的转化率（0-1）
+	StepConversion float64 `json:"stepConversion"`
+
+	// AvgTimeMs 从上一步到该步骤的平均耗时（毫秒）
+	AvgTimeMs int64 `json:"avgTimeMs"`
+}
+
+// FunnelResult 漏斗计算结果
+type FunnelResult struct {
+	Steps []FunnelStepResult `json:"steps"`
+
+	// Conversion 整体转化率（最后一步 / 第一步）
+	Conversion float64 `json:"conversion"`
+}
+
+// ComputeFunnel 根据访问记录计算漏斗转化
+//
+// 每个访客以第一步的首次发生时间为起点，后续步骤必须按顺序发生且不晚于起点 + 转化窗口。
+// 访问记录只保存每天每个 (事件, 路径) 的首次和最后发生时间，
+// 因此同一天内反复发生的步骤按这两个时间点近似判断先后顺序。
+func ComputeFunnel(funnel *Funnel, visits []*VisitStat) (*FunnelResult, error) {
+	if err := funnel.Validate(); err != nil {
+		return nil, err
+	}
+
+	window, _ := funnel.window()
+
+	bySession := make(map[string][]*VisitStat)
+	for _, visit := range visits {
+		bySession[visit.SessionID] = append(bySession[visit.SessionID], visit)
+	}
+
+	reached := make([]int64, len(funnel.Steps))
+	elapsed := make([]int64, len(funnel.Steps))
+
+	for _, sessionVisits := range bySession {
+		var start, prev int64
+		for i, step := range funnel.Steps {
+			ts, ok := nextStepOccurrence(step, sessionVisits, prev, i == 0)
+			if !ok || (i > 0 && ts-start > window.Milliseconds()) {
+				break
+			}
+
+			if i == 0 {
+				start = ts
+			} else {
+				elapsed[i] += ts - prev
+			}
+			reached[i]++
+			prev = ts
+		}
+	}
+
+	result := &FunnelResult{Steps: make([]FunnelStepResult, len(funnel.Steps))}
+	for i, step := range funnel.Steps {
+		stepResult := FunnelStepResult{FunnelStep: step, Visitors: reached[i]}
+		if reached[0] > 0 {
+			stepResult.Conversion = float64(reached[i]) / float64(reached[0])
+		}
+		if i == 0 {
+			if reached[0] > 0 {
+				stepResult.StepConversion = 1
+			}
+		} else if reached[i-1] > 0 {
+			stepResult.StepConversion = float64(reached[i]) / float64(reached[i-1])
+		}
+		if i > 0 && reached[i] > 0 {
+			stepResult.AvgTimeMs = elapsed[i] / reached[i]
+		}
+		result.Steps[i] = stepResult
+	}
+	result.Conversion = result.Steps[len(result.Steps)-1].Conversion
+
+	return result, nil
+}
+
+// nextStepOccurrence 返回步骤在 after 之后（含）最早的已知发生时间
+// first 为 true 时返回该步骤的首次发生时间
+func nextStepOccurrence(step FunnelStep, visits []*VisitStat, after int64, first bool) (int64, bool) {
+	var best int64
+	found := false
+
+	for _, visit := range visits {
+		if !step.matches(visit) {
+			continue
+		}
+
+		var ts int64
+		switch {
+		case first || visit.FirstTs >= after:
+			ts = visit.FirstTs
+		case visit.LastTs >= after:
+			ts = visit.LastTs
+		default:
+			continue
+		}
+
+		if !found || ts < best {
+			best = ts
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// matches 返回访问记录是否匹配该步骤
+func (s FunnelStep) matches(visit *VisitStat) bool {
+	if s.Event != "" && visit.Event != s.Event {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(s.Path, "*"); ok {
+		return strings.HasPrefix(visit.Path, prefix)
+	}
+	return s.Path == "" || visit.Path == s.Path
+}
@@ -0,0 +1,172 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+// visit 构造测试用的访问记录
+func visit(sid, event, path string, ts time.Time) *VisitStat {
+	return &VisitStat{
+		Date:      ts.Format("2006-01-02"),
+		SessionID: sid,
+		Event:     event,
+		Path:      path,
+		FirstTs:   ts.UnixMilli(),
+		LastTs:    ts.UnixMilli(),
+		Hits:      1,
+	}
+}
+
+func TestFunnelValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		funnel  Funnel
+		wantErr bool
+	}{
+		{"valid", Funnel{Steps: []FunnelStep{{Path: "/pricing"}, {Event: "signup"}}}, false},
+		{"single step", Funnel{Steps: []FunnelStep{{Path: "/pricing"}}}, true},
+		{"empty step", Funnel{Steps: []FunnelStep{{Path: "/pricing"}, {}}}, true},
+		{"day window", Funnel{Steps: []FunnelStep{{Path: "/a"}, {Path: "/b"}}, Window: "14d"}, false},
+		{"hour window", Funnel{Steps: []FunnelStep{{Path: "/a"}, {Path: "/b"}}, Window: "12h"}, false},
+		{"invalid window", Funnel{Steps: []FunnelStep{{Path: "/a"}, {Path: "/b"}}, Window: "soon"}, true},
+		{"negative window", Funnel{Steps: []FunnelStep{{Path: "/a"}, {Path: "/b"}}, Window: "-1d"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.funnel.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFunnelStepMatches(t *testing.T) {
+	v := &VisitStat{Event: "page_view", Path: "/docs/intro"}
+
+	tests := []struct {
+		step FunnelStep
+		want bool
+	}{
+		{FunnelStep{Path: "/docs/intro"}, true},
+		{FunnelStep{Path: "/docs/*"}, true},
+		{FunnelStep{Path: "/docs"}, false},
+		{FunnelStep{Event: "page_view"}, true},
+		{FunnelStep{Event: "signup"}, false},
+		{FunnelStep{Event: "page_view", Path: "/blog/*"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.step.matches(v); got != tt.want {
+			t.Errorf("%+v.matches() = %v, want %v", tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestComputeFunnel(t *testing.T) {
+	base := time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	funnel := &Funnel{
+		Steps: []FunnelStep{
+			{Name: "pricing", Path: "/pricing"},
+			{Name: "signup", Event: "signup"},
+			{Name: "purchase", Event: "click_buy"},
+		},
+		Window: "7d",
+	}
+
+	visits := []*VisitStat{
+		// s1: 完整转化
+		visit("s1", "page_view", "/pricing", base),
+		visit("s1", "signup", "/register", base.Add(time.Hour)),
+		visit("s1", "click_buy", "/checkout", base.Add(3*time.Hour)),
+		// s2: 只注册
+		visit("s2", "page_view", "/pricing", base),
+		visit("s2", "signup", "/register", base.Add(2*24*time.Hour)),
+		// s3: 超出转化窗口
+		visit("s3", "page_view", "/pricing", base),
+		visit("s3", "signup", "/register", base.Add(8*24*time.Hour)),
+		// s4: 顺序错误（先注册后访问定价页）
+		visit("s4", "signup", "/register", base),
+		visit("s4", "page_view", "/pricing", base.Add(time.Hour)),
+		// s5: 没有进入第一步
+		visit("s5", "signup", "/register", base),
+	}
+
+	result, err := ComputeFunnel(funnel, visits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int64{4, 2, 1}
+	for i, step := range result.Steps {
+		if step.Visitors != want[i] {
+			t.Errorf("steps[%d].Visitors = %d, want %d", i, step.Visitors, want[i])
+		}
+	}
+
+	if result.Steps[1].Conversion != 0.5 || result.Steps[2].StepConversion != 0.5 {
+		t.Errorf("unexpected conversion %+v", result.Steps)
+	}
+	if result.Conversion != 0.25 {
+		t.Errorf("Conversion = %v, want 0.25", result.Conversion)
+	}
+
+	// s1: 1h，s2: 48h
+	if avg := result.Steps[1].AvgTimeMs; avg != (49 * time.Hour / 2).Milliseconds() {
+		t.Errorf("steps[1].AvgTimeMs = %d", avg)
+	}
+}
+
+func TestComputeFunnel_SameDayRepeat(t *testing.T) {
+	base := time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	// 同一天先注册、后访问定价页、再次注册：末次注册时间晚于定价页
+	signup := visit("s1", "signup", "/register", base)
+	signup.LastTs = base.Add(2 * time.Hour).UnixMilli()
+	signup.Hits = 2
+
+	funnel := &Funnel{Steps: []FunnelStep{{Path: "/pricing"}, {Event: "signup"}}}
+	result, err := ComputeFunnel(funnel, []*VisitStat{
+		signup,
+		visit("s1", "page_view", "/pricing", base.Add(time.Hour)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Steps[1].Visitors != 1 || result.Steps[1].AvgTimeMs != time.Hour.Milliseconds() {
+		t.Errorf("unexpected result %+v", result.Steps[1])
+	}
+}
+
+func TestFunnelWindowForCookieless(t *testing.T) {
+	steps := []FunnelStep{{Path: "/a"}, {Path: "/b"}}
+
+	tests := []struct {
+		window   string
+		mode     ConsentMode
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", ConsentStandard, DefaultFunnelWindow, false},
+		{"", ConsentCookieless, CookielessFunnelWindow, false},
+		{"12h", ConsentCookieless, 12 * time.Hour, false},
+		{"1d", ConsentCookieless, 24 * time.Hour, false},
+		{"2d", ConsentCookieless, 0, true},
+		{"2d", ConsentStandard, 48 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		funnel := &Funnel{Steps: steps, Window: tt.window}
+		window, err := funnel.windowFor(tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("windowFor(%q, %s) error = %v, wantErr %v", tt.window, tt.mode, err, tt.wantErr)
+		}
+		if window != tt.expected {
+			t.Errorf("windowFor(%q, %s) = %s, want %s", tt.window, tt.mode, window, tt.expected)
+		}
+	}
+}
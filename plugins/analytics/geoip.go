@@ -0,0 +1,422 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// GeoInfo 表示 IP 对应的地理位置
+type GeoInfo struct {
+	Country string // ISO 3166-1 国家代码，如 "US"
+	Region  string // 一级行政区名称，如 "California"
+	City    string // 城市名称，如 "Mountain View"
+}
+
+// ErrInvalidGeoIPDatabase 表示 GeoIP 数据库文件格式无效
+var ErrInvalidGeoIPDatabase = errors.New("invalid MaxMind DB file")
+
+// mmdbMetadataMarker 元数据段的起始标记
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbDataSectionSeparator 搜索树与数据段之间的 16 字节分隔
+const mmdbDataSectionSeparator = 16
+
+// GeoIP 是 MaxMind 格式（MMDB，如 GeoLite2-Country/City）数据库的只读查询器。
+// 数据库文件在打开时完整加载到内存，查询不进行任何网络请求。
+type GeoIP struct {
+	buf         []byte
+	data        []byte // 数据段
+	nodeCount   uint
+	recordSize  uint
+	ipVersion   uint
+	ipv4Start   uint
+	ipv4Invalid bool // IPv6 数据库中不存在 IPv4 子树
+}
+
+// OpenGeoIP 打开本地 MMDB 数据库文件。
+func OpenGeoIP(path string) (*GeoIP, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGeoIP(buf)
+}
+
+// NewGeoIP 从 MMDB 文件内容创建查询器。
+func NewGeoIP(buf []byte) (*GeoIP, error) {
+	markerIndex := bytes.LastIndex(buf, mmdbMetadataMarker)
+	if markerIndex < 0 {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+
+	metadataStart := markerIndex + len(mmdbMetadataMarker)
+	metadata, _, err := (&mmdbDecoder{buf: buf[metadataStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGeoIPDatabase, err)
+	}
+	meta, ok := metadata.(map[string]any)
+	if !ok {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+
+	g := &GeoIP{
+		buf:        buf,
+		nodeCount:  mmdbUint(meta["node_count"]),
+		recordSize: mmdbUint(meta["record_size"]),
+		ipVersion:  mmdbUint(meta["ip_version"]),
+	}
+
+	switch g.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidGeoIPDatabase, g.recordSize)
+	}
+
+	// 先限制节点数再计算搜索树大小，避免损坏的 node_count 导致溢出
+	if g.nodeCount == 0 || g.nodeCount > uint(markerIndex) {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+	treeSize := g.nodeCount * g.recordSize / 4
+	dataStart := treeSize + mmdbDataSectionSeparator
+	if dataStart > uint(markerIndex) {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+	g.data = buf[dataStart:markerIndex]
+
+	// IPv6 数据库中 IPv4 地址位于 ::/96 子树
+	if g.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < g.nodeCount; i++ {
+			node = g.readRecord(node, 0)
+		}
+		g.ipv4Start = node
+		g.ipv4Invalid = node >= g.nodeCount
+	}
+
+	return g, nil
+}
+
+// Lookup 查询 IP 对应的地理位置，未找到时返回 ok = false。
+func (g *GeoIP) Lookup(ip string) (GeoInfo, bool) {
+	record, err := g.lookupRecord(net.ParseIP(ip))
+	if err != nil || record == nil {
+		return GeoInfo{}, false
+	}
+
+	info := GeoInfo{
+		Country: mmdbPath(record, "country", "iso_code"),
+		City:    mmdbPath(record, "city", "names", "en"),
+	}
+	if info.Country == "" {
+		info.Country = mmdbPath(record, "registered_country", "iso_code")
+	}
+	if subdivisions, ok := record["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+		if first, ok := subdivisions[0].(map[string]any); ok {
+			info.Region = mmdbPath(first, "names", "en")
+			if info.Region == "" {
+				info.Region = mmdbPath(first, "iso_code")
+			}
+		}
+	}
+
+	return info, info.Country != ""
+}
+
+// lookupRecord 在搜索树中查找 IP 对应的数据记录
+func (g *GeoIP) lookupRecord(ip net.IP) (map[string]any, error) {
+	if ip == nil {
+		return nil, errors.New("invalid IP address")
+	}
+
+	node := uint(0)
+	bits := ip.To16()
+	if v4 := ip.To4(); v4 != nil {
+		bits = v4
+		if g.ipVersion == 6 {
+			if g.ipv4Invalid {
+				return nil, nil
+			}
+			node = g.ipv4Start
+		}
+	} else if g.ipVersion == 4 {
+		return nil, errors.New("IPv6 address in an IPv4-only database")
+	}
+
+	for i := 0; i < len(bits)*8 && node < g.nodeCount; i++ {
+		bit := (bits[i/8] >> (7 - uint(i%8))) & 1
+		node = g.readRecord(node, uint(bit))
+	}
+
+	if node == g.nodeCount {
+		// 未找到
+		return nil, nil
+	}
+	if node < g.nodeCount {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+
+	offset := node - g.nodeCount - mmdbDataSectionSeparator
+	if offset >= uint(len(g.data)) {
+		return nil, ErrInvalidGeoIPDatabase
+	}
+
+	value, _, err := (&mmdbDecoder{buf: g.data}).decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// readRecord 读取节点的左（bit = 0）或右（bit = 1）记录
+func (g *GeoIP) readRecord(node, bit uint) uint {
+	offset := node * g.recordSize / 4
+	b := g.buf[offset : offset+g.recordSize/4]
+
+	switch g.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// MMDB 数据类型
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// mmdbMaxDepth 解码时允许的最大嵌套深度，防止损坏的文件导致无限递归
+const mmdbMaxDepth = 32
+
+// mmdbMaxValues 单次解码允许的最大值数量，防止指针反复引用同一数据导致解码量指数级放大
+const mmdbMaxValues = 1 << 16
+
+// mmdbDecoder MMDB 数据段解码器
+type mmdbDecoder struct {
+	buf    []byte
+	depth  int
+	values int
+}
+
+// decode 解码 offset 处的值，返回值与下一个值的偏移
+func (d *mmdbDecoder) decode(offset uint) (any, uint, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > mmdbMaxDepth {
+		return nil, 0, errors.New("maximum data structure depth exceeded")
+	}
+	if d.values++; d.values > mmdbMaxValues {
+		return nil, 0, errors.New("maximum number of values exceeded")
+	}
+
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, errors.New("unexpected end of data")
+	}
+
+	ctrl := d.buf[offset]
+	offset++
+
+	typeNum := uint(ctrl >> 5)
+	if typeNum == mmdbExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, errors.New("unexpected end of data")
+		}
+		typeNum = uint(d.buf[offset]) + 7
+		offset++
+	}
+
+	if typeNum == mmdbPointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		// 指针不能指向另一个指针
+		if pointer < uint(len(d.buf)) && d.buf[pointer]>>5 == mmdbPointer {
+			return nil, 0, errors.New("pointer to a pointer")
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// map 的每个键值对至少占 2 字节，数组的每个元素至少占 1 字节，
+	// 先按剩余数据长度校验，避免损坏的长度导致超大的预分配
+	if (typeNum == mmdbMap || typeNum == mmdbArray) && size > uint(len(d.buf))-offset {
+		return nil, 0, errors.New("container size exceeds data")
+	}
+
+	switch typeNum {
+	case mmdbMap:
+		result := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			var key, value any
+			key, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			result[keyStr] = value
+		}
+		return result, offset, nil
+	case mmdbArray:
+		result := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value any
+			value, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+		}
+		return result, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.buf)) {
+		return nil, 0, errors.New("unexpected end of data")
+	}
+	b := d.buf[offset:end]
+
+	switch typeNum {
+	case mmdbString:
+		return string(b), end, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), end, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64, mmdbInt32:
+		if size > 8 {
+			return nil, 0, errors.New("invalid integer size")
+		}
+		var value uint64
+		for _, c := range b {
+			value = value<<8 | uint64(c)
+		}
+		if typeNum == mmdbInt32 {
+			return int64(int32(uint32(value))), end, nil
+		}
+		return value, end, nil
+	case mmdbUint128:
+		// 地理位置查询不需要 uint128，按原始字节返回
+		return append([]byte(nil), b...), end, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typeNum)
+	}
+}
+
+// size 解析控制字节中的长度
+func (d *mmdbDecoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1F)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("unexpected end of data")
+	}
+	b := d.buf[offset : offset+n]
+
+	switch size {
+	case 29:
+		return 29 + uint(b[0]), offset + n, nil
+	case 30:
+		return 285 + uint(b[0])<<8 | uint(b[1]), offset + n, nil
+	default:
+		return 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])), offset + n, nil
+	}
+}
+
+// pointer 解析指针，返回指向的偏移（相对数据段）与下一个值的偏移
+func (d *mmdbDecoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	n := uint((ctrl>>3)&0x3) + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("unexpected end of data")
+	}
+	b := d.buf[offset : offset+n]
+	v := uint(ctrl & 0x7)
+
+	var pointer uint
+	switch n {
+	case 1:
+		pointer = v<<8 | uint(b[0])
+	case 2:
+		pointer = (v<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		pointer = (v<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		pointer = uint(binary.BigEndian.Uint32(b))
+	}
+
+	return pointer, offset + n, nil
+}
+
+// mmdbUint 将解码后的整数转换为 uint
+func mmdbUint(value any) uint {
+	if v, ok := value.(uint64); ok {
+		return uint(v)
+	}
+	return 0
+}
+
+// mmdbPath 按路径读取嵌套 map 中的字符串
+func mmdbPath(record map[string]any, path ...string) string {
+	var current any = record
+	for _, key := range path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return ""
+		}
+		current = m[key]
+	}
+	str, _ := current.(string)
+	return str
+}
@@ -0,0 +1,353 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// testMMDBNetwork 测试数据库中的一个网段及其数据
+type testMMDBNetwork struct {
+	cidr   string
+	record map[string]any
+}
+
+// testMMDBEncoder 编码 MMDB 数据段，重复的字符串使用指针引用
+type testMMDBEncoder struct {
+	buf     bytes.Buffer
+	strings map[string]int
+}
+
+func (enc *testMMDBEncoder) ctrl(typeNum, size int) {
+	first := byte(typeNum << 5)
+	var ext []byte
+	if typeNum > 7 {
+		first = 0
+		ext = []byte{byte(typeNum - 7)}
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		first |= byte(size)
+	case size < 285:
+		first |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	default:
+		first |= 30
+		sizeBytes = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	}
+
+	enc.buf.WriteByte(first)
+	enc.buf.Write(ext)
+	enc.buf.Write(sizeBytes)
+}
+
+func (enc *testMMDBEncoder) encode(value any) {
+	switch v := value.(type) {
+	case string:
+		if offset, ok := enc.strings[v]; ok && enc.strings != nil && offset < 2048 {
+			enc.buf.WriteByte(byte(mmdbPointer<<5) | byte(offset>>8))
+			enc.buf.WriteByte(byte(offset))
+			return
+		}
+		if enc.strings != nil {
+			enc.strings[v] = enc.buf.Len()
+		}
+		enc.ctrl(mmdbString, len(v))
+		enc.buf.WriteString(v)
+	case uint64:
+		var b []byte
+		for n := v; n > 0; n >>= 8 {
+			b = append([]byte{byte(n)}, b...)
+		}
+		enc.ctrl(mmdbUint64, len(b))
+		enc.buf.Write(b)
+	case uint16:
+		enc.ctrl(mmdbUint16, 2)
+		enc.buf.Write(binary.BigEndian.AppendUint16(nil, v))
+	case uint32:
+		enc.ctrl(mmdbUint32, 4)
+		enc.buf.Write(binary.BigEndian.AppendUint32(nil, v))
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		enc.ctrl(mmdbBool, size)
+	case []any:
+		enc.ctrl(mmdbArray, len(v))
+		for _, item := range v {
+			enc.encode(item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		enc.ctrl(mmdbMap, len(v))
+		for _, key := range keys {
+			enc.encode(key)
+			enc.encode(v[key])
+		}
+	default:
+		panic(fmt.Sprintf("unsupported test value %T", value))
+	}
+}
+
+// buildTestMMDB 构建一个 IPv6 MMDB 数据库（IPv4 网段映射到 ::/96）
+func buildTestMMDB(t testing.TB, recordSize int, networks []testMMDBNetwork) []byte {
+	t.Helper()
+
+	const empty = -1
+
+	// nodes[i][bit] >= 0 为子节点，<= -2 为数据 -(n+2)
+	nodes := [][2]int{{empty, empty}}
+	data := &testMMDBEncoder{strings: map[string]int{}}
+	var dataOffsets []int
+
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, bits := ipNet.Mask.Size()
+		ip := ipNet.IP.To16()
+		if bits == 32 {
+			ip = append(make(net.IP, 12), ipNet.IP.To4()...)
+			ones += 96
+		}
+
+		dataOffsets = append(dataOffsets, data.buf.Len())
+		data.encode(network.record)
+		dataRef := -(len(dataOffsets) - 1 + 2)
+
+		node := 0
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+			if i == ones-1 {
+				nodes[node][bit] = dataRef
+				break
+			}
+			if nodes[node][bit] < 0 {
+				nodes = append(nodes, [2]int{empty, empty})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	value := func(record int) uint32 {
+		switch {
+		case record == empty:
+			return uint32(nodeCount)
+		case record <= -2:
+			return uint32(nodeCount + mmdbDataSectionSeparator + dataOffsets[-record-2])
+		default:
+			return uint32(record)
+		}
+	}
+
+	var out bytes.Buffer
+	for _, node := range nodes {
+		left, right := value(node[0]), value(node[1])
+		switch recordSize {
+		case 24:
+			out.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left), byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			out.Write([]byte{
+				byte(left >> 16), byte(left >> 8), byte(left),
+				byte((left>>24)<<4) | byte(right>>24&0x0F),
+				byte(right >> 16), byte(right >> 8), byte(right),
+			})
+		default:
+			out.Write(binary.BigEndian.AppendUint32(nil, left))
+			out.Write(binary.BigEndian.AppendUint32(nil, right))
+		}
+	}
+	out.Write(make([]byte, mmdbDataSectionSeparator))
+	out.Write(data.buf.Bytes())
+	out.Write(mmdbMetadataMarker)
+
+	meta := &testMMDBEncoder{}
+	meta.encode(map[string]any{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+		"ip_version":                  uint16(6),
+		"database_type":               "Test-City",
+		"languages":                   []any{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1767225600),
+		"description":                 map[string]any{"en": "analytics test database"},
+	})
+	out.Write(meta.buf.Bytes())
+
+	return out.Bytes()
+}
+
+// testGeoNetworks 测试用的网段数据
+func testGeoNetworks() []testMMDBNetwork {
+	return []testMMDBNetwork{
+		{
+			cidr: "8.8.8.0/24",
+			record: map[string]any{
+				"country":      map[string]any{"iso_code": "US", "names": map[string]any{"en": "United States"}},
+				"subdivisions": []any{map[string]any{"iso_code": "CA", "names": map[string]any{"en": "California"}}},
+				"city":         map[string]any{"names": map[string]any{"en": "Mountain View"}},
+				"location":     map[string]any{"is_in_european_union": false},
+			},
+		},
+		{
+			cidr: "81.2.69.0/24",
+			record: map[string]any{
+				"country":      map[string]any{"iso_code": "GB", "names": map[string]any{"en": "United Kingdom"}},
+				"subdivisions": []any{map[string]any{"iso_code": "WLS"}},
+				"city":         map[string]any{"names": map[string]any{"en": "Llanfairpwllgwyngyllgogerychwyrndrobwll"}},
+			},
+		},
+		{
+			cidr: "2001:db8::/32",
+			record: map[string]any{
+				"registered_country": map[string]any{"iso_code": "DE", "names": map[string]any{"en": "Germany"}},
+			},
+		},
+	}
+}
+
+func TestGeoIP_Lookup(t *testing.T) {
+	for _, recordSize := range []int{24, 28, 32} {
+		t.Run(fmt.Sprintf("record_size_%d", recordSize), func(t *testing.T) {
+			geoIP, err := NewGeoIP(buildTestMMDB(t, recordSize, testGeoNetworks()))
+			if err != nil {
+				t.Fatalf("NewGeoIP failed: %v", err)
+			}
+
+			tests := []struct {
+				ip       string
+				expected GeoInfo
+				found    bool
+			}{
+				{"8.8.8.8", GeoInfo{Country: "US", Region: "California", City: "Mountain View"}, true},
+				{"8.8.8.255", GeoInfo{Country: "US", Region: "California", City: "Mountain View"}, true},
+				{"81.2.69.160", GeoInfo{Country: "GB", Region: "WLS", City: "Llanfairpwllgwyngyllgogerychwyrndrobwll"}, true},
+				{"2001:db8::1", GeoInfo{Country: "DE"}, true},
+				{"8.8.4.4", GeoInfo{}, false},
+				{"2001:db9::1", GeoInfo{}, false},
+				{"not-an-ip", GeoInfo{}, false},
+				{"", GeoInfo{}, false},
+			}
+
+			for _, tt := range tests {
+				info, ok := geoIP.Lookup(tt.ip)
+				if ok != tt.found {
+					t.Errorf("Lookup(%q) found = %v, want %v", tt.ip, ok, tt.found)
+				}
+				if info != tt.expected {
+					t.Errorf("Lookup(%q) = %+v, want %+v", tt.ip, info, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenGeoIP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buildTestMMDB(t, 24, testGeoNetworks()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	geoIP, err := OpenGeoIP(path)
+	if err != nil {
+		t.Fatalf("OpenGeoIP failed: %v", err)
+	}
+	if info, ok := geoIP.Lookup("8.8.8.8"); !ok || info.Country != "US" {
+		t.Errorf("Lookup = %+v, %v", info, ok)
+	}
+
+	if _, err := OpenGeoIP(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestNewGeoIP_Invalid(t *testing.T) {
+	valid := buildTestMMDB(t, 24, testGeoNetworks())
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"empty", nil},
+		{"no metadata", []byte("not a maxmind database")},
+		{"truncated metadata", valid[:bytes.LastIndex(valid, mmdbMetadataMarker)+len(mmdbMetadataMarker)+1]},
+		{"truncated tree", valid[len(valid)/2:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGeoIP(tt.buf); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestMMDBDecoder_Malformed(t *testing.T) {
+	// 30 层数组，每层两个元素都指向上一层，完整展开需要 2^30 次解码
+	amplified := []byte{0x41, 'a'}
+	previous := 0
+	for i := 0; i < 30; i++ {
+		offset := len(amplified)
+		amplified = append(amplified, 0x02, byte(mmdbArray-7))
+		for j := 0; j < 2; j++ {
+			amplified = append(amplified, 0x20|byte(previous>>8), byte(previous))
+		}
+		previous = offset
+	}
+
+	tests := []struct {
+		name   string
+		buf    []byte
+		offset uint
+	}{
+		{"huge map size", []byte{0xE0 | 31, 0xFF, 0xFF, 0xFF}, 0},
+		{"huge array size", []byte{0x1F, byte(mmdbArray - 7), 0xFF, 0xFF, 0xFF}, 0},
+		{"pointer to itself", []byte{0x20, 0x00}, 0},
+		{"pointer out of range", []byte{0x27, 0xFF}, 0},
+		{"pointer amplification", amplified, uint(previous)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := (&mmdbDecoder{buf: tt.buf}).decode(tt.offset); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func FuzzNewGeoIP(f *testing.F) {
+	for _, recordSize := range []int{24, 28, 32} {
+		valid := buildTestMMDB(f, recordSize, testGeoNetworks())
+		f.Add(valid)
+		f.Add(valid[:len(valid)/2])
+		f.Add(valid[bytes.LastIndex(valid, mmdbMetadataMarker):])
+	}
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		geoIP, err := NewGeoIP(buf)
+		if err != nil {
+			return
+		}
+		for _, ip := range []string{"8.8.8.8", "81.2.69.160", "2001:db8::1", "::1", "255.255.255.255"} {
+			geoIP.Lookup(ip)
+		}
+	})
+}
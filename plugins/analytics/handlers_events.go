@@ -35,13 +35,26 @@ func eventsHandler(app core.App) func(*core.RequestEvent) error {
 		ip := e.RealIP()
 		ua := e.Request.Header.Get("User-Agent")
 
-		// 过滤爬虫流量
-		if IsBotUserAgent(ua) {
-			// 静默丢弃爬虫流量
+		// 自定义 Analytics 实现未提供采集过滤器时，使用基于配置的默认过滤器
+		var ingestion *Ingestion
+		if provider, ok := analytics.(IngestionProvider); ok && provider.Ingestion() != nil {
+			ingestion = provider.Ingestion()
+		} else {
+			ingestion = NewIngestion(analytics.Config())
+		}
+
+		// 过滤爬虫流量与拒绝追踪的请求（静默丢弃）
+		switch ingestion.Check(e.Request) {
+		case DropReasonBot:
 			return e.JSON(http.StatusAccepted, map[string]any{
 				"accepted": 0,
 				"message":  "bot traffic ignored",
 			})
+		case DropReasonDoNotTrack:
+			return e.JSON(http.StatusAccepted, map[string]any{
+				"accepted": 0,
+				"message":  "do not track honored",
+			})
 		}
 
 		// 解析 User-Agent
@@ -50,6 +63,14 @@ func eventsHandler(app core.App) func(*core.RequestEvent) error {
 		// 处理每个事件
 		accepted := 0
 		for _, eventInput := range input.Events {
+			// 提取查询字符串（UTM 参数），需在规范化路径之前进行
+			if eventInput.Query == "" {
+				eventInput.Query = ExtractQuery(eventInput.Path)
+			}
+			if eventInput.Query == "" {
+				eventInput.Query = ExtractQuery(eventInput.URL)
+			}
+
 			// 规范化 URL
 			normalizedPath := NormalizeURL(eventInput.Path)
 			eventInput.Path = normalizedPath
@@ -67,6 +88,11 @@ func eventsHandler(app core.App) func(*core.RequestEvent) error {
 				uaInfo.Device,
 			)
 
+			// 会话频率检查与匿名化（需在验证之前，无 Cookie 模式下会生成会话 ID）
+			if reason := ingestion.Prepare(&event); reason != "" {
+				continue
+			}
+
 			// 验证事件
 			if err := event.Validate(); err != nil {
 				// 跳过无效事件，继续处理其他事件
@@ -69,6 +69,46 @@ func TestEventsHandler_BotTraffic(t *testing.T) {
 	}
 }
 
+func TestEventsHandler_DoNotTrack(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	mux := setupTestRouter(t, app)
+
+	for _, header := range []string{"DNT", "Sec-GPC"} {
+		t.Run(header, func(t *testing.T) {
+			body := []byte(`{"events":[{"event":"page_view","path":"/home","sid":"test-session"}]}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/analytics/events", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+			req.Header.Set(header, "1")
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("Expected status %d, got %d", http.StatusAccepted, rec.Code)
+			}
+
+			var resp map[string]any
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if resp["accepted"] != float64(0) {
+				t.Errorf("Expected accepted=0 with %s, got %v", header, resp["accepted"])
+			}
+		})
+	}
+
+	if n := GetAnalytics(app).(*analyticsImpl).buffer.Len(); n != 0 {
+		t.Errorf("Expected no buffered events, got %d", n)
+	}
+}
+
 func TestEventsHandler_ValidEvents(t *testing.T) {
 	app, err := tests.NewTestApp()
 	if err != nil {
@@ -0,0 +1,198 @@
+// Package analytics 提供漏斗与留存分析 handlers。
+package analytics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// funnelsListHandler 列出预定义的漏斗。
+// GET /api/analytics/funnels
+func funnelsListHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		funnels := []Funnel{}
+		if config := analytics.Config(); config != nil && config.Funnels != nil {
+			funnels = config.Funnels
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"funnels": funnels,
+		})
+	}
+}
+
+// funnelHandler 计算漏斗转化。
+// GET  /api/analytics/funnels/{name}?range=30d  计算预定义漏斗
+// POST /api/analytics/funnels?range=30d         计算请求体中的临时漏斗定义
+func funnelHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		var funnel *Funnel
+		if name := e.Request.PathValue("name"); name != "" {
+			if config := analytics.Config(); config != nil {
+				for i := range config.Funnels {
+					if config.Funnels[i].Name == name {
+						funnel = &config.Funnels[i]
+						break
+					}
+				}
+			}
+			if funnel == nil {
+				return e.NotFoundError("Funnel not found", nil)
+			}
+		} else {
+			funnel = &Funnel{}
+			if err := e.BindBody(funnel); err != nil {
+				return e.BadRequestError("Invalid funnel definition", err)
+			}
+			if err := funnel.Validate(); err != nil {
+				return e.BadRequestError("Invalid funnel definition", err)
+			}
+		}
+
+		// 无 Cookie 模式下访客 ID 每天轮换，转化窗口限制在一天内
+		mode := ConsentStandard
+		if config := analytics.Config(); config != nil {
+			mode = config.ConsentMode
+		}
+		window, err := funnel.windowFor(mode)
+		if err != nil {
+			return e.BadRequestError("Invalid funnel definition", err)
+		}
+		scoped := *funnel
+		scoped.Window = window.String()
+		funnel = &scoped
+
+		startDate, endDate := parseDateRange(e.Request.URL.Query().Get("range"))
+
+		// 窗口可能跨越结束日期，查询范围向后延伸一个窗口
+		visitsEnd := endDate
+		if end, err := time.Parse("2006-01-02", endDate); err == nil {
+			visitsEnd = end.Add(window).Format("2006-01-02")
+		}
+
+		visits, err := repo.GetVisits(e.Request.Context(), startDate, visitsEnd, funnel.filters())
+		if err != nil {
+			return e.InternalServerError("Failed to query visits", err)
+		}
+
+		// 只统计在查询范围内进入第一步的访客
+		entered := make(map[string]struct{})
+		for _, visit := range visits {
+			if visit.Date <= endDate && funnel.Steps[0].matches(visit) {
+				entered[visit.SessionID] = struct{}{}
+			}
+		}
+		filtered := visits[:0:0]
+		for _, visit := range visits {
+			if _, ok := entered[visit.SessionID]; ok {
+				filtered = append(filtered, visit)
+			}
+		}
+
+		result, err := ComputeFunnel(funnel, filtered)
+		if err != nil {
+			return e.BadRequestError("Invalid funnel definition", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"name":       funnel.Name,
+			"window":     window.String(),
+			"steps":      result.Steps,
+			"conversion": result.Conversion,
+			"startDate":  startDate,
+			"endDate":    endDate,
+		})
+	}
+}
+
+// retentionHandler 计算留存队列。
+// GET /api/analytics/retention?range=90d&interval=week&periods=8&entryEvent=signup&returnPath=/app/*
+//
+// entryEvent/entryPath 定义进入队列的条件（默认任意访问），
+// returnEvent/returnPath 定义回访的条件（默认任意访问）。
+func retentionHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		// 无 Cookie 模式下访客 ID 每天轮换，任何回访周期都无法关联到队列中的访客
+		if config := analytics.Config(); config != nil && config.ConsentMode == ConsentCookieless {
+			return e.BadRequestError("Retention is not available in cookieless mode, visitors cannot be linked across days", nil)
+		}
+
+		query := e.Request.URL.Query()
+		startDate, endDate := parseDateRange(query.Get("range"))
+
+		interval := RetentionInterval(query.Get("interval"))
+		switch interval {
+		case "":
+			interval = RetentionWeekly
+		case RetentionDaily, RetentionWeekly:
+		default:
+			return e.BadRequestError("Invalid interval, expected day or week", nil)
+		}
+
+		periods := 8
+		if interval == RetentionDaily {
+			periods = 14
+		}
+		if raw := query.Get("periods"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 2 || n > MaxRetentionPeriods {
+				return e.BadRequestError("Invalid periods, expected 2-"+strconv.Itoa(MaxRetentionPeriods), nil)
+			}
+			periods = n
+		}
+
+		var entryFilters, returnFilters []VisitFilter
+		if entry := (VisitFilter{Event: query.Get("entryEvent"), Path: query.Get("entryPath")}); entry != (VisitFilter{}) {
+			entryFilters = []VisitFilter{entry}
+		}
+		if ret := (VisitFilter{Event: query.Get("returnEvent"), Path: query.Get("returnPath")}); ret != (VisitFilter{}) {
+			returnFilters = []VisitFilter{ret}
+		}
+
+		// 首次进入日期在数据库中聚合，查询范围之前已进入的访客不会返回
+		entries, err := repo.GetFirstVisits(e.Request.Context(), startDate, endDate, entryFilters)
+		if err != nil {
+			return e.InternalServerError("Failed to query visits", err)
+		}
+
+		returns, err := repo.GetVisits(e.Request.Context(), startDate, endDate, returnFilters)
+		if err != nil {
+			return e.InternalServerError("Failed to query visits", err)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"interval":  interval,
+			"cohorts":   ComputeRetention(entries, returns, startDate, endDate, interval, periods),
+			"startDate": startDate,
+			"endDate":   endDate,
+		})
+	}
+}
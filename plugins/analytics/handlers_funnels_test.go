@@ -0,0 +1,277 @@
+package analytics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/router"
+)
+
+func TestFunnelAndRetentionHandlers(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	err = Register(app, Config{
+		Mode:    ModeFull,
+		Enabled: true,
+		Funnels: []Funnel{{
+			Name:  "pricing-signup",
+			Steps: []FunnelStep{{Path: "/pricing"}, {Event: "signup"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+
+	// 固定在昨天中午，避免事件跨越日期边界
+	y, m, d := time.Now().AddDate(0, 0, -1).Date()
+	now := time.Date(y, m, d, 12, 0, 0, 0, time.Local)
+	for _, event := range []*Event{
+		{Event: "page_view", Path: "/pricing", SessionID: "s1", Timestamp: now.Add(-time.Hour)},
+		{Event: "signup", Path: "/register", SessionID: "s1", Timestamp: now},
+		{Event: "page_view", Path: "/pricing", SessionID: "s2", Timestamp: now},
+	} {
+		if err := analytics.Track(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analytics.Flush()
+
+	call := func(handler func(*core.RequestEvent) error, method, url, body, name string) (map[string]any, error) {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+		e.Request.Header.Set("Content-Type", "application/json")
+		if name != "" {
+			e.Request.SetPathValue("name", name)
+		}
+		e.Response = rec
+
+		if err := handler(e); err != nil {
+			return nil, err
+		}
+
+		var result map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result, nil
+	}
+
+	checkSteps := func(t *testing.T, result map[string]any) {
+		steps := result["steps"].([]any)
+		if len(steps) != 2 {
+			t.Fatalf("steps = %v", steps)
+		}
+		if steps[0].(map[string]any)["visitors"] != float64(2) || steps[1].(map[string]any)["visitors"] != float64(1) {
+			t.Errorf("steps = %v", steps)
+		}
+		if result["conversion"] != 0.5 {
+			t.Errorf("conversion = %v", result["conversion"])
+		}
+	}
+
+	t.Run("named funnel", func(t *testing.T) {
+		result, err := call(funnelHandler(app), http.MethodGet, "/api/analytics/funnels/pricing-signup?range=7d", "", "pricing-signup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSteps(t, result)
+	})
+
+	t.Run("ad hoc funnel", func(t *testing.T) {
+		body := `{"steps":[{"path":"/pricing"},{"event":"signup"}],"window":"1d"}`
+		result, err := call(funnelHandler(app), http.MethodPost, "/api/analytics/funnels?range=7d", body, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSteps(t, result)
+	})
+
+	t.Run("unknown funnel", func(t *testing.T) {
+		_, err := call(funnelHandler(app), http.MethodGet, "/api/analytics/funnels/missing", "", "missing")
+
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+			t.Fatalf("expected 404 error, got %v", err)
+		}
+	})
+
+	t.Run("invalid funnel", func(t *testing.T) {
+		_, err := call(funnelHandler(app), http.MethodPost, "/api/analytics/funnels", `{"steps":[{"path":"/pricing"}]}`, "")
+
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		result, err := call(retentionHandler(app), http.MethodGet, "/api/analytics/retention?range=7d&interval=day&periods=3", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cohorts := result["cohorts"].([]any)
+		if len(cohorts) != 1 {
+			t.Fatalf("cohorts = %v", cohorts)
+		}
+		if cohort := cohorts[0].(map[string]any); cohort["size"] != float64(2) {
+			t.Errorf("cohort = %v", cohort)
+		}
+	})
+
+	t.Run("retention invalid interval", func(t *testing.T) {
+		_, err := call(retentionHandler(app), http.MethodGet, "/api/analytics/retention?interval=month", "", "")
+
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	})
+}
+
+func TestRegister_InvalidFunnel(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	err = Register(app, Config{
+		Mode:    ModeFull,
+		Enabled: true,
+		Funnels: []Funnel{{Name: "broken", Steps: []FunnelStep{{Path: "/pricing"}}}},
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid funnel")
+	}
+}
+
+func TestFunnelAndRetentionHandlers_Cookieless(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	config := Config{
+		Mode:        ModeFull,
+		Enabled:     true,
+		ConsentMode: ConsentCookieless,
+		Funnels: []Funnel{{
+			Name:  "pricing-signup",
+			Steps: []FunnelStep{{Path: "/pricing"}, {Event: "signup"}},
+		}},
+	}
+	if err := Register(app, config); err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+
+	// 同一访客昨天访问 /pricing、今天注册，盐轮换后得到两个不同的访客 ID
+	ingestion := newTestIngestion(Config{ConsentMode: ConsentCookieless})
+	sessionID := func(ip string) string {
+		event := &Event{IP: ip, UserAgent: "Mozilla/5.0"}
+		if reason := ingestion.Prepare(event); reason != "" {
+			t.Fatalf("Prepare() = %q", reason)
+		}
+		return event.SessionID
+	}
+	yesterday := sessionID("203.0.113.42")
+	ingestion.saltDate = "2000-01-01"
+	today := sessionID("203.0.113.42")
+	other := sessionID("203.0.113.43")
+	if yesterday == today {
+		t.Fatal("expected the visitor id to rotate across days")
+	}
+
+	y, m, d := time.Now().AddDate(0, 0, -1).Date()
+	now := time.Date(y, m, d, 12, 0, 0, 0, time.Local)
+	for _, event := range []*Event{
+		{Event: "page_view", Path: "/pricing", SessionID: yesterday, Timestamp: now.Add(-24 * time.Hour)},
+		{Event: "signup", Path: "/register", SessionID: today, Timestamp: now},
+		{Event: "page_view", Path: "/pricing", SessionID: other, Timestamp: now.Add(-time.Hour)},
+		{Event: "signup", Path: "/register", SessionID: other, Timestamp: now},
+	} {
+		if err := analytics.Track(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analytics.Flush()
+
+	call := func(handler func(*core.RequestEvent) error, method, url, body, name string) (map[string]any, error) {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+		e.Request.Header.Set("Content-Type", "application/json")
+		if name != "" {
+			e.Request.SetPathValue("name", name)
+		}
+		e.Response = rec
+
+		if err := handler(e); err != nil {
+			return nil, err
+		}
+
+		var result map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result, nil
+	}
+
+	expectBadRequest := func(t *testing.T, err error) {
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	}
+
+	t.Run("funnel defaults to a one day window", func(t *testing.T) {
+		result, err := call(funnelHandler(app), http.MethodGet, "/api/analytics/funnels/pricing-signup?range=7d", "", "pricing-signup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result["window"] != "24h0m0s" {
+			t.Errorf("window = %v", result["window"])
+		}
+
+		// 跨天的访客无法关联，只有当天完成的访客计入转化
+		steps := result["steps"].([]any)
+		if steps[0].(map[string]any)["visitors"] != float64(2) || steps[1].(map[string]any)["visitors"] != float64(1) {
+			t.Errorf("steps = %v", steps)
+		}
+	})
+
+	t.Run("multi-day funnel rejected", func(t *testing.T) {
+		body := `{"steps":[{"path":"/pricing"},{"event":"signup"}],"window":"7d"}`
+		_, err := call(funnelHandler(app), http.MethodPost, "/api/analytics/funnels?range=7d", body, "")
+		expectBadRequest(t, err)
+	})
+
+	t.Run("retention rejected", func(t *testing.T) {
+		_, err := call(retentionHandler(app), http.MethodGet, "/api/analytics/retention?range=7d&interval=day&periods=3", "", "")
+		expectBadRequest(t, err)
+	})
+
+	t.Run("multi-day predefined funnel rejected", func(t *testing.T) {
+		config.Funnels[0].Window = "7d"
+		if err := Register(app, config); err == nil {
+			t.Fatal("expected error for a multi-day funnel in cookieless mode")
+		}
+	})
+}
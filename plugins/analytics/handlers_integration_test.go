@@ -120,6 +120,63 @@ func testDBWithData(t *testing.T) (dbx.Builder, *RepositorySQLite) {
 			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
 			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS "_analytics_events" (
+			"id"         TEXT PRIMARY KEY NOT NULL,
+			"date"       TEXT NOT NULL,
+			"event"      TEXT NOT NULL,
+			"prop_key"   TEXT DEFAULT '' NOT NULL,
+			"prop_value" TEXT DEFAULT '' NOT NULL,
+			"count"      INTEGER DEFAULT 0 NOT NULL,
+			"hll"        BLOB,
+			"visitors"   INTEGER DEFAULT 0 NOT NULL,
+			"created"    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_visits" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"sid"      TEXT NOT NULL,
+			"event"    TEXT NOT NULL,
+			"path"     TEXT NOT NULL,
+			"first_ts" INTEGER DEFAULT 0 NOT NULL,
+			"last_ts"  INTEGER DEFAULT 0 NOT NULL,
+			"hits"     INTEGER DEFAULT 0 NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_campaigns" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"source"   TEXT DEFAULT '' NOT NULL,
+			"medium"   TEXT DEFAULT '' NOT NULL,
+			"campaign" TEXT DEFAULT '' NOT NULL,
+			"count"    INTEGER DEFAULT 0 NOT NULL,
+			"hll"      BLOB,
+			"visitors" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_geo" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"country"  TEXT NOT NULL,
+			"region"   TEXT DEFAULT '' NOT NULL,
+			"city"     TEXT DEFAULT '' NOT NULL,
+			"count"    INTEGER DEFAULT 0 NOT NULL,
+			"hll"      BLOB,
+			"visitors" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_session_pages" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"path"     TEXT NOT NULL,
+			"entries"  INTEGER DEFAULT 0 NOT NULL,
+			"exits"    INTEGER DEFAULT 0 NOT NULL,
+			"bounces"  INTEGER DEFAULT 0 NOT NULL,
+			"duration" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
 	`
 	_, err = dbxDB.NewQuery(sql).Execute()
 	if err != nil {
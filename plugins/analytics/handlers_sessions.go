@@ -0,0 +1,186 @@
+// Package analytics 提供营销活动、地理位置、会话与实时分析 handlers。
+package analytics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// campaignsHandler 处理 Top Campaigns 查询请求。
+// GET /api/analytics/campaigns?range=7d&limit=10
+func campaignsHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		startDate, endDate := parseDateRange(e.Request.URL.Query().Get("range"))
+		limit := parseLimit(e.Request.URL.Query().Get("limit"), 10)
+
+		campaigns, err := repo.GetTopCampaigns(e.Request.Context(), startDate, endDate, limit)
+		if err != nil {
+			return e.InternalServerError("Failed to query campaigns", err)
+		}
+
+		result := make([]map[string]any, 0, len(campaigns))
+		for _, campaign := range campaigns {
+			result = append(result, map[string]any{
+				"source":   campaign.Source,
+				"medium":   campaign.Medium,
+				"campaign": campaign.Campaign,
+				"count":    campaign.Count,
+				"visitors": campaign.Visitors,
+			})
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"campaigns": result,
+			"startDate": startDate,
+			"endDate":   endDate,
+		})
+	}
+}
+
+// countriesHandler 处理地理位置排行查询请求。
+// GET /api/analytics/countries?range=7d&limit=10            按国家汇总
+// GET /api/analytics/countries?range=7d&country=US&limit=10 指定国家按地区/城市汇总
+func countriesHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		query := e.Request.URL.Query()
+		startDate, endDate := parseDateRange(query.Get("range"))
+		limit := parseLimit(query.Get("limit"), 10)
+		country := strings.ToUpper(strings.TrimSpace(query.Get("country")))
+
+		stats, err := repo.GetTopGeo(e.Request.Context(), startDate, endDate, country, limit)
+		if err != nil {
+			return e.InternalServerError("Failed to query countries", err)
+		}
+
+		result := make([]map[string]any, 0, len(stats))
+		for _, stat := range stats {
+			item := map[string]any{
+				"country":  stat.Country,
+				"count":    stat.Count,
+				"visitors": stat.Visitors,
+			}
+			if country != "" {
+				item["region"] = stat.Region
+				item["city"] = stat.City
+			}
+			result = append(result, item)
+		}
+
+		response := map[string]any{
+			"startDate": startDate,
+			"endDate":   endDate,
+		}
+		if country != "" {
+			response["country"] = country
+			response["locations"] = result
+		} else {
+			response["countries"] = result
+		}
+
+		return e.JSON(http.StatusOK, response)
+	}
+}
+
+// sessionPagesHandler 处理入口页/退出页查询请求。
+// GET /api/analytics/entry-pages?range=7d&limit=10（orderBy = "entries"）
+// GET /api/analytics/exit-pages?range=7d&limit=10（orderBy = "exits"）
+func sessionPagesHandler(app core.App, orderBy string) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		startDate, endDate := parseDateRange(e.Request.URL.Query().Get("range"))
+		limit := parseLimit(e.Request.URL.Query().Get("limit"), 10)
+
+		pages, err := repo.GetTopSessionPages(e.Request.Context(), startDate, endDate, orderBy, limit)
+		if err != nil {
+			return e.InternalServerError("Failed to query session pages", err)
+		}
+
+		result := make([]map[string]any, 0, len(pages))
+		for _, page := range pages {
+			item := map[string]any{"path": page.Path}
+			if orderBy == "exits" {
+				item["exits"] = page.Exits
+			} else {
+				// 跳出率与平均时长均以该页面为入口的会话计算
+				item["entries"] = page.Entries
+				item["bounceRate"] = bounceRate(page)
+				item["avgDuration"] = avgSessionDuration(page)
+			}
+			result = append(result, item)
+		}
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"pages":     result,
+			"startDate": startDate,
+			"endDate":   endDate,
+		})
+	}
+}
+
+// bounceRate 返回跳出率（0-1），无会话时返回 0
+func bounceRate(stat *SessionPageStat) float64 {
+	if stat == nil || stat.Entries == 0 {
+		return 0
+	}
+	return float64(stat.Bounces) / float64(stat.Entries)
+}
+
+// avgSessionDuration 返回平均会话时长（毫秒），无会话时返回 0
+func avgSessionDuration(stat *SessionPageStat) int64 {
+	if stat == nil || stat.Entries == 0 {
+		return 0
+	}
+	return stat.Duration / stat.Entries
+}
+
+// realtimeHandler 返回最近 30 分钟的实时分析快照。
+// GET /api/analytics/realtime
+//
+// 同样的快照每隔 DefaultRealtimeInterval 推送给订阅了 RealtimeTopic 的 superuser 实时连接。
+func realtimeHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		provider, ok := analytics.(RealtimeProvider)
+		if !ok || provider.Realtime() == nil {
+			return e.NotFoundError("Realtime analytics is not available", nil)
+		}
+
+		return e.JSON(http.StatusOK, provider.Realtime().Snapshot(time.Now()))
+	}
+}
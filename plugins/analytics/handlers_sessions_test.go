@@ -0,0 +1,181 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+func TestCampaignAndSessionPageHandlers(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+
+	call := func(handler func(*core.RequestEvent) error, method, url, body string) map[string]any {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+		e.Request.Header.Set("Content-Type", "application/json")
+		e.Request.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0")
+		e.Response = rec
+
+		if err := handler(e); err != nil {
+			t.Fatal(err)
+		}
+
+		var result map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// 会话已超时，刷新时即结束
+	start := time.Now().Add(-2 * time.Hour).UnixMilli()
+
+	// UTM 参数分别来自 query、path 和 SDK 的 url 字段
+	body := fmt.Sprintf(`{"events":[
+		{"event":"page_view","sid":"s1","ts":%[1]d,"path":"/landing","query":"utm_source=newsletter&utm_campaign=launch"},
+		{"event":"page_view","sid":"s1","ts":%[2]d,"path":"/pricing"},
+		{"event":"page_view","sid":"s2","ts":%[1]d,"path":"/landing?utm_source=newsletter&utm_campaign=launch"},
+		{"event":"page_view","sid":"s3","ts":%[1]d,"path":"/blog","url":"https://example.com/blog?utm_source=twitter"}
+	]}`, start, start+60000)
+	if result := call(eventsHandler(app), "POST", "/api/analytics/events", body); result["accepted"] != float64(4) {
+		t.Fatalf("accepted = %v", result["accepted"])
+	}
+	analytics.Flush()
+
+	t.Run("campaigns", func(t *testing.T) {
+		result := call(campaignsHandler(app), "GET", "/api/analytics/campaigns?range=7d", "")
+		campaigns := result["campaigns"].([]any)
+		if len(campaigns) != 2 {
+			t.Fatalf("campaigns = %v", campaigns)
+		}
+		top := campaigns[0].(map[string]any)
+		if top["source"] != "newsletter" || top["campaign"] != "launch" || top["count"] != float64(2) || top["visitors"] != float64(2) {
+			t.Errorf("top campaign = %v", top)
+		}
+	})
+
+	t.Run("entry pages", func(t *testing.T) {
+		result := call(sessionPagesHandler(app, "entries"), "GET", "/api/analytics/entry-pages?range=7d", "")
+		pages := result["pages"].([]any)
+		if len(pages) != 2 {
+			t.Fatalf("pages = %v", pages)
+		}
+		landing := pages[0].(map[string]any)
+		if landing["path"] != "/landing" || landing["entries"] != float64(2) || landing["bounceRate"] != 0.5 || landing["avgDuration"] != float64(30000) {
+			t.Errorf("landing = %v", landing)
+		}
+	})
+
+	t.Run("exit pages", func(t *testing.T) {
+		result := call(sessionPagesHandler(app, "exits"), "GET", "/api/analytics/exit-pages?range=7d", "")
+		pages := result["pages"].([]any)
+		if len(pages) != 3 {
+			t.Fatalf("pages = %v", pages)
+		}
+		for _, page := range pages {
+			if page.(map[string]any)["exits"] != float64(1) {
+				t.Errorf("page = %v", page)
+			}
+		}
+	})
+
+	t.Run("stats summary", func(t *testing.T) {
+		result := call(statsHandler(app), "GET", "/api/analytics/stats?range=7d", "")
+		summary := result["summary"].(map[string]any)
+		if summary["sessions"] != float64(3) || summary["bounceRate"] != 2.0/3.0 || summary["avgDur"] != float64(20000) {
+			t.Errorf("summary = %v", summary)
+		}
+	})
+}
+
+func TestCountriesHandler(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	// httptest 请求的客户端地址为 192.0.2.1
+	networks := append(testGeoNetworks(), testMMDBNetwork{
+		cidr: "192.0.2.0/24",
+		record: map[string]any{
+			"country":      map[string]any{"iso_code": "FR"},
+			"subdivisions": []any{map[string]any{"names": map[string]any{"en": "Île-de-France"}}},
+			"city":         map[string]any{"names": map[string]any{"en": "Paris"}},
+		},
+	})
+	path := filepath.Join(t.TempDir(), "geo.mmdb")
+	if err := os.WriteFile(path, buildTestMMDB(t, 28, networks), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true, GeoIPPath: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(handler func(*core.RequestEvent) error, method, url, body string) map[string]any {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(method, url, strings.NewReader(body))
+		e.Request.Header.Set("Content-Type", "application/json")
+		e.Request.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0")
+		e.Response = rec
+
+		if err := handler(e); err != nil {
+			t.Fatal(err)
+		}
+
+		var result map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	body := fmt.Sprintf(`{"events":[
+		{"event":"page_view","sid":"s1","ts":%[1]d,"path":"/"},
+		{"event":"page_view","sid":"s2","ts":%[1]d,"path":"/pricing"}
+	]}`, time.Now().UnixMilli())
+	if result := call(eventsHandler(app), "POST", "/api/analytics/events", body); result["accepted"] != float64(2) {
+		t.Fatalf("accepted = %v", result["accepted"])
+	}
+	GetAnalytics(app).Flush()
+
+	result := call(countriesHandler(app), "GET", "/api/analytics/countries?range=7d", "")
+	countries := result["countries"].([]any)
+	if len(countries) != 1 {
+		t.Fatalf("countries = %v", countries)
+	}
+	if top := countries[0].(map[string]any); top["country"] != "FR" || top["count"] != float64(2) || top["visitors"] != float64(2) {
+		t.Errorf("top country = %v", top)
+	}
+
+	result = call(countriesHandler(app), "GET", "/api/analytics/countries?range=7d&country=fr", "")
+	locations := result["locations"].([]any)
+	if result["country"] != "FR" || len(locations) != 1 {
+		t.Fatalf("result = %v", result)
+	}
+	if location := locations[0].(map[string]any); location["region"] != "Île-de-France" || location["city"] != "Paris" {
+		t.Errorf("location = %v", location)
+	}
+}
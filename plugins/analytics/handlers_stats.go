@@ -2,8 +2,12 @@
 package analytics
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core"
@@ -75,12 +79,19 @@ func statsHandler(app core.App) func(*core.RequestEvent) error {
 			}
 		}
 
+		// 跳出率与平均停留时长来自已结束的会话
+		sessions, err := repo.GetSessionTotals(e.Request.Context(), startDate, endDate)
+		if err != nil {
+			return e.InternalServerError("Failed to query sessions", err)
+		}
+
 		return e.JSON(http.StatusOK, map[string]any{
 			"summary": map[string]any{
 				"totalPV":    totalPV,
 				"totalUV":    totalUV,
-				"bounceRate": 0, // TODO: 计算跳出率
-				"avgDur":     0, // TODO: 计算平均停留时长
+				"sessions":   sessions.Entries,
+				"bounceRate": bounceRate(sessions),
+				"avgDur":     avgSessionDuration(sessions),
 			},
 			"daily":     dailyData,
 			"startDate": startDate,
@@ -221,6 +232,173 @@ func devicesHandler(app core.App) func(*core.RequestEvent) error {
 	}
 }
 
+// customEventsHandler 处理自定义事件统计查询请求。
+// GET /api/analytics/events?range=30d&event=click_buy&groupBy=props.plan&limit=10
+//
+// 不指定 event 时返回各事件的次数与访客数；指定 event 时返回该事件的汇总与每日趋势，
+// 同时指定 groupBy=props.<key> 时返回按属性值的拆分（属性需在 Config.EventProps 中配置）。
+func customEventsHandler(app core.App) func(*core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		analytics := GetAnalytics(app)
+		if analytics == nil || !analytics.IsEnabled() {
+			return e.NotFoundError("Analytics is disabled", nil)
+		}
+
+		repo := analytics.Repository()
+		if repo == nil {
+			return e.InternalServerError("Analytics repository not initialized", nil)
+		}
+
+		query := e.Request.URL.Query()
+		startDate, endDate := parseDateRange(query.Get("range"))
+		limit := parseLimit(query.Get("limit"), 10)
+		event := query.Get("event")
+		groupBy := query.Get("groupBy")
+
+		var propKey string
+		if groupBy != "" {
+			var ok bool
+			propKey, ok = strings.CutPrefix(groupBy, "props.")
+			if !ok || propKey == "" {
+				return e.BadRequestError("Invalid groupBy, expected props.<key>", nil)
+			}
+			if event == "" {
+				return e.BadRequestError("The event parameter is required when groupBy is set", nil)
+			}
+			if config := analytics.Config(); config == nil || !slices.Contains(config.EventProps, propKey) {
+				return e.BadRequestError("Property "+propKey+" is not configured for breakdown", nil)
+			}
+		}
+
+		stats, err := repo.GetEventStats(e.Request.Context(), startDate, endDate, event, propKey)
+		if err != nil {
+			return e.InternalServerError("Failed to query events", err)
+		}
+
+		// 不指定事件时列出所有事件
+		if event == "" {
+			buckets := groupEventStats(stats, func(stat *EventStat) string { return stat.Event })
+			if len(buckets) > limit {
+				buckets = buckets[:limit]
+			}
+
+			events := make([]map[string]any, 0, len(buckets))
+			for _, bucket := range buckets {
+				events = append(events, map[string]any{
+					"event":    bucket.key,
+					"count":    bucket.count,
+					"visitors": bucket.visitors,
+				})
+			}
+
+			return e.JSON(http.StatusOK, map[string]any{
+				"events":    events,
+				"startDate": startDate,
+				"endDate":   endDate,
+			})
+		}
+
+		summary := groupEventStats(stats, func(*EventStat) string { return "" })
+		total := eventBucket{}
+		if len(summary) > 0 {
+			total = summary[0]
+		}
+
+		days := groupEventStats(stats, func(stat *EventStat) string { return stat.Date })
+		sort.Slice(days, func(i, j int) bool { return days[i].key < days[j].key })
+		daily := make([]map[string]any, 0, len(days))
+		for _, day := range days {
+			daily = append(daily, map[string]any{
+				"date":     day.key,
+				"count":    day.count,
+				"visitors": day.visitors,
+			})
+		}
+
+		result := map[string]any{
+			"event": event,
+			"summary": map[string]any{
+				"count":    total.count,
+				"visitors": total.visitors,
+			},
+			"daily":     daily,
+			"startDate": startDate,
+			"endDate":   endDate,
+		}
+
+		if propKey != "" {
+			buckets := groupEventStats(stats, func(stat *EventStat) string { return stat.PropValue })
+			if len(buckets) > limit {
+				buckets = buckets[:limit]
+			}
+
+			breakdown := make([]map[string]any, 0, len(buckets))
+			for _, bucket := range buckets {
+				breakdown = append(breakdown, map[string]any{
+					"value":    bucket.key,
+					"count":    bucket.count,
+					"visitors": bucket.visitors,
+				})
+			}
+
+			result["groupBy"] = groupBy
+			result["breakdown"] = breakdown
+		}
+
+		return e.JSON(http.StatusOK, result)
+	}
+}
+
+// eventBucket 自定义事件统计的分组汇总
+type eventBucket struct {
+	key      string
+	count    int64
+	visitors int64
+}
+
+// groupEventStats 按 keyFn 分组汇总事件统计，访客数使用 HLL 合并去重，结果按次数倒序。
+func groupEventStats(stats []*EventStat, keyFn func(*EventStat) string) []eventBucket {
+	type group struct {
+		eventBucket
+		sketches [][]byte
+	}
+
+	groups := make(map[string]*group)
+	for _, stat := range stats {
+		key := keyFn(stat)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{eventBucket: eventBucket{key: key}}
+			groups[key] = g
+		}
+		g.count += stat.Count
+		g.visitors += stat.Visitors
+		if len(stat.HLL) > 0 {
+			g.sketches = append(g.sketches, stat.HLL)
+		}
+	}
+
+	buckets := make([]eventBucket, 0, len(groups))
+	for _, g := range groups {
+		// 使用 HLL 合并计算准确的跨天 UV，失败时降级为简单累加
+		if len(g.sketches) > 0 {
+			if _, visitors, err := MergeHLLBytes(g.sketches...); err == nil {
+				g.visitors = int64(visitors)
+			}
+		}
+		buckets = append(buckets, g.eventBucket)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].count != buckets[j].count {
+			return buckets[i].count > buckets[j].count
+		}
+		return buckets[i].key < buckets[j].key
+	})
+
+	return buckets
+}
+
 // rawLogsHandler 列出可下载的原始日志日期。
 // GET /api/analytics/raw-logs
 func rawLogsHandler(app core.App) func(*core.RequestEvent) error {
@@ -230,14 +408,25 @@ func rawLogsHandler(app core.App) func(*core.RequestEvent) error {
 			return e.NotFoundError("Analytics is disabled", nil)
 		}
 
-		// TODO: 实现列出可下载日期的逻辑
+		provider, ok := analytics.(RawArchiveProvider)
+		if !ok || provider.RawArchive() == nil {
+			return e.JSON(http.StatusOK, map[string]any{
+				"dates": []RawLogDate{},
+			})
+		}
+
+		dates, err := provider.RawArchive().ListDates(e.Request.Context())
+		if err != nil {
+			return e.InternalServerError("Failed to list raw logs", err)
+		}
+
 		return e.JSON(http.StatusOK, map[string]any{
-			"dates": []string{},
+			"dates": dates,
 		})
 	}
 }
 
-// rawLogDownloadHandler 下载指定日期的原始日志。
+// rawLogDownloadHandler 下载指定日期的原始日志（gzip 压缩的 NDJSON）。
 // GET /api/analytics/raw-logs/{date}
 func rawLogDownloadHandler(app core.App) func(*core.RequestEvent) error {
 	return func(e *core.RequestEvent) error {
@@ -246,14 +435,51 @@ func rawLogDownloadHandler(app core.App) func(*core.RequestEvent) error {
 			return e.NotFoundError("Analytics is disabled", nil)
 		}
 
-		// date := e.Request.PathValue("date")
+		date := e.Request.PathValue("date")
+		if !isValidRawLogDate(date) {
+			return e.BadRequestError("Invalid date, expected YYYY-MM-DD", nil)
+		}
 
-		// TODO: 实现下载逻辑
-		// - SQLite 模式：返回本地 Parquet 文件
-		// - PostgreSQL 模式：生成 S3 Presigned URL 并重定向
+		provider, ok := analytics.(RawArchiveProvider)
+		if !ok || provider.RawArchive() == nil {
+			return e.NotFoundError("Raw logs not found", nil)
+		}
+
+		// 流式输出，响应头在写入第一个字节时才设置，
+		// 这样日期不存在时仍可返回 JSON 错误响应
+		w := &rawLogResponseWriter{e: e, filename: "analytics-raw-" + date + rawLogExt}
+		if err := provider.RawArchive().WriteTo(e.Request.Context(), date, w); err != nil {
+			if w.started {
+				// 已开始输出，无法再返回错误响应
+				return err
+			}
+			if errors.Is(err, ErrRawLogNotFound) {
+				return e.NotFoundError("Raw logs not found", nil)
+			}
+			return e.InternalServerError("Failed to read raw logs", err)
+		}
+
+		return nil
+	}
+}
+
+// rawLogResponseWriter 在首次写入时设置下载响应头
+type rawLogResponseWriter struct {
+	e        *core.RequestEvent
+	filename string
+	started  bool
+}
 
-		return e.NotFoundError("Raw logs download not implemented yet", nil)
+// Write 实现 io.Writer
+func (w *rawLogResponseWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		header := w.e.Response.Header()
+		header.Set("Content-Type", "application/gzip")
+		header.Set("Content-Disposition", "attachment; filename="+w.filename)
+		w.e.Response.WriteHeader(http.StatusOK)
 	}
+	return w.e.Response.Write(p)
 }
 
 // configHandler 返回分析配置。
@@ -273,6 +499,10 @@ func configHandler(app core.App) func(*core.RequestEvent) error {
 			"retention":     config.Retention,
 			"flushInterval": config.FlushInterval,
 			"hasS3":         config.S3Bucket != "",
+			"eventProps":    config.EventProps,
+			"consentMode":   config.ConsentMode,
+			"ipMode":        config.IPMode,
+			"hasGeoIP":      config.GeoIPPath != "",
 		})
 	}
 }
@@ -1,7 +1,16 @@
 package analytics
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/router"
 )
 
 func TestParseDateRange(t *testing.T) {
@@ -80,3 +89,104 @@ func TestStatsHandler_NoRepository(t *testing.T) {
 	// statsHandler 在 repository 为 nil 时应返回 500 错误
 	// 这个测试需要模拟 repository 为 nil 的情况
 }
+
+func TestCustomEventsHandler(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true, EventProps: []string{"plan"}}); err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+
+	now := time.Now()
+	events := []*Event{
+		{Event: "click_buy", Path: "/pricing", SessionID: "s1", Timestamp: now, Props: map[string]any{"plan": "pro"}},
+		{Event: "click_buy", Path: "/pricing", SessionID: "s1", Timestamp: now, Props: map[string]any{"plan": "pro"}},
+		{Event: "click_buy", Path: "/pricing", SessionID: "s2", Timestamp: now, Props: map[string]any{"plan": "free"}},
+		{Event: "page_view", Path: "/pricing", SessionID: "s3", Timestamp: now},
+	}
+	for _, event := range events {
+		if err := analytics.Track(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	analytics.Flush()
+
+	call := func(query string) (map[string]any, error) {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(http.MethodGet, "/api/analytics/events?"+query, nil)
+		e.Response = rec
+
+		if err := customEventsHandler(app)(e); err != nil {
+			return nil, err
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body, nil
+	}
+
+	t.Run("list events", func(t *testing.T) {
+		body, err := call("range=30d")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list := body["events"].([]any)
+		if len(list) != 2 {
+			t.Fatalf("events = %v", list)
+		}
+		first := list[0].(map[string]any)
+		if first["event"] != "click_buy" || first["count"] != float64(3) || first["visitors"] != float64(2) {
+			t.Errorf("first = %v", first)
+		}
+	})
+
+	t.Run("group by property", func(t *testing.T) {
+		body, err := call("range=30d&event=click_buy&groupBy=props.plan")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		summary := body["summary"].(map[string]any)
+		if summary["count"] != float64(3) || summary["visitors"] != float64(2) {
+			t.Errorf("summary = %v", summary)
+		}
+
+		breakdown := body["breakdown"].([]any)
+		if len(breakdown) != 2 {
+			t.Fatalf("breakdown = %v", breakdown)
+		}
+		pro := breakdown[0].(map[string]any)
+		if pro["value"] != "pro" || pro["count"] != float64(2) || pro["visitors"] != float64(1) {
+			t.Errorf("pro = %v", pro)
+		}
+
+		if daily := body["daily"].([]any); len(daily) != 1 {
+			t.Errorf("daily = %v", daily)
+		}
+	})
+
+	t.Run("invalid groupBy", func(t *testing.T) {
+		for _, query := range []string{
+			"event=click_buy&groupBy=plan",
+			"event=click_buy&groupBy=props.country",
+			"groupBy=props.plan",
+		} {
+			_, err := call(query)
+
+			var apiErr *router.ApiError
+			if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+				t.Errorf("%s: expected 400 error, got %v", query, err)
+			}
+		}
+	})
+}
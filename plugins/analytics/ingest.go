@@ -0,0 +1,207 @@
+package analytics
+
+import (
+	"crypto/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSessionEventsPerMinute 单个会话每分钟允许的最大事件数，
+// 超过时该会话被判定为机器人，当天的后续事件全部丢弃
+const DefaultMaxSessionEventsPerMinute = 60
+
+// 事件被丢弃的原因
+const (
+	DropReasonBot         = "bot"
+	DropReasonDoNotTrack  = "dnt"
+	DropReasonRateLimited = "rate"
+)
+
+// IngestionProvider 可选接口，由提供采集过滤的 Analytics 实现
+type IngestionProvider interface {
+	Ingestion() *Ingestion
+}
+
+// sessionRate 会话在当前分钟内的事件数
+type sessionRate struct {
+	minute int64
+	count  int
+}
+
+// Ingestion 负责事件进入缓冲区之前的过滤与匿名化：
+// - 机器人识别：User-Agent 特征 + 会话事件频率
+// - 尊重 DNT / Global Privacy Control
+// - 无 Cookie 模式下生成每日轮换的会话 ID
+// - 可选的离线 GeoIP 地理位置解析
+// - IP 截断/哈希，默认不保留原始 User-Agent
+type Ingestion struct {
+	config *Config
+
+	// geoIP 地理位置数据库（可选）
+	geoIP *GeoIP
+
+	mu sync.Mutex
+
+	// salt 每日轮换的随机盐，只保存在内存中，轮换后旧哈希无法还原
+	salt     []byte
+	saltDate string
+
+	// rates 会话当前分钟的事件数
+	rates map[string]*sessionRate
+
+	// flagged 被判定为机器人的会话及判定日期
+	flagged map[string]string
+
+	lastPrune time.Time
+}
+
+// NewIngestion 创建采集过滤器。
+func NewIngestion(config *Config) *Ingestion {
+	if config == nil {
+		defaults := applyDefaults(Config{})
+		config = &defaults
+	}
+
+	return &Ingestion{
+		config:  config,
+		rates:   make(map[string]*sessionRate),
+		flagged: make(map[string]string),
+	}
+}
+
+// SetGeoIP 设置地理位置数据库，设置后事件会按原始 IP 解析国家/地区/城市。
+func (g *Ingestion) SetGeoIP(geoIP *GeoIP) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.geoIP = geoIP
+}
+
+// Check 检查请求级别的过滤规则（DNT/GPC、User-Agent 机器人特征），
+// 返回丢弃原因，允许采集时返回空字符串。
+func (g *Ingestion) Check(r *http.Request) string {
+	if !g.config.IgnoreDNT && HasDoNotTrack(r) {
+		return DropReasonDoNotTrack
+	}
+
+	if IsBotUserAgent(r.Header.Get("User-Agent")) {
+		return DropReasonBot
+	}
+
+	return ""
+}
+
+// Prepare 在事件进入缓冲区之前应用会话与匿名化规则。
+// 会话被判定为机器人时返回丢弃原因。
+func (g *Ingestion) Prepare(event *Event) string {
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	salt := g.dailySalt(now)
+
+	if g.config.ConsentMode == ConsentCookieless {
+		// 无 Cookie 模式：会话 ID 由服务端生成，客户端 ID 被忽略
+		event.SessionID = saltedHash(salt, "sid", event.IP, event.UserAgent)
+		event.UserID = ""
+	}
+
+	if event.SessionID != "" && !g.allow(event.SessionID, now) {
+		return DropReasonRateLimited
+	}
+
+	// 地理位置必须在匿名化之前使用原始 IP 解析
+	if g.geoIP != nil && event.IP != "" {
+		if info, ok := g.geoIP.Lookup(event.IP); ok {
+			event.Country = info.Country
+			event.Region = info.Region
+			event.City = info.City
+		}
+
+		// 已有地理位置维度，开启匿名化时不再保留 IP
+		if g.config.IPMode != IPFull {
+			event.IP = ""
+		}
+	}
+
+	switch g.config.IPMode {
+	case IPFull:
+	case IPHash:
+		if event.IP != "" {
+			event.IP = saltedHash(salt, "ip", event.IP)
+		}
+	default:
+		event.IP = TruncateIP(event.IP)
+	}
+
+	if !g.config.StoreUserAgent {
+		event.UserAgent = ""
+	}
+
+	return ""
+}
+
+// allow 返回会话是否未超过事件频率限制（调用方需持有锁）。
+func (g *Ingestion) allow(sid string, now time.Time) bool {
+	if now.Sub(g.lastPrune) >= time.Minute {
+		g.prune(now)
+	}
+
+	today := now.UTC().Format("2006-01-02")
+	if date, ok := g.flagged[sid]; ok && date == today {
+		return false
+	}
+
+	limit := g.config.MaxSessionEventsPerMinute
+	if limit <= 0 {
+		return true
+	}
+
+	minute := now.Unix() / 60
+	rate, ok := g.rates[sid]
+	if !ok || rate.minute != minute {
+		rate = &sessionRate{minute: minute}
+		g.rates[sid] = rate
+	}
+	rate.count++
+
+	if rate.count > limit {
+		g.flagged[sid] = today
+		delete(g.rates, sid)
+		return false
+	}
+
+	return true
+}
+
+// prune 清理过期的频率计数与机器人标记（调用方需持有锁）。
+func (g *Ingestion) prune(now time.Time) {
+	g.lastPrune = now
+
+	minute := now.Unix() / 60
+	for sid, rate := range g.rates {
+		if rate.minute != minute {
+			delete(g.rates, sid)
+		}
+	}
+
+	today := now.UTC().Format("2006-01-02")
+	for sid, date := range g.flagged {
+		if date != today {
+			delete(g.flagged, sid)
+		}
+	}
+}
+
+// dailySalt 返回当天（UTC）的随机盐，跨天时重新生成（调用方需持有锁）。
+func (g *Ingestion) dailySalt(now time.Time) []byte {
+	today := now.UTC().Format("2006-01-02")
+	if g.saltDate != today {
+		salt := make([]byte, 32)
+		_, _ = rand.Read(salt)
+		g.salt = salt
+		g.saltDate = today
+	}
+	return g.salt
+}
@@ -0,0 +1,185 @@
+package analytics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestIngestion(config Config) *Ingestion {
+	config = applyDefaults(config)
+	return NewIngestion(&config)
+}
+
+func TestIngestionCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		headers map[string]string
+		want    string
+	}{
+		{"browser", Config{}, map[string]string{"User-Agent": "Mozilla/5.0 Chrome/120.0"}, ""},
+		{"crawler", Config{}, map[string]string{"User-Agent": "Googlebot/2.1"}, DropReasonBot},
+		{"headless", Config{}, map[string]string{"User-Agent": "Mozilla/5.0 HeadlessChrome/120.0"}, DropReasonBot},
+		{"dnt", Config{}, map[string]string{"DNT": "1"}, DropReasonDoNotTrack},
+		{"gpc", Config{}, map[string]string{"Sec-GPC": "1"}, DropReasonDoNotTrack},
+		{"dnt ignored", Config{IgnoreDNT: true}, map[string]string{"DNT": "1"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/analytics/events", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := newTestIngestion(tt.config).Check(r); got != tt.want {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngestionPrepare_Anonymization(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		event := &Event{SessionID: "s1", IP: "203.0.113.42", UserAgent: "Mozilla/5.0"}
+		if reason := newTestIngestion(Config{}).Prepare(event); reason != "" {
+			t.Fatalf("Prepare() = %q", reason)
+		}
+		if event.IP != "203.0.113.0" || event.UserAgent != "" || event.SessionID != "s1" {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		ingestion := newTestIngestion(Config{IPMode: IPHash, StoreUserAgent: true})
+		a := &Event{SessionID: "s1", IP: "203.0.113.42", UserAgent: "Mozilla/5.0"}
+		b := &Event{SessionID: "s2", IP: "203.0.113.42"}
+		ingestion.Prepare(a)
+		ingestion.Prepare(b)
+		if len(a.IP) != 16 || a.IP == "203.0.113.42" || a.IP != b.IP {
+			t.Fatalf("Expected stable hashed IPs, got %q and %q", a.IP, b.IP)
+		}
+		if a.UserAgent != "Mozilla/5.0" {
+			t.Fatalf("Expected the user agent to be kept, got %q", a.UserAgent)
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		event := &Event{SessionID: "s1", IP: "203.0.113.42"}
+		newTestIngestion(Config{IPMode: IPFull}).Prepare(event)
+		if event.IP != "203.0.113.42" {
+			t.Fatalf("Expected full IP, got %q", event.IP)
+		}
+	})
+}
+
+func TestIngestionPrepare_Cookieless(t *testing.T) {
+	ingestion := newTestIngestion(Config{ConsentMode: ConsentCookieless})
+
+	a := &Event{SessionID: "client-a", UserID: "u1", IP: "203.0.113.42", UserAgent: "Mozilla/5.0"}
+	b := &Event{SessionID: "client-b", IP: "203.0.113.42", UserAgent: "Mozilla/5.0"}
+	c := &Event{IP: "203.0.113.43", UserAgent: "Mozilla/5.0"}
+	for _, event := range []*Event{a, b, c} {
+		if reason := ingestion.Prepare(event); reason != "" {
+			t.Fatalf("Prepare() = %q", reason)
+		}
+	}
+
+	if a.SessionID == "client-a" || len(a.SessionID) != 16 || a.UserID != "" {
+		t.Fatalf("Expected a derived session ID and no user ID, got %+v", a)
+	}
+	if a.SessionID != b.SessionID {
+		t.Fatalf("Expected the same visitor to get the same session ID, got %q and %q", a.SessionID, b.SessionID)
+	}
+	if a.SessionID == c.SessionID {
+		t.Fatal("Expected different visitors to get different session IDs")
+	}
+
+	// 盐每天轮换，同一访客次日的会话 ID 不同
+	ingestion.saltDate = "2000-01-01"
+	d := &Event{IP: "203.0.113.42", UserAgent: "Mozilla/5.0"}
+	ingestion.Prepare(d)
+	if d.SessionID == a.SessionID {
+		t.Fatal("Expected the session ID to change after the salt rotation")
+	}
+}
+
+func TestIngestionPrepare_RateLimit(t *testing.T) {
+	ingestion := newTestIngestion(Config{MaxSessionEventsPerMinute: 3})
+
+	for i := 0; i < 3; i++ {
+		if reason := ingestion.Prepare(&Event{SessionID: "s1"}); reason != "" {
+			t.Fatalf("Event %d: Prepare() = %q", i, reason)
+		}
+	}
+	if reason := ingestion.Prepare(&Event{SessionID: "s1"}); reason != DropReasonRateLimited {
+		t.Fatalf("Expected the session to be rate limited, got %q", reason)
+	}
+
+	// 被判定为机器人后，下一分钟仍然丢弃
+	ingestion.mu.Lock()
+	delete(ingestion.rates, "s1")
+	ingestion.mu.Unlock()
+	if reason := ingestion.Prepare(&Event{SessionID: "s1"}); reason != DropReasonRateLimited {
+		t.Fatalf("Expected the flagged session to stay dropped, got %q", reason)
+	}
+
+	// 其它会话不受影响
+	if reason := ingestion.Prepare(&Event{SessionID: "s2"}); reason != "" {
+		t.Fatalf("Prepare() = %q", reason)
+	}
+
+	// 标记只在当天有效
+	ingestion.mu.Lock()
+	ingestion.prune(time.Now().Add(24 * time.Hour))
+	_, flagged := ingestion.flagged["s1"]
+	ingestion.mu.Unlock()
+	if flagged {
+		t.Fatal("Expected the bot flag to expire on the next day")
+	}
+
+	unlimited := newTestIngestion(Config{MaxSessionEventsPerMinute: -1})
+	for i := 0; i < 100; i++ {
+		if reason := unlimited.Prepare(&Event{SessionID: "s1"}); reason != "" {
+			t.Fatalf("Expected no limit, got %q", reason)
+		}
+	}
+}
+
+func TestIngestionPrepare_GeoIP(t *testing.T) {
+	geoIP, err := NewGeoIP(buildTestMMDB(t, 24, testGeoNetworks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("anonymized", func(t *testing.T) {
+		ingestion := newTestIngestion(Config{})
+		ingestion.SetGeoIP(geoIP)
+
+		event := &Event{SessionID: "s1", IP: "8.8.8.8"}
+		ingestion.Prepare(event)
+		if event.Country != "US" || event.Region != "California" || event.City != "Mountain View" {
+			t.Fatalf("Unexpected geo fields %+v", event)
+		}
+		if event.IP != "" {
+			t.Fatalf("Expected the IP to be discarded, got %q", event.IP)
+		}
+
+		unknown := &Event{SessionID: "s2", IP: "203.0.113.42"}
+		ingestion.Prepare(unknown)
+		if unknown.Country != "" || unknown.IP != "" {
+			t.Fatalf("Unexpected event %+v", unknown)
+		}
+	})
+
+	t.Run("full", func(t *testing.T) {
+		ingestion := newTestIngestion(Config{IPMode: IPFull})
+		ingestion.SetGeoIP(geoIP)
+
+		event := &Event{SessionID: "s1", IP: "8.8.8.8"}
+		ingestion.Prepare(event)
+		if event.Country != "US" || event.IP != "8.8.8.8" {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ConsentMode 定义访客标识方式
+type ConsentMode string
+
+const (
+	// ConsentStandard 使用 SDK 提供的会话 ID（持久化在 localStorage 中，默认）
+	ConsentStandard ConsentMode = "standard"
+
+	// ConsentCookieless 无 Cookie 模式：忽略客户端会话 ID，
+	// 由服务端根据每日轮换的随机盐、IP 和 User-Agent 哈希生成，跨天无法关联同一访客
+	ConsentCookieless ConsentMode = "cookieless"
+)
+
+// IsValid 检查模式是否有效
+func (m ConsentMode) IsValid() bool {
+	switch m {
+	case ConsentStandard, ConsentCookieless:
+		return true
+	default:
+		return false
+	}
+}
+
+// IPMode 定义 IP 地址在进入缓冲区前的处理方式
+type IPMode string
+
+const (
+	// IPTruncate 截断 IP：IPv4 保留前 3 段（/24），IPv6 保留前 48 位（默认）
+	IPTruncate IPMode = "truncate"
+
+	// IPHash 使用每日轮换的随机盐对 IP 做哈希
+	IPHash IPMode = "hash"
+
+	// IPFull 保留完整 IP
+	IPFull IPMode = "full"
+)
+
+// IsValid 检查模式是否有效
+func (m IPMode) IsValid() bool {
+	switch m {
+	case IPTruncate, IPHash, IPFull:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasDoNotTrack 返回请求是否声明了 Do Not Track 或 Global Privacy Control。
+func HasDoNotTrack(r *http.Request) bool {
+	return r.Header.Get("DNT") == "1" || r.Header.Get("Sec-GPC") == "1"
+}
+
+// TruncateIP 截断 IP 地址：IPv4 将最后一段置 0，IPv6 只保留前 48 位。
+// 无法解析的输入返回空字符串。
+func TruncateIP(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// saltedHash 返回加盐哈希的前 16 个十六进制字符
+func saltedHash(salt []byte, parts ...string) string {
+	h := sha256.New()
+	h.Write(salt)
+	for _, part := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,73 @@
+package analytics
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTruncateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.168.1.123", "192.168.1.0"},
+		{" 10.0.0.1 ", "10.0.0.0"},
+		{"2001:db8:85a3:8d3:1319:8a2e:370:7348", "2001:db8:85a3::"},
+		{"::ffff:192.168.1.9", "192.168.1.0"},
+		{"invalid", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := TruncateIP(tt.ip); got != tt.want {
+				t.Errorf("TruncateIP(%q) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasDoNotTrack(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"none", nil, false},
+		{"dnt", map[string]string{"DNT": "1"}, true},
+		{"dnt disabled", map[string]string{"DNT": "0"}, false},
+		{"gpc", map[string]string{"Sec-GPC": "1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/analytics/events", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := HasDoNotTrack(r); got != tt.want {
+				t.Errorf("HasDoNotTrack() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivacyModesIsValid(t *testing.T) {
+	for _, mode := range []ConsentMode{ConsentStandard, ConsentCookieless} {
+		if !mode.IsValid() {
+			t.Errorf("ConsentMode(%q).IsValid() = false", mode)
+		}
+	}
+	if ConsentMode("opt-in").IsValid() {
+		t.Error(`ConsentMode("opt-in").IsValid() = true`)
+	}
+
+	for _, mode := range []IPMode{IPTruncate, IPHash, IPFull} {
+		if !mode.IsValid() {
+			t.Errorf("IPMode(%q).IsValid() = false", mode)
+		}
+	}
+	if IPMode("").IsValid() {
+		t.Error(`IPMode("").IsValid() = true`)
+	}
+}
@@ -0,0 +1,285 @@
+package analytics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// RawLogsPrefix 原始事件归档在文件系统中的目录前缀
+// 文件布局：_analytics_raw/{date}/{unix_nano}_{rand}.ndjson.gz
+const RawLogsPrefix = "_analytics_raw/"
+
+// rawLogExt 原始事件归档文件扩展名
+const rawLogExt = ".ndjson.gz"
+
+// ErrRawLogNotFound 指定日期没有原始事件归档
+var ErrRawLogNotFound = errors.New("raw log not found")
+
+// RawLogDate 某一天的原始事件归档信息
+type RawLogDate struct {
+	Date  string    `json:"date"`
+	Files int       `json:"files"`
+	Size  int64     `json:"size"` // 压缩后的字节数
+	Mod   time.Time `json:"modified"`
+}
+
+// RawArchiveProvider 可选接口，由提供原始事件归档的 Analytics 实现
+type RawArchiveProvider interface {
+	RawArchive() *RawArchive
+}
+
+// RawArchive 按天归档原始事件（gzip 压缩的 NDJSON，每行一个 Event）
+//
+// 每次刷新写入一个独立的 gzip 分片；下载时按顺序拼接同一天的所有分片，
+// 多个 gzip member 首尾相接仍是合法的 gzip 流，可直接用 `zcat` / `gunzip` 解压。
+//
+// 存储位置：
+//   - 配置了 Config.S3Bucket 时写入该 S3 存储桶
+//   - 否则使用 app 文件系统（本地 pb_data/storage 或设置中启用的 S3）
+type RawArchive struct {
+	app    core.App
+	config *Config
+}
+
+// NewRawArchive 创建原始事件归档
+func NewRawArchive(app core.App, config *Config) *RawArchive {
+	return &RawArchive{app: app, config: config}
+}
+
+// newFilesystem 创建归档使用的文件系统，调用方负责 Close
+func (a *RawArchive) newFilesystem(ctx context.Context) (*filesystem.System, error) {
+	var fsys *filesystem.System
+	var err error
+
+	if a.config != nil && a.config.S3Bucket != "" {
+		fsys, err = filesystem.NewS3(
+			a.config.S3Bucket,
+			a.config.S3Region,
+			a.config.S3Endpoint,
+			a.config.S3AccessKey,
+			a.config.S3SecretKey,
+			a.config.S3Endpoint != "", // 自定义端点（MinIO 等）通常需要 path style
+		)
+	} else {
+		fsys, err = a.app.NewFilesystem()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fsys.SetContext(ctx)
+
+	return fsys, nil
+}
+
+// Write 将事件按日期分组写入归档，每个日期生成一个新的分片
+func (a *RawArchive) Write(ctx context.Context, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	byDate := make(map[string][]*Event)
+	for _, event := range events {
+		ts := event.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		date := ts.Format("2006-01-02")
+		byDate[date] = append(byDate[date], event)
+	}
+
+	fsys, err := a.newFilesystem(ctx)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	for date, dateEvents := range byDate {
+		content, err := encodeRawEvents(dateEvents)
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s%s/%d_%s%s", RawLogsPrefix, date, time.Now().UnixNano(), security.RandomString(6), rawLogExt)
+		if err := fsys.Upload(content, key); err != nil {
+			return fmt.Errorf("failed to upload raw events for %s: %w", date, err)
+		}
+	}
+
+	return nil
+}
+
+// encodeRawEvents 将事件编码为 gzip 压缩的 NDJSON
+func encodeRawEvents(events []*Event) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			gz.Close()
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ListDates 列出所有有归档的日期（按日期倒序）
+func (a *RawArchive) ListDates(ctx context.Context) ([]RawLogDate, error) {
+	fsys, err := a.newFilesystem(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+
+	objects, err := fsys.List(RawLogsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*RawLogDate)
+	for _, obj := range objects {
+		date, ok := rawLogDateFromKey(obj.Key)
+		if !ok {
+			continue
+		}
+
+		item, exists := byDate[date]
+		if !exists {
+			item = &RawLogDate{Date: date}
+			byDate[date] = item
+		}
+		item.Files++
+		item.Size += obj.Size
+		if obj.ModTime.After(item.Mod) {
+			item.Mod = obj.ModTime
+		}
+	}
+
+	result := make([]RawLogDate, 0, len(byDate))
+	for _, item := range byDate {
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date > result[j].Date
+	})
+
+	return result, nil
+}
+
+// WriteTo 将指定日期的所有分片按写入顺序拼接输出到 w
+// 日期不存在时返回 ErrRawLogNotFound
+func (a *RawArchive) WriteTo(ctx context.Context, date string, w io.Writer) error {
+	if !isValidRawLogDate(date) {
+		return ErrRawLogNotFound
+	}
+
+	fsys, err := a.newFilesystem(ctx)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	objects, err := fsys.List(RawLogsPrefix + date + "/")
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		if strings.HasSuffix(obj.Key, rawLogExt) {
+			keys = append(keys, obj.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return ErrRawLogNotFound
+	}
+
+	// 分片文件名以 unix nano 开头，按 key 排序即按写入时间排序
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := copyRawLogFile(fsys, key, w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyRawLogFile 复制单个分片
+func copyRawLogFile(fsys *filesystem.System, key string, w io.Writer) error {
+	r, err := fsys.GetReader(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// DeleteBefore 删除指定日期（不含）之前的归档，返回删除的日期数
+func (a *RawArchive) DeleteBefore(ctx context.Context, date string) (int, error) {
+	dates, err := a.ListDates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	fsys, err := a.newFilesystem(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer fsys.Close()
+
+	deleted := 0
+	var errs []error
+	for _, item := range dates {
+		if item.Date >= date {
+			continue
+		}
+		if delErrs := fsys.DeletePrefix(RawLogsPrefix + item.Date + "/"); len(delErrs) > 0 {
+			errs = append(errs, delErrs...)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// rawLogDateFromKey 从分片 key 中解析日期
+func rawLogDateFromKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, RawLogsPrefix) || !strings.HasSuffix(key, rawLogExt) {
+		return "", false
+	}
+
+	date, _, found := strings.Cut(strings.TrimPrefix(key, RawLogsPrefix), "/")
+	if !found || !isValidRawLogDate(date) {
+		return "", false
+	}
+
+	return date, true
+}
+
+// isValidRawLogDate 校验日期格式为 YYYY-MM-DD（同时防止路径穿越）
+func isValidRawLogDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
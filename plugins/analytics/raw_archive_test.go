@@ -0,0 +1,263 @@
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/router"
+)
+
+// decodeRawLog 解压并解析下载的原始日志
+func decodeRawLog(t *testing.T, data []byte) []*Event {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gz.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		event := &Event{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			t.Fatalf("invalid ndjson line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestRawArchive_WriteListDownload(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	config := DefaultConfig()
+	archive := NewRawArchive(app, &config)
+	ctx := context.Background()
+
+	day1 := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	// 两次写入同一天，生成两个分片
+	if err := archive.Write(ctx, []*Event{
+		{Event: "page_view", Path: "/a", SessionID: "s1", Timestamp: day1},
+		{Event: "page_view", Path: "/b", SessionID: "s1", Timestamp: day2},
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := archive.Write(ctx, []*Event{
+		{Event: "click", Path: "/c", SessionID: "s2", Timestamp: day1},
+	}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	dates, err := archive.ListDates(ctx)
+	if err != nil {
+		t.Fatalf("ListDates() error = %v", err)
+	}
+	if len(dates) != 2 || dates[0].Date != "2026-01-11" || dates[1].Date != "2026-01-10" {
+		t.Fatalf("ListDates() = %+v", dates)
+	}
+	if dates[1].Files != 2 || dates[1].Size <= 0 {
+		t.Errorf("unexpected 2026-01-10 entry %+v", dates[1])
+	}
+
+	// 多个分片拼接后仍是合法的 gzip 流，并保持写入顺序
+	var buf bytes.Buffer
+	if err := archive.WriteTo(ctx, "2026-01-10", &buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	events := decodeRawLog(t, buf.Bytes())
+	if len(events) != 2 || events[0].Path != "/a" || events[1].Path != "/c" {
+		t.Errorf("downloaded events = %+v", events)
+	}
+
+	// 不存在的日期和非法日期
+	if err := archive.WriteTo(ctx, "2026-01-01", &buf); !errors.Is(err, ErrRawLogNotFound) {
+		t.Errorf("WriteTo(missing) error = %v, want ErrRawLogNotFound", err)
+	}
+	if err := archive.WriteTo(ctx, "../2026-01-10", &buf); !errors.Is(err, ErrRawLogNotFound) {
+		t.Errorf("WriteTo(invalid) error = %v, want ErrRawLogNotFound", err)
+	}
+
+	// 保留期清理
+	deleted, err := archive.DeleteBefore(ctx, "2026-01-11")
+	if err != nil {
+		t.Fatalf("DeleteBefore() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteBefore() deleted = %d, want 1", deleted)
+	}
+	dates, _ = archive.ListDates(ctx)
+	if len(dates) != 1 || dates[0].Date != "2026-01-11" {
+		t.Errorf("ListDates() after prune = %+v", dates)
+	}
+}
+
+func TestRawLogDateFromKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		date string
+		ok   bool
+	}{
+		{"_analytics_raw/2026-01-10/1_abc.ndjson.gz", "2026-01-10", true},
+		{"_analytics_raw/2026-01-10/1_abc.json", "", false},
+		{"_analytics_raw/latest/1_abc.ndjson.gz", "", false},
+		{"other/2026-01-10/1_abc.ndjson.gz", "", false},
+	}
+
+	for _, tt := range tests {
+		date, ok := rawLogDateFromKey(tt.key)
+		if date != tt.date || ok != tt.ok {
+			t.Errorf("rawLogDateFromKey(%q) = (%q, %v), want (%q, %v)", tt.key, date, ok, tt.date, tt.ok)
+		}
+	}
+}
+
+func TestFlusherFlushRawEvents_Archive(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	config := DefaultConfig()
+	buffer := NewBuffer(1024 * 1024)
+	flusher := NewFlusher(app, buffer, newMockRepository(), &config)
+	flusher.SetRawArchive(NewRawArchive(app, &config))
+
+	now := time.Now()
+	buffer.Push(&Event{Event: "page_view", Path: "/home", SessionID: "s1", Timestamp: now})
+
+	// 未达到容量和时间间隔时不写入归档
+	if flusher.shouldFlushRaw() {
+		t.Fatal("shouldFlushRaw() = true, want false")
+	}
+
+	// Stop 时强制写入
+	ctx := context.Background()
+	if err := flusher.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := flusher.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if buffer.Len() != 0 {
+		t.Errorf("buffer.Len() = %d, want 0", buffer.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := flusher.archive.WriteTo(ctx, now.Format("2006-01-02"), &buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if events := decodeRawLog(t, buf.Bytes()); len(events) != 1 || events[0].Path != "/home" {
+		t.Errorf("archived events = %+v", events)
+	}
+}
+
+func TestRawLogHandlers(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+
+	date := time.Now().Format("2006-01-02")
+	if err := analytics.Track(&Event{Event: "page_view", Path: "/pricing", SessionID: "s1", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := analytics.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := analytics.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	newEvent := func(path string) (*core.RequestEvent, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest(http.MethodGet, path, nil)
+		e.Response = rec
+		return e, rec
+	}
+
+	t.Run("list", func(t *testing.T) {
+		e, rec := newEvent("/api/analytics/raw-logs")
+		if err := rawLogsHandler(app)(e); err != nil {
+			t.Fatal(err)
+		}
+
+		var body struct {
+			Dates []RawLogDate `json:"dates"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Dates) != 1 || body.Dates[0].Date != date {
+			t.Errorf("dates = %+v", body.Dates)
+		}
+	})
+
+	t.Run("download", func(t *testing.T) {
+		e, rec := newEvent("/api/analytics/raw-logs/" + date)
+		e.Request.SetPathValue("date", date)
+		if err := rawLogDownloadHandler(app)(e); err != nil {
+			t.Fatal(err)
+		}
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/gzip" {
+			t.Fatalf("status = %d, content-type = %q", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if events := decodeRawLog(t, rec.Body.Bytes()); len(events) != 1 || events[0].Path != "/pricing" {
+			t.Errorf("downloaded events = %+v", events)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		e, _ := newEvent("/api/analytics/raw-logs/2000-01-01")
+		e.Request.SetPathValue("date", "2000-01-01")
+		err := rawLogDownloadHandler(app)(e)
+
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+			t.Fatalf("expected 404 error, got %v", err)
+		}
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		e, _ := newEvent("/api/analytics/raw-logs/latest")
+		e.Request.SetPathValue("date", "latest")
+		err := rawLogDownloadHandler(app)(e)
+
+		var apiErr *router.ApiError
+		if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package analytics
+
+import (
+	"container/list"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/routine"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+)
+
+// RealtimeTopic 实时分析的订阅主题，只推送给已认证的 superuser 客户端
+const RealtimeTopic = "analytics/realtime"
+
+// DefaultRealtimeInterval 实时分析快照的推送间隔
+const DefaultRealtimeInterval = 5 * time.Second
+
+const (
+	// realtimeWindow 滚动窗口长度，更早的数据会被丢弃
+	realtimeWindow = 30 * time.Minute
+
+	// realtimeActiveWindow 判定"当前在线"的时间窗口
+	realtimeActiveWindow = 5 * time.Minute
+
+	// realtimeTopPagesLimit 当前热门页面的最大数量
+	realtimeTopPagesLimit = 10
+)
+
+// RealtimeProvider 可选接口，由提供实时分析的 Analytics 实现
+type RealtimeProvider interface {
+	Realtime() *Realtime
+}
+
+// RealtimePage 当前热门页面
+type RealtimePage struct {
+	Path     string `json:"path"`
+	Visitors int64  `json:"visitors"` // 最近一次页面浏览停留在该页面的在线会话数
+}
+
+// RealtimeMinute 每分钟的事件数
+type RealtimeMinute struct {
+	Minute    string `json:"minute"` // 分钟开始时间（RFC3339）
+	Events    int64  `json:"events"`
+	PageViews int64  `json:"pageViews"`
+}
+
+// RealtimeSnapshot 实时分析快照
+type RealtimeSnapshot struct {
+	Timestamp string `json:"timestamp"`
+
+	// Active5m/Active30m 最近 5/30 分钟内有事件的会话数
+	Active5m  int64 `json:"active5m"`
+	Active30m int64 `json:"active30m"`
+
+	// TopPages 最近 5 分钟内在线会话当前所在的页面
+	TopPages []RealtimePage `json:"topPages"`
+
+	// PerMinute 最近 30 分钟每分钟的事件数（从早到晚，包含没有事件的分钟）
+	PerMinute []RealtimeMinute `json:"perMinute"`
+}
+
+// realtimeSession 滚动窗口内的会话
+type realtimeSession struct {
+	sid      string
+	elem     *list.Element // 在 sessionOrder 中的位置
+	lastSeen time.Time
+	path     string // 最近一次页面浏览的路径
+}
+
+// realtimeCounter 一分钟内的事件计数
+type realtimeCounter struct {
+	events    int64
+	pageViews int64
+}
+
+// Realtime 维护最近 30 分钟的内存滚动窗口（不落库），
+// 由 Buffer 在接收事件时更新，用于"当前在线"实时看板。
+type Realtime struct {
+	mu sync.Mutex
+
+	// sessions 按 SessionID 记录最近活跃时间和所在页面
+	sessions map[string]*realtimeSession
+
+	// sessionOrder 按最近活动排序的会话，队首为最久未活动的会话；
+	// 会话数达到 maxSessions 时丢弃队首会话
+	sessionOrder *list.List
+	maxSessions  int
+
+	// minutes 按 Unix 分钟记录事件数
+	minutes map[int64]*realtimeCounter
+
+	lastPrune time.Time
+}
+
+// NewRealtime 创建一个新的实时分析窗口。
+func NewRealtime() *Realtime {
+	return &Realtime{
+		sessions:     make(map[string]*realtimeSession),
+		sessionOrder: list.New(),
+		maxSessions:  DefaultMaxSessions,
+		minutes:      make(map[int64]*realtimeCounter),
+	}
+}
+
+// SetMaxSessions 设置滚动窗口内记录的会话数上限。
+func (r *Realtime) SetMaxSessions(n int) {
+	if n <= 0 {
+		n = DefaultMaxSessions
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxSessions = n
+}
+
+// Add 将事件计入滚动窗口。
+// 早于窗口的事件被忽略，晚于当前时间的事件按当前时间计入。
+func (r *Realtime) Add(event *Event) {
+	if event == nil {
+		return
+	}
+
+	now := time.Now()
+	ts := event.Timestamp
+	if ts.IsZero() || ts.After(now) {
+		ts = now
+	}
+	if now.Sub(ts) > realtimeWindow {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	isPageView := event.Event == pageViewEvent
+
+	minute := ts.Unix() / 60
+	counter, ok := r.minutes[minute]
+	if !ok {
+		counter = &realtimeCounter{}
+		r.minutes[minute] = counter
+	}
+	counter.events++
+	if isPageView {
+		counter.pageViews++
+	}
+
+	if event.SessionID != "" {
+		session, ok := r.sessions[event.SessionID]
+		if ok {
+			r.sessionOrder.MoveToBack(session.elem)
+		} else {
+			for len(r.sessions) >= r.maxSessions && r.sessionOrder.Len() > 0 {
+				r.removeSession(r.sessionOrder.Front().Value.(*realtimeSession))
+			}
+			session = &realtimeSession{sid: event.SessionID}
+			session.elem = r.sessionOrder.PushBack(session)
+			r.sessions[event.SessionID] = session
+		}
+		// 乱序到达的旧事件不覆盖当前页面
+		if !ts.Before(session.lastSeen) {
+			session.lastSeen = ts
+			if isPageView || session.path == "" {
+				session.path = event.Path
+			}
+		}
+	}
+
+	// 每分钟最多清理一次过期数据
+	if now.Sub(r.lastPrune) >= time.Minute {
+		r.prune(now)
+	}
+}
+
+// Snapshot 返回指定时间点的实时分析快照。
+func (r *Realtime) Snapshot(now time.Time) *RealtimeSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune(now)
+
+	snapshot := &RealtimeSnapshot{
+		Timestamp: now.UTC().Format(time.RFC3339),
+		TopPages:  []RealtimePage{},
+		PerMinute: make([]RealtimeMinute, 0, int(realtimeWindow/time.Minute)),
+	}
+
+	activeSince := now.Add(-realtimeActiveWindow)
+	pages := make(map[string]int64)
+	for _, session := range r.sessions {
+		snapshot.Active30m++
+		if session.lastSeen.Before(activeSince) {
+			continue
+		}
+		snapshot.Active5m++
+		pages[session.path]++
+	}
+
+	for path, visitors := range pages {
+		snapshot.TopPages = append(snapshot.TopPages, RealtimePage{Path: path, Visitors: visitors})
+	}
+	sort.Slice(snapshot.TopPages, func(i, j int) bool {
+		if snapshot.TopPages[i].Visitors != snapshot.TopPages[j].Visitors {
+			return snapshot.TopPages[i].Visitors > snapshot.TopPages[j].Visitors
+		}
+		return snapshot.TopPages[i].Path < snapshot.TopPages[j].Path
+	})
+	if len(snapshot.TopPages) > realtimeTopPagesLimit {
+		snapshot.TopPages = snapshot.TopPages[:realtimeTopPagesLimit]
+	}
+
+	current := now.Unix() / 60
+	for minute := current - int64(realtimeWindow/time.Minute) + 1; minute <= current; minute++ {
+		item := RealtimeMinute{Minute: time.Unix(minute*60, 0).UTC().Format(time.RFC3339)}
+		if counter, ok := r.minutes[minute]; ok {
+			item.Events = counter.events
+			item.PageViews = counter.pageViews
+		}
+		snapshot.PerMinute = append(snapshot.PerMinute, item)
+	}
+
+	return snapshot
+}
+
+// prune 丢弃早于滚动窗口的数据（调用方需持有锁）。
+func (r *Realtime) prune(now time.Time) {
+	r.lastPrune = now
+
+	cutoff := now.Add(-realtimeWindow)
+	for _, session := range r.sessions {
+		if session.lastSeen.Before(cutoff) {
+			r.removeSession(session)
+		}
+	}
+
+	oldestMinute := now.Unix()/60 - int64(realtimeWindow/time.Minute) + 1
+	for minute := range r.minutes {
+		if minute < oldestMinute {
+			delete(r.minutes, minute)
+		}
+	}
+}
+
+// removeSession 从滚动窗口中移除会话（调用方需持有锁）。
+func (r *Realtime) removeSession(session *realtimeSession) {
+	delete(r.sessions, session.sid)
+	r.sessionOrder.Remove(session.elem)
+}
+
+// broadcastRealtime 将实时分析快照推送给订阅了 RealtimeTopic 的 superuser 客户端。
+// 没有订阅者时不会生成快照。
+func broadcastRealtime(app core.App, realtime *Realtime) error {
+	var recipients []subscriptions.Client
+	for _, client := range app.SubscriptionsBroker().Clients() {
+		if client.IsDiscarded() || !client.HasSubscription(RealtimeTopic) {
+			continue
+		}
+		auth, _ := client.Get(apis.RealtimeClientAuthKey).(*core.Record)
+		if auth == nil || !auth.IsSuperuser() {
+			continue
+		}
+		recipients = append(recipients, client)
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(realtime.Snapshot(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	msg := subscriptions.Message{
+		Name: RealtimeTopic,
+		Data: data,
+	}
+
+	for _, client := range recipients {
+		routine.FireAndForget(func() {
+			client.Send(msg)
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/subscriptions"
+)
+
+func TestRealtimeSnapshot(t *testing.T) {
+	realtime := NewRealtime()
+	now := time.Now()
+
+	for _, event := range []*Event{
+		{Event: "page_view", Path: "/pricing", SessionID: "s1", Timestamp: now.Add(-2 * time.Minute)},
+		{Event: "click", Path: "/pricing", SessionID: "s1", Timestamp: now.Add(-time.Minute)},
+		{Event: "page_view", Path: "/pricing", SessionID: "s2", Timestamp: now.Add(-time.Minute)},
+		{Event: "page_view", Path: "/blog", SessionID: "s3", Timestamp: now},
+		// 乱序到达的旧事件不覆盖当前页面
+		{Event: "page_view", Path: "/home", SessionID: "s3", Timestamp: now.Add(-3 * time.Minute)},
+		// 5 分钟前活跃的会话只计入 active30m
+		{Event: "page_view", Path: "/docs", SessionID: "s4", Timestamp: now.Add(-10 * time.Minute)},
+		// 窗口之外的事件被忽略
+		{Event: "page_view", Path: "/old", SessionID: "s5", Timestamp: now.Add(-time.Hour)},
+	} {
+		realtime.Add(event)
+	}
+
+	snapshot := realtime.Snapshot(now)
+
+	if snapshot.Active5m != 3 || snapshot.Active30m != 4 {
+		t.Fatalf("Expected 3/4 active sessions, got %d/%d", snapshot.Active5m, snapshot.Active30m)
+	}
+
+	expectedPages := []RealtimePage{{"/pricing", 2}, {"/blog", 1}}
+	if len(snapshot.TopPages) != len(expectedPages) {
+		t.Fatalf("Expected top pages %v, got %v", expectedPages, snapshot.TopPages)
+	}
+	for i, page := range expectedPages {
+		if snapshot.TopPages[i] != page {
+			t.Fatalf("Expected top pages %v, got %v", expectedPages, snapshot.TopPages)
+		}
+	}
+
+	if len(snapshot.PerMinute) != 30 {
+		t.Fatalf("Expected 30 minutes, got %d", len(snapshot.PerMinute))
+	}
+	var events, pageViews int64
+	for _, minute := range snapshot.PerMinute {
+		events += minute.Events
+		pageViews += minute.PageViews
+	}
+	if events != 6 || pageViews != 5 {
+		t.Fatalf("Expected 6 events and 5 page views, got %d and %d", events, pageViews)
+	}
+	if last := snapshot.PerMinute[29]; last.Minute != now.Truncate(time.Minute).UTC().Format(time.RFC3339) || last.Events != 1 {
+		t.Fatalf("Unexpected last minute %+v", last)
+	}
+
+	// 窗口滚动后数据被清理
+	snapshot = realtime.Snapshot(now.Add(time.Hour))
+	if snapshot.Active30m != 0 || len(snapshot.TopPages) != 0 {
+		t.Fatalf("Expected empty snapshot, got %+v", snapshot)
+	}
+}
+
+func TestRealtimeSetMaxSessions(t *testing.T) {
+	realtime := NewRealtime()
+	realtime.SetMaxSessions(2)
+	now := time.Now()
+
+	realtime.Add(&Event{Event: "page_view", Path: "/a", SessionID: "s1", Timestamp: now.Add(-3 * time.Minute)})
+	realtime.Add(&Event{Event: "page_view", Path: "/b", SessionID: "s2", Timestamp: now.Add(-2 * time.Minute)})
+	// s1 再次活动后，s2 成为最久未活动的会话
+	realtime.Add(&Event{Event: "page_view", Path: "/a", SessionID: "s1", Timestamp: now.Add(-time.Minute)})
+	realtime.Add(&Event{Event: "page_view", Path: "/c", SessionID: "s3", Timestamp: now})
+
+	snapshot := realtime.Snapshot(now)
+	if snapshot.Active30m != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", snapshot.Active30m)
+	}
+	for _, page := range snapshot.TopPages {
+		if page.Path == "/b" {
+			t.Fatalf("Expected s2 to be dropped, got %v", snapshot.TopPages)
+		}
+	}
+}
+
+func TestRealtimeHandlerAndBroadcast(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	analytics := GetAnalytics(app)
+	if err := analytics.Track(&Event{Event: "page_view", Path: "/launch", SessionID: "s1", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("handler", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		e := &core.RequestEvent{}
+		e.App = app
+		e.Request = httptest.NewRequest("GET", "/api/analytics/realtime", nil)
+		e.Response = rec
+
+		if err := realtimeHandler(app)(e); err != nil {
+			t.Fatal(err)
+		}
+
+		var snapshot RealtimeSnapshot
+		if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Active5m != 1 || len(snapshot.TopPages) != 1 || snapshot.TopPages[0].Path != "/launch" {
+			t.Fatalf("Unexpected snapshot %+v", snapshot)
+		}
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		superuser, err := app.FindAuthRecordByEmail(core.CollectionNameSuperusers, "test@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		user, err := app.FindAuthRecordByEmail("users", "test@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		superuserClient := subscriptions.NewDefaultClient()
+		superuserClient.Set(apis.RealtimeClientAuthKey, superuser)
+		superuserClient.Subscribe(RealtimeTopic)
+
+		userClient := subscriptions.NewDefaultClient()
+		userClient.Set(apis.RealtimeClientAuthKey, user)
+		userClient.Subscribe(RealtimeTopic)
+
+		guestClient := subscriptions.NewDefaultClient()
+		guestClient.Subscribe(RealtimeTopic)
+
+		for _, client := range []*subscriptions.DefaultClient{superuserClient, userClient, guestClient} {
+			app.SubscriptionsBroker().Register(client)
+		}
+
+		if err := broadcastRealtime(app, analytics.(RealtimeProvider).Realtime()); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case msg := <-superuserClient.Channel():
+			var snapshot RealtimeSnapshot
+			if msg.Name != RealtimeTopic || json.Unmarshal(msg.Data, &snapshot) != nil || snapshot.Active5m != 1 {
+				t.Fatalf("Unexpected message %s: %s", msg.Name, msg.Data)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the superuser client to receive a snapshot")
+		}
+
+		for _, client := range []*subscriptions.DefaultClient{userClient, guestClient} {
+			select {
+			case msg := <-client.Channel():
+				t.Fatalf("Expected no message for non-superuser client, got %s", msg.Name)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+}
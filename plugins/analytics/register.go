@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -32,6 +33,32 @@ func Register(app core.App, config Config) error {
 	// 应用默认值
 	config = applyDefaults(config)
 
+	if !config.ConsentMode.IsValid() {
+		return fmt.Errorf("analytics: invalid consent mode %q", config.ConsentMode)
+	}
+	if !config.IPMode.IsValid() {
+		return fmt.Errorf("analytics: invalid IP mode %q", config.IPMode)
+	}
+
+	// 校验预定义漏斗
+	names := make(map[string]struct{}, len(config.Funnels))
+	for i := range config.Funnels {
+		funnel := &config.Funnels[i]
+		if funnel.Name == "" {
+			return fmt.Errorf("analytics: funnels[%d]: name is required", i)
+		}
+		if _, ok := names[funnel.Name]; ok {
+			return fmt.Errorf("analytics: duplicated funnel name %q", funnel.Name)
+		}
+		names[funnel.Name] = struct{}{}
+		if err := funnel.Validate(); err != nil {
+			return fmt.Errorf("analytics: funnel %q: %w", funnel.Name, err)
+		}
+		if _, err := funnel.windowFor(config.ConsentMode); err != nil {
+			return fmt.Errorf("analytics: funnel %q: %w", funnel.Name, err)
+		}
+	}
+
 	var analytics Analytics
 
 	// 如果模式为 Off 或未启用，注册 NoopAnalytics
@@ -39,7 +66,24 @@ func Register(app core.App, config Config) error {
 		analytics = NewNoopAnalytics()
 	} else {
 		// 创建真实的 Analytics 实例
-		analytics = newAnalyticsImpl(app, &config)
+		impl := newAnalyticsImpl(app, &config)
+		analytics = impl
+
+		if config.GeoIPPath != "" {
+			geoIP, err := OpenGeoIP(config.GeoIPPath)
+			if err != nil {
+				return fmt.Errorf("analytics: failed to open GeoIP database: %w", err)
+			}
+			impl.ingestion.SetGeoIP(geoIP)
+		}
+
+		// 服务启动时开始定时刷新
+		app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+			if err := impl.Start(context.Background()); err != nil {
+				return err
+			}
+			return e.Next()
+		})
 
 		// 注册数据清理 Cron 任务
 		registerPruneCronJob(app, &config)
@@ -105,6 +149,22 @@ func registerPruneCronJob(app core.App, config *Config) {
 				"retention_days", config.Retention,
 			)
 		}
+
+		// 原始事件归档使用相同的保留期
+		if provider, ok := analytics.(RawArchiveProvider); ok && provider.RawArchive() != nil {
+			deleted, err := provider.RawArchive().DeleteBefore(ctx, dateStr)
+			if err != nil {
+				app.Logger().Warn("Analytics raw logs prune failed",
+					"error", err,
+					"cutoff_date", dateStr,
+				)
+			} else if deleted > 0 {
+				app.Logger().Info("Analytics raw logs pruned",
+					"cutoff_date", dateStr,
+					"deleted_dates", deleted,
+				)
+			}
+		}
 	})
 }
 
@@ -124,15 +184,53 @@ type analyticsImpl struct {
 	app    core.App
 	config *Config
 
+	buffer  *Buffer
+	repo    Repository
+	archive *RawArchive
+	flusher *Flusher
+
+	ingestion *Ingestion
+
+	realtime     *Realtime
+	realtimeStop chan struct{}
+
 	mu      sync.RWMutex
 	running bool
 }
 
 // newAnalyticsImpl 创建 Analytics 实现
 func newAnalyticsImpl(app core.App, config *Config) *analyticsImpl {
+	// 统计表在 SQLite 模式下位于辅助数据库，PostgreSQL 模式下位于主数据库
+	var repo Repository
+	if app.IsPostgres() {
+		repo = NewRepositoryPostgres(app.DB())
+	} else {
+		repo = NewRepositorySQLite(app.AuxDB())
+	}
+
+	buffer := NewBuffer(int(config.MaxRawSize))
+	buffer.SetEventProps(config.EventProps)
+	buffer.SetSessionTimeout(config.SessionTimeout)
+	buffer.SetMaxSessions(config.MaxSessions)
+	archive := NewRawArchive(app, config)
+	realtime := NewRealtime()
+	realtime.SetMaxSessions(config.MaxSessions)
+	buffer.SetRealtime(realtime)
+
+	flusher := NewFlusher(app, buffer, repo, config)
+	flusher.SetRawArchive(archive)
+
 	return &analyticsImpl{
-		app:    app,
-		config: config,
+		app:     app,
+		config:  config,
+		buffer:  buffer,
+		repo:    repo,
+		archive: archive,
+		flusher: flusher,
+
+		realtime: realtime,
+
+		ingestion: NewIngestion(config),
 	}
 }
 
@@ -140,8 +238,7 @@ func (a *analyticsImpl) Track(event *Event) error {
 	if !a.IsEnabled() {
 		return ErrDisabled
 	}
-	// TODO: 实现完整的 Track 逻辑
-	return nil
+	return a.buffer.Push(event)
 }
 
 func (a *analyticsImpl) Push(event *Event) error {
@@ -157,6 +254,14 @@ func (a *analyticsImpl) IsEnabled() bool {
 func (a *analyticsImpl) Start(ctx context.Context) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	if a.running {
+		return nil
+	}
+	if err := a.flusher.Start(ctx); err != nil {
+		return err
+	}
+	a.realtimeStop = make(chan struct{})
+	go a.publishRealtime(a.realtimeStop)
 	a.running = true
 	return nil
 }
@@ -164,12 +269,35 @@ func (a *analyticsImpl) Start(ctx context.Context) error {
 func (a *analyticsImpl) Stop(ctx context.Context) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	if !a.running {
+		return nil
+	}
 	a.running = false
-	return nil
+	close(a.realtimeStop)
+	return a.flusher.Stop(ctx)
+}
+
+// publishRealtime 定时向 RealtimeTopic 的订阅者推送实时分析快照，直到 stop 被关闭。
+func (a *analyticsImpl) publishRealtime(stop chan struct{}) {
+	ticker := time.NewTicker(DefaultRealtimeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := broadcastRealtime(a.app, a.realtime); err != nil {
+				a.app.Logger().Warn("Failed to broadcast realtime analytics", "error", err)
+			}
+		}
+	}
 }
 
 func (a *analyticsImpl) Flush() {
-	// TODO: 实现完整的 Flush 逻辑（Phase 2）
+	if err := a.flusher.Flush(context.Background()); err != nil {
+		a.app.Logger().Error("Failed to flush analytics", "error", err)
+	}
 }
 
 func (a *analyticsImpl) Close() error {
@@ -177,8 +305,22 @@ func (a *analyticsImpl) Close() error {
 }
 
 func (a *analyticsImpl) Repository() Repository {
-	// TODO: 返回 repository（Phase 2）
-	return nil
+	return a.repo
+}
+
+// RawArchive 返回原始事件归档（实现 RawArchiveProvider）
+func (a *analyticsImpl) RawArchive() *RawArchive {
+	return a.archive
+}
+
+// Ingestion 返回采集过滤器（实现 IngestionProvider）
+func (a *analyticsImpl) Ingestion() *Ingestion {
+	return a.ingestion
+}
+
+// Realtime 返回实时分析滚动窗口（实现 RealtimeProvider）
+func (a *analyticsImpl) Realtime() *Realtime {
+	return a.realtime
 }
 
 func (a *analyticsImpl) Config() *Config {
@@ -188,4 +330,9 @@ func (a *analyticsImpl) Config() *Config {
 }
 
 // 确保实现了 Analytics 接口
-var _ Analytics = (*analyticsImpl)(nil)
+var (
+	_ Analytics          = (*analyticsImpl)(nil)
+	_ RawArchiveProvider = (*analyticsImpl)(nil)
+	_ RealtimeProvider   = (*analyticsImpl)(nil)
+	_ IngestionProvider  = (*analyticsImpl)(nil)
+)
@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/pocketbase/pocketbase/tests"
@@ -33,6 +34,34 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestRegister_InvalidPrivacyModes(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true, ConsentMode: "opt-in"}); err == nil {
+		t.Error("Expected error for invalid consent mode")
+	}
+	if err := Register(app, Config{Mode: ModeFull, Enabled: true, IPMode: "none"}); err == nil {
+		t.Error("Expected error for invalid IP mode")
+	}
+}
+
+func TestRegister_InvalidGeoIPPath(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	err = Register(app, Config{Mode: ModeFull, Enabled: true, GeoIPPath: filepath.Join(t.TempDir(), "missing.mmdb")})
+	if err == nil {
+		t.Error("Expected error for missing GeoIP database")
+	}
+}
+
 func TestRegister_ModeOff(t *testing.T) {
 	app, err := tests.NewTestApp()
 	if err != nil {
@@ -197,10 +226,10 @@ func TestAnalyticsImpl_Repository(t *testing.T) {
 	Register(app, Config{Mode: ModeConditional, Enabled: true})
 	analytics := GetAnalytics(app)
 
-	// Repository 当前返回 nil（Phase 2 实现）
+	// SQLite 模式下使用辅助数据库的 Repository
 	repo := analytics.Repository()
-	if repo != nil {
-		t.Error("Repository() should return nil (not yet implemented)")
+	if _, ok := repo.(*RepositorySQLite); !ok {
+		t.Errorf("Repository() = %T, want *RepositorySQLite", repo)
 	}
 }
 
@@ -2,6 +2,9 @@ package analytics
 
 import (
 	"context"
+	"strings"
+
+	"github.com/pocketbase/dbx"
 )
 
 // Repository 定义分析数据存储接口
@@ -15,6 +18,9 @@ type Repository interface {
 	// UpsertDevice 更新或插入设备统计数据
 	UpsertDevice(ctx context.Context, stat *DeviceStat) error
 
+	// UpsertEvent 更新或插入自定义事件统计数据
+	UpsertEvent(ctx context.Context, stat *EventStat) error
+
 	// GetDailyStats 查询指定日期范围的每日统计数据
 	GetDailyStats(ctx context.Context, startDate, endDate string) ([]*DailyStat, error)
 
@@ -30,9 +36,101 @@ type Repository interface {
 	// GetDailyHLLSketches 获取指定日期范围的 HLL Sketches
 	GetDailyHLLSketches(ctx context.Context, startDate, endDate string) ([][]byte, error)
 
+	// GetEventStats 查询指定日期范围的自定义事件统计（每天一行，包含 HLL）
+	// event 为空时返回所有事件；propKey 为空时返回事件总量，否则返回该属性的拆分明细
+	GetEventStats(ctx context.Context, startDate, endDate, event, propKey string) ([]*EventStat, error)
+
+	// UpsertVisit 更新或插入访客访问记录
+	UpsertVisit(ctx context.Context, stat *VisitStat) error
+
+	// GetVisits 查询指定日期范围内匹配任一过滤条件的访客访问记录（filters 为空时返回全部）
+	// startDate 为空时不限制开始日期
+	GetVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error)
+
+	// GetFirstVisits 返回截至 endDate 首次匹配过滤条件的日期落在 [startDate, endDate] 内的访客，
+	// 每个访客一条记录，只包含 SessionID 和首次日期 Date
+	GetFirstVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error)
+
+	// UpsertCampaign 更新或插入 UTM 营销活动统计数据
+	UpsertCampaign(ctx context.Context, stat *CampaignStat) error
+
+	// GetTopCampaigns 查询指定日期范围的 Top Campaigns（按 source/medium/campaign 汇总）
+	GetTopCampaigns(ctx context.Context, startDate, endDate string, limit int) ([]*CampaignStat, error)
+
+	// UpsertGeo 更新或插入地理位置统计数据
+	UpsertGeo(ctx context.Context, stat *GeoStat) error
+
+	// GetTopGeo 查询指定日期范围的地理位置排行。
+	// country 为空时按国家汇总，否则返回该国家按 region/city 汇总的明细
+	GetTopGeo(ctx context.Context, startDate, endDate, country string, limit int) ([]*GeoStat, error)
+
+	// UpsertSessionPage 更新或插入会话页面统计数据
+	UpsertSessionPage(ctx context.Context, stat *SessionPageStat) error
+
+	// GetTopSessionPages 查询指定日期范围按入口（orderBy = "entries"）
+	// 或退出（orderBy = "exits"）次数排序的页面（按 path 汇总）
+	GetTopSessionPages(ctx context.Context, startDate, endDate, orderBy string, limit int) ([]*SessionPageStat, error)
+
+	// GetSessionTotals 查询指定日期范围的会话汇总（Entries 即会话数）
+	GetSessionTotals(ctx context.Context, startDate, endDate string) (*SessionPageStat, error)
+
 	// DeleteBefore 删除指定日期之前的所有统计数据
 	DeleteBefore(ctx context.Context, date string) error
 
 	// Close 关闭存储连接
 	Close() error
 }
+
+// mergeEventHLL 合并已存储与新增的事件 HLL，返回合并后的 Sketch 与 UV 估算值。
+// HLL 不可用时降级为访客数简单累加。
+func mergeEventHLL(existingHLL []byte, existingVisitors int64, stat *EventStat) ([]byte, int64) {
+	return mergeVisitorsHLL(existingHLL, existingVisitors, stat.HLL, stat.Visitors)
+}
+
+// mergeVisitorsHLL 合并两个访客 HLL Sketch，返回合并后的 Sketch 与 UV 估算值。
+// HLL 不可用时降级为访客数简单累加。
+func mergeVisitorsHLL(existingHLL []byte, existingVisitors int64, hll []byte, visitors int64) ([]byte, int64) {
+	if len(existingHLL) == 0 && len(hll) == 0 {
+		return nil, existingVisitors + visitors
+	}
+
+	merged, count, err := MergeHLLBytes(existingHLL, hll)
+	if err != nil {
+		return existingHLL, existingVisitors + visitors
+	}
+
+	return merged, int64(count)
+}
+
+// sessionPageOrders GetTopSessionPages 支持的排序字段
+var sessionPageOrders = map[string]struct{}{
+	"entries": {},
+	"exits":   {},
+}
+
+// visitFiltersExp 将访问过滤条件转换为 OR 连接的查询表达式，无条件时返回 nil
+func visitFiltersExp(filters []VisitFilter) dbx.Expression {
+	exps := make([]dbx.Expression, 0, len(filters))
+	for _, filter := range filters {
+		conds := make([]dbx.Expression, 0, 2)
+		if filter.Event != "" {
+			conds = append(conds, dbx.HashExp{"event": filter.Event})
+		}
+		if prefix, ok := strings.CutSuffix(filter.Path, "*"); ok {
+			conds = append(conds, dbx.Like("path", prefix).Match(false, true))
+		} else if filter.Path != "" {
+			conds = append(conds, dbx.HashExp{"path": filter.Path})
+		}
+		if len(conds) == 0 {
+			// 空条件匹配全部
+			return nil
+		}
+		exps = append(exps, dbx.And(conds...))
+	}
+
+	if len(exps) == 0 {
+		return nil
+	}
+
+	return dbx.Or(exps...)
+}
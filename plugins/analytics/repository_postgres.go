@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pocketbase/dbx"
@@ -153,6 +154,395 @@ func (r *RepositoryPostgres) UpsertDevice(ctx context.Context, stat *DeviceStat)
 	return err
 }
 
+// UpsertEvent 更新或插入自定义事件统计数据。
+// 如果记录已存在，则累加次数并合并 HLL Sketch。
+func (r *RepositoryPostgres) UpsertEvent(ctx context.Context, stat *EventStat) error {
+	now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+
+	var existing struct {
+		Count    int64  `db:"count"`
+		HLL      []byte `db:"hll"`
+		Visitors int64  `db:"visitors"`
+	}
+	err := r.db.Select("count", "hll", "visitors").
+		From("_analytics_events").
+		Where(dbx.HashExp{"id": stat.ID}).
+		One(&existing)
+
+	if err == nil {
+		// 记录存在，累加次数并合并 HLL
+		newHLL, newVisitors := mergeEventHLL(existing.HLL, existing.Visitors, stat)
+
+		_, err = r.db.Update("_analytics_events",
+			dbx.Params{
+				"count":    existing.Count + stat.Count,
+				"hll":      newHLL,
+				"visitors": newVisitors,
+				"updated":  now,
+			},
+			dbx.HashExp{"id": stat.ID},
+		).Execute()
+		return err
+	}
+
+	// 记录不存在，插入新记录
+	_, err = r.db.Insert("_analytics_events", dbx.Params{
+		"id":         stat.ID,
+		"date":       stat.Date,
+		"event":      stat.Event,
+		"prop_key":   stat.PropKey,
+		"prop_value": stat.PropValue,
+		"count":      stat.Count,
+		"hll":        stat.HLL,
+		"visitors":   stat.Visitors,
+		"created":    now,
+		"updated":    now,
+	}).Execute()
+
+	return err
+}
+
+// GetEventStats 查询指定日期范围的自定义事件统计数据。
+func (r *RepositoryPostgres) GetEventStats(ctx context.Context, startDate, endDate, event, propKey string) ([]*EventStat, error) {
+	var stats []*EventStat
+
+	where := dbx.And(
+		dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+		dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+		dbx.HashExp{"prop_key": propKey},
+	)
+	if event != "" {
+		where = dbx.And(where, dbx.HashExp{"event": event})
+	}
+
+	err := r.db.Select("id", "date", "event", "prop_key", "prop_value", "count", "hll", "visitors").
+		From("_analytics_events").
+		Where(where).
+		OrderBy("date ASC").
+		All(&stats)
+
+	return stats, err
+}
+
+// UpsertVisit 更新或插入访客访问记录。
+// 如果记录已存在，则合并首次/末次时间并累加次数。
+func (r *RepositoryPostgres) UpsertVisit(ctx context.Context, stat *VisitStat) error {
+	var existing struct {
+		FirstTs int64 `db:"first_ts"`
+		LastTs  int64 `db:"last_ts"`
+		Hits    int64 `db:"hits"`
+	}
+	err := r.db.Select("first_ts", "last_ts", "hits").
+		From("_analytics_visits").
+		Where(dbx.HashExp{"id": stat.ID}).
+		One(&existing)
+
+	if err == nil {
+		_, err = r.db.Update("_analytics_visits",
+			dbx.Params{
+				"first_ts": min(existing.FirstTs, stat.FirstTs),
+				"last_ts":  max(existing.LastTs, stat.LastTs),
+				"hits":     existing.Hits + stat.Hits,
+			},
+			dbx.HashExp{"id": stat.ID},
+		).Execute()
+		return err
+	}
+
+	_, err = r.db.Insert("_analytics_visits", dbx.Params{
+		"id":       stat.ID,
+		"date":     stat.Date,
+		"sid":      stat.SessionID,
+		"event":    stat.Event,
+		"path":     stat.Path,
+		"first_ts": stat.FirstTs,
+		"last_ts":  stat.LastTs,
+		"hits":     stat.Hits,
+	}).Execute()
+
+	return err
+}
+
+// GetVisits 查询指定日期范围内的访客访问记录。
+func (r *RepositoryPostgres) GetVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error) {
+	var stats []*VisitStat
+
+	where := dbx.And(
+		dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+		dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+	)
+	if exp := visitFiltersExp(filters); exp != nil {
+		where = dbx.And(where, exp)
+	}
+
+	err := r.db.Select("id", "date", "sid", "event", "path", "first_ts", "last_ts", "hits").
+		From("_analytics_visits").
+		Where(where).
+		OrderBy("first_ts ASC").
+		All(&stats)
+
+	return stats, err
+}
+
+// GetFirstVisits 查询在指定日期范围内首次匹配过滤条件的访客及其首次日期。
+// 首次日期在数据库中按访客聚合，只返回查询范围内的新访客。
+func (r *RepositoryPostgres) GetFirstVisits(ctx context.Context, startDate, endDate string, filters []VisitFilter) ([]*VisitStat, error) {
+	var stats []*VisitStat
+
+	where := dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate})
+	if exp := visitFiltersExp(filters); exp != nil {
+		where = dbx.And(where, exp)
+	}
+
+	err := r.db.Select("sid", "MIN(date) AS date").
+		From("_analytics_visits").
+		Where(where).
+		GroupBy("sid").
+		Having(dbx.NewExp("MIN(date) >= {:start}", dbx.Params{"start": startDate})).
+		All(&stats)
+
+	return stats, err
+}
+
+// UpsertCampaign 更新或插入 UTM 营销活动统计数据。
+// 如果记录已存在，则累加次数并合并 HLL Sketch。
+func (r *RepositoryPostgres) UpsertCampaign(ctx context.Context, stat *CampaignStat) error {
+	now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+
+	var existing struct {
+		Count    int64  `db:"count"`
+		HLL      []byte `db:"hll"`
+		Visitors int64  `db:"visitors"`
+	}
+	err := r.db.Select("count", "hll", "visitors").
+		From("_analytics_campaigns").
+		Where(dbx.HashExp{"id": stat.ID}).
+		One(&existing)
+
+	if err == nil {
+		// 记录存在，累加次数并合并 HLL
+		newHLL, newVisitors := mergeVisitorsHLL(existing.HLL, existing.Visitors, stat.HLL, stat.Visitors)
+
+		_, err = r.db.Update("_analytics_campaigns",
+			dbx.Params{
+				"count":    existing.Count + stat.Count,
+				"hll":      newHLL,
+				"visitors": newVisitors,
+				"updated":  now,
+			},
+			dbx.HashExp{"id": stat.ID},
+		).Execute()
+		return err
+	}
+
+	// 记录不存在，插入新记录
+	_, err = r.db.Insert("_analytics_campaigns", dbx.Params{
+		"id":       stat.ID,
+		"date":     stat.Date,
+		"source":   stat.Source,
+		"medium":   stat.Medium,
+		"campaign": stat.Campaign,
+		"count":    stat.Count,
+		"hll":      stat.HLL,
+		"visitors": stat.Visitors,
+		"created":  now,
+		"updated":  now,
+	}).Execute()
+
+	return err
+}
+
+// GetTopCampaigns 查询指定日期范围的 Top Campaigns。
+func (r *RepositoryPostgres) GetTopCampaigns(ctx context.Context, startDate, endDate string, limit int) ([]*CampaignStat, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var stats []*CampaignStat
+
+	err := r.db.Select("source", "medium", "campaign", "SUM(count) as count", "SUM(visitors) as visitors").
+		From("_analytics_campaigns").
+		Where(dbx.And(
+			dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+			dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+		)).
+		GroupBy("source", "medium", "campaign").
+		OrderBy("visitors DESC", "count DESC").
+		Limit(int64(limit)).
+		All(&stats)
+
+	return stats, err
+}
+
+// UpsertGeo 更新或插入地理位置统计数据。
+// 如果记录已存在，则累加次数并合并 HLL Sketch。
+func (r *RepositoryPostgres) UpsertGeo(ctx context.Context, stat *GeoStat) error {
+	now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+
+	var existing struct {
+		Count    int64  `db:"count"`
+		HLL      []byte `db:"hll"`
+		Visitors int64  `db:"visitors"`
+	}
+	err := r.db.Select("count", "hll", "visitors").
+		From("_analytics_geo").
+		Where(dbx.HashExp{"id": stat.ID}).
+		One(&existing)
+
+	if err == nil {
+		// 记录存在，累加次数并合并 HLL
+		newHLL, newVisitors := mergeVisitorsHLL(existing.HLL, existing.Visitors, stat.HLL, stat.Visitors)
+
+		_, err = r.db.Update("_analytics_geo",
+			dbx.Params{
+				"count":    existing.Count + stat.Count,
+				"hll":      newHLL,
+				"visitors": newVisitors,
+				"updated":  now,
+			},
+			dbx.HashExp{"id": stat.ID},
+		).Execute()
+		return err
+	}
+
+	// 记录不存在，插入新记录
+	_, err = r.db.Insert("_analytics_geo", dbx.Params{
+		"id":       stat.ID,
+		"date":     stat.Date,
+		"country":  stat.Country,
+		"region":   stat.Region,
+		"city":     stat.City,
+		"count":    stat.Count,
+		"hll":      stat.HLL,
+		"visitors": stat.Visitors,
+		"created":  now,
+		"updated":  now,
+	}).Execute()
+
+	return err
+}
+
+// GetTopGeo 查询指定日期范围的地理位置排行。
+// country 为空时按国家汇总，否则返回该国家按 region/city 汇总的明细。
+func (r *RepositoryPostgres) GetTopGeo(ctx context.Context, startDate, endDate, country string, limit int) ([]*GeoStat, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	where := dbx.And(
+		dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+		dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+	)
+	groupBy := []string{"country"}
+	if country != "" {
+		where = dbx.And(where, dbx.HashExp{"country": country})
+		groupBy = []string{"country", "region", "city"}
+	}
+
+	var stats []*GeoStat
+
+	err := r.db.Select(append(groupBy, "SUM(count) as count", "SUM(visitors) as visitors")...).
+		From("_analytics_geo").
+		Where(where).
+		GroupBy(groupBy...).
+		OrderBy("visitors DESC", "count DESC").
+		Limit(int64(limit)).
+		All(&stats)
+
+	return stats, err
+}
+
+// UpsertSessionPage 更新或插入会话页面统计数据。
+// 如果记录已存在，则累加各项计数。
+func (r *RepositoryPostgres) UpsertSessionPage(ctx context.Context, stat *SessionPageStat) error {
+	now := time.Now().UTC().Format("2006-01-02 15:04:05.000Z")
+
+	var existing struct {
+		Entries  int64 `db:"entries"`
+		Exits    int64 `db:"exits"`
+		Bounces  int64 `db:"bounces"`
+		Duration int64 `db:"duration"`
+	}
+	err := r.db.Select("entries", "exits", "bounces", "duration").
+		From("_analytics_session_pages").
+		Where(dbx.HashExp{"id": stat.ID}).
+		One(&existing)
+
+	if err == nil {
+		_, err = r.db.Update("_analytics_session_pages",
+			dbx.Params{
+				"entries":  existing.Entries + stat.Entries,
+				"exits":    existing.Exits + stat.Exits,
+				"bounces":  existing.Bounces + stat.Bounces,
+				"duration": existing.Duration + stat.Duration,
+				"updated":  now,
+			},
+			dbx.HashExp{"id": stat.ID},
+		).Execute()
+		return err
+	}
+
+	_, err = r.db.Insert("_analytics_session_pages", dbx.Params{
+		"id":       stat.ID,
+		"date":     stat.Date,
+		"path":     stat.Path,
+		"entries":  stat.Entries,
+		"exits":    stat.Exits,
+		"bounces":  stat.Bounces,
+		"duration": stat.Duration,
+		"created":  now,
+		"updated":  now,
+	}).Execute()
+
+	return err
+}
+
+// GetTopSessionPages 查询指定日期范围的 Top 入口页或退出页。
+func (r *RepositoryPostgres) GetTopSessionPages(ctx context.Context, startDate, endDate, orderBy string, limit int) ([]*SessionPageStat, error) {
+	if _, ok := sessionPageOrders[orderBy]; !ok {
+		return nil, fmt.Errorf("unsupported order %q", orderBy)
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var stats []*SessionPageStat
+
+	err := r.db.Select("path", "SUM(entries) as entries", "SUM(exits) as exits", "SUM(bounces) as bounces", "SUM(duration) as duration").
+		From("_analytics_session_pages").
+		Where(dbx.And(
+			dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+			dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+		)).
+		GroupBy("path").
+		Having(dbx.NewExp("SUM(" + orderBy + ") > 0")).
+		OrderBy(orderBy + " DESC").
+		Limit(int64(limit)).
+		All(&stats)
+
+	return stats, err
+}
+
+// GetSessionTotals 查询指定日期范围的会话汇总。
+func (r *RepositoryPostgres) GetSessionTotals(ctx context.Context, startDate, endDate string) (*SessionPageStat, error) {
+	totals := &SessionPageStat{}
+
+	err := r.db.Select(
+		"COALESCE(SUM(entries), 0) as entries",
+		"COALESCE(SUM(exits), 0) as exits",
+		"COALESCE(SUM(bounces), 0) as bounces",
+		"COALESCE(SUM(duration), 0) as duration",
+	).
+		From("_analytics_session_pages").
+		Where(dbx.And(
+			dbx.NewExp("date >= {:start}", dbx.Params{"start": startDate}),
+			dbx.NewExp("date <= {:end}", dbx.Params{"end": endDate}),
+		)).
+		One(totals)
+
+	return totals, err
+}
+
 // GetDailyStats 查询指定日期范围的每日统计数据。
 func (r *RepositoryPostgres) GetDailyStats(ctx context.Context, startDate, endDate string) ([]*DailyStat, error) {
 	var stats []*DailyStat
@@ -261,7 +651,7 @@ func (r *RepositoryPostgres) GetDailyHLLSketches(ctx context.Context, startDate,
 
 // DeleteBefore 删除指定日期之前的所有统计数据。
 func (r *RepositoryPostgres) DeleteBefore(ctx context.Context, date string) error {
-	tables := []string{"_analytics_daily", "_analytics_sources", "_analytics_devices"}
+	tables := []string{"_analytics_daily", "_analytics_sources", "_analytics_devices", "_analytics_events", "_analytics_visits", "_analytics_campaigns", "_analytics_geo", "_analytics_session_pages"}
 
 	for _, table := range tables {
 		_, err := r.db.Delete(table,
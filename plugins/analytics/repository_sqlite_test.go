@@ -50,6 +50,63 @@ func testDB(t *testing.T) dbx.Builder {
 			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
 			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
 		);
+		CREATE TABLE IF NOT EXISTS "_analytics_events" (
+			"id"         TEXT PRIMARY KEY NOT NULL,
+			"date"       TEXT NOT NULL,
+			"event"      TEXT NOT NULL,
+			"prop_key"   TEXT DEFAULT '' NOT NULL,
+			"prop_value" TEXT DEFAULT '' NOT NULL,
+			"count"      INTEGER DEFAULT 0 NOT NULL,
+			"hll"        BLOB,
+			"visitors"   INTEGER DEFAULT 0 NOT NULL,
+			"created"    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"    TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_visits" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"sid"      TEXT NOT NULL,
+			"event"    TEXT NOT NULL,
+			"path"     TEXT NOT NULL,
+			"first_ts" INTEGER DEFAULT 0 NOT NULL,
+			"last_ts"  INTEGER DEFAULT 0 NOT NULL,
+			"hits"     INTEGER DEFAULT 0 NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_campaigns" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"source"   TEXT DEFAULT '' NOT NULL,
+			"medium"   TEXT DEFAULT '' NOT NULL,
+			"campaign" TEXT DEFAULT '' NOT NULL,
+			"count"    INTEGER DEFAULT 0 NOT NULL,
+			"hll"      BLOB,
+			"visitors" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_geo" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"country"  TEXT NOT NULL,
+			"region"   TEXT DEFAULT '' NOT NULL,
+			"city"     TEXT DEFAULT '' NOT NULL,
+			"count"    INTEGER DEFAULT 0 NOT NULL,
+			"hll"      BLOB,
+			"visitors" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS "_analytics_session_pages" (
+			"id"       TEXT PRIMARY KEY NOT NULL,
+			"date"     TEXT NOT NULL,
+			"path"     TEXT NOT NULL,
+			"entries"  INTEGER DEFAULT 0 NOT NULL,
+			"exits"    INTEGER DEFAULT 0 NOT NULL,
+			"bounces"  INTEGER DEFAULT 0 NOT NULL,
+			"duration" INTEGER DEFAULT 0 NOT NULL,
+			"created"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL,
+			"updated"  TEXT DEFAULT (strftime('%Y-%m-%d %H:%M:%fZ')) NOT NULL
+		);
 	`
 	_, err = dbxDB.NewQuery(sql).Execute()
 	if err != nil {
@@ -579,3 +636,266 @@ func TestRepositorySQLite_UpsertDaily_HLLMergeFallback(t *testing.T) {
 		t.Errorf("Expected TotalPV 150, got %d", stats[0].TotalPV)
 	}
 }
+
+func TestRepositorySQLite_UpsertEvent(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepositorySQLite(db)
+	ctx := context.Background()
+
+	hll1 := NewHLL()
+	hll1.Add("s1")
+	bytes1, _ := hll1.Bytes()
+
+	hll2 := NewHLL()
+	hll2.Add("s1")
+	hll2.Add("s2")
+	bytes2, _ := hll2.Bytes()
+
+	stats := []*EventStat{
+		{ID: "2024-01-01|click_buy||", Date: "2024-01-01", Event: "click_buy", Count: 1, HLL: bytes1, Visitors: 1},
+		{ID: "2024-01-01|click_buy||", Date: "2024-01-01", Event: "click_buy", Count: 2, HLL: bytes2, Visitors: 2},
+		{ID: "2024-01-01|click_buy|plan|pro", Date: "2024-01-01", Event: "click_buy", PropKey: "plan", PropValue: "pro", Count: 2, HLL: bytes2, Visitors: 2},
+		{ID: "2024-01-01|signup||", Date: "2024-01-01", Event: "signup", Count: 1, HLL: bytes1, Visitors: 1},
+	}
+	for _, stat := range stats {
+		if err := repo.UpsertEvent(ctx, stat); err != nil {
+			t.Fatalf("UpsertEvent failed: %v", err)
+		}
+	}
+
+	// 总量行：次数累加，访客 HLL 合并
+	totals, err := repo.GetEventStats(ctx, "2024-01-01", "2024-01-01", "click_buy", "")
+	if err != nil {
+		t.Fatalf("GetEventStats failed: %v", err)
+	}
+	if len(totals) != 1 || totals[0].Count != 3 || totals[0].Visitors != 2 {
+		t.Fatalf("totals = %+v", totals)
+	}
+
+	// 属性拆分
+	breakdown, err := repo.GetEventStats(ctx, "2024-01-01", "2024-01-01", "click_buy", "plan")
+	if err != nil {
+		t.Fatalf("GetEventStats failed: %v", err)
+	}
+	if len(breakdown) != 1 || breakdown[0].PropValue != "pro" || len(breakdown[0].HLL) == 0 {
+		t.Fatalf("breakdown = %+v", breakdown)
+	}
+
+	// 不指定事件时返回所有事件总量
+	all, err := repo.GetEventStats(ctx, "2024-01-01", "2024-01-01", "", "")
+	if err != nil {
+		t.Fatalf("GetEventStats failed: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("len(all) = %d, want 2", len(all))
+	}
+
+	// DeleteBefore 同时清理事件统计
+	if err := repo.DeleteBefore(ctx, "2024-01-02"); err != nil {
+		t.Fatalf("DeleteBefore failed: %v", err)
+	}
+	all, _ = repo.GetEventStats(ctx, "2024-01-01", "2024-01-01", "", "")
+	if len(all) != 0 {
+		t.Errorf("len(all) after DeleteBefore = %d, want 0", len(all))
+	}
+}
+
+func TestRepositorySQLite_Visits(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepositorySQLite(db)
+	ctx := context.Background()
+
+	stats := []*VisitStat{
+		{ID: "2024-01-01|s1|page_view|/docs/a", Date: "2024-01-01", SessionID: "s1", Event: "page_view", Path: "/docs/a", FirstTs: 200, LastTs: 300, Hits: 2},
+		{ID: "2024-01-01|s1|page_view|/docs/a", Date: "2024-01-01", SessionID: "s1", Event: "page_view", Path: "/docs/a", FirstTs: 100, LastTs: 250, Hits: 1},
+		{ID: "2024-01-01|s1|signup|/register", Date: "2024-01-01", SessionID: "s1", Event: "signup", Path: "/register", FirstTs: 400, LastTs: 400, Hits: 1},
+		{ID: "2024-01-02|s2|page_view|/docs_old", Date: "2024-01-02", SessionID: "s2", Event: "page_view", Path: "/docs_old", FirstTs: 500, LastTs: 500, Hits: 1},
+	}
+	for _, stat := range stats {
+		if err := repo.UpsertVisit(ctx, stat); err != nil {
+			t.Fatalf("UpsertVisit failed: %v", err)
+		}
+	}
+
+	// 合并首次/末次时间与次数
+	visits, err := repo.GetVisits(ctx, "2024-01-01", "2024-01-01", []VisitFilter{{Path: "/docs/*"}})
+	if err != nil {
+		t.Fatalf("GetVisits failed: %v", err)
+	}
+	if len(visits) != 1 || visits[0].FirstTs != 100 || visits[0].LastTs != 300 || visits[0].Hits != 3 {
+		t.Fatalf("visits = %+v", visits)
+	}
+
+	// 前缀匹配不应把 "_" 当作通配符
+	visits, _ = repo.GetVisits(ctx, "", "2024-12-31", []VisitFilter{{Path: "/docs/*"}})
+	if len(visits) != 1 {
+		t.Errorf("prefix visits = %+v", visits)
+	}
+
+	// 多个条件 OR 连接，按首次时间排序
+	visits, _ = repo.GetVisits(ctx, "", "2024-12-31", []VisitFilter{{Event: "signup"}, {Path: "/docs_old"}})
+	if len(visits) != 2 || visits[0].Event != "signup" || visits[1].SessionID != "s2" {
+		t.Errorf("or visits = %+v", visits)
+	}
+
+	// 无条件返回全部
+	visits, _ = repo.GetVisits(ctx, "", "2024-12-31", nil)
+	if len(visits) != 3 {
+		t.Errorf("len(all visits) = %d, want 3", len(visits))
+	}
+
+	// 首次访问：s1 在 2024-01-01 已出现，不属于 2024-01-02 起的新访客
+	first, err := repo.GetFirstVisits(ctx, "2024-01-02", "2024-12-31", nil)
+	if err != nil {
+		t.Fatalf("GetFirstVisits failed: %v", err)
+	}
+	if len(first) != 1 || first[0].SessionID != "s2" || first[0].Date != "2024-01-02" {
+		t.Errorf("first visits = %+v", first)
+	}
+
+	first, _ = repo.GetFirstVisits(ctx, "2024-01-01", "2024-12-31", []VisitFilter{{Event: "signup"}})
+	if len(first) != 1 || first[0].SessionID != "s1" || first[0].Date != "2024-01-01" {
+		t.Errorf("first signup visits = %+v", first)
+	}
+}
+
+func TestRepositorySQLite_Campaigns(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepositorySQLite(db)
+	ctx := context.Background()
+
+	hll1 := NewHLL()
+	hll1.Add("s1")
+	hll1Bytes, _ := hll1.Bytes()
+	hll2 := NewHLL()
+	hll2.Add("s1")
+	hll2.Add("s2")
+	hll2Bytes, _ := hll2.Bytes()
+
+	stats := []*CampaignStat{
+		{ID: "2024-01-01|newsletter|email|launch", Date: "2024-01-01", Source: "newsletter", Medium: "email", Campaign: "launch", Count: 2, HLL: hll1Bytes, Visitors: 1},
+		{ID: "2024-01-01|newsletter|email|launch", Date: "2024-01-01", Source: "newsletter", Medium: "email", Campaign: "launch", Count: 3, HLL: hll2Bytes, Visitors: 2},
+		{ID: "2024-01-02|newsletter|email|launch", Date: "2024-01-02", Source: "newsletter", Medium: "email", Campaign: "launch", Count: 1, Visitors: 1},
+		{ID: "2024-01-02|twitter||", Date: "2024-01-02", Source: "twitter", Count: 1, Visitors: 1},
+	}
+	for _, stat := range stats {
+		if err := repo.UpsertCampaign(ctx, stat); err != nil {
+			t.Fatalf("UpsertCampaign failed: %v", err)
+		}
+	}
+
+	campaigns, err := repo.GetTopCampaigns(ctx, "2024-01-01", "2024-01-31", 10)
+	if err != nil {
+		t.Fatalf("GetTopCampaigns failed: %v", err)
+	}
+	if len(campaigns) != 2 {
+		t.Fatalf("Expected 2 campaigns, got %d", len(campaigns))
+	}
+
+	// 同一天的 HLL 合并去重（2），跨天按天累加（+1）
+	top := campaigns[0]
+	if top.Source != "newsletter" || top.Campaign != "launch" || top.Count != 6 || top.Visitors != 3 {
+		t.Errorf("Unexpected top campaign: %+v", top)
+	}
+
+	campaigns, _ = repo.GetTopCampaigns(ctx, "2024-01-01", "2024-01-31", 1)
+	if len(campaigns) != 1 {
+		t.Errorf("Expected limit 1, got %d", len(campaigns))
+	}
+}
+
+func TestRepositorySQLite_Geo(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepositorySQLite(db)
+	ctx := context.Background()
+
+	stats := []*GeoStat{
+		{ID: "2024-01-01|US|California|Mountain View", Date: "2024-01-01", Country: "US", Region: "California", City: "Mountain View", Count: 2, Visitors: 2},
+		{ID: "2024-01-01|US|California|Mountain View", Date: "2024-01-01", Country: "US", Region: "California", City: "Mountain View", Count: 1, Visitors: 1},
+		{ID: "2024-01-02|US|New York|New York", Date: "2024-01-02", Country: "US", Region: "New York", City: "New York", Count: 1, Visitors: 1},
+		{ID: "2024-01-02|DE||", Date: "2024-01-02", Country: "DE", Count: 5, Visitors: 1},
+	}
+	for _, stat := range stats {
+		if err := repo.UpsertGeo(ctx, stat); err != nil {
+			t.Fatalf("UpsertGeo failed: %v", err)
+		}
+	}
+
+	countries, err := repo.GetTopGeo(ctx, "2024-01-01", "2024-01-31", "", 10)
+	if err != nil {
+		t.Fatalf("GetTopGeo failed: %v", err)
+	}
+	if len(countries) != 2 {
+		t.Fatalf("Expected 2 countries, got %d", len(countries))
+	}
+	if top := countries[0]; top.Country != "US" || top.Count != 4 || top.Visitors != 4 {
+		t.Errorf("Unexpected top country: %+v", top)
+	}
+
+	locations, err := repo.GetTopGeo(ctx, "2024-01-01", "2024-01-31", "US", 10)
+	if err != nil {
+		t.Fatalf("GetTopGeo failed: %v", err)
+	}
+	if len(locations) != 2 || locations[0].City != "Mountain View" || locations[0].Count != 3 {
+		t.Errorf("Unexpected locations: %+v", locations)
+	}
+
+	if err := repo.DeleteBefore(ctx, "2024-01-02"); err != nil {
+		t.Fatalf("DeleteBefore failed: %v", err)
+	}
+	countries, _ = repo.GetTopGeo(ctx, "2024-01-01", "2024-01-31", "", 10)
+	if len(countries) != 2 || countries[0].Count+countries[1].Count != 6 {
+		t.Errorf("Unexpected countries after prune: %+v", countries)
+	}
+}
+
+func TestRepositorySQLite_SessionPages(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepositorySQLite(db)
+	ctx := context.Background()
+
+	stats := []*SessionPageStat{
+		{ID: "2024-01-01|/landing", Date: "2024-01-01", Path: "/landing", Entries: 2, Bounces: 1, Duration: 60000},
+		{ID: "2024-01-01|/landing", Date: "2024-01-01", Path: "/landing", Entries: 1, Exits: 1, Bounces: 1},
+		{ID: "2024-01-02|/checkout", Date: "2024-01-02", Path: "/checkout", Exits: 2},
+		{ID: "2024-01-02|/blog", Date: "2024-01-02", Path: "/blog", Entries: 1, Exits: 1, Duration: 30000},
+	}
+	for _, stat := range stats {
+		if err := repo.UpsertSessionPage(ctx, stat); err != nil {
+			t.Fatalf("UpsertSessionPage failed: %v", err)
+		}
+	}
+
+	entries, err := repo.GetTopSessionPages(ctx, "2024-01-01", "2024-01-31", "entries", 10)
+	if err != nil {
+		t.Fatalf("GetTopSessionPages failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Path != "/landing" || entries[0].Entries != 3 || entries[0].Bounces != 2 || entries[0].Duration != 60000 {
+		t.Fatalf("Unexpected entry pages: %+v", entries)
+	}
+
+	exits, err := repo.GetTopSessionPages(ctx, "2024-01-01", "2024-01-31", "exits", 10)
+	if err != nil {
+		t.Fatalf("GetTopSessionPages failed: %v", err)
+	}
+	if len(exits) != 3 || exits[0].Path != "/checkout" || exits[0].Exits != 2 {
+		t.Fatalf("Unexpected exit pages: %+v", exits)
+	}
+
+	if _, err := repo.GetTopSessionPages(ctx, "2024-01-01", "2024-01-31", "path; DROP TABLE x", 10); err == nil {
+		t.Fatal("Expected error for unsupported order")
+	}
+
+	totals, err := repo.GetSessionTotals(ctx, "2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("GetSessionTotals failed: %v", err)
+	}
+	if totals.Entries != 4 || totals.Bounces != 2 || totals.Duration != 90000 {
+		t.Fatalf("Unexpected totals: %+v", totals)
+	}
+
+	totals, err = repo.GetSessionTotals(ctx, "2025-01-01", "2025-01-31")
+	if err != nil || totals.Entries != 0 {
+		t.Fatalf("Expected empty totals, got %+v (%v)", totals, err)
+	}
+}
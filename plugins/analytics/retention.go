@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"time"
+)
+
+// RetentionInterval 留存分析的周期粒度
+type RetentionInterval string
+
+const (
+	// RetentionDaily 按天统计留存
+	RetentionDaily RetentionInterval = "day"
+
+	// RetentionWeekly 按周（周一开始）统计留存
+	RetentionWeekly RetentionInterval = "week"
+)
+
+// MaxRetentionPeriods 留存分析最大周期数
+const MaxRetentionPeriods = 52
+
+// days 返回周期包含的天数
+func (i RetentionInterval) days() int {
+	if i == RetentionDaily {
+		return 1
+	}
+	return 7
+}
+
+// periodStart 返回日期所在周期的开始日期
+func (i RetentionInterval) periodStart(date time.Time) time.Time {
+	if i == RetentionDaily {
+		return date
+	}
+	// 以周一作为一周的开始
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// RetentionCohort 一个留存队列
+type RetentionCohort struct {
+	// Cohort 队列所在周期的开始日期
+	Cohort string `json:"cohort"`
+
+	// Size 队列访客数
+	Size int64 `json:"size"`
+
+	// Retained 第 N 个周期回访的访客数，Retained[0] 等于 Size
+	Retained []int64 `json:"retained"`
+
+	// Rates 第 N 个周期的留存率（0-1）
+	Rates []float64 `json:"rates"`
+}
+
+// ComputeRetention 根据访问记录计算留存队列
+//
+// entries 为匹配进入条件的访问记录（每个访客需包含其首次进入的记录，如 Repository.GetFirstVisits 的结果），
+// 访客首次进入的周期即所属队列，只统计首次进入日期在 [startDate, endDate] 内的访客；
+// returns 为匹配回访条件的访问记录，访客在队列之后第 N 个周期有任意回访即计入 Retained[N]。
+// 尚未结束的周期（开始日期晚于 endDate）不会出现在结果中。
+func ComputeRetention(entries, returns []*VisitStat, startDate, endDate string, interval RetentionInterval, periods int) []RetentionCohort {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return []RetentionCohort{}
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return []RetentionCohort{}
+	}
+
+	// 每个访客的首次进入日期
+	firstEntry := make(map[string]string)
+	for _, visit := range entries {
+		if first, ok := firstEntry[visit.SessionID]; !ok || visit.Date < first {
+			firstEntry[visit.SessionID] = visit.Date
+		}
+	}
+
+	// 每个访客所属的队列
+	type cohortData struct {
+		start    time.Time
+		members  map[string]struct{}
+		retained []map[string]struct{}
+	}
+	cohorts := make(map[string]*cohortData)
+	memberOf := make(map[string]*cohortData)
+
+	for sid, date := range firstEntry {
+		if date < startDate || date > endDate {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+
+		cohortStart := interval.periodStart(day)
+		key := cohortStart.Format("2006-01-02")
+		cohort, ok := cohorts[key]
+		if !ok {
+			cohort = &cohortData{
+				start:    cohortStart,
+				members:  make(map[string]struct{}),
+				retained: make([]map[string]struct{}, periods),
+			}
+			cohorts[key] = cohort
+		}
+		cohort.members[sid] = struct{}{}
+		memberOf[sid] = cohort
+	}
+
+	// 统计回访
+	periodDays := interval.days()
+	for _, visit := range returns {
+		cohort, ok := memberOf[visit.SessionID]
+		if !ok {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", visit.Date)
+		if err != nil {
+			continue
+		}
+
+		n := int(interval.periodStart(day).Sub(cohort.start).Hours()/24) / periodDays
+		if n <= 0 || n >= periods {
+			continue
+		}
+		if cohort.retained[n] == nil {
+			cohort.retained[n] = make(map[string]struct{})
+		}
+		cohort.retained[n][visit.SessionID] = struct{}{}
+	}
+
+	// 按队列日期排序输出
+	result := make([]RetentionCohort, 0, len(cohorts))
+	lastPeriod := interval.periodStart(end)
+	for cursor := interval.periodStart(start); !cursor.After(lastPeriod); cursor = cursor.AddDate(0, 0, periodDays) {
+		cohort, ok := cohorts[cursor.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+
+		// 只输出已开始的周期
+		available := int(lastPeriod.Sub(cohort.start).Hours()/24)/periodDays + 1
+		available = min(available, periods)
+
+		size := int64(len(cohort.members))
+		item := RetentionCohort{
+			Cohort:   cursor.Format("2006-01-02"),
+			Size:     size,
+			Retained: make([]int64, available),
+			Rates:    make([]float64, available),
+		}
+		for n := 0; n < available; n++ {
+			retained := size
+			if n > 0 {
+				retained = int64(len(cohort.retained[n]))
+			}
+			item.Retained[n] = retained
+			if size > 0 {
+				item.Rates[n] = float64(retained) / float64(size)
+			}
+		}
+
+		result = append(result, item)
+	}
+
+	return result
+}
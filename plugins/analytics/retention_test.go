@@ -0,0 +1,91 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeRetention_Weekly(t *testing.T) {
+	// 2026-01-05 为周一
+	week1 := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	week2 := week1.AddDate(0, 0, 7)
+	week4 := week1.AddDate(0, 0, 21)
+
+	entries := []*VisitStat{
+		// 查询范围之前已进入的访客不计入队列
+		visit("old", "signup", "/register", week1.AddDate(0, 0, -30)),
+		visit("s1", "signup", "/register", week1),
+		visit("s2", "signup", "/register", week1.AddDate(0, 0, 3)),
+		visit("s3", "signup", "/register", week2),
+	}
+	returns := []*VisitStat{
+		visit("old", "page_view", "/", week2),
+		visit("s1", "page_view", "/", week1.AddDate(0, 0, 1)), // 同一周不计入后续周期
+		visit("s1", "page_view", "/", week2),
+		visit("s1", "page_view", "/", week4),
+		visit("s2", "page_view", "/", week4.AddDate(0, 0, 2)),
+		visit("s3", "page_view", "/", week2.AddDate(0, 0, 8)),
+	}
+
+	cohorts := ComputeRetention(entries, returns, "2026-01-05", "2026-01-28", RetentionWeekly, 8)
+	if len(cohorts) != 2 {
+		t.Fatalf("len(cohorts) = %d, want 2: %+v", len(cohorts), cohorts)
+	}
+
+	first := cohorts[0]
+	if first.Cohort != "2026-01-05" || first.Size != 2 {
+		t.Fatalf("first cohort = %+v", first)
+	}
+	// 2026-01-28 所在周为第 4 周（索引 3），之后的周期尚未开始
+	wantRetained := []int64{2, 1, 0, 2}
+	if len(first.Retained) != len(wantRetained) {
+		t.Fatalf("first.Retained = %v, want %v", first.Retained, wantRetained)
+	}
+	for i, want := range wantRetained {
+		if first.Retained[i] != want {
+			t.Errorf("first.Retained[%d] = %d, want %d", i, first.Retained[i], want)
+		}
+	}
+	if first.Rates[1] != 0.5 || first.Rates[3] != 1 {
+		t.Errorf("first.Rates = %v", first.Rates)
+	}
+
+	second := cohorts[1]
+	if second.Cohort != "2026-01-12" || second.Size != 1 || len(second.Retained) != 3 || second.Retained[1] != 1 {
+		t.Errorf("second cohort = %+v", second)
+	}
+}
+
+func TestComputeRetention_Daily(t *testing.T) {
+	day := time.Date(2026, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	entries := []*VisitStat{visit("s1", "page_view", "/", day)}
+	returns := []*VisitStat{
+		visit("s1", "page_view", "/", day),
+		visit("s1", "page_view", "/", day.AddDate(0, 0, 2)),
+		visit("s1", "page_view", "/", day.AddDate(0, 0, 20)), // 超出周期数
+	}
+
+	cohorts := ComputeRetention(entries, returns, "2026-01-10", "2026-01-31", RetentionDaily, 3)
+	if len(cohorts) != 1 {
+		t.Fatalf("len(cohorts) = %d, want 1", len(cohorts))
+	}
+
+	want := []int64{1, 0, 1}
+	for i := range want {
+		if cohorts[0].Retained[i] != want[i] {
+			t.Errorf("Retained = %v, want %v", cohorts[0].Retained, want)
+			break
+		}
+	}
+}
+
+func TestRetentionIntervalPeriodStart(t *testing.T) {
+	sunday := time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)
+	if got := RetentionWeekly.periodStart(sunday).Format("2006-01-02"); got != "2026-01-05" {
+		t.Errorf("periodStart(sunday) = %s, want 2026-01-05", got)
+	}
+	if got := RetentionDaily.periodStart(sunday); !got.Equal(sunday) {
+		t.Errorf("daily periodStart = %s", got)
+	}
+}
@@ -23,6 +23,16 @@ func BindRoutes(app core.App, rg *router.RouterGroup[*core.RequestEvent]) {
 	subGroup.GET("/stats", statsHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "stats"))
 	subGroup.GET("/top-pages", topPagesHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "top-pages"))
 	subGroup.GET("/top-sources", topSourcesHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "top-sources"))
+	subGroup.GET("/campaigns", campaignsHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "campaigns"))
+	subGroup.GET("/entry-pages", sessionPagesHandler(app, "entries")).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "entry-pages"))
+	subGroup.GET("/exit-pages", sessionPagesHandler(app, "exits")).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "exit-pages"))
+	subGroup.GET("/countries", countriesHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "countries"))
+	subGroup.GET("/realtime", realtimeHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "realtime"))
+	subGroup.GET("/events", customEventsHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "custom-events"))
+	subGroup.GET("/funnels", funnelsListHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "funnels"))
+	subGroup.POST("/funnels", funnelHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "funnel"))
+	subGroup.GET("/funnels/{name}", funnelHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "funnel"))
+	subGroup.GET("/retention", retentionHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "retention"))
 	subGroup.GET("/devices", devicesHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "devices"))
 	subGroup.GET("/raw-logs", rawLogsHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "raw-logs"))
 	subGroup.GET("/raw-logs/{date}", rawLogDownloadHandler(app)).Bind(apis.RequireSuperuserAuth(), requestLogger(app, "raw-log-download"))
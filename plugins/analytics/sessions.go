@@ -0,0 +1,226 @@
+package analytics
+
+import (
+	"container/list"
+	"time"
+)
+
+// DefaultSessionTimeout 默认会话超时时间：同一 SessionID 超过该时长无新事件即视为会话结束
+const DefaultSessionTimeout = 30 * time.Minute
+
+// DefaultMaxSessions 默认进行中会话数上限，达到上限时提前结束最久未活动的会话
+const DefaultMaxSessions = 100000
+
+// pageViewEvent 页面浏览事件名称，入口页/退出页/跳出只按页面浏览计算
+const pageViewEvent = "page_view"
+
+// sessionState 进行中的会话
+type sessionState struct {
+	sid       string        // SessionID
+	elem      *list.Element // 在 sessionOrder 中的位置
+	date      string        // 会话开始日期
+	start     int64         // 首个事件时间（毫秒）
+	last      int64         // 最后一个事件时间（毫秒）
+	entryPath string
+	exitPath  string
+	pageViews int64
+}
+
+// SetSessionTimeout 设置会话超时时间。
+func (b *Buffer) SetSessionTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultSessionTimeout
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sessionTimeout = timeout
+}
+
+// SetMaxSessions 设置进行中会话数上限。
+func (b *Buffer) SetMaxSessions(n int) {
+	if n <= 0 {
+		n = DefaultMaxSessions
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxSessions = n
+}
+
+// SessionCount 返回进行中的会话数量。
+func (b *Buffer) SessionCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.sessions)
+}
+
+// trackSession 根据事件更新所属会话（调用方需持有写锁）。
+//
+// 与上一个事件间隔超过 sessionTimeout 时，旧会话结束并开始新会话。
+// 入口页与退出页取会话中最早/最晚的页面浏览路径；
+// 没有页面浏览的会话以首个事件的路径作为入口页和退出页。
+// 进行中会话数达到 maxSessions 时，最久未活动的会话提前结束。
+func (b *Buffer) trackSession(event *Event) {
+	if event.SessionID == "" {
+		return
+	}
+
+	ts := event.Timestamp.UnixMilli()
+	isPageView := event.Event == pageViewEvent
+
+	session, exists := b.sessions[event.SessionID]
+	if exists && ts-session.last > b.sessionTimeout.Milliseconds() {
+		b.closeSession(session)
+		b.removeSession(session)
+		exists = false
+	}
+
+	if !exists {
+		for len(b.sessions) >= b.maxSessions && b.sessionOrder.Len() > 0 {
+			oldest := b.sessionOrder.Front().Value.(*sessionState)
+			b.closeSession(oldest)
+			b.removeSession(oldest)
+		}
+
+		session = &sessionState{
+			sid:       event.SessionID,
+			date:      event.Timestamp.Format("2006-01-02"),
+			start:     ts,
+			last:      ts,
+			entryPath: event.Path,
+			exitPath:  event.Path,
+		}
+		if isPageView {
+			session.pageViews = 1
+		}
+		session.elem = b.sessionOrder.PushBack(session)
+		b.sessions[event.SessionID] = session
+		return
+	}
+
+	b.sessionOrder.MoveToBack(session.elem)
+
+	// 事件可能乱序到达，只按时间先后更新入口页和退出页
+	switch {
+	case ts >= session.last:
+		session.last = ts
+		if isPageView || session.pageViews == 0 {
+			session.exitPath = event.Path
+		}
+	case ts < session.start:
+		session.start = ts
+		session.date = event.Timestamp.Format("2006-01-02")
+		if isPageView || session.pageViews == 0 {
+			session.entryPath = event.Path
+		}
+	}
+
+	if isPageView {
+		if session.pageViews == 0 {
+			// 首次页面浏览取代非页面浏览事件的路径
+			session.entryPath = event.Path
+			session.exitPath = event.Path
+		}
+		session.pageViews++
+	}
+}
+
+// CloseIdleSessions 结束超过 sessionTimeout 无新事件的会话，返回结束的会话数量。
+func (b *Buffer) CloseIdleSessions(now time.Time) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := 0
+	deadline := now.Add(-b.sessionTimeout).UnixMilli()
+	for _, session := range b.sessions {
+		if session.last >= deadline {
+			continue
+		}
+		b.closeSession(session)
+		b.removeSession(session)
+		closed++
+	}
+
+	return closed
+}
+
+// CloseAllSessions 结束所有进行中的会话（用于关闭时刷新），返回结束的会话数量。
+func (b *Buffer) CloseAllSessions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	closed := len(b.sessions)
+	for _, session := range b.sessions {
+		b.closeSession(session)
+	}
+	b.sessions = make(map[string]*sessionState)
+	b.sessionOrder.Init()
+
+	return closed
+}
+
+// removeSession 从进行中的会话中移除会话（调用方需持有写锁）。
+func (b *Buffer) removeSession(session *sessionState) {
+	delete(b.sessions, session.sid)
+	b.sessionOrder.Remove(session.elem)
+}
+
+// closeSession 将结束的会话计入会话聚合数据（调用方需持有写锁）。
+func (b *Buffer) closeSession(session *sessionState) {
+	entry := b.sessionAggregation(session.date, session.entryPath)
+	entry.Entries++
+	entry.Duration += session.last - session.start
+	if session.pageViews <= 1 {
+		entry.Bounces++
+	}
+
+	exit := b.sessionAggregation(session.date, session.exitPath)
+	exit.Exits++
+}
+
+// sessionAggregation 返回（必要时创建）date+path 对应的会话聚合条目。
+func (b *Buffer) sessionAggregation(date, path string) *SessionAggregation {
+	key := date + "|" + path
+
+	agg, exists := b.sessionAggregations[key]
+	if !exists {
+		agg = &SessionAggregation{Date: date, Path: path}
+		b.sessionAggregations[key] = agg
+	}
+
+	return agg
+}
+
+// DrainSessionAggregations 取出并清空 Session Aggregation Map。
+// 进行中的会话不受影响。
+func (b *Buffer) DrainSessionAggregations() map[string]*SessionAggregation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := b.sessionAggregations
+	b.sessionAggregations = make(map[string]*SessionAggregation)
+
+	return result
+}
+
+// RestoreSessionAggregations 将会话聚合数据放回 buffer。
+func (b *Buffer) RestoreSessionAggregations(aggs map[string]*SessionAggregation) {
+	if len(aggs) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, agg := range aggs {
+		if existing, ok := b.sessionAggregations[key]; ok {
+			existing.Entries += agg.Entries
+			existing.Exits += agg.Exits
+			existing.Bounces += agg.Bounces
+			existing.Duration += agg.Duration
+		} else {
+			b.sessionAggregations[key] = agg
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBufferSessions(t *testing.T) {
+	buffer := NewBuffer(0)
+	base := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	for _, event := range []*Event{
+		// s1: 3 次页面浏览，入口 /landing，退出 /checkout
+		{Event: "page_view", Path: "/landing", SessionID: "s1", Timestamp: base},
+		{Event: "click", Path: "/pricing", SessionID: "s1", Timestamp: base.Add(2 * time.Minute)},
+		{Event: "page_view", Path: "/pricing", SessionID: "s1", Timestamp: base.Add(time.Minute)},
+		{Event: "page_view", Path: "/checkout", SessionID: "s1", Timestamp: base.Add(3 * time.Minute)},
+		// s2: 跳出
+		{Event: "page_view", Path: "/landing", SessionID: "s2", Timestamp: base},
+		// s2 超时后的新会话
+		{Event: "page_view", Path: "/blog", SessionID: "s2", Timestamp: base.Add(time.Hour)},
+		// 无 SessionID 的事件不参与会话统计
+		{Event: "page_view", Path: "/landing", Timestamp: base},
+	} {
+		buffer.Push(event)
+	}
+
+	if n := buffer.SessionCount(); n != 2 {
+		t.Fatalf("Expected 2 open sessions, got %d", n)
+	}
+
+	// s2 的第一个会话已因超时结束
+	aggs := buffer.DrainSessionAggregations()
+	if agg := aggs["2026-01-09|/landing"]; agg == nil || agg.Entries != 1 || agg.Exits != 1 || agg.Bounces != 1 {
+		t.Fatalf("Unexpected aggregation after timeout: %+v", agg)
+	}
+	buffer.RestoreSessionAggregations(aggs)
+
+	// s1 在 base+3min 后空闲，s2 的新会话在 base+1h 开始
+	if closed := buffer.CloseIdleSessions(base.Add(40 * time.Minute)); closed != 1 {
+		t.Fatalf("Expected 1 idle session to be closed, got %d", closed)
+	}
+	if closed := buffer.CloseAllSessions(); closed != 1 {
+		t.Fatalf("Expected 1 remaining session to be closed, got %d", closed)
+	}
+
+	aggs = buffer.DrainSessionAggregations()
+
+	landing := aggs["2026-01-09|/landing"]
+	if landing == nil || landing.Entries != 2 || landing.Bounces != 1 || landing.Exits != 1 {
+		t.Fatalf("Unexpected /landing aggregation: %+v", landing)
+	}
+	if landing.Duration != (3 * time.Minute).Milliseconds() {
+		t.Fatalf("Expected /landing duration %d, got %d", (3 * time.Minute).Milliseconds(), landing.Duration)
+	}
+
+	// 非页面浏览事件（click）不影响入口页和退出页
+	checkout := aggs["2026-01-09|/checkout"]
+	if checkout == nil || checkout.Exits != 1 || checkout.Entries != 0 {
+		t.Fatalf("Unexpected /checkout aggregation: %+v", checkout)
+	}
+
+	blog := aggs["2026-01-09|/blog"]
+	if blog == nil || blog.Entries != 1 || blog.Exits != 1 || blog.Bounces != 1 || blog.Duration != 0 {
+		t.Fatalf("Unexpected /blog aggregation: %+v", blog)
+	}
+
+	if _, ok := aggs["2026-01-09|/pricing"]; ok {
+		t.Fatal("Expected /pricing to be neither an entry nor an exit page")
+	}
+}
+
+func TestBufferSessions_OutOfOrder(t *testing.T) {
+	buffer := NewBuffer(0)
+	base := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	buffer.Push(&Event{Event: "page_view", Path: "/second", SessionID: "s1", Timestamp: base.Add(time.Minute)})
+	buffer.Push(&Event{Event: "page_view", Path: "/first", SessionID: "s1", Timestamp: base})
+	buffer.CloseAllSessions()
+
+	aggs := buffer.DrainSessionAggregations()
+	if agg := aggs["2026-01-09|/first"]; agg == nil || agg.Entries != 1 || agg.Bounces != 0 {
+		t.Fatalf("Unexpected entry aggregation: %+v", agg)
+	}
+	if agg := aggs["2026-01-09|/second"]; agg == nil || agg.Exits != 1 {
+		t.Fatalf("Unexpected exit aggregation: %+v", agg)
+	}
+}
+
+func TestBufferSetSessionTimeout(t *testing.T) {
+	buffer := NewBuffer(0)
+	buffer.SetSessionTimeout(time.Minute)
+	base := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	buffer.Push(&Event{Event: "page_view", Path: "/a", SessionID: "s1", Timestamp: base})
+	buffer.Push(&Event{Event: "page_view", Path: "/b", SessionID: "s1", Timestamp: base.Add(2 * time.Minute)})
+	buffer.CloseAllSessions()
+
+	aggs := buffer.DrainSessionAggregations()
+	if agg := aggs["2026-01-09|/a"]; agg == nil || agg.Entries != 1 || agg.Exits != 1 {
+		t.Fatalf("Expected /a to be a separate session, got %+v", agg)
+	}
+	if agg := aggs["2026-01-09|/b"]; agg == nil || agg.Entries != 1 || agg.Exits != 1 {
+		t.Fatalf("Expected /b to be a separate session, got %+v", agg)
+	}
+}
+
+func TestBufferSetMaxSessions(t *testing.T) {
+	buffer := NewBuffer(0)
+	buffer.SetMaxSessions(2)
+	base := time.Date(2026, 1, 9, 10, 0, 0, 0, time.UTC)
+
+	buffer.Push(&Event{Event: "page_view", Path: "/a", SessionID: "s1", Timestamp: base})
+	buffer.Push(&Event{Event: "page_view", Path: "/b", SessionID: "s2", Timestamp: base.Add(time.Second)})
+	// s1 再次活动后，s2 成为最久未活动的会话
+	buffer.Push(&Event{Event: "page_view", Path: "/a2", SessionID: "s1", Timestamp: base.Add(2 * time.Second)})
+	buffer.Push(&Event{Event: "page_view", Path: "/c", SessionID: "s3", Timestamp: base.Add(3 * time.Second)})
+
+	if count := buffer.SessionCount(); count != 2 {
+		t.Fatalf("Expected 2 open sessions, got %d", count)
+	}
+
+	aggs := buffer.DrainSessionAggregations()
+	if len(aggs) != 1 {
+		t.Fatalf("Expected only the evicted session to be closed, got %+v", aggs)
+	}
+	if agg := aggs["2026-01-09|/b"]; agg == nil || agg.Entries != 1 || agg.Exits != 1 {
+		t.Fatalf("Expected s2 to be closed, got %+v", agg)
+	}
+
+	if closed := buffer.CloseAllSessions(); closed != 2 {
+		t.Fatalf("Expected 2 sessions to be closed, got %d", closed)
+	}
+	aggs = buffer.DrainSessionAggregations()
+	if agg := aggs["2026-01-09|/a"]; agg == nil || agg.Entries != 1 || agg.Bounces != 0 {
+		t.Fatalf("Expected s1 to be kept open, got %+v", agg)
+	}
+	if agg := aggs["2026-01-09|/c"]; agg == nil || agg.Entries != 1 {
+		t.Fatalf("Expected s3 to be kept open, got %+v", agg)
+	}
+}
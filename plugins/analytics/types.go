@@ -38,6 +38,81 @@ type DeviceStat struct {
 	Updated  types.DateTime `db:"updated" json:"updated"`
 }
 
+// EventStat 表示自定义事件统计数据（对应 _analytics_events 表）
+// PropKey 为空的行是事件总量；否则为按属性值拆分的明细
+type EventStat struct {
+	ID        string         `db:"id" json:"id"`
+	Date      string         `db:"date" json:"date"`
+	Event     string         `db:"event" json:"event"`           // 事件名称
+	PropKey   string         `db:"prop_key" json:"prop_key"`     // 拆分的属性名（空为总量）
+	PropValue string         `db:"prop_value" json:"prop_value"` // 属性值
+	Count     int64          `db:"count" json:"count"`           // 事件次数
+	HLL       []byte         `db:"hll" json:"-"`                 // HLL Sketch (二进制)
+	Visitors  int64          `db:"visitors" json:"visitors"`     // 估算的 UV 值
+	Created   types.DateTime `db:"created" json:"created"`
+	Updated   types.DateTime `db:"updated" json:"updated"`
+}
+
+// CampaignStat 表示 UTM 营销活动统计数据（对应 _analytics_campaigns 表）
+type CampaignStat struct {
+	ID       string         `db:"id" json:"id"`
+	Date     string         `db:"date" json:"date"`
+	Source   string         `db:"source" json:"source"`     // utm_source
+	Medium   string         `db:"medium" json:"medium"`     // utm_medium
+	Campaign string         `db:"campaign" json:"campaign"` // utm_campaign
+	Count    int64          `db:"count" json:"count"`       // 带 UTM 参数的事件数
+	HLL      []byte         `db:"hll" json:"-"`             // HLL Sketch (二进制)
+	Visitors int64          `db:"visitors" json:"visitors"` // 估算的 UV 值
+	Created  types.DateTime `db:"created" json:"created"`
+	Updated  types.DateTime `db:"updated" json:"updated"`
+}
+
+// GeoStat 表示地理位置统计数据（对应 _analytics_geo 表）
+type GeoStat struct {
+	ID       string         `db:"id" json:"id"`
+	Date     string         `db:"date" json:"date"`
+	Country  string         `db:"country" json:"country"`   // ISO 3166-1 国家代码
+	Region   string         `db:"region" json:"region"`     // 一级行政区
+	City     string         `db:"city" json:"city"`         // 城市
+	Count    int64          `db:"count" json:"count"`       // 事件数
+	HLL      []byte         `db:"hll" json:"-"`             // HLL Sketch (二进制)
+	Visitors int64          `db:"visitors" json:"visitors"` // 估算的 UV 值
+	Created  types.DateTime `db:"created" json:"created"`
+	Updated  types.DateTime `db:"updated" json:"updated"`
+}
+
+// SessionPageStat 表示按会话统计的页面数据（对应 _analytics_session_pages 表）
+// 会话按开始日期归档；Entries/Bounces/Duration 记在入口页，Exits 记在退出页
+type SessionPageStat struct {
+	ID       string `db:"id" json:"id"`
+	Date     string `db:"date" json:"date"`
+	Path     string `db:"path" json:"path"`
+	Entries  int64  `db:"entries" json:"entries"`   // 以该页面开始的会话数
+	Exits    int64  `db:"exits" json:"exits"`       // 以该页面结束的会话数
+	Bounces  int64  `db:"bounces" json:"bounces"`   // 以该页面开始且只有一次浏览的会话数
+	Duration int64  `db:"duration" json:"duration"` // 以该页面开始的会话总时长（毫秒）
+}
+
+// VisitStat 表示访客每天对某个事件/路径的访问记录（对应 _analytics_visits 表）
+// 用于漏斗与留存分析，每个访客每天每个 (事件, 路径) 只保存一行
+type VisitStat struct {
+	ID        string `db:"id" json:"id"`
+	Date      string `db:"date" json:"date"`
+	SessionID string `db:"sid" json:"sid"`           // 访客标识（SDK 持久化的 session ID）
+	Event     string `db:"event" json:"event"`       // 事件名称
+	Path      string `db:"path" json:"path"`         // 路径
+	FirstTs   int64  `db:"first_ts" json:"first_ts"` // 当天首次发生时间（毫秒时间戳）
+	LastTs    int64  `db:"last_ts" json:"last_ts"`   // 当天最后发生时间（毫秒时间戳）
+	Hits      int64  `db:"hits" json:"hits"`         // 当天发生次数
+}
+
+// VisitFilter 访问记录过滤条件，Event 与 Path 为空时不限制
+// Path 以 "*" 结尾时按前缀匹配，如 "/docs/*"
+type VisitFilter struct {
+	Event string `json:"event,omitempty"`
+	Path  string `json:"path,omitempty"`
+}
+
 // Aggregation 表示内存中的聚合数据
 type Aggregation struct {
 	Date     string // 日期
@@ -64,3 +139,54 @@ type DeviceAggregation struct {
 	Count   int64
 	HLL     []byte // 用于 UV 去重
 }
+
+// EventAggregation 表示内存中的自定义事件聚合数据
+type EventAggregation struct {
+	Date      string
+	Event     string
+	PropKey   string
+	PropValue string
+	Count     int64
+	HLL       []byte // 用于 UV 去重
+}
+
+// VisitAggregation 表示内存中的访客访问聚合数据
+type VisitAggregation struct {
+	Date      string
+	SessionID string
+	Event     string
+	Path      string
+	FirstTs   int64
+	LastTs    int64
+	Hits      int64
+}
+
+// CampaignAggregation 表示内存中的 UTM 营销活动聚合数据
+type CampaignAggregation struct {
+	Date     string
+	Source   string
+	Medium   string
+	Campaign string
+	Count    int64
+	HLL      []byte // 用于 UV 去重
+}
+
+// GeoAggregation 表示内存中的地理位置聚合数据
+type GeoAggregation struct {
+	Date    string
+	Country string
+	Region  string
+	City    string
+	Count   int64
+	HLL     []byte // 用于 UV 去重
+}
+
+// SessionAggregation 表示内存中已结束会话的页面聚合数据
+type SessionAggregation struct {
+	Date     string
+	Path     string
+	Entries  int64
+	Exits    int64
+	Bounces  int64
+	Duration int64 // 毫秒
+}
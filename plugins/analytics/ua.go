@@ -127,6 +127,16 @@ func IsBotUserAgent(ua string) bool {
 		"twitterbot", "linkedinbot", "pinterest", "whatsapp",
 		"telegrambot", "applebot", "semrush", "ahrefs", "mj12bot",
 		"dotbot", "petalbot", "bytespider", "gptbot", "claudebot",
+		// 无头浏览器与自动化工具
+		"headlesschrome", "phantomjs", "puppeteer", "playwright", "selenium",
+		"webdriver", "lighthouse", "pagespeed",
+		// 可用性监控
+		"pingdom", "uptimerobot", "statuscake", "site24x7", "newrelicpinger",
+		"datadog", "checkly",
+		// 命令行与 HTTP 库
+		"curl/", "wget/", "python-requests", "python-urllib", "aiohttp",
+		"go-http-client", "node-fetch", "axios/", "okhttp", "java/",
+		"libwww-perl", "httpclient",
 	}
 
 	for _, bot := range bots {
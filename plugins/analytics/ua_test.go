@@ -224,6 +224,12 @@ func TestIsBotUserAgent(t *testing.T) {
 		{"Mozilla/5.0 (compatible; ClaudeBot/1.0; +https://anthropic.com)", true},
 		{"Semrush/1.0", true},
 		{"AhrefsBot/7.0", true},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 HeadlessChrome/120.0.0.0 Safari/537.36", true},
+		{"Mozilla/5.0 (compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)", true},
+		{"Pingdom.com_bot_version_1.4", true},
+		{"curl/8.4.0", true},
+		{"python-requests/2.31.0", true},
+		{"Go-http-client/1.1", true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,54 @@
+package analytics
+
+import (
+	"net/url"
+	"strings"
+)
+
+// UTM 营销活动参数
+type UTM struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+}
+
+// maxUTMValueLength UTM 参数值的最大长度（字符数），超出部分截断
+const maxUTMValueLength = 100
+
+// ParseUTM 从查询字符串中解析 UTM 参数（可带前导 "?"）。
+// utm_source 与 utm_campaign 都不存在时返回 false。
+//
+// 示例:
+//   - "utm_source=newsletter&utm_medium=email&utm_campaign=launch" → {newsletter email launch}
+//   - "?utm_source=Twitter" → {twitter  }
+func ParseUTM(query string) (UTM, bool) {
+	query = strings.TrimPrefix(query, "?")
+	if query == "" || !strings.Contains(query, "utm_") {
+		return UTM{}, false
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil && len(values) == 0 {
+		return UTM{}, false
+	}
+
+	utm := UTM{
+		Source:   normalizeUTMValue(values.Get("utm_source")),
+		Medium:   normalizeUTMValue(values.Get("utm_medium")),
+		Campaign: normalizeUTMValue(values.Get("utm_campaign")),
+	}
+	if utm.Source == "" && utm.Campaign == "" {
+		return UTM{}, false
+	}
+
+	return utm, true
+}
+
+// normalizeUTMValue 统一大小写、去除空白并截断，避免同一来源被拆成多行
+func normalizeUTMValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if runes := []rune(value); len(runes) > maxUTMValueLength {
+		value = string(runes[:maxUTMValueLength])
+	}
+	return value
+}
@@ -0,0 +1,32 @@
+package analytics
+
+import (
+	"testing"
+)
+
+func TestParseUTM(t *testing.T) {
+	scenarios := []struct {
+		query    string
+		expected UTM
+		ok       bool
+	}{
+		{"", UTM{}, false},
+		{"page=2", UTM{}, false},
+		{"utm_medium=email", UTM{}, false},
+		{"utm_source=newsletter&utm_medium=email&utm_campaign=launch", UTM{"newsletter", "email", "launch"}, true},
+		{"?utm_source=%20Twitter%20", UTM{Source: "twitter"}, true},
+		{"utm_campaign=Spring+Sale&ref=x", UTM{Campaign: "spring sale"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.query, func(t *testing.T) {
+			utm, ok := ParseUTM(s.query)
+			if ok != s.ok {
+				t.Fatalf("Expected ok %v, got %v", s.ok, ok)
+			}
+			if utm != s.expected {
+				t.Fatalf("Expected %+v, got %+v", s.expected, utm)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogConfig 网关访问日志配置
+// 每个代理请求记录一条结构化日志，写入 app 日志存储（_logs）
+type AccessLogConfig struct {
+	// Disabled 关闭访问日志
+	Disabled bool
+
+	// SampleRate 正常请求的采样率（0-1），默认 1（全部记录）
+	// 状态码 >= 500 或上游不可达的请求不受采样影响，始终记录
+	SampleRate float64
+}
+
+// shouldLog 根据状态码和采样率判断是否记录
+func (c AccessLogConfig) shouldLog(status int) bool {
+	if c.Disabled {
+		return false
+	}
+
+	if status == 0 || status >= http.StatusInternalServerError {
+		return true
+	}
+
+	if c.SampleRate <= 0 || c.SampleRate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < c.SampleRate
+}
+
+// accessLogEntry 单个代理请求的访问日志
+type accessLogEntry struct {
+	ProxyID         string
+	ProxyPath       string
+	AuthID          string
+	Method          string
+	Path            string
+	Upstream        string // 实际选中的上游（流量拆分后）
+	Status          int
+	BytesIn         int64
+	BytesOut        int64
+	UpstreamLatency time.Duration
+	Duration        time.Duration
+	Retries         int
+	TraceID         string
+}
+
+// level 5xx 请求使用 Warn 级别，便于在日志中筛选
+func (e *accessLogEntry) level() slog.Level {
+	if e.Status == 0 || e.Status >= http.StatusInternalServerError {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// attrs 返回日志属性
+func (e *accessLogEntry) attrs() []any {
+	attrs := []any{
+		"type", "gateway",
+		"proxy_id", e.ProxyID,
+		"proxy_name", e.ProxyPath,
+		"method", e.Method,
+		"path", e.Path,
+		"upstream", e.Upstream,
+		"status", e.Status,
+		"bytes_in", e.BytesIn,
+		"bytes_out", e.BytesOut,
+		"upstream_latency_ms", e.UpstreamLatency.Milliseconds(),
+		"proxy_latency_ms", (e.Duration - e.UpstreamLatency).Milliseconds(),
+		"duration_ms", e.Duration.Milliseconds(),
+		"retries", e.Retries,
+	}
+
+	if e.AuthID != "" {
+		attrs = append(attrs, "auth_id", e.AuthID)
+	}
+	if e.TraceID != "" {
+		attrs = append(attrs, "trace_id", e.TraceID)
+	}
+
+	return attrs
+}
+
+// countingReadCloser 统计已读取的请求体字节数
+type countingReadCloser struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+// Read 实现 io.Reader
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count 返回已读取的字节数
+func (c *countingReadCloser) Count() int64 {
+	return c.n.Load()
+}
@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// TestAccessLogConfigShouldLog 测试访问日志采样
+func TestAccessLogConfigShouldLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AccessLogConfig
+		status int
+		want   bool
+	}{
+		{"default logs all", AccessLogConfig{}, 200, true},
+		{"disabled", AccessLogConfig{Disabled: true}, 500, false},
+		{"sampled out", AccessLogConfig{SampleRate: 0.0000001}, 200, false},
+		{"errors always logged", AccessLogConfig{SampleRate: 0.0000001}, 502, true},
+		{"upstream unreachable always logged", AccessLogConfig{SampleRate: 0.0000001}, 0, true},
+		{"client errors sampled", AccessLogConfig{SampleRate: 0.0000001}, 404, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.shouldLog(tt.status); got != tt.want {
+				t.Errorf("shouldLog(%d) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAccessLogEntryAttrs 测试访问日志字段
+func TestAccessLogEntryAttrs(t *testing.T) {
+	entry := &accessLogEntry{
+		ProxyID:         "p1",
+		ProxyPath:       "/-/api",
+		AuthID:          "user1",
+		Method:          "POST",
+		Path:            "/-/api/chat",
+		Upstream:        "http://canary",
+		Status:          201,
+		BytesIn:         12,
+		BytesOut:        34,
+		UpstreamLatency: 80 * time.Millisecond,
+		Duration:        100 * time.Millisecond,
+		Retries:         1,
+		TraceID:         "trace1",
+	}
+
+	attrs := entry.attrs()
+	values := make(map[string]any, len(attrs)/2)
+	for i := 0; i < len(attrs); i += 2 {
+		values[attrs[i].(string)] = attrs[i+1]
+	}
+
+	expected := map[string]any{
+		"proxy_id":            "p1",
+		"auth_id":             "user1",
+		"upstream":            "http://canary",
+		"status":              201,
+		"bytes_in":            int64(12),
+		"bytes_out":           int64(34),
+		"upstream_latency_ms": int64(80),
+		"proxy_latency_ms":    int64(20),
+		"retries":             1,
+		"trace_id":            "trace1",
+	}
+	for key, want := range expected {
+		if values[key] != want {
+			t.Errorf("%s = %v, want %v", key, values[key], want)
+		}
+	}
+
+	if entry.level() != slog.LevelInfo {
+		t.Errorf("level = %v, want Info", entry.level())
+	}
+	entry.Status = 503
+	if entry.level() != slog.LevelWarn {
+		t.Errorf("level = %v, want Warn", entry.level())
+	}
+}
+
+// TestResponseWriterBytesWritten 测试响应字节统计
+func TestResponseWriterBytesWritten(t *testing.T) {
+	rw := newResponseWriter(httptest.NewRecorder())
+	rw.Write([]byte("hello"))
+	rw.Write([]byte(" world"))
+
+	if rw.BytesWritten() != 11 {
+		t.Errorf("BytesWritten() = %d, want 11", rw.BytesWritten())
+	}
+}
+
+// TestServeProxyAccessInfo 测试代理请求的字节统计与 traceparent 传播
+func TestServeProxyAccessInfo(t *testing.T) {
+	var gotTraceparent string
+	var gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceparent = r.Header.Get(TraceparentHeader)
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: upstream.URL, StripPath: true, Active: true}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	plugin := &gatewayPlugin{manager: m, config: Config{AccessLog: AccessLogConfig{Disabled: true}}}
+
+	incoming := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	req := httptest.NewRequest(http.MethodPost, "/-/api/chat", strings.NewReader("payload"))
+	req.Header.Set(TraceparentHeader, incoming)
+	rec := httptest.NewRecorder()
+
+	e := &core.RequestEvent{}
+	e.Request = req
+	e.Response = rec
+
+	plugin.serveProxy(e, proxy, nil)
+
+	if rec.Code != http.StatusOK || gotBody != "payload" {
+		t.Fatalf("status = %d, body = %q", rec.Code, gotBody)
+	}
+
+	if !strings.HasPrefix(gotTraceparent, "00-0af7651916cd43dd8448eb211c80319c-") || !strings.HasSuffix(gotTraceparent, "-01") {
+		t.Errorf("traceparent = %q, want same trace id", gotTraceparent)
+	}
+	if gotTraceparent == incoming {
+		t.Error("traceparent parent-id should be the gateway client span")
+	}
+
+	// 原始请求头不应被修改
+	if req.Header.Get(TraceparentHeader) != incoming {
+		t.Errorf("incoming traceparent was modified: %q", req.Header.Get(TraceparentHeader))
+	}
+}
@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand 创建 `gateway` 命令，用于管理声明式代理配置（export、apply、validate）
+//
+// 使用方式：
+//
+//	app.RootCmd.AddCommand(gateway.NewCommand(app, gatewayConfig))
+func NewCommand(app core.App, config Config) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "gateway",
+		Short: "Manage the declarative gateway proxies config",
+	}
+
+	command.AddCommand(gatewayExportCommand(app))
+	command.AddCommand(gatewayApplyCommand(app, config))
+	command.AddCommand(gatewayValidateCommand(config))
+
+	return command
+}
+
+func gatewayExportCommand(app core.App) *cobra.Command {
+	var format string
+	var output string
+
+	command := &cobra.Command{
+		Use:          "export",
+		Example:      "gateway export --format=yaml --output=pb_gateway.yaml",
+		Short:        "Exports the _proxies records as a declarative config file",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if output != "" && !command.Flags().Changed("format") {
+				format = formatFromPath(output)
+			}
+			if format != "yaml" && format != "json" {
+				return fmt.Errorf("unsupported format %q (expected yaml or json)", format)
+			}
+
+			file, err := ExportProxies(app)
+			if err != nil {
+				return fmt.Errorf("failed to export proxies: %w", err)
+			}
+
+			data, err := file.Marshal(format)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				_, err = command.OutOrStdout().Write(data)
+				return err
+			}
+
+			if err := os.WriteFile(output, data, 0644); err != nil {
+				return err
+			}
+
+			color.Green("Successfully exported %d proxies to %q!", len(file.Proxies), output)
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&format, "format", "yaml", "the output format (yaml or json)")
+	command.Flags().StringVarP(&output, "output", "o", "", "the output file (default: stdout)")
+
+	return command
+}
+
+func gatewayApplyCommand(app core.App, config Config) *cobra.Command {
+	var dryRun bool
+	var prune bool
+
+	command := &cobra.Command{
+		Use:          "apply [file]",
+		Example:      "gateway apply pb_gateway.yaml --dry-run",
+		Short:        "Syncs the _proxies records with a declarative config file",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			path, err := configFileFromArgs(args, config)
+			if err != nil {
+				return err
+			}
+
+			file, err := LoadGatewayFile(path)
+			if err != nil {
+				return fmt.Errorf("invalid config file %q:\n%w", path, err)
+			}
+
+			plan, err := PlanSync(app, file, prune || config.PruneProxies)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(command.OutOrStdout(), plan.String())
+
+			if dryRun || !plan.HasChanges() {
+				return nil
+			}
+
+			if err := plan.Apply(app); err != nil {
+				return err
+			}
+
+			color.Green("Successfully applied %q!", path)
+			return nil
+		},
+	}
+
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "only print the changes without applying them")
+	command.Flags().BoolVar(&prune, "prune", false, "delete the proxies that are not declared in the config file")
+
+	return command
+}
+
+func gatewayValidateCommand(config Config) *cobra.Command {
+	command := &cobra.Command{
+		Use:          "validate [file]",
+		Example:      "gateway validate pb_gateway.yaml",
+		Short:        "Validates a declarative config file without touching the database",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			path, err := configFileFromArgs(args, config)
+			if err != nil {
+				return err
+			}
+
+			file, err := LoadGatewayFile(path)
+			if err != nil {
+				return fmt.Errorf("invalid config file %q:\n%w", path, err)
+			}
+
+			color.Green("%q is valid (%d proxies)", path, len(file.Proxies))
+			return nil
+		},
+	}
+
+	return command
+}
+
+// configFileFromArgs 返回命令参数中的配置文件，未指定时使用插件配置或默认文件
+func configFileFromArgs(args []string, config Config) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	if path := ResolveConfigFile(config.ConfigFile); path != "" {
+		return path, nil
+	}
+
+	return "", errors.New("missing config file argument (no " + strings.Join(DefaultConfigFiles, ", ") + " found)")
+}
+
+// formatFromPath 根据文件扩展名推断导出格式
+func formatFromPath(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return "json"
+	}
+	return "yaml"
+}
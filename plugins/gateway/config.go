@@ -25,6 +25,16 @@ const (
 	ProxyFieldMaxConcurrent  = "maxConcurrent"  // 最大并发数 (FR-008)
 	ProxyFieldCircuitBreaker = "circuitBreaker" // 熔断器配置 (FR-012)
 	ProxyFieldTimeoutConfig  = "timeoutConfig"  // 精细超时配置
+
+	// 请求/响应变换字段
+	ProxyFieldTransform = "transform" // 变换配置 (JSON)
+
+	// 上游认证字段
+	ProxyFieldUpstreamAuth = "upstreamAuth" // 上游认证配置 (JSON)
+
+	// 重试与流量管理字段
+	ProxyFieldRetryPolicy = "retryPolicy" // 重试/对冲配置 (JSON)
+	ProxyFieldTraffic     = "traffic"     // 流量拆分/镜像配置 (JSON)
 )
 
 // DefaultTimeout 默认超时时间（秒）
@@ -56,6 +66,28 @@ type ProxyConfig struct {
 	// TimeoutConfig 精细超时配置
 	// nil 表示使用默认值
 	TimeoutConfig *TimeoutConfig `json:"timeout_config"`
+
+	// --- 请求/响应变换 ---
+
+	// Transform 请求/响应变换配置
+	// nil 表示不做变换
+	Transform *TransformConfig `json:"transform"`
+
+	// --- 上游认证 ---
+
+	// UpstreamAuth 上游认证配置（OAuth2、HMAC 签名、mTLS）
+	// nil 表示不做额外认证
+	UpstreamAuth *UpstreamAuthConfig `json:"upstream_auth"`
+
+	// --- 重试与流量管理 ---
+
+	// Retry 重试与对冲请求配置
+	// nil 表示不重试
+	Retry *RetryConfig `json:"retry"`
+
+	// Traffic 流量拆分与镜像配置
+	// nil 表示全部流量转发到 Upstream
+	Traffic *TrafficConfig `json:"traffic"`
 }
 
 // NewProxyConfig 创建一个带默认值的代理配置
@@ -0,0 +1,362 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigFiles 未指定 Config.ConfigFile 时按顺序查找的声明式配置文件
+var DefaultConfigFiles = []string{"pb_gateway.yaml", "pb_gateway.yml", "pb_gateway.json"}
+
+// GatewayFile 声明式网关配置文件（YAML 或 JSON）
+// 字段名与 _proxies collection 的字段一致，便于在环境之间迁移配置
+//
+// 示例（YAML）：
+//
+//	proxies:
+//	  - path: /-/openai
+//	    upstream: https://api.openai.com
+//	    accessRule: "@request.auth.id != ''"
+//	    headers:
+//	      Authorization: "Bearer {secret.OPENAI_API_KEY}"
+//	    retryPolicy:
+//	      max_attempts: 3
+type GatewayFile struct {
+	Proxies []*ProxyDefinition `json:"proxies"`
+}
+
+// ProxyDefinition 配置文件中的单个代理定义
+// 未声明的可选字段使用与 NewProxyConfig 相同的默认值
+type ProxyDefinition struct {
+	Path           string                `json:"path"`
+	Upstream       string                `json:"upstream"`
+	StripPath      *bool                 `json:"stripPath,omitempty"` // 默认 true
+	AccessRule     string                `json:"accessRule,omitempty"`
+	Headers        map[string]string     `json:"headers,omitempty"`
+	Timeout        int                   `json:"timeout,omitempty"` // 默认 DefaultTimeout
+	Active         *bool                 `json:"active,omitempty"`  // 默认 true
+	MaxConcurrent  int                   `json:"maxConcurrent,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `json:"circuitBreaker,omitempty"`
+	TimeoutConfig  *TimeoutConfig        `json:"timeoutConfig,omitempty"`
+	Transform      *TransformConfig      `json:"transform,omitempty"`
+	UpstreamAuth   *UpstreamAuthConfig   `json:"upstreamAuth,omitempty"`
+	RetryPolicy    *RetryConfig          `json:"retryPolicy,omitempty"`
+	Traffic        *TrafficConfig        `json:"traffic,omitempty"`
+}
+
+// EntryError 指向配置文件中具体代理条目的错误
+type EntryError struct {
+	Index int    // 条目在 proxies 数组中的下标
+	Path  string // 条目的 path（可能为空）
+	Err   error
+}
+
+// Error 实现 error 接口，格式如 `proxies[2] (/-/openai): upstream: host cannot be empty`
+func (e *EntryError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("proxies[%d]: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("proxies[%d] (%s): %v", e.Index, e.Path, e.Err)
+}
+
+// Unwrap 返回原始错误
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveConfigFile 返回实际使用的配置文件路径
+// 显式指定时原样返回；否则返回 DefaultConfigFiles 中第一个存在的文件，均不存在时返回空
+func ResolveConfigFile(configFile string) string {
+	if configFile != "" {
+		return configFile
+	}
+
+	for _, name := range DefaultConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// LoadGatewayFile 读取并校验声明式配置文件
+// 根据扩展名选择格式：.yaml/.yml 为 YAML，其余按 JSON 解析
+func LoadGatewayFile(path string) (*GatewayFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseGatewayFile(data, "yaml")
+	default:
+		return ParseGatewayFile(data, "json")
+	}
+}
+
+// ParseGatewayFile 解析并校验配置内容，format 为 "yaml" 或 "json"
+// 每个条目单独解码，未知字段和类型错误都会定位到具体条目
+func ParseGatewayFile(data []byte, format string) (*GatewayFile, error) {
+	if format == "yaml" {
+		converted, err := yamlToJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		data = converted
+	}
+
+	var raw struct {
+		Proxies []json.RawMessage `json:"proxies"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		return nil, err
+	}
+
+	file := &GatewayFile{Proxies: make([]*ProxyDefinition, 0, len(raw.Proxies))}
+	var errs []error
+	for i, item := range raw.Proxies {
+		def := &ProxyDefinition{}
+
+		decoder := json.NewDecoder(bytes.NewReader(item))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(def); err != nil {
+			errs = append(errs, &EntryError{Index: i, Path: rawEntryPath(item), Err: err})
+			continue
+		}
+
+		file.Proxies = append(file.Proxies, def)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// rawEntryPath 尽力从解码失败的条目中读取 path，便于定位错误
+func rawEntryPath(item json.RawMessage) string {
+	var entry struct {
+		Path string `json:"path"`
+	}
+	json.Unmarshal(item, &entry)
+	return entry.Path
+}
+
+// Validate 校验所有条目，返回的错误包含每个出错条目的下标和 path
+func (f *GatewayFile) Validate() error {
+	var errs []error
+
+	seen := make(map[string]int, len(f.Proxies))
+	for i, def := range f.Proxies {
+		if err := def.Validate(); err != nil {
+			errs = append(errs, &EntryError{Index: i, Path: def.Path, Err: err})
+			continue
+		}
+
+		if prev, ok := seen[def.Path]; ok {
+			errs = append(errs, &EntryError{
+				Index: i,
+				Path:  def.Path,
+				Err:   fmt.Errorf("duplicate path, already defined by proxies[%d]", prev),
+			})
+			continue
+		}
+		seen[def.Path] = i
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate 校验单个代理定义
+// 规则与 _proxies collection 的字段约束及 pbGatewayValidatePath hook 保持一致
+func (d *ProxyDefinition) Validate() error {
+	if d == nil {
+		return errors.New("entry cannot be null")
+	}
+
+	if err := ValidateProxyPath(d.Path); err != nil {
+		return fmt.Errorf("path: %w", err)
+	}
+
+	if err := validateUpstreamURL(d.Upstream); err != nil {
+		return fmt.Errorf("upstream: %w", err)
+	}
+
+	if d.Timeout < 0 || d.Timeout > 300 {
+		return errors.New("timeout must be between 1 and 300 seconds (0 uses the default)")
+	}
+
+	if d.MaxConcurrent < 0 || d.MaxConcurrent > 10000 {
+		return errors.New("maxConcurrent must be between 0 and 10000")
+	}
+
+	if err := d.Transform.Validate(); err != nil {
+		return err
+	}
+
+	if err := d.UpstreamAuth.Validate(); err != nil {
+		return err
+	}
+
+	if err := d.RetryPolicy.Validate(); err != nil {
+		return err
+	}
+
+	return d.Traffic.Validate()
+}
+
+// recordValues 返回写入 _proxies 记录的字段值（已填充默认值）
+func (d *ProxyDefinition) recordValues() map[string]any {
+	stripPath := true
+	if d.StripPath != nil {
+		stripPath = *d.StripPath
+	}
+
+	active := true
+	if d.Active != nil {
+		active = *d.Active
+	}
+
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	var headers map[string]string
+	if len(d.Headers) > 0 {
+		headers = d.Headers
+	}
+
+	return map[string]any{
+		ProxyFieldPath:           d.Path,
+		ProxyFieldUpstream:       d.Upstream,
+		ProxyFieldStripPath:      stripPath,
+		ProxyFieldAccessRule:     d.AccessRule,
+		ProxyFieldHeaders:        headers,
+		ProxyFieldTimeout:        timeout,
+		ProxyFieldActive:         active,
+		ProxyFieldMaxConcurrent:  d.MaxConcurrent,
+		ProxyFieldCircuitBreaker: d.CircuitBreaker,
+		ProxyFieldTimeoutConfig:  d.TimeoutConfig,
+		ProxyFieldTransform:      d.Transform,
+		ProxyFieldUpstreamAuth:   d.UpstreamAuth,
+		ProxyFieldRetryPolicy:    d.RetryPolicy,
+		ProxyFieldTraffic:        d.Traffic,
+	}
+}
+
+// definitionFromRecord 将 _proxies 记录转换为代理定义
+func definitionFromRecord(record *core.Record) *ProxyDefinition {
+	stripPath := record.GetBool(ProxyFieldStripPath)
+	active := record.GetBool(ProxyFieldActive)
+
+	def := &ProxyDefinition{
+		Path:          record.GetString(ProxyFieldPath),
+		Upstream:      record.GetString(ProxyFieldUpstream),
+		StripPath:     &stripPath,
+		AccessRule:    record.GetString(ProxyFieldAccessRule),
+		Timeout:       record.GetInt(ProxyFieldTimeout),
+		Active:        &active,
+		MaxConcurrent: record.GetInt(ProxyFieldMaxConcurrent),
+	}
+
+	// JSON 字段为 null 或空时保持 nil
+	record.UnmarshalJSONField(ProxyFieldHeaders, &def.Headers)
+	record.UnmarshalJSONField(ProxyFieldCircuitBreaker, &def.CircuitBreaker)
+	record.UnmarshalJSONField(ProxyFieldTimeoutConfig, &def.TimeoutConfig)
+	record.UnmarshalJSONField(ProxyFieldTransform, &def.Transform)
+	record.UnmarshalJSONField(ProxyFieldUpstreamAuth, &def.UpstreamAuth)
+	record.UnmarshalJSONField(ProxyFieldRetryPolicy, &def.RetryPolicy)
+	record.UnmarshalJSONField(ProxyFieldTraffic, &def.Traffic)
+
+	return def
+}
+
+// ExportProxies 将 _proxies 中的所有代理导出为声明式配置（按 path 排序）
+func ExportProxies(app core.App) (*GatewayFile, error) {
+	records, err := app.FindAllRecords(CollectionNameProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &GatewayFile{Proxies: make([]*ProxyDefinition, 0, len(records))}
+	for _, record := range records {
+		file.Proxies = append(file.Proxies, definitionFromRecord(record))
+	}
+
+	sort.Slice(file.Proxies, func(i, j int) bool {
+		return file.Proxies[i].Path < file.Proxies[j].Path
+	})
+
+	return file, nil
+}
+
+// Marshal 按指定格式（"yaml" 或 "json"）序列化配置
+func (f *GatewayFile) Marshal(format string) ([]byte, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if format != "yaml" {
+		return append(data, '\n'), nil
+	}
+
+	// JSON 是 YAML 的子集，借助 MapSlice 保留字段顺序
+	var ordered yaml.MapSlice
+	if err := yaml.Unmarshal(data, &ordered); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(ordered)
+}
+
+// yamlToJSON 将 YAML 内容转换为 JSON，以复用 json tag 的解码和校验逻辑
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(normalizeYAMLValue(v))
+}
+
+// normalizeYAMLValue 将 yaml.v2 解码出的 map[interface{}]interface{} 转换为 map[string]any
+func normalizeYAMLValue(v any) any {
+	switch val := v.(type) {
+	case map[any]any:
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			result[fmt.Sprint(k)] = normalizeYAMLValue(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = normalizeYAMLValue(item)
+		}
+		return result
+	default:
+		return val
+	}
+}
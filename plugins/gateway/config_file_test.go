@@ -0,0 +1,205 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseGatewayFileYAML 测试解析 YAML 配置
+func TestParseGatewayFileYAML(t *testing.T) {
+	data := []byte(`
+proxies:
+  - path: /-/openai
+    upstream: https://api.openai.com
+    accessRule: "@request.auth.id != ''"
+    headers:
+      Authorization: "Bearer {secret.OPENAI_API_KEY}"
+    retryPolicy:
+      max_attempts: 2
+      retry_on: [502, 503]
+  - path: /-/local
+    upstream: http://127.0.0.1:8001
+    stripPath: false
+    timeout: 120
+`)
+
+	file, err := ParseGatewayFile(data, "yaml")
+	if err != nil {
+		t.Fatalf("ParseGatewayFile() error = %v", err)
+	}
+
+	if len(file.Proxies) != 2 {
+		t.Fatalf("len(Proxies) = %d, want 2", len(file.Proxies))
+	}
+
+	openai := file.Proxies[0]
+	if openai.Headers["Authorization"] != "Bearer {secret.OPENAI_API_KEY}" {
+		t.Errorf("Headers = %v", openai.Headers)
+	}
+	if openai.RetryPolicy == nil || openai.RetryPolicy.MaxAttempts != 2 || len(openai.RetryPolicy.RetryOn) != 2 {
+		t.Errorf("RetryPolicy = %+v", openai.RetryPolicy)
+	}
+
+	local := file.Proxies[1]
+	if local.StripPath == nil || *local.StripPath {
+		t.Errorf("StripPath = %v, want false", local.StripPath)
+	}
+	if local.Timeout != 120 {
+		t.Errorf("Timeout = %d, want 120", local.Timeout)
+	}
+}
+
+// TestParseGatewayFileErrors 测试校验错误指向具体条目
+func TestParseGatewayFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		contains []string
+	}{
+		{
+			name:     "invalid path",
+			format:   "json",
+			data:     `{"proxies":[{"path":"/-/ok","upstream":"http://a"},{"path":"/api/x","upstream":"http://b"}]}`,
+			contains: []string{"proxies[1] (/api/x)", "path:"},
+		},
+		{
+			name:     "invalid upstream",
+			format:   "yaml",
+			data:     "proxies:\n  - path: /-/a\n    upstream: ftp://a\n",
+			contains: []string{"proxies[0] (/-/a)", "upstream:"},
+		},
+		{
+			name:     "duplicate path",
+			format:   "json",
+			data:     `{"proxies":[{"path":"/-/a","upstream":"http://a"},{"path":"/-/a","upstream":"http://b"}]}`,
+			contains: []string{"proxies[1] (/-/a)", "already defined by proxies[0]"},
+		},
+		{
+			name:     "unknown field",
+			format:   "yaml",
+			data:     "proxies:\n  - path: /-/a\n    upstream: http://a\n    upstrem: http://b\n",
+			contains: []string{"proxies[0] (/-/a)", "upstrem"},
+		},
+		{
+			name:     "wrong type",
+			format:   "json",
+			data:     `{"proxies":[{"path":"/-/a","upstream":"http://a","timeout":"slow"}]}`,
+			contains: []string{"proxies[0] (/-/a)", "timeout"},
+		},
+		{
+			name:     "nested config",
+			format:   "json",
+			data:     `{"proxies":[{"path":"/-/a","upstream":"http://a","traffic":{"sticky":"cookie"}}]}`,
+			contains: []string{"proxies[0] (/-/a)", "traffic.sticky"},
+		},
+		{
+			name:     "multiple entries",
+			format:   "json",
+			data:     `{"proxies":[{"path":"","upstream":"http://a"},{"path":"/-/b","upstream":""}]}`,
+			contains: []string{"proxies[0]:", "proxies[1] (/-/b)"},
+		},
+		{
+			name:     "json syntax",
+			format:   "json",
+			data:     "{\n\"proxies\": [\n{\"path\": }\n]}",
+			contains: []string{"line 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGatewayFile([]byte(tt.data), tt.format)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(err.Error(), s) {
+					t.Errorf("error %q does not contain %q", err.Error(), s)
+				}
+			}
+		})
+	}
+}
+
+// TestLoadGatewayFileByExtension 测试根据扩展名选择格式
+func TestLoadGatewayFileByExtension(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "pb_gateway.yml")
+	os.WriteFile(yamlPath, []byte("proxies:\n  - path: /-/a\n    upstream: http://a\n"), 0644)
+
+	jsonPath := filepath.Join(dir, "pb_gateway.json")
+	os.WriteFile(jsonPath, []byte(`{"proxies":[{"path":"/-/b","upstream":"http://b"}]}`), 0644)
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		file, err := LoadGatewayFile(path)
+		if err != nil {
+			t.Fatalf("LoadGatewayFile(%s) error = %v", path, err)
+		}
+		if len(file.Proxies) != 1 {
+			t.Errorf("LoadGatewayFile(%s) len = %d, want 1", path, len(file.Proxies))
+		}
+	}
+
+	if _, err := LoadGatewayFile(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+// TestGatewayFileMarshalRoundTrip 测试导出格式可以被重新解析
+func TestGatewayFileMarshalRoundTrip(t *testing.T) {
+	stripPath := false
+	file := &GatewayFile{Proxies: []*ProxyDefinition{
+		{
+			Path:      "/-/a",
+			Upstream:  "http://a",
+			StripPath: &stripPath,
+			Headers:   map[string]string{"X-Key": "{env.KEY}"},
+			Traffic: &TrafficConfig{
+				Splits: []WeightedUpstream{
+					{Upstream: "http://a1", Weight: 90},
+					{Upstream: "http://a2", Weight: 10},
+				},
+			},
+		},
+	}}
+
+	for _, format := range []string{"yaml", "json"} {
+		data, err := file.Marshal(format)
+		if err != nil {
+			t.Fatalf("Marshal(%s) error = %v", format, err)
+		}
+
+		parsed, err := ParseGatewayFile(data, format)
+		if err != nil {
+			t.Fatalf("ParseGatewayFile(%s) error = %v\n%s", format, err, data)
+		}
+
+		fields, err := diffDefinitions(file.Proxies[0], parsed.Proxies[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fields) > 0 {
+			t.Errorf("%s round trip changed fields %v\n%s", format, fields, data)
+		}
+	}
+}
+
+// TestProxyDefinitionDefaults 测试未声明字段使用默认值
+func TestProxyDefinitionDefaults(t *testing.T) {
+	def := &ProxyDefinition{Path: "/-/a", Upstream: "http://a"}
+	values := def.recordValues()
+
+	if values[ProxyFieldStripPath] != true {
+		t.Errorf("stripPath = %v, want true", values[ProxyFieldStripPath])
+	}
+	if values[ProxyFieldActive] != true {
+		t.Errorf("active = %v, want true", values[ProxyFieldActive])
+	}
+	if values[ProxyFieldTimeout] != DefaultTimeout {
+		t.Errorf("timeout = %v, want %d", values[ProxyFieldTimeout], DefaultTimeout)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 )
 
 // DefaultFlushInterval SSE 流式响应的默认刷新间隔
@@ -39,6 +40,17 @@ type Config struct {
 	// TransportConfig 自定义 Transport 配置（可选）
 	// 默认使用 DefaultTransportConfig()
 	TransportConfig *TransportConfig
+
+	// ConfigFile 声明式代理配置文件（YAML 或 JSON），serve 启动时同步到 _proxies
+	// 为空时依次查找 DefaultConfigFiles，文件不存在时跳过
+	ConfigFile string
+
+	// PruneProxies 同步时删除配置文件中未声明的代理
+	// 默认 false，仅新增和更新，不影响在 Admin UI 中手动创建的代理
+	PruneProxies bool
+
+	// AccessLog 代理请求访问日志配置（默认全部记录）
+	AccessLog AccessLogConfig
 }
 
 // gatewayPlugin 插件实例
@@ -85,6 +97,8 @@ func (p *gatewayPlugin) register() error {
 		managerConfig := ManagerConfig{
 			// T035, T052: 使用全局 BytesPool
 			BufferPool: DefaultBytesPool(),
+			// 上游认证配置中的 {secret.X} 模板
+			SecretGetter: p.createSecretGetter(),
 		}
 
 		// 配置 Transport
@@ -97,6 +111,7 @@ func (p *gatewayPlugin) register() error {
 		// T052: 启用 Metrics
 		if p.config.EnableMetrics {
 			managerConfig.Metrics = NewMetricsCollector()
+			metrics.GetRegistry(p.app).Register("gateway", managerConfig.Metrics)
 		}
 
 		// 创建 Manager
@@ -113,8 +128,10 @@ func (p *gatewayPlugin) register() error {
 	// 2. 注册 Hot Reload Hooks
 	p.registerHooks()
 
-	// 3. 注册路由
+	// 3. 同步声明式配置文件并注册路由
+	// 只在 serve 时同步，避免 `gateway apply --dry-run` 等命令在比较前被提前落库
 	p.app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		p.syncConfigFile()
 		p.registerRoutes(e)
 		return e.Next()
 	})
@@ -170,6 +187,58 @@ func (p *gatewayPlugin) loadProxies() error {
 			config.TimeoutConfig = &tcConfig
 		}
 
+		// transform JSON
+		var transform TransformConfig
+		if err := record.UnmarshalJSONField(ProxyFieldTransform, &transform); err == nil {
+			if err := transform.Validate(); err != nil {
+				p.app.Logger().Warn("invalid proxy transform config, ignored",
+					"proxy", config.Path,
+					"error", err,
+				)
+			} else if transform.Request != nil || transform.Response != nil {
+				config.Transform = &transform
+			}
+		}
+
+		// upstreamAuth JSON
+		var upstreamAuth UpstreamAuthConfig
+		if err := record.UnmarshalJSONField(ProxyFieldUpstreamAuth, &upstreamAuth); err == nil {
+			if err := upstreamAuth.Validate(); err != nil {
+				p.app.Logger().Warn("invalid proxy upstream auth config, ignored",
+					"proxy", config.Path,
+					"error", err,
+				)
+			} else if upstreamAuth.Type != "" || !upstreamAuth.TLS.IsEmpty() {
+				config.UpstreamAuth = &upstreamAuth
+			}
+		}
+
+		// retryPolicy JSON
+		var retry RetryConfig
+		if err := record.UnmarshalJSONField(ProxyFieldRetryPolicy, &retry); err == nil {
+			if err := retry.Validate(); err != nil {
+				p.app.Logger().Warn("invalid proxy retry policy, ignored",
+					"proxy", config.Path,
+					"error", err,
+				)
+			} else if retry.IsEnabled() {
+				config.Retry = &retry
+			}
+		}
+
+		// traffic JSON
+		var traffic TrafficConfig
+		if err := record.UnmarshalJSONField(ProxyFieldTraffic, &traffic); err == nil {
+			if err := traffic.Validate(); err != nil {
+				p.app.Logger().Warn("invalid proxy traffic config, ignored",
+					"proxy", config.Path,
+					"error", err,
+				)
+			} else if len(traffic.Splits) > 0 || traffic.Mirror != nil {
+				config.Traffic = &traffic
+			}
+		}
+
 		configs = append(configs, config)
 	}
 
@@ -15,6 +15,7 @@ type responseWriter struct {
 	http.ResponseWriter
 	statusCode      int
 	written         bool
+	bytesWritten    int64         // 已写入的响应体字节数（访问日志）
 	upstreamLatency time.Duration // T045: 记录上游延迟
 }
 
@@ -43,7 +44,9 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 		rw.statusCode = http.StatusOK
 		rw.written = true
 	}
-	return rw.ResponseWriter.Write(b)
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(n)
+	return n, err
 }
 
 // Flush implements http.Flusher for streaming support (e.g. SSE/LLM token stream).
@@ -67,6 +70,11 @@ func (rw *responseWriter) StatusCode() int {
 	return rw.statusCode
 }
 
+// BytesWritten 返回已写入的响应体字节数
+func (rw *responseWriter) BytesWritten() int64 {
+	return rw.bytesWritten
+}
+
 // UpstreamLatency 返回记录的上游延迟
 // T045: 用于记录 upstream_latency_ms
 func (rw *responseWriter) UpstreamLatency() time.Duration {
@@ -19,6 +19,9 @@ var (
 
 	// @request.auth.* - 用户上下文
 	authVarRegex = regexp.MustCompile(`@request\.auth\.([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+	// {auth.FIELD} - 用户上下文（@request.auth.* 的花括号写法）
+	authBraceVarRegex = regexp.MustCompile(`\{auth\.([a-zA-Z_][a-zA-Z0-9_]*)\}`)
 )
 
 // ParseHeaderTemplate 解析请求头模板，替换变量
@@ -27,6 +30,7 @@ var (
 // - {env.VAR_NAME}: 从环境变量读取
 // - {secret.VAR_NAME}: 从 secrets 获取（通过 SecretGetter）
 // - @request.auth.field: 从当前认证用户读取字段
+// - {auth.field}: 同 @request.auth.field
 func ParseHeaderTemplate(template string, authInfo *AuthInfo, secretGetter SecretGetter) (string, error) {
 	if template == "" {
 		return "", nil
@@ -117,13 +121,19 @@ func replaceSecretVars(template string, secretGetter SecretGetter) (string, erro
 
 // replaceAuthVars 替换认证上下文变量
 func replaceAuthVars(template string, authInfo *AuthInfo) string {
+	result := replaceAuthVarsWithRegex(template, authVarRegex, authInfo)
+	return replaceAuthVarsWithRegex(result, authBraceVarRegex, authInfo)
+}
+
+// replaceAuthVarsWithRegex 使用指定的正则替换认证上下文变量
+func replaceAuthVarsWithRegex(template string, regex *regexp.Regexp, authInfo *AuthInfo) string {
 	if authInfo == nil {
-		// 无认证时，清空所有 @request.auth.* 变量
-		return authVarRegex.ReplaceAllString(template, "")
+		// 无认证时，清空所有认证变量
+		return regex.ReplaceAllString(template, "")
 	}
 
-	return authVarRegex.ReplaceAllStringFunc(template, func(match string) string {
-		matches := authVarRegex.FindStringSubmatch(match)
+	return regex.ReplaceAllStringFunc(template, func(match string) string {
+		matches := regex.FindStringSubmatch(match)
 		if len(matches) < 2 {
 			return match
 		}
@@ -168,6 +168,12 @@ func TestReplaceAuthVars(t *testing.T) {
 			ID:     "user123",
 			Fields: map[string]any{"email": "test@example.com"},
 		}, "user123-test@example.com"},
+		{"brace auth id", "{auth.id}", &AuthInfo{ID: "user123"}, "user123"},
+		{"brace nil auth", "{auth.id}", nil, ""},
+		{"mixed syntax", "@request.auth.id:{auth.email}", &AuthInfo{
+			ID:     "user123",
+			Fields: map[string]any{"email": "test@example.com"},
+		}, "user123:test@example.com"},
 	}
 
 	for _, tt := range tests {
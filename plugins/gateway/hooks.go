@@ -17,6 +17,37 @@ func (p *gatewayPlugin) registerHooks() {
 			if err := ValidateProxyPath(path); err != nil {
 				return err
 			}
+
+			// 校验变换配置（如正则表达式是否合法）
+			var transform TransformConfig
+			if err := e.Record.UnmarshalJSONField(ProxyFieldTransform, &transform); err == nil {
+				if err := transform.Validate(); err != nil {
+					return err
+				}
+			}
+
+			// 校验上游认证配置
+			var upstreamAuth UpstreamAuthConfig
+			if err := e.Record.UnmarshalJSONField(ProxyFieldUpstreamAuth, &upstreamAuth); err == nil {
+				if err := upstreamAuth.Validate(); err != nil {
+					return err
+				}
+			}
+
+			// 校验重试与流量配置
+			var retry RetryConfig
+			if err := e.Record.UnmarshalJSONField(ProxyFieldRetryPolicy, &retry); err == nil {
+				if err := retry.Validate(); err != nil {
+					return err
+				}
+			}
+			var traffic TrafficConfig
+			if err := e.Record.UnmarshalJSONField(ProxyFieldTraffic, &traffic); err == nil {
+				if err := traffic.Validate(); err != nil {
+					return err
+				}
+			}
+
 			return e.Next()
 		},
 		Priority: 99, // 高优先级，确保在其他验证之前执行
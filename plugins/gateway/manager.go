@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"net/http"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
@@ -21,6 +22,10 @@ type ManagerConfig struct {
 
 	// Metrics 指标收集器（可选）
 	Metrics *MetricsCollector
+
+	// SecretGetter 密钥获取函数（可选）
+	// 用于解析上游认证配置中的 {secret.X} 模板
+	SecretGetter SecretGetter
 }
 
 // Manager 代理管理器
@@ -36,6 +41,14 @@ type Manager struct {
 	// T050: 每个代理独立的控制组件
 	limiters map[string]*ConcurrencyLimiter
 	breakers map[string]*CircuitBreaker
+
+	// 每个代理独立的上游认证组件
+	transports   map[string]http.RoundTripper // 带 TLS 配置的专用 Transport
+	tokenSources map[string]*OAuth2TokenSource
+
+	// 每个代理独立的重试预算与镜像并发控制
+	retryBudgets map[string]*RetryBudget
+	mirrorSems   map[string]chan struct{}
 }
 
 // NewManager 创建代理管理器实例（使用默认配置）
@@ -58,6 +71,12 @@ func NewManagerWithConfig(app core.App, config ManagerConfig) *Manager {
 		transport: transport,
 		limiters:  make(map[string]*ConcurrencyLimiter),
 		breakers:  make(map[string]*CircuitBreaker),
+
+		transports:   make(map[string]http.RoundTripper),
+		tokenSources: make(map[string]*OAuth2TokenSource),
+
+		retryBudgets: make(map[string]*RetryBudget),
+		mirrorSems:   make(map[string]chan struct{}),
 	}
 }
 
@@ -81,12 +100,22 @@ func (m *Manager) SetProxies(proxies []*ProxyConfig) {
 		return len(active[i].Path) > len(active[j].Path)
 	})
 
+	previous := m.proxies
 	m.proxies = active
 
 	// T050: 为每个代理创建控制组件
 	// 清理旧的组件
+	oldRetryBudgets := m.retryBudgets
+	oldMirrorSems := m.mirrorSems
+	oldProxies := make(map[string]*ProxyConfig, len(previous))
+	for _, proxy := range previous {
+		oldProxies[proxy.ID] = proxy
+	}
+
 	m.limiters = make(map[string]*ConcurrencyLimiter)
 	m.breakers = make(map[string]*CircuitBreaker)
+	m.retryBudgets = make(map[string]*RetryBudget)
+	m.mirrorSems = make(map[string]chan struct{})
 
 	for _, proxy := range active {
 		// 创建 ConcurrencyLimiter
@@ -98,6 +127,83 @@ func (m *Manager) SetProxies(proxies []*ProxyConfig) {
 		if proxy.CircuitBreaker != nil && proxy.CircuitBreaker.Enabled {
 			m.breakers[proxy.ID] = NewCircuitBreaker(*proxy.CircuitBreaker)
 		}
+
+		oldProxy := oldProxies[proxy.ID]
+
+		// 创建重试预算（重试配置未变化时复用，保留已累计的预算）
+		if proxy.Retry != nil {
+			if old, ok := oldRetryBudgets[proxy.ID]; ok && reflect.DeepEqual(oldProxy.Retry, proxy.Retry) {
+				m.retryBudgets[proxy.ID] = old
+			} else {
+				retry := proxy.Retry.withDefaults()
+				m.retryBudgets[proxy.ID] = NewRetryBudget(retry.BudgetRatio, retry.BudgetMinPerSecond)
+			}
+		}
+
+		// 创建镜像并发控制（镜像配置未变化时复用，进行中的镜像请求继续占用并发名额）
+		if proxy.Traffic != nil && proxy.Traffic.Mirror != nil {
+			if old, ok := oldMirrorSems[proxy.ID]; ok && oldProxy.Traffic != nil && reflect.DeepEqual(oldProxy.Traffic.Mirror, proxy.Traffic.Mirror) {
+				m.mirrorSems[proxy.ID] = old
+			} else {
+				m.mirrorSems[proxy.ID] = make(chan struct{}, DefaultMirrorMaxConcurrent)
+			}
+		}
+	}
+
+	m.setupUpstreamAuth(previous, active)
+}
+
+// setupUpstreamAuth 为配置了上游认证的代理创建专用 Transport 和 Token 缓存
+// previous 为重载前的代理列表，调用方需持有写锁
+func (m *Manager) setupUpstreamAuth(previous, proxies []*ProxyConfig) {
+	oldTransports := m.transports
+	oldTokenSources := m.tokenSources
+
+	oldAuths := make(map[string]*UpstreamAuthConfig, len(previous))
+	for _, proxy := range previous {
+		oldAuths[proxy.ID] = proxy.UpstreamAuth
+	}
+
+	m.transports = make(map[string]http.RoundTripper)
+	m.tokenSources = make(map[string]*OAuth2TokenSource)
+
+	for _, proxy := range proxies {
+		auth := proxy.UpstreamAuth
+		if auth == nil {
+			continue
+		}
+
+		// 1. mTLS / 自定义 CA
+		var base http.RoundTripper = m.transport
+		if !auth.TLS.IsEmpty() {
+			transport, err := NewUpstreamTLSTransport(m.config.TransportConfig, auth.TLS, m.config.SecretGetter)
+			if err != nil {
+				// 证书配置错误时拒绝转发，避免在无客户端证书的情况下访问上游
+				m.logWarn("failed to build upstream tls transport", "proxy", proxy.Path, "error", err)
+				base = errorTransport{err: err}
+			} else {
+				base = transport
+			}
+			m.transports[proxy.ID] = base
+		}
+
+		// 2. OAuth2 Token 缓存（认证配置未变化时复用，保留已获取的 Token 与进行中的刷新；
+		//    TLS Transport 每次重载都会重建以加载轮换后的证书，替换到复用的缓存中）
+		if auth.Type == UpstreamAuthOAuth2 && auth.OAuth2 != nil {
+			if old, ok := oldTokenSources[proxy.ID]; ok && reflect.DeepEqual(oldAuths[proxy.ID], auth) {
+				old.setTransport(base)
+				m.tokenSources[proxy.ID] = old
+			} else {
+				m.tokenSources[proxy.ID] = NewOAuth2TokenSource(*auth.OAuth2, base, m.config.SecretGetter)
+			}
+		}
+	}
+
+	// 释放旧 Transport 的空闲连接
+	for _, transport := range oldTransports {
+		if t, ok := transport.(*http.Transport); ok {
+			t.CloseIdleConnections()
+		}
 	}
 }
 
@@ -124,7 +230,13 @@ func (m *Manager) MatchProxy(requestPath string) *ProxyConfig {
 
 // BuildUpstreamURL 构建上游请求 URL
 func (m *Manager) BuildUpstreamURL(proxy *ProxyConfig, requestPath string) string {
-	upstream := strings.TrimSuffix(proxy.Upstream, "/")
+	return m.BuildUpstreamURLFor(proxy, proxy.Upstream, requestPath)
+}
+
+// BuildUpstreamURLFor 使用指定的上游地址构建上游请求 URL
+// 用于流量拆分时选中的上游
+func (m *Manager) BuildUpstreamURLFor(proxy *ProxyConfig, upstream string, requestPath string) string {
+	upstream = strings.TrimSuffix(upstream, "/")
 
 	var path string
 	if proxy.StripPath {
@@ -155,6 +267,53 @@ func (m *Manager) Transport() *http.Transport {
 	return m.transport
 }
 
+// RoundTripper 返回代理使用的 RoundTripper
+//
+// 调用链（外层到内层）：
+//
+//	mirror（影子流量） -> retry（重试/对冲） -> upstreamAuth（OAuth2/HMAC） -> Transport（共享或 mTLS 专用）
+//
+// 每次重试都会重新注入认证信息；镜像请求只发送一次，不参与重试，
+// 且默认使用共享 Transport、不注入认证信息（见 MirrorConfig.PropagateAuth）
+func (m *Manager) RoundTripper(proxy *ProxyConfig) http.RoundTripper {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rt http.RoundTripper = m.transport
+	if t, ok := m.transports[proxy.ID]; ok {
+		rt = t
+	}
+
+	if proxy.UpstreamAuth != nil && proxy.UpstreamAuth.Type != "" {
+		rt = newUpstreamAuthTransport(rt, proxy.UpstreamAuth, m.tokenSources[proxy.ID], m.config.SecretGetter)
+	}
+	authed := rt
+
+	if proxy.Retry != nil {
+		rt = newRetryTransport(rt, proxy.Retry, m.retryBudgets[proxy.ID], proxy.Path, m.config.Metrics)
+	}
+
+	if proxy.Traffic != nil && proxy.Traffic.Mirror != nil {
+		if sem, ok := m.mirrorSems[proxy.ID]; ok {
+			// 默认不向影子上游发送主上游的凭据，需显式开启 PropagateAuth
+			var shadow http.RoundTripper = m.transport
+			if proxy.Traffic.Mirror.PropagateAuth {
+				shadow = authed
+			}
+			rt = newMirrorTransport(rt, shadow, proxy.Traffic.Mirror, mirrorCredentialHeaders(proxy), sem, proxy.Path, m.config.Metrics)
+		}
+	}
+
+	return rt
+}
+
+// logWarn 输出警告日志（app 为 nil 时忽略）
+func (m *Manager) logWarn(msg string, args ...any) {
+	if m.app != nil {
+		m.app.Logger().Warn(msg, args...)
+	}
+}
+
 // BufferPool 返回缓冲区池
 // T035: 注入 BufferPool
 func (m *Manager) BufferPool() *BytesPool {
@@ -125,6 +125,39 @@ func TestManagerSetProxies(t *testing.T) {
 	}
 }
 
+// TestManagerReusesRetryBudgetAndMirrorSem 测试重载时复用配置未变化的重试预算与镜像并发控制
+func TestManagerReusesRetryBudgetAndMirrorSem(t *testing.T) {
+	newProxy := func(attempts int, mirror string) *ProxyConfig {
+		return &ProxyConfig{
+			ID: "p1", Path: "/-/api", Upstream: "http://localhost", Active: true,
+			Retry:   &RetryConfig{MaxAttempts: attempts},
+			Traffic: &TrafficConfig{Mirror: &MirrorConfig{Upstream: mirror}},
+		}
+	}
+
+	m := NewManager(nil)
+	m.SetProxies([]*ProxyConfig{newProxy(3, "http://shadow")})
+	budget := m.retryBudgets["p1"]
+	sem := m.mirrorSems["p1"]
+
+	// 每次重载都会读取新的配置对象
+	m.SetProxies([]*ProxyConfig{newProxy(3, "http://shadow")})
+	if m.retryBudgets["p1"] != budget {
+		t.Error("expected retry budget to be reused for unchanged retry config")
+	}
+	if m.mirrorSems["p1"] != sem {
+		t.Error("expected mirror semaphore to be reused for unchanged mirror config")
+	}
+
+	m.SetProxies([]*ProxyConfig{newProxy(5, "http://shadow2")})
+	if m.retryBudgets["p1"] == budget {
+		t.Error("expected a new retry budget after retry config change")
+	}
+	if m.mirrorSems["p1"] == sem {
+		t.Error("expected a new mirror semaphore after mirror config change")
+	}
+}
+
 // TestManagerConcurrentAccess 测试并发访问安全性
 func TestManagerConcurrentAccess(t *testing.T) {
 	m := NewManager(nil)
@@ -19,6 +19,9 @@ type ProxyStats struct {
 	RequestsTotal int64         // 总请求数
 	ErrorsTotal   int64         // 错误数（5xx）
 	AvgLatency    time.Duration // 平均延迟
+	RetriesTotal  int64         // 重试次数
+	HedgesTotal   int64         // 对冲请求数
+	MirroredTotal int64         // 镜像请求数
 }
 
 // SuccessRate 返回成功率
@@ -38,6 +41,10 @@ type proxyMetrics struct {
 	activeConns   int64  // 活跃连接数
 	circuitState  int64  // 熔断状态
 	histogram     []int64 // histogram buckets
+
+	retriesTotal  int64 // 重试次数
+	hedgesTotal   int64 // 对冲请求数
+	mirroredTotal int64 // 镜像请求数
 }
 
 // MetricsCollector 指标收集器
@@ -185,6 +192,45 @@ func (mc *MetricsCollector) GetCircuitState(proxyName string) CircuitState {
 	return CircuitState(atomic.LoadInt64(&pm.circuitState))
 }
 
+// RecordRetry 记录一次重试
+func (mc *MetricsCollector) RecordRetry(proxyName string) {
+	if mc == nil {
+		return
+	}
+
+	mc.mu.Lock()
+	pm := mc.getOrCreateProxy(proxyName)
+	mc.mu.Unlock()
+
+	atomic.AddInt64(&pm.retriesTotal, 1)
+}
+
+// RecordHedge 记录一次对冲请求
+func (mc *MetricsCollector) RecordHedge(proxyName string) {
+	if mc == nil {
+		return
+	}
+
+	mc.mu.Lock()
+	pm := mc.getOrCreateProxy(proxyName)
+	mc.mu.Unlock()
+
+	atomic.AddInt64(&pm.hedgesTotal, 1)
+}
+
+// RecordMirror 记录一次镜像请求
+func (mc *MetricsCollector) RecordMirror(proxyName string) {
+	if mc == nil {
+		return
+	}
+
+	mc.mu.Lock()
+	pm := mc.getOrCreateProxy(proxyName)
+	mc.mu.Unlock()
+
+	atomic.AddInt64(&pm.mirroredTotal, 1)
+}
+
 // GetStats 获取代理统计信息
 func (mc *MetricsCollector) GetStats(proxyName string) ProxyStats {
 	if mc == nil {
@@ -203,6 +249,9 @@ func (mc *MetricsCollector) GetStats(proxyName string) ProxyStats {
 	errorsTotal := atomic.LoadInt64(&pm.errorsTotal)
 	latencySumNs := atomic.LoadInt64(&pm.latencySumNs)
 	latencyCount := atomic.LoadInt64(&pm.latencyCount)
+	retriesTotal := atomic.LoadInt64(&pm.retriesTotal)
+	hedgesTotal := atomic.LoadInt64(&pm.hedgesTotal)
+	mirroredTotal := atomic.LoadInt64(&pm.mirroredTotal)
 
 	var avgLatency time.Duration
 	if latencyCount > 0 {
@@ -213,6 +262,9 @@ func (mc *MetricsCollector) GetStats(proxyName string) ProxyStats {
 		RequestsTotal: requestsTotal,
 		ErrorsTotal:   errorsTotal,
 		AvgLatency:    avgLatency,
+		RetriesTotal:  retriesTotal,
+		HedgesTotal:   hedgesTotal,
+		MirroredTotal: mirroredTotal,
 	}
 }
 
@@ -230,6 +282,9 @@ func (mc *MetricsCollector) Reset() {
 		atomic.StoreInt64(&pm.errorsTotal, 0)
 		atomic.StoreInt64(&pm.latencySumNs, 0)
 		atomic.StoreInt64(&pm.latencyCount, 0)
+		atomic.StoreInt64(&pm.retriesTotal, 0)
+		atomic.StoreInt64(&pm.hedgesTotal, 0)
+		atomic.StoreInt64(&pm.mirroredTotal, 0)
 		for i := range pm.histogram {
 			atomic.StoreInt64(&pm.histogram[i], 0)
 		}
@@ -276,6 +331,15 @@ func (mc *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "# HELP gateway_request_duration_seconds Request duration histogram")
 	fmt.Fprintln(w, "# TYPE gateway_request_duration_seconds histogram")
 
+	fmt.Fprintln(w, "# HELP gateway_retries_total Total number of upstream retries")
+	fmt.Fprintln(w, "# TYPE gateway_retries_total counter")
+
+	fmt.Fprintln(w, "# HELP gateway_hedged_requests_total Total number of hedged upstream requests")
+	fmt.Fprintln(w, "# TYPE gateway_hedged_requests_total counter")
+
+	fmt.Fprintln(w, "# HELP gateway_mirrored_requests_total Total number of mirrored (shadow) requests")
+	fmt.Fprintln(w, "# TYPE gateway_mirrored_requests_total counter")
+
 	// 输出每个代理的指标
 	for _, name := range proxyNames {
 		mc.mu.RLock()
@@ -310,5 +374,9 @@ func (mc *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// Sum 和 Count
 		fmt.Fprintf(w, "gateway_request_duration_seconds_sum{proxy=\"%s\"} %.6f\n", name, float64(latencySumNs)/1e9)
 		fmt.Fprintf(w, "gateway_request_duration_seconds_count{proxy=\"%s\"} %d\n", name, latencyCount)
+
+		fmt.Fprintf(w, "gateway_retries_total{proxy=\"%s\"} %d\n", name, atomic.LoadInt64(&pm.retriesTotal))
+		fmt.Fprintf(w, "gateway_hedged_requests_total{proxy=\"%s\"} %d\n", name, atomic.LoadInt64(&pm.hedgesTotal))
+		fmt.Fprintf(w, "gateway_mirrored_requests_total{proxy=\"%s\"} %d\n", name, atomic.LoadInt64(&pm.mirroredTotal))
 	}
 }
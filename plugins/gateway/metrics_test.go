@@ -1,12 +1,15 @@
 package gateway
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 )
 
 // TestNewMetricsCollector 验证创建
@@ -199,6 +202,42 @@ func TestMetricsCollectorHistogram(t *testing.T) {
 	}
 }
 
+// TestMetricsCollectorCollect 验证向统一 Prometheus 端点提供的指标
+func TestMetricsCollectorCollect(t *testing.T) {
+	mc := NewMetricsCollector()
+	mc.RecordRequest("proxy1", 200, 5*time.Millisecond)
+	mc.RecordRequest("proxy1", 502, 2*time.Second)
+	mc.RecordRetry("proxy1")
+	mc.IncrActiveConns("proxy1")
+
+	var buf bytes.Buffer
+	if err := metrics.WriteMetricFamilies(&buf, mc.Collect(), true); err != nil {
+		t.Fatal(err)
+	}
+	body := buf.String()
+
+	expected := []string{
+		`gateway_requests_total{proxy="proxy1"} 2`,
+		`gateway_errors_total{proxy="proxy1"} 1`,
+		`gateway_retries_total{proxy="proxy1"} 1`,
+		`gateway_active_connections{proxy="proxy1"} 1`,
+		`gateway_request_duration_seconds_bucket{proxy="proxy1",le="0.01"} 1`,
+		`gateway_request_duration_seconds_bucket{proxy="proxy1",le="2.5"} 2`,
+		`gateway_request_duration_seconds_bucket{proxy="proxy1",le="+Inf"} 2`,
+		`gateway_request_duration_seconds_count{proxy="proxy1"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Missing %q in:\n%s", line, body)
+		}
+	}
+
+	var nilCollector *MetricsCollector
+	if nilCollector.Collect() != nil {
+		t.Error("Expected nil collector to return no metrics")
+	}
+}
+
 // TestMetricsCollectorConcurrency 验证并发安全性
 func TestMetricsCollectorConcurrency(t *testing.T) {
 	mc := NewMetricsCollector()
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+)
+
+// Collect 实现 metrics.Collector 接口，向统一的 /api/metrics/prometheus 端点提供代理指标
+//
+// 指标名称与 ServeHTTP 输出的 gateway_* 指标一致。
+func (mc *MetricsCollector) Collect() []metrics.MetricFamily {
+	if mc == nil {
+		return nil
+	}
+
+	mc.mu.RLock()
+	names := make([]string, 0, len(mc.proxies))
+	for name := range mc.proxies {
+		names = append(names, name)
+	}
+	mc.mu.RUnlock()
+	sort.Strings(names)
+
+	families := []metrics.MetricFamily{
+		{Name: "gateway_requests", Help: "Total number of requests", Type: metrics.MetricTypeCounter},
+		{Name: "gateway_errors", Help: "Total number of errors (5xx)", Type: metrics.MetricTypeCounter},
+		{Name: "gateway_active_connections", Help: "Current number of active connections", Type: metrics.MetricTypeGauge},
+		{Name: "gateway_circuit_breaker_state", Help: "Circuit breaker state (0=closed, 1=open, 2=half-open)", Type: metrics.MetricTypeGauge},
+		{Name: "gateway_retries", Help: "Total number of upstream retries", Type: metrics.MetricTypeCounter},
+		{Name: "gateway_hedged_requests", Help: "Total number of hedged upstream requests", Type: metrics.MetricTypeCounter},
+		{Name: "gateway_mirrored_requests", Help: "Total number of mirrored (shadow) requests", Type: metrics.MetricTypeCounter},
+		{Name: "gateway_request_duration_seconds", Help: "Request duration histogram", Type: metrics.MetricTypeHistogram},
+	}
+
+	for _, name := range names {
+		mc.mu.RLock()
+		pm := mc.proxies[name]
+		mc.mu.RUnlock()
+
+		if pm == nil {
+			continue
+		}
+
+		labels := metrics.Labels("proxy", name)
+		values := []int64{
+			atomic.LoadInt64(&pm.requestsTotal),
+			atomic.LoadInt64(&pm.errorsTotal),
+			atomic.LoadInt64(&pm.activeConns),
+			atomic.LoadInt64(&pm.circuitState),
+			atomic.LoadInt64(&pm.retriesTotal),
+			atomic.LoadInt64(&pm.hedgesTotal),
+			atomic.LoadInt64(&pm.mirroredTotal),
+		}
+		for i, value := range values {
+			families[i].Samples = append(families[i].Samples, metrics.Sample{Labels: labels, Value: float64(value)})
+		}
+
+		histogram := &metrics.HistogramValue{
+			Buckets: HistogramBuckets,
+			Counts:  make([]uint64, len(HistogramBuckets)),
+			Sum:     float64(atomic.LoadInt64(&pm.latencySumNs)) / 1e9,
+			Count:   uint64(atomic.LoadInt64(&pm.histogram[len(HistogramBuckets)])),
+		}
+		var cumulative uint64
+		for i := range HistogramBuckets {
+			cumulative += uint64(atomic.LoadInt64(&pm.histogram[i]))
+			histogram.Counts[i] = cumulative
+		}
+		families[len(families)-1].Samples = append(families[len(families)-1].Samples, metrics.Sample{Labels: labels, Histogram: histogram})
+	}
+
+	return families
+}
@@ -33,6 +33,7 @@ func IsHopByHopHeader(header string) bool {
 // T031b: 注入 ModifyResponse 处理非标准响应
 func (p *gatewayPlugin) createReverseProxy(targetURL string, proxy *ProxyConfig, authInfo *AuthInfo) *httputil.ReverseProxy {
 	target, _ := url.Parse(targetURL)
+	secretGetter := p.createSecretGetter()
 
 	reverseProxy := &httputil.ReverseProxy{
 		Director: func(req *http.Request) {
@@ -67,7 +68,6 @@ func (p *gatewayPlugin) createReverseProxy(targetURL string, proxy *ProxyConfig,
 
 			// 6. 注入自定义请求头
 			if len(proxy.Headers) > 0 {
-				secretGetter := p.createSecretGetter()
 				headers, err := BuildProxyHeaders(proxy.Headers, authInfo, secretGetter)
 				if err == nil {
 					for key, value := range headers {
@@ -77,18 +77,37 @@ func (p *gatewayPlugin) createReverseProxy(targetURL string, proxy *ProxyConfig,
 					}
 				}
 			}
+
+			// 7. 请求变换（路径重写、查询参数、头部、JSON Body）
+			if proxy.Transform.HasRequestTransform() {
+				if err := ApplyRequestTransform(req, proxy.Transform.Request, authInfo, secretGetter); err != nil {
+					p.app.Logger().Warn("gateway request transform failed", "proxy", proxy.Path, "error", err)
+				}
+			}
 		},
-		Transport: p.manager.Transport(),
-		// 7. [关键] SSE 流式响应优化
+		// 上游认证（OAuth2、HMAC 签名、mTLS）在 Transport 层注入
+		Transport: p.manager.RoundTripper(proxy),
+		// 8. [关键] SSE 流式响应优化
 		FlushInterval: DefaultFlushInterval,
-		// 8. 结构化错误响应 (T031b)
+		// 9. 结构化错误响应 (T031b)
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
 			handleUpstreamError(w, r, err)
 		},
-		// 9. T031b: 非标准响应容错处理
+		// 10. T031b: 非标准响应容错处理
 		ModifyResponse: createModifyResponse(),
 	}
 
+	// 11. 响应变换（在归一化之后执行）
+	if proxy.Transform.HasResponseTransform() {
+		normalize := reverseProxy.ModifyResponse
+		reverseProxy.ModifyResponse = func(resp *http.Response) error {
+			if err := normalize(resp); err != nil {
+				return err
+			}
+			return ApplyResponseTransform(resp, proxy.Transform.Response, authInfo, secretGetter)
+		}
+	}
+
 	// T035: 注入 BufferPool 减少 GC 压力
 	if p.manager.BufferPool() != nil {
 		reverseProxy.BufferPool = p.manager.BufferPool()
@@ -102,8 +121,9 @@ func (p *gatewayPlugin) createReverseProxy(targetURL string, proxy *ProxyConfig,
 func (p *gatewayPlugin) serveProxy(e *core.RequestEvent, proxy *ProxyConfig, authInfo *AuthInfo) {
 	startTime := time.Now()
 
-	// 构建上游 URL
-	upstreamURL := p.manager.BuildUpstreamURL(proxy, e.Request.URL.RequestURI())
+	// 选择上游（流量拆分）并构建上游 URL
+	upstream := proxy.PickUpstream(e.Request, authInfo)
+	upstreamURL := p.manager.BuildUpstreamURLFor(proxy, upstream, e.Request.URL.RequestURI())
 
 	// 设置超时
 	timeout := proxy.Timeout
@@ -113,9 +133,24 @@ func (p *gatewayPlugin) serveProxy(e *core.RequestEvent, proxy *ProxyConfig, aut
 	ctx, cancel := context.WithTimeout(e.Request.Context(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
+	// 挂载重试统计，供日志记录重试次数
+	ctx, retryStats := withRetryStats(ctx)
+
 	// 更新请求的 Context
 	req := e.Request.WithContext(ctx)
 
+	// 统计请求体字节数（访问日志）
+	var bodyCounter *countingReadCloser
+	if req.Body != nil && req.Body != http.NoBody {
+		bodyCounter = &countingReadCloser{ReadCloser: req.Body}
+		req.Body = bodyCounter
+	}
+
+	// 创建客户端 Span 并向上游传播 W3C traceparent
+	span := p.startClientSpan(req, e.Request.Method+" "+proxy.Path)
+	req.Header = req.Header.Clone()
+	req.Header.Set(TraceparentHeader, span.traceparent())
+
 	// 解析目标 URL
 	target, err := url.Parse(upstreamURL)
 	if err != nil {
@@ -148,34 +183,51 @@ func (p *gatewayPlugin) serveProxy(e *core.RequestEvent, proxy *ProxyConfig, aut
 		metrics,
 	)
 
-	// 执行代理
-	wrapped.ServeHTTP(e.Response, req)
+	// 执行代理（外层包装用于捕获最终状态码和响应字节数，包括 429/503 等网关自身响应）
+	rw := newResponseWriter(e.Response)
+	wrapped.ServeHTTP(rw, req)
 
 	// T045: 计算延迟
 	upstreamLatency := time.Since(upstreamStart)
 	totalDuration := time.Since(startTime)
-	proxyLatency := totalDuration - upstreamLatency // 代理开销（不含上游）
 
-	// T045: 结构化日志增强
-	logFields := []any{
-		"proxy_name", proxy.Path,
-		"method", e.Request.Method,
-		"path", e.Request.URL.Path,
-		"upstream", proxy.Upstream,
-		"upstream_latency_ms", upstreamLatency.Milliseconds(),
-		"proxy_latency_ms", proxyLatency.Milliseconds(),
-		"duration_ms", totalDuration.Milliseconds(),
+	// 访问日志与客户端 Span
+	entry := &accessLogEntry{
+		ProxyID:         proxy.ID,
+		ProxyPath:       proxy.Path,
+		Method:          e.Request.Method,
+		Path:            e.Request.URL.Path,
+		Upstream:        upstream,
+		Status:          rw.StatusCode(),
+		BytesOut:        rw.BytesWritten(),
+		UpstreamLatency: upstreamLatency,
+		Duration:        totalDuration,
+		Retries:         retryStats.Retries(),
+		TraceID:         span.traceID,
 	}
-
-	// 添加可选字段
-	if limiter != nil {
-		logFields = append(logFields, "concurrent_count", limiter.InUse())
+	if authInfo != nil {
+		entry.AuthID = authInfo.ID
 	}
-	if breaker != nil {
-		logFields = append(logFields, "circuit_state", breaker.State().String())
+	if bodyCounter != nil {
+		entry.BytesIn = bodyCounter.Count()
 	}
 
-	p.app.Logger().Debug("gateway request", logFields...)
+	span.end(entry, upstreamURL)
+
+	// T045: 结构化日志增强
+	if p.config.AccessLog.shouldLog(entry.Status) {
+		logFields := entry.attrs()
+
+		// 添加可选字段
+		if limiter != nil {
+			logFields = append(logFields, "concurrent_count", limiter.InUse())
+		}
+		if breaker != nil {
+			logFields = append(logFields, "circuit_state", breaker.State().String())
+		}
+
+		p.app.Logger().Log(e.Request.Context(), entry.level(), "gateway access", logFields...)
+	}
 }
 
 // createSecretGetter 创建 Secret 获取函数
@@ -2,6 +2,7 @@
 package gateway
 
 import (
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -52,12 +53,18 @@ func (emptyReader) Read(p []byte) (n int, err error) {
 // 错误类型映射:
 // - 超时错误 -> 504 Gateway Timeout
 // - 连接错误 -> 502 Bad Gateway
+// - 签名的请求体过大 -> 413 Request Entity Too Large
 // - 其他错误 -> 502 Bad Gateway
 func handleUpstreamError(w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
 		return
 	}
 
+	if errors.Is(err, errSignBodyTooLarge) {
+		WriteGatewayError(w, http.StatusRequestEntityTooLarge, "Request Entity Too Large", err.Error())
+		return
+	}
+
 	// 检查是否是超时错误
 	if isTimeoutError(err) {
 		WriteGatewayError(w, http.StatusGatewayTimeout, "Gateway Timeout", "upstream request timed out: "+err.Error())
@@ -0,0 +1,614 @@
+// Package gateway 提供 API Gateway 插件功能
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxReplayBodySize 允许重放（重试/镜像）的最大请求体字节数
+// 超出该大小的请求不会被重试或镜像，避免缓冲大文件上传
+const MaxReplayBodySize = 1 << 20 // 1MB
+
+// 重试默认值
+const (
+	DefaultRetryMaxAttempts     = 3
+	DefaultRetryBackoffMs       = 100
+	DefaultRetryMaxBackoffMs    = 2000
+	DefaultRetryBudgetRatio     = 0.2
+	DefaultRetryBudgetMinPerSec = 1
+	DefaultHedgeDelayMs         = 100
+	DefaultHedgeMaxHedges       = 1
+
+	// retryBudgetWindow 重试预算的统计窗口
+	retryBudgetWindow = 10 * time.Second
+)
+
+// DefaultRetryOn 默认触发重试的上游状态码
+var DefaultRetryOn = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// DefaultIdempotentMethods 默认允许重试的幂等方法
+var DefaultIdempotentMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// RetryConfig 重试与对冲请求配置
+// 对应 _proxies.retryPolicy 字段（JSON）
+//
+// 示例：
+//
+//	{
+//	  "max_attempts": 3,
+//	  "retry_on": [502, 503, 504],
+//	  "backoff_ms": 100,
+//	  "budget_ratio": 0.2,
+//	  "hedge": {"delay_ms": 150, "max_hedges": 1}
+//	}
+type RetryConfig struct {
+	// MaxAttempts 最大尝试次数（含首次请求），默认 3，<= 1 表示不重试
+	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// RetryOn 触发重试的上游状态码，默认 [502, 503, 504]
+	// 连接错误（连接被拒绝/重置、DNS 失败等）总是会触发重试
+	RetryOn []int `json:"retry_on,omitempty"`
+
+	// Methods 允许重试的请求方法，默认仅幂等方法
+	Methods []string `json:"methods,omitempty"`
+
+	// BackoffMs 指数退避的基础间隔（毫秒），默认 100
+	BackoffMs int `json:"backoff_ms,omitempty"`
+
+	// MaxBackoffMs 单次退避的最大间隔（毫秒），默认 2000
+	MaxBackoffMs int `json:"max_backoff_ms,omitempty"`
+
+	// BudgetRatio 重试预算：窗口内重试数不超过请求数的该比例，默认 0.2
+	// 防止上游故障时重试流量放大
+	BudgetRatio float64 `json:"budget_ratio,omitempty"`
+
+	// BudgetMinPerSecond 重试预算的保底值（每秒），保证低流量时也可以重试，默认 1
+	BudgetMinPerSecond int `json:"budget_min_per_second,omitempty"`
+
+	// Hedge 对冲请求配置（可选），仅作用于无请求体的幂等请求
+	Hedge *HedgeConfig `json:"hedge,omitempty"`
+}
+
+// HedgeConfig 对冲请求配置
+// 首个请求在 DelayMs 内未返回时，向上游再发送一个相同请求，取先返回的成功响应
+type HedgeConfig struct {
+	// DelayMs 发送对冲请求前的等待时间（毫秒），默认 100
+	DelayMs int `json:"delay_ms,omitempty"`
+
+	// MaxHedges 最多额外发送的对冲请求数，默认 1
+	MaxHedges int `json:"max_hedges,omitempty"`
+}
+
+// Validate 校验重试配置
+func (c *RetryConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if c.MaxAttempts < 0 || c.MaxAttempts > 10 {
+		return errors.New("retryPolicy.max_attempts must be between 0 and 10")
+	}
+
+	for _, status := range c.RetryOn {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("retryPolicy.retry_on contains invalid status code %d", status)
+		}
+	}
+
+	if c.BackoffMs < 0 || c.MaxBackoffMs < 0 {
+		return errors.New("retryPolicy backoff cannot be negative")
+	}
+
+	if c.BudgetRatio < 0 || c.BudgetRatio > 1 {
+		return errors.New("retryPolicy.budget_ratio must be between 0 and 1")
+	}
+
+	if c.Hedge != nil {
+		if c.Hedge.DelayMs < 0 {
+			return errors.New("retryPolicy.hedge.delay_ms cannot be negative")
+		}
+		if c.Hedge.MaxHedges < 0 || c.Hedge.MaxHedges > 5 {
+			return errors.New("retryPolicy.hedge.max_hedges must be between 0 and 5")
+		}
+	}
+
+	return nil
+}
+
+// IsEnabled 是否启用重试或对冲
+// 空配置或 max_attempts=1 且未配置对冲时视为未启用
+func (c *RetryConfig) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	if c.Hedge != nil {
+		return true
+	}
+	return c.MaxAttempts != 1 && !reflect.DeepEqual(*c, RetryConfig{})
+}
+
+// withDefaults 返回填充默认值后的配置副本
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if len(c.RetryOn) == 0 {
+		c.RetryOn = DefaultRetryOn
+	}
+	if len(c.Methods) == 0 {
+		c.Methods = DefaultIdempotentMethods
+	}
+	if c.BackoffMs == 0 {
+		c.BackoffMs = DefaultRetryBackoffMs
+	}
+	if c.MaxBackoffMs == 0 {
+		c.MaxBackoffMs = DefaultRetryMaxBackoffMs
+	}
+	if c.BudgetRatio == 0 {
+		c.BudgetRatio = DefaultRetryBudgetRatio
+	}
+	if c.BudgetMinPerSecond == 0 {
+		c.BudgetMinPerSecond = DefaultRetryBudgetMinPerSec
+	}
+	if c.Hedge != nil {
+		hedge := *c.Hedge
+		if hedge.DelayMs == 0 {
+			hedge.DelayMs = DefaultHedgeDelayMs
+		}
+		if hedge.MaxHedges == 0 {
+			hedge.MaxHedges = DefaultHedgeMaxHedges
+		}
+		c.Hedge = &hedge
+	}
+	return c
+}
+
+// --- 重试统计 ---
+
+// retryStatsKey 请求 Context 中重试统计的 key
+type retryStatsKey struct{}
+
+// RetryStats 单个代理请求的重试统计
+type RetryStats struct {
+	retries atomic.Int32
+}
+
+// Retries 返回已执行的重试次数（不含首次请求）
+func (s *RetryStats) Retries() int {
+	if s == nil {
+		return 0
+	}
+	return int(s.retries.Load())
+}
+
+// withRetryStats 在 Context 中挂载重试统计
+func withRetryStats(ctx context.Context) (context.Context, *RetryStats) {
+	stats := &RetryStats{}
+	return context.WithValue(ctx, retryStatsKey{}, stats), stats
+}
+
+// recordAttempt 记录一次重试
+func recordAttempt(ctx context.Context) {
+	if stats, ok := ctx.Value(retryStatsKey{}).(*RetryStats); ok {
+		stats.retries.Add(1)
+	}
+}
+
+// --- Retry Budget ---
+
+// RetryBudget 重试预算
+// 在统计窗口内，允许的重试数 = BudgetMinPerSecond * 窗口秒数 + BudgetRatio * 请求数
+type RetryBudget struct {
+	ratio     float64
+	minPerSec int
+	window    time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+// NewRetryBudget 创建重试预算
+func NewRetryBudget(ratio float64, minPerSec int) *RetryBudget {
+	return &RetryBudget{
+		ratio:       ratio,
+		minPerSec:   minPerSec,
+		window:      retryBudgetWindow,
+		windowStart: time.Now(),
+	}
+}
+
+// rotate 窗口过期时重置计数（需持有锁）
+func (b *RetryBudget) rotate(now time.Time) {
+	if now.Sub(b.windowStart) >= b.window {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
+
+// RecordRequest 记录一次原始请求
+func (b *RetryBudget) RecordRequest() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rotate(time.Now())
+	b.requests++
+}
+
+// TryAcquire 尝试为一次重试/对冲扣减预算
+func (b *RetryBudget) TryAcquire() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rotate(time.Now())
+
+	allowed := float64(b.minPerSec)*b.window.Seconds() + b.ratio*float64(b.requests)
+	if float64(b.retries+1) > allowed {
+		return false
+	}
+
+	b.retries++
+	return true
+}
+
+// --- RoundTripper ---
+
+// retryTransport 实现重试、退避与对冲请求
+type retryTransport struct {
+	base      http.RoundTripper
+	config    RetryConfig
+	budget    *RetryBudget
+	proxyName string
+	metrics   *MetricsCollector
+}
+
+// newRetryTransport 创建带重试的 RoundTripper
+func newRetryTransport(base http.RoundTripper, config *RetryConfig, budget *RetryBudget, proxyName string, metrics *MetricsCollector) http.RoundTripper {
+	return &retryTransport{
+		base:      base,
+		config:    config.withDefaults(),
+		budget:    budget,
+		proxyName: proxyName,
+		metrics:   metrics,
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.budget.RecordRequest()
+
+	if !t.isRetryableMethod(req.Method) {
+		return t.base.RoundTrip(req)
+	}
+
+	// 请求体过大时无法重放，直接透传
+	if !makeBodyReplayable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	hedge := t.config.Hedge != nil && (req.Body == nil || req.Body == http.NoBody)
+
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			recordAttempt(req.Context())
+		}
+
+		attemptReq, cloneErr := cloneRequestForAttempt(req, req.Context())
+		if cloneErr != nil {
+			return nil, cloneErr
+		}
+
+		if hedge {
+			resp, err = t.hedgedRoundTrip(attemptReq)
+		} else {
+			resp, err = t.base.RoundTrip(attemptReq)
+		}
+
+		if !t.shouldRetry(resp, err) || attempt >= t.config.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		if !t.budget.TryAcquire() {
+			// 预算耗尽，返回当前结果，避免放大故障
+			return resp, err
+		}
+
+		if t.metrics != nil {
+			t.metrics.RecordRetry(t.proxyName)
+		}
+
+		// 丢弃本次失败响应
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if sleepErr := sleepWithContext(req.Context(), t.backoff(attempt)); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
+}
+
+// hedgedRoundTrip 执行对冲请求：首个请求超过 DelayMs 未返回时并发发送额外请求
+// 返回最先到达的成功响应，其余请求被取消
+func (t *retryTransport) hedgedRoundTrip(req *http.Request) (*http.Response, error) {
+	type result struct {
+		resp *http.Response
+		err  error
+		idx  int
+	}
+
+	maxLaunches := 1 + t.config.Hedge.MaxHedges
+	results := make(chan result, maxLaunches)
+	cancels := make([]context.CancelFunc, 0, maxLaunches)
+
+	launch := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		idx := len(cancels)
+		cancels = append(cancels, cancel)
+		r := req.Clone(ctx)
+		go func() {
+			resp, err := t.base.RoundTrip(r)
+			results <- result{resp: resp, err: err, idx: idx}
+		}()
+	}
+
+	launch()
+	launched, received := 1, 0
+
+	timer := time.NewTimer(time.Duration(t.config.Hedge.DelayMs) * time.Millisecond)
+	defer timer.Stop()
+
+	last := result{idx: -1}
+
+	// cleanup 取消除 winner 外的所有请求，并回收未返回的响应
+	cleanup := func(winner int) {
+		pending := launched - received
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for i := 0; i < pending; i++ {
+				r := <-results
+				if r.resp != nil {
+					r.resp.Body.Close()
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-timer.C:
+			if launched < maxLaunches && t.budget.TryAcquire() {
+				if t.metrics != nil {
+					t.metrics.RecordHedge(t.proxyName)
+				}
+				launch()
+				launched++
+				timer.Reset(time.Duration(t.config.Hedge.DelayMs) * time.Millisecond)
+			}
+
+		case r := <-results:
+			received++
+
+			if !t.shouldRetry(r.resp, r.err) {
+				if last.resp != nil {
+					last.resp.Body.Close()
+				}
+
+				// 不可重试的错误（如超时、TLS 握手或上游认证失败）同样是最终结果，取消其余请求
+				if r.err != nil {
+					cleanup(-1)
+					return nil, r.err
+				}
+
+				// 成功响应：保留其 Context 直到 Body 关闭
+				cleanup(r.idx)
+				r.resp.Body = &cancelOnCloseBody{ReadCloser: r.resp.Body, cancel: cancels[r.idx]}
+				return r.resp, nil
+			}
+
+			// 失败响应：保留最新的一个作为兜底结果
+			if last.resp != nil {
+				last.resp.Body.Close()
+			}
+			if last.idx >= 0 {
+				cancels[last.idx]()
+			}
+			last = r
+
+			if received == launched {
+				if launched < maxLaunches && req.Context().Err() == nil && t.budget.TryAcquire() {
+					// 所有请求都已失败，立即发送下一个对冲请求
+					if t.metrics != nil {
+						t.metrics.RecordHedge(t.proxyName)
+					}
+					launch()
+					launched++
+					continue
+				}
+
+				cleanup(last.idx)
+				if last.resp != nil {
+					last.resp.Body = &cancelOnCloseBody{ReadCloser: last.resp.Body, cancel: cancels[last.idx]}
+				} else {
+					cancels[last.idx]()
+				}
+				return last.resp, last.err
+			}
+
+		case <-req.Context().Done():
+			cleanup(-1)
+			if last.resp != nil {
+				last.resp.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// isRetryableMethod 检查请求方法是否允许重试
+func (t *retryTransport) isRetryableMethod(method string) bool {
+	for _, m := range t.config.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry 检查响应或错误是否需要重试
+func (t *retryTransport) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		// 客户端取消或超时不重试
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return isConnectionError(err) || isConnectionResetError(err)
+	}
+
+	return resp != nil && slices.Contains(t.config.RetryOn, resp.StatusCode)
+}
+
+// backoff 计算第 attempt 次失败后的退避时间（指数退避 + Full Jitter）
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	maxBackoff := time.Duration(t.config.MaxBackoffMs) * time.Millisecond
+	backoff := time.Duration(t.config.BackoffMs) * time.Millisecond << (attempt - 1)
+	if backoff <= 0 || backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(backoff) + 1))
+}
+
+// isConnectionResetError 检查是否为连接被重置/提前关闭
+func isConnectionResetError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe")
+}
+
+// sleepWithContext 在 Context 取消前休眠指定时间
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// makeBodyReplayable 缓冲请求体并设置 GetBody，使请求可以被重放
+// 请求体超过 MaxReplayBodySize 时返回 false（请求体保持完整可读）
+func makeBodyReplayable(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true
+	}
+
+	if req.ContentLength > MaxReplayBodySize {
+		return false
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, MaxReplayBodySize+1))
+	if err != nil {
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), errorReader{err}))
+		return false
+	}
+
+	if len(buf) > MaxReplayBodySize {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return false
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return true
+}
+
+// cloneRequestForAttempt 为一次尝试复制请求（含可重放的请求体）
+func cloneRequestForAttempt(req *http.Request, ctx context.Context) (*http.Request, error) {
+	clone := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// errorReader 总是返回指定错误的 Reader
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// cancelOnCloseBody 在 Body 关闭时取消对应请求的 Context
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close 关闭 Body 并取消 Context
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
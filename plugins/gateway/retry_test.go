@@ -0,0 +1,347 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRetryConfigValidate 测试重试配置校验
+func TestRetryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *RetryConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"empty", &RetryConfig{}, false},
+		{"valid", &RetryConfig{MaxAttempts: 3, RetryOn: []int{502}, BudgetRatio: 0.1}, false},
+		{"too many attempts", &RetryConfig{MaxAttempts: 20}, true},
+		{"invalid status", &RetryConfig{RetryOn: []int{99}}, true},
+		{"invalid ratio", &RetryConfig{BudgetRatio: 2}, true},
+		{"negative backoff", &RetryConfig{BackoffMs: -1}, true},
+		{"invalid hedges", &RetryConfig{Hedge: &HedgeConfig{MaxHedges: 10}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRetryConfigIsEnabled 测试重试启用判断
+func TestRetryConfigIsEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *RetryConfig
+		want   bool
+	}{
+		{"nil", nil, false},
+		{"empty", &RetryConfig{}, false},
+		{"single attempt", &RetryConfig{MaxAttempts: 1}, false},
+		{"defaults with retry_on", &RetryConfig{RetryOn: []int{503}}, true},
+		{"hedge only", &RetryConfig{MaxAttempts: 1, Hedge: &HedgeConfig{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.IsEnabled(); got != tt.want {
+				t.Errorf("IsEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetryBudget 测试重试预算
+func TestRetryBudget(t *testing.T) {
+	// 保底 0，比例 0.5：10 个请求最多 5 次重试
+	budget := NewRetryBudget(0.5, 0)
+	for i := 0; i < 10; i++ {
+		budget.RecordRequest()
+	}
+
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if budget.TryAcquire() {
+			allowed++
+		}
+	}
+
+	if allowed != 5 {
+		t.Errorf("allowed retries = %d, want 5", allowed)
+	}
+
+	// nil 预算不做限制
+	var nilBudget *RetryBudget
+	if !nilBudget.TryAcquire() {
+		t.Error("nil budget should always allow")
+	}
+}
+
+// newFlakyServer 前 failures 次请求返回 status，之后返回 200
+func newFlakyServer(failures int32, status int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		if n <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+}
+
+// TestRetryTransportRetriesOnStatus 测试按状态码重试
+func TestRetryTransportRetriesOnStatus(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(2, http.StatusBadGateway, &calls)
+	defer server.Close()
+
+	metrics := NewMetricsCollector()
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 3, BackoffMs: 1}, NewRetryBudget(1, 10), "test", metrics)
+
+	ctx, stats := withRetryStats(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, server.URL, strings.NewReader("payload"))
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" {
+		t.Errorf("status = %d body = %q, want 200 payload", resp.StatusCode, body)
+	}
+	if calls != 3 {
+		t.Errorf("upstream calls = %d, want 3", calls)
+	}
+	if stats.Retries() != 2 {
+		t.Errorf("Retries() = %d, want 2", stats.Retries())
+	}
+	if got := metrics.GetStats("test").RetriesTotal; got != 2 {
+		t.Errorf("RetriesTotal = %d, want 2", got)
+	}
+}
+
+// TestRetryTransportMaxAttempts 测试最大尝试次数
+func TestRetryTransportMaxAttempts(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(100, http.StatusServiceUnavailable, &calls)
+	defer server.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 2, BackoffMs: 1}, nil, "test", nil)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", calls)
+	}
+}
+
+// TestRetryTransportSkipsNonIdempotent 测试非幂等方法不重试
+func TestRetryTransportSkipsNonIdempotent(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(1, http.StatusBadGateway, &calls)
+	defer server.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 3, BackoffMs: 1}, nil, "test", nil)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("x"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+}
+
+// TestRetryTransportBudgetExhausted 测试预算耗尽时停止重试
+func TestRetryTransportBudgetExhausted(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(100, http.StatusBadGateway, &calls)
+	defer server.Close()
+
+	// 保底为 0，比例很小：没有可用预算
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 5, BackoffMs: 1}, NewRetryBudget(0.01, 0), "test", nil)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("upstream calls = %d, want 1 (budget exhausted)", calls)
+	}
+}
+
+// TestRetryTransportConnectionError 测试连接错误重试
+func TestRetryTransportConnectionError(t *testing.T) {
+	// 获取一个未监听的端口
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, stats := withRetryStats(context.Background())
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 3, BackoffMs: 1}, nil, "test", nil)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr, nil)
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected connection error")
+	}
+
+	if stats.Retries() != 2 {
+		t.Errorf("Retries() = %d, want 2", stats.Retries())
+	}
+}
+
+// TestRetryTransportLargeBodyNotRetried 测试超大请求体不重试
+func TestRetryTransportLargeBodyNotRetried(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(1, http.StatusBadGateway, &calls)
+	defer server.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{MaxAttempts: 3, BackoffMs: 1}, nil, "test", nil)
+
+	large := strings.Repeat("a", MaxReplayBodySize+10)
+	req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader(large)))
+	req.ContentLength = -1
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+}
+
+// TestHedgedRequest 测试对冲请求：慢请求被更快的对冲请求取代
+func TestHedgedRequest(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			// 首个请求很慢
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	metrics := NewMetricsCollector()
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{
+		MaxAttempts: 1,
+		Hedge:       &HedgeConfig{DelayMs: 20, MaxHedges: 1},
+	}, nil, "test", metrics)
+
+	start := time.Now()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "fast" {
+		t.Errorf("body = %q, want fast", body)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("hedged request took %v, expected the hedge to win", elapsed)
+	}
+	if got := metrics.GetStats("test").HedgesTotal; got != 1 {
+		t.Errorf("HedgesTotal = %d, want 1", got)
+	}
+}
+
+// TestHedgedRequestNoHedgeWhenFast 测试快速响应不触发对冲
+func TestHedgedRequestNoHedgeWhenFast(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	rt := newRetryTransport(http.DefaultTransport, &RetryConfig{
+		MaxAttempts: 1,
+		Hedge:       &HedgeConfig{DelayMs: 500},
+	}, nil, "test", nil)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+}
+
+// roundTripFunc 将函数适配为 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestHedgedRequestFinalError 测试对冲请求返回不可重试的错误时直接返回该错误
+func TestHedgedRequestFinalError(t *testing.T) {
+	authErr := errors.New("upstream auth failed")
+
+	var calls int32
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, authErr
+	})
+
+	rt := newRetryTransport(base, &RetryConfig{
+		MaxAttempts: 3,
+		Hedge:       &HedgeConfig{DelayMs: 20, MaxHedges: 2},
+	}, nil, "test", nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://upstream.local", nil)
+	resp, err := rt.RoundTrip(req)
+	if !errors.Is(err, authErr) {
+		t.Fatalf("err = %v, want %v", err, authErr)
+	}
+	if resp != nil {
+		t.Errorf("expected nil response, got %v", resp.Status)
+	}
+	if calls != 1 {
+		t.Errorf("upstream calls = %d, want 1", calls)
+	}
+}
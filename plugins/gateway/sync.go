@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// SyncAction 声明式同步的动作类型
+type SyncAction string
+
+const (
+	SyncActionCreate SyncAction = "create"
+	SyncActionUpdate SyncAction = "update"
+	SyncActionDelete SyncAction = "delete"
+)
+
+// syncFields 参与比较的 _proxies 字段（同时决定 diff 输出顺序）
+var syncFields = []string{
+	ProxyFieldPath,
+	ProxyFieldUpstream,
+	ProxyFieldStripPath,
+	ProxyFieldAccessRule,
+	ProxyFieldHeaders,
+	ProxyFieldTimeout,
+	ProxyFieldActive,
+	ProxyFieldMaxConcurrent,
+	ProxyFieldCircuitBreaker,
+	ProxyFieldTimeoutConfig,
+	ProxyFieldTransform,
+	ProxyFieldUpstreamAuth,
+	ProxyFieldRetryPolicy,
+	ProxyFieldTraffic,
+}
+
+// SyncChange 单个代理的变更
+type SyncChange struct {
+	Action SyncAction
+	Path   string
+
+	// Fields 发生变化的字段（仅 update）
+	Fields []string
+
+	definition *ProxyDefinition
+	record     *core.Record
+}
+
+// SyncPlan 配置文件与 _proxies 之间的差异
+// 由 PlanSync 生成，可直接打印（dry-run）或调用 Apply 落库
+type SyncPlan struct {
+	Changes   []*SyncChange
+	Unchanged []string
+}
+
+// PlanSync 比较配置文件与 _proxies 中的现有记录，生成同步计划
+// prune 为 true 时，文件中未声明的代理会被标记为删除
+func PlanSync(app core.App, file *GatewayFile, prune bool) (*SyncPlan, error) {
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+
+	records, err := app.FindAllRecords(CollectionNameProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*core.Record, len(records))
+	for _, record := range records {
+		existing[record.GetString(ProxyFieldPath)] = record
+	}
+
+	plan := &SyncPlan{}
+	declared := make(map[string]struct{}, len(file.Proxies))
+	for _, def := range file.Proxies {
+		declared[def.Path] = struct{}{}
+
+		record, ok := existing[def.Path]
+		if !ok {
+			plan.Changes = append(plan.Changes, &SyncChange{
+				Action:     SyncActionCreate,
+				Path:       def.Path,
+				definition: def,
+			})
+			continue
+		}
+
+		fields, err := diffDefinitions(definitionFromRecord(record), def)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare proxy %s: %w", def.Path, err)
+		}
+		if len(fields) == 0 {
+			plan.Unchanged = append(plan.Unchanged, def.Path)
+			continue
+		}
+
+		plan.Changes = append(plan.Changes, &SyncChange{
+			Action:     SyncActionUpdate,
+			Path:       def.Path,
+			Fields:     fields,
+			definition: def,
+			record:     record,
+		})
+	}
+
+	if prune {
+		deletes := make([]*SyncChange, 0)
+		for path, record := range existing {
+			if _, ok := declared[path]; ok {
+				continue
+			}
+			deletes = append(deletes, &SyncChange{
+				Action: SyncActionDelete,
+				Path:   path,
+				record: record,
+			})
+		}
+		sort.Slice(deletes, func(i, j int) bool {
+			return deletes[i].Path < deletes[j].Path
+		})
+		plan.Changes = append(plan.Changes, deletes...)
+	}
+
+	return plan, nil
+}
+
+// diffDefinitions 返回两个定义之间不同的字段名
+// 两侧都经过 recordValues 填充默认值后再按 JSON 比较，避免默认值造成误报
+func diffDefinitions(current, desired *ProxyDefinition) ([]string, error) {
+	currentValues := current.recordValues()
+	desiredValues := desired.recordValues()
+
+	var changed []string
+	for _, field := range syncFields {
+		a, err := json.Marshal(currentValues[field])
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(desiredValues[field])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(a, b) {
+			changed = append(changed, field)
+		}
+	}
+
+	return changed, nil
+}
+
+// HasChanges 是否存在需要落库的变更
+func (p *SyncPlan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// String 返回类似 diff 的可读摘要：+ 新增、~ 更新、- 删除
+func (p *SyncPlan) String() string {
+	var sb strings.Builder
+
+	for _, change := range p.Changes {
+		switch change.Action {
+		case SyncActionCreate:
+			fmt.Fprintf(&sb, "+ %s\n", change.Path)
+		case SyncActionUpdate:
+			fmt.Fprintf(&sb, "~ %s (%s)\n", change.Path, strings.Join(change.Fields, ", "))
+		case SyncActionDelete:
+			fmt.Fprintf(&sb, "- %s\n", change.Path)
+		}
+	}
+
+	creates, updates, deletes := p.counts()
+	fmt.Fprintf(&sb, "%d to create, %d to update, %d to delete, %d unchanged\n",
+		creates, updates, deletes, len(p.Unchanged))
+
+	return sb.String()
+}
+
+// counts 按动作统计变更数量
+func (p *SyncPlan) counts() (creates, updates, deletes int) {
+	for _, change := range p.Changes {
+		switch change.Action {
+		case SyncActionCreate:
+			creates++
+		case SyncActionUpdate:
+			updates++
+		case SyncActionDelete:
+			deletes++
+		}
+	}
+	return
+}
+
+// Apply 在单个事务中执行同步计划，任一记录失败则整体回滚
+func (p *SyncPlan) Apply(app core.App) error {
+	if !p.HasChanges() {
+		return nil
+	}
+
+	return app.RunInTransaction(func(txApp core.App) error {
+		var collection *core.Collection
+
+		for _, change := range p.Changes {
+			switch change.Action {
+			case SyncActionCreate:
+				if collection == nil {
+					var err error
+					collection, err = txApp.FindCollectionByNameOrId(CollectionNameProxies)
+					if err != nil {
+						return err
+					}
+				}
+
+				record := core.NewRecord(collection)
+				record.Load(change.definition.recordValues())
+				if err := txApp.Save(record); err != nil {
+					return fmt.Errorf("failed to create proxy %s: %w", change.Path, err)
+				}
+			case SyncActionUpdate:
+				change.record.Load(change.definition.recordValues())
+				if err := txApp.Save(change.record); err != nil {
+					return fmt.Errorf("failed to update proxy %s: %w", change.Path, err)
+				}
+			case SyncActionDelete:
+				if err := txApp.Delete(change.record); err != nil {
+					return fmt.Errorf("failed to delete proxy %s: %w", change.Path, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// syncConfigFile 启动时将声明式配置文件同步到 _proxies
+// 文件不存在时跳过；解析或校验失败时仅记录错误，不影响启动（与 processman 行为一致）
+func (p *gatewayPlugin) syncConfigFile() {
+	path := ResolveConfigFile(p.config.ConfigFile)
+	if path == "" {
+		return
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		p.app.Logger().Info("No gateway config file found", "path", path)
+		return
+	}
+
+	file, err := LoadGatewayFile(path)
+	if err != nil {
+		p.app.Logger().Error("Invalid gateway config file", "path", path, "error", err)
+		return
+	}
+
+	plan, err := PlanSync(p.app, file, p.config.PruneProxies)
+	if err != nil {
+		p.app.Logger().Error("Failed to plan gateway config sync", "path", path, "error", err)
+		return
+	}
+
+	if err := plan.Apply(p.app); err != nil {
+		p.app.Logger().Error("Failed to sync gateway config file", "path", path, "error", err)
+		return
+	}
+
+	creates, updates, deletes := plan.counts()
+	p.app.Logger().Info("Gateway config file synced",
+		"path", path,
+		"created", creates,
+		"updated", updates,
+		"deleted", deletes,
+		"unchanged", len(plan.Unchanged),
+	)
+}
@@ -0,0 +1,228 @@
+package gateway_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/gateway"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// createProxyRecord 在测试 app 中创建代理记录
+func createProxyRecord(t *testing.T, app core.App, values map[string]any) *core.Record {
+	t.Helper()
+
+	collection, err := app.FindCollectionByNameOrId(gateway.CollectionNameProxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Load(values)
+	if err := app.Save(record); err != nil {
+		t.Fatal(err)
+	}
+
+	return record
+}
+
+// TestPlanSyncAndApply 测试生成同步计划并落库
+func TestPlanSyncAndApply(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	createProxyRecord(t, app, map[string]any{
+		"path": "/-/same", "upstream": "http://same", "stripPath": true, "timeout": 30, "active": true,
+	})
+	createProxyRecord(t, app, map[string]any{
+		"path": "/-/changed", "upstream": "http://old", "stripPath": true, "timeout": 30, "active": true,
+	})
+	createProxyRecord(t, app, map[string]any{
+		"path": "/-/manual", "upstream": "http://manual", "stripPath": true, "timeout": 30, "active": true,
+	})
+
+	file, err := gateway.ParseGatewayFile([]byte(`
+proxies:
+  - path: /-/same
+    upstream: http://same
+  - path: /-/changed
+    upstream: http://new
+    retryPolicy:
+      max_attempts: 2
+  - path: /-/new
+    upstream: http://new
+`), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 不裁剪：手动创建的代理保持不变
+	plan, err := gateway.PlanSync(app, file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := plan.String()
+	for _, s := range []string{
+		"+ /-/new",
+		"~ /-/changed (upstream, retryPolicy)",
+		"1 to create, 1 to update, 0 to delete, 1 unchanged",
+	} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("plan summary missing %q:\n%s", s, summary)
+		}
+	}
+
+	// dry-run 不应修改数据库
+	if _, err := app.FindFirstRecordByData(gateway.CollectionNameProxies, "path", "/-/new"); err == nil {
+		t.Fatal("PlanSync should not create records")
+	}
+
+	// 裁剪：未声明的代理被删除
+	plan, err = gateway.PlanSync(app, file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan.String(), "- /-/manual") {
+		t.Errorf("expected /-/manual to be deleted:\n%s", plan.String())
+	}
+
+	if err := plan.Apply(app); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	changed, err := app.FindFirstRecordByData(gateway.CollectionNameProxies, "path", "/-/changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.GetString("upstream") != "http://new" {
+		t.Errorf("upstream = %q, want http://new", changed.GetString("upstream"))
+	}
+
+	if _, err := app.FindFirstRecordByData(gateway.CollectionNameProxies, "path", "/-/manual"); err == nil {
+		t.Error("expected /-/manual to be deleted")
+	}
+
+	// 再次比较时应无差异
+	plan, err = gateway.PlanSync(app, file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("expected no changes after apply:\n%s", plan.String())
+	}
+}
+
+// TestExportProxiesRoundTrip 测试导出后重新应用无差异
+func TestExportProxiesRoundTrip(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	createProxyRecord(t, app, map[string]any{
+		"path":           "/-/b",
+		"upstream":       "http://b",
+		"stripPath":      false,
+		"timeout":        60,
+		"active":         true,
+		"headers":        map[string]string{"X-Key": "{env.KEY}"},
+		"circuitBreaker": map[string]any{"enabled": true, "failure_threshold": 3},
+	})
+	createProxyRecord(t, app, map[string]any{
+		"path": "/-/a", "upstream": "http://a", "stripPath": true, "timeout": 30, "active": false,
+	})
+
+	file, err := gateway.ExportProxies(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Proxies) != 2 || file.Proxies[0].Path != "/-/a" {
+		t.Fatalf("unexpected export %+v", file.Proxies)
+	}
+
+	data, err := file.Marshal("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := gateway.ParseGatewayFile(data, "yaml")
+	if err != nil {
+		t.Fatalf("exported file is invalid: %v\n%s", err, data)
+	}
+
+	plan, err := gateway.PlanSync(app, parsed, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("expected no changes for exported file:\n%s\n%s", plan.String(), data)
+	}
+}
+
+// TestGatewayCommand 测试 gateway export/apply/validate 子命令
+func TestGatewayCommand(t *testing.T) {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Cleanup()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "pb_gateway.yaml")
+	os.WriteFile(configPath, []byte("proxies:\n  - path: /-/a\n    upstream: http://a\n"), 0644)
+
+	run := func(args ...string) (string, error) {
+		cmd := gateway.NewCommand(app, gateway.Config{})
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	if _, err := run("validate", configPath); err != nil {
+		t.Fatalf("validate error = %v", err)
+	}
+
+	out, err := run("apply", configPath, "--dry-run")
+	if err != nil {
+		t.Fatalf("apply --dry-run error = %v", err)
+	}
+	if !strings.Contains(out, "+ /-/a") {
+		t.Errorf("dry-run output = %q", out)
+	}
+	if _, err := app.FindFirstRecordByData(gateway.CollectionNameProxies, "path", "/-/a"); err == nil {
+		t.Fatal("dry-run should not create records")
+	}
+
+	if _, err := run("apply", configPath); err != nil {
+		t.Fatalf("apply error = %v", err)
+	}
+	if _, err := app.FindFirstRecordByData(gateway.CollectionNameProxies, "path", "/-/a"); err != nil {
+		t.Fatalf("apply should create /-/a: %v", err)
+	}
+
+	out, err = run("export", "--format", "json")
+	if err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	if !strings.Contains(out, `"path": "/-/a"`) {
+		t.Errorf("export output = %q", out)
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"proxies":[{"path":"/_/x","upstream":"http://a"}]}`), 0644)
+	if _, err := run("validate", invalidPath); err == nil || !strings.Contains(err.Error(), "proxies[0] (/_/x)") {
+		t.Errorf("expected entry error, got %v", err)
+	}
+}
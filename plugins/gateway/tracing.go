@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/pocketbase/pocketbase/plugins/trace"
+)
+
+// TraceparentHeader W3C Trace Context 请求头
+const TraceparentHeader = "traceparent"
+
+// clientSpan 一次上游调用的客户端 Span
+// 无论 trace 插件是否启用都会向上游传播 traceparent；
+// 仅当请求处于 trace 中间件追踪下时记录 Span，是否保留由中间件的模式、过滤器与尾部采样决定
+type clientSpan struct {
+	builder  trace.SpanBuilder // 不在被追踪的请求中时为 nil
+	traceID  string
+	spanID   string
+	parentID string
+	sampled  bool
+}
+
+// startClientSpan 为代理请求创建客户端 Span，name 为 Span 名称
+// 父上下文优先级：trace 插件放入 context 的 Span > context 中的 TraceContext > 客户端 traceparent 头 > 新 trace（不采样）
+func (p *gatewayPlugin) startClientSpan(r *http.Request, name string) *clientSpan {
+	ctx := r.Context()
+
+	span := &clientSpan{spanID: trace.GenerateSpanID()}
+
+	if parent := trace.SpanFromContext(ctx); parent != nil && parent.TraceID != "" {
+		span.traceID = parent.TraceID
+		span.parentID = parent.SpanID
+		span.sampled = true
+	} else if tc := trace.TraceContextFromContext(ctx); tc != nil && tc.TraceID != "" {
+		span.traceID = tc.TraceID
+		span.parentID = tc.ParentID
+		span.sampled = tc.Sampled
+	} else if tc, err := trace.ParseTraceparent(r.Header.Get(TraceparentHeader)); err == nil {
+		span.traceID = tc.TraceID
+		span.parentID = tc.ParentID
+		span.sampled = tc.Sampled
+	} else {
+		span.traceID = trace.GenerateTraceID()
+	}
+
+	// 请求被 trace 中间件追踪时作为其子 Span 记录，未被保留的请求连同子 Span 一起丢弃
+	if spanCtx, builder := trace.StartChildSpan(ctx, name); spanCtx != ctx {
+		if child := trace.SpanFromContext(spanCtx); child != nil {
+			span.builder = builder.SetKind(trace.SpanKindClient)
+			span.traceID = child.TraceID
+			span.spanID = child.SpanID
+			span.parentID = child.ParentID
+		}
+	}
+
+	return span
+}
+
+// traceparent 返回注入上游请求的 traceparent（parent-id 为当前客户端 Span）
+func (s *clientSpan) traceparent() string {
+	return trace.FormatTraceparent(&trace.TraceContext{
+		TraceID:  s.traceID,
+		ParentID: s.spanID,
+		Sampled:  s.sampled,
+	})
+}
+
+// end 结束 Span，请求不在 trace 中间件追踪下时不做任何操作
+func (s *clientSpan) end(entry *accessLogEntry, upstreamURL string) {
+	if s.builder == nil {
+		return
+	}
+
+	status := trace.SpanStatusOK
+	if entry.Status == 0 || entry.Status >= http.StatusInternalServerError {
+		status = trace.SpanStatusError
+	}
+
+	s.builder.
+		SetStatus(status, "").
+		SetAttribute("http.method", entry.Method).
+		SetAttribute("http.url", upstreamURL).
+		SetAttribute("http.status_code", entry.Status).
+		SetAttribute("http.request_size", entry.BytesIn).
+		SetAttribute("http.response_size", entry.BytesOut).
+		SetAttribute("gateway.proxy_id", entry.ProxyID).
+		SetAttribute("gateway.proxy", entry.ProxyPath).
+		SetAttribute("gateway.upstream", entry.Upstream).
+		SetAttribute("gateway.retries", entry.Retries).
+		SetAttribute("gateway.upstream_latency", entry.UpstreamLatency.Milliseconds()).
+		End()
+}
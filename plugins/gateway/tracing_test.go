@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/plugins/trace"
+)
+
+// recordingTracer 记录 Span 的测试 Tracer
+type recordingTracer struct {
+	trace.NoopTracer
+	spans []*trace.Span
+}
+
+func (t *recordingTracer) RecordSpan(span *trace.Span) { t.spans = append(t.spans, span) }
+func (t *recordingTracer) IsEnabled() bool             { return true }
+
+// TestStartClientSpanParent 测试父上下文的选择
+func TestStartClientSpanParent(t *testing.T) {
+	plugin := &gatewayPlugin{}
+
+	t.Run("new trace", func(t *testing.T) {
+		span := plugin.startClientSpan(httptest.NewRequest(http.MethodGet, "/", nil), "GET /")
+		// 没有父追踪时不做采样决定，向上游传播未采样的 traceparent
+		if len(span.traceID) != 32 || span.parentID != "" || span.sampled || span.builder != nil {
+			t.Errorf("unexpected span %+v", span)
+		}
+
+		tc, err := trace.ParseTraceparent(span.traceparent())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.TraceID != span.traceID || tc.ParentID != span.spanID || tc.Sampled {
+			t.Errorf("traceparent = %s", span.traceparent())
+		}
+	})
+
+	t.Run("incoming header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+
+		span := plugin.startClientSpan(req, "GET /")
+		if span.traceID != "0af7651916cd43dd8448eb211c80319c" || span.parentID != "b7ad6b7169203331" || span.sampled {
+			t.Errorf("unexpected span %+v", span)
+		}
+	})
+
+	t.Run("server span in context", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TraceparentHeader, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		ctx := trace.ContextWithSpan(context.Background(), &trace.Span{
+			TraceID: "11111111111111111111111111111111",
+			SpanID:  "2222222222222222",
+		})
+
+		span := plugin.startClientSpan(req.WithContext(ctx), "GET /")
+		if span.traceID != "11111111111111111111111111111111" || span.parentID != "2222222222222222" || !span.sampled {
+			t.Errorf("unexpected span %+v", span)
+		}
+	})
+}
+
+// TestClientSpanRecording 测试客户端 Span 仅作为被追踪请求的子 Span 记录
+func TestClientSpanRecording(t *testing.T) {
+	plugin := &gatewayPlugin{}
+
+	serve := func(tracer trace.Tracer, mode trace.TraceMode) *clientSpan {
+		var span *clientSpan
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span = plugin.startClientSpan(r, "GET /-/api")
+			span.end(&accessLogEntry{Method: "GET", ProxyPath: "/-/api", Status: 502, Retries: 2}, "http://upstream/x")
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/-/api/x", nil)
+		trace.TraceMiddleware(tracer, &trace.MiddlewareConfig{Mode: mode})(handler).ServeHTTP(httptest.NewRecorder(), req)
+		return span
+	}
+
+	t.Run("traced request", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		span := serve(tracer, trace.ModeFull)
+
+		if len(tracer.spans) != 2 {
+			t.Fatalf("recorded %d spans, want 2", len(tracer.spans))
+		}
+
+		client, server := tracer.spans[0], tracer.spans[1]
+		if client.Kind != trace.SpanKindClient || client.Status != trace.SpanStatusError {
+			t.Errorf("kind = %s, status = %s", client.Kind, client.Status)
+		}
+		if client.TraceID != server.TraceID || client.ParentID != server.SpanID || client.Attributes["gateway.retries"] != 2 {
+			t.Errorf("unexpected span %+v", client)
+		}
+
+		tc, err := trace.ParseTraceparent(span.traceparent())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.TraceID != client.TraceID || tc.ParentID != client.SpanID {
+			t.Errorf("traceparent = %s", span.traceparent())
+		}
+	})
+
+	t.Run("request not kept by middleware", func(t *testing.T) {
+		// 条件模式下未命中染色与过滤器的请求不被追踪，客户端 Span 随之丢弃
+		tracer := &recordingTracer{}
+		span := serve(tracer, trace.ModeConditional)
+
+		if len(tracer.spans) != 0 {
+			t.Errorf("recorded %d spans, want 0", len(tracer.spans))
+		}
+		if span.traceID == "" {
+			t.Errorf("traceparent should still be propagated, got %+v", span)
+		}
+	})
+
+	t.Run("no trace middleware", func(t *testing.T) {
+		span := plugin.startClientSpan(httptest.NewRequest(http.MethodGet, "/", nil), "GET /-/api")
+		span.end(&accessLogEntry{Status: 200}, "http://upstream/x")
+
+		if span.builder != nil {
+			t.Errorf("span should not be recorded without a traced request")
+		}
+	})
+}
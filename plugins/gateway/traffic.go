@@ -0,0 +1,297 @@
+// Package gateway 提供 API Gateway 插件功能
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// 流量镜像默认值
+const (
+	DefaultMirrorTimeoutMs     = 5000
+	DefaultMirrorMaxConcurrent = 100
+)
+
+// MirrorHeader 镜像请求携带的标记头，便于影子上游识别
+const MirrorHeader = "X-Gateway-Mirror"
+
+// TrafficConfig 流量拆分与镜像配置
+// 对应 _proxies.traffic 字段（JSON）
+//
+// 示例（90/10 灰度 + 10% 影子流量）：
+//
+//	{
+//	  "splits": [
+//	    {"upstream": "http://api-stable:8080", "weight": 90},
+//	    {"upstream": "http://api-canary:8080", "weight": 10}
+//	  ],
+//	  "sticky": "auth",
+//	  "mirror": {"upstream": "http://api-next:8080", "percent": 10}
+//	}
+type TrafficConfig struct {
+	// Splits 按权重拆分流量的上游列表，为空时使用代理的 upstream
+	Splits []WeightedUpstream `json:"splits,omitempty"`
+
+	// Sticky 粘性选择依据：auth（按用户 ID）| ip（按客户端 IP）| 空（随机）
+	// 保证同一用户在灰度期间始终访问同一版本
+	Sticky string `json:"sticky,omitempty"`
+
+	// Mirror 影子流量配置（可选），镜像请求的响应会被丢弃
+	Mirror *MirrorConfig `json:"mirror,omitempty"`
+}
+
+// WeightedUpstream 带权重的上游
+type WeightedUpstream struct {
+	// Upstream 上游服务地址
+	Upstream string `json:"upstream"`
+
+	// Weight 权重（非负整数）
+	Weight int `json:"weight"`
+}
+
+// MirrorConfig 影子流量配置
+type MirrorConfig struct {
+	// Upstream 镜像目标地址（仅替换 scheme 和 host，路径与主请求一致）
+	Upstream string `json:"upstream"`
+
+	// Percent 镜像比例（0-100），默认 100
+	Percent float64 `json:"percent,omitempty"`
+
+	// TimeoutMs 镜像请求超时（毫秒），默认 5000
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+
+	// PropagateAuth 镜像请求是否携带主上游的认证信息（默认 false）
+	// 关闭时镜像请求不经过上游认证（OAuth2、HMAC、mTLS 客户端证书），
+	// 并移除 Authorization、Cookie 以及代理注入的请求头，避免将主上游的凭据发送给影子上游
+	PropagateAuth bool `json:"propagate_auth,omitempty"`
+}
+
+// Validate 校验流量配置
+func (c *TrafficConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	totalWeight := 0
+	for i, split := range c.Splits {
+		if err := validateUpstreamURL(split.Upstream); err != nil {
+			return fmt.Errorf("traffic.splits[%d].upstream is invalid: %w", i, err)
+		}
+		if split.Weight < 0 {
+			return fmt.Errorf("traffic.splits[%d].weight cannot be negative", i)
+		}
+		totalWeight += split.Weight
+	}
+	if len(c.Splits) > 0 && totalWeight == 0 {
+		return errors.New("traffic.splits total weight must be greater than 0")
+	}
+
+	switch c.Sticky {
+	case "", "auth", "ip":
+	default:
+		return fmt.Errorf("traffic.sticky %q is not supported", c.Sticky)
+	}
+
+	if c.Mirror != nil {
+		if err := validateUpstreamURL(c.Mirror.Upstream); err != nil {
+			return fmt.Errorf("traffic.mirror.upstream is invalid: %w", err)
+		}
+		if c.Mirror.Percent < 0 || c.Mirror.Percent > 100 {
+			return errors.New("traffic.mirror.percent must be between 0 and 100")
+		}
+		if c.Mirror.TimeoutMs < 0 {
+			return errors.New("traffic.mirror.timeout_ms cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// validateUpstreamURL 校验上游地址必须是带 host 的 http(s) URL
+func validateUpstreamURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("host cannot be empty")
+	}
+	return nil
+}
+
+// PickUpstream 根据流量拆分配置为请求选择上游
+// 未配置拆分时返回代理的 upstream
+func (p *ProxyConfig) PickUpstream(r *http.Request, authInfo *AuthInfo) string {
+	if p.Traffic == nil || len(p.Traffic.Splits) == 0 {
+		return p.Upstream
+	}
+
+	totalWeight := 0
+	for _, split := range p.Traffic.Splits {
+		totalWeight += split.Weight
+	}
+	if totalWeight <= 0 {
+		return p.Upstream
+	}
+
+	var n int
+	if key := stickyKey(p.Traffic.Sticky, r, authInfo); key != "" {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		n = int(h.Sum32() % uint32(totalWeight))
+	} else {
+		n = rand.IntN(totalWeight)
+	}
+
+	for _, split := range p.Traffic.Splits {
+		if n < split.Weight {
+			return split.Upstream
+		}
+		n -= split.Weight
+	}
+
+	return p.Traffic.Splits[len(p.Traffic.Splits)-1].Upstream
+}
+
+// stickyKey 返回粘性选择使用的 key，无法确定时返回空
+func stickyKey(sticky string, r *http.Request, authInfo *AuthInfo) string {
+	switch sticky {
+	case "auth":
+		if authInfo != nil {
+			return authInfo.ID
+		}
+	case "ip":
+		if r != nil {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				return r.RemoteAddr
+			}
+			return host
+		}
+	}
+	return ""
+}
+
+// mirrorTransport 将请求异步复制到影子上游，响应被丢弃
+type mirrorTransport struct {
+	next      http.RoundTripper // 主请求链路
+	shadow    http.RoundTripper // 镜像请求使用的 RoundTripper
+	config    MirrorConfig
+	strip     []string // 镜像请求中移除的凭据请求头
+	target    *url.URL
+	sem       chan struct{}
+	proxyName string
+	metrics   *MetricsCollector
+}
+
+// newMirrorTransport 创建流量镜像 RoundTripper
+// strip 为镜像请求中需要移除的凭据请求头（PropagateAuth 为 true 时忽略）
+func newMirrorTransport(next, shadow http.RoundTripper, config *MirrorConfig, strip []string, sem chan struct{}, proxyName string, metrics *MetricsCollector) http.RoundTripper {
+	target, err := url.Parse(config.Upstream)
+	if err != nil {
+		return next
+	}
+
+	mc := *config
+	if mc.Percent == 0 {
+		mc.Percent = 100
+	}
+	if mc.TimeoutMs == 0 {
+		mc.TimeoutMs = DefaultMirrorTimeoutMs
+	}
+
+	return &mirrorTransport{
+		next:      next,
+		shadow:    shadow,
+		config:    mc,
+		strip:     strip,
+		target:    target,
+		sem:       sem,
+		proxyName: proxyName,
+		metrics:   metrics,
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.shouldMirror() && makeBodyReplayable(req) {
+		t.mirror(req)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// shouldMirror 按比例抽样
+func (t *mirrorTransport) shouldMirror() bool {
+	return t.config.Percent >= 100 || rand.Float64()*100 < t.config.Percent
+}
+
+// mirror 异步发送镜像请求
+// 超过并发上限时直接丢弃，避免影子上游变慢拖垮网关
+func (t *mirrorTransport) mirror(req *http.Request) {
+	select {
+	case t.sem <- struct{}{}:
+	default:
+		return
+	}
+
+	// 脱离客户端请求的生命周期：主请求结束后镜像请求仍可继续
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), time.Duration(t.config.TimeoutMs)*time.Millisecond)
+
+	shadowReq, err := cloneRequestForAttempt(req, ctx)
+	if err != nil {
+		cancel()
+		<-t.sem
+		return
+	}
+	shadowReq.URL.Scheme = t.target.Scheme
+	shadowReq.URL.Host = t.target.Host
+	shadowReq.Host = t.target.Host
+	shadowReq.Header.Set(MirrorHeader, "1")
+	if !t.config.PropagateAuth {
+		for _, name := range t.strip {
+			shadowReq.Header.Del(name)
+		}
+	}
+
+	if t.metrics != nil {
+		t.metrics.RecordMirror(t.proxyName)
+	}
+
+	go func() {
+		defer func() { <-t.sem }()
+		defer cancel()
+
+		resp, err := t.shadow.RoundTrip(shadowReq)
+		if err != nil {
+			return
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+}
+
+// mirrorCredentialHeaders 返回镜像请求中默认移除的凭据请求头：
+// 客户端认证头与 Cookie，以及代理配置注入的请求头（通常包含主上游的 API Key）
+func mirrorCredentialHeaders(proxy *ProxyConfig) []string {
+	headers := []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	for name := range proxy.Headers {
+		headers = append(headers, name)
+	}
+	if proxy.Transform.HasRequestTransform() && proxy.Transform.Request.Headers != nil {
+		for name := range proxy.Transform.Request.Headers.Add {
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
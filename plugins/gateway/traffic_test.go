@@ -0,0 +1,242 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestTrafficConfigValidate 测试流量配置校验
+func TestTrafficConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *TrafficConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"valid split", &TrafficConfig{Splits: []WeightedUpstream{
+			{Upstream: "http://a", Weight: 90}, {Upstream: "http://b", Weight: 10},
+		}}, false},
+		{"zero total weight", &TrafficConfig{Splits: []WeightedUpstream{{Upstream: "http://a"}}}, true},
+		{"negative weight", &TrafficConfig{Splits: []WeightedUpstream{{Upstream: "http://a", Weight: -1}}}, true},
+		{"invalid upstream", &TrafficConfig{Splits: []WeightedUpstream{{Upstream: "ftp://a", Weight: 1}}}, true},
+		{"invalid sticky", &TrafficConfig{Sticky: "cookie"}, true},
+		{"valid mirror", &TrafficConfig{Mirror: &MirrorConfig{Upstream: "http://shadow", Percent: 10}}, false},
+		{"invalid mirror percent", &TrafficConfig{Mirror: &MirrorConfig{Upstream: "http://shadow", Percent: 150}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPickUpstream 测试按权重选择上游
+func TestPickUpstream(t *testing.T) {
+	proxy := &ProxyConfig{
+		Upstream: "http://primary",
+		Traffic: &TrafficConfig{Splits: []WeightedUpstream{
+			{Upstream: "http://stable", Weight: 80},
+			{Upstream: "http://canary", Weight: 20},
+			{Upstream: "http://disabled", Weight: 0},
+		}},
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 5000; i++ {
+		counts[proxy.PickUpstream(nil, nil)]++
+	}
+
+	if counts["http://disabled"] != 0 || counts["http://primary"] != 0 {
+		t.Errorf("unexpected upstream selection %v", counts)
+	}
+	ratio := float64(counts["http://canary"]) / 5000
+	if ratio < 0.15 || ratio > 0.25 {
+		t.Errorf("canary ratio = %.2f, want ~0.20", ratio)
+	}
+
+	// 未配置拆分时使用 upstream
+	if got := (&ProxyConfig{Upstream: "http://primary"}).PickUpstream(nil, nil); got != "http://primary" {
+		t.Errorf("PickUpstream() = %q, want http://primary", got)
+	}
+}
+
+// TestPickUpstreamSticky 测试粘性选择
+func TestPickUpstreamSticky(t *testing.T) {
+	proxy := &ProxyConfig{
+		Traffic: &TrafficConfig{
+			Sticky: "auth",
+			Splits: []WeightedUpstream{
+				{Upstream: "http://stable", Weight: 50},
+				{Upstream: "http://canary", Weight: 50},
+			},
+		},
+	}
+
+	authInfo := &AuthInfo{ID: "user123"}
+	first := proxy.PickUpstream(nil, authInfo)
+	for i := 0; i < 50; i++ {
+		if got := proxy.PickUpstream(nil, authInfo); got != first {
+			t.Fatalf("sticky selection changed: %q != %q", got, first)
+		}
+	}
+
+	proxy.Traffic.Sticky = "ip"
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	first = proxy.PickUpstream(req, nil)
+	req.RemoteAddr = "10.0.0.1:5678"
+	if got := proxy.PickUpstream(req, nil); got != first {
+		t.Errorf("ip sticky selection changed across ports: %q != %q", got, first)
+	}
+}
+
+// TestMirrorTransport 测试影子流量：主请求正常返回，镜像请求响应被丢弃
+func TestMirrorTransport(t *testing.T) {
+	mirrored := make(chan string, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(MirrorHeader) != "1" {
+			t.Error("expected mirror header")
+		}
+		mirrored <- r.URL.Path + ":" + string(body)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer primary.Close()
+
+	m := NewManagerWithConfig(nil, ManagerConfig{
+		TransportConfig: DefaultTransportConfig(),
+		Metrics:         NewMetricsCollector(),
+	})
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: primary.URL, Active: true,
+		Traffic: &TrafficConfig{Mirror: &MirrorConfig{Upstream: shadow.URL, Percent: 100}}}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	client := &http.Client{Transport: m.RoundTripper(proxy)}
+	resp, err := client.Post(primary.URL+"/items", "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("primary response = %d %q, want 200 hello", resp.StatusCode, body)
+	}
+
+	select {
+	case got := <-mirrored:
+		if got != "/items:hello" {
+			t.Errorf("mirrored request = %q, want /items:hello", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("mirror request not received")
+	}
+
+	if got := m.Metrics().GetStats("/-/api").MirroredTotal; got != 1 {
+		t.Errorf("MirroredTotal = %d, want 1", got)
+	}
+}
+
+// TestMirrorTransportUpstreamAuth 测试镜像请求默认不携带主上游的认证信息，PropagateAuth 开启时才携带
+func TestMirrorTransportUpstreamAuth(t *testing.T) {
+	var tokenCalls int32
+	tokenServer := newTestTokenServer(t, 3600, &tokenCalls)
+	defer tokenServer.Close()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			t.Errorf("primary Authorization = %q", r.Header.Get("Authorization"))
+		}
+	}))
+	defer primary.Close()
+
+	for _, propagate := range []bool{false, true} {
+		mirrored := make(chan http.Header, 1)
+		shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mirrored <- r.Header.Clone()
+		}))
+
+		m := NewManagerWithConfig(nil, ManagerConfig{TransportConfig: DefaultTransportConfig()})
+		proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: primary.URL, Active: true,
+			Headers: map[string]string{"X-Api-Key": "primary-key"},
+			UpstreamAuth: &UpstreamAuthConfig{Type: UpstreamAuthOAuth2, OAuth2: &OAuth2ClientConfig{
+				TokenURL: tokenServer.URL, ClientID: "client", ClientSecret: "s3cret",
+			}},
+			Traffic: &TrafficConfig{Mirror: &MirrorConfig{Upstream: shadow.URL, PropagateAuth: propagate}}}
+		m.SetProxies([]*ProxyConfig{proxy})
+
+		// 模拟 Director 注入的代理请求头与客户端 Cookie
+		req, _ := http.NewRequest(http.MethodGet, primary.URL+"/items", nil)
+		req.Header.Set("X-Api-Key", "primary-key")
+		req.Header.Set("Cookie", "session=abc")
+
+		resp, err := m.RoundTripper(proxy).RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		select {
+		case header := <-mirrored:
+			if propagate {
+				if !strings.HasPrefix(header.Get("Authorization"), "Bearer token-") {
+					t.Errorf("[propagate] mirror Authorization = %q, want bearer token", header.Get("Authorization"))
+				}
+			} else {
+				for _, name := range []string{"Authorization", "X-Api-Key", "Cookie"} {
+					if v := header.Get(name); v != "" {
+						t.Errorf("mirror request has %s = %q", name, v)
+					}
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("mirror request not received")
+		}
+
+		shadow.Close()
+	}
+}
+
+// TestMirrorTransportPercent 测试镜像比例为 0 之外的抽样
+func TestMirrorTransportPercent(t *testing.T) {
+	var mirrored int32
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&mirrored, 1)
+	}))
+	defer shadow.Close()
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer primary.Close()
+
+	sem := make(chan struct{}, DefaultMirrorMaxConcurrent)
+	rt := newMirrorTransport(http.DefaultTransport, http.DefaultTransport, &MirrorConfig{Upstream: shadow.URL, Percent: 0.0001}, nil, sem, "test", nil)
+
+	for i := 0; i < 20; i++ {
+		req, _ := http.NewRequest(http.MethodGet, primary.URL, nil)
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&mirrored); n > 1 {
+		t.Errorf("mirrored %d requests, expected almost none", n)
+	}
+}
@@ -0,0 +1,465 @@
+// Package gateway 提供 API Gateway 插件功能
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxTransformBodySize 允许进行 JSON Body 变换的最大字节数
+// 超出该大小的 Body 原样透传，避免为大文件/流式请求缓冲全部内容
+const MaxTransformBodySize = 1 << 20 // 1MB
+
+// TransformConfig 代理的请求/响应变换配置
+// 对应 _proxies.transform 字段（JSON）
+//
+// 示例：
+//
+//	{
+//	  "request": {
+//	    "headers": {"add": {"X-User": "{auth.id}"}, "remove": ["Cookie"]},
+//	    "query": {"rename": {"q": "query"}},
+//	    "body": {"set": {"user": "{auth.id}"}, "remove": ["debug"]},
+//	    "path_rewrite": {"pattern": "^/v1/(.*)$", "replacement": "/api/$1"}
+//	  },
+//	  "response": {
+//	    "headers": {"remove": ["Server", "X-Powered-By"]},
+//	    "status_map": {"404": 200},
+//	    "error_body": "{\"error\":\"upstream failed\",\"status\":{status}}"
+//	  }
+//	}
+type TransformConfig struct {
+	// Request 请求变换（在 Director 中执行）
+	Request *RequestTransform `json:"request,omitempty"`
+
+	// Response 响应变换（在 ModifyResponse 中执行）
+	Response *ResponseTransform `json:"response,omitempty"`
+}
+
+// RequestTransform 请求变换配置
+type RequestTransform struct {
+	// Headers 请求头增删改
+	Headers *HeaderTransform `json:"headers,omitempty"`
+
+	// Query 查询参数增删改
+	Query *QueryTransform `json:"query,omitempty"`
+
+	// Body JSON 请求体字段设置/删除
+	// 仅对 Content-Type 为 JSON 且不超过 MaxTransformBodySize 的请求生效
+	Body *BodyTransform `json:"body,omitempty"`
+
+	// PathRewrite 上游路径正则重写
+	PathRewrite *PathRewrite `json:"path_rewrite,omitempty"`
+}
+
+// ResponseTransform 响应变换配置
+type ResponseTransform struct {
+	// Headers 响应头增删改
+	Headers *HeaderTransform `json:"headers,omitempty"`
+
+	// StatusMap 状态码映射，如 {"404": 200}
+	StatusMap map[int]int `json:"status_map,omitempty"`
+
+	// Body JSON 响应体字段设置/删除
+	// 流式响应（SSE）、非 JSON 或超过 MaxTransformBodySize 的响应不做处理
+	Body *BodyTransform `json:"body,omitempty"`
+
+	// ErrorBody 上游返回 >= 400 时替换的响应体模板（JSON）
+	// 支持 {status} 占位符（映射后的状态码）
+	ErrorBody string `json:"error_body,omitempty"`
+}
+
+// HeaderTransform 头部变换
+// 执行顺序：rename -> remove -> add
+type HeaderTransform struct {
+	// Add 添加/覆盖头部，值支持模板语法（同 headers 字段）
+	Add map[string]string `json:"add,omitempty"`
+
+	// Remove 删除的头部名称
+	Remove []string `json:"remove,omitempty"`
+
+	// Rename 重命名头部，key 为原名称，value 为新名称
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// QueryTransform 查询参数变换
+// 执行顺序：rename -> remove -> add
+type QueryTransform struct {
+	// Add 添加/覆盖参数，值支持模板语法
+	Add map[string]string `json:"add,omitempty"`
+
+	// Remove 删除的参数名称
+	Remove []string `json:"remove,omitempty"`
+
+	// Rename 重命名参数，key 为原名称，value 为新名称
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// BodyTransform JSON Body 变换
+// 路径使用点号分隔，如 "meta.owner"
+type BodyTransform struct {
+	// Set 设置字段值，字符串值支持模板语法
+	Set map[string]any `json:"set,omitempty"`
+
+	// Remove 删除的字段路径
+	Remove []string `json:"remove,omitempty"`
+}
+
+// PathRewrite 路径重写配置
+type PathRewrite struct {
+	// Pattern 匹配上游路径的正则表达式
+	Pattern string `json:"pattern"`
+
+	// Replacement 替换内容，支持 $1、${name} 等捕获组引用
+	Replacement string `json:"replacement"`
+
+	regex *regexp.Regexp
+}
+
+// Validate 校验变换配置并预编译正则表达式
+// 应在加载代理配置时调用
+func (tc *TransformConfig) Validate() error {
+	if tc == nil {
+		return nil
+	}
+
+	if tc.Request != nil && tc.Request.PathRewrite != nil {
+		pr := tc.Request.PathRewrite
+		if pr.Pattern == "" {
+			return errors.New("request.path_rewrite.pattern cannot be empty")
+		}
+		regex, err := regexp.Compile(pr.Pattern)
+		if err != nil {
+			return fmt.Errorf("request.path_rewrite.pattern is invalid: %w", err)
+		}
+		pr.regex = regex
+	}
+
+	if tc.Response != nil {
+		for from, to := range tc.Response.StatusMap {
+			if from < 100 || from > 599 || to < 100 || to > 599 {
+				return fmt.Errorf("response.status_map contains invalid status code %d -> %d", from, to)
+			}
+		}
+	}
+
+	return nil
+}
+
+// HasRequestTransform 是否配置了请求变换
+func (tc *TransformConfig) HasRequestTransform() bool {
+	return tc != nil && tc.Request != nil
+}
+
+// HasResponseTransform 是否配置了响应变换
+func (tc *TransformConfig) HasResponseTransform() bool {
+	return tc != nil && tc.Response != nil
+}
+
+// ApplyRequestTransform 对上游请求执行变换
+// 在 ReverseProxy.Director 中调用，模板渲染失败的值会被跳过
+func ApplyRequestTransform(req *http.Request, rt *RequestTransform, authInfo *AuthInfo, secretGetter SecretGetter) error {
+	if rt == nil {
+		return nil
+	}
+
+	// 1. 路径重写
+	if rt.PathRewrite != nil {
+		rewritePath(req.URL, rt.PathRewrite)
+	}
+
+	// 2. 查询参数
+	if rt.Query != nil {
+		query := req.URL.Query()
+		applyQueryTransform(query, rt.Query, authInfo, secretGetter)
+		req.URL.RawQuery = query.Encode()
+	}
+
+	// 3. 请求头
+	if rt.Headers != nil {
+		applyHeaderTransform(req.Header, rt.Headers, authInfo, secretGetter)
+	}
+
+	// 4. JSON Body
+	if rt.Body != nil && req.Body != nil && req.Body != http.NoBody && isJSONContentType(req.Header.Get("Content-Type")) {
+		body, ok, err := readLimitedBody(req.Body, req.ContentLength)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// 超过大小限制，原样透传
+			req.Body = body
+			return nil
+		}
+
+		data, _ := io.ReadAll(body)
+		transformed, err := applyBodyTransform(data, rt.Body, authInfo, secretGetter)
+		if err != nil {
+			// 非法 JSON，原样透传
+			transformed = data
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(transformed))
+		req.ContentLength = int64(len(transformed))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(transformed)), nil
+		}
+	}
+
+	return nil
+}
+
+// ApplyResponseTransform 对上游响应执行变换
+// 在 ReverseProxy.ModifyResponse 中调用
+func ApplyResponseTransform(resp *http.Response, rt *ResponseTransform, authInfo *AuthInfo, secretGetter SecretGetter) error {
+	if rt == nil || resp == nil {
+		return nil
+	}
+
+	// 1. 状态码映射
+	if to, ok := rt.StatusMap[resp.StatusCode]; ok {
+		resp.StatusCode = to
+		resp.Status = fmt.Sprintf("%d %s", to, http.StatusText(to))
+	}
+
+	// 2. 响应头
+	if rt.Headers != nil {
+		applyHeaderTransform(resp.Header, rt.Headers, authInfo, secretGetter)
+	}
+
+	// 3. 错误响应体重写（无需读取原始 Body）
+	if rt.ErrorBody != "" && resp.StatusCode >= 400 {
+		body := strings.ReplaceAll(rt.ErrorBody, "{status}", strconv.Itoa(resp.StatusCode))
+		replaceResponseBody(resp, []byte(body))
+		resp.Header.Set("Content-Type", "application/json")
+		return nil
+	}
+
+	// 4. JSON Body（跳过流式响应与压缩响应）
+	if rt.Body != nil && resp.Body != nil && !isStreamingResponse(resp) && !hasContentEncoding(resp.Header) && isJSONContentType(resp.Header.Get("Content-Type")) {
+		body, ok, err := readLimitedBody(resp.Body, resp.ContentLength)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			resp.Body = body
+			return nil
+		}
+
+		data, _ := io.ReadAll(body)
+		transformed, err := applyBodyTransform(data, rt.Body, authInfo, secretGetter)
+		if err != nil {
+			// 解析或变换失败时原样返回，不修改响应头
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			return nil
+		}
+		replaceResponseBody(resp, transformed)
+	}
+
+	return nil
+}
+
+// hasContentEncoding 判断响应体是否经过压缩等编码（identity 除外）
+func hasContentEncoding(header http.Header) bool {
+	encoding := strings.TrimSpace(header.Get("Content-Encoding"))
+	return encoding != "" && !strings.EqualFold(encoding, "identity")
+}
+
+// rewritePath 使用正则重写 URL 路径
+func rewritePath(u *url.URL, pr *PathRewrite) {
+	regex := pr.regex
+	if regex == nil {
+		var err error
+		regex, err = regexp.Compile(pr.Pattern)
+		if err != nil {
+			return
+		}
+	}
+
+	if !regex.MatchString(u.Path) {
+		return
+	}
+
+	u.Path = regex.ReplaceAllString(u.Path, pr.Replacement)
+	u.RawPath = ""
+}
+
+// applyHeaderTransform 执行头部变换
+func applyHeaderTransform(header http.Header, ht *HeaderTransform, authInfo *AuthInfo, secretGetter SecretGetter) {
+	for from, to := range ht.Rename {
+		values := header.Values(from)
+		if len(values) == 0 {
+			continue
+		}
+		header.Del(from)
+		for _, v := range values {
+			header.Add(to, v)
+		}
+	}
+
+	for _, name := range ht.Remove {
+		header.Del(name)
+	}
+
+	for name, template := range ht.Add {
+		value, err := ParseHeaderTemplate(template, authInfo, secretGetter)
+		if err != nil || value == "" {
+			continue
+		}
+		header.Set(name, value)
+	}
+}
+
+// applyQueryTransform 执行查询参数变换
+func applyQueryTransform(query url.Values, qt *QueryTransform, authInfo *AuthInfo, secretGetter SecretGetter) {
+	for from, to := range qt.Rename {
+		values, ok := query[from]
+		if !ok {
+			continue
+		}
+		delete(query, from)
+		query[to] = append(query[to], values...)
+	}
+
+	for _, name := range qt.Remove {
+		query.Del(name)
+	}
+
+	for name, template := range qt.Add {
+		value, err := ParseHeaderTemplate(template, authInfo, secretGetter)
+		if err != nil {
+			continue
+		}
+		query.Set(name, value)
+	}
+}
+
+// applyBodyTransform 对 JSON 文档执行字段设置和删除
+// 仅支持顶层为对象的 JSON
+func applyBodyTransform(data []byte, bt *BodyTransform, authInfo *AuthInfo, secretGetter SecretGetter) ([]byte, error) {
+	doc := map[string]any{}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range bt.Remove {
+		removeJSONPath(doc, splitJSONPath(path))
+	}
+
+	for path, value := range bt.Set {
+		if str, ok := value.(string); ok {
+			rendered, err := ParseHeaderTemplate(str, authInfo, secretGetter)
+			if err != nil {
+				continue
+			}
+			value = rendered
+		}
+		setJSONPath(doc, splitJSONPath(path), value)
+	}
+
+	return json.Marshal(doc)
+}
+
+// splitJSONPath 拆分点号分隔的路径
+func splitJSONPath(path string) []string {
+	return strings.Split(strings.Trim(path, "."), ".")
+}
+
+// setJSONPath 按路径设置值，自动创建中间对象
+func setJSONPath(doc map[string]any, keys []string, value any) {
+	current := doc
+	for i, key := range keys {
+		if i == len(keys)-1 {
+			current[key] = value
+			return
+		}
+
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			current[key] = next
+		}
+		current = next
+	}
+}
+
+// removeJSONPath 按路径删除字段
+func removeJSONPath(doc map[string]any, keys []string) {
+	current := doc
+	for i, key := range keys {
+		if i == len(keys)-1 {
+			delete(current, key)
+			return
+		}
+
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			return
+		}
+		current = next
+	}
+}
+
+// readLimitedBody 读取不超过 MaxTransformBodySize 的 Body
+// 返回的 ReadCloser 始终包含完整的原始内容；ok 为 false 表示超过限制
+func readLimitedBody(body io.ReadCloser, contentLength int64) (io.ReadCloser, bool, error) {
+	if contentLength > MaxTransformBodySize {
+		return body, false, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(body, MaxTransformBodySize+1))
+	if err != nil {
+		body.Close()
+		return nil, false, err
+	}
+
+	if len(buf) > MaxTransformBodySize {
+		// 拼接已读取部分和剩余部分，保持原始内容完整
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), body), body}, false, nil
+	}
+
+	body.Close()
+	return io.NopCloser(bytes.NewReader(buf)), true, nil
+}
+
+// replaceResponseBody 替换响应体并更新长度
+func replaceResponseBody(resp *http.Response, body []byte) {
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Del("Content-Encoding")
+}
+
+// isJSONContentType 判断是否为 JSON 内容类型
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isStreamingResponse 判断是否为 SSE 流式响应
+func isStreamingResponse(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
@@ -0,0 +1,384 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestTransformConfigValidate 测试变换配置校验
+func TestTransformConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *TransformConfig
+		wantErr bool
+	}{
+		{"nil config", nil, false},
+		{"empty config", &TransformConfig{}, false},
+		{"valid path rewrite", &TransformConfig{Request: &RequestTransform{
+			PathRewrite: &PathRewrite{Pattern: "^/v1/(.*)$", Replacement: "/api/$1"},
+		}}, false},
+		{"empty pattern", &TransformConfig{Request: &RequestTransform{
+			PathRewrite: &PathRewrite{Pattern: ""},
+		}}, true},
+		{"invalid pattern", &TransformConfig{Request: &RequestTransform{
+			PathRewrite: &PathRewrite{Pattern: "("},
+		}}, true},
+		{"valid status map", &TransformConfig{Response: &ResponseTransform{
+			StatusMap: map[int]int{404: 200},
+		}}, false},
+		{"invalid status map", &TransformConfig{Response: &ResponseTransform{
+			StatusMap: map[int]int{404: 1000},
+		}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestTransformConfigUnmarshal 测试从 JSON 解析变换配置
+func TestTransformConfigUnmarshal(t *testing.T) {
+	raw := `{
+		"request": {"path_rewrite": {"pattern": "^/a/(.*)$", "replacement": "/b/$1"}},
+		"response": {"status_map": {"404": 200}}
+	}`
+
+	var tc TransformConfig
+	if err := json.Unmarshal([]byte(raw), &tc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := tc.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if tc.Request.PathRewrite.regex == nil {
+		t.Error("expected path rewrite regex to be compiled")
+	}
+	if tc.Response.StatusMap[404] != 200 {
+		t.Errorf("StatusMap[404] = %d, want 200", tc.Response.StatusMap[404])
+	}
+}
+
+// TestApplyRequestTransform 测试请求变换
+func TestApplyRequestTransform(t *testing.T) {
+	rt := &RequestTransform{
+		Headers: &HeaderTransform{
+			Add:    map[string]string{"X-User": "{auth.id}"},
+			Remove: []string{"Cookie"},
+			Rename: map[string]string{"X-Token": "Authorization"},
+		},
+		Query: &QueryTransform{
+			Add:    map[string]string{"version": "2"},
+			Remove: []string{"debug"},
+			Rename: map[string]string{"q": "query"},
+		},
+		Body: &BodyTransform{
+			Set:    map[string]any{"user": "{auth.id}", "meta.source": "gateway"},
+			Remove: []string{"internal"},
+		},
+		PathRewrite: &PathRewrite{Pattern: "^/v1/(.*)$", Replacement: "/api/v2/$1"},
+	}
+	tc := &TransformConfig{Request: rt}
+	if err := tc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/v1/chat?q=hello&debug=1", strings.NewReader(`{"model":"gpt","internal":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("X-Token", "Bearer abc")
+
+	authInfo := &AuthInfo{ID: "user123"}
+	if err := ApplyRequestTransform(req, rt, authInfo, nil); err != nil {
+		t.Fatalf("ApplyRequestTransform() error = %v", err)
+	}
+
+	if req.URL.Path != "/api/v2/chat" {
+		t.Errorf("Path = %q, want /api/v2/chat", req.URL.Path)
+	}
+
+	query := req.URL.Query()
+	if query.Get("query") != "hello" || query.Has("q") || query.Has("debug") || query.Get("version") != "2" {
+		t.Errorf("unexpected query %q", req.URL.RawQuery)
+	}
+
+	if req.Header.Get("Cookie") != "" {
+		t.Error("expected Cookie header to be removed")
+	}
+	if req.Header.Get("Authorization") != "Bearer abc" || req.Header.Get("X-Token") != "" {
+		t.Error("expected X-Token to be renamed to Authorization")
+	}
+	if req.Header.Get("X-User") != "user123" {
+		t.Errorf("X-User = %q, want user123", req.Header.Get("X-User"))
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if req.ContentLength != int64(len(body)) {
+		t.Errorf("ContentLength = %d, want %d", req.ContentLength, len(body))
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["user"] != "user123" || doc["model"] != "gpt" {
+		t.Errorf("unexpected body %s", body)
+	}
+	if _, ok := doc["internal"]; ok {
+		t.Errorf("expected internal to be removed, got %s", body)
+	}
+	if meta, _ := doc["meta"].(map[string]any); meta["source"] != "gateway" {
+		t.Errorf("expected meta.source to be set, got %s", body)
+	}
+}
+
+// TestApplyRequestTransformNonJSONBody 测试非 JSON Body 原样透传
+func TestApplyRequestTransformNonJSONBody(t *testing.T) {
+	rt := &RequestTransform{Body: &BodyTransform{Set: map[string]any{"user": "x"}}}
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/", strings.NewReader("plain text"))
+	req.Header.Set("Content-Type", "text/plain")
+
+	if err := ApplyRequestTransform(req, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != "plain text" {
+		t.Errorf("body = %q, want unchanged", body)
+	}
+}
+
+// TestApplyRequestTransformLargeBody 测试超过大小限制的 Body 原样透传
+func TestApplyRequestTransformLargeBody(t *testing.T) {
+	rt := &RequestTransform{Body: &BodyTransform{Set: map[string]any{"user": "x"}}}
+
+	large := `{"data":"` + strings.Repeat("a", MaxTransformBodySize) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/", strings.NewReader(large))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+
+	if err := ApplyRequestTransform(req, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != large {
+		t.Error("expected large body to be passed through unchanged")
+	}
+}
+
+// TestApplyResponseTransform 测试响应变换
+func TestApplyResponseTransform(t *testing.T) {
+	rt := &ResponseTransform{
+		Headers:   &HeaderTransform{Remove: []string{"Server"}, Add: map[string]string{"X-Gateway": "pb"}},
+		StatusMap: map[int]int{201: 200},
+		Body:      &BodyTransform{Remove: []string{"secret"}},
+	}
+
+	resp := &http.Response{
+		StatusCode:    201,
+		Header:        http.Header{"Content-Type": {"application/json"}, "Server": {"nginx"}},
+		Body:          io.NopCloser(strings.NewReader(`{"id":1,"secret":"x"}`)),
+		ContentLength: -1,
+	}
+
+	if err := ApplyResponseTransform(resp, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+	if resp.Header.Get("Server") != "" || resp.Header.Get("X-Gateway") != "pb" {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"id":1}` {
+		t.Errorf("body = %s, want {\"id\":1}", body)
+	}
+}
+
+// TestApplyResponseTransformErrorBody 测试上游错误响应体重写
+func TestApplyResponseTransformErrorBody(t *testing.T) {
+	rt := &ResponseTransform{
+		StatusMap: map[int]int{500: 502},
+		ErrorBody: `{"error":"upstream failed","status":{status}}`,
+	}
+
+	resp := &http.Response{
+		StatusCode: 500,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       io.NopCloser(strings.NewReader("<html>stack trace</html>")),
+	}
+
+	if err := ApplyResponseTransform(resp, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"error":"upstream failed","status":502}` {
+		t.Errorf("body = %s", body)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+}
+
+// TestApplyResponseTransformSkipsStreaming 测试 SSE 响应不被缓冲
+func TestApplyResponseTransformSkipsStreaming(t *testing.T) {
+	rt := &ResponseTransform{Body: &BodyTransform{Remove: []string{"x"}}}
+
+	original := io.NopCloser(strings.NewReader("data: {\"x\":1}\n\n"))
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       original,
+	}
+
+	if err := ApplyResponseTransform(resp, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Body != original {
+		t.Error("expected streaming body to be left untouched")
+	}
+}
+
+// TestApplyResponseTransformSkipsCompressed 测试压缩的 JSON 响应体不被变换，响应头保持不变
+func TestApplyResponseTransformSkipsCompressed(t *testing.T) {
+	rt := &ResponseTransform{Body: &BodyTransform{Remove: []string{"secret"}}}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"id":1,"secret":"x"}`))
+	gz.Close()
+	compressed := buf.Bytes()
+
+	resp := &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Content-Type":     {"application/json"},
+			"Content-Encoding": {"gzip"},
+			"Content-Length":   {strconv.Itoa(len(compressed))},
+		},
+		Body:          io.NopCloser(bytes.NewReader(compressed)),
+		ContentLength: int64(len(compressed)),
+	}
+
+	if err := ApplyResponseTransform(resp, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", resp.Header.Get("Content-Encoding"))
+	}
+
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("expected gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != `{"id":1,"secret":"x"}` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+// TestApplyResponseTransformInvalidJSON 测试响应体解析失败时原样返回
+func TestApplyResponseTransformInvalidJSON(t *testing.T) {
+	rt := &ResponseTransform{Body: &BodyTransform{Remove: []string{"secret"}}}
+
+	resp := &http.Response{
+		StatusCode:    200,
+		Header:        http.Header{"Content-Type": {"application/json"}, "Content-Length": {"9"}, "Content-Encoding": {"identity"}},
+		Body:          io.NopCloser(strings.NewReader("not json!")),
+		ContentLength: 9,
+	}
+
+	if err := ApplyResponseTransform(resp, rt, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "not json!" {
+		t.Errorf("body = %s", body)
+	}
+	if resp.Header.Get("Content-Encoding") != "identity" || resp.Header.Get("Content-Length") != "9" {
+		t.Errorf("expected headers to be untouched, got %v", resp.Header)
+	}
+}
+
+// TestReverseProxyWithTransform 测试变换在 ReverseProxy 中端到端生效
+func TestReverseProxyWithTransform(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Powered-By", "php")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","body":` + string(body) + `}`))
+	}))
+	defer upstream.Close()
+
+	tc := &TransformConfig{
+		Request: &RequestTransform{
+			Body:        &BodyTransform{Set: map[string]any{"user": "{auth.id}"}},
+			PathRewrite: &PathRewrite{Pattern: "^/old/(.*)$", Replacement: "/new/$1"},
+		},
+		Response: &ResponseTransform{
+			Headers: &HeaderTransform{Remove: []string{"X-Powered-By"}},
+		},
+	}
+	if err := tc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	target, _ := url.Parse(upstream.URL)
+	authInfo := &AuthInfo{ID: "u1"}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			ApplyRequestTransform(req, tc.Request, authInfo, nil)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			return ApplyResponseTransform(resp, tc.Response, authInfo, nil)
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/old/items", strings.NewReader(`{"a":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	if rec.Header().Get("X-Powered-By") != "" {
+		t.Error("expected X-Powered-By to be removed")
+	}
+
+	var result struct {
+		Path string         `json:"path"`
+		Body map[string]any `json:"body"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response %s: %v", rec.Body.String(), err)
+	}
+	if result.Path != "/new/items" {
+		t.Errorf("upstream path = %q, want /new/items", result.Path)
+	}
+	if result.Body["user"] != "u1" {
+		t.Errorf("upstream body = %v, want user=u1", result.Body)
+	}
+}
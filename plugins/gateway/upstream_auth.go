@@ -0,0 +1,617 @@
+// Package gateway 提供 API Gateway 插件功能
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 上游认证模式
+const (
+	UpstreamAuthOAuth2 = "oauth2" // OAuth2 Client Credentials
+	UpstreamAuthHMAC   = "hmac"   // HMAC 请求签名
+)
+
+// DefaultTokenRefreshSkew Token 提前刷新的时间窗口
+// 避免 Token 在请求途中过期
+const DefaultTokenRefreshSkew = 30 * time.Second
+
+// DefaultTokenFetchTimeout 单次向 Token 端点获取令牌的超时时间
+// 获取与发起请求的客户端解耦，客户端断开不会中断获取
+const DefaultTokenFetchTimeout = 30 * time.Second
+
+// UpstreamAuthConfig 上游认证配置
+// 对应 _proxies.upstreamAuth 字段（JSON）
+//
+// 示例：
+//
+//	{
+//	  "type": "oauth2",
+//	  "oauth2": {
+//	    "token_url": "https://auth.example.com/oauth/token",
+//	    "client_id": "{secret.UPSTREAM_CLIENT_ID}",
+//	    "client_secret": "{secret.UPSTREAM_CLIENT_SECRET}",
+//	    "scopes": ["read", "write"]
+//	  },
+//	  "tls": {"ca_file": "/etc/ssl/upstream-ca.pem"}
+//	}
+type UpstreamAuthConfig struct {
+	// Type 认证模式：oauth2 | hmac，空表示仅使用 TLS 配置
+	Type string `json:"type,omitempty"`
+
+	// OAuth2 Client Credentials 配置
+	OAuth2 *OAuth2ClientConfig `json:"oauth2,omitempty"`
+
+	// HMAC 请求签名配置
+	HMAC *HMACSignConfig `json:"hmac,omitempty"`
+
+	// TLS 客户端证书与自定义 CA 配置
+	TLS *UpstreamTLSConfig `json:"tls,omitempty"`
+}
+
+// OAuth2ClientConfig OAuth2 Client Credentials 配置
+// client_id / client_secret 支持模板语法（{secret.X}、{env.X}）
+type OAuth2ClientConfig struct {
+	// TokenURL Token 端点地址
+	TokenURL string `json:"token_url"`
+
+	// ClientID 客户端 ID
+	ClientID string `json:"client_id"`
+
+	// ClientSecret 客户端密钥
+	ClientSecret string `json:"client_secret"`
+
+	// Scopes 申请的权限范围
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Audience 目标受众（部分 IdP 需要，如 Auth0）
+	Audience string `json:"audience,omitempty"`
+
+	// AuthStyle 客户端凭据传递方式：header（Basic Auth，默认）| body
+	AuthStyle string `json:"auth_style,omitempty"`
+}
+
+// HMACSignConfig HMAC 请求签名配置
+//
+// 签名字符串由 components 按顺序拼接（以 separator 分隔）：
+// - method: 大写请求方法
+// - path: 请求路径
+// - query: 按 key 排序后的查询字符串
+// - timestamp: Unix 秒级时间戳（同时写入 timestamp_header）
+// - body: 请求体原文
+// - body_sha256: 请求体 SHA256（hex）
+// - header:Name: 指定请求头的值
+type HMACSignConfig struct {
+	// Secret 签名密钥，支持模板语法
+	Secret string `json:"secret"`
+
+	// Algorithm 哈希算法：sha256（默认）| sha512 | sha1
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Components 参与签名的组成部分，默认 ["method", "path", "timestamp", "body_sha256"]
+	Components []string `json:"components,omitempty"`
+
+	// Separator 组成部分之间的分隔符，默认 "\n"
+	Separator string `json:"separator,omitempty"`
+
+	// Encoding 签名编码：hex（默认）| base64
+	Encoding string `json:"encoding,omitempty"`
+
+	// SignatureHeader 签名写入的请求头，默认 X-Signature
+	SignatureHeader string `json:"signature_header,omitempty"`
+
+	// SignaturePrefix 签名值前缀，如 "sha256="
+	SignaturePrefix string `json:"signature_prefix,omitempty"`
+
+	// TimestampHeader 时间戳写入的请求头，默认 X-Timestamp
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+
+	// KeyID 密钥标识（可选），写入 key_id_header
+	KeyID string `json:"key_id,omitempty"`
+
+	// KeyIDHeader 密钥标识请求头，默认 X-Key-Id
+	KeyIDHeader string `json:"key_id_header,omitempty"`
+}
+
+// Validate 校验上游认证配置
+func (c *UpstreamAuthConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Type {
+	case "":
+	case UpstreamAuthOAuth2:
+		if c.OAuth2 == nil {
+			return errors.New("upstreamAuth.oauth2 is required for type oauth2")
+		}
+		if c.OAuth2.TokenURL == "" {
+			return errors.New("upstreamAuth.oauth2.token_url cannot be empty")
+		}
+		if _, err := url.ParseRequestURI(c.OAuth2.TokenURL); err != nil {
+			return fmt.Errorf("upstreamAuth.oauth2.token_url is invalid: %w", err)
+		}
+		if c.OAuth2.ClientID == "" {
+			return errors.New("upstreamAuth.oauth2.client_id cannot be empty")
+		}
+		switch c.OAuth2.AuthStyle {
+		case "", "header", "body":
+		default:
+			return fmt.Errorf("upstreamAuth.oauth2.auth_style %q is not supported", c.OAuth2.AuthStyle)
+		}
+	case UpstreamAuthHMAC:
+		if c.HMAC == nil {
+			return errors.New("upstreamAuth.hmac is required for type hmac")
+		}
+		if c.HMAC.Secret == "" {
+			return errors.New("upstreamAuth.hmac.secret cannot be empty")
+		}
+		if _, err := hmacHashFunc(c.HMAC.Algorithm); err != nil {
+			return err
+		}
+		switch c.HMAC.Encoding {
+		case "", "hex", "base64":
+		default:
+			return fmt.Errorf("upstreamAuth.hmac.encoding %q is not supported", c.HMAC.Encoding)
+		}
+		for _, component := range c.HMAC.Components {
+			if !isValidHMACComponent(component) {
+				return fmt.Errorf("upstreamAuth.hmac.components contains unknown component %q", component)
+			}
+		}
+	default:
+		return fmt.Errorf("upstreamAuth.type %q is not supported", c.Type)
+	}
+
+	return c.TLS.Validate()
+}
+
+// --- OAuth2 Client Credentials ---
+
+// OAuth2Token 缓存的访问令牌
+type OAuth2Token struct {
+	AccessToken string
+	TokenType   string
+	ExpiresAt   time.Time
+}
+
+// valid 检查令牌在 skew 窗口外是否仍然有效
+func (t *OAuth2Token) valid(skew time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.ExpiresAt.IsZero() {
+		return true
+	}
+	return time.Now().Add(skew).Before(t.ExpiresAt)
+}
+
+// OAuth2TokenSource 带缓存的 Client Credentials Token 获取器
+// 并发请求共享同一次刷新，Token 在过期前 DefaultTokenRefreshSkew 自动刷新
+type OAuth2TokenSource struct {
+	config       OAuth2ClientConfig
+	secretGetter SecretGetter
+
+	mu       sync.Mutex
+	client   *http.Client
+	token    *OAuth2Token
+	inflight *tokenFetch // 进行中的刷新
+}
+
+// tokenFetch 一次进行中的 Token 刷新，done 关闭后 token/err 可读
+type tokenFetch struct {
+	done  chan struct{}
+	token *OAuth2Token
+	err   error
+}
+
+// NewOAuth2TokenSource 创建 Token 获取器
+// transport 为 nil 时使用 http.DefaultTransport
+func NewOAuth2TokenSource(config OAuth2ClientConfig, transport http.RoundTripper, secretGetter SecretGetter) *OAuth2TokenSource {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &OAuth2TokenSource{
+		config:       config,
+		client:       &http.Client{Transport: transport, Timeout: DefaultTokenFetchTimeout},
+		secretGetter: secretGetter,
+	}
+}
+
+// Token 返回有效的访问令牌，必要时向 Token 端点刷新
+// 刷新在后台进行且不持有锁，ctx 取消时仅当前调用返回，刷新继续供其他请求使用；
+// 刷新失败时，若缓存的令牌尚未过期则仍返回该令牌
+func (s *OAuth2TokenSource) Token(ctx context.Context) (*OAuth2Token, error) {
+	s.mu.Lock()
+	if s.token.valid(DefaultTokenRefreshSkew) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	f := s.inflight
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		s.inflight = f
+		go s.refresh(context.WithoutCancel(ctx), s.client, f)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			// 提前刷新失败时，缓存的令牌未真正过期则继续使用
+			s.mu.Lock()
+			token := s.token
+			s.mu.Unlock()
+			if token.valid(0) {
+				return token, nil
+			}
+			return nil, f.err
+		}
+		return f.token, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh 获取新令牌并唤醒等待的请求
+func (s *OAuth2TokenSource) refresh(ctx context.Context, client *http.Client, f *tokenFetch) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTokenFetchTimeout)
+	defer cancel()
+
+	f.token, f.err = s.fetch(ctx, client)
+
+	s.mu.Lock()
+	if f.err == nil {
+		s.token = f.token
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+
+	close(f.done)
+}
+
+// setTransport 替换获取令牌使用的 Transport（如重载时重建了 mTLS Transport），已缓存的令牌保留
+func (s *OAuth2TokenSource) setTransport(transport http.RoundTripper) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	s.mu.Lock()
+	s.client = &http.Client{Transport: transport, Timeout: DefaultTokenFetchTimeout}
+	s.mu.Unlock()
+}
+
+// Invalidate 使缓存的令牌失效（如上游返回 401 时）
+func (s *OAuth2TokenSource) Invalidate() {
+	s.mu.Lock()
+	s.token = nil
+	s.mu.Unlock()
+}
+
+// fetch 请求新的访问令牌
+func (s *OAuth2TokenSource) fetch(ctx context.Context, client *http.Client) (*OAuth2Token, error) {
+	clientID, err := ParseHeaderTemplate(s.config.ClientID, nil, s.secretGetter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve oauth2 client_id: %w", err)
+	}
+	clientSecret, err := ParseHeaderTemplate(s.config.ClientSecret, nil, s.secretGetter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve oauth2 client_secret: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	if s.config.Audience != "" {
+		form.Set("audience", s.config.Audience)
+	}
+	if s.config.AuthStyle == "body" {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.AuthStyle != "body" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oauth2 token response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("oauth2 token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		AccessToken string          `json:"access_token"`
+		TokenType   string          `json:"token_type"`
+		ExpiresIn   json.RawMessage `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("invalid oauth2 token response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return nil, errors.New("oauth2 token response missing access_token")
+	}
+
+	token := &OAuth2Token{
+		AccessToken: result.AccessToken,
+		TokenType:   result.TokenType,
+	}
+	if token.TokenType == "" || strings.EqualFold(token.TokenType, "bearer") {
+		token.TokenType = "Bearer"
+	}
+
+	// expires_in 可能是数字或字符串
+	if expiresIn, err := strconv.Atoi(strings.Trim(string(result.ExpiresIn), `"`)); err == nil && expiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// --- HMAC 请求签名 ---
+
+var hmacComponentNames = map[string]bool{
+	"method":      true,
+	"path":        true,
+	"query":       true,
+	"timestamp":   true,
+	"body":        true,
+	"body_sha256": true,
+}
+
+// isValidHMACComponent 检查签名组成部分是否合法
+func isValidHMACComponent(component string) bool {
+	if strings.HasPrefix(component, "header:") {
+		return len(component) > len("header:")
+	}
+	return hmacComponentNames[component]
+}
+
+// hmacHashFunc 根据算法名称返回哈希构造函数
+func hmacHashFunc(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	case "sha1":
+		return sha1.New, nil
+	default:
+		return nil, fmt.Errorf("upstreamAuth.hmac.algorithm %q is not supported", algorithm)
+	}
+}
+
+// SignRequest 使用 HMAC 对请求签名并写入签名头
+// 如果签名需要请求体，会缓冲请求体并重新设置 req.Body
+func SignRequest(req *http.Request, config *HMACSignConfig, secretGetter SecretGetter, now time.Time) error {
+	secret, err := ParseHeaderTemplate(config.Secret, nil, secretGetter)
+	if err != nil {
+		return fmt.Errorf("failed to resolve hmac secret: %w", err)
+	}
+	if secret == "" {
+		return errors.New("hmac secret is empty")
+	}
+
+	hashFunc, err := hmacHashFunc(config.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	components := config.Components
+	if len(components) == 0 {
+		components = []string{"method", "path", "timestamp", "body_sha256"}
+	}
+
+	separator := config.Separator
+	if separator == "" {
+		separator = "\n"
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	var body []byte
+	for _, component := range components {
+		if component == "body" || component == "body_sha256" {
+			body, err = bufferRequestBody(req)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	parts := make([]string, 0, len(components))
+	for _, component := range components {
+		switch {
+		case component == "method":
+			parts = append(parts, strings.ToUpper(req.Method))
+		case component == "path":
+			parts = append(parts, req.URL.EscapedPath())
+		case component == "query":
+			parts = append(parts, canonicalQuery(req.URL.Query()))
+		case component == "timestamp":
+			parts = append(parts, timestamp)
+		case component == "body":
+			parts = append(parts, string(body))
+		case component == "body_sha256":
+			sum := sha256.Sum256(body)
+			parts = append(parts, hex.EncodeToString(sum[:]))
+		case strings.HasPrefix(component, "header:"):
+			parts = append(parts, strings.TrimSpace(req.Header.Get(strings.TrimPrefix(component, "header:"))))
+		}
+	}
+
+	mac := hmac.New(hashFunc, []byte(secret))
+	mac.Write([]byte(strings.Join(parts, separator)))
+	sum := mac.Sum(nil)
+
+	var signature string
+	if config.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	} else {
+		signature = hex.EncodeToString(sum)
+	}
+
+	req.Header.Set(defaultString(config.SignatureHeader, "X-Signature"), config.SignaturePrefix+signature)
+	req.Header.Set(defaultString(config.TimestampHeader, "X-Timestamp"), timestamp)
+	if config.KeyID != "" {
+		req.Header.Set(defaultString(config.KeyIDHeader, "X-Key-Id"), config.KeyID)
+	}
+
+	return nil
+}
+
+// canonicalQuery 返回按 key 排序的查询字符串
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			if sb.Len() > 0 {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(url.QueryEscape(key))
+			sb.WriteByte('=')
+			sb.WriteString(url.QueryEscape(value))
+		}
+	}
+	return sb.String()
+}
+
+// errSignBodyTooLarge 需要签名的请求体超过 MaxReplayBodySize
+var errSignBodyTooLarge = fmt.Errorf("request body exceeds %d bytes and cannot be signed", MaxReplayBodySize)
+
+// bufferRequestBody 读取完整请求体并重置 req.Body，便于后续发送
+// 请求体超过 MaxReplayBodySize 时返回 errSignBodyTooLarge，避免无限制地缓冲请求体
+func bufferRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.ContentLength > MaxReplayBodySize {
+		return nil, errSignBodyTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxReplayBodySize+1))
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body for signing: %w", err)
+	}
+	if len(body) > MaxReplayBodySize {
+		return nil, errSignBodyTooLarge
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
+
+// defaultString 返回 value，为空时返回 fallback
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// --- RoundTripper ---
+
+// upstreamAuthTransport 在发送请求前注入上游认证信息
+// 位于 Director（以及请求变换）之后执行，确保签名覆盖最终请求
+type upstreamAuthTransport struct {
+	base         http.RoundTripper
+	config       *UpstreamAuthConfig
+	tokenSource  *OAuth2TokenSource
+	secretGetter SecretGetter
+}
+
+// newUpstreamAuthTransport 创建带上游认证的 RoundTripper
+func newUpstreamAuthTransport(base http.RoundTripper, config *UpstreamAuthConfig, tokenSource *OAuth2TokenSource, secretGetter SecretGetter) http.RoundTripper {
+	return &upstreamAuthTransport{
+		base:         base,
+		config:       config,
+		tokenSource:  tokenSource,
+		secretGetter: secretGetter,
+	}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *upstreamAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper 不应修改传入的请求，复制后再注入认证头
+	req = req.Clone(req.Context())
+
+	switch t.config.Type {
+	case UpstreamAuthOAuth2:
+		if t.tokenSource == nil {
+			return nil, errors.New("oauth2 token source is not initialized")
+		}
+		token, err := t.tokenSource.Token(req.Context())
+		if err != nil {
+			return nil, fmt.Errorf("upstream auth failed: %w", err)
+		}
+		req.Header.Set("Authorization", token.TokenType+" "+token.AccessToken)
+
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			// Token 可能已被上游吊销，下次请求重新获取
+			t.tokenSource.Invalidate()
+		}
+		return resp, err
+
+	case UpstreamAuthHMAC:
+		if err := SignRequest(req, t.config.HMAC, t.secretGetter, time.Now()); err != nil {
+			return nil, fmt.Errorf("upstream auth failed: %w", err)
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
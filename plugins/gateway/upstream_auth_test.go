@@ -0,0 +1,513 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestUpstreamAuthConfigValidate 测试上游认证配置校验
+func TestUpstreamAuthConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *UpstreamAuthConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"empty", &UpstreamAuthConfig{}, false},
+		{"unknown type", &UpstreamAuthConfig{Type: "basic"}, true},
+		{"oauth2 missing config", &UpstreamAuthConfig{Type: UpstreamAuthOAuth2}, true},
+		{"oauth2 missing token url", &UpstreamAuthConfig{Type: UpstreamAuthOAuth2, OAuth2: &OAuth2ClientConfig{ClientID: "id"}}, true},
+		{"oauth2 invalid auth style", &UpstreamAuthConfig{Type: UpstreamAuthOAuth2, OAuth2: &OAuth2ClientConfig{
+			TokenURL: "https://auth.example.com/token", ClientID: "id", AuthStyle: "query",
+		}}, true},
+		{"oauth2 valid", &UpstreamAuthConfig{Type: UpstreamAuthOAuth2, OAuth2: &OAuth2ClientConfig{
+			TokenURL: "https://auth.example.com/token", ClientID: "id", ClientSecret: "secret",
+		}}, false},
+		{"hmac missing secret", &UpstreamAuthConfig{Type: UpstreamAuthHMAC, HMAC: &HMACSignConfig{}}, true},
+		{"hmac invalid algorithm", &UpstreamAuthConfig{Type: UpstreamAuthHMAC, HMAC: &HMACSignConfig{Secret: "s", Algorithm: "md5"}}, true},
+		{"hmac invalid component", &UpstreamAuthConfig{Type: UpstreamAuthHMAC, HMAC: &HMACSignConfig{Secret: "s", Components: []string{"cookie"}}}, true},
+		{"hmac valid", &UpstreamAuthConfig{Type: UpstreamAuthHMAC, HMAC: &HMACSignConfig{
+			Secret: "s", Components: []string{"method", "header:X-Date"},
+		}}, false},
+		{"tls cert without key", &UpstreamAuthConfig{TLS: &UpstreamTLSConfig{CertFile: "cert.pem"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newTestTokenServer 创建模拟的 OAuth2 Token 端点
+func newTestTokenServer(t *testing.T, expiresIn int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm() error = %v", err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" {
+			t.Errorf("grant_type = %q", r.Form.Get("grant_type"))
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":%d}`, n, expiresIn)
+	}))
+}
+
+// TestOAuth2TokenSourceCaching 测试 Token 缓存
+func TestOAuth2TokenSourceCaching(t *testing.T) {
+	var calls int32
+	server := newTestTokenServer(t, 3600, &calls)
+	defer server.Close()
+
+	secretGetter := func(name string) (string, error) {
+		if name == "CLIENT_SECRET" {
+			return "s3cret", nil
+		}
+		return "", nil
+	}
+
+	source := NewOAuth2TokenSource(OAuth2ClientConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "{secret.CLIENT_SECRET}",
+		Scopes:       []string{"read"},
+	}, nil, secretGetter)
+
+	for i := 0; i < 3; i++ {
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+		if token.AccessToken != "token-1" || token.TokenType != "Bearer" {
+			t.Errorf("unexpected token %+v", token)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls)
+	}
+
+	// 失效后重新获取
+	source.Invalidate()
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "token-2" {
+		t.Errorf("AccessToken = %q, want token-2", token.AccessToken)
+	}
+}
+
+// TestOAuth2TokenSourceRefreshBeforeExpiry 测试 Token 临近过期时刷新
+func TestOAuth2TokenSourceRefreshBeforeExpiry(t *testing.T) {
+	var calls int32
+	// 过期时间小于 DefaultTokenRefreshSkew，每次都需要刷新
+	server := newTestTokenServer(t, 10, &calls)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2ClientConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+	}, nil, nil)
+
+	source.Token(context.Background())
+	source.Token(context.Background())
+
+	if calls != 2 {
+		t.Errorf("token endpoint called %d times, want 2", calls)
+	}
+}
+
+// TestOAuth2TokenSourceError 测试 Token 端点错误
+func TestOAuth2TokenSourceError(t *testing.T) {
+	var calls int32
+	server := newTestTokenServer(t, 3600, &calls)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2ClientConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	}, nil, nil)
+
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Error("expected error for rejected credentials")
+	}
+}
+
+// TestOAuth2TokenSourceRefreshErrorKeepsValidToken 测试提前刷新失败时继续使用未过期的令牌
+func TestOAuth2TokenSourceRefreshErrorKeepsValidToken(t *testing.T) {
+	var calls int32
+	server := newTestTokenServer(t, 3600, &calls)
+	defer server.Close()
+
+	source := NewOAuth2TokenSource(OAuth2ClientConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "wrong",
+	}, nil, nil)
+
+	// 处于提前刷新窗口内但尚未过期
+	source.token = &OAuth2Token{AccessToken: "cached", TokenType: "Bearer", ExpiresAt: time.Now().Add(DefaultTokenRefreshSkew / 2)}
+
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if token.AccessToken != "cached" || calls != 1 {
+		t.Errorf("expected cached token after failed refresh, got %+v (%d calls)", token, calls)
+	}
+
+	// 已过期的令牌不再使用
+	source.token.ExpiresAt = time.Now().Add(-time.Second)
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Error("expected error once the cached token has expired")
+	}
+}
+
+// TestOAuth2TokenSourceCallerCancel 测试刷新不持有锁，且不受发起刷新的请求取消影响
+func TestOAuth2TokenSourceCallerCancel(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600}`, n)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	source := NewOAuth2TokenSource(OAuth2ClientConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cret",
+	}, nil, nil)
+
+	// 发起刷新的请求断开
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := source.Token(ctx)
+		firstErr <- err
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-firstErr:
+		if err != context.Canceled {
+			t.Errorf("first Token() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled caller should not wait for the token endpoint")
+	}
+
+	// 刷新进行中时其他调用不被锁阻塞，可自行超时
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	if _, err := source.Token(waitCtx); err != context.DeadlineExceeded {
+		t.Errorf("waiting Token() error = %v, want context.DeadlineExceeded", err)
+	}
+	source.Invalidate()
+
+	// 同一次刷新完成后供后续请求使用
+	second := make(chan *OAuth2Token, 1)
+	go func() {
+		token, err := source.Token(context.Background())
+		if err != nil {
+			t.Errorf("second Token() error = %v", err)
+		}
+		second <- token
+	}()
+	release <- struct{}{}
+
+	if token := <-second; token == nil || token.AccessToken != "token-1" {
+		t.Errorf("unexpected token %+v", token)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("token endpoint called %d times, want 1", n)
+	}
+}
+
+// TestSignRequest 测试 HMAC 签名
+func TestSignRequest(t *testing.T) {
+	config := &HMACSignConfig{
+		Secret:          "key",
+		Components:      []string{"method", "path", "query", "timestamp", "body", "header:X-Tenant"},
+		SignaturePrefix: "sha256=",
+		KeyID:           "k1",
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/items?b=2&a=1", strings.NewReader(`{"x":1}`))
+	req.Header.Set("X-Tenant", "acme")
+
+	now := time.Unix(1700000000, 0)
+	if err := SignRequest(req, config, nil, now); err != nil {
+		t.Fatalf("SignRequest() error = %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("POST\n/items\na=1&b=2\n1700000000\n{\"x\":1}\nacme"))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := req.Header.Get("X-Signature"); got != want {
+		t.Errorf("X-Signature = %q, want %q", got, want)
+	}
+	if req.Header.Get("X-Timestamp") != "1700000000" {
+		t.Errorf("X-Timestamp = %q", req.Header.Get("X-Timestamp"))
+	}
+	if req.Header.Get("X-Key-Id") != "k1" {
+		t.Errorf("X-Key-Id = %q", req.Header.Get("X-Key-Id"))
+	}
+
+	// Body 被缓冲后仍可读取
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"x":1}` {
+		t.Errorf("body = %q, want original body", body)
+	}
+}
+
+// TestSignRequestBodyTooLarge 测试签名请求体的大小上限
+func TestSignRequestBodyTooLarge(t *testing.T) {
+	config := &HMACSignConfig{Secret: "key", Components: []string{"body_sha256"}}
+	large := strings.Repeat("a", MaxReplayBodySize+10)
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/", strings.NewReader(large))
+	if err := SignRequest(req, config, nil, time.Now()); !errors.Is(err, errSignBodyTooLarge) {
+		t.Fatalf("expected errSignBodyTooLarge, got %v", err)
+	}
+
+	// 未知长度的请求体
+	req = httptest.NewRequest(http.MethodPost, "http://upstream/", io.NopCloser(strings.NewReader(large)))
+	req.ContentLength = -1
+	if err := SignRequest(req, config, nil, time.Now()); !errors.Is(err, errSignBodyTooLarge) {
+		t.Fatalf("expected errSignBodyTooLarge for chunked body, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handleUpstreamError(rec, req, fmt.Errorf("upstream auth failed: %w", errSignBodyTooLarge))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
+
+// TestSignRequestBase64 测试 base64 编码与自定义头
+func TestSignRequestBase64(t *testing.T) {
+	config := &HMACSignConfig{
+		Secret:          "{env.TEST_HMAC_SECRET}",
+		Algorithm:       "sha512",
+		Encoding:        "base64",
+		Components:      []string{"method"},
+		SignatureHeader: "X-Sig",
+	}
+	t.Setenv("TEST_HMAC_SECRET", "key")
+
+	req := httptest.NewRequest(http.MethodGet, "http://upstream/", nil)
+	if err := SignRequest(req, config, nil, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Header.Get("X-Sig") == "" || req.Header.Get("X-Signature") != "" {
+		t.Errorf("unexpected signature headers %v", req.Header)
+	}
+}
+
+// TestUpstreamAuthTransportOAuth2 测试 OAuth2 Token 注入及 401 失效
+func TestUpstreamAuthTransportOAuth2(t *testing.T) {
+	var tokenCalls int32
+	tokenServer := newTestTokenServer(t, 3600, &tokenCalls)
+	defer tokenServer.Close()
+
+	var reject atomic.Bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject.Load() {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer upstream.Close()
+
+	config := &UpstreamAuthConfig{
+		Type: UpstreamAuthOAuth2,
+		OAuth2: &OAuth2ClientConfig{
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "s3cret",
+		},
+	}
+
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: upstream.URL, Active: true, UpstreamAuth: config}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	client := &http.Client{Transport: m.RoundTripper(proxy)}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Bearer token-1" {
+		t.Errorf("Authorization = %q, want Bearer token-1", body)
+	}
+
+	// 上游返回 401 后 Token 失效
+	reject.Store(true)
+	resp, _ = client.Get(upstream.URL)
+	resp.Body.Close()
+	reject.Store(false)
+
+	resp, _ = client.Get(upstream.URL)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Bearer token-2" {
+		t.Errorf("Authorization = %q, want Bearer token-2", body)
+	}
+
+	// 配置未变化的 Hot Reload 保留 Token 缓存
+	m.SetProxies([]*ProxyConfig{proxy})
+	resp, _ = client.Get(upstream.URL)
+	resp.Body.Close()
+	if tokenCalls != 2 {
+		t.Errorf("token endpoint called %d times, want 2", tokenCalls)
+	}
+}
+
+// TestUpstreamAuthTransportTokenError 测试 Token 获取失败返回 502
+func TestUpstreamAuthTransportTokenError(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer tokenServer.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("upstream should not be called")
+	}))
+	defer upstream.Close()
+
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: upstream.URL, Active: true, UpstreamAuth: &UpstreamAuthConfig{
+		Type:   UpstreamAuthOAuth2,
+		OAuth2: &OAuth2ClientConfig{TokenURL: tokenServer.URL, ClientID: "client"},
+	}}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	plugin := &gatewayPlugin{manager: m}
+	reverseProxy := plugin.createReverseProxy(upstream.URL, proxy, nil)
+
+	rec := httptest.NewRecorder()
+	reverseProxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, upstream.URL+"/", nil))
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+}
+
+// TestUpstreamAuthTransportHMAC 测试 HMAC 签名注入
+func TestUpstreamAuthTransportHMAC(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, []byte("key"))
+		mac.Write([]byte(r.Method + "\n" + r.URL.Path + "\n" + r.Header.Get("X-Timestamp") + "\n" + string(body)))
+		if hex.EncodeToString(mac.Sum(nil)) != r.Header.Get("X-Signature") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: upstream.URL, Active: true, UpstreamAuth: &UpstreamAuthConfig{
+		Type: UpstreamAuthHMAC,
+		HMAC: &HMACSignConfig{Secret: "key", Components: []string{"method", "path", "timestamp", "body"}},
+	}}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	client := &http.Client{Transport: m.RoundTripper(proxy)}
+	resp, err := client.Post(upstream.URL+"/sign", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200 (signature mismatch)", resp.StatusCode)
+	}
+}
+
+// TestManagerRoundTripperWithoutAuth 测试未配置认证时使用共享 Transport
+func TestManagerRoundTripperWithoutAuth(t *testing.T) {
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/api", Upstream: "http://localhost", Active: true}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	if m.RoundTripper(proxy) != http.RoundTripper(m.Transport()) {
+		t.Error("expected shared transport for proxy without upstream auth")
+	}
+}
+
+// TestManagerReusesTokenSource 测试重载时认证配置未变化则复用 Token 缓存
+func TestManagerReusesTokenSource(t *testing.T) {
+	newProxy := func(scope string) *ProxyConfig {
+		return &ProxyConfig{
+			ID: "p1", Path: "/-/api", Upstream: "https://localhost", Active: true,
+			UpstreamAuth: &UpstreamAuthConfig{
+				Type: UpstreamAuthOAuth2,
+				OAuth2: &OAuth2ClientConfig{
+					TokenURL: "https://auth.example.com/token", ClientID: "id", ClientSecret: "secret", Scopes: []string{scope},
+				},
+				TLS: &UpstreamTLSConfig{ServerName: "api.internal"},
+			},
+		}
+	}
+
+	m := NewManager(nil)
+	m.SetProxies([]*ProxyConfig{newProxy("read")})
+	source := m.tokenSources["p1"]
+	transport := m.transports["p1"]
+
+	// 每次重载都会读取新的配置对象
+	m.SetProxies([]*ProxyConfig{newProxy("read")})
+	if m.tokenSources["p1"] != source {
+		t.Error("expected token source to be reused for unchanged auth config")
+	}
+	if m.transports["p1"] == transport {
+		t.Error("expected tls transport to be rebuilt on reload")
+	}
+	if source.client.Transport != m.transports["p1"] {
+		t.Error("expected reused token source to use the rebuilt transport")
+	}
+
+	m.SetProxies([]*ProxyConfig{newProxy("write")})
+	if m.tokenSources["p1"] == source {
+		t.Error("expected a new token source after auth config change")
+	}
+}
@@ -0,0 +1,167 @@
+// Package gateway 提供 API Gateway 插件功能
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// UpstreamTLSConfig 上游 TLS 配置（mTLS 客户端证书、自定义 CA）
+//
+// 证书和私钥既可以通过文件路径指定，也可以直接给出 PEM 内容；
+// PEM 内容支持模板语法，推荐使用 {secret.X} 从 secrets 插件读取私钥
+type UpstreamTLSConfig struct {
+	// CertFile 客户端证书文件路径（PEM）
+	CertFile string `json:"cert_file,omitempty"`
+
+	// KeyFile 客户端私钥文件路径（PEM）
+	KeyFile string `json:"key_file,omitempty"`
+
+	// Cert 客户端证书 PEM 内容（支持模板）
+	Cert string `json:"cert,omitempty"`
+
+	// Key 客户端私钥 PEM 内容（支持模板）
+	Key string `json:"key,omitempty"`
+
+	// CAFile 自定义 CA Bundle 文件路径（PEM），追加到系统根证书
+	CAFile string `json:"ca_file,omitempty"`
+
+	// CA 自定义 CA Bundle PEM 内容（支持模板）
+	CA string `json:"ca,omitempty"`
+
+	// ServerName 覆盖 SNI / 证书校验使用的主机名
+	ServerName string `json:"server_name,omitempty"`
+
+	// InsecureSkipVerify 跳过服务端证书校验（仅用于测试环境）
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// Validate 校验 TLS 配置的字段组合
+func (c *UpstreamTLSConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("upstreamAuth.tls.cert_file and key_file must be set together")
+	}
+
+	if (c.Cert == "") != (c.Key == "") {
+		return errors.New("upstreamAuth.tls.cert and key must be set together")
+	}
+
+	if c.CertFile != "" && c.Cert != "" {
+		return errors.New("upstreamAuth.tls cannot set both cert_file and cert")
+	}
+
+	return nil
+}
+
+// IsEmpty 是否未配置任何 TLS 选项
+func (c *UpstreamTLSConfig) IsEmpty() bool {
+	return c == nil || *c == UpstreamTLSConfig{}
+}
+
+// BuildTLSConfig 根据配置构建 tls.Config
+func (c *UpstreamTLSConfig) BuildTLSConfig(secretGetter SecretGetter) (*tls.Config, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	// 1. 客户端证书
+	var certPEM, keyPEM []byte
+	switch {
+	case c.CertFile != "":
+		var err error
+		if certPEM, err = os.ReadFile(c.CertFile); err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		if keyPEM, err = os.ReadFile(c.KeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+	case c.Cert != "":
+		cert, err := ParseHeaderTemplate(c.Cert, nil, secretGetter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve client certificate: %w", err)
+		}
+		key, err := ParseHeaderTemplate(c.Key, nil, secretGetter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve client key: %w", err)
+		}
+		certPEM, keyPEM = []byte(cert), []byte(key)
+	}
+
+	if len(certPEM) > 0 {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// 2. 自定义 CA
+	var caPEM []byte
+	switch {
+	case c.CAFile != "":
+		var err error
+		if caPEM, err = os.ReadFile(c.CAFile); err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+	case c.CA != "":
+		ca, err := ParseHeaderTemplate(c.CA, nil, secretGetter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve CA bundle: %w", err)
+		}
+		caPEM = []byte(ca)
+	}
+
+	if len(caPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("CA bundle does not contain any valid certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// NewUpstreamTLSTransport 在 HardenedTransport 基础上创建带 TLS 配置的 Transport
+func NewUpstreamTLSTransport(config TransportConfig, tlsConfig *UpstreamTLSConfig, secretGetter SecretGetter) (*http.Transport, error) {
+	clientTLS, err := tlsConfig.BuildTLSConfig(secretGetter)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := NewHardenedTransport(config)
+	transport.TLSClientConfig = clientTLS
+
+	return transport, nil
+}
+
+// errorTransport 总是返回固定错误的 RoundTripper
+// 用于上游 TLS 配置无效时拒绝转发
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t errorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, fmt.Errorf("upstream tls misconfigured: %w", t.err)
+}
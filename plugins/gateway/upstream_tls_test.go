@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert 测试用证书及 PEM 内容
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert 生成测试证书，parent 为 nil 时生成自签名 CA
+func newTestCert(t *testing.T, parent *testCert, isClient bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pb-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		if isClient {
+			template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		} else {
+			template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+			template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// TestUpstreamTLSConfigValidate 测试 TLS 配置校验
+func TestUpstreamTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *UpstreamTLSConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"ca only", &UpstreamTLSConfig{CAFile: "ca.pem"}, false},
+		{"cert file without key", &UpstreamTLSConfig{CertFile: "cert.pem"}, true},
+		{"pem cert without key", &UpstreamTLSConfig{Cert: "---"}, true},
+		{"both file and pem", &UpstreamTLSConfig{CertFile: "c", KeyFile: "k", Cert: "c", Key: "k"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestBuildTLSConfigInvalidCA 测试无效 CA
+func TestBuildTLSConfigInvalidCA(t *testing.T) {
+	config := &UpstreamTLSConfig{CA: "not a certificate"}
+	if _, err := config.BuildTLSConfig(nil); err == nil {
+		t.Error("expected error for invalid CA bundle")
+	}
+}
+
+// TestUpstreamMutualTLS 测试 mTLS：客户端证书 + 自定义 CA
+func TestUpstreamMutualTLS(t *testing.T) {
+	ca := newTestCert(t, nil, false)
+	serverCert := newTestCert(t, ca, false)
+	clientCert := newTestCert(t, ca, true)
+
+	serverTLS, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	upstream.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverTLS},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	// 证书文件方式
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+	os.WriteFile(certFile, clientCert.certPEM, 0600)
+	os.WriteFile(keyFile, clientCert.keyPEM, 0600)
+	os.WriteFile(caFile, ca.certPEM, 0600)
+
+	secrets := map[string]string{
+		"CLIENT_CERT": string(clientCert.certPEM),
+		"CLIENT_KEY":  string(clientCert.keyPEM),
+	}
+	secretGetter := func(name string) (string, error) {
+		return secrets[name], nil
+	}
+
+	tests := []struct {
+		name    string
+		config  *UpstreamTLSConfig
+		wantErr bool
+	}{
+		{"files", &UpstreamTLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile}, false},
+		{"secrets", &UpstreamTLSConfig{Cert: "{secret.CLIENT_CERT}", Key: "{secret.CLIENT_KEY}", CA: string(ca.certPEM)}, false},
+		{"no client cert", &UpstreamTLSConfig{CAFile: caFile}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManagerWithConfig(nil, ManagerConfig{
+				TransportConfig: DefaultTransportConfig(),
+				SecretGetter:    secretGetter,
+			})
+			proxy := &ProxyConfig{ID: "p1", Path: "/-/mtls", Upstream: upstream.URL, Active: true,
+				UpstreamAuth: &UpstreamAuthConfig{TLS: tt.config}}
+			m.SetProxies([]*ProxyConfig{proxy})
+
+			client := &http.Client{Transport: m.RoundTripper(proxy)}
+			resp, err := client.Get(upstream.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Error("expected handshake error without client certificate")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want 200", resp.StatusCode)
+			}
+		})
+	}
+}
+
+// TestManagerInvalidTLSConfigRejects 测试无效 TLS 配置拒绝转发
+func TestManagerInvalidTLSConfigRejects(t *testing.T) {
+	m := NewManager(nil)
+	proxy := &ProxyConfig{ID: "p1", Path: "/-/mtls", Upstream: "https://127.0.0.1:1", Active: true,
+		UpstreamAuth: &UpstreamAuthConfig{TLS: &UpstreamTLSConfig{CertFile: "/nonexistent.pem", KeyFile: "/nonexistent-key.pem"}}}
+	m.SetProxies([]*ProxyConfig{proxy})
+
+	client := &http.Client{Transport: m.RoundTripper(proxy)}
+	if resp, err := client.Get(proxy.Upstream); err == nil {
+		resp.Body.Close()
+		t.Error("expected error for misconfigured upstream tls")
+	}
+}
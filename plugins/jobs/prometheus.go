@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+)
+
+// Collect 实现 metrics.Collector 接口，提供各状态的任务数与按 topic 的队列深度
+func (js *JobStore) Collect() []metrics.MetricFamily {
+	type topicCount struct {
+		Topic  string `db:"topic"`
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+
+	var counts []topicCount
+	err := js.app.DB().NewQuery(`
+		SELECT topic, status, COUNT(*) as count
+		FROM _jobs
+		GROUP BY topic, status
+		ORDER BY topic, status
+	`).All(&counts)
+	if err != nil {
+		js.app.Logger().Warn("Failed to collect jobs metrics", "error", err)
+		return nil
+	}
+
+	totals := map[string]int{
+		JobStatusPending:    0,
+		JobStatusProcessing: 0,
+		JobStatusCompleted:  0,
+		JobStatusFailed:     0,
+	}
+
+	depth := metrics.MetricFamily{
+		Name: "pb_jobs_queue_depth",
+		Help: "Number of pending and processing jobs per topic.",
+		Type: metrics.MetricTypeGauge,
+	}
+	for _, c := range counts {
+		totals[c.Status] += c.Count
+		if c.Status == JobStatusPending || c.Status == JobStatusProcessing {
+			depth.Samples = append(depth.Samples, metrics.Sample{
+				Labels: metrics.Labels("topic", c.Topic, "status", c.Status),
+				Value:  float64(c.Count),
+			})
+		}
+	}
+
+	jobs := metrics.MetricFamily{
+		Name: "pb_jobs",
+		Help: "Number of jobs by status.",
+		Type: metrics.MetricTypeGauge,
+	}
+	for _, status := range []string{JobStatusPending, JobStatusProcessing, JobStatusCompleted, JobStatusFailed} {
+		jobs.Samples = append(jobs.Samples, metrics.Sample{
+			Labels: metrics.Labels("status", status),
+			Value:  float64(totals[status]),
+		})
+	}
+
+	return []metrics.MetricFamily{jobs, depth}
+}
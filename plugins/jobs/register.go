@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 )
 
 var (
@@ -40,6 +41,9 @@ func Register(app core.App, config Config) error {
 	storeRegistry[app] = store
 	storeMu.Unlock()
 
+	// 向 /api/metrics/prometheus 提供队列指标
+	metrics.GetRegistry(app).Register("jobs", store)
+
 	// 6. 如果应用已经引导，直接创建表
 	if app.IsBootstrapped() {
 		if err := createJobsTable(app); err != nil {
@@ -89,6 +93,8 @@ func Register(app core.App, config Config) error {
 		delete(storeRegistry, app)
 		storeMu.Unlock()
 
+		metrics.GetRegistry(app).Unregister("jobs")
+
 		return e.Next()
 	})
 
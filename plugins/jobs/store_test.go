@@ -1,11 +1,14 @@
 package jobs_test
 
 import (
+	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pocketbase/pocketbase/plugins/jobs"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 	"github.com/pocketbase/pocketbase/tests"
 )
 
@@ -351,6 +354,36 @@ func TestJobStats(t *testing.T) {
 	})
 }
 
+func TestJobPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		jobs.MustRegister(app, jobs.DefaultConfig())
+		store := jobs.GetJobStore(app)
+
+		store.Enqueue("metrics_a", map[string]any{})
+		store.Enqueue("metrics_a", map[string]any{})
+		store.Enqueue("metrics_b", map[string]any{})
+
+		var buf bytes.Buffer
+		if err := metrics.GetRegistry(app).WriteText(&buf, true); err != nil {
+			t.Fatal(err)
+		}
+
+		output := buf.String()
+		for _, expected := range []string{
+			`pb_jobs{status="pending"} 3`,
+			`pb_jobs{status="failed"} 0`,
+			`pb_jobs_queue_depth{topic="metrics_a",status="pending"} 2`,
+			`pb_jobs_queue_depth{topic="metrics_b",status="pending"} 1`,
+		} {
+			if !strings.Contains(output, expected+"\n") {
+				t.Errorf("expected %q in output:\n%s", expected, output)
+			}
+		}
+	})
+}
+
 // ==================== Phase 7: Job 结构体方法测试 ====================
 
 func TestJobUnmarshalPayload(t *testing.T) {
@@ -25,6 +25,8 @@ import (
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/forms"
 	"github.com/pocketbase/pocketbase/mails"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/plugins/trace"
 	"github.com/pocketbase/pocketbase/tools/filesystem"
 	"github.com/pocketbase/pocketbase/tools/hook"
 	"github.com/pocketbase/pocketbase/tools/inflector"
@@ -692,6 +694,21 @@ func mailsBinds(vm *goja.Runtime) {
 	obj.Set("sendRecordAuthAlert", mails.SendRecordAuthAlert)
 }
 
+func metricsBinds(app core.App, vm *goja.Runtime) {
+	obj := vm.NewObject()
+	vm.Set("$metrics", obj)
+
+	obj.Set("counter", func(name string, labels map[string]string) (*metrics.CustomCounter, error) {
+		return metrics.Counter(app, name, labels)
+	})
+	obj.Set("gauge", func(name string, labels map[string]string) (*metrics.CustomGauge, error) {
+		return metrics.Gauge(app, name, labels)
+	})
+	obj.Set("histogram", func(name string, labels map[string]string, buckets []float64) (*metrics.CustomHistogram, error) {
+		return metrics.Histogram(app, name, labels, buckets...)
+	})
+}
+
 func securityBinds(vm *goja.Runtime) {
 	obj := vm.NewObject()
 	vm.Set("$security", obj)
@@ -909,7 +926,8 @@ func httpClientBinds(vm *goja.Runtime) {
 			config.Timeout = 120
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
+		// 继承当前处理器事件的追踪信息（不继承取消）
+		ctx, cancel := context.WithTimeout(currentEventContext(vm), time.Duration(config.Timeout)*time.Second)
 		defer cancel()
 
 		var reqBody io.Reader
@@ -954,12 +972,28 @@ func httpClientBinds(vm *goja.Runtime) {
 			req.Header.Set("content-type", contentType)
 		}
 
+		// 在被追踪的请求中为出站调用创建客户端 Span 并传播 traceparent
+		spanCtx, span := trace.StartChildSpan(ctx, req.Method+" "+req.URL.Host)
+		defer span.End()
+		span.SetKind(trace.SpanKindClient).
+			SetAttribute("http.method", req.Method).
+			SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+		trace.InjectTraceparent(spanCtx, req.Header)
+
 		res, err := http.DefaultClient.Do(req)
 		if err != nil {
+			span.RecordError(err)
 			return nil, err
 		}
 		defer res.Body.Close()
 
+		span.SetAttribute("http.status_code", res.StatusCode)
+		if res.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(trace.SpanStatusError, res.Status)
+		} else {
+			span.SetStatus(trace.SpanStatusOK, "")
+		}
+
 		bodyRaw, _ := io.ReadAll(res.Body)
 
 		result := &sendResult{
@@ -994,6 +1028,20 @@ func httpClientBinds(vm *goja.Runtime) {
 	})
 }
 
+// currentEventContext 返回 vm 当前正在执行的 hook/路由处理器事件携带的 context
+// （只保留值，不继承取消），不在处理器中执行时返回 context.Background()。
+func currentEventContext(vm *goja.Runtime) context.Context {
+	if v := vm.Get("__args"); v != nil {
+		if args, ok := v.Export().([]any); ok && len(args) > 0 {
+			if ctx := trace.EventContext(args[0]); ctx != nil {
+				return context.WithoutCancel(ctx)
+			}
+		}
+	}
+
+	return context.Background()
+}
+
 // -------------------------------------------------------------------
 
 // checkGojaValueForError resolves the provided goja.Value and tries
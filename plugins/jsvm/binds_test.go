@@ -20,6 +20,8 @@ import (
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/plugins/trace"
 	"github.com/pocketbase/pocketbase/tests"
 	"github.com/pocketbase/pocketbase/tools/filesystem"
 	"github.com/pocketbase/pocketbase/tools/mailer"
@@ -848,6 +850,61 @@ func TestMailsBinds(t *testing.T) {
 	})
 }
 
+func TestMetricsBindsCount(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	vm := goja.New()
+	metricsBinds(app, vm)
+
+	testBindsCount(vm, "$metrics", 3, t)
+}
+
+func TestMetricsBinds(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	vm := goja.New()
+	baseBinds(vm)
+	metricsBinds(app, vm)
+
+	_, err := vm.RunString(`
+		$metrics.counter("orders_placed", {provider: "stripe"}).inc();
+		$metrics.counter("orders_placed", {provider: "stripe"}).add(2);
+		$metrics.gauge("queue_size").set(7);
+		$metrics.histogram("payment_latency_seconds", {provider: "stripe"}, [0.1, 1]).observe(0.5);
+
+		let errored = false;
+		try {
+			$metrics.gauge("orders_placed", {provider: "stripe"});
+		} catch (err) {
+			errored = true;
+		}
+		if (!errored) {
+			throw new Error("Expected type conflict error");
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counter, err := metrics.Counter(app, "orders_placed", map[string]string{"provider": "stripe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := counter.Value(); v != 3 {
+		t.Fatalf("Expected counter value 3, got %v", v)
+	}
+
+	gauge, err := metrics.Gauge(app, "queue_size", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := gauge.Value(); v != 7 {
+		t.Fatalf("Expected gauge value 7, got %v", v)
+	}
+}
+
 func TestSecurityBindsCount(t *testing.T) {
 	vm := goja.New()
 	securityBinds(vm)
@@ -1556,6 +1613,71 @@ func TestHttpClientBindsSend(t *testing.T) {
 	}
 }
 
+// jsvmTestTracer 记录所有 Span 的 Tracer
+type jsvmTestTracer struct {
+	trace.NoopTracer
+	spans []*trace.Span
+}
+
+func (t *jsvmTestTracer) IsEnabled() bool { return true }
+
+func (t *jsvmTestTracer) RecordSpan(span *trace.Span) { t.spans = append(t.spans, span) }
+
+func TestHttpClientBindsSendTracing(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(req.Header.Get("traceparent")))
+	}))
+	defer server.Close()
+
+	vm := goja.New()
+	baseBinds(vm)
+	httpClientBinds(vm)
+	vm.Set("testURL", server.URL)
+
+	// 不在处理器中执行时不传播追踪
+	result, err := vm.RunString(`$http.send({ url: testURL }).raw`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.String() != "" {
+		t.Fatalf("Expected no traceparent outside of a traced handler, got %q", result.String())
+	}
+
+	tracer := &jsvmTestTracer{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := &core.RequestEvent{}
+		e.Request = r
+		vm.Set("__args", []any{e})
+		defer vm.Set("__args", goja.Undefined())
+
+		result, err = vm.RunString(`$http.send({ url: testURL + "/test?token=123", method: "post" }).raw`)
+	})
+	trace.TraceMiddleware(tracer, &trace.MiddlewareConfig{Mode: trace.ModeFull})(handler).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected request and client spans, got %d", len(tracer.spans))
+	}
+	requestSpan, clientSpan := tracer.spans[0], tracer.spans[1]
+
+	if clientSpan.Kind != trace.SpanKindClient || clientSpan.ParentID != requestSpan.SpanID || clientSpan.TraceID != requestSpan.TraceID {
+		t.Fatalf("Expected client span under the request span, got %+v", clientSpan)
+	}
+	if clientSpan.Attributes["http.url"] != server.URL+"/test" || clientSpan.Attributes["http.method"] != "POST" || clientSpan.Attributes["http.status_code"] != 200 {
+		t.Fatalf("Unexpected client span attributes %v", clientSpan.Attributes)
+	}
+
+	expected := "00-" + clientSpan.TraceID + "-" + clientSpan.SpanID + "-01"
+	if result.String() != expected {
+		t.Fatalf("Expected traceparent %q, got %q", expected, result.String())
+	}
+}
+
 func TestCronBindsCount(t *testing.T) {
 	t.Parallel()
 
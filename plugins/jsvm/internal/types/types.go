@@ -1206,6 +1206,66 @@ declare namespace $http {
   };
 }
 
+// -------------------------------------------------------------------
+// metricsBinds
+// -------------------------------------------------------------------
+
+/**
+ * ` + "`" + `$metrics` + "`" + ` defines helpers to record custom application metrics
+ * (counters, gauges and histograms) alongside the system metrics.
+ *
+ * The values are persisted by the metrics plugin on each collection interval
+ * and exposed on the Prometheus endpoint. Each metric keeps at most
+ * ` + "`" + `CustomMetricsMaxSeries` + "`" + ` label combinations, the rest are recorded
+ * with the "__overflow__" label values.
+ *
+ * Example:
+ *
+ * ` + "```" + `js
+ * $metrics.counter("orders_placed", { provider: "stripe" }).inc()
+ * $metrics.gauge("queue_size").set(42)
+ * $metrics.histogram("payment_latency_seconds", { provider: "stripe" }).observe(0.31)
+ * ` + "```" + `
+ *
+ * @group PocketBase
+ */
+declare namespace $metrics {
+  interface Counter {
+    inc(): void
+    add(v: number): void
+    value(): number
+  }
+
+  interface Gauge {
+    set(v: number): void
+    add(v: number): void
+    inc(): void
+    dec(): void
+    value(): number
+  }
+
+  interface Histogram {
+    observe(v: number): void
+  }
+
+  /**
+   * Returns the counter series for the specified name and labels.
+   */
+  function counter(name: string, labels?: { [key:string]: string }): Counter;
+
+  /**
+   * Returns the gauge series for the specified name and labels.
+   */
+  function gauge(name: string, labels?: { [key:string]: string }): Gauge;
+
+  /**
+   * Returns the histogram series for the specified name and labels.
+   *
+   * The buckets are applied only on the first registration of the metric.
+   */
+  function histogram(name: string, labels?: { [key:string]: string }, buckets?: Array<number>): Histogram;
+}
+
 // -------------------------------------------------------------------
 // migrate only
 // -------------------------------------------------------------------
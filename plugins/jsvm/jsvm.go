@@ -298,6 +298,7 @@ func (p *plugin) registerHooks() error {
 		formsBinds(vm)
 		apisBinds(vm)
 		mailsBinds(vm)
+		metricsBinds(p.app, vm)
 
 		vm.Set("$app", p.app)
 		vm.Set("$template", templateRegistry)
@@ -0,0 +1,49 @@
+package kv
+
+import (
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+)
+
+// Collect 实现 metrics.Collector 接口，提供 L1/L2 读取命中统计与 L1 命中率
+func (kv *kvStore) Collect() []metrics.MetricFamily {
+	l1Hits, l1Misses := kv.l1Hits.Load(), kv.l1Misses.Load()
+	l2Hits, l2Misses := kv.l2Hits.Load(), kv.l2Misses.Load()
+
+	var ratio float64
+	if total := l1Hits + l1Misses; total > 0 {
+		ratio = float64(l1Hits) / float64(total)
+	}
+
+	return []metrics.MetricFamily{
+		{
+			Name: "pb_kv_cache_hits",
+			Help: "Number of KV reads served by each cache tier.",
+			Type: metrics.MetricTypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: metrics.Labels("tier", "l1"), Value: float64(l1Hits)},
+				{Labels: metrics.Labels("tier", "l2"), Value: float64(l2Hits)},
+			},
+		},
+		{
+			Name: "pb_kv_cache_misses",
+			Help: "Number of KV reads missed by each cache tier.",
+			Type: metrics.MetricTypeCounter,
+			Samples: []metrics.Sample{
+				{Labels: metrics.Labels("tier", "l1"), Value: float64(l1Misses)},
+				{Labels: metrics.Labels("tier", "l2"), Value: float64(l2Misses)},
+			},
+		},
+		{
+			Name:    "pb_kv_l1_hit_ratio",
+			Help:    "Ratio of KV reads served by the in-process L1 cache.",
+			Type:    metrics.MetricTypeGauge,
+			Samples: []metrics.Sample{{Value: ratio}},
+		},
+		{
+			Name:    "pb_kv_l1_entries",
+			Help:    "Number of entries in the in-process L1 cache.",
+			Type:    metrics.MetricTypeGauge,
+			Samples: []metrics.Sample{{Value: float64(kv.l1.Size())}},
+		},
+	}
+}
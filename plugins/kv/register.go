@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 )
 
 var (
@@ -34,6 +35,9 @@ func Register(app core.App, config Config) error {
 	storeRegistry[app] = store
 	storeMu.Unlock()
 
+	// 向 /api/metrics/prometheus 提供缓存命中指标
+	metrics.GetRegistry(app).Register("kv", store)
+
 	// 如果应用已经引导，直接创建表和启动清理任务
 	if app.IsBootstrapped() {
 		if err := createKVTable(app); err != nil {
@@ -73,6 +77,7 @@ func Register(app core.App, config Config) error {
 		storeMu.Lock()
 		delete(storeRegistry, app)
 		storeMu.Unlock()
+		metrics.GetRegistry(app).Unregister("kv")
 		return e.Next()
 	})
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pocketbase/pocketbase/plugins/metrics"
 	"github.com/pocketbase/pocketbase/tests"
 )
 
@@ -1302,3 +1303,46 @@ func TestRegisterWithHTTPEnabled(t *testing.T) {
 		}
 	})
 }
+
+func TestKVStorePrometheusMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		if err := Register(app, DefaultConfig()); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		store := GetStore(app)
+
+		store.Set("metrics:1", "a")
+		store.Get("metrics:1")       // L1 miss, L2 hit
+		store.Get("metrics:1")       // L1 hit
+		store.Get("metrics:missing") // L1 miss, L2 miss
+		store.MGet("metrics:1", "metrics:missing")
+
+		families := metrics.GetRegistry(app).Gather()
+		values := map[string]float64{}
+		for _, family := range families {
+			for _, sample := range family.Samples {
+				key := family.Name
+				for _, label := range sample.Labels {
+					key += ":" + label.Value
+				}
+				values[key] = sample.Value
+			}
+		}
+
+		expected := map[string]float64{
+			"pb_kv_cache_hits:l1":   2,
+			"pb_kv_cache_misses:l1": 3,
+			"pb_kv_cache_hits:l2":   1,
+			"pb_kv_cache_misses:l2": 2,
+			"pb_kv_l1_hit_ratio":    0.4,
+			"pb_kv_l1_entries":      1,
+		}
+		for key, value := range expected {
+			if values[key] != value {
+				t.Errorf("expected %s = %v, got %v", key, value, values[key])
+			}
+		}
+	})
+}
@@ -2,6 +2,8 @@ package kv
 
 import (
 	"encoding/json"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/pocketbase/pocketbase/core"
@@ -17,6 +19,12 @@ type kvStore struct {
 
 	// 锁持有者标识（用于分布式锁）
 	lockOwner string
+
+	// 读取命中统计（用于 Prometheus 指标）
+	l1Hits   atomic.Int64
+	l1Misses atomic.Int64
+	l2Hits   atomic.Int64
+	l2Misses atomic.Int64
 }
 
 // newKVStore 创建 Store 实例
@@ -67,15 +75,21 @@ func (kv *kvStore) Get(key string) (any, error) {
 	// 1. 检查 L1 缓存（如果启用）
 	if kv.config.L1Enabled {
 		if value, found := kv.l1.Get(key); found {
+			kv.l1Hits.Add(1)
 			return value, nil
 		}
+		kv.l1Misses.Add(1)
 	}
 
 	// 2. 查询 L2 数据库
 	value, err := kv.l2.Get(key)
 	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			kv.l2Misses.Add(1)
+		}
 		return nil, err
 	}
+	kv.l2Hits.Add(1)
 
 	// 3. 获取 L2 中的 TTL，确保 L1 缓存不会超过 L2 的过期时间
 	if kv.config.L1Enabled {
@@ -337,6 +351,8 @@ func (kv *kvStore) MGet(keys ...string) (map[string]any, error) {
 				missedKeys = append(missedKeys, key)
 			}
 		}
+		kv.l1Hits.Add(int64(len(keys) - len(missedKeys)))
+		kv.l1Misses.Add(int64(len(missedKeys)))
 	} else {
 		missedKeys = keys
 	}
@@ -347,6 +363,8 @@ func (kv *kvStore) MGet(keys ...string) (map[string]any, error) {
 		if err != nil {
 			return nil, err
 		}
+		kv.l2Hits.Add(int64(len(l2Result)))
+		kv.l2Misses.Add(int64(len(missedKeys) - len(l2Result)))
 
 		// 合并结果并缓存
 		for k, v := range l2Result {
@@ -0,0 +1,549 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// AlertRuleType 告警规则类型
+type AlertRuleType string
+
+const (
+	// AlertRuleThreshold 窗口内聚合值与阈值比较
+	AlertRuleThreshold AlertRuleType = "threshold"
+
+	// AlertRuleRateOfChange 窗口内首尾样本的每分钟变化量与阈值比较
+	AlertRuleRateOfChange AlertRuleType = "rate_of_change"
+
+	// AlertRuleAbsence 窗口内没有任何采集数据
+	AlertRuleAbsence AlertRuleType = "absence"
+)
+
+// AlertState 告警状态
+type AlertState string
+
+const (
+	// AlertStatePending 条件成立但尚未持续 For 时长
+	AlertStatePending AlertState = "pending"
+
+	// AlertStateFiring 告警触发中
+	AlertStateFiring AlertState = "firing"
+
+	// AlertStateResolved 已触发的告警条件不再成立
+	AlertStateResolved AlertState = "resolved"
+
+	// AlertStateInactive pending 期间条件不再成立（未触发过）
+	AlertStateInactive AlertState = "inactive"
+)
+
+// AlertRule 基于 _metrics 字段的告警规则
+type AlertRule struct {
+	// Name 规则名称（唯一，作为告警状态的键）
+	Name string
+
+	// Field 监控字段，如 "http_5xx_count"、"p95_latency_ms"（Absence 规则可为空）
+	Field string
+
+	// Type 规则类型（默认 AlertRuleThreshold）
+	Type AlertRuleType
+
+	// Operator 比较运算符：">"、">="、"<"、"<="、"=="、"!="（默认 ">"）
+	Operator string
+
+	// Threshold 阈值；RateOfChange 规则为每分钟的变化量
+	Threshold float64
+
+	// Aggregation Threshold 规则在窗口内的聚合方式："avg"、"max"、"min"、"sum"、"last"（默认 "avg"）
+	Aggregation string
+
+	// Window 评估窗口（默认 DefaultAlertWindow），应不小于采集间隔
+	Window time.Duration
+
+	// For 条件需持续的时长，期间为 pending 状态（默认 0，立即触发）
+	For time.Duration
+
+	// Severity 严重级别（默认 "warning"）
+	Severity string
+
+	// Summary 自定义告警消息（可选，默认根据规则自动生成）
+	Summary string
+
+	// Channels 通知渠道名称（为空时通知所有渠道）
+	Channels []string
+}
+
+// withDefaults 返回填充默认值后的规则
+func (r AlertRule) withDefaults() AlertRule {
+	if r.Type == "" {
+		r.Type = AlertRuleThreshold
+	}
+	if r.Operator == "" {
+		r.Operator = ">"
+	}
+	if r.Aggregation == "" {
+		r.Aggregation = "avg"
+	}
+	if r.Window <= 0 {
+		r.Window = DefaultAlertWindow
+	}
+	if r.Severity == "" {
+		r.Severity = "warning"
+	}
+	return r
+}
+
+// validate 校验规则配置
+func (r AlertRule) validate() error {
+	if r.Name == "" {
+		return errors.New("alert rule name is required")
+	}
+
+	switch r.Type {
+	case AlertRuleThreshold, AlertRuleRateOfChange:
+		if _, ok := systemMetricsFields[r.Field]; !ok {
+			return fmt.Errorf("alert rule %q: unknown field %q", r.Name, r.Field)
+		}
+	case AlertRuleAbsence:
+		if _, ok := systemMetricsFields[r.Field]; r.Field != "" && !ok {
+			return fmt.Errorf("alert rule %q: unknown field %q", r.Name, r.Field)
+		}
+	default:
+		return fmt.Errorf("alert rule %q: unknown type %q", r.Name, r.Type)
+	}
+
+	switch r.Operator {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("alert rule %q: unknown operator %q", r.Name, r.Operator)
+	}
+
+	switch r.Aggregation {
+	case "avg", "max", "min", "sum", "last":
+	default:
+		return fmt.Errorf("alert rule %q: unknown aggregation %q", r.Name, r.Aggregation)
+	}
+
+	return nil
+}
+
+// evaluate 在 now 之前 Window 内的样本上评估规则，返回当前值、条件是否成立及消息
+// samples 须按时间升序
+func (r AlertRule) evaluate(samples []*SystemMetrics, now time.Time) (float64, bool, string) {
+	since := now.Add(-r.Window)
+	values := make([]float64, 0, len(samples))
+	times := make([]time.Time, 0, len(samples))
+	for _, s := range samples {
+		t := s.Timestamp.Time()
+		if t.Before(since) || t.After(now) {
+			continue
+		}
+		if getter, ok := systemMetricsFields[r.Field]; ok {
+			values = append(values, getter(s))
+		} else {
+			values = append(values, 0)
+		}
+		times = append(times, t)
+	}
+
+	switch r.Type {
+	case AlertRuleAbsence:
+		active := len(values) == 0
+		return float64(len(values)), active, r.message(fmt.Sprintf("no metrics collected in the last %s", r.Window))
+	case AlertRuleRateOfChange:
+		if len(values) < 2 {
+			return 0, false, ""
+		}
+		minutes := times[len(times)-1].Sub(times[0]).Minutes()
+		if minutes <= 0 {
+			return 0, false, ""
+		}
+		value := (values[len(values)-1] - values[0]) / minutes
+		return value, compareAlertValue(value, r.Operator, r.Threshold),
+			r.message(fmt.Sprintf("%s changed %s/min over %s (%s %s)", r.Field, formatFloat(value), r.Window, r.Operator, formatFloat(r.Threshold)))
+	default:
+		if len(values) == 0 {
+			return 0, false, ""
+		}
+		value := aggregateAlertValues(values, r.Aggregation)
+		return value, compareAlertValue(value, r.Operator, r.Threshold),
+			r.message(fmt.Sprintf("%s %s is %s over %s (%s %s)", r.Field, r.Aggregation, formatFloat(value), r.Window, r.Operator, formatFloat(r.Threshold)))
+	}
+}
+
+func (r AlertRule) message(auto string) string {
+	if r.Summary != "" {
+		return r.Summary
+	}
+	return auto
+}
+
+// aggregateAlertValues 按聚合方式计算窗口值（values 非空）
+func aggregateAlertValues(values []float64, aggregation string) float64 {
+	switch aggregation {
+	case "last":
+		return values[len(values)-1]
+	case "max":
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+		return result
+	case "min":
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+		return result
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	if aggregation == "sum" {
+		return sum
+	}
+	return sum / float64(len(values))
+}
+
+// compareAlertValue 按运算符比较当前值与阈值
+func compareAlertValue(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	default:
+		return value > threshold
+	}
+}
+
+// AlertEvent OnMetricsAlert hook 事件，在告警进入 firing 或由 firing 恢复为 resolved 时触发
+// 调用 e.Next() 继续发送配置的邮件与 Webhook 通知
+type AlertEvent struct {
+	hook.Event
+
+	App   core.App
+	Rule  AlertRule
+	Alert *MetricsAlert
+}
+
+// OnMetricsAlert 返回告警通知 hook，可用于自定义处理（如推送到 IM）
+// 不调用 e.Next() 时将跳过配置的通知渠道
+func OnMetricsAlert(app core.App) *hook.Hook[*AlertEvent] {
+	v := app.Store().GetOrSet(alertHookStoreKey, func() any {
+		return &hook.Hook[*AlertEvent]{}
+	})
+	h, _ := v.(*hook.Hook[*AlertEvent])
+	return h
+}
+
+// AlertManager 定期评估告警规则、维护告警状态并发送通知
+type AlertManager struct {
+	app        core.App
+	repository *MetricsRepository
+	config     Config
+	rules      []AlertRule
+	channels   map[string]*alertChannel
+	httpClient *http.Client
+
+	// startedAt 启动时间（未调用 Start 时为首次评估时间），用于 absence 规则的启动宽限期
+	startedAt time.Time
+
+	mu      sync.Mutex
+	running bool
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewAlertManager 创建告警管理器，规则或渠道配置无效时返回错误
+func NewAlertManager(app core.App, repository *MetricsRepository, config Config) (*AlertManager, error) {
+	config = applyDefaults(config)
+
+	rules, channels, err := parseAlertConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AlertManager{
+		app:        app,
+		repository: repository,
+		config:     config,
+		rules:      rules,
+		channels:   channels,
+		httpClient: &http.Client{Timeout: DefaultAlertWebhookTimeout},
+	}, nil
+}
+
+// parseAlertConfig 校验规则与渠道并预编译 Webhook 模板
+func parseAlertConfig(config Config) ([]AlertRule, map[string]*alertChannel, error) {
+	channels := make(map[string]*alertChannel, len(config.AlertChannels))
+	for _, c := range config.AlertChannels {
+		if _, ok := channels[c.Name]; ok {
+			return nil, nil, fmt.Errorf("duplicated alert channel %q", c.Name)
+		}
+		parsed, err := newAlertChannel(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		channels[c.Name] = parsed
+	}
+
+	rules := make([]AlertRule, 0, len(config.AlertRules))
+	names := make(map[string]bool, len(config.AlertRules))
+	for _, r := range config.AlertRules {
+		r = r.withDefaults()
+		if err := r.validate(); err != nil {
+			return nil, nil, err
+		}
+		if names[r.Name] {
+			return nil, nil, fmt.Errorf("duplicated alert rule %q", r.Name)
+		}
+		names[r.Name] = true
+
+		for _, name := range r.Channels {
+			if _, ok := channels[name]; !ok {
+				return nil, nil, fmt.Errorf("alert rule %q: unknown channel %q", r.Name, name)
+			}
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, channels, nil
+}
+
+// Start 启动定期评估
+func (m *AlertManager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running || len(m.rules) == 0 {
+		return
+	}
+
+	m.running = true
+	m.startedAt = time.Now()
+	m.stopCh = make(chan struct{})
+	m.wg.Add(1)
+	go m.evaluationLoop()
+}
+
+// Stop 停止定期评估
+func (m *AlertManager) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = false
+	close(m.stopCh)
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// evaluationLoop 评估主循环
+func (m *AlertManager) evaluationLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.AlertEvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			if err := m.Evaluate(time.Now()); err != nil {
+				m.app.Logger().Error("Failed to evaluate metrics alerts", "error", err)
+			}
+		}
+	}
+}
+
+// Evaluate 在 now 时刻评估所有规则，更新告警状态并发送通知
+//
+// 状态流转：
+//   - 条件成立：无状态/resolved -> pending（For 为 0 时直接 firing）-> 持续 For 后 firing
+//   - 条件不成立：firing -> resolved，pending -> inactive
+//
+// 仅在进入 firing 和由 firing 恢复为 resolved 时通知。
+// 启动后首次采集完成或经过一个 Window 之前不评估 absence 规则，
+// 避免重启后因停机期间没有数据立即触发。
+func (m *AlertManager) Evaluate(now time.Time) error {
+	if len(m.rules) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	if m.startedAt.IsZero() {
+		m.startedAt = now
+	}
+	startedAt := m.startedAt
+	m.mu.Unlock()
+
+	var maxWindow time.Duration
+	for _, r := range m.rules {
+		maxWindow = max(maxWindow, r.Window)
+	}
+
+	samples, err := m.repository.GetSince(now.Add(-maxWindow))
+	if err != nil {
+		return err
+	}
+
+	existing, err := m.repository.GetAlerts("")
+	if err != nil {
+		return err
+	}
+	alerts := make(map[string]*MetricsAlert, len(existing))
+	for _, a := range existing {
+		alerts[a.Rule] = a
+	}
+
+	nowDT, _ := types.ParseDateTime(now)
+
+	collected := false
+	for _, sample := range samples {
+		if t := sample.Timestamp.Time(); !t.Before(startedAt) && !t.After(now) {
+			collected = true
+			break
+		}
+	}
+
+	var errs []error
+	for _, rule := range m.rules {
+		if rule.Type == AlertRuleAbsence && !collected && now.Sub(startedAt) < rule.Window {
+			continue
+		}
+
+		value, active, message := rule.evaluate(samples, now)
+
+		alert := alerts[rule.Name]
+		if alert == nil {
+			if !active {
+				continue // 从未触发的规则不写入状态
+			}
+			alert = &MetricsAlert{Rule: rule.Name}
+		}
+
+		notify := m.transition(rule, alert, active, nowDT)
+
+		alert.Field = rule.Field
+		alert.Severity = rule.Severity
+		alert.Threshold = rule.Threshold
+		alert.Value = value
+		if message != "" {
+			alert.Message = message
+		}
+		alert.EvaluatedAt = nowDT
+
+		if err := m.repository.SaveAlert(alert); err != nil {
+			errs = append(errs, fmt.Errorf("alert rule %q: %w", rule.Name, err))
+			continue
+		}
+
+		if notify {
+			if err := m.notify(rule, alert); err != nil {
+				m.app.Logger().Error("Failed to send metrics alert notification",
+					"rule", rule.Name,
+					"state", alert.State,
+					"error", err,
+				)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// transition 根据条件是否成立更新告警状态，返回是否需要发送通知
+func (m *AlertManager) transition(rule AlertRule, alert *MetricsAlert, active bool, now types.DateTime) bool {
+	if !active {
+		switch alert.State {
+		case AlertStateFiring:
+			alert.State = AlertStateResolved
+			alert.ResolvedAt = now
+			return true
+		case AlertStatePending:
+			alert.State = AlertStateInactive
+			alert.ActiveAt = types.DateTime{}
+		}
+		return false
+	}
+
+	switch alert.State {
+	case AlertStateFiring:
+		return false
+	case AlertStatePending:
+	default:
+		alert.State = AlertStatePending
+		alert.ActiveAt = now
+		alert.FiredAt = types.DateTime{}
+		alert.ResolvedAt = types.DateTime{}
+	}
+
+	if now.Time().Sub(alert.ActiveAt.Time()) >= rule.For {
+		alert.State = AlertStateFiring
+		alert.FiredAt = now
+		return true
+	}
+
+	return false
+}
+
+// notify 触发 OnMetricsAlert hook，默认处理为发送到规则配置的通知渠道
+func (m *AlertManager) notify(rule AlertRule, alert *MetricsAlert) error {
+	event := &AlertEvent{
+		App:   m.app,
+		Rule:  rule,
+		Alert: alert,
+	}
+
+	return OnMetricsAlert(m.app).Trigger(event, func(e *AlertEvent) error {
+		var errs []error
+		for _, channel := range m.channelsFor(e.Rule) {
+			if err := channel.send(e.App, m.httpClient, e.Rule, e.Alert); err != nil {
+				errs = append(errs, fmt.Errorf("channel %q: %w", channel.Name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// channelsFor 返回规则对应的通知渠道（未指定时为所有渠道）
+func (m *AlertManager) channelsFor(rule AlertRule) []*alertChannel {
+	if len(rule.Channels) == 0 {
+		result := make([]*alertChannel, 0, len(m.config.AlertChannels))
+		for _, c := range m.config.AlertChannels {
+			result = append(result, m.channels[c.Name])
+		}
+		return result
+	}
+
+	result := make([]*alertChannel, 0, len(rule.Channels))
+	for _, name := range rule.Channels {
+		result = append(result, m.channels[name])
+	}
+	return result
+}
+
+// alertTemplateFuncs Webhook 模板可用的函数
+var alertTemplateFuncs = template.FuncMap{
+	"json": alertTemplateJSON,
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"text/template"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// AlertChannelType 通知渠道类型
+type AlertChannelType string
+
+const (
+	// AlertChannelEmail 通过 App 邮件配置发送
+	AlertChannelEmail AlertChannelType = "email"
+
+	// AlertChannelWebhook POST JSON 到指定 URL
+	AlertChannelWebhook AlertChannelType = "webhook"
+)
+
+// AlertChannel 告警通知渠道
+type AlertChannel struct {
+	// Name 渠道名称（唯一，供 AlertRule.Channels 引用）
+	Name string
+
+	// Type 渠道类型
+	Type AlertChannelType
+
+	// To 邮件收件人（Email 渠道）
+	To []string
+
+	// URL Webhook 地址（Webhook 渠道）
+	URL string
+
+	// Headers Webhook 请求头（可选）
+	Headers map[string]string
+
+	// BodyTemplate Webhook 请求体模板（text/template 语法，可选）
+	// 可用字段：.AppName、.AppURL、.Rule（AlertRule）、.Alert（MetricsAlert）
+	// 使用 {{json .Alert.Message}} 输出转义后的 JSON 值；渲染结果必须是合法 JSON
+	// 为空时发送 {"app": ..., "alert": ...}
+	BodyTemplate string
+}
+
+// alertChannel 解析后的通知渠道
+type alertChannel struct {
+	AlertChannel
+
+	to       []mail.Address
+	template *template.Template
+}
+
+// alertWebhookData Webhook 模板数据
+type alertWebhookData struct {
+	AppName string
+	AppURL  string
+	Rule    AlertRule
+	Alert   *MetricsAlert
+}
+
+// newAlertChannel 校验渠道配置并预编译模板
+func newAlertChannel(c AlertChannel) (*alertChannel, error) {
+	if c.Name == "" {
+		return nil, errors.New("alert channel name is required")
+	}
+
+	result := &alertChannel{AlertChannel: c}
+
+	switch c.Type {
+	case AlertChannelEmail:
+		if len(c.To) == 0 {
+			return nil, fmt.Errorf("alert channel %q: at least one recipient is required", c.Name)
+		}
+		for _, to := range c.To {
+			addr, err := mail.ParseAddress(to)
+			if err != nil {
+				return nil, fmt.Errorf("alert channel %q: invalid recipient %q: %w", c.Name, to, err)
+			}
+			result.to = append(result.to, *addr)
+		}
+	case AlertChannelWebhook:
+		if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+			return nil, fmt.Errorf("alert channel %q: invalid webhook url %q", c.Name, c.URL)
+		}
+		if c.BodyTemplate != "" {
+			tmpl, err := template.New(c.Name).Funcs(alertTemplateFuncs).Parse(c.BodyTemplate)
+			if err != nil {
+				return nil, fmt.Errorf("alert channel %q: invalid body template: %w", c.Name, err)
+			}
+			result.template = tmpl
+		}
+	default:
+		return nil, fmt.Errorf("alert channel %q: unknown type %q", c.Name, c.Type)
+	}
+
+	return result, nil
+}
+
+// send 发送告警通知
+func (c *alertChannel) send(app core.App, client *http.Client, rule AlertRule, alert *MetricsAlert) error {
+	if c.Type == AlertChannelEmail {
+		return c.sendEmail(app, alert)
+	}
+	return c.sendWebhook(app, client, rule, alert)
+}
+
+// sendEmail 使用 App 邮件配置发送告警邮件
+func (c *alertChannel) sendEmail(app core.App, alert *MetricsAlert) error {
+	appName := app.Settings().Meta.AppName
+
+	subject := fmt.Sprintf("[%s] %s - %s", strings.ToUpper(string(alert.State)), alert.Rule, appName)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<p><strong>%s</strong> is <strong>%s</strong> (%s).</p>",
+		html.EscapeString(alert.Rule), html.EscapeString(string(alert.State)), html.EscapeString(alert.Severity))
+	fmt.Fprintf(&body, "<p>%s</p>", html.EscapeString(alert.Message))
+	fmt.Fprintf(&body, "<p>Value: %s<br/>Threshold: %s<br/>Active since: %s</p>",
+		formatFloat(alert.Value), formatFloat(alert.Threshold), alert.ActiveAt.String())
+	if alert.State == AlertStateResolved {
+		fmt.Fprintf(&body, "<p>Resolved at: %s</p>", alert.ResolvedAt.String())
+	}
+
+	return app.NewMailClient().Send(&mailer.Message{
+		From: mail.Address{
+			Name:    app.Settings().Meta.SenderName,
+			Address: app.Settings().Meta.SenderAddress,
+		},
+		To:      c.to,
+		Subject: subject,
+		HTML:    body.String(),
+	})
+}
+
+// sendWebhook POST 渲染后的 JSON 到 Webhook 地址
+func (c *alertChannel) sendWebhook(app core.App, client *http.Client, rule AlertRule, alert *MetricsAlert) error {
+	data := alertWebhookData{
+		AppName: app.Settings().Meta.AppName,
+		AppURL:  app.Settings().Meta.AppURL,
+		Rule:    rule,
+		Alert:   alert,
+	}
+
+	var body []byte
+	if c.template != nil {
+		var buf bytes.Buffer
+		if err := c.template.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render body template: %w", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			return errors.New("rendered body template is not valid JSON")
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		body, err = json.Marshal(map[string]any{
+			"app":   data.AppName,
+			"alert": alert,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// alertTemplateJSON 将值编码为 JSON（字符串会带引号并转义，不转义 HTML 字符）
+func alertTemplateJSON(v any) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
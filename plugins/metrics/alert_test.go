@@ -0,0 +1,428 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestAlertConfigValidation(t *testing.T) {
+	scenarios := []struct {
+		name   string
+		config metrics.Config
+		err    string
+	}{
+		{
+			"missing rule name",
+			metrics.Config{AlertRules: []metrics.AlertRule{{Field: "http_5xx_count"}}},
+			"name is required",
+		},
+		{
+			"unknown field",
+			metrics.Config{AlertRules: []metrics.AlertRule{{Name: "a", Field: "unknown"}}},
+			`unknown field "unknown"`,
+		},
+		{
+			"unknown operator",
+			metrics.Config{AlertRules: []metrics.AlertRule{{Name: "a", Field: "http_5xx_count", Operator: "=>"}}},
+			`unknown operator "=>"`,
+		},
+		{
+			"duplicated rule",
+			metrics.Config{AlertRules: []metrics.AlertRule{
+				{Name: "a", Field: "http_5xx_count"},
+				{Name: "a", Field: "p95_latency_ms"},
+			}},
+			`duplicated alert rule "a"`,
+		},
+		{
+			"unknown channel",
+			metrics.Config{AlertRules: []metrics.AlertRule{{Name: "a", Field: "http_5xx_count", Channels: []string{"ops"}}}},
+			`unknown channel "ops"`,
+		},
+		{
+			"invalid recipient",
+			metrics.Config{AlertChannels: []metrics.AlertChannel{{Name: "ops", Type: metrics.AlertChannelEmail, To: []string{"invalid"}}}},
+			`invalid recipient "invalid"`,
+		},
+		{
+			"invalid webhook template",
+			metrics.Config{AlertChannels: []metrics.AlertChannel{{Name: "hook", Type: metrics.AlertChannelWebhook, URL: "https://example.com", BodyTemplate: "{{.Alert"}}},
+			"invalid body template",
+		},
+		{
+			"valid",
+			metrics.Config{
+				AlertRules: []metrics.AlertRule{
+					{Name: "errors", Field: "http_5xx_count", Channels: []string{"ops"}},
+					{Name: "no data", Type: metrics.AlertRuleAbsence},
+				},
+				AlertChannels: []metrics.AlertChannel{{Name: "ops", Type: metrics.AlertChannelEmail, To: []string{"ops@example.com"}}},
+			},
+			"",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app, _ := tests.NewTestApp()
+			defer app.Cleanup()
+
+			err := metrics.Register(app, s.config)
+			if s.err == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), s.err) {
+				t.Fatalf("expected error containing %q, got %v", s.err, err)
+			}
+		})
+	}
+}
+
+// insertAlertSamples 按 offset（相对 base）写入 _metrics 样本
+func insertAlertSamples(t testing.TB, repo *metrics.MetricsRepository, base time.Time, samples map[time.Duration]*metrics.SystemMetrics) {
+	for offset, m := range samples {
+		m.Timestamp, _ = types.ParseDateTime(base.Add(offset))
+		if err := repo.Insert(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAlertManagerThresholdLifecycle(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		var webhookMu sync.Mutex
+		var webhookBodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, _ := io.ReadAll(r.Body)
+			webhookMu.Lock()
+			webhookBodies = append(webhookBodies, r.Header.Get("X-Token")+" "+string(raw))
+			webhookMu.Unlock()
+		}))
+		defer server.Close()
+
+		repo := metrics.NewMetricsRepository(app)
+		config := metrics.DefaultConfig()
+		config.AlertRules = []metrics.AlertRule{{
+			Name:      "5xx spike",
+			Field:     "http_5xx_count",
+			Threshold: 5,
+			For:       2 * time.Minute,
+			Severity:  "critical",
+		}}
+		config.AlertChannels = []metrics.AlertChannel{
+			{Name: "ops", Type: metrics.AlertChannelEmail, To: []string{"ops@example.com"}},
+			{
+				Name:         "chat",
+				Type:         metrics.AlertChannelWebhook,
+				URL:          server.URL,
+				Headers:      map[string]string{"X-Token": "secret"},
+				BodyTemplate: `{"text": {{json (printf "%s is %s: %s" .Alert.Rule .Alert.State .Alert.Message)}}, "severity": {{json .Rule.Severity}}}`,
+			},
+		}
+
+		manager, err := metrics.NewAlertManager(app, repo, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var events []metrics.AlertState
+		metrics.OnMetricsAlert(app).BindFunc(func(e *metrics.AlertEvent) error {
+			events = append(events, e.Alert.State)
+			return e.Next()
+		})
+
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			-time.Minute:    {Http5xxCount: 8},
+			0:               {Http5xxCount: 12},
+			2 * time.Minute: {Http5xxCount: 10},
+			9 * time.Minute: {Http5xxCount: 0},
+		})
+
+		assertState := func(now time.Time, expected metrics.AlertState) *metrics.MetricsAlert {
+			t.Helper()
+			if err := manager.Evaluate(now); err != nil {
+				t.Fatal(err)
+			}
+			alerts, err := repo.GetAlerts("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(alerts) != 1 || alerts[0].State != expected {
+				t.Fatalf("expected a single %s alert, got %+v", expected, alerts)
+			}
+			return alerts[0]
+		}
+
+		// 条件成立但未持续 For 时长
+		alert := assertState(base, metrics.AlertStatePending)
+		if alert.Value != 10 || alert.Severity != "critical" || alert.ActiveAt.IsZero() || !alert.FiredAt.IsZero() {
+			t.Errorf("unexpected pending alert %+v", alert)
+		}
+		if len(events) != 0 {
+			t.Fatalf("expected no notifications while pending, got %v", events)
+		}
+
+		// 持续 For 后触发
+		alert = assertState(base.Add(2*time.Minute), metrics.AlertStateFiring)
+		if alert.FiredAt.IsZero() || alert.Message != "http_5xx_count avg is 10 over 5m0s (> 5)" {
+			t.Errorf("unexpected firing alert %+v", alert)
+		}
+
+		// 持续触发不重复通知
+		assertState(base.Add(3*time.Minute), metrics.AlertStateFiring)
+
+		// 窗口内只有 0 值样本后恢复
+		alert = assertState(base.Add(10*time.Minute), metrics.AlertStateResolved)
+		if alert.ResolvedAt.IsZero() || alert.Value != 0 {
+			t.Errorf("unexpected resolved alert %+v", alert)
+		}
+
+		if len(events) != 2 || events[0] != metrics.AlertStateFiring || events[1] != metrics.AlertStateResolved {
+			t.Fatalf("expected firing and resolved notifications, got %v", events)
+		}
+
+		if total := app.TestMailer.TotalSend(); total != 2 {
+			t.Fatalf("expected 2 emails, got %d", total)
+		}
+		if msg := app.TestMailer.FirstMessage(); msg.Subject != "[FIRING] 5xx spike - "+app.Settings().Meta.AppName || msg.To[0].Address != "ops@example.com" {
+			t.Errorf("unexpected email %q to %v", msg.Subject, msg.To)
+		}
+
+		webhookMu.Lock()
+		defer webhookMu.Unlock()
+		if len(webhookBodies) != 2 {
+			t.Fatalf("expected 2 webhook calls, got %d", len(webhookBodies))
+		}
+		expectedBody := `secret {"text": "5xx spike is firing: http_5xx_count avg is 10 over 5m0s (> 5)", "severity": "critical"}`
+		if webhookBodies[0] != expectedBody {
+			t.Errorf("unexpected webhook body:\n%s\nexpected:\n%s", webhookBodies[0], expectedBody)
+		}
+	})
+}
+
+func TestAlertManagerRateOfChangeAndAbsence(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		var received map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&received)
+		}))
+		defer server.Close()
+
+		repo := metrics.NewMetricsRepository(app)
+		config := metrics.DefaultConfig()
+		config.AlertRules = []metrics.AlertRule{
+			{Name: "goroutine leak", Type: metrics.AlertRuleRateOfChange, Field: "goroutines_count", Threshold: 100, Channels: []string{"hook"}},
+			{Name: "collector down", Type: metrics.AlertRuleAbsence, Window: time.Minute, Channels: []string{"hook"}},
+			{Name: "slow", Field: "p95_latency_ms", Aggregation: "max", Threshold: 1000, Channels: []string{"hook"}},
+		}
+		config.AlertChannels = []metrics.AlertChannel{{Name: "hook", Type: metrics.AlertChannelWebhook, URL: server.URL}}
+
+		manager, err := metrics.NewAlertManager(app, repo, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var notified []string
+		metrics.OnMetricsAlert(app).BindFunc(func(e *metrics.AlertEvent) error {
+			notified = append(notified, e.Rule.Name)
+			if e.Rule.Name == "collector down" {
+				return nil // 不调用 e.Next() 时跳过通知渠道
+			}
+			return e.Next()
+		})
+
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			-2 * time.Minute: {GoroutinesCount: 100, P95LatencyMs: 200},
+			0:                {GoroutinesCount: 400, P95LatencyMs: 300},
+		})
+
+		if err := manager.Evaluate(base); err != nil {
+			t.Fatal(err)
+		}
+
+		alerts, err := repo.GetAlerts(metrics.AlertStateFiring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 1 || alerts[0].Rule != "goroutine leak" || alerts[0].Value != 150 {
+			t.Fatalf("expected only the rate of change alert to fire, got %+v", alerts)
+		}
+		if alert, _ := received["alert"].(map[string]any); alert["rule"] != "goroutine leak" || alert["state"] != "firing" {
+			t.Errorf("unexpected default webhook payload %v", received)
+		}
+
+		// 采集停止后 absence 规则触发，rate of change 因样本不足恢复
+		received = nil
+		if err := manager.Evaluate(base.Add(4 * time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		alerts, err = repo.GetAlerts("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		states := map[string]metrics.AlertState{}
+		for _, a := range alerts {
+			states[a.Rule] = a.State
+		}
+		if len(states) != 2 || states["collector down"] != metrics.AlertStateFiring || states["goroutine leak"] != metrics.AlertStateResolved {
+			t.Fatalf("unexpected states %v", states)
+		}
+
+		if strings.Join(notified, ",") != "goroutine leak,goroutine leak,collector down" {
+			t.Errorf("unexpected notifications %v", notified)
+		}
+		if alert, _ := received["alert"].(map[string]any); alert["rule"] != "goroutine leak" || alert["state"] != "resolved" {
+			t.Errorf("expected only the resolved webhook, got %v", received)
+		}
+	})
+}
+
+func TestAlertManagerAbsenceGraceAndInactive(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		repo := metrics.NewMetricsRepository(app)
+		config := metrics.DefaultConfig()
+		config.AlertRules = []metrics.AlertRule{
+			{Name: "collector down", Type: metrics.AlertRuleAbsence, Window: 2 * time.Minute},
+			{Name: "busy", Field: "goroutines_count", Threshold: 100, Window: time.Minute, For: 5 * time.Minute},
+		}
+
+		manager, err := metrics.NewAlertManager(app, repo, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var notified []string
+		metrics.OnMetricsAlert(app).BindFunc(func(e *metrics.AlertEvent) error {
+			notified = append(notified, e.Rule.Name+":"+string(e.Alert.State))
+			return nil
+		})
+
+		base := time.Now().Add(-time.Hour).Truncate(time.Second)
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			-10 * time.Minute: {GoroutinesCount: 10},
+			30 * time.Second:  {GoroutinesCount: 200},
+		})
+
+		states := func(now time.Time) map[string]metrics.AlertState {
+			t.Helper()
+			if err := manager.Evaluate(now); err != nil {
+				t.Fatal(err)
+			}
+			alerts, err := repo.GetAlerts("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := map[string]metrics.AlertState{}
+			for _, a := range alerts {
+				result[a.Rule] = a.State
+			}
+			return result
+		}
+
+		// 启动后（停机期间没有数据）尚未采集、也未经过一个 Window 时不评估 absence 规则
+		if s := states(base); len(s) != 0 {
+			t.Fatalf("expected no alerts right after startup, got %v", s)
+		}
+
+		// 条件成立进入 pending
+		if s := states(base.Add(time.Minute)); s["busy"] != metrics.AlertStatePending || s["collector down"] != "" {
+			t.Fatalf("expected only a pending busy alert, got %v", s)
+		}
+
+		// pending 期间条件不再成立时进入 inactive 而不是 resolved；
+		// 启动后已有采集数据，absence 规则按窗口正常评估
+		s := states(base.Add(3 * time.Minute))
+		if s["busy"] != metrics.AlertStateInactive || s["collector down"] != metrics.AlertStateFiring {
+			t.Fatalf("expected inactive busy and firing absence alerts, got %v", s)
+		}
+
+		if strings.Join(notified, ",") != "collector down:firing" {
+			t.Errorf("unexpected notifications %v", notified)
+		}
+	})
+}
+
+func TestMetricsAlertsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testAppWithMetrics := func(tb testing.TB) *tests.TestApp {
+		config := metrics.DefaultConfig()
+		config.CollectionInterval = time.Hour
+		app := newTestAppWithMetrics(tb, config)
+
+		repo := metrics.NewMetricsRepository(app)
+		for _, a := range []*metrics.MetricsAlert{
+			{Rule: "cpu", Field: "cpu_usage_percent", State: metrics.AlertStateFiring, Value: 95, FiredAt: types.NowDateTime()},
+			{Rule: "wal", Field: "sqlite_wal_size_mb", State: metrics.AlertStateResolved, ResolvedAt: types.NowDateTime()},
+		} {
+			if err := repo.SaveAlert(a); err != nil {
+				tb.Fatal(err)
+			}
+		}
+
+		return app
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unauthorized",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/alerts",
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "invalid state",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/alerts?state=unknown",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "all alerts",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/alerts",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  200,
+			ExpectedContent: []string{`"rule":"cpu"`, `"state":"firing"`, `"rule":"wal"`, `"state":"resolved"`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:               "filtered by state",
+			Method:             http.MethodGet,
+			URL:                "/api/system/metrics/alerts?state=firing",
+			Headers:            map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:     200,
+			ExpectedContent:    []string{`"rule":"cpu"`},
+			NotExpectedContent: []string{`"rule":"wal"`},
+			ExpectedEvents:     map[string]int{"*": 0},
+			TestAppFactory:     testAppWithMetrics,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -21,6 +21,9 @@ type MetricsCollector struct {
 	repository    *MetricsRepository
 	config        Config
 	latencyBuffer *LatencyBuffer
+	requests      *RequestMetrics
+	routes        *routeWindow
+	slowQueries   *slowQueryLog
 	cpuSampler    *CPUSampler
 	http5xxCount  atomic.Int64
 	stopCh        chan struct{}
@@ -36,6 +39,9 @@ func NewMetricsCollector(app core.App, repository *MetricsRepository, config Con
 		repository:    repository,
 		config:        config,
 		latencyBuffer: NewLatencyBuffer(config.LatencyBufferSize),
+		requests:      NewRequestMetrics(config.RequestDurationBuckets),
+		routes:        newRouteWindow(config.RequestDurationBuckets),
+		slowQueries:   newSlowQueryLog(config.SlowQueryThreshold),
 		cpuSampler:    NewCPUSampler(),
 	}
 }
@@ -88,6 +94,22 @@ func (c *MetricsCollector) RecordError(statusCode int) {
 	}
 }
 
+// RecordRequest 记录按路由模式、方法和状态码分组的请求耗时（由 HTTP 中间件调用）
+func (c *MetricsCollector) RecordRequest(method, route string, status int, duration time.Duration) {
+	c.requests.Record(method, route, status, duration)
+	c.routes.record(method, route, status, duration)
+}
+
+// RecordQuery 记录一条数据库语句的耗时，超过慢查询阈值时暂存至下一个采集周期（由数据库查询回调调用）
+func (c *MetricsCollector) RecordQuery(statement string, duration time.Duration, err error) {
+	c.slowQueries.record(statement, duration, err)
+}
+
+// GetRequestMetrics 返回请求耗时直方图（用于外部访问）
+func (c *MetricsCollector) GetRequestMetrics() *RequestMetrics {
+	return c.requests
+}
+
 // GetLatencyBuffer 返回延迟 buffer（用于外部访问）
 func (c *MetricsCollector) GetLatencyBuffer() *LatencyBuffer {
 	return c.latencyBuffer
@@ -123,6 +145,38 @@ func (c *MetricsCollector) collectAndStore() {
 			"error", err,
 		)
 	}
+
+	// 按路由的请求统计与系统指标使用相同的时间戳
+	if err := c.repository.InsertRouteMetrics(c.routes.flush(metrics.Timestamp)); err != nil {
+		c.app.Logger().Error(
+			"Failed to store route metrics",
+			"error", err,
+		)
+	}
+
+	// 自定义指标的周期内增量
+	if err := c.repository.InsertCustomMetrics(GetCustomMetrics(c.app).flush(metrics.Timestamp)); err != nil {
+		c.app.Logger().Error(
+			"Failed to store custom metrics",
+			"error", err,
+		)
+	}
+
+	// 周期内捕获的 SQLite 慢查询
+	slowQueries, dropped := c.slowQueries.flush(c.app)
+	if dropped > 0 {
+		c.app.Logger().Warn(
+			"Slow query log buffer is full, some slow queries were not recorded",
+			"dropped", dropped,
+			"limit", DefaultSlowQueryBufferSize,
+		)
+	}
+	if err := c.repository.InsertSlowQueries(slowQueries); err != nil {
+		c.app.Logger().Error(
+			"Failed to store slow queries",
+			"error", err,
+		)
+	}
 }
 
 // collectMetrics 采集所有指标
@@ -31,6 +31,47 @@ type Config struct {
 	// 设为 true 时，P95 反映的是「采集周期内」的延迟分布
 	// 设为 false 时，P95 反映的是「最近 N 个请求」的延迟分布
 	ResetLatencyBufferOnCollect bool
+
+	// DisablePrometheus 禁用 /api/metrics/prometheus 抓取端点
+	DisablePrometheus bool
+
+	// PrometheusToken 抓取端点的 Bearer Token（可选）
+	// 未设置时仅 Superuser 可访问，设置后携带 "Authorization: Bearer <token>" 的请求也可访问
+	PrometheusToken string
+
+	// RequestDurationBuckets HTTP 请求耗时直方图分桶（秒，默认 DefaultRequestDurationBuckets）
+	RequestDurationBuckets []float64
+
+	// DisableRollups 禁用降采样（5 分钟、小时、天）
+	DisableRollups bool
+
+	// Rollup5mRetentionDays 5 分钟降采样保留天数（默认 30 天）
+	Rollup5mRetentionDays int
+
+	// RollupHourlyRetentionDays 小时降采样保留天数（默认 180 天）
+	RollupHourlyRetentionDays int
+
+	// RollupDailyRetentionDays 天降采样保留天数（默认 730 天）
+	RollupDailyRetentionDays int
+
+	// CustomMetricsMaxSeries 每个自定义指标的最大序列数（默认 100）
+	// 超出后新的标签组合合并到标签值为 CustomMetricsOverflowValue 的序列中
+	CustomMetricsMaxSeries int
+
+	// DisableSlowQueryLog 禁用 SQLite 慢查询日志
+	DisableSlowQueryLog bool
+
+	// SlowQueryThreshold SQLite 慢查询阈值（默认 500ms），耗时不低于阈值的语句会连同查询计划一起记录
+	SlowQueryThreshold time.Duration
+
+	// AlertRules 告警规则（基于 _metrics 中采集的字段）
+	AlertRules []AlertRule
+
+	// AlertChannels 告警通知渠道（邮件、Webhook）
+	AlertChannels []AlertChannel
+
+	// AlertEvaluationInterval 告警规则评估间隔（默认与 CollectionInterval 相同）
+	AlertEvaluationInterval time.Duration
 }
 
 // DefaultConfig 返回默认配置
@@ -43,6 +84,11 @@ func DefaultConfig() Config {
 		EnableMiddleware:            true,
 		CleanupCron:                 DefaultCleanupCron,
 		ResetLatencyBufferOnCollect: false,
+		Rollup5mRetentionDays:       DefaultRollup5mRetentionDays,
+		RollupHourlyRetentionDays:   DefaultRollupHourlyRetentionDays,
+		RollupDailyRetentionDays:    DefaultRollupDailyRetentionDays,
+		CustomMetricsMaxSeries:      DefaultCustomMetricsMaxSeries,
+		SlowQueryThreshold:          DefaultSlowQueryThreshold,
 	}
 }
 
@@ -60,6 +106,27 @@ func applyDefaults(config Config) Config {
 	if config.CleanupCron == "" {
 		config.CleanupCron = DefaultCleanupCron
 	}
+	if len(config.RequestDurationBuckets) == 0 {
+		config.RequestDurationBuckets = DefaultRequestDurationBuckets
+	}
+	if config.Rollup5mRetentionDays <= 0 {
+		config.Rollup5mRetentionDays = DefaultRollup5mRetentionDays
+	}
+	if config.RollupHourlyRetentionDays <= 0 {
+		config.RollupHourlyRetentionDays = DefaultRollupHourlyRetentionDays
+	}
+	if config.RollupDailyRetentionDays <= 0 {
+		config.RollupDailyRetentionDays = DefaultRollupDailyRetentionDays
+	}
+	if config.CustomMetricsMaxSeries <= 0 {
+		config.CustomMetricsMaxSeries = DefaultCustomMetricsMaxSeries
+	}
+	if config.SlowQueryThreshold <= 0 {
+		config.SlowQueryThreshold = DefaultSlowQueryThreshold
+	}
+	if config.AlertEvaluationInterval <= 0 {
+		config.AlertEvaluationInterval = config.CollectionInterval
+	}
 	return config
 }
 
@@ -106,5 +173,60 @@ func applyEnvOverrides(config Config) Config {
 		config.ResetLatencyBufferOnCollect = strings.EqualFold(v, "true") || v == "1"
 	}
 
+	// PB_METRICS_PROMETHEUS_DISABLED
+	if v := os.Getenv("PB_METRICS_PROMETHEUS_DISABLED"); v != "" {
+		config.DisablePrometheus = strings.EqualFold(v, "true") || v == "1"
+	}
+
+	// PB_METRICS_PROMETHEUS_TOKEN
+	if v := os.Getenv("PB_METRICS_PROMETHEUS_TOKEN"); v != "" {
+		config.PrometheusToken = v
+	}
+
+	// PB_METRICS_ROLLUPS_DISABLED
+	if v := os.Getenv("PB_METRICS_ROLLUPS_DISABLED"); v != "" {
+		config.DisableRollups = strings.EqualFold(v, "true") || v == "1"
+	}
+
+	// PB_METRICS_ROLLUP_5M_RETENTION_DAYS
+	if v := os.Getenv("PB_METRICS_ROLLUP_5M_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			config.Rollup5mRetentionDays = days
+		}
+	}
+
+	// PB_METRICS_ROLLUP_1H_RETENTION_DAYS
+	if v := os.Getenv("PB_METRICS_ROLLUP_1H_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			config.RollupHourlyRetentionDays = days
+		}
+	}
+
+	// PB_METRICS_ROLLUP_1D_RETENTION_DAYS
+	if v := os.Getenv("PB_METRICS_ROLLUP_1D_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			config.RollupDailyRetentionDays = days
+		}
+	}
+
+	// PB_METRICS_CUSTOM_MAX_SERIES
+	if v := os.Getenv("PB_METRICS_CUSTOM_MAX_SERIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.CustomMetricsMaxSeries = n
+		}
+	}
+
+	// PB_METRICS_SLOW_QUERY_DISABLED
+	if v := os.Getenv("PB_METRICS_SLOW_QUERY_DISABLED"); v != "" {
+		config.DisableSlowQueryLog = strings.EqualFold(v, "true") || v == "1"
+	}
+
+	// PB_METRICS_SLOW_QUERY_THRESHOLD_MS (毫秒)
+	if v := os.Getenv("PB_METRICS_SLOW_QUERY_THRESHOLD_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			config.SlowQueryThreshold = time.Duration(ms) * time.Millisecond
+		}
+	}
+
 	return config
 }
@@ -8,6 +8,27 @@ import "time"
 const (
 	// SystemMetricsTableName 系统监控指标表名
 	SystemMetricsTableName = "_metrics"
+
+	// RouteMetricsTableName 按路由统计的请求指标表名
+	RouteMetricsTableName = "_metrics_routes"
+
+	// MetricsAlertsTableName 告警状态表名
+	MetricsAlertsTableName = "_metrics_alerts"
+
+	// CustomMetricsTableName 自定义指标表名
+	CustomMetricsTableName = "_metrics_custom"
+
+	// Metrics5mTableName 5 分钟降采样表名
+	Metrics5mTableName = "_metrics_5m"
+
+	// MetricsHourlyTableName 小时降采样表名
+	MetricsHourlyTableName = "_metrics_1h"
+
+	// MetricsDailyTableName 天降采样表名
+	MetricsDailyTableName = "_metrics_1d"
+
+	// SlowQueriesTableName SQLite 慢查询日志表名
+	SlowQueriesTableName = "_metrics_slow_queries"
 )
 
 // 默认配置常量
@@ -23,10 +44,55 @@ const (
 
 	// DefaultCleanupCron 默认清理任务 Cron 表达式（每天 03:00）
 	DefaultCleanupCron = "0 3 * * *"
+
+	// DefaultRollup5mRetentionDays 5 分钟降采样默认保留天数
+	DefaultRollup5mRetentionDays = 30
+
+	// DefaultRollupHourlyRetentionDays 小时降采样默认保留天数
+	DefaultRollupHourlyRetentionDays = 180
+
+	// DefaultRollupDailyRetentionDays 天降采样默认保留天数（约 2 年）
+	DefaultRollupDailyRetentionDays = 730
+
+	// DefaultRollupCron 降采样任务 Cron 表达式（每分钟检查已结束的时间桶）
+	DefaultRollupCron = "* * * * *"
+
+	// DefaultCustomMetricsMaxSeries 每个自定义指标默认的最大序列数（标签组合数）
+	DefaultCustomMetricsMaxSeries = 100
+
+	// DefaultSlowQueryThreshold SQLite 慢查询默认阈值
+	DefaultSlowQueryThreshold = 500 * time.Millisecond
+
+	// DefaultSlowQueryBufferSize 每个采集周期最多记录的慢查询数，超出的语句只计数不记录
+	DefaultSlowQueryBufferSize = 100
+
+	// DefaultLongTransactionThreshold PostgreSQL 长事务的默认判定阈值
+	DefaultLongTransactionThreshold = time.Minute
+
+	// DefaultAlertWindow 告警规则默认评估窗口（5分钟）
+	DefaultAlertWindow = 5 * time.Minute
+
+	// DefaultAlertWebhookTimeout Webhook 通知的默认超时
+	DefaultAlertWebhookTimeout = 10 * time.Second
+
+	// PrometheusRoute Prometheus 抓取端点
+	PrometheusRoute = "/api/metrics/prometheus"
 )
 
+// DefaultRequestDurationBuckets HTTP 请求耗时直方图的默认分桶（秒）
+var DefaultRequestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
 // Store Key 常量
 const (
 	// pluginStoreKey 在 app.Store() 中存储插件实例的键
 	pluginStoreKey = "__pbMetricsPlugin__"
+
+	// registryStoreKey 在 app.Store() 中存储指标注册表的键
+	registryStoreKey = "__pbMetricsRegistry__"
+
+	// alertHookStoreKey 在 app.Store() 中存储 OnMetricsAlert hook 的键
+	alertHookStoreKey = "__pbMetricsAlertHook__"
+
+	// customMetricsStoreKey 在 app.Store() 中存储自定义指标注册表的键
+	customMetricsStoreKey = "__pbMetricsCustom__"
 )
@@ -0,0 +1,492 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// CustomMetricsOverflowValue 超出序列数上限后，新标签组合统一使用的标签值
+const CustomMetricsOverflowValue = "__overflow__"
+
+// maxCustomMetricLabels 单个自定义指标允许的最大标签数
+const maxCustomMetricLabels = 10
+
+var (
+	customMetricNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	customMetricLabelRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// CustomMetrics 业务代码记录的自定义指标（counter、gauge、histogram）
+//
+// 每个指标的序列数（不同标签组合）不超过 maxSeries，超出后新的标签组合
+// 会合并到所有标签值为 CustomMetricsOverflowValue 的溢出序列中。
+// 采集器每个周期将增量写入 _metrics_custom，同时通过 Registry 输出到 Prometheus 端点。
+type CustomMetrics struct {
+	app       core.App
+	mu        sync.RWMutex
+	maxSeries int
+	families  map[string]*customFamily
+	flushMu   sync.Mutex // 保证同一时间只有一个 flush 计算增量
+}
+
+// customFamily 同名自定义指标的定义与序列
+type customFamily struct {
+	name      string
+	typ       MetricType
+	labelKeys []string  // 升序，首次注册时确定
+	buckets   []float64 // histogram 分桶上界（升序）
+
+	mu             sync.RWMutex
+	series         map[string]*customSeries
+	overflowWarned bool
+}
+
+// customSeries 单个标签组合的当前值
+type customSeries struct {
+	labels types.JSONMap[string]
+
+	value  atomic.Uint64   // counter/gauge 的值（math.Float64bits）
+	counts []atomic.Uint64 // histogram 各分桶的非累计计数，最后一个为 +Inf
+	sum    atomic.Uint64   // histogram 观测值之和（math.Float64bits）
+
+	// 上次持久化时的值，用于计算周期内增量（仅在 flush 中访问）
+	lastValue  float64
+	lastCounts []uint64
+	lastSum    float64
+}
+
+// newCustomMetrics 创建自定义指标注册表
+func newCustomMetrics(app core.App) *CustomMetrics {
+	return &CustomMetrics{
+		app:       app,
+		maxSeries: DefaultCustomMetricsMaxSeries,
+		families:  make(map[string]*customFamily),
+	}
+}
+
+// GetCustomMetrics 获取指定 App 的自定义指标注册表（不存在时创建）
+//
+// 首次创建时以 "app" 注册到 GetRegistry(app)，与 metrics 插件的注册顺序无关；
+// 插件未注册时指标仍可记录并通过 Prometheus 端点输出，但不会持久化。
+func GetCustomMetrics(app core.App) *CustomMetrics {
+	// 先获取 Registry，GetOrSet 的回调中不能再访问 Store
+	registry := GetRegistry(app)
+
+	v := app.Store().GetOrSet(customMetricsStoreKey, func() any {
+		m := newCustomMetrics(app)
+		registry.Register("app", m)
+		return m
+	})
+	m, _ := v.(*CustomMetrics)
+	return m
+}
+
+// Counter 获取或创建 App 的自定义 counter 序列
+func Counter(app core.App, name string, labels map[string]string) (*CustomCounter, error) {
+	return GetCustomMetrics(app).Counter(name, labels)
+}
+
+// Gauge 获取或创建 App 的自定义 gauge 序列
+func Gauge(app core.App, name string, labels map[string]string) (*CustomGauge, error) {
+	return GetCustomMetrics(app).Gauge(name, labels)
+}
+
+// Histogram 获取或创建 App 的自定义 histogram 序列
+// buckets 仅在指标首次注册时生效，为空时使用 DefaultRequestDurationBuckets
+func Histogram(app core.App, name string, labels map[string]string, buckets ...float64) (*CustomHistogram, error) {
+	return GetCustomMetrics(app).Histogram(name, labels, buckets...)
+}
+
+// CustomCounter 只增不减的计数器
+type CustomCounter struct {
+	s *customSeries
+}
+
+// Inc 加 1
+func (c *CustomCounter) Inc() {
+	c.Add(1)
+}
+
+// Add 增加 v，v 为负数或 NaN 时忽略
+func (c *CustomCounter) Add(v float64) {
+	if !(v > 0) {
+		return
+	}
+	addFloat(&c.s.value, v)
+}
+
+// Value 返回当前累计值
+func (c *CustomCounter) Value() float64 {
+	return math.Float64frombits(c.s.value.Load())
+}
+
+// CustomGauge 可任意设置的瞬时值
+type CustomGauge struct {
+	s *customSeries
+}
+
+// Set 设置当前值
+func (g *CustomGauge) Set(v float64) {
+	g.s.value.Store(math.Float64bits(v))
+}
+
+// Add 增加 v（可为负数）
+func (g *CustomGauge) Add(v float64) {
+	addFloat(&g.s.value, v)
+}
+
+// Inc 加 1
+func (g *CustomGauge) Inc() {
+	g.Add(1)
+}
+
+// Dec 减 1
+func (g *CustomGauge) Dec() {
+	g.Add(-1)
+}
+
+// Value 返回当前值
+func (g *CustomGauge) Value() float64 {
+	return math.Float64frombits(g.s.value.Load())
+}
+
+// CustomHistogram 观测值分布直方图
+type CustomHistogram struct {
+	s      *customSeries
+	bounds []float64
+}
+
+// Observe 记录一次观测值，NaN 时忽略
+func (h *CustomHistogram) Observe(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	h.s.counts[sort.SearchFloat64s(h.bounds, v)].Add(1)
+	addFloat(&h.s.sum, v)
+}
+
+// Counter 获取或创建 counter 序列
+func (m *CustomMetrics) Counter(name string, labels map[string]string) (*CustomCounter, error) {
+	s, _, err := m.series(name, MetricTypeCounter, labels, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomCounter{s: s}, nil
+}
+
+// Gauge 获取或创建 gauge 序列
+func (m *CustomMetrics) Gauge(name string, labels map[string]string) (*CustomGauge, error) {
+	s, _, err := m.series(name, MetricTypeGauge, labels, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomGauge{s: s}, nil
+}
+
+// Histogram 获取或创建 histogram 序列
+// buckets 仅在指标首次注册时生效，为空时使用 DefaultRequestDurationBuckets
+func (m *CustomMetrics) Histogram(name string, labels map[string]string, buckets ...float64) (*CustomHistogram, error) {
+	s, f, err := m.series(name, MetricTypeHistogram, labels, buckets)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomHistogram{s: s, bounds: f.buckets}, nil
+}
+
+// setMaxSeries 设置每个指标的最大序列数（由插件注册时调用）
+func (m *CustomMetrics) setMaxSeries(n int) {
+	if n <= 0 {
+		n = DefaultCustomMetricsMaxSeries
+	}
+	m.mu.Lock()
+	m.maxSeries = n
+	m.mu.Unlock()
+}
+
+// series 校验并返回指定标签组合的序列，序列数达到上限时返回溢出序列
+func (m *CustomMetrics) series(name string, typ MetricType, labels map[string]string, buckets []float64) (*customSeries, *customFamily, error) {
+	f, maxSeries, err := m.family(name, typ, labels, buckets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]string, len(f.labelKeys))
+	for i, k := range f.labelKeys {
+		values[i] = labels[k]
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s, f, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok = f.series[key]; ok {
+		return s, f, nil
+	}
+
+	// 溢出序列不计入上限，保证超出后仍能记录
+	if len(f.series) >= maxSeries {
+		for i := range values {
+			values[i] = CustomMetricsOverflowValue
+		}
+		key = strings.Join(values, "\xff")
+		if s, ok = f.series[key]; ok {
+			return s, f, nil
+		}
+
+		if !f.overflowWarned {
+			f.overflowWarned = true
+			m.app.Logger().Warn(
+				"Custom metric reached the max series limit, new label values are recorded as overflow",
+				"name", name,
+				"maxSeries", maxSeries,
+			)
+		}
+	}
+
+	s = &customSeries{labels: make(types.JSONMap[string], len(values))}
+	for i, k := range f.labelKeys {
+		s.labels[k] = values[i]
+	}
+	if typ == MetricTypeHistogram {
+		s.counts = make([]atomic.Uint64, len(f.buckets)+1)
+		s.lastCounts = make([]uint64, len(f.buckets)+1)
+	}
+	f.series[key] = s
+
+	return s, f, nil
+}
+
+// family 返回指定名称的指标定义（不存在时创建），并校验类型与标签名是否一致
+func (m *CustomMetrics) family(name string, typ MetricType, labels map[string]string, buckets []float64) (*customFamily, int, error) {
+	m.mu.RLock()
+	f, ok := m.families[name]
+	maxSeries := m.maxSeries
+	m.mu.RUnlock()
+
+	if !ok {
+		if err := validateCustomMetric(name, labels); err != nil {
+			return nil, 0, err
+		}
+
+		m.mu.Lock()
+		if f, ok = m.families[name]; !ok {
+			f = newCustomFamily(name, typ, labels, buckets)
+			m.families[name] = f
+		}
+		m.mu.Unlock()
+	}
+
+	if f.typ != typ {
+		return nil, 0, fmt.Errorf("custom metric %q is already registered as %s", name, f.typ)
+	}
+	if len(labels) != len(f.labelKeys) {
+		return nil, 0, fmt.Errorf("custom metric %q expects labels %v", name, f.labelKeys)
+	}
+	for _, k := range f.labelKeys {
+		if _, ok := labels[k]; !ok {
+			return nil, 0, fmt.Errorf("custom metric %q expects labels %v", name, f.labelKeys)
+		}
+	}
+
+	return f, maxSeries, nil
+}
+
+// newCustomFamily 创建指标定义
+func newCustomFamily(name string, typ MetricType, labels map[string]string, buckets []float64) *customFamily {
+	f := &customFamily{
+		name:      name,
+		typ:       typ,
+		labelKeys: make([]string, 0, len(labels)),
+		series:    make(map[string]*customSeries),
+	}
+	for k := range labels {
+		f.labelKeys = append(f.labelKeys, k)
+	}
+	sort.Strings(f.labelKeys)
+
+	if typ == MetricTypeHistogram {
+		if len(buckets) == 0 {
+			buckets = DefaultRequestDurationBuckets
+		}
+		f.buckets = append([]float64(nil), buckets...)
+		sort.Float64s(f.buckets)
+		f.buckets = slices.Compact(f.buckets)
+	}
+
+	return f
+}
+
+// validateCustomMetric 校验指标名与标签名
+func validateCustomMetric(name string, labels map[string]string) error {
+	if !customMetricNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid custom metric name %q", name)
+	}
+	if strings.HasPrefix(name, "pb_") || strings.HasPrefix(name, "go_") {
+		return fmt.Errorf("custom metric name %q uses a reserved prefix", name)
+	}
+	if len(labels) > maxCustomMetricLabels {
+		return fmt.Errorf("custom metric %q has too many labels (max %d)", name, maxCustomMetricLabels)
+	}
+	for k := range labels {
+		if !customMetricLabelRegex.MatchString(k) || strings.HasPrefix(k, "__") || k == "le" {
+			return fmt.Errorf("custom metric %q has invalid label name %q", name, k)
+		}
+	}
+	return nil
+}
+
+// sortedFamilies 返回按名称排序的指标定义
+func (m *CustomMetrics) sortedFamilies() []*customFamily {
+	m.mu.RLock()
+	families := make([]*customFamily, 0, len(m.families))
+	for _, f := range m.families {
+		families = append(families, f)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	return families
+}
+
+// sortedSeries 返回按序列键排序的序列
+func (f *customFamily) sortedSeries() []*customSeries {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*customSeries, len(keys))
+	for i, key := range keys {
+		result[i] = f.series[key]
+	}
+	f.mu.RUnlock()
+	return result
+}
+
+// Collect 实现 Collector 接口（输出累计值）
+func (m *CustomMetrics) Collect() []MetricFamily {
+	families := m.sortedFamilies()
+	result := make([]MetricFamily, 0, len(families))
+
+	for _, f := range families {
+		family := MetricFamily{Name: f.name, Type: f.typ}
+
+		for _, s := range f.sortedSeries() {
+			labels := make([]Label, len(f.labelKeys))
+			for i, k := range f.labelKeys {
+				labels[i] = Label{Name: k, Value: s.labels[k]}
+			}
+
+			sample := Sample{Labels: labels}
+			if f.typ == MetricTypeHistogram {
+				h := &HistogramValue{
+					Buckets: f.buckets,
+					Counts:  make([]uint64, len(f.buckets)),
+					Sum:     math.Float64frombits(s.sum.Load()),
+				}
+				var cumulative uint64
+				for i := range f.buckets {
+					cumulative += s.counts[i].Load()
+					h.Counts[i] = cumulative
+				}
+				h.Count = cumulative + s.counts[len(f.buckets)].Load()
+				sample.Histogram = h
+			} else {
+				sample.Value = math.Float64frombits(s.value.Load())
+			}
+			family.Samples = append(family.Samples, sample)
+		}
+
+		result = append(result, family)
+	}
+
+	return result
+}
+
+// flush 返回自上次调用以来的各序列数据，按名称排序
+// counter、histogram 为周期内增量（无变化时不返回），gauge 为当前值
+func (m *CustomMetrics) flush(timestamp types.DateTime) []*CustomMetric {
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	var result []*CustomMetric
+	for _, f := range m.sortedFamilies() {
+		for _, s := range f.sortedSeries() {
+			row := &CustomMetric{
+				Timestamp: timestamp,
+				Name:      f.name,
+				Type:      f.typ,
+				Labels:    s.labels,
+			}
+
+			switch f.typ {
+			case MetricTypeCounter:
+				value := math.Float64frombits(s.value.Load())
+				if value == s.lastValue {
+					continue
+				}
+				row.Value = value - s.lastValue
+				s.lastValue = value
+			case MetricTypeGauge:
+				row.Value = math.Float64frombits(s.value.Load())
+			case MetricTypeHistogram:
+				// 先读取计数再读取总和，并发观测最多延迟到下个周期计入
+				buckets := make(types.JSONMap[int64])
+				for i := range s.counts {
+					n := s.counts[i].Load()
+					delta := n - s.lastCounts[i]
+					s.lastCounts[i] = n
+					if delta == 0 {
+						continue
+					}
+					row.Count += int64(delta)
+					if i < len(f.buckets) {
+						buckets[formatFloat(f.buckets[i])] = int64(delta)
+					} else {
+						buckets[infBucketKey] = int64(delta)
+					}
+				}
+				sum := math.Float64frombits(s.sum.Load())
+				if row.Count == 0 {
+					continue
+				}
+				row.Value = sum - s.lastSum
+				s.lastSum = sum
+				row.Buckets = buckets
+			}
+
+			row.Id = security.RandomString(15)
+			result = append(result, row)
+		}
+	}
+
+	return result
+}
+
+// addFloat 原子地为 math.Float64bits 编码的值增加 delta
+func addFloat(v *atomic.Uint64, delta float64) {
+	for {
+		old := v.Load()
+		if v.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestCustomMetricsValidation(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	if _, err := metrics.Counter(app, "orders_placed", map[string]string{"provider": "stripe"}); err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name string
+		fn   func() error
+	}{
+		{"invalid name", func() error {
+			_, err := metrics.Counter(app, "orders-placed", nil)
+			return err
+		}},
+		{"reserved prefix", func() error {
+			_, err := metrics.Gauge(app, "pb_queue_size", nil)
+			return err
+		}},
+		{"invalid label name", func() error {
+			_, err := metrics.Counter(app, "refunds", map[string]string{"__name": "x"})
+			return err
+		}},
+		{"type conflict", func() error {
+			_, err := metrics.Gauge(app, "orders_placed", map[string]string{"provider": "stripe"})
+			return err
+		}},
+		{"label names mismatch", func() error {
+			_, err := metrics.Counter(app, "orders_placed", map[string]string{"region": "eu"})
+			return err
+		}},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			if err := s.fn(); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestCustomMetricsMaxSeries(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	config := metrics.DefaultConfig()
+	config.CustomMetricsMaxSeries = 2
+	metrics.MustRegister(app, config)
+
+	for _, provider := range []string{"stripe", "paypal", "adyen", "klarna"} {
+		counter, err := metrics.Counter(app, "orders_placed", map[string]string{"provider": provider})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counter.Inc()
+	}
+
+	histogram, err := metrics.Histogram(app, "payment_latency_seconds", nil, 0.5, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	histogram.Observe(0.2)
+	histogram.Observe(3)
+
+	var buf bytes.Buffer
+	if err := metrics.GetRegistry(app).WriteText(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		`orders_placed_total{provider="paypal"} 1`,
+		`orders_placed_total{provider="stripe"} 1`,
+		`orders_placed_total{provider="__overflow__"} 2`,
+		`payment_latency_seconds_bucket{le="0.1"} 0`,
+		`payment_latency_seconds_bucket{le="0.5"} 1`,
+		`payment_latency_seconds_bucket{le="+Inf"} 2`,
+		`payment_latency_seconds_sum 3.2`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "adyen") || strings.Contains(output, "klarna") {
+		t.Errorf("expected overflow label values to be merged:\n%s", output)
+	}
+}
+
+func TestCustomMetricsCollectedPerInterval(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		repo := metrics.NewMetricsRepository(app)
+		config := metrics.DefaultConfig()
+		config.CollectionInterval = time.Hour // 只依赖启动时的立即采集
+		collector := metrics.NewMetricsCollector(app, repo, config)
+
+		collect := func() {
+			collector.Start()
+			time.Sleep(200 * time.Millisecond)
+			collector.Stop()
+		}
+
+		counter, _ := metrics.Counter(app, "orders_placed", map[string]string{"provider": "stripe"})
+		gauge, _ := metrics.Gauge(app, "queue_size", nil)
+		histogram, _ := metrics.Histogram(app, "payment_latency_seconds", nil, 0.1, 1)
+
+		counter.Add(3)
+		gauge.Set(5)
+		histogram.Observe(0.05)
+		histogram.Observe(0.5)
+		collect()
+
+		// 第二个周期 histogram 无新观测，只写入 counter 增量与 gauge 当前值
+		counter.Inc()
+		gauge.Dec()
+		collect()
+
+		since := time.Now().Add(-time.Hour)
+
+		orders, _, err := repo.GetCustomMetrics("orders_placed", since, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orders) != 2 || orders[0].Value != 3 || orders[1].Value != 1 {
+			t.Fatalf("unexpected counter rows %+v", orders)
+		}
+		if orders[0].Type != metrics.MetricTypeCounter || orders[0].Labels["provider"] != "stripe" {
+			t.Errorf("unexpected counter row %+v", orders[0])
+		}
+
+		queue, _, err := repo.GetCustomMetrics("queue_size", since, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(queue) != 2 || queue[0].Value != 5 || queue[1].Value != 4 {
+			t.Fatalf("unexpected gauge rows %+v", queue)
+		}
+
+		latency, _, err := repo.GetCustomMetrics("payment_latency_seconds", since, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(latency) != 1 || latency[0].Count != 2 || latency[0].Value != 0.55 {
+			t.Fatalf("unexpected histogram rows %+v", latency)
+		}
+		if latency[0].Buckets["0.1"] != 1 || latency[0].Buckets["1"] != 1 {
+			t.Errorf("unexpected histogram buckets %v", latency[0].Buckets)
+		}
+
+		names, err := repo.GetCustomMetricNames(since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 3 || names[0].Name != "orders_placed" || names[0].Points != 2 || names[1].Type != metrics.MetricTypeHistogram {
+			t.Fatalf("unexpected custom metric names %+v", names)
+		}
+	})
+}
+
+func TestCustomMetricsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testAppWithMetrics := func(tb testing.TB) *tests.TestApp {
+		config := metrics.DefaultConfig()
+		config.CollectionInterval = time.Hour
+		app := newTestAppWithMetrics(tb, config)
+
+		old, _ := types.ParseDateTime(time.Now().Add(-48 * time.Hour))
+		now := types.NowDateTime()
+
+		err := metrics.NewMetricsRepository(app).InsertCustomMetrics([]*metrics.CustomMetric{
+			{Timestamp: old, Name: "orders_placed", Type: metrics.MetricTypeCounter, Labels: types.JSONMap[string]{"provider": "old"}, Value: 9},
+			{Timestamp: now, Name: "orders_placed", Type: metrics.MetricTypeCounter, Labels: types.JSONMap[string]{"provider": "stripe"}, Value: 3},
+			{Timestamp: now, Name: "queue_size", Type: metrics.MetricTypeGauge, Value: 5},
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		return app
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unauthorized",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/custom",
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "regular user",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/custom/orders_placed",
+			Headers:         map[string]string{"Authorization": testUserToken},
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:           "list metric names",
+			Method:         http.MethodGet,
+			URL:            "/api/system/metrics/custom",
+			Headers:        map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"name":"orders_placed","type":"counter","points":1`,
+				`"name":"queue_size","type":"gauge","points":1`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testAppWithMetrics,
+		},
+		{
+			Name:           "metric series within range",
+			Method:         http.MethodGet,
+			URL:            "/api/system/metrics/custom/orders_placed?hours=24",
+			Headers:        map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"name":"orders_placed"`,
+				`"labels":{"provider":"stripe"}`,
+				`"totalItems":1`,
+			},
+			NotExpectedContent: []string{`"provider":"old"`},
+			ExpectedEvents:     map[string]int{"*": 0},
+			TestAppFactory:     testAppWithMetrics,
+		},
+		{
+			Name:           "metric series with extended range",
+			Method:         http.MethodGet,
+			URL:            "/api/system/metrics/custom/orders_placed?hours=72",
+			Headers:        map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"provider":"old"`,
+				`"totalItems":2`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testAppWithMetrics,
+		},
+		{
+			Name:            "invalid metric name",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/custom/orders-placed",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
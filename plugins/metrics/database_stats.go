@@ -1,14 +1,19 @@
 package metrics
 
 import (
+	"encoding/binary"
+	"io"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cast"
 )
 
 // DatabaseStatsResponse 数据库统计响应结构
@@ -17,8 +22,45 @@ type DatabaseStatsResponse struct {
 	Stats map[string]any `json:"stats"` // 统计数据
 }
 
+// SQLitePageCacheStats SQLite 主数据库的页缓存容量与覆盖率。
+// 缓存命中/未命中计数（sqlite3_db_status）只能通过 C API 按连接读取，
+// database/sql 驱动没有暴露，因此这里只提供可以通过 PRAGMA 获取的数据。
+type SQLitePageCacheStats struct {
+	PageSize      int64 `json:"page_size"`
+	CachePages    int64 `json:"cache_pages"` // 每个连接的页缓存容量（页）
+	CacheSpill    int64 `json:"cache_spill"` // 事务中超过该页数时提前写出脏页
+	MmapSize      int64 `json:"mmap_size"`
+	DatabasePages int64 `json:"database_pages"`
+	FreelistPages int64 `json:"freelist_pages"`
+
+	// Coverage 单个连接的页缓存可容纳的数据库页比例（0~1），低于 1 时大查询会出现缓存淘汰
+	Coverage float64 `json:"coverage"`
+}
+
+// SQLiteCheckpointStats SQLite 数据库的 WAL checkpoint 状态。
+// 数据读取自 WAL 文件头与 -shm 索引文件，不会触发 checkpoint。
+type SQLiteCheckpointStats struct {
+	Database       string `json:"database"` // data 或 auxiliary
+	WalSize        int64  `json:"wal_size"`
+	AutoCheckpoint int64  `json:"auto_checkpoint"` // WAL 达到该页数时自动 checkpoint
+
+	// CheckpointSequence WAL 重置次数，每次完整 checkpoint 后 WAL 从头写入时加 1
+	CheckpointSequence int64 `json:"checkpoint_sequence"`
+
+	WalFrames        int64 `json:"wal_frames"`        // WAL 中的有效帧数
+	BackfilledFrames int64 `json:"backfilled_frames"` // 已写回数据库文件的帧数
+	PendingFrames    int64 `json:"pending_frames"`    // 尚未 checkpoint 的帧数
+}
+
+// SlowQueryLogStats 慢查询日志状态与时间范围内的慢查询
+type SlowQueryLogStats struct {
+	Enabled     bool         `json:"enabled"`
+	ThresholdMs float64      `json:"threshold_ms"`
+	Items       []*SlowQuery `json:"items"`
+}
+
 // databaseStats 处理 GET /api/system/metrics/database 请求
-func databaseStats(e *core.RequestEvent) error {
+func (p *metricsPlugin) databaseStats(e *core.RequestEvent) error {
 	// 检测数据库类型
 	dbType := detectDatabaseType()
 
@@ -36,6 +78,14 @@ func databaseStats(e *core.RequestEvent) error {
 		return apis.NewBadRequestError("Failed to collect database statistics", err)
 	}
 
+	if dbType == "sqlite" && p.repository != nil {
+		slowQueries, err := p.slowQueryLogStats(e)
+		if err != nil {
+			return apis.NewBadRequestError("Failed to query slow queries", err)
+		}
+		stats["slow_query_log"] = slowQueries
+	}
+
 	response := DatabaseStatsResponse{
 		Type:  dbType,
 		Stats: stats,
@@ -111,9 +161,114 @@ func collectSQLiteStats(app core.App) (map[string]any, error) {
 		stats["journal_mode"] = journalMode
 	}
 
+	// 7. 页缓存
+	stats["page_cache"] = collectSQLitePageCacheStats(db, pageSize, pageCount, cacheSize)
+
+	// 8. WAL checkpoint
+	stats["checkpoints"] = []*SQLiteCheckpointStats{
+		collectSQLiteCheckpointStats(db, "data", filepath.Join(app.DataDir(), "data.db")),
+		collectSQLiteCheckpointStats(app.AuxDB(), "auxiliary", filepath.Join(app.DataDir(), "auxiliary.db")),
+	}
+
 	return stats, nil
 }
 
+// slowQueryLogStats 返回慢查询日志状态与最近 hours 小时（默认 24，最多 168）内最新的 limit 条（默认 20，最多 100）慢查询
+func (p *metricsPlugin) slowQueryLogStats(e *core.RequestEvent) (*SlowQueryLogStats, error) {
+	hours := cast.ToInt(e.Request.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 168 {
+		hours = 168
+	}
+
+	limit := cast.ToInt(e.Request.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	items, err := p.repository.GetSlowQueries(time.Now().Add(-time.Duration(hours)*time.Hour), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SlowQueryLogStats{
+		Enabled:     !p.config.DisableSlowQueryLog,
+		ThresholdMs: float64(p.config.SlowQueryThreshold.Microseconds()) / 1000,
+		Items:       items,
+	}, nil
+}
+
+// collectSQLitePageCacheStats 收集主数据库的页缓存配置，cacheSize 为 PRAGMA cache_size 的值（负数表示 KiB）
+func collectSQLitePageCacheStats(db dbx.Builder, pageSize, pageCount, cacheSize int64) *SQLitePageCacheStats {
+	result := &SQLitePageCacheStats{
+		PageSize:      pageSize,
+		CachePages:    cacheSize,
+		DatabasePages: pageCount,
+	}
+	if cacheSize < 0 && pageSize > 0 {
+		result.CachePages = -cacheSize * 1024 / pageSize
+	}
+
+	_ = db.NewQuery("PRAGMA cache_spill").Row(&result.CacheSpill)
+	_ = db.NewQuery("PRAGMA mmap_size").Row(&result.MmapSize)
+	_ = db.NewQuery("PRAGMA freelist_count").Row(&result.FreelistPages)
+
+	if pageCount > 0 {
+		result.Coverage = math.Min(1, math.Round(float64(result.CachePages)/float64(pageCount)*1000)/1000)
+	}
+
+	return result
+}
+
+// collectSQLiteCheckpointStats 读取数据库 WAL 文件头与 -shm 索引文件中的 checkpoint 状态。
+// WAL 文件头的第 12~15 字节为大端序的 checkpoint 序号；
+// -shm 文件中 WAL 索引头的 mxFrame（偏移 16）与 checkpoint 信息的 nBackfill（偏移 96）为本机字节序。
+func collectSQLiteCheckpointStats(db dbx.Builder, name, dbPath string) *SQLiteCheckpointStats {
+	result := &SQLiteCheckpointStats{Database: name}
+
+	_ = db.NewQuery("PRAGMA wal_autocheckpoint").Row(&result.AutoCheckpoint)
+
+	if info, err := os.Stat(dbPath + "-wal"); err == nil {
+		result.WalSize = info.Size()
+	}
+
+	if header, err := readFileHeader(dbPath+"-wal", 32); err == nil {
+		magic := binary.BigEndian.Uint32(header[0:4])
+		if magic == 0x377f0682 || magic == 0x377f0683 {
+			result.CheckpointSequence = int64(binary.BigEndian.Uint32(header[12:16]))
+		}
+	}
+
+	if shm, err := readFileHeader(dbPath+"-shm", 100); err == nil && shm[12] == 1 {
+		result.WalFrames = int64(binary.NativeEndian.Uint32(shm[16:20]))
+		result.BackfilledFrames = int64(binary.NativeEndian.Uint32(shm[96:100]))
+		result.PendingFrames = max(0, result.WalFrames-result.BackfilledFrames)
+	}
+
+	return result
+}
+
+// readFileHeader 读取文件开头的 n 个字节
+func readFileHeader(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
 // collectPostgreSQLStats 收集 PostgreSQL 统计信息
 func collectPostgreSQLStats(app core.App) (map[string]any, error) {
 	stats := make(map[string]any)
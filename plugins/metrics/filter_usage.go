@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ganigeorgiev/fexpr"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+)
+
+// maxIndexSuggestions 每个表最多返回的索引建议数
+const maxIndexSuggestions = 3
+
+// FieldUsage 集合字段在列表请求 filter/sort 中出现的次数
+type FieldUsage struct {
+	Field  string `json:"field"`
+	Filter int64  `json:"filter"`
+	Sort   int64  `json:"sort"`
+}
+
+// IndexSuggestion 基于实际执行的 filter/sort 建议创建的索引
+type IndexSuggestion struct {
+	Field  string `json:"field"`
+	Filter int64  `json:"filter"`
+	Sort   int64  `json:"sort"`
+
+	// Index 集合索引格式的 CREATE INDEX 语句，可直接添加到集合的 indexes 中
+	Index string `json:"index"`
+}
+
+// FilterUsage 统计记录列表请求中各集合字段被用于 filter/sort 的次数
+// 仅统计进程启动以来的请求，字段必须属于集合，因此基数受集合结构限制
+type FilterUsage struct {
+	mu     sync.RWMutex
+	fields map[string]map[string]*FieldUsage // collection name -> field name -> usage
+}
+
+// NewFilterUsage 创建空的字段使用统计
+func NewFilterUsage() *FilterUsage {
+	return &FilterUsage{fields: make(map[string]map[string]*FieldUsage)}
+}
+
+// Record 记录一次列表请求的 filter 与 sort，忽略无法解析的表达式和不属于集合的字段
+func (u *FilterUsage) Record(collection *core.Collection, filter, sortExpr string) {
+	if collection == nil || (filter == "" && sortExpr == "") {
+		return
+	}
+
+	filterFields := map[string]struct{}{}
+	if filter != "" {
+		if groups, err := fexpr.Parse(filter); err == nil {
+			collectFilterFields(groups, filterFields)
+		}
+	}
+
+	sortFields := map[string]struct{}{}
+	for _, part := range strings.Split(sortExpr, ",") {
+		part = strings.TrimLeft(strings.TrimSpace(part), "+-")
+		if part != "" {
+			sortFields[localFieldName(part)] = struct{}{}
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	record := func(name string, isSort bool) {
+		if name == "" || collection.Fields.GetByName(name) == nil {
+			return
+		}
+		fields, ok := u.fields[collection.Name]
+		if !ok {
+			fields = make(map[string]*FieldUsage)
+			u.fields[collection.Name] = fields
+		}
+		usage, ok := fields[name]
+		if !ok {
+			usage = &FieldUsage{Field: name}
+			fields[name] = usage
+		}
+		if isSort {
+			usage.Sort++
+		} else {
+			usage.Filter++
+		}
+	}
+
+	for name := range filterFields {
+		record(name, false)
+	}
+	for name := range sortFields {
+		record(name, true)
+	}
+}
+
+// Fields 返回集合的字段使用统计，按 filter+sort 次数降序
+func (u *FilterUsage) Fields(collectionName string) []FieldUsage {
+	u.mu.RLock()
+	result := make([]FieldUsage, 0, len(u.fields[collectionName]))
+	for _, usage := range u.fields[collectionName] {
+		result = append(result, *usage)
+	}
+	u.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		ti, tj := result[i].Filter+result[i].Sort, result[j].Filter+result[j].Sort
+		if ti != tj {
+			return ti > tj
+		}
+		return result[i].Field < result[j].Field
+	})
+	return result
+}
+
+// SuggestIndexes 返回集合中常用于 filter/sort、但不是任何已有索引首列的字段（最多 maxIndexSuggestions 个）
+func (u *FilterUsage) SuggestIndexes(collection *core.Collection) []*IndexSuggestion {
+	indexed := map[string]struct{}{core.FieldNameId: {}}
+	for _, raw := range collection.Indexes {
+		idx := dbutils.ParseIndex(raw)
+		if len(idx.Columns) > 0 {
+			indexed[strings.ToLower(idx.Columns[0].Name)] = struct{}{}
+		}
+	}
+
+	var result []*IndexSuggestion
+	for _, usage := range u.Fields(collection.Name) {
+		if _, ok := indexed[strings.ToLower(usage.Field)]; ok {
+			continue
+		}
+
+		result = append(result, &IndexSuggestion{
+			Field:  usage.Field,
+			Filter: usage.Filter,
+			Sort:   usage.Sort,
+			Index: dbutils.Index{
+				IndexName: "idx_" + collection.Name + "_" + usage.Field,
+				TableName: collection.Name,
+				Columns:   []dbutils.IndexColumn{{Name: usage.Field}},
+			}.Build(),
+		})
+		if len(result) >= maxIndexSuggestions {
+			break
+		}
+	}
+
+	return result
+}
+
+// collectFilterFields 收集 filter 表达式中引用的本集合字段名（忽略 @request 等宏）
+func collectFilterFields(groups []fexpr.ExprGroup, fields map[string]struct{}) {
+	for _, group := range groups {
+		switch item := group.Item.(type) {
+		case fexpr.Expr:
+			for _, token := range []fexpr.Token{item.Left, item.Right} {
+				if token.Type == fexpr.TokenIdentifier && !strings.HasPrefix(token.Literal, "@") {
+					fields[localFieldName(token.Literal)] = struct{}{}
+				}
+			}
+		case []fexpr.ExprGroup:
+			collectFilterFields(item, fields)
+		}
+	}
+}
+
+// localFieldName 返回标识符对应的本集合字段名（去掉关联路径与 :lower 等修饰符）
+func localFieldName(identifier string) string {
+	if i := strings.IndexAny(identifier, ".:"); i >= 0 {
+		identifier = identifier[:i]
+	}
+	return identifier
+}
@@ -0,0 +1,149 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+func TestFilterUsage(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	collection, err := app.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := metrics.NewFilterUsage()
+	usage.Record(collection, "text ~ 'a' && (number > 1 || rel_one.title = 'x') && @request.auth.id != ''", "-number,created,@random")
+	usage.Record(collection, "text:lower = 'b' && missing = 1", "")
+	usage.Record(collection, "invalid ((", "bool")
+	usage.Record(nil, "text = 'c'", "")
+
+	fields := usage.Fields("demo1")
+	expected := []metrics.FieldUsage{
+		{Field: "number", Filter: 1, Sort: 1},
+		{Field: "text", Filter: 2},
+		{Field: "bool", Sort: 1},
+		{Field: "created", Sort: 1},
+		{Field: "rel_one", Filter: 1},
+	}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %+v", len(expected), fields)
+	}
+	for i, f := range expected {
+		if fields[i] != f {
+			t.Errorf("[%d] expected %+v, got %+v", i, f, fields[i])
+		}
+	}
+
+	// created 已有索引，其余按使用次数取前 3 个
+	suggestions := usage.SuggestIndexes(collection)
+	if len(suggestions) != 3 {
+		t.Fatalf("expected 3 suggestions, got %d", len(suggestions))
+	}
+	for i, field := range []string{"number", "text", "bool"} {
+		if suggestions[i].Field != field {
+			t.Errorf("[%d] expected suggestion for %q, got %q", i, field, suggestions[i].Field)
+		}
+	}
+	if suggestions[0].Index != "CREATE INDEX `idx_demo1_number` ON `demo1` (`number`)" {
+		t.Errorf("unexpected index %q", suggestions[0].Index)
+	}
+}
+
+func TestFilterUsageNotRecordedOnSQLite(t *testing.T) {
+	t.Parallel()
+
+	query := url.Values{}
+	query.Set("filter", "text = '__none__' && number > 0")
+	query.Set("sort", "-bool")
+
+	scenario := tests.ApiScenario{
+		Name:            "list request filter and sort are not recorded on SQLite",
+		Method:          http.MethodGet,
+		URL:             "/api/collections/demo1/records?" + query.Encode(),
+		Headers:         map[string]string{"Authorization": testSuperuserToken},
+		ExpectedStatus:  200,
+		ExpectedContent: []string{`"totalItems":0`},
+		ExpectedEvents:  map[string]int{"*": 0, "OnRecordsListRequest": 1},
+		TestAppFactory: func(tb testing.TB) *tests.TestApp {
+			return newTestAppWithMetrics(tb, metrics.Config{})
+		},
+		AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+			usage := metrics.GetFilterUsage(app)
+			if usage == nil {
+				tb.Fatal("expected filter usage to be available")
+			}
+
+			if fields := usage.Fields("demo1"); len(fields) != 0 {
+				tb.Fatalf("expected no recorded fields on SQLite, got %+v", fields)
+			}
+		},
+	}
+
+	scenario.Test(t)
+}
+
+func TestDatabaseInsightsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	testAppWithMetrics := func(tb testing.TB) *tests.TestApp {
+		app, err := tests.NewTestApp()
+		if err != nil {
+			tb.Fatal(err)
+		}
+		metrics.MustRegister(app, metrics.Config{})
+		return app
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unauthorized",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/database/insights",
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "regular user",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/database/insights",
+			Headers:         map[string]string{"Authorization": testUserToken},
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "insights on SQLite",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/database/insights",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"message":"Query insights are only available for PostgreSQL."`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+		{
+			Name:            "reset statements on SQLite",
+			Method:          http.MethodPost,
+			URL:             "/api/system/metrics/database/statements/reset",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"message":"Query insights are only available for PostgreSQL."`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -18,26 +18,7 @@ func (p *metricsPlugin) middlewareFunc() func(*core.RequestEvent) error {
 		start := time.Now()
 		err := e.Next()
 
-		// 记录延迟
-		latencyMs := float64(time.Since(start).Microseconds()) / 1000.0
-		p.collector.RecordLatency(latencyMs)
-
-		// 记录 5xx 错误
-		// 策略：同时检查 error 返回值和 response status code
-		statusCode := getResponseStatus(e.Response)
-
-		// 1. 优先通过 error 判断（ApiError 包含精确的 status）
-		if err != nil {
-			if apiErr, ok := err.(*router.ApiError); ok && apiErr.Status >= 500 {
-				p.collector.RecordError(apiErr.Status)
-				return err
-			}
-		}
-
-		// 2. 通过 response status 判断（捕获直接写入 5xx 但没返回 error 的情况）
-		if statusCode >= 500 && statusCode < 600 {
-			p.collector.RecordError(statusCode)
-		}
+		recordRequest(p.collector, e, start, err)
 
 		return err
 	}
@@ -55,28 +36,40 @@ func Middleware(app core.App) func(*core.RequestEvent) error {
 		start := time.Now()
 		err := e.Next()
 
-		// 记录延迟
-		latencyMs := float64(time.Since(start).Microseconds()) / 1000.0
-		collector.RecordLatency(latencyMs)
+		recordRequest(collector, e, start, err)
 
-		// 记录 5xx 错误
-		statusCode := getResponseStatus(e.Response)
+		return err
+	}
+}
 
-		// 1. 优先通过 error 判断
-		if err != nil {
-			if apiErr, ok := err.(*router.ApiError); ok && apiErr.Status >= 500 {
-				collector.RecordError(apiErr.Status)
-				return err
-			}
-		}
+// recordRequest 记录请求延迟、5xx 错误以及按路由模式的耗时直方图
+func recordRequest(collector *MetricsCollector, e *core.RequestEvent, start time.Time, err error) {
+	duration := time.Since(start)
 
-		// 2. 通过 response status 判断
-		if statusCode >= 500 && statusCode < 600 {
-			collector.RecordError(statusCode)
+	// 记录延迟
+	latencyMs := float64(duration.Microseconds()) / 1000.0
+	collector.RecordLatency(latencyMs)
+
+	// 记录 5xx 错误
+	// 策略：同时检查 error 返回值和 response status code
+	statusCode := getResponseStatus(e.Response)
+
+	// 1. 优先通过 error 判断（ApiError 包含精确的 status，错误响应在中间件之后才写入）
+	if err != nil {
+		if apiErr := router.ToApiError(err); statusCode == 0 || apiErr.Status >= 500 {
+			statusCode = apiErr.Status
 		}
+	}
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
 
-		return err
+	// 2. 通过 response status 判断（同时捕获直接写入 5xx 但没返回 error 的情况）
+	if statusCode >= 500 && statusCode < 600 {
+		collector.RecordError(statusCode)
 	}
+
+	collector.RecordRequest(e.Request.Method, routePattern(e.Request), statusCode, duration)
 }
 
 // getResponseStatus 从 http.ResponseWriter 获取响应状态码
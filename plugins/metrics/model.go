@@ -5,8 +5,13 @@ import (
 	"github.com/pocketbase/pocketbase/tools/types"
 )
 
-// 编译时检查：确保 SystemMetrics 实现 Model 接口
-var _ core.Model = (*SystemMetrics)(nil)
+// 编译时检查：确保 SystemMetrics、RouteMetrics、MetricsAlert、CustomMetric 实现 Model 接口
+var (
+	_ core.Model = (*SystemMetrics)(nil)
+	_ core.Model = (*RouteMetrics)(nil)
+	_ core.Model = (*MetricsAlert)(nil)
+	_ core.Model = (*CustomMetric)(nil)
+)
 
 // SystemMetrics 系统监控指标数据模型
 // 存储在 auxiliary.db 数据库中（与 _logs 表同库）
@@ -28,8 +33,181 @@ func (m *SystemMetrics) TableName() string {
 	return SystemMetricsTableName
 }
 
+// systemMetricsFields 可用于告警与汇总的数值字段（键为 JSON 字段名）
+var systemMetricsFields = map[string]func(m *SystemMetrics) float64{
+	"cpu_usage_percent":  func(m *SystemMetrics) float64 { return m.CpuUsagePercent },
+	"memory_alloc_mb":    func(m *SystemMetrics) float64 { return m.MemoryAllocMB },
+	"goroutines_count":   func(m *SystemMetrics) float64 { return float64(m.GoroutinesCount) },
+	"sqlite_wal_size_mb": func(m *SystemMetrics) float64 { return m.SqliteWalSizeMB },
+	"sqlite_open_conns":  func(m *SystemMetrics) float64 { return float64(m.SqliteOpenConns) },
+	"p95_latency_ms":     func(m *SystemMetrics) float64 { return m.P95LatencyMs },
+	"http_5xx_count":     func(m *SystemMetrics) float64 { return float64(m.Http5xxCount) },
+}
+
 // SystemMetricsResponse API 响应结构
 type SystemMetricsResponse struct {
 	Items      []*SystemMetrics `json:"items"`
 	TotalItems int              `json:"totalItems"`
+
+	// Resolution 返回数据的分辨率；非 raw 时 Items 为各字段的平均值
+	Resolution Resolution `json:"resolution"`
+
+	// Rollups 非 raw 分辨率时的完整降采样数据（包含 min/max/avg/p95）
+	Rollups []*MetricsRollup `json:"rollups,omitempty"`
+}
+
+// RouteMetrics 单个采集周期内某个路由模式与方法的请求统计
+// 存储在 auxiliary.db 数据库中
+type RouteMetrics struct {
+	core.BaseModel
+
+	Timestamp    types.DateTime `db:"timestamp" json:"timestamp"`
+	Method       string         `db:"method" json:"method"`
+	Route        string         `db:"route" json:"route"`
+	RequestCount int            `db:"request_count" json:"request_count"`
+	ErrorCount   int            `db:"error_count" json:"error_count"`
+	AvgLatencyMs float64        `db:"avg_latency_ms" json:"avg_latency_ms"`
+	MaxLatencyMs float64        `db:"max_latency_ms" json:"max_latency_ms"`
+	P50LatencyMs float64        `db:"p50_latency_ms" json:"p50_latency_ms"`
+	P95LatencyMs float64        `db:"p95_latency_ms" json:"p95_latency_ms"`
+	P99LatencyMs float64        `db:"p99_latency_ms" json:"p99_latency_ms"`
+
+	// LatencyBuckets 非累计的耗时直方图，键为分桶上界（ms，最后一个为 "+Inf"）
+	LatencyBuckets types.JSONMap[int64] `db:"latency_buckets" json:"latency_buckets"`
+}
+
+// TableName 返回表名
+func (m *RouteMetrics) TableName() string {
+	return RouteMetricsTableName
+}
+
+// RouteSummary 时间范围内某个路由的汇总统计
+type RouteSummary struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	RequestCount int     `json:"request_count"`
+	ErrorCount   int     `json:"error_count"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P95LatencyMs float64 `json:"p95_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// RouteMetricsResponse /api/system/metrics/routes 响应结构
+type RouteMetricsResponse struct {
+	From       types.DateTime  `json:"from"`
+	To         types.DateTime  `json:"to"`
+	Slowest    []*RouteSummary `json:"slowest"`
+	MostErrors []*RouteSummary `json:"most_errors"`
+}
+
+// MetricsAlert 告警规则的当前状态，每条规则一行
+// 存储在 auxiliary.db 数据库中
+type MetricsAlert struct {
+	core.BaseModel
+
+	Rule        string         `db:"rule" json:"rule"`
+	Field       string         `db:"field" json:"field"`
+	Severity    string         `db:"severity" json:"severity"`
+	State       AlertState     `db:"state" json:"state"`
+	Value       float64        `db:"value" json:"value"`
+	Threshold   float64        `db:"threshold" json:"threshold"`
+	Message     string         `db:"message" json:"message"`
+	ActiveAt    types.DateTime `db:"active_at" json:"active_at"`
+	FiredAt     types.DateTime `db:"fired_at" json:"fired_at"`
+	ResolvedAt  types.DateTime `db:"resolved_at" json:"resolved_at"`
+	EvaluatedAt types.DateTime `db:"evaluated_at" json:"evaluated_at"`
+}
+
+// TableName 返回表名
+func (m *MetricsAlert) TableName() string {
+	return MetricsAlertsTableName
+}
+
+// MetricsAlertsResponse /api/system/metrics/alerts 响应结构
+type MetricsAlertsResponse struct {
+	Items []*MetricsAlert `json:"items"`
+}
+
+// CustomMetric 单个采集周期内某个自定义指标序列的数据
+// 存储在 auxiliary.db 数据库中
+type CustomMetric struct {
+	core.BaseModel
+
+	Timestamp types.DateTime        `db:"timestamp" json:"timestamp"`
+	Name      string                `db:"name" json:"name"`
+	Type      MetricType            `db:"type" json:"type"`
+	Labels    types.JSONMap[string] `db:"labels" json:"labels"`
+
+	// Value counter 为周期内增量，gauge 为采集时的当前值，histogram 为周期内观测值之和
+	Value float64 `db:"value" json:"value"`
+
+	// Count histogram 周期内的观测次数
+	Count int64 `db:"count" json:"count"`
+
+	// Buckets histogram 周期内非累计的分桶计数，键为分桶上界（最后一个为 "+Inf"）
+	Buckets types.JSONMap[int64] `db:"buckets" json:"buckets"`
+}
+
+// TableName 返回表名
+func (m *CustomMetric) TableName() string {
+	return CustomMetricsTableName
+}
+
+// CustomMetricInfo 时间范围内出现过的自定义指标
+type CustomMetricInfo struct {
+	Name     string         `db:"name" json:"name"`
+	Type     MetricType     `db:"type" json:"type"`
+	Points   int            `db:"points" json:"points"`
+	LastSeen types.DateTime `db:"last_seen" json:"last_seen"`
+}
+
+// CustomMetricsListResponse /api/system/metrics/custom 响应结构
+type CustomMetricsListResponse struct {
+	Items []*CustomMetricInfo `json:"items"`
+}
+
+// CustomMetricsResponse /api/system/metrics/custom/{name} 响应结构
+type CustomMetricsResponse struct {
+	Name       string          `json:"name"`
+	Items      []*CustomMetric `json:"items"`
+	TotalItems int             `json:"totalItems"`
+}
+
+// SlowQuery 耗时超过阈值的 SQLite 语句
+type SlowQuery struct {
+	core.BaseModel
+
+	Timestamp types.DateTime `db:"timestamp" json:"timestamp"`
+
+	// SQL 字面量（包括 dbx 内联的绑定参数）替换为 ? 后的语句
+	SQL string `db:"sql" json:"sql"`
+
+	// Params 语句中各字面量的类型与长度（如 text(12)、integer、blob(16)、null），不记录实际值
+	Params types.JSONArray[string] `db:"params" json:"params"`
+
+	DurationMs float64 `db:"duration_ms" json:"duration_ms"`
+
+	// Error 语句执行失败时的错误信息
+	Error string `db:"error" json:"error"`
+
+	// Plan EXPLAIN QUERY PLAN 的输出
+	Plan types.JSONArray[QueryPlanStep] `db:"plan" json:"plan"`
+
+	// PlanError 获取查询计划失败时的错误信息（如语句依赖事务中的临时表）
+	PlanError string `db:"plan_error" json:"plan_error"`
+}
+
+// TableName 返回表名
+func (m *SlowQuery) TableName() string {
+	return SlowQueriesTableName
+}
+
+// QueryPlanStep EXPLAIN QUERY PLAN 输出的一行，Parent 为 0 表示顶层步骤
+type QueryPlanStep struct {
+	Id     int    `db:"id" json:"id"`
+	Parent int    `db:"parent" json:"parent"`
+	Detail string `db:"detail" json:"detail"`
 }
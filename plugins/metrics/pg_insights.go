@@ -0,0 +1,290 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// pgSeqScanMinRows 顺序扫描统计中忽略的小表行数阈值
+const pgSeqScanMinRows = 1000
+
+// pgQueryMaxLength 返回的 SQL 文本最大长度
+const pgQueryMaxLength = 2000
+
+// PGStatement pg_stat_statements 中的一条归一化语句
+type PGStatement struct {
+	QueryId     int64   `db:"queryid" json:"queryid"`
+	Query       string  `db:"query" json:"query"`
+	Calls       int64   `db:"calls" json:"calls"`
+	TotalTimeMs float64 `db:"total_time_ms" json:"total_time_ms"`
+	MeanTimeMs  float64 `db:"mean_time_ms" json:"mean_time_ms"`
+	Rows        int64   `db:"rows" json:"rows"`
+}
+
+// PGStatementsInsights 按不同维度排序的语句 Top N
+type PGStatementsInsights struct {
+	// Available pg_stat_statements 扩展是否可用
+	Available   bool           `json:"available"`
+	ByTotalTime []*PGStatement `json:"by_total_time"`
+	ByCalls     []*PGStatement `json:"by_calls"`
+	ByMeanTime  []*PGStatement `json:"by_mean_time"`
+	ByRows      []*PGStatement `json:"by_rows"`
+}
+
+// PGTableBloat 表的死元组统计（基于 pg_stat_user_tables 的估算）
+type PGTableBloat struct {
+	Table          string         `db:"table_name" json:"table"`
+	LiveTuples     int64          `db:"live_tuples" json:"live_tuples"`
+	DeadTuples     int64          `db:"dead_tuples" json:"dead_tuples"`
+	DeadRatio      float64        `db:"dead_ratio" json:"dead_ratio"`
+	SizeBytes      int64          `db:"size_bytes" json:"size_bytes"`
+	LastAutovacuum types.DateTime `db:"last_autovacuum" json:"last_autovacuum"`
+}
+
+// PGSeqScanTable 顺序扫描多于索引扫描的表
+type PGSeqScanTable struct {
+	Table      string `db:"table_name" json:"table"`
+	SeqScan    int64  `db:"seq_scan" json:"seq_scan"`
+	SeqTupRead int64  `db:"seq_tup_read" json:"seq_tup_read"`
+	IdxScan    int64  `db:"idx_scan" json:"idx_scan"`
+	LiveTuples int64  `db:"live_tuples" json:"live_tuples"`
+
+	// SuggestedIndexes 基于该集合实际执行的 filter/sort 建议的索引
+	SuggestedIndexes []*IndexSuggestion `db:"-" json:"suggested_indexes"`
+}
+
+// PGIndexUsage 集合表上单个索引的使用统计
+type PGIndexUsage struct {
+	Table        string `db:"table_name" json:"table"`
+	Index        string `db:"index_name" json:"index"`
+	Scans        int64  `db:"scans" json:"scans"`
+	TuplesRead   int64  `db:"tuples_read" json:"tuples_read"`
+	TuplesFetch  int64  `db:"tuples_fetched" json:"tuples_fetched"`
+	SizeBytes    int64  `db:"size_bytes" json:"size_bytes"`
+	IsUnique     bool   `db:"is_unique" json:"is_unique"`
+	IsPrimaryKey bool   `db:"is_primary" json:"is_primary"`
+}
+
+// PGLockWait 正在等待锁的会话
+type PGLockWait struct {
+	Pid         int                  `db:"pid" json:"pid"`
+	BlockedBy   types.JSONArray[int] `db:"blocked_by" json:"blocked_by"`
+	WaitEvent   string               `db:"wait_event" json:"wait_event"`
+	State       string               `db:"state" json:"state"`
+	Query       string               `db:"query" json:"query"`
+	WaitSeconds float64              `db:"wait_seconds" json:"wait_seconds"`
+}
+
+// PGLongTransaction 运行时间超过阈值的事务
+type PGLongTransaction struct {
+	Pid             int     `db:"pid" json:"pid"`
+	State           string  `db:"state" json:"state"`
+	ApplicationName string  `db:"application_name" json:"application_name"`
+	Query           string  `db:"query" json:"query"`
+	DurationSeconds float64 `db:"duration_seconds" json:"duration_seconds"`
+}
+
+// PGInsights /api/system/metrics/database/insights 响应结构
+type PGInsights struct {
+	Statements       *PGStatementsInsights `json:"statements"`
+	Bloat            []*PGTableBloat       `json:"bloat"`
+	SeqScanTables    []*PGSeqScanTable     `json:"seq_scan_tables"`
+	IndexUsage       []*PGIndexUsage       `json:"index_usage"`
+	LockWaits        []*PGLockWait         `json:"lock_waits"`
+	LongTransactions []*PGLongTransaction  `json:"long_transactions"`
+
+	// Errors 查询失败的部分（其余部分仍会返回）
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// pgStatementsOrder 语句排序维度对应的列
+var pgStatementsOrder = []struct {
+	column string
+	target func(s *PGStatementsInsights) *[]*PGStatement
+}{
+	{"total_exec_time", func(s *PGStatementsInsights) *[]*PGStatement { return &s.ByTotalTime }},
+	{"calls", func(s *PGStatementsInsights) *[]*PGStatement { return &s.ByCalls }},
+	{"mean_exec_time", func(s *PGStatementsInsights) *[]*PGStatement { return &s.ByMeanTime }},
+	{"rows", func(s *PGStatementsInsights) *[]*PGStatement { return &s.ByRows }},
+}
+
+// collectPostgreSQLInsights 收集 PostgreSQL 查询性能分析数据
+// 各部分独立查询，失败时记录到 Errors 并继续
+func collectPostgreSQLInsights(app core.App, filters *FilterUsage, limit int, longTxThreshold time.Duration) (*PGInsights, error) {
+	db := app.DB()
+
+	collections, err := app.FindAllCollections(core.CollectionTypeBase, core.CollectionTypeAuth)
+	if err != nil {
+		return nil, err
+	}
+	collectionsByName := make(map[string]*core.Collection, len(collections))
+	collectionNames := make([]any, 0, len(collections))
+	for _, c := range collections {
+		collectionsByName[c.Name] = c
+		collectionNames = append(collectionNames, c.Name)
+	}
+
+	result := &PGInsights{
+		Statements:       &PGStatementsInsights{},
+		Bloat:            []*PGTableBloat{},
+		SeqScanTables:    []*PGSeqScanTable{},
+		IndexUsage:       []*PGIndexUsage{},
+		LockWaits:        []*PGLockWait{},
+		LongTransactions: []*PGLongTransaction{},
+	}
+	addError := func(section string, err error) {
+		if result.Errors == nil {
+			result.Errors = make(map[string]string)
+		}
+		result.Errors[section] = err.Error()
+	}
+
+	// 1. 归一化语句 Top N（需要 pg_stat_statements 扩展，仅统计当前数据库）
+	var hasStatements bool
+	err = db.NewQuery(`
+		SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')
+	`).Row(&hasStatements)
+	if err != nil {
+		addError("statements", err)
+	}
+	if hasStatements {
+		result.Statements.Available = true
+		for _, order := range pgStatementsOrder {
+			rows := []*PGStatement{}
+			err := db.NewQuery(fmt.Sprintf(`
+				SELECT
+					COALESCE(queryid, 0) AS queryid,
+					left(query, {:maxLength}) AS query,
+					calls,
+					round(total_exec_time::numeric, 2)::float8 AS total_time_ms,
+					round(mean_exec_time::numeric, 2)::float8 AS mean_time_ms,
+					rows
+				FROM pg_stat_statements
+				WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+				ORDER BY %s DESC
+				LIMIT {:limit}
+			`, order.column)).Bind(dbx.Params{"maxLength": pgQueryMaxLength, "limit": limit}).All(&rows)
+			if err != nil {
+				addError("statements", err)
+				break
+			}
+			*order.target(result.Statements) = rows
+		}
+	}
+
+	// 2. 死元组较多的表
+	err = db.NewQuery(`
+		SELECT
+			relname AS table_name,
+			n_live_tup AS live_tuples,
+			n_dead_tup AS dead_tuples,
+			round((n_dead_tup::numeric / GREATEST(n_live_tup + n_dead_tup, 1)), 4)::float8 AS dead_ratio,
+			pg_total_relation_size(relid) AS size_bytes,
+			last_autovacuum
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema() AND n_dead_tup > 0
+		ORDER BY n_dead_tup DESC
+		LIMIT {:limit}
+	`).Bind(dbx.Params{"limit": limit}).All(&result.Bloat)
+	if err != nil {
+		addError("bloat", err)
+	}
+
+	// 3. 顺序扫描多于索引扫描的表，并基于实际执行的 filter/sort 建议索引
+	err = db.NewQuery(`
+		SELECT
+			relname AS table_name,
+			seq_scan,
+			seq_tup_read,
+			COALESCE(idx_scan, 0) AS idx_scan,
+			n_live_tup AS live_tuples
+		FROM pg_stat_user_tables
+		WHERE schemaname = current_schema()
+			AND n_live_tup >= {:minRows}
+			AND seq_scan > COALESCE(idx_scan, 0)
+		ORDER BY seq_tup_read DESC
+		LIMIT {:limit}
+	`).Bind(dbx.Params{"minRows": pgSeqScanMinRows, "limit": limit}).All(&result.SeqScanTables)
+	if err != nil {
+		addError("seq_scan_tables", err)
+	}
+	for _, table := range result.SeqScanTables {
+		table.SuggestedIndexes = []*IndexSuggestion{}
+		if collection, ok := collectionsByName[table.Table]; ok && filters != nil {
+			table.SuggestedIndexes = filters.SuggestIndexes(collection)
+		}
+	}
+
+	// 4. 集合表的索引使用情况
+	if len(collectionNames) > 0 {
+		err = db.Select(
+			"s.relname AS table_name",
+			"s.indexrelname AS index_name",
+			"s.idx_scan AS scans",
+			"s.idx_tup_read AS tuples_read",
+			"s.idx_tup_fetch AS tuples_fetched",
+			"pg_relation_size(s.indexrelid) AS size_bytes",
+			"i.indisunique AS is_unique",
+			"i.indisprimary AS is_primary",
+		).
+			From("pg_stat_user_indexes s").
+			InnerJoin("pg_index i", dbx.NewExp("i.indexrelid = s.indexrelid")).
+			Where(dbx.NewExp("s.schemaname = current_schema()")).
+			AndWhere(dbx.In("s.relname", collectionNames...)).
+			OrderBy("s.relname ASC", "s.idx_scan ASC").
+			All(&result.IndexUsage)
+		if err != nil {
+			addError("index_usage", err)
+		}
+	}
+
+	// 5. 锁等待
+	err = db.NewQuery(`
+		SELECT
+			pid,
+			to_json(pg_blocking_pids(pid))::text AS blocked_by,
+			COALESCE(wait_event, '') AS wait_event,
+			COALESCE(state, '') AS state,
+			left(COALESCE(query, ''), {:maxLength}) AS query,
+			EXTRACT(EPOCH FROM (now() - COALESCE(state_change, query_start, now())))::float8 AS wait_seconds
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND wait_event_type = 'Lock'
+		ORDER BY wait_seconds DESC
+	`).Bind(dbx.Params{"maxLength": pgQueryMaxLength}).All(&result.LockWaits)
+	if err != nil {
+		addError("lock_waits", err)
+	}
+
+	// 6. 长事务（排除当前连接）
+	err = db.NewQuery(`
+		SELECT
+			pid,
+			COALESCE(state, '') AS state,
+			COALESCE(application_name, '') AS application_name,
+			left(COALESCE(query, ''), {:maxLength}) AS query,
+			EXTRACT(EPOCH FROM (now() - xact_start))::float8 AS duration_seconds
+		FROM pg_stat_activity
+		WHERE datname = current_database()
+			AND pid <> pg_backend_pid()
+			AND xact_start IS NOT NULL
+			AND xact_start < now() - make_interval(secs => {:threshold})
+		ORDER BY xact_start ASC
+	`).Bind(dbx.Params{"maxLength": pgQueryMaxLength, "threshold": longTxThreshold.Seconds()}).All(&result.LongTransactions)
+	if err != nil {
+		addError("long_transactions", err)
+	}
+
+	return result, nil
+}
+
+// resetPostgreSQLStatements 重置当前数据库的 pg_stat_statements 统计
+func resetPostgreSQLStatements(app core.App) error {
+	_, err := app.DB().NewQuery(`
+		SELECT pg_stat_statements_reset(0, (SELECT oid FROM pg_database WHERE datname = current_database()), 0)
+	`).Execute()
+	return err
+}
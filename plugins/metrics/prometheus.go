@@ -0,0 +1,327 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Prometheus 文本格式的 Content-Type
+const (
+	// OpenMetricsContentType OpenMetrics 1.0 文本格式
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+	// PrometheusTextContentType Prometheus 0.0.4 文本格式
+	PrometheusTextContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// MetricType 指标类型
+type MetricType string
+
+const (
+	MetricTypeCounter   MetricType = "counter"
+	MetricTypeGauge     MetricType = "gauge"
+	MetricTypeHistogram MetricType = "histogram"
+)
+
+// Label 指标标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Labels 按 name, value 成对创建标签列表
+func Labels(pairs ...string) []Label {
+	labels := make([]Label, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, Label{Name: pairs[i], Value: pairs[i+1]})
+	}
+	return labels
+}
+
+// HistogramValue 直方图样本
+type HistogramValue struct {
+	// Buckets 各分桶的上界（升序，不含 +Inf）
+	Buckets []float64
+	// Counts 各分桶的累计计数，与 Buckets 一一对应（+Inf 分桶即 Count）
+	Counts []uint64
+	Sum    float64
+	Count  uint64
+}
+
+// Sample 单个时间序列的当前值
+type Sample struct {
+	Labels []Label
+	// Value counter/gauge 的值
+	Value float64
+	// Histogram histogram 类型的值
+	Histogram *HistogramValue
+}
+
+// MetricFamily 同名指标的集合
+//
+// counter 的 Name 不含 _total 后缀，输出时自动追加。
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Collector 向 Registry 提供指标的组件，每次抓取时调用
+type Collector interface {
+	Collect() []MetricFamily
+}
+
+// CollectorFunc 函数形式的 Collector
+type CollectorFunc func() []MetricFamily
+
+// Collect 实现 Collector 接口
+func (f CollectorFunc) Collect() []MetricFamily {
+	return f()
+}
+
+// Registry 汇总各插件的指标并输出为 OpenMetrics / Prometheus 文本格式
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry 创建空的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// GetRegistry 获取指定 App 的指标注册表（不存在时创建）
+//
+// 其他插件在注册时调用 GetRegistry(app).Register(...) 提供指标，
+// 与 metrics 插件的注册顺序无关。
+func GetRegistry(app core.App) *Registry {
+	v := app.Store().GetOrSet(registryStoreKey, func() any {
+		return NewRegistry()
+	})
+	registry, _ := v.(*Registry)
+	return registry
+}
+
+// Register 以 name 注册 Collector，同名时替换
+func (r *Registry) Register(name string, collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[name] = collector
+}
+
+// Unregister 移除指定名称的 Collector
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.collectors, name)
+}
+
+// Names 返回已注册的 Collector 名称（升序）
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+// Gather 调用所有 Collector 并按名称排序返回指标
+// 不同 Collector 返回的同名同类型指标会合并，类型冲突时保留先出现的
+func (r *Registry) Gather() []MetricFamily {
+	r.mu.RLock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, name := range r.namesLocked() {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	index := make(map[string]int)
+	var families []MetricFamily
+	for _, collector := range collectors {
+		for _, family := range collector.Collect() {
+			family.Name = sanitizeMetricName(family.Name)
+			if family.Type == MetricTypeCounter {
+				family.Name = strings.TrimSuffix(family.Name, "_total")
+			}
+
+			if i, ok := index[family.Name]; ok {
+				if families[i].Type == family.Type {
+					families[i].Samples = append(families[i].Samples, family.Samples...)
+				}
+				continue
+			}
+			index[family.Name] = len(families)
+			families = append(families, family)
+		}
+	}
+
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// namesLocked 返回排序后的名称（调用方需持有锁）
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteText 将所有指标写入 w，openMetrics 为 false 时输出 Prometheus 0.0.4 格式
+func (r *Registry) WriteText(w io.Writer, openMetrics bool) error {
+	return WriteMetricFamilies(w, r.Gather(), openMetrics)
+}
+
+// WriteMetricFamilies 将指标编码为 OpenMetrics（openMetrics=true）或 Prometheus 0.0.4 文本格式
+func WriteMetricFamilies(w io.Writer, families []MetricFamily, openMetrics bool) error {
+	bw := bufio.NewWriter(w)
+
+	for _, family := range families {
+		// Prometheus 0.0.4 格式中 counter 的 HELP/TYPE 使用带 _total 的完整名称
+		headerName := family.Name
+		if family.Type == MetricTypeCounter && !openMetrics {
+			headerName += "_total"
+		}
+
+		if family.Help != "" {
+			bw.WriteString("# HELP " + headerName + " " + escapeHelp(family.Help) + "\n")
+		}
+		bw.WriteString("# TYPE " + headerName + " " + string(family.Type) + "\n")
+
+		for _, sample := range family.Samples {
+			switch family.Type {
+			case MetricTypeCounter:
+				writeSample(bw, family.Name+"_total", sample.Labels, nil, sample.Value)
+			case MetricTypeHistogram:
+				h := sample.Histogram
+				if h == nil {
+					continue
+				}
+				for i, bound := range h.Buckets {
+					var count uint64
+					if i < len(h.Counts) {
+						count = h.Counts[i]
+					}
+					writeSample(bw, family.Name+"_bucket", sample.Labels, &Label{Name: "le", Value: formatFloat(bound)}, float64(count))
+				}
+				writeSample(bw, family.Name+"_bucket", sample.Labels, &Label{Name: "le", Value: "+Inf"}, float64(h.Count))
+				writeSample(bw, family.Name+"_sum", sample.Labels, nil, h.Sum)
+				writeSample(bw, family.Name+"_count", sample.Labels, nil, float64(h.Count))
+			default:
+				writeSample(bw, family.Name, sample.Labels, nil, sample.Value)
+			}
+		}
+	}
+
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+
+	return bw.Flush()
+}
+
+// writeSample 写入一行样本
+func writeSample(w *bufio.Writer, name string, labels []Label, extra *Label, value float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extra != nil {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label)
+		}
+		if extra != nil {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, *extra)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// writeLabel 写入 name="value"
+func writeLabel(w *bufio.Writer, label Label) {
+	w.WriteString(sanitizeMetricName(label.Name))
+	w.WriteString(`="`)
+	w.WriteString(labelValueReplacer.Replace(label.Value))
+	w.WriteByte('"')
+}
+
+var (
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// escapeHelp 转义 HELP 文本
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+// formatFloat 按 Prometheus 文本格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// sanitizeMetricName 将不合法的字符替换为 _（[a-zA-Z_:][a-zA-Z0-9_:]*）
+func sanitizeMetricName(name string) string {
+	valid := true
+	for i, c := range name {
+		if !isMetricNameChar(c, i == 0) {
+			valid = false
+			break
+		}
+	}
+	if valid && name != "" {
+		return name
+	}
+
+	var b strings.Builder
+	for i, c := range name {
+		if isMetricNameChar(c, i == 0) {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func isMetricNameChar(c rune, first bool) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// acceptsOpenMetrics 根据 Accept 头判断输出格式
+// 未指定或包含 application/openmetrics-text 时使用 OpenMetrics，仅接受 text/plain 时使用 0.0.4 格式
+func acceptsOpenMetrics(accept string) bool {
+	if strings.Contains(accept, "application/openmetrics-text") {
+		return true
+	}
+	return !strings.Contains(accept, "text/plain")
+}
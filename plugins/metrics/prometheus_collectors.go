@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// RuntimeCollector 返回 Go 运行时指标（Goroutine、内存、GC）的 Collector
+func RuntimeCollector() Collector {
+	return CollectorFunc(func() []MetricFamily {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+
+		gauge := func(name, help string, value float64) MetricFamily {
+			return MetricFamily{Name: name, Help: help, Type: MetricTypeGauge, Samples: []Sample{{Value: value}}}
+		}
+
+		return []MetricFamily{
+			{
+				Name:    "go_info",
+				Help:    "Information about the Go environment.",
+				Type:    MetricTypeGauge,
+				Samples: []Sample{{Labels: Labels("version", runtime.Version()), Value: 1}},
+			},
+			gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())),
+			gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(m.Alloc)),
+			gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", float64(m.Sys)),
+			gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", float64(m.HeapInuse)),
+			gauge("go_memstats_heap_objects", "Number of allocated objects.", float64(m.HeapObjects)),
+			{
+				Name:    "go_gc_cycles",
+				Help:    "Number of completed GC cycles.",
+				Type:    MetricTypeCounter,
+				Samples: []Sample{{Value: float64(m.NumGC)}},
+			},
+			{
+				Name:    "go_gc_pause_seconds",
+				Help:    "Cumulative GC stop-the-world pause duration.",
+				Type:    MetricTypeCounter,
+				Samples: []Sample{{Value: float64(m.PauseTotalNs) / float64(time.Second)}},
+			},
+		}
+	})
+}
+
+// DatabaseCollector 返回主库与辅助库连接池指标的 Collector
+func DatabaseCollector(app core.App) Collector {
+	return CollectorFunc(func() []MetricFamily {
+		type pool struct {
+			db, name string
+			builder  dbx.Builder
+		}
+		pools := []pool{
+			{"data", "concurrent", app.ConcurrentDB()},
+			{"data", "nonconcurrent", app.NonconcurrentDB()},
+			{"auxiliary", "concurrent", app.AuxConcurrentDB()},
+			{"auxiliary", "nonconcurrent", app.AuxNonconcurrentDB()},
+		}
+
+		families := []MetricFamily{
+			{Name: "pb_db_max_open_connections", Help: "Maximum number of open connections to the database.", Type: MetricTypeGauge},
+			{Name: "pb_db_open_connections", Help: "Number of established connections both in use and idle.", Type: MetricTypeGauge},
+			{Name: "pb_db_in_use_connections", Help: "Number of connections currently in use.", Type: MetricTypeGauge},
+			{Name: "pb_db_idle_connections", Help: "Number of idle connections.", Type: MetricTypeGauge},
+			{Name: "pb_db_wait", Help: "Total number of connections waited for.", Type: MetricTypeCounter},
+			{Name: "pb_db_wait_duration_seconds", Help: "Total time blocked waiting for a new connection.", Type: MetricTypeCounter},
+		}
+
+		// PostgreSQL 等场景下并发与非并发可能是同一个连接池
+		seen := make(map[*dbx.DB]bool, len(pools))
+		for _, p := range pools {
+			db, ok := p.builder.(*dbx.DB)
+			if !ok || db == nil || db.DB() == nil || seen[db] {
+				continue
+			}
+			seen[db] = true
+
+			stats := db.DB().Stats()
+			labels := Labels("db", p.db, "pool", p.name)
+			values := []float64{
+				float64(stats.MaxOpenConnections),
+				float64(stats.OpenConnections),
+				float64(stats.InUse),
+				float64(stats.Idle),
+				float64(stats.WaitCount),
+				stats.WaitDuration.Seconds(),
+			}
+			for i, value := range values {
+				families[i].Samples = append(families[i].Samples, Sample{Labels: labels, Value: value})
+			}
+		}
+
+		return families
+	})
+}
+
+// RealtimeCollector 返回实时订阅客户端与订阅数指标的 Collector
+func RealtimeCollector(app core.App) Collector {
+	return CollectorFunc(func() []MetricFamily {
+		broker := app.SubscriptionsBroker()
+		if broker == nil {
+			return nil
+		}
+
+		var subscriptions int
+		for _, client := range broker.Clients() {
+			subscriptions += len(client.Subscriptions())
+		}
+
+		return []MetricFamily{
+			{
+				Name:    "pb_realtime_clients",
+				Help:    "Number of connected realtime clients.",
+				Type:    MetricTypeGauge,
+				Samples: []Sample{{Value: float64(broker.TotalClients())}},
+			},
+			{
+				Name:    "pb_realtime_subscriptions",
+				Help:    "Number of active realtime subscriptions.",
+				Type:    MetricTypeGauge,
+				Samples: []Sample{{Value: float64(subscriptions)}},
+			},
+		}
+	})
+}
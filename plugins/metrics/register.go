@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/hook"
 )
@@ -11,6 +13,8 @@ type metricsPlugin struct {
 	config     Config
 	collector  *MetricsCollector
 	repository *MetricsRepository
+	alerts     *AlertManager
+	filters    *FilterUsage
 }
 
 // MustRegister 注册 metrics 插件，失败时 panic
@@ -32,9 +36,17 @@ func Register(app core.App, config Config) error {
 	// 应用默认值
 	config = applyDefaults(config)
 
+	// 提前校验告警配置，避免启动后才发现规则错误
+	if _, _, err := parseAlertConfig(config); err != nil {
+		return err
+	}
+
+	GetCustomMetrics(app).setMaxSeries(config.CustomMetricsMaxSeries)
+
 	p := &metricsPlugin{
-		app:    app,
-		config: config,
+		app:     app,
+		config:  config,
+		filters: NewFilterUsage(),
 	}
 
 	return p.register()
@@ -56,6 +68,39 @@ func (p *metricsPlugin) register() error {
 		p.collector = NewMetricsCollector(p.app, p.repository, p.config)
 		p.collector.Start()
 
+		// 记录 SQLite 慢查询（PostgreSQL 使用 pg_stat_statements，见 /metrics/database/insights）
+		if !p.config.DisableSlowQueryLog && !p.app.IsPostgres() {
+			instrumentSlowQueries(p.app, p.collector)
+		}
+
+		// 记录列表请求实际使用的 filter/sort 字段，用于 PostgreSQL 索引建议
+		// （数据库类型在引导后才能确定；SQLite 下不使用，不注册）
+		if p.app.IsPostgres() {
+			p.app.OnRecordsListRequest().Bind(&hook.Handler[*core.RecordsListRequestEvent]{
+				Id: "__pbMetricsFilterUsage__",
+				Func: func(e *core.RecordsListRequestEvent) error {
+					query := e.Request.URL.Query()
+					p.filters.Record(e.Collection, query.Get("filter"), query.Get("sort"))
+					return e.Next()
+				},
+			})
+		}
+
+		// 创建并启动告警评估（未配置规则时不启动）
+		alerts, err := NewAlertManager(p.app, p.repository, p.config)
+		if err != nil {
+			return err
+		}
+		p.alerts = alerts
+		p.alerts.Start()
+
+		// 注册内置 Prometheus 指标
+		registry := GetRegistry(p.app)
+		registry.Register("http", p.collector.GetRequestMetrics())
+		registry.Register("runtime", RuntimeCollector())
+		registry.Register("database", DatabaseCollector(p.app))
+		registry.Register("realtime", RealtimeCollector(p.app))
+
 		// 存储到 app.Store() 供外部访问
 		p.app.Store().Set(pluginStoreKey, p)
 
@@ -87,8 +132,15 @@ func (p *metricsPlugin) register() error {
 			if p.collector != nil {
 				p.collector.Stop()
 			}
+			if p.alerts != nil {
+				p.alerts.Stop()
+			}
 
-			// 清理 Store
+			// 清理内置指标与 Store
+			registry := GetRegistry(p.app)
+			for _, name := range []string{"http", "runtime", "database", "realtime"} {
+				registry.Unregister(name)
+			}
 			p.app.Store().Remove(pluginStoreKey)
 
 			return e.Next()
@@ -105,9 +157,33 @@ func (p *metricsPlugin) register() error {
 			} else if rowsDeleted > 0 {
 				p.app.Logger().Info("Cleaned up old metrics", "rowsDeleted", rowsDeleted)
 			}
+
+			if !p.config.DisableRollups {
+				rowsDeleted, err := p.repository.CleanupOldRollups(map[Resolution]int{
+					Resolution5m:     p.config.Rollup5mRetentionDays,
+					ResolutionHourly: p.config.RollupHourlyRetentionDays,
+					ResolutionDaily:  p.config.RollupDailyRetentionDays,
+				})
+				if err != nil {
+					p.app.Logger().Error("Failed to cleanup old metrics rollups", "error", err)
+				} else if rowsDeleted > 0 {
+					p.app.Logger().Info("Cleaned up old metrics rollups", "rowsDeleted", rowsDeleted)
+				}
+			}
 		}
 	})
 
+	// 5. 注册 Cron 降采样任务（汇总已结束的 5 分钟、小时、天时间桶）
+	if !p.config.DisableRollups {
+		p.app.Cron().Add("__pbMetricsRollup__", DefaultRollupCron, func() {
+			if p.repository != nil {
+				if _, err := p.repository.Rollup(time.Now()); err != nil {
+					p.app.Logger().Error("Failed to rollup metrics", "error", err)
+				}
+			}
+		})
+	}
+
 	return nil
 }
 
@@ -129,6 +205,24 @@ func GetRepository(app core.App) *MetricsRepository {
 	return nil
 }
 
+// GetAlertManager 获取指定 App 的 AlertManager
+// 如果插件未注册，返回 nil
+func GetAlertManager(app core.App) *AlertManager {
+	if p := getPlugin(app); p != nil {
+		return p.alerts
+	}
+	return nil
+}
+
+// GetFilterUsage 获取指定 App 记录列表请求的 filter/sort 字段统计
+// 如果插件未注册，返回 nil
+func GetFilterUsage(app core.App) *FilterUsage {
+	if p := getPlugin(app); p != nil {
+		return p.filters
+	}
+	return nil
+}
+
 // getPlugin 从 app.Store() 获取插件实例
 func getPlugin(app core.App) *metricsPlugin {
 	if v := app.Store().Get(pluginStoreKey); v != nil {
@@ -7,6 +7,7 @@ import (
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
 // MetricsRepository 监控数据仓库
@@ -103,6 +104,177 @@ func (r *MetricsRepository) GetByTimeRange(hours int, limit int) ([]*SystemMetri
 	return results, totalItems, nil
 }
 
+// GetSince 查询 since 之后的监控记录（按时间升序）
+func (r *MetricsRepository) GetSince(since time.Time) ([]*SystemMetrics, error) {
+	sinceDT, _ := types.ParseDateTime(since)
+
+	var results []*SystemMetrics
+	err := r.app.AuxModelQuery(&SystemMetrics{}).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		OrderBy("timestamp ASC").
+		All(&results)
+
+	return results, err
+}
+
+// InsertRouteMetrics 批量插入按路由的请求统计
+func (r *MetricsRepository) InsertRouteMetrics(records []*RouteMetrics) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	for _, m := range records {
+		if m.Id == "" {
+			m.Id = security.RandomString(15)
+		}
+	}
+
+	return r.app.AuxRunInTransaction(func(txApp core.App) error {
+		for _, m := range records {
+			if err := txApp.AuxSave(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetRouteMetrics 查询 [since, until] 范围内的按路由统计
+func (r *MetricsRepository) GetRouteMetrics(since, until time.Time) ([]*RouteMetrics, error) {
+	// 使用 DateTime 参数，与存储格式一致（SQLite 按字符串比较）
+	sinceDT, _ := types.ParseDateTime(since)
+	untilDT, _ := types.ParseDateTime(until)
+
+	var results []*RouteMetrics
+	err := r.app.AuxModelQuery(&RouteMetrics{}).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		AndWhere(dbx.NewExp("timestamp <= {:until}", dbx.Params{"until": untilDT})).
+		OrderBy("timestamp ASC").
+		All(&results)
+
+	return results, err
+}
+
+// InsertCustomMetrics 批量插入自定义指标数据
+func (r *MetricsRepository) InsertCustomMetrics(records []*CustomMetric) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	for _, m := range records {
+		if m.Id == "" {
+			m.Id = security.RandomString(15)
+		}
+	}
+
+	return r.app.AuxRunInTransaction(func(txApp core.App) error {
+		for _, m := range records {
+			if err := txApp.AuxSave(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCustomMetricNames 查询 since 之后出现过的自定义指标（按名称排序）
+func (r *MetricsRepository) GetCustomMetricNames(since time.Time) ([]*CustomMetricInfo, error) {
+	sinceDT, _ := types.ParseDateTime(since)
+
+	var results []*CustomMetricInfo
+	err := r.app.AuxDB().Select("name", "type", "COUNT(*) AS points", "MAX(timestamp) AS last_seen").
+		From(CustomMetricsTableName).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		GroupBy("name", "type").
+		OrderBy("name ASC").
+		All(&results)
+
+	return results, err
+}
+
+// GetCustomMetrics 查询 since 之后指定自定义指标的数据（按时间升序）
+// 返回值 totalItems：当等于 limit+1 时表示可能有更多数据
+func (r *MetricsRepository) GetCustomMetrics(name string, since time.Time, limit int) ([]*CustomMetric, int, error) {
+	sinceDT, _ := types.ParseDateTime(since)
+
+	var results []*CustomMetric
+	err := r.app.AuxModelQuery(&CustomMetric{}).
+		AndWhere(dbx.HashExp{"name": name}).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		OrderBy("timestamp ASC").
+		Limit(int64(limit + 1)).
+		All(&results)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalItems := len(results)
+	if totalItems > limit {
+		results = results[:limit]
+	}
+
+	return results, totalItems, nil
+}
+
+// InsertSlowQueries 批量插入慢查询
+func (r *MetricsRepository) InsertSlowQueries(records []*SlowQuery) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	for _, m := range records {
+		if m.Id == "" {
+			m.Id = security.RandomString(15)
+		}
+	}
+
+	return r.app.AuxRunInTransaction(func(txApp core.App) error {
+		for _, m := range records {
+			if err := txApp.AuxSave(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetSlowQueries 查询 since 之后记录的慢查询（按时间降序）
+func (r *MetricsRepository) GetSlowQueries(since time.Time, limit int) ([]*SlowQuery, error) {
+	sinceDT, _ := types.ParseDateTime(since)
+
+	var results []*SlowQuery
+	err := r.app.AuxModelQuery(&SlowQuery{}).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		OrderBy("timestamp DESC").
+		Limit(int64(limit)).
+		All(&results)
+
+	return results, err
+}
+
+// GetAlerts 查询所有告警状态，state 非空时按状态过滤
+func (r *MetricsRepository) GetAlerts(state AlertState) ([]*MetricsAlert, error) {
+	query := r.app.AuxModelQuery(&MetricsAlert{}).OrderBy("rule ASC")
+	if state != "" {
+		query.AndWhere(dbx.HashExp{"state": state})
+	}
+
+	var results []*MetricsAlert
+	err := query.All(&results)
+
+	return results, err
+}
+
+// SaveAlert 保存告警状态
+// 自动生成 Id
+func (r *MetricsRepository) SaveAlert(alert *MetricsAlert) error {
+	if alert.Id == "" {
+		alert.Id = security.RandomString(15)
+	}
+
+	return r.app.AuxSave(alert)
+}
+
 // CleanupOldMetrics 清理过期的监控数据
 func (r *MetricsRepository) CleanupOldMetrics(retentionDays int) (int64, error) {
 	if retentionDays <= 0 {
@@ -121,5 +293,38 @@ func (r *MetricsRepository) CleanupOldMetrics(retentionDays int) (int64, error)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
-	return rowsAffected, nil
+
+	// 按路由的请求统计使用相同的保留期
+	routesResult, err := r.app.AuxNonconcurrentDB().Delete(
+		RouteMetricsTableName,
+		dbx.NewExp("timestamp < {:cutoff}", dbx.Params{"cutoff": cutoff}),
+	).Execute()
+	if err != nil {
+		return rowsAffected, err
+	}
+
+	routesAffected, _ := routesResult.RowsAffected()
+
+	// 自定义指标同样使用原始数据的保留期
+	customResult, err := r.app.AuxNonconcurrentDB().Delete(
+		CustomMetricsTableName,
+		dbx.NewExp("timestamp < {:cutoff}", dbx.Params{"cutoff": cutoff}),
+	).Execute()
+	if err != nil {
+		return rowsAffected + routesAffected, err
+	}
+
+	customAffected, _ := customResult.RowsAffected()
+
+	// 慢查询同样使用原始数据的保留期
+	slowResult, err := r.app.AuxNonconcurrentDB().Delete(
+		SlowQueriesTableName,
+		dbx.NewExp("timestamp < {:cutoff}", dbx.Params{"cutoff": cutoff}),
+	).Execute()
+	if err != nil {
+		return rowsAffected + routesAffected + customAffected, err
+	}
+
+	slowAffected, _ := slowResult.RowsAffected()
+	return rowsAffected + routesAffected + customAffected + slowAffected, nil
 }
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// requestSeriesKey HTTP 请求时间序列的标签组合
+type requestSeriesKey struct {
+	method string
+	route  string
+	status int
+}
+
+// requestSeries 单个时间序列的直方图计数
+type requestSeries struct {
+	counts []atomic.Uint64 // 各分桶的非累计计数，最后一个为 +Inf
+	sumNs  atomic.Int64
+}
+
+// RequestMetrics 按路由模式、方法和状态码统计 HTTP 请求耗时直方图
+type RequestMetrics struct {
+	buckets []float64
+	mu      sync.RWMutex
+	series  map[requestSeriesKey]*requestSeries
+}
+
+// NewRequestMetrics 创建请求直方图，buckets 为空时使用 DefaultRequestDurationBuckets
+func NewRequestMetrics(buckets []float64) *RequestMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultRequestDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &RequestMetrics{
+		buckets: buckets,
+		series:  make(map[requestSeriesKey]*requestSeries),
+	}
+}
+
+// Record 记录一次请求
+func (m *RequestMetrics) Record(method, route string, status int, duration time.Duration) {
+	key := requestSeriesKey{method: method, route: route, status: status}
+
+	m.mu.RLock()
+	s, ok := m.series[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		m.mu.Lock()
+		if s, ok = m.series[key]; !ok {
+			s = &requestSeries{counts: make([]atomic.Uint64, len(m.buckets)+1)}
+			m.series[key] = s
+		}
+		m.mu.Unlock()
+	}
+
+	seconds := duration.Seconds()
+	i := sort.SearchFloat64s(m.buckets, seconds)
+	s.counts[i].Add(1)
+	s.sumNs.Add(int64(duration))
+}
+
+// Collect 实现 Collector 接口
+func (m *RequestMetrics) Collect() []MetricFamily {
+	m.mu.RLock()
+	keys := make([]requestSeriesKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	family := MetricFamily{
+		Name: "pb_http_request_duration_seconds",
+		Help: "HTTP request duration by route pattern, method and status code.",
+		Type: MetricTypeHistogram,
+	}
+
+	for _, key := range keys {
+		m.mu.RLock()
+		s := m.series[key]
+		m.mu.RUnlock()
+
+		h := &HistogramValue{
+			Buckets: m.buckets,
+			Counts:  make([]uint64, len(m.buckets)),
+			Sum:     float64(s.sumNs.Load()) / float64(time.Second),
+		}
+		var cumulative uint64
+		for i := range m.buckets {
+			cumulative += s.counts[i].Load()
+			h.Counts[i] = cumulative
+		}
+		h.Count = cumulative + s.counts[len(m.buckets)].Load()
+
+		family.Samples = append(family.Samples, Sample{
+			Labels:    Labels("method", key.method, "route", key.route, "status", strconv.Itoa(key.status)),
+			Histogram: h,
+		})
+	}
+
+	return []MetricFamily{family}
+}
+
+// routePattern 返回请求匹配的路由模式（去掉方法前缀），未匹配时返回 "unmatched"
+func routePattern(r *http.Request) string {
+	pattern := r.Pattern
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	if pattern == "" {
+		return "unmatched"
+	}
+	return pattern
+}
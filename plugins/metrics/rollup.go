@@ -0,0 +1,356 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Resolution 监控数据的时间分辨率
+type Resolution string
+
+const (
+	// ResolutionRaw 原始采集样本（_metrics）
+	ResolutionRaw Resolution = "raw"
+
+	// Resolution5m 5 分钟降采样
+	Resolution5m Resolution = "5m"
+
+	// ResolutionHourly 小时降采样
+	ResolutionHourly Resolution = "1h"
+
+	// ResolutionDaily 天降采样
+	ResolutionDaily Resolution = "1d"
+)
+
+// RollupResolutions 所有降采样分辨率（由细到粗）
+var RollupResolutions = []Resolution{Resolution5m, ResolutionHourly, ResolutionDaily}
+
+// rollupDelay 时间桶结束后等待的时长，避免遗漏刚写入的样本
+const rollupDelay = 30 * time.Second
+
+// Step 返回降采样时间桶的长度，原始分辨率返回 0
+func (r Resolution) Step() time.Duration {
+	switch r {
+	case Resolution5m:
+		return 5 * time.Minute
+	case ResolutionHourly:
+		return time.Hour
+	case ResolutionDaily:
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// TableName 返回分辨率对应的表名
+func (r Resolution) TableName() string {
+	switch r {
+	case Resolution5m:
+		return Metrics5mTableName
+	case ResolutionHourly:
+		return MetricsHourlyTableName
+	case ResolutionDaily:
+		return MetricsDailyTableName
+	}
+	return SystemMetricsTableName
+}
+
+// RollupStats 单个字段在时间桶内的统计
+type RollupStats struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+	P95 float64 `json:"p95"`
+}
+
+// MetricsRollup 降采样后的监控数据，Timestamp 为时间桶起点
+// 存储在 auxiliary.db 数据库中，表名由 Resolution 决定
+type MetricsRollup struct {
+	core.BaseModel
+
+	Resolution  Resolution                 `db:"-" json:"resolution"`
+	Timestamp   types.DateTime             `db:"timestamp" json:"timestamp"`
+	SampleCount int                        `db:"sample_count" json:"sample_count"`
+	Stats       types.JSONMap[RollupStats] `db:"stats" json:"stats"`
+}
+
+// TableName 返回表名
+func (m *MetricsRollup) TableName() string {
+	return m.Resolution.TableName()
+}
+
+// ToSystemMetrics 以各字段的平均值转换为 SystemMetrics，便于与原始数据统一展示
+func (m *MetricsRollup) ToSystemMetrics() *SystemMetrics {
+	result := &SystemMetrics{
+		Timestamp:       m.Timestamp,
+		CpuUsagePercent: m.Stats["cpu_usage_percent"].Avg,
+		MemoryAllocMB:   m.Stats["memory_alloc_mb"].Avg,
+		GoroutinesCount: int(math.Round(m.Stats["goroutines_count"].Avg)),
+		SqliteWalSizeMB: m.Stats["sqlite_wal_size_mb"].Avg,
+		SqliteOpenConns: int(math.Round(m.Stats["sqlite_open_conns"].Avg)),
+		P95LatencyMs:    m.Stats["p95_latency_ms"].Avg,
+		Http5xxCount:    int(math.Round(m.Stats["http_5xx_count"].Avg)),
+	}
+	result.Id = m.Id
+	return result
+}
+
+// newMetricsRollup 汇总时间桶内的原始样本（samples 非空）
+func newMetricsRollup(resolution Resolution, bucket time.Time, samples []*SystemMetrics) *MetricsRollup {
+	timestamp, _ := types.ParseDateTime(bucket)
+
+	rollup := &MetricsRollup{
+		Resolution:  resolution,
+		Timestamp:   timestamp,
+		SampleCount: len(samples),
+		Stats:       make(types.JSONMap[RollupStats], len(systemMetricsFields)),
+	}
+	rollup.Id = security.RandomString(15)
+
+	values := make([]float64, len(samples))
+	for field, getter := range systemMetricsFields {
+		var sum float64
+		for i, s := range samples {
+			values[i] = getter(s)
+			sum += values[i]
+		}
+		sort.Float64s(values)
+
+		// P95 与 LatencyBuffer 相同，取排序后 ceil(n*0.95) 位置的样本
+		p95Index := min(max(int(math.Ceil(float64(len(values))*0.95))-1, 0), len(values)-1)
+
+		rollup.Stats[field] = RollupStats{
+			Min: values[0],
+			Max: values[len(values)-1],
+			Avg: sum / float64(len(values)),
+			P95: values[p95Index],
+		}
+	}
+
+	return rollup
+}
+
+// Rollup 将已结束且尚未汇总的时间桶从原始样本降采样到各分辨率表，返回写入的行数
+// 每个分辨率从最新一行之后继续（首次从最早的原始样本开始），可重复调用
+func (r *MetricsRepository) Rollup(now time.Time) (int, error) {
+	var total int
+	var errs []error
+	for _, resolution := range RollupResolutions {
+		n, err := r.rollup(resolution, now)
+		total += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+func (r *MetricsRepository) rollup(resolution Resolution, now time.Time) (int, error) {
+	step := resolution.Step()
+	end := now.UTC().Add(-rollupDelay).Truncate(step)
+
+	var start time.Time
+	latest, err := r.latestRollup(resolution)
+	if err != nil {
+		return 0, err
+	}
+	if latest != nil {
+		start = latest.Timestamp.Time().Add(step)
+	} else {
+		oldest, err := r.oldestSample()
+		if err != nil || oldest == nil {
+			return 0, err
+		}
+		start = oldest.Timestamp.Time().UTC().Truncate(step)
+	}
+
+	if !start.Before(end) {
+		return 0, nil
+	}
+
+	startDT, _ := types.ParseDateTime(start)
+	endDT, _ := types.ParseDateTime(end)
+
+	var samples []*SystemMetrics
+	err = r.app.AuxModelQuery(&SystemMetrics{}).
+		AndWhere(dbx.NewExp("timestamp >= {:start}", dbx.Params{"start": startDT})).
+		AndWhere(dbx.NewExp("timestamp < {:end}", dbx.Params{"end": endDT})).
+		OrderBy("timestamp ASC").
+		All(&samples)
+	if err != nil {
+		return 0, err
+	}
+
+	// 按时间桶分组（samples 已按时间升序，空桶不写入）
+	var rollups []*MetricsRollup
+	var bucket time.Time
+	var bucketSamples []*SystemMetrics
+	for _, s := range samples {
+		b := s.Timestamp.Time().UTC().Truncate(step)
+		if !b.Equal(bucket) && len(bucketSamples) > 0 {
+			rollups = append(rollups, newMetricsRollup(resolution, bucket, bucketSamples))
+			bucketSamples = nil
+		}
+		bucket = b
+		bucketSamples = append(bucketSamples, s)
+	}
+	if len(bucketSamples) > 0 {
+		rollups = append(rollups, newMetricsRollup(resolution, bucket, bucketSamples))
+	}
+
+	if len(rollups) == 0 {
+		return 0, nil
+	}
+
+	err = r.app.AuxRunInTransaction(func(txApp core.App) error {
+		for _, rollup := range rollups {
+			if err := txApp.AuxSave(rollup); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(rollups), nil
+}
+
+// latestRollup 返回分辨率表中最新的一行，没有数据时返回 nil
+func (r *MetricsRepository) latestRollup(resolution Resolution) (*MetricsRollup, error) {
+	m := &MetricsRollup{Resolution: resolution}
+	err := r.app.AuxModelQuery(m).
+		OrderBy("timestamp DESC").
+		Limit(1).
+		One(m)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// oldestSample 返回最早的一条原始样本，没有数据时返回 nil
+func (r *MetricsRepository) oldestSample() (*SystemMetrics, error) {
+	var m SystemMetrics
+	err := r.app.AuxModelQuery(&m).
+		OrderBy("timestamp ASC").
+		Limit(1).
+		One(&m)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetRollups 查询 since 之后指定分辨率的降采样数据（按时间升序）
+// 返回值 totalItems：当等于 limit+1 时表示可能有更多数据
+func (r *MetricsRepository) GetRollups(resolution Resolution, since time.Time, limit int) ([]*MetricsRollup, int, error) {
+	sinceDT, _ := types.ParseDateTime(since)
+
+	var results []*MetricsRollup
+	err := r.app.AuxModelQuery(&MetricsRollup{Resolution: resolution}).
+		AndWhere(dbx.NewExp("timestamp >= {:since}", dbx.Params{"since": sinceDT})).
+		OrderBy("timestamp ASC").
+		Limit(int64(limit + 1)).
+		All(&results)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, m := range results {
+		m.Resolution = resolution
+	}
+
+	totalItems := len(results)
+	if totalItems > limit {
+		results = results[:limit]
+	}
+
+	return results, totalItems, nil
+}
+
+// CleanupOldRollups 按各分辨率的保留天数清理降采样数据
+func (r *MetricsRepository) CleanupOldRollups(retentionDays map[Resolution]int) (int64, error) {
+	var total int64
+	for _, resolution := range RollupResolutions {
+		days := retentionDays[resolution]
+		if days <= 0 {
+			continue
+		}
+
+		cutoff, _ := types.ParseDateTime(time.Now().AddDate(0, 0, -days))
+		result, err := r.app.AuxNonconcurrentDB().Delete(
+			resolution.TableName(),
+			dbx.NewExp("timestamp < {:cutoff}", dbx.Params{"cutoff": cutoff}),
+		).Execute()
+		if err != nil {
+			return total, err
+		}
+
+		rowsAffected, _ := result.RowsAffected()
+		total += rowsAffected
+	}
+	return total, nil
+}
+
+// rollupRetention 返回各分辨率的保留时长（原始数据为 RetentionDays）
+func (c Config) rollupRetention(resolution Resolution) time.Duration {
+	days := c.RetentionDays
+	switch resolution {
+	case Resolution5m:
+		days = c.Rollup5mRetentionDays
+	case ResolutionHourly:
+		days = c.RollupHourlyRetentionDays
+	case ResolutionDaily:
+		days = c.RollupDailyRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// chooseResolution 选择最细的分辨率，使其保留期覆盖时间范围且数据点数不超过 maxPoints
+// 没有满足条件的分辨率时，返回保留期覆盖时间范围的最粗分辨率（仍不覆盖时返回最粗分辨率）
+func (c Config) chooseResolution(timeRange time.Duration, maxPoints int) Resolution {
+	candidates := []Resolution{ResolutionRaw}
+	if !c.DisableRollups {
+		candidates = append(candidates, RollupResolutions...)
+	}
+
+	fallback := candidates[len(candidates)-1]
+	covered := false
+	for _, resolution := range candidates {
+		if timeRange > c.rollupRetention(resolution) {
+			continue
+		}
+
+		step := resolution.Step()
+		if resolution == ResolutionRaw {
+			step = c.CollectionInterval
+		}
+		points := int(math.Ceil(float64(timeRange) / float64(step)))
+		if points <= maxPoints {
+			return resolution
+		}
+
+		fallback = resolution // candidates 由细到粗，保留最后一个覆盖时间范围的分辨率
+		covered = true
+	}
+
+	if !covered {
+		return candidates[len(candidates)-1]
+	}
+	return fallback
+}
@@ -0,0 +1,234 @@
+package metrics_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+func TestMetricsRepositoryRollup(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		repo := metrics.NewMetricsRepository(app)
+
+		base := time.Now().UTC().AddDate(0, 0, -3).Truncate(24 * time.Hour).Add(10 * time.Hour)
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			0:                {CpuUsagePercent: 10, Http5xxCount: 1},
+			time.Minute:      {CpuUsagePercent: 20, Http5xxCount: 0},
+			2 * time.Minute:  {CpuUsagePercent: 30, Http5xxCount: 2},
+			6 * time.Minute:  {CpuUsagePercent: 40},
+			61 * time.Minute: {CpuUsagePercent: 50},
+		})
+
+		// 12:01 时 5 分钟桶与 10:00、11:00 的小时桶已结束，天桶尚未结束
+		now := base.Add(2*time.Hour + time.Minute)
+		total, err := repo.Rollup(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 3+2 {
+			t.Fatalf("expected 5 rollup rows, got %d", total)
+		}
+
+		// 重复执行不会重复写入
+		if total, err := repo.Rollup(now); err != nil || total != 0 {
+			t.Fatalf("expected no new rows, got %d (%v)", total, err)
+		}
+
+		rollups, totalItems, err := repo.GetRollups(metrics.Resolution5m, base.Add(-time.Hour), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if totalItems != 3 || len(rollups) != 3 {
+			t.Fatalf("expected 3 5m rollups, got %d", totalItems)
+		}
+
+		first := rollups[0]
+		if !first.Timestamp.Time().Equal(base) || first.SampleCount != 3 || first.Resolution != metrics.Resolution5m {
+			t.Errorf("unexpected first bucket %+v", first)
+		}
+		if cpu := first.Stats["cpu_usage_percent"]; cpu.Min != 10 || cpu.Max != 30 || cpu.Avg != 20 || cpu.P95 != 30 {
+			t.Errorf("unexpected cpu stats %+v", cpu)
+		}
+		if m := first.ToSystemMetrics(); m.CpuUsagePercent != 20 || m.Http5xxCount != 1 || m.Id != first.Id {
+			t.Errorf("unexpected converted metrics %+v", m)
+		}
+		if !rollups[2].Timestamp.Time().Equal(base.Add(time.Hour)) || rollups[2].SampleCount != 1 {
+			t.Errorf("unexpected last bucket %+v", rollups[2])
+		}
+
+		// 新样本只汇总之后的时间桶
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			3 * time.Hour: {CpuUsagePercent: 60},
+		})
+		total, err = repo.Rollup(base.Add(4 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 仅 13:00 的 5 分钟桶（13:00 的小时桶尚未结束）
+		if total != 1 {
+			t.Fatalf("expected 1 new row, got %d", total)
+		}
+
+		// 当天结束后写入 13:00 的小时桶和天桶
+		total, err = repo.Rollup(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 2 {
+			t.Fatalf("expected 2 new rows, got %d", total)
+		}
+
+		daily, _, err := repo.GetRollups(metrics.ResolutionDaily, base.Add(-48*time.Hour), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(daily) != 1 || daily[0].SampleCount != 6 || daily[0].Stats["cpu_usage_percent"].Avg != 35 {
+			t.Fatalf("unexpected daily rollups %+v", daily)
+		}
+	})
+}
+
+func TestMetricsRepositoryCleanupOldRollups(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		repo := metrics.NewMetricsRepository(app)
+
+		base := time.Now().UTC().AddDate(0, 0, -40).Truncate(24 * time.Hour)
+		insertAlertSamples(t, repo, base, map[time.Duration]*metrics.SystemMetrics{
+			0:                   {CpuUsagePercent: 10},
+			39 * 24 * time.Hour: {CpuUsagePercent: 20},
+		})
+		if _, err := repo.Rollup(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		deleted, err := repo.CleanupOldRollups(map[metrics.Resolution]int{
+			metrics.Resolution5m:     30,
+			metrics.ResolutionHourly: 180,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 1 {
+			t.Fatalf("expected only the old 5m rollup to be deleted, got %d", deleted)
+		}
+
+		for resolution, expected := range map[metrics.Resolution]int{
+			metrics.Resolution5m:     1,
+			metrics.ResolutionHourly: 2,
+			metrics.ResolutionDaily:  2,
+		} {
+			_, total, err := repo.GetRollups(resolution, base.Add(-time.Hour), 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != expected {
+				t.Errorf("expected %d %s rollups, got %d", expected, resolution, total)
+			}
+		}
+	})
+}
+
+func TestSystemMetricsResolution(t *testing.T) {
+	t.Parallel()
+
+	testAppWithMetrics := func(config metrics.Config) func(tb testing.TB) *tests.TestApp {
+		return func(tb testing.TB) *tests.TestApp {
+			config.CollectionInterval = time.Minute
+			app := newTestAppWithMetrics(tb, config)
+
+			repo := metrics.NewMetricsRepository(app)
+			base := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+			insertAlertSamples(tb, repo, base, map[time.Duration]*metrics.SystemMetrics{
+				0:           {CpuUsagePercent: 10},
+				time.Minute: {CpuUsagePercent: 30},
+			})
+			if !config.DisableRollups {
+				if _, err := repo.Rollup(time.Now()); err != nil {
+					tb.Fatal(err)
+				}
+			}
+			return app
+		}
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:               "short range uses raw samples",
+			Method:             http.MethodGet,
+			URL:                "/api/system/metrics?hours=4",
+			Headers:            map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:     200,
+			ExpectedContent:    []string{`"resolution":"raw"`, `"cpu_usage_percent":30`},
+			NotExpectedContent: []string{`"rollups"`},
+			ExpectedEvents:     map[string]int{"*": 0},
+			TestAppFactory:     testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "day range uses 5m rollups",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?hours=24",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  200,
+			ExpectedContent: []string{`"resolution":"5m"`, `"cpu_usage_percent":20`, `"rollups":[{`, `"cpu_usage_percent":{"min":10,"max":30,"avg":20,"p95":30}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "month range uses hourly rollups",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?hours=720",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  200,
+			ExpectedContent: []string{`"resolution":"1h"`, `"sample_count":2`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "small point count falls back to daily rollups",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?hours=720&limit=10",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  200,
+			ExpectedContent: []string{`"resolution":"1d"`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "explicit resolution",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?hours=720&resolution=raw",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  200,
+			ExpectedContent: []string{`"resolution":"raw"`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "invalid resolution",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?resolution=1w",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{}),
+		},
+		{
+			Name:            "rollups disabled",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics?hours=24&resolution=5m",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(metrics.Config{DisableRollups: true}),
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// infBucketKey 直方图溢出分桶的键
+const infBucketKey = "+Inf"
+
+// routeKey 路由统计的分组键
+type routeKey struct {
+	method string
+	route  string
+}
+
+// routeAccumulator 单个路由在当前采集周期内的累计值
+type routeAccumulator struct {
+	count   int
+	errors  int
+	sumMs   float64
+	maxMs   float64
+	buckets []int64 // 非累计计数，最后一个为 +Inf
+}
+
+// routeWindow 当前采集周期内按路由模式和方法累积的请求统计
+// 每次采集时取出并重置，与 LatencyBuffer 的全局 P95 互不影响
+type routeWindow struct {
+	mu     sync.Mutex
+	bounds []float64 // 分桶上界（ms，升序）
+	routes map[routeKey]*routeAccumulator
+}
+
+// newRouteWindow 创建路由统计窗口，bucketsSec 为分桶上界（秒）
+func newRouteWindow(bucketsSec []float64) *routeWindow {
+	if len(bucketsSec) == 0 {
+		bucketsSec = DefaultRequestDurationBuckets
+	}
+
+	bounds := make([]float64, len(bucketsSec))
+	for i, b := range bucketsSec {
+		bounds[i] = math.Round(b*1e9) / 1e6 // 秒转毫秒，避免 0.025*1000 之类的浮点误差
+	}
+	sort.Float64s(bounds)
+
+	return &routeWindow{
+		bounds: bounds,
+		routes: make(map[routeKey]*routeAccumulator),
+	}
+}
+
+// record 记录一次请求，5xx 计为错误
+func (w *routeWindow) record(method, route string, status int, duration time.Duration) {
+	ms := float64(duration.Microseconds()) / 1000.0
+	key := routeKey{method: method, route: route}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	acc, ok := w.routes[key]
+	if !ok {
+		acc = &routeAccumulator{buckets: make([]int64, len(w.bounds)+1)}
+		w.routes[key] = acc
+	}
+
+	acc.count++
+	if status >= 500 && status < 600 {
+		acc.errors++
+	}
+	acc.sumMs += ms
+	acc.maxMs = math.Max(acc.maxMs, ms)
+	acc.buckets[sort.SearchFloat64s(w.bounds, ms)]++
+}
+
+// flush 取出当前周期的统计并重置窗口，按 route、method 排序
+func (w *routeWindow) flush(timestamp types.DateTime) []*RouteMetrics {
+	w.mu.Lock()
+	routes := w.routes
+	w.routes = make(map[routeKey]*routeAccumulator, len(routes))
+	w.mu.Unlock()
+
+	result := make([]*RouteMetrics, 0, len(routes))
+	for key, acc := range routes {
+		buckets := make(types.JSONMap[int64], len(acc.buckets))
+		for i, n := range acc.buckets {
+			if n == 0 {
+				continue
+			}
+			if i < len(w.bounds) {
+				buckets[formatFloat(w.bounds[i])] = n
+			} else {
+				buckets[infBucketKey] = n
+			}
+		}
+
+		parsed := parseLatencyBuckets(buckets)
+		m := &RouteMetrics{
+			Timestamp:      timestamp,
+			Method:         key.method,
+			Route:          key.route,
+			RequestCount:   acc.count,
+			ErrorCount:     acc.errors,
+			AvgLatencyMs:   roundMs(acc.sumMs / float64(acc.count)),
+			MaxLatencyMs:   roundMs(acc.maxMs),
+			P50LatencyMs:   roundMs(latencyQuantile(parsed, 0.50, acc.maxMs)),
+			P95LatencyMs:   roundMs(latencyQuantile(parsed, 0.95, acc.maxMs)),
+			P99LatencyMs:   roundMs(latencyQuantile(parsed, 0.99, acc.maxMs)),
+			LatencyBuckets: buckets,
+		}
+		m.Id = security.RandomString(15)
+		result = append(result, m)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+
+	return result
+}
+
+// latencyBucket 解析后的直方图分桶
+type latencyBucket struct {
+	le    float64
+	count int64
+}
+
+// parseLatencyBuckets 将持久化的直方图解析为按上界升序的分桶，忽略无法解析的键
+func parseLatencyBuckets(buckets types.JSONMap[int64]) []latencyBucket {
+	result := make([]latencyBucket, 0, len(buckets))
+	for key, n := range buckets {
+		le := math.Inf(1)
+		if key != infBucketKey {
+			v, err := strconv.ParseFloat(key, 64)
+			if err != nil {
+				continue
+			}
+			le = v
+		}
+		result = append(result, latencyBucket{le: le, count: n})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].le < result[j].le })
+	return result
+}
+
+// latencyQuantile 在直方图分桶内线性插值估算分位数（同 Prometheus histogram_quantile）
+// 结果不超过 maxMs，落在 +Inf 分桶时返回 maxMs
+func latencyQuantile(buckets []latencyBucket, q, maxMs float64) float64 {
+	var total int64
+	for _, b := range buckets {
+		total += b.count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative, lower float64
+	for _, b := range buckets {
+		if b.count > 0 && cumulative+float64(b.count) >= rank {
+			if math.IsInf(b.le, 1) {
+				return maxMs
+			}
+			upper := math.Min(b.le, maxMs)
+			if upper < lower {
+				return upper
+			}
+			return lower + (upper-lower)*(rank-cumulative)/float64(b.count)
+		}
+		cumulative += float64(b.count)
+		lower = b.le
+	}
+
+	return maxMs
+}
+
+// SummarizeRouteMetrics 合并多个采集周期的路由统计，按 route、method 排序
+// 分位数由合并后的直方图计算，而不是对各周期的分位数取平均
+func SummarizeRouteMetrics(rows []*RouteMetrics) []*RouteSummary {
+	type aggregate struct {
+		count   int
+		errors  int
+		sumMs   float64
+		maxMs   float64
+		buckets types.JSONMap[int64]
+	}
+
+	aggregates := make(map[routeKey]*aggregate)
+	for _, row := range rows {
+		key := routeKey{method: row.Method, route: row.Route}
+		agg, ok := aggregates[key]
+		if !ok {
+			agg = &aggregate{buckets: types.JSONMap[int64]{}}
+			aggregates[key] = agg
+		}
+
+		agg.count += row.RequestCount
+		agg.errors += row.ErrorCount
+		agg.sumMs += row.AvgLatencyMs * float64(row.RequestCount)
+		agg.maxMs = math.Max(agg.maxMs, row.MaxLatencyMs)
+		for le, n := range row.LatencyBuckets {
+			agg.buckets[le] += n
+		}
+	}
+
+	result := make([]*RouteSummary, 0, len(aggregates))
+	for key, agg := range aggregates {
+		if agg.count == 0 {
+			continue
+		}
+
+		buckets := parseLatencyBuckets(agg.buckets)
+		result = append(result, &RouteSummary{
+			Method:       key.method,
+			Route:        key.route,
+			RequestCount: agg.count,
+			ErrorCount:   agg.errors,
+			ErrorRate:    math.Round(float64(agg.errors)/float64(agg.count)*10000) / 10000,
+			AvgLatencyMs: roundMs(agg.sumMs / float64(agg.count)),
+			MaxLatencyMs: roundMs(agg.maxMs),
+			P50LatencyMs: roundMs(latencyQuantile(buckets, 0.50, agg.maxMs)),
+			P95LatencyMs: roundMs(latencyQuantile(buckets, 0.95, agg.maxMs)),
+			P99LatencyMs: roundMs(latencyQuantile(buckets, 0.99, agg.maxMs)),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+
+	return result
+}
+
+// slowestRoutes 按 P95 延迟降序返回前 limit 个路由
+func slowestRoutes(summaries []*RouteSummary, limit int) []*RouteSummary {
+	sorted := append([]*RouteSummary(nil), summaries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].P95LatencyMs != sorted[j].P95LatencyMs {
+			return sorted[i].P95LatencyMs > sorted[j].P95LatencyMs
+		}
+		return sorted[i].AvgLatencyMs > sorted[j].AvgLatencyMs
+	})
+	return truncateRoutes(sorted, limit)
+}
+
+// mostErrorRoutes 按错误数降序返回前 limit 个有错误的路由
+func mostErrorRoutes(summaries []*RouteSummary, limit int) []*RouteSummary {
+	sorted := make([]*RouteSummary, 0, len(summaries))
+	for _, s := range summaries {
+		if s.ErrorCount > 0 {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ErrorCount != sorted[j].ErrorCount {
+			return sorted[i].ErrorCount > sorted[j].ErrorCount
+		}
+		return sorted[i].ErrorRate > sorted[j].ErrorRate
+	})
+	return truncateRoutes(sorted, limit)
+}
+
+func truncateRoutes(summaries []*RouteSummary, limit int) []*RouteSummary {
+	if limit > 0 && len(summaries) > limit {
+		return summaries[:limit]
+	}
+	return summaries
+}
+
+// roundMs 保留两位小数
+func roundMs(ms float64) float64 {
+	return math.Round(ms*100) / 100
+}
@@ -0,0 +1,218 @@
+package metrics_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestSummarizeRouteMetrics(t *testing.T) {
+	rows := []*metrics.RouteMetrics{
+		{
+			Method:         "GET",
+			Route:          "/api/a",
+			RequestCount:   10,
+			ErrorCount:     1,
+			AvgLatencyMs:   10,
+			MaxLatencyMs:   40,
+			LatencyBuckets: types.JSONMap[int64]{"5": 5, "50": 5},
+		},
+		{
+			Method:         "GET",
+			Route:          "/api/a",
+			RequestCount:   10,
+			AvgLatencyMs:   30,
+			MaxLatencyMs:   90,
+			LatencyBuckets: types.JSONMap[int64]{"50": 5, "100": 5},
+		},
+		{
+			Method:         "POST",
+			Route:          "/api/a",
+			RequestCount:   2,
+			ErrorCount:     2,
+			AvgLatencyMs:   1,
+			MaxLatencyMs:   1.5,
+			LatencyBuckets: types.JSONMap[int64]{"+Inf": 2},
+		},
+	}
+
+	summaries := metrics.SummarizeRouteMetrics(rows)
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 summaries, got %d", len(summaries))
+	}
+
+	get := summaries[0]
+	if get.Method != "GET" || get.RequestCount != 20 || get.ErrorCount != 1 || get.ErrorRate != 0.05 {
+		t.Errorf("unexpected GET summary %+v", get)
+	}
+	if get.AvgLatencyMs != 20 || get.MaxLatencyMs != 90 {
+		t.Errorf("expected avg 20 and max 90, got %v and %v", get.AvgLatencyMs, get.MaxLatencyMs)
+	}
+	// 合并后的直方图：5ms 5 个、50ms 10 个、100ms 5 个
+	// P50 落在 (5, 50] 分桶第 5 个，插值为 27.5；P95 落在 (50, 100] 分桶，上界截断为 max 90 后插值为 82
+	if get.P50LatencyMs != 27.5 {
+		t.Errorf("expected P50 27.5, got %v", get.P50LatencyMs)
+	}
+	if get.P95LatencyMs != 82 {
+		t.Errorf("expected P95 82, got %v", get.P95LatencyMs)
+	}
+
+	// +Inf 分桶的分位数取最大值
+	if post := summaries[1]; post.Method != "POST" || post.ErrorRate != 1 || post.P99LatencyMs != 1.5 {
+		t.Errorf("unexpected POST summary %+v", post)
+	}
+}
+
+func TestRouteMetricsCollectedPerInterval(t *testing.T) {
+	tests.DualDBTest(t, func(t *testing.T, app *tests.TestApp, dbType tests.DBType) {
+		repo := metrics.NewMetricsRepository(app)
+		config := metrics.DefaultConfig()
+		config.CollectionInterval = time.Hour // 只依赖启动时的立即采集
+		collector := metrics.NewMetricsCollector(app, repo, config)
+
+		collector.RecordRequest("GET", "/api/collections/{collection}/records", 200, 3*time.Millisecond)
+		collector.RecordRequest("GET", "/api/collections/{collection}/records", 200, 30*time.Millisecond)
+		collector.RecordRequest("GET", "/api/collections/{collection}/records", 503, 300*time.Millisecond)
+		collector.RecordRequest("POST", "/api/collections/{collection}/records", 201, 10*time.Millisecond)
+
+		collector.Start()
+		time.Sleep(200 * time.Millisecond)
+		collector.Stop()
+
+		rows, err := repo.GetRouteMetrics(time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 route rows, got %d", len(rows))
+		}
+
+		get := rows[0]
+		if get.Method != "GET" {
+			get = rows[1]
+		}
+		if get.Route != "/api/collections/{collection}/records" || get.RequestCount != 3 || get.ErrorCount != 1 {
+			t.Errorf("unexpected GET row %+v", get)
+		}
+		if get.MaxLatencyMs != 300 || get.P99LatencyMs > 300 || get.P50LatencyMs <= 0 {
+			t.Errorf("unexpected latencies %+v", get)
+		}
+		var total int64
+		for _, n := range get.LatencyBuckets {
+			total += n
+		}
+		if total != 3 {
+			t.Errorf("expected 3 histogram observations, got %d (%v)", total, get.LatencyBuckets)
+		}
+
+		// 窗口在采集后重置，下一次采集不会重复写入
+		collector.Start()
+		time.Sleep(200 * time.Millisecond)
+		collector.Stop()
+
+		rows, err = repo.GetRouteMetrics(time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 {
+			t.Errorf("expected no new route rows, got %d", len(rows))
+		}
+	})
+}
+
+func TestRouteMetricsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	seed := func(t testing.TB, app *tests.TestApp) {
+		repo := metrics.NewMetricsRepository(app)
+
+		old, _ := types.ParseDateTime(time.Now().Add(-48 * time.Hour))
+		now := types.NowDateTime()
+
+		err := repo.InsertRouteMetrics([]*metrics.RouteMetrics{
+			{Timestamp: now, Method: "GET", Route: "/api/fast", RequestCount: 100, AvgLatencyMs: 2, MaxLatencyMs: 4, LatencyBuckets: types.JSONMap[int64]{"5": 100}},
+			{Timestamp: now, Method: "GET", Route: "/api/slow", RequestCount: 10, ErrorCount: 1, AvgLatencyMs: 800, MaxLatencyMs: 2000, LatencyBuckets: types.JSONMap[int64]{"1000": 5, "2500": 5}},
+			{Timestamp: now, Method: "POST", Route: "/api/broken", RequestCount: 4, ErrorCount: 3, AvgLatencyMs: 20, MaxLatencyMs: 40, LatencyBuckets: types.JSONMap[int64]{"50": 4}},
+			{Timestamp: old, Method: "GET", Route: "/api/old", RequestCount: 1, ErrorCount: 1, AvgLatencyMs: 9000, MaxLatencyMs: 9000, LatencyBuckets: types.JSONMap[int64]{"+Inf": 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testAppWithMetrics := func(bootstrap bool) func(tb testing.TB) *tests.TestApp {
+		return func(tb testing.TB) *tests.TestApp {
+			config := metrics.DefaultConfig()
+			config.CollectionInterval = time.Hour
+			if bootstrap {
+				return newTestAppWithMetrics(tb, config)
+			}
+
+			app, err := tests.NewTestApp()
+			if err != nil {
+				tb.Fatal(err)
+			}
+			metrics.MustRegister(app, config)
+			return app
+		}
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unauthorized",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/routes",
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(false),
+		},
+		{
+			Name:            "regular user",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/routes",
+			Headers:         map[string]string{"Authorization": testUserToken},
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(false),
+		},
+		{
+			Name:            "superuser without initialized repository",
+			Method:          http.MethodGet,
+			URL:             "/api/system/metrics/routes",
+			Headers:         map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus:  503,
+			ExpectedContent: []string{`"message":"Metrics service is not available"`},
+			ExpectedEvents:  map[string]int{"*": 0},
+			TestAppFactory:  testAppWithMetrics(false),
+		},
+		{
+			Name:           "superuser",
+			Method:         http.MethodGet,
+			URL:            "/api/system/metrics/routes?hours=24&limit=2",
+			Headers:        map[string]string{"Authorization": testSuperuserToken},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"slowest":[{"method":"GET","route":"/api/slow"`,
+				`"route":"/api/broken"`,
+				`"most_errors":[{"method":"POST","route":"/api/broken","request_count":4,"error_count":3,"error_rate":0.75`,
+			},
+			NotExpectedContent: []string{`"/api/old"`, `"route":"/api/fast"`},
+			ExpectedEvents:     map[string]int{"*": 0},
+			TestAppFactory:     testAppWithMetrics(true),
+			BeforeTestFunc: func(t testing.TB, app *tests.TestApp, e *core.ServeEvent) {
+				seed(t, app)
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -1,10 +1,14 @@
 package metrics
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
 	"github.com/spf13/cast"
 )
 
@@ -28,10 +32,15 @@ func (p *metricsPlugin) bindRoutes(e *core.ServeEvent) {
 		if hours <= 0 {
 			hours = 24
 		}
-		if hours > 168 { // 最多 7 天
-			hours = 168
+		maxHours := 168 // 最多 7 天
+		if !p.config.DisableRollups {
+			maxHours = max(maxHours, p.config.RollupDailyRetentionDays*24)
+		}
+		if hours > maxHours {
+			hours = maxHours
 		}
 
+		// limit 同时作为最大数据点数，用于自动选择分辨率
 		limit := cast.ToInt(re.Request.URL.Query().Get("limit"))
 		if limit <= 0 {
 			limit = 1000
@@ -40,6 +49,42 @@ func (p *metricsPlugin) bindRoutes(e *core.ServeEvent) {
 			limit = 10000
 		}
 
+		resolution := Resolution(re.Request.URL.Query().Get("resolution"))
+		switch resolution {
+		case "", "auto":
+			resolution = p.config.chooseResolution(time.Duration(hours)*time.Hour, limit)
+		case ResolutionRaw:
+		case Resolution5m, ResolutionHourly, ResolutionDaily:
+			if p.config.DisableRollups {
+				return re.BadRequestError("Metrics rollups are disabled.", nil)
+			}
+		default:
+			return re.BadRequestError("Invalid resolution.", nil)
+		}
+
+		if resolution != ResolutionRaw {
+			since := time.Now().Add(-time.Duration(hours) * time.Hour).Truncate(resolution.Step())
+			rollups, totalItems, err := p.repository.GetRollups(resolution, since, limit)
+			if err != nil {
+				return re.JSON(http.StatusInternalServerError, map[string]any{
+					"message": "Failed to query metrics",
+					"error":   err.Error(),
+				})
+			}
+
+			items := make([]*SystemMetrics, len(rollups))
+			for i, rollup := range rollups {
+				items[i] = rollup.ToSystemMetrics()
+			}
+
+			return re.JSON(http.StatusOK, &SystemMetricsResponse{
+				Items:      items,
+				TotalItems: totalItems,
+				Resolution: resolution,
+				Rollups:    rollups,
+			})
+		}
+
 		// 查询数据
 		items, totalItems, err := p.repository.GetByTimeRange(hours, limit)
 		if err != nil {
@@ -52,6 +97,7 @@ func (p *metricsPlugin) bindRoutes(e *core.ServeEvent) {
 		return re.JSON(http.StatusOK, &SystemMetricsResponse{
 			Items:      items,
 			TotalItems: totalItems,
+			Resolution: ResolutionRaw,
 		})
 	})
 
@@ -81,5 +127,242 @@ func (p *metricsPlugin) bindRoutes(e *core.ServeEvent) {
 	})
 
 	// GET /api/system/metrics/database - 获取数据库统计信息
-	subGroup.GET("/metrics/database", databaseStats)
+	subGroup.GET("/metrics/database", p.databaseStats)
+
+	// GET /api/system/metrics/database/insights - PostgreSQL 查询性能分析
+	subGroup.GET("/metrics/database/insights", p.databaseInsightsHandler)
+
+	// POST /api/system/metrics/database/statements/reset - 重置 pg_stat_statements 统计
+	subGroup.POST("/metrics/database/statements/reset", func(re *core.RequestEvent) error {
+		if !re.App.IsPostgres() {
+			return re.BadRequestError("Query insights are only available for PostgreSQL.", nil)
+		}
+
+		if err := resetPostgreSQLStatements(re.App); err != nil {
+			return re.BadRequestError("Failed to reset statement statistics.", err)
+		}
+
+		return re.NoContent(http.StatusNoContent)
+	})
+
+	// GET /api/system/metrics/routes - 获取时间范围内最慢、错误最多的路由
+	subGroup.GET("/metrics/routes", p.routeMetricsHandler)
+
+	// GET /api/system/metrics/alerts - 获取告警状态（可按 state 过滤）
+	subGroup.GET("/metrics/alerts", func(re *core.RequestEvent) error {
+		if p.repository == nil {
+			return re.JSON(http.StatusServiceUnavailable, map[string]any{
+				"message": "Metrics service is not available",
+			})
+		}
+
+		state := AlertState(re.Request.URL.Query().Get("state"))
+		switch state {
+		case "", AlertStatePending, AlertStateFiring, AlertStateResolved, AlertStateInactive:
+		default:
+			return re.BadRequestError("Invalid alert state.", nil)
+		}
+
+		items, err := p.repository.GetAlerts(state)
+		if err != nil {
+			return re.JSON(http.StatusInternalServerError, map[string]any{
+				"message": "Failed to query alerts",
+				"error":   err.Error(),
+			})
+		}
+
+		return re.JSON(http.StatusOK, &MetricsAlertsResponse{Items: items})
+	})
+
+	// GET /api/system/metrics/custom - 获取时间范围内出现过的自定义指标
+	subGroup.GET("/metrics/custom", p.customMetricNamesHandler)
+
+	// GET /api/system/metrics/custom/{name} - 获取单个自定义指标的各周期数据
+	subGroup.GET("/metrics/custom/{name}", p.customMetricsHandler)
+
+	// GET /api/metrics/prometheus - Prometheus/OpenMetrics 抓取端点（Token 或 Superuser）
+	if !p.config.DisablePrometheus {
+		e.Router.GET(PrometheusRoute, p.prometheusHandler)
+	}
+}
+
+// databaseInsightsHandler 返回 PostgreSQL 语句 Top N、表膨胀、顺序扫描、索引使用、锁等待与长事务
+func (p *metricsPlugin) databaseInsightsHandler(re *core.RequestEvent) error {
+	if !re.App.IsPostgres() {
+		return re.BadRequestError("Query insights are only available for PostgreSQL.", nil)
+	}
+
+	limit := cast.ToInt(re.Request.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	longTxThreshold := DefaultLongTransactionThreshold
+	if seconds := cast.ToInt(re.Request.URL.Query().Get("tx_seconds")); seconds > 0 {
+		longTxThreshold = time.Duration(seconds) * time.Second
+	}
+
+	insights, err := collectPostgreSQLInsights(re.App, p.filters, limit, longTxThreshold)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]any{
+			"message": "Failed to collect query insights",
+			"error":   err.Error(),
+		})
+	}
+
+	return re.JSON(http.StatusOK, insights)
+}
+
+// customMetricNamesHandler 返回时间范围内出现过的自定义指标
+func (p *metricsPlugin) customMetricNamesHandler(re *core.RequestEvent) error {
+	if p.repository == nil {
+		return re.JSON(http.StatusServiceUnavailable, map[string]any{
+			"message": "Metrics service is not available",
+		})
+	}
+
+	hours := cast.ToInt(re.Request.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 168 { // 最多 7 天
+		hours = 168
+	}
+
+	items, err := p.repository.GetCustomMetricNames(time.Now().Add(-time.Duration(hours) * time.Hour))
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]any{
+			"message": "Failed to query custom metrics",
+			"error":   err.Error(),
+		})
+	}
+
+	return re.JSON(http.StatusOK, &CustomMetricsListResponse{Items: items})
+}
+
+// customMetricsHandler 返回单个自定义指标时间范围内各采集周期的数据
+func (p *metricsPlugin) customMetricsHandler(re *core.RequestEvent) error {
+	if p.repository == nil {
+		return re.JSON(http.StatusServiceUnavailable, map[string]any{
+			"message": "Metrics service is not available",
+		})
+	}
+
+	name := re.Request.PathValue("name")
+	if !customMetricNameRegex.MatchString(name) {
+		return re.BadRequestError("Invalid custom metric name.", nil)
+	}
+
+	hours := cast.ToInt(re.Request.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 168 { // 最多 7 天
+		hours = 168
+	}
+
+	limit := cast.ToInt(re.Request.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 1000
+	}
+	if limit > 10000 {
+		limit = 10000
+	}
+
+	items, totalItems, err := p.repository.GetCustomMetrics(name, time.Now().Add(-time.Duration(hours)*time.Hour), limit)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]any{
+			"message": "Failed to query custom metrics",
+			"error":   err.Error(),
+		})
+	}
+
+	return re.JSON(http.StatusOK, &CustomMetricsResponse{
+		Name:       name,
+		Items:      items,
+		TotalItems: totalItems,
+	})
+}
+
+// routeMetricsHandler 合并最近 hours 小时内的按路由统计，返回 P95 最慢与错误最多的前 limit 个路由
+func (p *metricsPlugin) routeMetricsHandler(re *core.RequestEvent) error {
+	if p.repository == nil {
+		return re.JSON(http.StatusServiceUnavailable, map[string]any{
+			"message": "Metrics service is not available",
+		})
+	}
+
+	hours := cast.ToInt(re.Request.URL.Query().Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if hours > 168 { // 最多 7 天
+		hours = 168
+	}
+
+	limit := cast.ToInt(re.Request.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-time.Duration(hours) * time.Hour)
+
+	rows, err := p.repository.GetRouteMetrics(since, until)
+	if err != nil {
+		return re.JSON(http.StatusInternalServerError, map[string]any{
+			"message": "Failed to query route metrics",
+			"error":   err.Error(),
+		})
+	}
+
+	summaries := SummarizeRouteMetrics(rows)
+
+	from, _ := types.ParseDateTime(since)
+	to, _ := types.ParseDateTime(until)
+
+	return re.JSON(http.StatusOK, &RouteMetricsResponse{
+		From:       from,
+		To:         to,
+		Slowest:    slowestRoutes(summaries, limit),
+		MostErrors: mostErrorRoutes(summaries, limit),
+	})
+}
+
+// prometheusHandler 输出注册表中所有插件的指标
+func (p *metricsPlugin) prometheusHandler(re *core.RequestEvent) error {
+	if !p.hasPrometheusToken(re.Request) {
+		if re.Auth == nil {
+			return re.UnauthorizedError("The request requires a valid metrics token or superuser authorization token.", nil)
+		}
+		if !re.HasSuperuserAuth() {
+			return re.ForbiddenError("Only superusers can access this endpoint.", nil)
+		}
+	}
+
+	openMetrics := acceptsOpenMetrics(re.Request.Header.Get("Accept"))
+	if openMetrics {
+		re.Response.Header().Set("Content-Type", OpenMetricsContentType)
+	} else {
+		re.Response.Header().Set("Content-Type", PrometheusTextContentType)
+	}
+	re.Response.WriteHeader(http.StatusOK)
+
+	return GetRegistry(p.app).WriteText(re.Response, openMetrics)
+}
+
+// hasPrometheusToken 检查请求是否携带配置的抓取 Token
+func (p *metricsPlugin) hasPrometheusToken(r *http.Request) bool {
+	if p.config.PrometheusToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(p.config.PrometheusToken)) == 1
 }
@@ -0,0 +1,28 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// newTestAppWithMetrics 创建注册了 metrics 插件的测试 App。
+//
+// 测试 App 已完成 Bootstrap，注册后重新 Bootstrap 以初始化 Repository 与 Collector，
+// 随后停止 Collector，避免后台采集写入干扰事件断言。
+func newTestAppWithMetrics(tb testing.TB, config metrics.Config) *tests.TestApp {
+	app, err := tests.NewTestApp()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	metrics.MustRegister(app, config)
+
+	if err := app.Bootstrap(); err != nil {
+		tb.Fatal(err)
+	}
+	metrics.GetCollector(app).Stop()
+
+	return app
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// maxSlowQueryLength 记录的 SQL 语句最大长度
+const maxSlowQueryLength = 2048
+
+// maxSlowQueryParams 每条语句最多记录的字面量数，超出部分以 "..." 表示
+const maxSlowQueryParams = 50
+
+// slowQuery 采集周期内捕获、尚未写入的慢查询
+type slowQuery struct {
+	timestamp types.DateTime
+	statement string // dbx 内联参数后的原始语句，仅用于获取查询计划，不会持久化
+	duration  time.Duration
+	err       error
+}
+
+// slowQueryLog 缓存耗时超过阈值的语句，由 Collector 在每个采集周期写入辅助库
+type slowQueryLog struct {
+	threshold time.Duration
+	mu        sync.Mutex
+	pending   []*slowQuery
+	dropped   int
+}
+
+// newSlowQueryLog 创建慢查询缓存
+func newSlowQueryLog(threshold time.Duration) *slowQueryLog {
+	return &slowQueryLog{threshold: threshold}
+}
+
+// record 记录一条语句，耗时低于阈值或周期内已达到 DefaultSlowQueryBufferSize 时忽略
+func (l *slowQueryLog) record(statement string, duration time.Duration, err error) {
+	if duration < l.threshold || dbutils.SQLOperation(statement) == "EXPLAIN" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.pending) >= DefaultSlowQueryBufferSize {
+		l.dropped++
+		return
+	}
+
+	l.pending = append(l.pending, &slowQuery{
+		timestamp: types.NowDateTime(),
+		statement: statement,
+		duration:  duration,
+		err:       err,
+	})
+}
+
+// take 取出周期内捕获的语句与因缓存已满被忽略的数量
+func (l *slowQueryLog) take() ([]*slowQuery, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pending, dropped := l.pending, l.dropped
+	l.pending, l.dropped = nil, 0
+	return pending, dropped
+}
+
+// flush 取出周期内捕获的语句并通过 EXPLAIN QUERY PLAN 获取查询计划。
+// 查询计划在采集周期中获取而不是在查询回调中，避免占用请求的连接；
+// 同一周期内相同语句只获取一次。
+func (l *slowQueryLog) flush(app core.App) ([]*SlowQuery, int) {
+	pending, dropped := l.take()
+	if len(pending) == 0 {
+		return nil, dropped
+	}
+
+	type plan struct {
+		steps []QueryPlanStep
+		err   error
+	}
+	plans := make(map[string]*plan, len(pending))
+
+	result := make([]*SlowQuery, 0, len(pending))
+	for _, q := range pending {
+		p, ok := plans[q.statement]
+		if !ok {
+			p = &plan{}
+			p.steps, p.err = explainQueryPlan(app, q.statement)
+			plans[q.statement] = p
+		}
+
+		normalized, params := dbutils.NormalizeSQL(q.statement, maxSlowQueryLength, maxSlowQueryParams)
+
+		m := &SlowQuery{
+			Timestamp:  q.timestamp,
+			SQL:        normalized,
+			Params:     params,
+			DurationMs: float64(q.duration.Microseconds()) / 1000,
+			Plan:       p.steps,
+		}
+		m.Id = security.RandomString(15)
+		if q.err != nil {
+			m.Error = q.err.Error()
+		}
+		if p.err != nil {
+			m.PlanError = p.err.Error()
+		}
+
+		result = append(result, m)
+	}
+
+	return result, dropped
+}
+
+// instrumentSlowQueries 为主数据库的 dbx 连接注册查询回调，记录耗时超过阈值的语句。
+// 已有的 QueryLogFunc/ExecLogFunc（如开发模式下的 SQL 日志、trace 插件）会被保留。
+// 辅助库（日志、监控数据）不做拦截，写入慢查询本身也因此不会被记录。
+func instrumentSlowQueries(app core.App, collector *MetricsCollector) {
+	seen := make(map[*dbx.DB]struct{}, 2)
+	for _, builder := range []dbx.Builder{
+		app.ConcurrentDB(),
+		app.NonconcurrentDB(),
+	} {
+		db, ok := builder.(*dbx.DB)
+		if !ok || db == nil {
+			continue
+		}
+		if _, ok := seen[db]; ok {
+			continue
+		}
+		seen[db] = struct{}{}
+
+		prevQuery := db.QueryLogFunc
+		db.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+			if prevQuery != nil {
+				prevQuery(ctx, t, sql, rows, err)
+			}
+			collector.RecordQuery(sql, t, err)
+		}
+
+		prevExec := db.ExecLogFunc
+		db.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+			if prevExec != nil {
+				prevExec(ctx, t, sql, result, err)
+			}
+			collector.RecordQuery(sql, t, err)
+		}
+	}
+}
+
+// explainQueryPlan 获取语句的查询计划，仅支持 SELECT/WITH/INSERT/UPDATE/DELETE/REPLACE
+func explainQueryPlan(app core.App, statement string) ([]QueryPlanStep, error) {
+	switch dbutils.SQLOperation(statement) {
+	case "SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "REPLACE":
+	default:
+		return nil, nil
+	}
+
+	// dbx 将 nil 参数内联为 <nil>
+	statement = strings.ReplaceAll(statement, "<nil>", "NULL")
+
+	var steps []QueryPlanStep
+	err := app.ConcurrentDB().NewQuery("EXPLAIN QUERY PLAN " + statement).All(&steps)
+
+	return steps, err
+}
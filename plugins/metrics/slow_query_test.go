@@ -0,0 +1,159 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/plugins/metrics"
+	"github.com/pocketbase/pocketbase/tests"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestSlowQueriesCollected(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	repo := metrics.NewMetricsRepository(app)
+	config := metrics.DefaultConfig()
+	config.CollectionInterval = time.Hour // 只依赖启动时的立即采集
+	config.SlowQueryThreshold = 100 * time.Millisecond
+	collector := metrics.NewMetricsCollector(app, repo, config)
+
+	collector.RecordQuery("SELECT `id` FROM `demo1` WHERE `text` = 'it''s' AND `number` > 5 AND `id` != 0x6162 LIMIT 10", 2*time.Second, nil)
+	collector.RecordQuery("UPDATE `missing` SET `value` = 1.5 WHERE `id` = <nil>", 150*time.Millisecond, errors.New("no such table: missing"))
+	collector.RecordQuery("SELECT 1", 10*time.Millisecond, nil) // 低于阈值
+
+	collector.Start()
+	time.Sleep(200 * time.Millisecond)
+	collector.Stop()
+
+	items, err := repo.GetSlowQueries(time.Now().Add(-time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 slow queries, got %d", len(items))
+	}
+
+	var selectQuery, updateQuery *metrics.SlowQuery
+	for _, item := range items {
+		if strings.HasPrefix(item.SQL, "SELECT") {
+			selectQuery = item
+		} else {
+			updateQuery = item
+		}
+	}
+	if selectQuery == nil || updateQuery == nil {
+		t.Fatalf("unexpected slow queries %+v", items)
+	}
+
+	if selectQuery.SQL != "SELECT `id` FROM `demo1` WHERE `text` = ? AND `number` > ? AND `id` != ? LIMIT ?" {
+		t.Errorf("unexpected normalized sql %q", selectQuery.SQL)
+	}
+	if strings.Join(selectQuery.Params, ",") != "text(4),integer,blob(2),integer" {
+		t.Errorf("unexpected params %v", selectQuery.Params)
+	}
+	if selectQuery.DurationMs != 2000 {
+		t.Errorf("expected duration 2000ms, got %v", selectQuery.DurationMs)
+	}
+	if len(selectQuery.Plan) == 0 || !strings.Contains(selectQuery.Plan[0].Detail, "demo1") || selectQuery.PlanError != "" {
+		t.Errorf("unexpected plan %+v (%s)", selectQuery.Plan, selectQuery.PlanError)
+	}
+
+	if strings.Join(updateQuery.Params, ",") != "real,null" {
+		t.Errorf("unexpected params %v", updateQuery.Params)
+	}
+	if updateQuery.Error != "no such table: missing" {
+		t.Errorf("unexpected error %q", updateQuery.Error)
+	}
+	if len(updateQuery.Plan) != 0 || updateQuery.PlanError == "" {
+		t.Errorf("expected plan error, got %+v", updateQuery.Plan)
+	}
+}
+
+func TestSlowQueriesInstrumentedOnBootstrap(t *testing.T) {
+	app := newTestAppWithMetrics(t, metrics.Config{
+		CollectionInterval: time.Hour,
+		SlowQueryThreshold: time.Nanosecond, // 记录所有语句
+	})
+	defer app.Cleanup()
+
+	collector := metrics.GetCollector(app)
+
+	var ids []string
+	err := app.DB().Select("id").From("demo1").
+		Where(dbx.NewExp("[[text]] = {:text}", dbx.Params{"text": "__slow__"})).
+		Column(&ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector.Start()
+	time.Sleep(200 * time.Millisecond)
+	collector.Stop()
+
+	items, err := metrics.GetRepository(app).GetSlowQueries(time.Now().Add(-time.Hour), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range items {
+		// dbx 回调中的语句保留 [[column]] 等引用标记，记录时被去除
+		if item.SQL == "SELECT `id` FROM `demo1` WHERE text = ?" {
+			if len(item.Params) != 1 || item.Params[0] != "text(8)" {
+				t.Errorf("unexpected params %v", item.Params)
+			}
+			if len(item.Plan) == 0 {
+				t.Errorf("expected query plan, got %q", item.PlanError)
+			}
+			return
+		}
+	}
+
+	t.Fatalf("expected the demo1 query to be recorded, got %d other items", len(items))
+}
+
+func TestDatabaseStatsSlowQueryLog(t *testing.T) {
+	t.Parallel()
+
+	scenario := tests.ApiScenario{
+		Name:           "sqlite diagnostics",
+		Method:         http.MethodGet,
+		URL:            "/api/system/metrics/database?limit=5",
+		Headers:        map[string]string{"Authorization": testSuperuserToken},
+		ExpectedStatus: 200,
+		ExpectedContent: []string{
+			`"type":"sqlite"`,
+			`"page_cache":{"page_size":`,
+			`"checkpoints":[{"database":"data"`,
+			`"database":"auxiliary"`,
+			`"slow_query_log":{"enabled":true,"threshold_ms":250,"items":[{`,
+			`"sql":"SELECT * FROM ` + "`demo1`" + ` WHERE ` + "`id`" + ` = ?"`,
+			`"params":["text(15)"]`,
+			`"plan":[{"id":`,
+		},
+		ExpectedEvents: map[string]int{"*": 0},
+		TestAppFactory: func(tb testing.TB) *tests.TestApp {
+			app := newTestAppWithMetrics(tb, metrics.Config{SlowQueryThreshold: 250 * time.Millisecond})
+
+			item := &metrics.SlowQuery{
+				Timestamp:  types.NowDateTime(),
+				SQL:        "SELECT * FROM `demo1` WHERE `id` = ?",
+				Params:     types.JSONArray[string]{"text(15)"},
+				DurationMs: 320,
+				Plan:       types.JSONArray[metrics.QueryPlanStep]{{Id: 2, Detail: "SEARCH demo1 USING INDEX sqlite_autoindex_demo1_1 (id=?)"}},
+			}
+			if err := metrics.GetRepository(app).InsertSlowQueries([]*metrics.SlowQuery{item}); err != nil {
+				tb.Fatal(err)
+			}
+
+			return app
+		},
+	}
+
+	scenario.Test(t)
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/pocketbase/pocketbase/plugins/trace/dye"
 )
 
 // Config 定义 trace 插件配置
@@ -33,6 +35,27 @@ type Config struct {
 	DyeMaxUsers int
 	// DyeDefaultTTL 默认染色 TTL
 	DyeDefaultTTL time.Duration
+	// DyeStorage 染色存储方式（memory/db），默认 memory
+	DyeStorage DyeStorage
+	// DyeSyncInterval db 存储从数据库同步其他节点变更的间隔，默认 5s
+	DyeSyncInterval time.Duration
+
+	// 导出相关配置
+	// ExportMode 完成的 Span 的输出方式（store/otlp/both），默认 store
+	ExportMode ExportMode
+	// Repository 本地 Span 存储（store/both 模式使用，为空时不写入本地）
+	Repository TraceRepository
+	// OTLP OTLP/HTTP 导出配置（otlp/both 模式使用）
+	OTLP OTLPConfig
+
+	// DisableAutoSpans 关闭数据库查询、hook 处理器与文件上传的自动子 Span
+	DisableAutoSpans bool
+
+	// TailSampling 尾部采样配置
+	TailSampling TailSamplingConfig
+
+	// Stats RED 指标与服务依赖图的聚合配置
+	Stats StatsConfig
 }
 
 // DefaultConfig 返回默认配置
@@ -47,6 +70,8 @@ func DefaultConfig() Config {
 		DebugLevel:    false,
 		DyeMaxUsers:   100,
 		DyeDefaultTTL: time.Hour,
+		DyeStorage:    DyeStorageMemory,
+		ExportMode:    ExportModeStore,
 	}
 }
 
@@ -76,6 +101,33 @@ func applyDefaults(c Config) Config {
 	if c.DyeDefaultTTL <= 0 {
 		c.DyeDefaultTTL = time.Hour
 	}
+	if c.DyeStorage == "" {
+		c.DyeStorage = DyeStorageMemory
+	}
+	if c.DyeSyncInterval <= 0 {
+		c.DyeSyncInterval = dye.DefaultSyncInterval
+	}
+	if c.ExportMode == "" {
+		c.ExportMode = ExportModeStore
+	}
+	if c.TailSampling.MaxWait <= 0 {
+		c.TailSampling.MaxWait = 30 * time.Second
+	}
+	if c.TailSampling.MaxSpans <= 0 {
+		c.TailSampling.MaxSpans = 50000
+	}
+	if c.TailSampling.DecisionTTL <= 0 {
+		c.TailSampling.DecisionTTL = time.Minute
+	}
+	if c.Stats.BucketSize <= 0 {
+		c.Stats.BucketSize = time.Minute
+	}
+	if c.Stats.Retention <= 0 {
+		c.Stats.Retention = 24 * time.Hour
+	}
+	if c.Stats.MaxSeries <= 0 {
+		c.Stats.MaxSeries = 1000
+	}
 	return c
 }
 
@@ -138,5 +190,119 @@ func applyEnvOverrides(c Config) Config {
 		}
 	}
 
+	// PB_TRACE_DYE_STORAGE (memory 或 db)
+	if storage := os.Getenv("PB_TRACE_DYE_STORAGE"); storage != "" {
+		c.DyeStorage = DyeStorage(storage)
+	}
+
+	// PB_TRACE_DYE_SYNC_INTERVAL (格式: "5s", "1m")
+	if interval := os.Getenv("PB_TRACE_DYE_SYNC_INTERVAL"); interval != "" {
+		if d, err := time.ParseDuration(interval); err == nil {
+			c.DyeSyncInterval = d
+		}
+	}
+
+	// 导出相关环境变量
+	// PB_TRACE_EXPORT_MODE
+	if mode := os.Getenv("PB_TRACE_EXPORT_MODE"); mode != "" {
+		c.ExportMode = ExportMode(mode)
+	}
+
+	// PB_TRACE_OTLP_ENDPOINT
+	if endpoint := os.Getenv("PB_TRACE_OTLP_ENDPOINT"); endpoint != "" {
+		c.OTLP.Endpoint = endpoint
+	}
+
+	// PB_TRACE_OTLP_PROTOCOL (http/protobuf 或 http/json)
+	if protocol := os.Getenv("PB_TRACE_OTLP_PROTOCOL"); protocol != "" {
+		c.OTLP.Protocol = protocol
+	}
+
+	// PB_TRACE_OTLP_HEADERS (格式: "key1=value1,key2=value2")
+	if headers := os.Getenv("PB_TRACE_OTLP_HEADERS"); headers != "" {
+		c.OTLP.Headers = parseOTLPHeaders(headers)
+	}
+
+	// PB_TRACE_SERVICE_NAME
+	if name := os.Getenv("PB_TRACE_SERVICE_NAME"); name != "" {
+		c.OTLP.ServiceName = name
+	}
+
+	// PB_TRACE_SERVICE_VERSION
+	if version := os.Getenv("PB_TRACE_SERVICE_VERSION"); version != "" {
+		c.OTLP.ServiceVersion = version
+	}
+
+	// PB_TRACE_DISABLE_AUTO_SPANS
+	if disable := os.Getenv("PB_TRACE_DISABLE_AUTO_SPANS"); disable != "" {
+		c.DisableAutoSpans = strings.ToLower(disable) == "true" || disable == "1"
+	}
+
+	// PB_TRACE_NODE_ID
+	if nodeID := os.Getenv("PB_TRACE_NODE_ID"); nodeID != "" {
+		c.OTLP.NodeID = nodeID
+	}
+
+	// 尾部采样相关环境变量
+	// PB_TRACE_TAIL_SAMPLING
+	if enabled := os.Getenv("PB_TRACE_TAIL_SAMPLING"); enabled != "" {
+		c.TailSampling.Enabled = strings.ToLower(enabled) == "true" || enabled == "1"
+	}
+
+	// PB_TRACE_TAIL_MAX_WAIT (格式: "30s", "1m")
+	if wait := os.Getenv("PB_TRACE_TAIL_MAX_WAIT"); wait != "" {
+		if d, err := time.ParseDuration(wait); err == nil {
+			c.TailSampling.MaxWait = d
+		}
+	}
+
+	// PB_TRACE_TAIL_MAX_SPANS
+	if maxSpans := os.Getenv("PB_TRACE_TAIL_MAX_SPANS"); maxSpans != "" {
+		if m, err := strconv.Atoi(maxSpans); err == nil {
+			c.TailSampling.MaxSpans = m
+		}
+	}
+
+	// 以下变量追加对应的策略，并同时启用出错追踪策略
+	var envPolicies []TailPolicy
+
+	// PB_TRACE_TAIL_LATENCY (格式: "500ms", "2s")
+	if latency := os.Getenv("PB_TRACE_TAIL_LATENCY"); latency != "" {
+		if d, err := time.ParseDuration(latency); err == nil {
+			envPolicies = append(envPolicies, LatencyTailPolicy(d))
+		}
+	}
+
+	// PB_TRACE_TAIL_BASELINE_RATE (0.0-1.0)
+	if rate := os.Getenv("PB_TRACE_TAIL_BASELINE_RATE"); rate != "" {
+		if r, err := strconv.ParseFloat(rate, 64); err == nil {
+			envPolicies = append(envPolicies, ProbabilisticTailPolicy(r))
+		}
+	}
+
+	// PB_TRACE_TAIL_ROUTE_RATE (每个路由每秒保留的追踪数)
+	if rate := os.Getenv("PB_TRACE_TAIL_ROUTE_RATE"); rate != "" {
+		if r, err := strconv.ParseFloat(rate, 64); err == nil {
+			envPolicies = append(envPolicies, RateLimitTailPolicy(r))
+		}
+	}
+
+	if len(envPolicies) > 0 {
+		c.TailSampling.Policies = append(append(c.TailSampling.Policies, ErrorTailPolicy()), envPolicies...)
+	}
+
+	// 聚合统计相关环境变量
+	// PB_TRACE_DISABLE_STATS
+	if disable := os.Getenv("PB_TRACE_DISABLE_STATS"); disable != "" {
+		c.Stats.Disabled = strings.ToLower(disable) == "true" || disable == "1"
+	}
+
+	// PB_TRACE_STATS_RETENTION (格式: "24h", "6h")
+	if retention := os.Getenv("PB_TRACE_STATS_RETENTION"); retention != "" {
+		if d, err := time.ParseDuration(retention); err == nil {
+			c.Stats.Retention = d
+		}
+	}
+
 	return c
 }
@@ -2,6 +2,7 @@ package trace
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -285,4 +286,135 @@ func TestDyeConfig(t *testing.T) {
 			t.Errorf("DyeDefaultTTL should be 2h, got %v", config.DyeDefaultTTL)
 		}
 	})
+
+	t.Run("dye storage", func(t *testing.T) {
+		if config := applyDefaults(Config{}); config.DyeStorage != DyeStorageMemory || config.DyeSyncInterval != 5*time.Second {
+			t.Errorf("unexpected dye storage defaults %s/%v", config.DyeStorage, config.DyeSyncInterval)
+		}
+
+		t.Setenv("PB_TRACE_DYE_STORAGE", "db")
+		t.Setenv("PB_TRACE_DYE_SYNC_INTERVAL", "30s")
+
+		config := applyEnvOverrides(Config{})
+		if config.DyeStorage != DyeStorageDB || config.DyeSyncInterval != 30*time.Second {
+			t.Errorf("unexpected dye storage config %s/%v", config.DyeStorage, config.DyeSyncInterval)
+		}
+	})
+}
+
+func TestExportConfig(t *testing.T) {
+	// 保存原始环境变量
+	originalVars := map[string]string{
+		"PB_TRACE_EXPORT_MODE":     os.Getenv("PB_TRACE_EXPORT_MODE"),
+		"PB_TRACE_OTLP_ENDPOINT":   os.Getenv("PB_TRACE_OTLP_ENDPOINT"),
+		"PB_TRACE_OTLP_PROTOCOL":   os.Getenv("PB_TRACE_OTLP_PROTOCOL"),
+		"PB_TRACE_OTLP_HEADERS":    os.Getenv("PB_TRACE_OTLP_HEADERS"),
+		"PB_TRACE_SERVICE_NAME":    os.Getenv("PB_TRACE_SERVICE_NAME"),
+		"PB_TRACE_SERVICE_VERSION": os.Getenv("PB_TRACE_SERVICE_VERSION"),
+		"PB_TRACE_NODE_ID":         os.Getenv("PB_TRACE_NODE_ID"),
+	}
+
+	cleanup := func() {
+		for key, val := range originalVars {
+			if val == "" {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, val)
+			}
+		}
+	}
+	defer cleanup()
+
+	t.Run("default export mode", func(t *testing.T) {
+		if mode := DefaultConfig().ExportMode; mode != ExportModeStore {
+			t.Errorf("ExportMode should default to store, got %s", mode)
+		}
+		if mode := applyDefaults(Config{}).ExportMode; mode != ExportModeStore {
+			t.Errorf("applyDefaults ExportMode should be store, got %s", mode)
+		}
+	})
+
+	t.Run("export env overrides", func(t *testing.T) {
+		cleanup()
+		os.Setenv("PB_TRACE_EXPORT_MODE", "both")
+		os.Setenv("PB_TRACE_OTLP_ENDPOINT", "http://collector:4318")
+		os.Setenv("PB_TRACE_OTLP_PROTOCOL", "http/json")
+		os.Setenv("PB_TRACE_OTLP_HEADERS", "Authorization=Bearer abc, x-tenant = demo,invalid")
+		os.Setenv("PB_TRACE_SERVICE_NAME", "api")
+		os.Setenv("PB_TRACE_SERVICE_VERSION", "1.0.0")
+		os.Setenv("PB_TRACE_NODE_ID", "node-2")
+
+		config := applyEnvOverrides(Config{})
+		if config.ExportMode != ExportModeBoth {
+			t.Errorf("ExportMode should be both, got %s", config.ExportMode)
+		}
+		if config.OTLP.Endpoint != "http://collector:4318" || config.OTLP.Protocol != OTLPProtocolJSON {
+			t.Errorf("unexpected OTLP endpoint/protocol: %s %s", config.OTLP.Endpoint, config.OTLP.Protocol)
+		}
+		if len(config.OTLP.Headers) != 2 || config.OTLP.Headers["Authorization"] != "Bearer abc" || config.OTLP.Headers["x-tenant"] != "demo" {
+			t.Errorf("unexpected OTLP headers: %v", config.OTLP.Headers)
+		}
+		if config.OTLP.ServiceName != "api" || config.OTLP.ServiceVersion != "1.0.0" || config.OTLP.NodeID != "node-2" {
+			t.Errorf("unexpected resource config: %+v", config.OTLP)
+		}
+	})
+}
+
+func TestTailSamplingConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		config := applyDefaults(Config{})
+		if config.TailSampling.Enabled {
+			t.Error("TailSampling should be disabled by default")
+		}
+		if config.TailSampling.MaxWait != 30*time.Second || config.TailSampling.MaxSpans != 50000 ||
+			config.TailSampling.DecisionTTL != time.Minute {
+			t.Errorf("unexpected tail sampling defaults: %+v", config.TailSampling)
+		}
+	})
+
+	t.Run("env overrides", func(t *testing.T) {
+		t.Setenv("PB_TRACE_TAIL_SAMPLING", "true")
+		t.Setenv("PB_TRACE_TAIL_MAX_WAIT", "10s")
+		t.Setenv("PB_TRACE_TAIL_MAX_SPANS", "1000")
+		t.Setenv("PB_TRACE_TAIL_LATENCY", "500ms")
+		t.Setenv("PB_TRACE_TAIL_BASELINE_RATE", "0.1")
+		t.Setenv("PB_TRACE_TAIL_ROUTE_RATE", "2")
+
+		config := applyEnvOverrides(Config{})
+		if !config.TailSampling.Enabled || config.TailSampling.MaxWait != 10*time.Second || config.TailSampling.MaxSpans != 1000 {
+			t.Errorf("unexpected tail sampling config: %+v", config.TailSampling)
+		}
+
+		var names []string
+		for _, policy := range config.TailSampling.Policies {
+			names = append(names, policy.Name())
+		}
+		if strings.Join(names, ",") != "error,latency,probabilistic,rate_limit" {
+			t.Errorf("unexpected policies %v", names)
+		}
+	})
+
+	t.Run("no policy env keeps configured policies", func(t *testing.T) {
+		t.Setenv("PB_TRACE_TAIL_SAMPLING", "1")
+
+		config := applyEnvOverrides(Config{TailSampling: TailSamplingConfig{Policies: []TailPolicy{ErrorTailPolicy()}}})
+		if len(config.TailSampling.Policies) != 1 {
+			t.Errorf("expected configured policies only, got %d", len(config.TailSampling.Policies))
+		}
+	})
+}
+
+func TestStatsConfig(t *testing.T) {
+	config := applyDefaults(Config{})
+	if config.Stats.Disabled || config.Stats.BucketSize != time.Minute || config.Stats.Retention != 24*time.Hour || config.Stats.MaxSeries != 1000 {
+		t.Errorf("unexpected stats defaults %+v", config.Stats)
+	}
+
+	t.Setenv("PB_TRACE_DISABLE_STATS", "true")
+	t.Setenv("PB_TRACE_STATS_RETENTION", "6h")
+
+	config = applyEnvOverrides(Config{})
+	if !config.Stats.Disabled || config.Stats.Retention != 6*time.Hour {
+		t.Errorf("unexpected stats config %+v", config.Stats)
+	}
 }
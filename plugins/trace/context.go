@@ -14,6 +14,7 @@ type contextKey int
 const (
 	traceContextKey contextKey = iota
 	spanContextKey
+	recorderContextKey
 )
 
 // TraceContext 表示 W3C Trace Context 信息
@@ -24,11 +24,40 @@ type DyeListResponse struct {
 }
 
 // AddDyedUserRequest 添加染色用户请求
+//
+// 按用户染色时只需 userId；按其他维度染色时设置 dimension 和 value，
+// header 维度还需要 header 指定请求头名。
 type AddDyedUserRequest struct {
-	UserID  string `json:"userId"`
-	TTL     string `json:"ttl"`     // 格式: "1h", "30m", "24h" 等
-	AddedBy string `json:"addedBy"` // 可选
-	Reason  string `json:"reason"`  // 可选
+	UserID    string    `json:"userId"`
+	Dimension Dimension `json:"dimension"` // 可选: user/ip/header/collection
+	Value     string    `json:"value"`     // dimension 不为 user 时必填
+	Header    string    `json:"header"`    // dimension 为 header 时必填
+	TTL       string    `json:"ttl"`       // 格式: "1h", "30m", "24h" 等
+	AddedBy   string    `json:"addedBy"`   // 可选
+	Reason    string    `json:"reason"`    // 可选
+}
+
+// targetKey 返回请求对应的染色目标键，参数无效时返回错误信息
+func (req *AddDyedUserRequest) targetKey() (string, string) {
+	switch req.Dimension {
+	case "", DimensionUser:
+		if req.UserID == "" {
+			return "", "userId is required"
+		}
+		return req.UserID, ""
+	case DimensionHeader:
+		if req.Header == "" || req.Value == "" {
+			return "", "header and value are required"
+		}
+		return HeaderTargetKey(req.Header, req.Value), ""
+	case DimensionIP, DimensionCollection:
+		if req.Value == "" {
+			return "", "value is required"
+		}
+		return TargetKey(req.Dimension, req.Value), ""
+	default:
+		return "", "Invalid dimension. Use one of 'user', 'ip', 'header', 'collection'"
+	}
 }
 
 // UpdateTTLRequest 更新 TTL 请求
@@ -99,8 +128,9 @@ func (h *DyeAPIHandler) AddDyedUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" {
-		writeError(w, http.StatusBadRequest, "userId is required")
+	key, message := req.targetKey()
+	if message != "" {
+		writeError(w, http.StatusBadRequest, message)
 		return
 	}
 
@@ -116,7 +146,7 @@ func (h *DyeAPIHandler) AddDyedUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 添加用户
-	err := h.store.Add(req.UserID, ttl, req.AddedBy, req.Reason)
+	err := h.store.Add(key, ttl, req.AddedBy, req.Reason)
 	if err != nil {
 		if errors.Is(err, ErrMaxDyedUsersReached) {
 			writeError(w, http.StatusConflict, "Maximum dyed users limit reached")
@@ -127,7 +157,7 @@ func (h *DyeAPIHandler) AddDyedUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 获取添加后的用户信息
-	user, _ := h.store.Get(req.UserID)
+	user, _ := h.store.Get(key)
 	writeJSON(w, http.StatusCreated, user)
 }
 
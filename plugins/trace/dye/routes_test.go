@@ -157,6 +157,48 @@ func TestDyeAPIHandler_AddDyedUser(t *testing.T) {
 		}
 	})
 
+	t.Run("adds other dimensions", func(t *testing.T) {
+		store := NewMemoryDyeStore(100, time.Hour)
+		defer store.Close()
+
+		handler := NewDyeAPIHandler(store)
+
+		scenarios := []struct {
+			body     AddDyedUserRequest
+			expected int
+			key      string
+		}{
+			{AddDyedUserRequest{Dimension: DimensionHeader, Header: "x-debug-session", Value: "abc"}, http.StatusCreated, "header:X-Debug-Session=abc"},
+			{AddDyedUserRequest{Dimension: DimensionIP, Value: "10.0.0.1"}, http.StatusCreated, "ip:10.0.0.1"},
+			{AddDyedUserRequest{Dimension: DimensionCollection, Value: "admins"}, http.StatusCreated, "collection:admins"},
+			{AddDyedUserRequest{Dimension: DimensionHeader, Value: "abc"}, http.StatusBadRequest, ""},
+			{AddDyedUserRequest{Dimension: DimensionIP}, http.StatusBadRequest, ""},
+			{AddDyedUserRequest{Dimension: "unknown", Value: "x"}, http.StatusBadRequest, ""},
+		}
+
+		for i, s := range scenarios {
+			jsonBody, _ := json.Marshal(s.body)
+			req := httptest.NewRequest("POST", "/api/_/trace/dyed-users", bytes.NewReader(jsonBody))
+			rec := httptest.NewRecorder()
+
+			handler.AddDyedUser(rec, req)
+
+			if rec.Code != s.expected {
+				t.Errorf("(%d) expected status %d, got %d", i, s.expected, rec.Code)
+				continue
+			}
+			if s.key == "" {
+				continue
+			}
+
+			var user DyedUser
+			json.Unmarshal(rec.Body.Bytes(), &user)
+			if user.UserID != s.key || user.Dimension != s.body.Dimension {
+				t.Errorf("(%d) expected %s entry %s, got %+v", i, s.body.Dimension, s.key, user)
+			}
+		}
+	})
+
 	t.Run("returns 400 for invalid TTL format", func(t *testing.T) {
 		store := NewMemoryDyeStore(100, time.Hour)
 		defer store.Close()
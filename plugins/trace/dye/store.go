@@ -10,11 +10,17 @@ import (
 var (
 	ErrMaxDyedUsersReached = errors.New("max dyed users limit reached")
 	ErrDyedUserNotFound    = errors.New("dyed user not found")
+	ErrDyeStoreNotStarted  = errors.New("dye store not started")
 )
 
 // DyedUser 染色用户信息
+//
+// UserID 为染色目标键（见 TargetKey），按用户染色时即用户 ID，
+// 其他维度时为带维度前缀的键，Dimension 与 Value 为解析后的维度与值。
 type DyedUser struct {
 	UserID    string        `json:"userId"`
+	Dimension Dimension     `json:"dimension"`
+	Value     string        `json:"value"`
 	AddedAt   time.Time     `json:"addedAt"`
 	ExpiresAt time.Time     `json:"expiresAt"`
 	TTL       time.Duration `json:"ttl"`
@@ -24,7 +30,7 @@ type DyedUser struct {
 
 // DyeStore 染色存储接口
 type DyeStore interface {
-	// Add 添加染色用户
+	// Add 添加染色用户，userID 可以是其他维度的目标键（见 TargetKey）
 	Add(userID string, ttl time.Duration, addedBy, reason string) error
 	// Remove 移除染色用户
 	Remove(userID string) error
@@ -36,6 +42,8 @@ type DyeStore interface {
 	List() []DyedUser
 	// UpdateTTL 更新染色 TTL
 	UpdateTTL(userID string, ttl time.Duration) error
+	// Match 返回与请求特征匹配的染色条目（任一维度命中即可）
+	Match(subject Subject) (*DyedUser, bool)
 	// Count 获取染色用户数量
 	Count() int
 	// Close 关闭存储（清理资源）
@@ -0,0 +1,452 @@
+package dye
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// DefaultSyncInterval DBDyeStore 默认的快照同步间隔
+const DefaultSyncInterval = 5 * time.Second
+
+// dyePruneInterval 清理数据库中过期条目的最小间隔
+const dyePruneInterval = time.Minute
+
+// DBDyeStore 基于 App 数据库（SQLite/PostgreSQL）的染色存储。
+//
+// 染色目标保存在 _trace_dyes 表中，重启后保留，并对共享同一数据库的所有节点可见。
+// 每个节点在内存中维护一份快照供请求路径匹配：本节点的写操作立即生效，
+// 其他节点的变更最迟在一个同步间隔后生效。
+// 同步只读取数据库，快照中存在过期条目时才清理（最多每分钟一次），
+// 避免 SQLite 上每次同步都获取写锁。
+//
+// 数量上限在写入时按数据库中未过期的条目数检查，多个节点同时添加时可能短暂超出。
+type DBDyeStore struct {
+	app          core.App
+	maxUsers     int
+	defaultTTL   time.Duration
+	syncInterval time.Duration
+
+	mu      sync.RWMutex
+	index   dyeIndex
+	started bool
+
+	// syncMu 串行化 Sync；localWrites 记录同步期间本节点的写操作
+	// （nil 值表示删除），替换快照时合并，避免被读取时刻的旧数据覆盖
+	syncMu      sync.Mutex
+	localWrites map[string]*DyedUser
+	lastPrune   time.Time
+
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	closeOnce sync.Once
+}
+
+// dyeRow _trace_dyes 表的一行，时间与 TTL 以毫秒存储
+type dyeRow struct {
+	Key       string `db:"key"`
+	Dimension string `db:"dimension"`
+	AddedAt   int64  `db:"added_at"`
+	ExpiresAt int64  `db:"expires_at"`
+	TTL       int64  `db:"ttl"`
+	AddedBy   string `db:"added_by"`
+	Reason    string `db:"reason"`
+}
+
+// NewDBDyeStore 创建数据库染色存储。
+// 创建时不访问数据库，需要在 App 引导完成后调用 Start。
+func NewDBDyeStore(app core.App, maxUsers int, defaultTTL, syncInterval time.Duration) *DBDyeStore {
+	if maxUsers <= 0 {
+		maxUsers = 100
+	}
+	if defaultTTL <= 0 {
+		defaultTTL = time.Hour
+	}
+	if syncInterval <= 0 {
+		syncInterval = DefaultSyncInterval
+	}
+
+	return &DBDyeStore{
+		app:          app,
+		maxUsers:     maxUsers,
+		defaultTTL:   defaultTTL,
+		syncInterval: syncInterval,
+		index:        newDyeIndex(),
+		stopCh:       make(chan struct{}),
+		doneCh:       make(chan struct{}),
+	}
+}
+
+// Start 创建染色表、加载快照并启动定时同步。
+// 必须在 App 引导（数据库连接初始化）之后调用，重复调用无效。
+func (s *DBDyeStore) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	if err := createDyeTable(s.app); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	go s.syncLoop()
+
+	return s.Sync()
+}
+
+// createDyeTable 创建 _trace_dyes 表
+func createDyeTable(app core.App) error {
+	_, err := app.DB().NewQuery(`
+		CREATE TABLE IF NOT EXISTS _trace_dyes (
+			key TEXT PRIMARY KEY,
+			dimension TEXT NOT NULL,
+			added_at BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			ttl BIGINT NOT NULL,
+			added_by TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_trace_dyes_expires_at ON _trace_dyes (expires_at);
+	`).Execute()
+	return err
+}
+
+// isStarted 返回 Start 是否已完成
+func (s *DBDyeStore) isStarted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.started
+}
+
+// Add 添加染色用户（或其他维度的染色目标）
+func (s *DBDyeStore) Add(userID string, ttl time.Duration, addedBy, reason string) error {
+	if !s.isStarted() {
+		return ErrDyeStoreNotStarted
+	}
+
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+	entry := newDyedUser(userID, ttl, addedBy, reason, time.Now())
+
+	err := s.app.RunInTransaction(func(txApp core.App) error {
+		// 检查数量上限（不计入被覆盖的同名条目）
+		var count int
+		err := txApp.DB().NewQuery(`
+			SELECT COUNT(*) FROM _trace_dyes WHERE expires_at > {:now} AND key != {:key}
+		`).Bind(dbx.Params{
+			"now": entry.AddedAt.UnixMilli(),
+			"key": userID,
+		}).Row(&count)
+		if err != nil {
+			return err
+		}
+		if count >= s.maxUsers {
+			return ErrMaxDyedUsersReached
+		}
+
+		_, err = txApp.DB().NewQuery(`
+			INSERT INTO _trace_dyes (key, dimension, added_at, expires_at, ttl, added_by, reason)
+			VALUES ({:key}, {:dimension}, {:addedAt}, {:expiresAt}, {:ttl}, {:addedBy}, {:reason})
+			ON CONFLICT (key) DO UPDATE
+			SET dimension = EXCLUDED.dimension, added_at = EXCLUDED.added_at,
+				expires_at = EXCLUDED.expires_at, ttl = EXCLUDED.ttl,
+				added_by = EXCLUDED.added_by, reason = EXCLUDED.reason
+		`).Bind(dbx.Params{
+			"key":       userID,
+			"dimension": string(entry.Dimension),
+			"addedAt":   entry.AddedAt.UnixMilli(),
+			"expiresAt": entry.ExpiresAt.UnixMilli(),
+			"ttl":       ttl.Milliseconds(),
+			"addedBy":   addedBy,
+			"reason":    reason,
+		}).Execute()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.setLocal(entry)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Remove 移除染色用户
+func (s *DBDyeStore) Remove(userID string) error {
+	if !s.isStarted() {
+		return ErrDyeStoreNotStarted
+	}
+
+	_, err := s.app.DB().NewQuery("DELETE FROM _trace_dyes WHERE key = {:key}").
+		Bind(dbx.Params{"key": userID}).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.removeLocal(userID)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// IsDyed 检查用户是否被染色（读取本地快照）
+func (s *DBDyeStore) IsDyed(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, dyed := s.index.get(userID, time.Now())
+	return dyed
+}
+
+// Get 获取染色用户信息（读取本地快照）
+func (s *DBDyeStore) Get(userID string) (*DyedUser, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.index.get(userID, time.Now())
+	if !exists {
+		return nil, false
+	}
+	copy := *user
+	return &copy, true
+}
+
+// Match 返回与请求特征匹配的染色条目（读取本地快照）
+func (s *DBDyeStore) Match(subject Subject) (*DyedUser, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, matched := s.index.match(subject, time.Now())
+	if !matched {
+		return nil, false
+	}
+	copy := *user
+	return &copy, true
+}
+
+// List 获取所有未过期的染色用户（读取本地快照）
+func (s *DBDyeStore) List() []DyedUser {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]DyedUser, 0, len(s.index.entries))
+	for _, user := range s.index.entries {
+		if now.Before(user.ExpiresAt) {
+			result = append(result, *user)
+		}
+	}
+	return result
+}
+
+// UpdateTTL 更新染色 TTL，过期时间从当前时间重新计算
+func (s *DBDyeStore) UpdateTTL(userID string, ttl time.Duration) error {
+	if !s.isStarted() {
+		return ErrDyeStoreNotStarted
+	}
+
+	now := time.Now()
+	result, err := s.app.DB().NewQuery(`
+		UPDATE _trace_dyes SET ttl = {:ttl}, expires_at = {:expiresAt}
+		WHERE key = {:key} AND expires_at > {:now}
+	`).Bind(dbx.Params{
+		"key":       userID,
+		"ttl":       ttl.Milliseconds(),
+		"expiresAt": now.Add(ttl).UnixMilli(),
+		"now":       now.UnixMilli(),
+	}).Execute()
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrDyedUserNotFound
+	}
+
+	return s.reload(userID)
+}
+
+// Count 获取未过期的染色用户数量（读取本地快照）
+func (s *DBDyeStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	count := 0
+	for _, user := range s.index.entries {
+		if now.Before(user.ExpiresAt) {
+			count++
+		}
+	}
+	return count
+}
+
+// Close 停止定时同步，不关闭数据库连接
+func (s *DBDyeStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		if s.isStarted() {
+			<-s.doneCh
+		}
+	})
+	return nil
+}
+
+// Sync 从数据库重新加载本地快照，快照中存在过期条目时清理数据库
+func (s *DBDyeStore) Sync() error {
+	if !s.isStarted() {
+		return ErrDyeStoreNotStarted
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	s.trackLocalWrites()
+
+	now := time.Now()
+
+	var rows []dyeRow
+	err := s.app.DB().NewQuery("SELECT * FROM _trace_dyes").All(&rows)
+	if err != nil {
+		s.mu.Lock()
+		s.localWrites = nil
+		s.mu.Unlock()
+		return err
+	}
+
+	if !s.replaceSnapshot(rows, now) || now.Sub(s.lastPrune) < dyePruneInterval {
+		return nil
+	}
+
+	s.lastPrune = now
+	_, err = s.app.DB().NewQuery("DELETE FROM _trace_dyes WHERE expires_at <= {:now}").
+		Bind(dbx.Params{"now": now.UnixMilli()}).
+		Execute()
+	return err
+}
+
+// trackLocalWrites 开始记录本节点的写操作，在读取数据库快照之前调用
+func (s *DBDyeStore) trackLocalWrites() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.localWrites = make(map[string]*DyedUser)
+}
+
+// replaceSnapshot 用读取到的数据库行替换本地快照，并合并读取之后本节点的写操作。
+// 返回读取到的行中是否存在过期条目。
+func (s *DBDyeStore) replaceSnapshot(rows []dyeRow, now time.Time) bool {
+	index := newDyeIndex()
+	hasExpired := false
+	for _, row := range rows {
+		if row.ExpiresAt <= now.UnixMilli() {
+			hasExpired = true
+			continue
+		}
+		index.set(row.toDyedUser())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range s.localWrites {
+		if entry == nil {
+			index.remove(key)
+		} else {
+			index.set(entry)
+		}
+	}
+	s.localWrites = nil
+	s.index = index
+
+	return hasExpired
+}
+
+// setLocal 将本节点写入的条目更新到快照（调用方需持有写锁）
+func (s *DBDyeStore) setLocal(entry *DyedUser) {
+	s.index.set(entry)
+	if s.localWrites != nil {
+		s.localWrites[entry.UserID] = entry
+	}
+}
+
+// removeLocal 将本节点删除的条目从快照移除（调用方需持有写锁）
+func (s *DBDyeStore) removeLocal(key string) {
+	s.index.remove(key)
+	if s.localWrites != nil {
+		s.localWrites[key] = nil
+	}
+}
+
+// reload 从数据库重新加载单个条目到本地快照
+func (s *DBDyeStore) reload(userID string) error {
+	var rows []dyeRow
+	err := s.app.DB().NewQuery("SELECT * FROM _trace_dyes WHERE key = {:key}").
+		Bind(dbx.Params{"key": userID}).
+		All(&rows)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(rows) == 0 {
+		s.removeLocal(userID)
+	} else {
+		s.setLocal(rows[0].toDyedUser())
+	}
+	return nil
+}
+
+// syncLoop 定时同步其他节点的变更
+func (s *DBDyeStore) syncLoop() {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(s.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Sync(); err != nil {
+				s.app.Logger().Warn("Trace dye store sync failed", "error", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// toDyedUser 将数据库行转换为染色条目
+func (r dyeRow) toDyedUser() *DyedUser {
+	_, value := ParseTargetKey(r.Key)
+	return &DyedUser{
+		UserID:    r.Key,
+		Dimension: Dimension(r.Dimension),
+		Value:     value,
+		AddedAt:   time.UnixMilli(r.AddedAt),
+		ExpiresAt: time.UnixMilli(r.ExpiresAt),
+		TTL:       time.Duration(r.TTL) * time.Millisecond,
+		AddedBy:   r.AddedBy,
+		Reason:    r.Reason,
+	}
+}
+
+// 确保实现了接口
+var _ DyeStore = (*DBDyeStore)(nil)
@@ -0,0 +1,81 @@
+package dye
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestDBDyeStoreSyncKeepsLocalWrites(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+
+	store := NewDBDyeStore(app, 10, time.Hour, time.Hour)
+	defer store.Close()
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("removed", time.Hour, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟读取数据库快照之后、替换本地快照之前本节点的写操作
+	store.trackLocalWrites()
+	var rows []dyeRow
+	if err := app.DB().NewQuery("SELECT * FROM _trace_dyes").All(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("added", time.Hour, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Remove("removed"); err != nil {
+		t.Fatal(err)
+	}
+	store.replaceSnapshot(rows, time.Now())
+
+	if !store.IsDyed("added") {
+		t.Error("expected the entry added during sync to stay in the snapshot")
+	}
+	if store.IsDyed("removed") {
+		t.Error("expected the entry removed during sync to stay removed")
+	}
+}
+
+func TestDBDyeStoreSyncPrunesOnlyExpired(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+
+	store := NewDBDyeStore(app, 10, time.Hour, time.Hour)
+	defer store.Close()
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("u1", time.Hour, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !store.lastPrune.IsZero() {
+		t.Fatal("expected no prune without expired entries")
+	}
+
+	if err := store.Add("short", time.Millisecond, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := store.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if store.lastPrune.IsZero() {
+		t.Fatal("expected expired entries to be pruned")
+	}
+}
@@ -0,0 +1,123 @@
+package dye_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	_ "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/plugins/trace/dye"
+)
+
+// newTestDBApp 创建已引导的临时 App
+func newTestDBApp(t *testing.T) core.App {
+	t.Helper()
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("Bootstrap failed: %v", err)
+	}
+	t.Cleanup(func() { _ = app.ResetBootstrapState() })
+
+	return app
+}
+
+func TestDBDyeStore(t *testing.T) {
+	app := newTestDBApp(t)
+
+	store := dye.NewDBDyeStore(app, 2, time.Hour, time.Hour)
+	defer store.Close()
+
+	if err := store.Add("u1", 0, "", ""); err != dye.ErrDyeStoreNotStarted {
+		t.Fatalf("expected ErrDyeStoreNotStarted before Start, got %v", err)
+	}
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Add("u1", 0, "admin", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(dye.HeaderTargetKey("X-Debug-Session", "abc"), time.Minute, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("max entries", func(t *testing.T) {
+		if err := store.Add("u2", time.Hour, "", ""); err != dye.ErrMaxDyedUsersReached {
+			t.Errorf("expected ErrMaxDyedUsersReached, got %v", err)
+		}
+		// 覆盖已有条目不受上限限制
+		if err := store.Add("u1", 2*time.Hour, "admin2", "debug"); err != nil {
+			t.Errorf("expected existing entry to be updated, got %v", err)
+		}
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		user, ok := store.Get("u1")
+		if !ok || user.AddedBy != "admin2" || user.TTL != 2*time.Hour || user.Dimension != dye.DimensionUser {
+			t.Fatalf("unexpected entry %+v", user)
+		}
+		if !store.IsDyed("u1") || store.Count() != 2 || len(store.List()) != 2 {
+			t.Error("expected 2 dyed entries")
+		}
+		entry, ok := store.Match(dye.Subject{Header: map[string][]string{"X-Debug-Session": {"abc"}}})
+		if !ok || entry.Dimension != dye.DimensionHeader || entry.Value != "X-Debug-Session=abc" {
+			t.Errorf("expected header entry to match, got %+v", entry)
+		}
+	})
+
+	t.Run("update ttl", func(t *testing.T) {
+		if err := store.UpdateTTL("u1", 3*time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if user, _ := store.Get("u1"); user.TTL != 3*time.Hour {
+			t.Errorf("expected updated TTL, got %v", user.TTL)
+		}
+		if err := store.UpdateTTL("missing", time.Hour); err != dye.ErrDyedUserNotFound {
+			t.Errorf("expected ErrDyedUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("persisted and shared", func(t *testing.T) {
+		// 同一数据库上的另一个存储（模拟其他节点或重启）
+		other := dye.NewDBDyeStore(app, 2, time.Hour, time.Hour)
+		defer other.Close()
+		if err := other.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		user, ok := other.Get("u1")
+		if !ok || user.AddedBy != "admin2" || user.Reason != "debug" || user.TTL != 3*time.Hour {
+			t.Fatalf("expected entry to be loaded from the database, got %+v", user)
+		}
+
+		if err := other.Remove("u1"); err != nil {
+			t.Fatal(err)
+		}
+		if !store.IsDyed("u1") {
+			t.Fatal("expected the change to reach the first store only after sync")
+		}
+		if err := store.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		if store.IsDyed("u1") {
+			t.Error("expected removal to be propagated by Sync")
+		}
+	})
+
+	t.Run("expired entries are cleaned up", func(t *testing.T) {
+		if err := store.Add("short", time.Millisecond, "", ""); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+		if err := store.Sync(); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		err := app.DB().NewQuery("SELECT COUNT(*) FROM _trace_dyes WHERE key = 'short'").Row(&count)
+		if err != nil || count != 0 {
+			t.Errorf("expected expired row to be deleted, got %d (%v)", count, err)
+		}
+	})
+}
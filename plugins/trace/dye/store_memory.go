@@ -8,7 +8,7 @@ import (
 // MemoryDyeStore 内存染色存储实现
 type MemoryDyeStore struct {
 	mu         sync.RWMutex
-	users      map[string]*DyedUser
+	index      dyeIndex
 	maxUsers   int
 	defaultTTL time.Duration
 	stopCh     chan struct{}
@@ -24,7 +24,7 @@ func NewMemoryDyeStore(maxUsers int, defaultTTL time.Duration) *MemoryDyeStore {
 	}
 
 	store := &MemoryDyeStore{
-		users:      make(map[string]*DyedUser),
+		index:      newDyeIndex(),
 		maxUsers:   maxUsers,
 		defaultTTL: defaultTTL,
 		stopCh:     make(chan struct{}),
@@ -42,9 +42,9 @@ func (s *MemoryDyeStore) Add(userID string, ttl time.Duration, addedBy, reason s
 	defer s.mu.Unlock()
 
 	// 检查是否已存在
-	if _, exists := s.users[userID]; !exists {
+	if _, exists := s.index.entries[userID]; !exists {
 		// 检查数量上限
-		if len(s.users) >= s.maxUsers {
+		if len(s.index.entries) >= s.maxUsers {
 			return ErrMaxDyedUsersReached
 		}
 	}
@@ -53,15 +53,7 @@ func (s *MemoryDyeStore) Add(userID string, ttl time.Duration, addedBy, reason s
 		ttl = s.defaultTTL
 	}
 
-	now := time.Now()
-	s.users[userID] = &DyedUser{
-		UserID:    userID,
-		AddedAt:   now,
-		ExpiresAt: now.Add(ttl),
-		TTL:       ttl,
-		AddedBy:   addedBy,
-		Reason:    reason,
-	}
+	s.index.set(newDyedUser(userID, ttl, addedBy, reason, time.Now()))
 
 	return nil
 }
@@ -70,7 +62,7 @@ func (s *MemoryDyeStore) Add(userID string, ttl time.Duration, addedBy, reason s
 func (s *MemoryDyeStore) Remove(userID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.users, userID)
+	s.index.remove(userID)
 	return nil
 }
 
@@ -79,13 +71,8 @@ func (s *MemoryDyeStore) IsDyed(userID string) bool {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	user, exists := s.users[userID]
-	if !exists {
-		return false
-	}
-
-	// 检查是否过期
-	return time.Now().Before(user.ExpiresAt)
+	_, dyed := s.index.get(userID, time.Now())
+	return dyed
 }
 
 // Get 获取染色用户信息
@@ -93,8 +80,8 @@ func (s *MemoryDyeStore) Get(userID string) (*DyedUser, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	user, exists := s.users[userID]
-	if !exists || time.Now().After(user.ExpiresAt) {
+	user, exists := s.index.get(userID, time.Now())
+	if !exists {
 		return nil, false
 	}
 	// 返回副本
@@ -102,14 +89,27 @@ func (s *MemoryDyeStore) Get(userID string) (*DyedUser, bool) {
 	return &copy, true
 }
 
+// Match 返回与请求特征匹配的染色条目
+func (s *MemoryDyeStore) Match(subject Subject) (*DyedUser, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, matched := s.index.match(subject, time.Now())
+	if !matched {
+		return nil, false
+	}
+	copy := *user
+	return &copy, true
+}
+
 // List 获取所有染色用户列表
 func (s *MemoryDyeStore) List() []DyedUser {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	now := time.Now()
-	result := make([]DyedUser, 0, len(s.users))
-	for _, user := range s.users {
+	result := make([]DyedUser, 0, len(s.index.entries))
+	for _, user := range s.index.entries {
 		if now.Before(user.ExpiresAt) {
 			result = append(result, *user)
 		}
@@ -122,7 +122,7 @@ func (s *MemoryDyeStore) UpdateTTL(userID string, ttl time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	user, exists := s.users[userID]
+	user, exists := s.index.entries[userID]
 	if !exists {
 		return ErrDyedUserNotFound
 	}
@@ -136,7 +136,7 @@ func (s *MemoryDyeStore) UpdateTTL(userID string, ttl time.Duration) error {
 func (s *MemoryDyeStore) Count() int {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return len(s.users)
+	return len(s.index.entries)
 }
 
 // Close 关闭存储
@@ -166,9 +166,9 @@ func (s *MemoryDyeStore) cleanup() {
 	defer s.mu.Unlock()
 
 	now := time.Now()
-	for userID, user := range s.users {
+	for userID, user := range s.index.entries {
 		if now.After(user.ExpiresAt) {
-			delete(s.users, userID)
+			s.index.remove(userID)
 		}
 	}
 }
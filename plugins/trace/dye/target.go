@@ -0,0 +1,188 @@
+package dye
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Dimension 染色维度
+type Dimension string
+
+const (
+	// DimensionUser 按用户 ID 染色
+	DimensionUser Dimension = "user"
+	// DimensionIP 按客户端 IP 染色
+	DimensionIP Dimension = "ip"
+	// DimensionHeader 按请求头的值染色，如 X-Debug-Session
+	DimensionHeader Dimension = "header"
+	// DimensionCollection 按认证用户所属的 auth 集合染色
+	DimensionCollection Dimension = "collection"
+)
+
+// IsValid 检查染色维度是否有效
+func (d Dimension) IsValid() bool {
+	switch d {
+	case DimensionUser, DimensionIP, DimensionHeader, DimensionCollection:
+		return true
+	default:
+		return false
+	}
+}
+
+// TargetKey 返回染色目标在存储中的键。
+//
+// 用户维度直接使用用户 ID（与只支持按用户染色的版本兼容），
+// 其他维度为 "<维度>:<值>"，如 "ip:10.0.0.1"、"collection:admins"。
+// 请求头维度的值为 "<请求头名>=<值>"，建议使用 HeaderTargetKey 构造。
+func TargetKey(dimension Dimension, value string) string {
+	if dimension == "" || dimension == DimensionUser {
+		return value
+	}
+	return string(dimension) + ":" + value
+}
+
+// HeaderTargetKey 返回请求头维度的染色目标键，请求头名会被规范化，
+// 如 HeaderTargetKey("x-debug-session", "abc") 返回 "header:X-Debug-Session=abc"。
+func HeaderTargetKey(name, value string) string {
+	return TargetKey(DimensionHeader, http.CanonicalHeaderKey(name)+"="+value)
+}
+
+// ParseTargetKey 解析染色目标键，返回维度与值，不带已知维度前缀的键视为用户 ID
+func ParseTargetKey(key string) (Dimension, string) {
+	for _, dimension := range []Dimension{DimensionIP, DimensionHeader, DimensionCollection} {
+		if value, ok := strings.CutPrefix(key, string(dimension)+":"); ok {
+			return dimension, value
+		}
+	}
+	return DimensionUser, key
+}
+
+// Subject 参与染色匹配的请求特征
+type Subject struct {
+	// UserID 认证用户 ID
+	UserID string
+	// Collection 认证用户所属的 auth 集合
+	Collection string
+	// IP 客户端 IP
+	IP string
+	// Header 请求头
+	Header http.Header
+}
+
+// SubjectFromRequest 从请求构造染色匹配特征，IP 取自 RemoteAddr。
+// 部署在反向代理之后时，调用方可以自行用可信的客户端 IP 覆盖 IP 字段。
+func SubjectFromRequest(r *http.Request, userID, collection string) Subject {
+	subject := Subject{
+		UserID:     userID,
+		Collection: collection,
+	}
+	if r == nil {
+		return subject
+	}
+
+	subject.Header = r.Header
+	subject.IP = r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		subject.IP = host
+	}
+	return subject
+}
+
+// newDyedUser 创建染色条目，维度与值由 key 解析
+func newDyedUser(key string, ttl time.Duration, addedBy, reason string, now time.Time) *DyedUser {
+	dimension, value := ParseTargetKey(key)
+	return &DyedUser{
+		UserID:    key,
+		Dimension: dimension,
+		Value:     value,
+		AddedAt:   now,
+		ExpiresAt: now.Add(ttl),
+		TTL:       ttl,
+		AddedBy:   addedBy,
+		Reason:    reason,
+	}
+}
+
+// dyeIndex 按目标键索引的染色条目，同时记录被染色的请求头名，
+// 匹配请求时只需查找这些请求头。不是并发安全的，由调用方加锁。
+type dyeIndex struct {
+	entries map[string]*DyedUser
+	headers map[string]int // 规范化请求头名 -> 条目数
+}
+
+func newDyeIndex() dyeIndex {
+	return dyeIndex{
+		entries: make(map[string]*DyedUser),
+		headers: make(map[string]int),
+	}
+}
+
+// set 添加或替换染色条目
+func (x *dyeIndex) set(entry *DyedUser) {
+	x.remove(entry.UserID)
+	x.entries[entry.UserID] = entry
+	if name, ok := headerName(entry); ok {
+		x.headers[name]++
+	}
+}
+
+// remove 删除染色条目
+func (x *dyeIndex) remove(key string) {
+	entry, ok := x.entries[key]
+	if !ok {
+		return
+	}
+	delete(x.entries, key)
+	if name, ok := headerName(entry); ok {
+		if x.headers[name]--; x.headers[name] <= 0 {
+			delete(x.headers, name)
+		}
+	}
+}
+
+// get 返回未过期的染色条目
+func (x *dyeIndex) get(key string, now time.Time) (*DyedUser, bool) {
+	entry, ok := x.entries[key]
+	if !ok || !now.Before(entry.ExpiresAt) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// match 按用户、集合、IP、请求头的顺序返回第一个命中的未过期条目
+func (x *dyeIndex) match(subject Subject, now time.Time) (*DyedUser, bool) {
+	if subject.UserID != "" {
+		if entry, ok := x.get(subject.UserID, now); ok {
+			return entry, true
+		}
+	}
+	if subject.Collection != "" {
+		if entry, ok := x.get(TargetKey(DimensionCollection, subject.Collection), now); ok {
+			return entry, true
+		}
+	}
+	if subject.IP != "" {
+		if entry, ok := x.get(TargetKey(DimensionIP, subject.IP), now); ok {
+			return entry, true
+		}
+	}
+	for name := range x.headers {
+		for _, value := range subject.Header.Values(name) {
+			if entry, ok := x.get(HeaderTargetKey(name, value), now); ok {
+				return entry, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// headerName 返回请求头维度条目的规范化请求头名
+func headerName(entry *DyedUser) (string, bool) {
+	if entry.Dimension != DimensionHeader {
+		return "", false
+	}
+	name, _, ok := strings.Cut(entry.Value, "=")
+	return http.CanonicalHeaderKey(name), ok
+}
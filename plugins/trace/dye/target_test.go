@@ -0,0 +1,101 @@
+package dye
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTargetKey(t *testing.T) {
+	scenarios := []struct {
+		key       string
+		dimension Dimension
+		value     string
+	}{
+		{TargetKey(DimensionUser, "u1"), DimensionUser, "u1"},
+		{TargetKey("", "u1"), DimensionUser, "u1"},
+		{TargetKey(DimensionIP, "10.0.0.1"), DimensionIP, "10.0.0.1"},
+		{TargetKey(DimensionCollection, "admins"), DimensionCollection, "admins"},
+		{HeaderTargetKey("x-debug-session", "abc=1"), DimensionHeader, "X-Debug-Session=abc=1"},
+	}
+
+	for i, s := range scenarios {
+		dimension, value := ParseTargetKey(s.key)
+		if dimension != s.dimension || value != s.value {
+			t.Errorf("(%d) expected %s/%s, got %s/%s", i, s.dimension, s.value, dimension, value)
+		}
+	}
+}
+
+func TestSubjectFromRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:5678"
+	req.Header.Set("X-Debug-Session", "abc")
+
+	subject := SubjectFromRequest(req, "u1", "users")
+	if subject.UserID != "u1" || subject.Collection != "users" || subject.IP != "10.0.0.1" {
+		t.Errorf("unexpected subject %+v", subject)
+	}
+	if subject.Header.Get("X-Debug-Session") != "abc" {
+		t.Error("expected request headers in subject")
+	}
+
+	if subject := SubjectFromRequest(nil, "u1", ""); subject.UserID != "u1" || subject.Header != nil {
+		t.Errorf("unexpected subject without request %+v", subject)
+	}
+}
+
+func TestMemoryDyeStoreMatch(t *testing.T) {
+	store := NewMemoryDyeStore(100, time.Hour)
+	defer store.Close()
+
+	_ = store.Add("u1", time.Hour, "", "")
+	_ = store.Add(TargetKey(DimensionIP, "10.0.0.1"), time.Hour, "", "")
+	_ = store.Add(TargetKey(DimensionCollection, "admins"), time.Hour, "", "")
+	_ = store.Add(HeaderTargetKey("X-Debug-Session", "abc"), time.Hour, "", "")
+	_ = store.Add(HeaderTargetKey("X-Expired", "1"), time.Nanosecond, "", "")
+	time.Sleep(time.Millisecond)
+
+	header := func(name, value string) map[string][]string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Add(name, "other")
+		req.Header.Add(name, value)
+		return req.Header
+	}
+
+	scenarios := []struct {
+		name      string
+		subject   Subject
+		dimension Dimension
+	}{
+		{"user", Subject{UserID: "u1", IP: "10.0.0.1"}, DimensionUser},
+		{"collection", Subject{UserID: "u2", Collection: "admins"}, DimensionCollection},
+		{"ip", Subject{UserID: "u2", IP: "10.0.0.1"}, DimensionIP},
+		{"header", Subject{Header: header("x-debug-session", "abc")}, DimensionHeader},
+		{"header value mismatch", Subject{Header: header("X-Debug-Session", "xyz")}, ""},
+		{"expired header", Subject{Header: header("X-Expired", "1")}, ""},
+		{"no match", Subject{UserID: "u2", IP: "10.0.0.2", Collection: "users"}, ""},
+		{"empty", Subject{}, ""},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			entry, matched := store.Match(s.subject)
+			if matched != (s.dimension != "") {
+				t.Fatalf("expected matched %v, got %v", s.dimension != "", matched)
+			}
+			if matched && entry.Dimension != s.dimension {
+				t.Errorf("expected dimension %s, got %s", s.dimension, entry.Dimension)
+			}
+		})
+	}
+
+	// 删除后请求头索引同步更新
+	_ = store.Remove(HeaderTargetKey("X-Debug-Session", "abc"))
+	if _, matched := store.Match(Subject{Header: header("X-Debug-Session", "abc")}); matched {
+		t.Error("expected removed header target not to match")
+	}
+	if _, ok := store.index.headers["X-Debug-Session"]; ok {
+		t.Error("expected header name to be removed from the index")
+	}
+}
@@ -7,6 +7,21 @@ import (
 	"github.com/pocketbase/pocketbase/plugins/trace/dye"
 )
 
+// DyeStorage 染色存储方式
+type DyeStorage string
+
+const (
+	// DyeStorageMemory 保存在进程内存中（默认），重启后丢失且只对当前节点生效
+	DyeStorageMemory DyeStorage = "memory"
+	// DyeStorageDB 保存在 App 数据库中，重启后保留并同步到共享数据库的所有节点
+	DyeStorageDB DyeStorage = "db"
+)
+
+// IsValid 检查染色存储方式是否有效
+func (s DyeStorage) IsValid() bool {
+	return s == DyeStorageMemory || s == DyeStorageDB
+}
+
 // DyeStoreProvider 是提供 DyeStore 的接口
 type DyeStoreProvider interface {
 	DyeStore() dye.DyeStore
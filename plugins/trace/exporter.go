@@ -0,0 +1,43 @@
+package trace
+
+import "context"
+
+// ExportMode 定义完成的 Span 的输出方式
+type ExportMode string
+
+const (
+	// ExportModeStore 只写入本地 TraceRepository（默认）
+	ExportModeStore ExportMode = "store"
+	// ExportModeOTLP 只通过 OTLP/HTTP 导出到外部 Collector
+	ExportModeOTLP ExportMode = "otlp"
+	// ExportModeBoth 同时写入本地存储并导出
+	ExportModeBoth ExportMode = "both"
+)
+
+// IsValid 检查输出方式是否有效
+func (m ExportMode) IsValid() bool {
+	switch m {
+	case ExportModeStore, ExportModeOTLP, ExportModeBoth:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stores 返回是否写入本地存储
+func (m ExportMode) Stores() bool {
+	return m == ExportModeStore || m == ExportModeBoth
+}
+
+// Exports 返回是否导出到外部 Collector
+func (m ExportMode) Exports() bool {
+	return m == ExportModeOTLP || m == ExportModeBoth
+}
+
+// SpanExporter 将完成的 Span 批量导出到外部系统
+type SpanExporter interface {
+	// Export 导出一批 Span，返回错误时该批次视为导出失败
+	Export(ctx context.Context, spans []*Span) error
+	// Shutdown 关闭导出器并释放资源
+	Shutdown(ctx context.Context) error
+}
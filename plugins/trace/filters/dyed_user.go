@@ -28,10 +28,8 @@ func (f *dyedUserFilter) Phase() trace.FilterPhase {
 }
 
 // ShouldTrace 判断是否应该追踪
-// 如果用户被染色且未过期，返回 true
+// 如果用户或请求的 IP、请求头命中未过期的染色条目，返回 true
 func (f *dyedUserFilter) ShouldTrace(ctx *trace.FilterContext) bool {
-	if ctx.UserID == "" {
-		return false
-	}
-	return f.store.IsDyed(ctx.UserID)
+	_, matched := f.store.Match(dye.SubjectFromRequest(ctx.Request, ctx.UserID, ""))
+	return matched
 }
@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -78,6 +79,32 @@ func TestDyedUser(t *testing.T) {
 		}
 	})
 
+	t.Run("returns true for dyed ip and header", func(t *testing.T) {
+		store := dye.NewMemoryDyeStore(100, time.Hour)
+		defer store.Close()
+
+		_ = store.Add(dye.TargetKey(dye.DimensionIP, "10.0.0.1"), time.Hour, "admin", "debug")
+		_ = store.Add(dye.HeaderTargetKey("X-Debug-Session", "abc"), time.Hour, "admin", "debug")
+
+		filter := DyedUser(store)
+
+		byIP := httptest.NewRequest("GET", "/", nil)
+		byIP.RemoteAddr = "10.0.0.1:1234"
+
+		byHeader := httptest.NewRequest("GET", "/", nil)
+		byHeader.Header.Set("X-Debug-Session", "abc")
+
+		if !filter.ShouldTrace(&trace.FilterContext{Request: byIP}) {
+			t.Error("expected true for dyed ip")
+		}
+		if !filter.ShouldTrace(&trace.FilterContext{Request: byHeader}) {
+			t.Error("expected true for dyed header")
+		}
+		if filter.ShouldTrace(&trace.FilterContext{Request: httptest.NewRequest("GET", "/", nil)}) {
+			t.Error("expected false for request without dyed attributes")
+		}
+	})
+
 	t.Run("has correct name", func(t *testing.T) {
 		store := dye.NewMemoryDyeStore(100, time.Hour)
 		defer store.Close()
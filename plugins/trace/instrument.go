@@ -0,0 +1,171 @@
+package trace
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/hook"
+)
+
+// maxStatementLength 记录在 db.statement 属性中的 SQL 最大长度
+const maxStatementLength = 2048
+
+// installObserversOnce 确保全局观察者只安装一次
+var installObserversOnce sync.Once
+
+// installObservers 安装进程级的 hook 处理器与文件上传观察者。
+// 观察者只在事件/文件系统的 context 属于被追踪的请求时创建 Span，其他情况下几乎没有开销。
+func installObservers() {
+	installObserversOnce.Do(func() {
+		hook.SetTriggerObserver(observeHookHandler)
+		filesystem.SetUploadObserver(observeUpload)
+	})
+}
+
+// observeHookHandler 为 hook 处理器的执行创建子 Span，
+// 处理器执行期间产生的其他自动 Span 以它为父 Span
+func observeHookHandler(event hook.Resolver, handlerId string) func(err error) {
+	ctx := EventContext(event)
+	r := recorderFromContext(ctx)
+	if r == nil {
+		return nil
+	}
+
+	eventType := reflect.TypeOf(event)
+	if eventType.Kind() == reflect.Pointer {
+		eventType = eventType.Elem()
+	}
+
+	builder := r.start(ctx, "hook "+eventType.Name())
+	builder.SetAttribute("hook.event", eventType.Name())
+	if handlerId != "" {
+		builder.SetAttribute("hook.handler_id", handlerId)
+	} else {
+		builder.SetAttribute("hook.handler_id", "(default)")
+	}
+
+	spanID := builder.span.SpanID
+	r.push(spanID)
+
+	return func(err error) {
+		r.pop(spanID)
+		if err != nil {
+			builder.SetStatus(SpanStatusError, err.Error())
+		}
+		builder.End()
+	}
+}
+
+// observeUpload 为文件上传创建子 Span
+func observeUpload(ctx context.Context, fileKey string, size int64) func(err error) {
+	r := recorderFromContext(ctx)
+	if r == nil {
+		return nil
+	}
+
+	builder := r.start(ctx, "file upload")
+	builder.SetAttribute("file.key", fileKey)
+	builder.SetAttribute("file.size", size)
+
+	return func(err error) {
+		if err != nil {
+			builder.SetStatus(SpanStatusError, err.Error())
+		}
+		builder.End()
+	}
+}
+
+// instrumentDB 为 App 的 dbx 连接注册查询回调，为被追踪请求中的 SQL 语句创建子 Span。
+// 已有的 QueryLogFunc/ExecLogFunc（如开发模式下的 SQL 日志）会被保留。
+func instrumentDB(app core.App) {
+	system := "sqlite"
+	if app.IsPostgres() {
+		system = "postgresql"
+	}
+
+	seen := make(map[*dbx.DB]struct{}, 4)
+	for _, builder := range []dbx.Builder{
+		app.ConcurrentDB(),
+		app.NonconcurrentDB(),
+		app.AuxConcurrentDB(),
+		app.AuxNonconcurrentDB(),
+	} {
+		db, ok := builder.(*dbx.DB)
+		if !ok || db == nil {
+			continue
+		}
+		if _, ok := seen[db]; ok {
+			continue
+		}
+		seen[db] = struct{}{}
+
+		prevQuery := db.QueryLogFunc
+		db.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+			if prevQuery != nil {
+				prevQuery(ctx, t, sql, rows, err)
+			}
+			recordDBSpan(ctx, system, sql, t, -1, err)
+		}
+
+		prevExec := db.ExecLogFunc
+		db.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+			if prevExec != nil {
+				prevExec(ctx, t, sql, result, err)
+			}
+			rowsAffected := int64(-1)
+			if err == nil && result != nil {
+				if n, rowsErr := result.RowsAffected(); rowsErr == nil {
+					rowsAffected = n
+				}
+			}
+			recordDBSpan(ctx, system, sql, t, rowsAffected, err)
+		}
+	}
+}
+
+// recordDBSpan 记录一条 SQL 语句的子 Span，rowsAffected 小于 0 表示未知
+func recordDBSpan(ctx context.Context, system, statement string, duration time.Duration, rowsAffected int64, err error) {
+	r := recorderFromContext(ctx)
+	if r == nil {
+		return
+	}
+
+	statement = RedactSQL(statement)
+	operation := sqlOperation(statement)
+
+	builder := r.start(ctx, "db "+operation)
+	builder.SetKind(SpanKindClient)
+	builder.SetAttribute("db.system", system)
+	builder.SetAttribute("db.operation", operation)
+	builder.SetAttribute("db.statement", statement)
+	if rowsAffected >= 0 {
+		builder.SetAttribute("db.rows_affected", rowsAffected)
+	}
+	if err != nil {
+		builder.SetStatus(SpanStatusError, err.Error())
+	}
+	builder.endAt(duration)
+}
+
+// RedactSQL 将 SQL 语句中的字面量参数（字符串、数字、十六进制）替换为 ?，
+// 并去掉 dbx 的 {{table}} / [[column]] 引用标记，用于在追踪中安全地展示语句。
+// 结果超过 2048 个字符时被截断。
+func RedactSQL(statement string) string {
+	result, _ := dbutils.NormalizeSQL(statement, maxStatementLength, 0)
+	return result
+}
+
+// sqlOperation 返回 SQL 语句的操作类型（首个关键字的大写形式），空语句返回 QUERY
+func sqlOperation(statement string) string {
+	if operation := dbutils.SQLOperation(statement); operation != "" {
+		return operation
+	}
+	return "QUERY"
+}
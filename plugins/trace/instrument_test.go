@@ -0,0 +1,158 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"github.com/pocketbase/pocketbase/tools/hook"
+)
+
+func TestRedactSQL(t *testing.T) {
+	scenarios := []struct {
+		sql      string
+		expected string
+	}{
+		{
+			"SELECT {{users}}.* FROM {{users}} WHERE [[users.email]] = 'john@example.com' LIMIT 1",
+			"SELECT users.* FROM users WHERE users.email = ? LIMIT ?",
+		},
+		{
+			"UPDATE `posts` SET `title`='it''s secret', `views`=42, `rating`=4.5 WHERE `id`='abc'",
+			"UPDATE `posts` SET `title`=?, `views`=?, `rating`=? WHERE `id`=?",
+		},
+		{
+			"INSERT INTO t1 (col2, data) VALUES (-7, 0x68656c6c6f)",
+			"INSERT INTO t1 (col2, data) VALUES (-?, ?)",
+		},
+		{
+			"SELECT count(*) FROM _analytics_geo2",
+			"SELECT count(*) FROM _analytics_geo2",
+		},
+	}
+
+	for _, s := range scenarios {
+		if result := RedactSQL(s.sql); result != s.expected {
+			t.Errorf("RedactSQL(%q)\n got: %s\nwant: %s", s.sql, result, s.expected)
+		}
+	}
+
+	long := "SELECT " + strings.Repeat("a", maxStatementLength*2)
+	if result := RedactSQL(long); len(result) != maxStatementLength {
+		t.Errorf("expected statement to be truncated to %d, got %d", maxStatementLength, len(result))
+	}
+}
+
+func TestSQLOperation(t *testing.T) {
+	scenarios := map[string]string{
+		"select * from users":           "SELECT",
+		"  (SELECT 1) UNION (SELECT 2)": "SELECT",
+		"WITH cte AS (SELECT 1)":        "WITH",
+		"DELETE FROM users":             "DELETE",
+		"":                              "QUERY",
+	}
+
+	for sql, expected := range scenarios {
+		if result := sqlOperation(sql); result != expected {
+			t.Errorf("sqlOperation(%q) = %s, want %s", sql, result, expected)
+		}
+	}
+}
+
+func TestObserveHookHandler(t *testing.T) {
+	installObservers()
+
+	traceID, rootID := GenerateTraceID(), GenerateSpanID()
+	r := newSpanRecorder(traceID, rootID)
+	ctx := contextWithRecorder(context.Background(), r)
+
+	testErr := errors.New("test")
+
+	h := hook.Hook[*core.ModelEvent]{}
+	h.Bind(&hook.Handler[*core.ModelEvent]{
+		Id: "outer",
+		Func: func(e *core.ModelEvent) error {
+			return e.Next()
+		},
+	})
+	h.Bind(&hook.Handler[*core.ModelEvent]{
+		Id: "inner",
+		Func: func(e *core.ModelEvent) error {
+			_, span := StartChildSpan(e.Context, "work")
+			span.End()
+			e.Next()
+			return testErr
+		},
+	})
+
+	event := &core.ModelEvent{}
+	event.Context = ctx
+	if err := h.Trigger(event); err != testErr {
+		t.Fatalf("expected test error, got %v", err)
+	}
+
+	// 没有追踪 context 的事件不产生 Span
+	h.Trigger(&core.ModelEvent{Context: context.Background()})
+
+	spans, _ := r.finish()
+	byName := map[string]*Span{}
+	for _, span := range spans {
+		if span.Name == "hook ModelEvent" {
+			byName[span.Attributes["hook.handler_id"].(string)] = span
+		} else {
+			byName[span.Name] = span
+		}
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	outer, inner, work := byName["outer"], byName["inner"], byName["work"]
+	if outer == nil || inner == nil || work == nil {
+		t.Fatalf("missing spans %v", byName)
+	}
+	if outer.ParentID != rootID || inner.ParentID != outer.SpanID || work.ParentID != inner.SpanID {
+		t.Errorf("unexpected span hierarchy: outer=%s inner=%s work=%s", outer.ParentID, inner.ParentID, work.ParentID)
+	}
+	if inner.Status != SpanStatusError || outer.Status != SpanStatusError || inner.StatusMessage != "test" {
+		t.Errorf("expected handler errors to be recorded, got %s/%s", outer.Status, inner.Status)
+	}
+	if outer.Attributes["hook.event"] != "ModelEvent" {
+		t.Errorf("unexpected hook.event %v", outer.Attributes["hook.event"])
+	}
+	if r.current() != rootID {
+		t.Errorf("expected handler stack to be empty after trigger")
+	}
+}
+
+func TestObserveUpload(t *testing.T) {
+	installObservers()
+
+	fsys, err := filesystem.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	rootID := GenerateSpanID()
+	r := newSpanRecorder(GenerateTraceID(), rootID)
+	fsys.SetContext(contextWithRecorder(context.Background(), r))
+
+	if err := fsys.Upload([]byte("hello"), "dir/test.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans, _ := r.finish()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name != "file upload" || span.ParentID != rootID ||
+		span.Attributes["file.key"] != "dir/test.txt" || span.Attributes["file.size"] != int64(5) {
+		t.Errorf("unexpected upload span %+v", span)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pocketbase/pocketbase/plugins/trace/dye"
 	"github.com/pocketbase/pocketbase/tools/types"
 )
 
@@ -17,7 +18,8 @@ type MiddlewareConfig struct {
 	// Filters 过滤器列表
 	Filters []Filter
 
-	// DyeStore 染色用户存储（可选）
+	// DyeStore 染色用户存储（可选）。
+	// 同时实现 Match 方法（如 dye.DyeStore）时还按集合、客户端 IP 与请求头匹配染色条目。
 	DyeStore interface {
 		IsDyed(userID string) bool
 	}
@@ -25,6 +27,9 @@ type MiddlewareConfig struct {
 	// GetUserID 从请求中获取用户 ID 的函数
 	GetUserID func(r *http.Request) string
 
+	// GetAuthCollection 从请求中获取认证用户所属 auth 集合的函数（可选）
+	GetAuthCollection func(r *http.Request) string
+
 	// SkipPaths 要跳过的路径列表
 	SkipPaths []string
 
@@ -69,20 +74,27 @@ func TraceMiddleware(tracer Tracer, config *MiddlewareConfig) func(http.Handler)
 			// 记录开始时间
 			startTime := time.Now()
 
-			// 创建 LazySpan（延迟创建）
+			// 创建 LazySpan（延迟创建），SpanID 预先生成以便子 Span 关联
 			spanName := getSpanName(r, config.SpanNameFunc)
-			lazySpan := NewLazySpan(spanName, func() string { return traceCtx.TraceID }, func() string { return GenerateSpanID() })
+			spanID := GenerateSpanID()
+			lazySpan := NewLazySpan(spanName, func() string { return traceCtx.TraceID }, func() string { return spanID })
+
+			// 通过 context 向下游传播追踪信息，并收集请求内的子 Span
+			recorder := newSpanRecorder(traceCtx.TraceID, spanID)
+			ctx := ContextWithTraceContext(r.Context(), &TraceContext{
+				TraceID:  traceCtx.TraceID,
+				ParentID: spanID,
+				Sampled:  traceCtx.Sampled,
+			})
+			r = r.WithContext(contextWithRecorder(ctx, recorder))
+
+			// 检查是否是染色用户
+			userID, dyeTarget, dyed := matchDyed(config, r)
 
 			// 检查预执行过滤器（决定是否需要追踪）
 			shouldTrace := config.Mode == ModeFull
 			if !shouldTrace && config.Mode == ModeConditional {
-				// 检查是否是染色用户
-				if config.DyeStore != nil && config.GetUserID != nil {
-					userID := config.GetUserID(r)
-					if userID != "" && config.DyeStore.IsDyed(userID) {
-						shouldTrace = true
-					}
-				}
+				shouldTrace = dyed
 
 				// 检查预执行过滤器
 				if !shouldTrace {
@@ -119,8 +131,12 @@ func TraceMiddleware(tracer Tracer, config *MiddlewareConfig) func(http.Handler)
 				}
 			}
 
+			// 启用尾部采样时记录所有请求，由尾部采样决定是否保留，
+			// 染色用户与过滤器命中的请求标记为必须保留
+			tailSampled := tailSamplingEnabled(tracer)
+
 			// 如果需要追踪，创建并记录 Span
-			if shouldTrace {
+			if shouldTrace || tailSampled {
 				span := lazySpan.GetOrCreate()
 				span.ParentID = traceCtx.ParentID
 				span.StartTime = startTime.UnixMicro()
@@ -131,23 +147,83 @@ func TraceMiddleware(tracer Tracer, config *MiddlewareConfig) func(http.Handler)
 				span.Attributes = buildSpanAttributes(r, capture, duration)
 
 				// 设置染色标记
-				if config.DyeStore != nil && config.GetUserID != nil {
-					userID := config.GetUserID(r)
-					if userID != "" && config.DyeStore.IsDyed(userID) {
-						if span.Attributes == nil {
-							span.Attributes = make(map[string]any)
-						}
-						span.Attributes["trace.dyed"] = true
+				if dyed {
+					if span.Attributes == nil {
+						span.Attributes = make(map[string]any)
+					}
+					span.Attributes["trace.dyed"] = true
+					if userID != "" {
 						span.Attributes["user.id"] = userID
 					}
+					if dyeTarget != userID {
+						span.Attributes["trace.dye_target"] = dyeTarget
+					}
+				}
+
+				children, dropped := recorder.finish()
+				if dropped > 0 {
+					if span.Attributes == nil {
+						span.Attributes = make(map[string]any)
+					}
+					span.Attributes["trace.dropped_spans"] = dropped
+				}
+
+				if tailSampled && shouldTrace && config.Mode == ModeConditional {
+					if span.Attributes == nil {
+						span.Attributes = make(map[string]any)
+					}
+					span.Attributes[AttrSamplingPriority] = 1
 				}
 
+				// 子 Span 先于请求 Span 记录，尾部采样在请求 Span 到达时已拥有完整的追踪
+				for _, child := range children {
+					tracer.RecordSpan(child)
+				}
 				tracer.RecordSpan(span)
+			} else {
+				// 未被采集的请求丢弃子 Span
+				recorder.finish()
 			}
 		})
 	}
 }
 
+// matchDyed 检查请求是否命中染色条目，返回请求的用户 ID 与命中的染色目标键。
+// DyeStore 实现了 Match 时按用户、集合、客户端 IP 与请求头匹配，否则只按用户 ID 匹配。
+func matchDyed(config *MiddlewareConfig, r *http.Request) (userID string, target string, dyed bool) {
+	if config.DyeStore == nil {
+		return "", "", false
+	}
+
+	if config.GetUserID != nil {
+		userID = config.GetUserID(r)
+	}
+
+	if matcher, ok := config.DyeStore.(interface {
+		Match(subject dye.Subject) (*dye.DyedUser, bool)
+	}); ok {
+		collection := ""
+		if config.GetAuthCollection != nil {
+			collection = config.GetAuthCollection(r)
+		}
+		if entry, ok := matcher.Match(dye.SubjectFromRequest(r, userID, collection)); ok {
+			return userID, entry.UserID, true
+		}
+		return userID, "", false
+	}
+
+	if userID != "" && config.DyeStore.IsDyed(userID) {
+		return userID, userID, true
+	}
+	return userID, "", false
+}
+
+// tailSamplingEnabled 判断 tracer 是否启用了尾部采样
+func tailSamplingEnabled(tracer Tracer) bool {
+	t, ok := tracer.(interface{ TailSamplingEnabled() bool })
+	return ok && t.TailSamplingEnabled()
+}
+
 // ResponseCapture 捕获响应状态码和大小
 type ResponseCapture struct {
 	http.ResponseWriter
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pocketbase/pocketbase/plugins/trace/dye"
 	"github.com/pocketbase/pocketbase/tools/types"
 )
 
@@ -227,6 +228,52 @@ func TestTraceMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("dyed collection, ip and header in conditional mode", func(t *testing.T) {
+		store := dye.NewMemoryDyeStore(100, time.Hour)
+		defer store.Close()
+		_ = store.Add(dye.TargetKey(dye.DimensionCollection, "admins"), time.Hour, "", "")
+		_ = store.Add(dye.TargetKey(dye.DimensionIP, "10.0.0.1"), time.Hour, "", "")
+		_ = store.Add(dye.HeaderTargetKey("X-Debug-Session", "abc"), time.Hour, "", "")
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+		scenarios := []struct {
+			name   string
+			setup  func(r *http.Request)
+			target string
+		}{
+			{"collection", func(r *http.Request) { r.Header.Set("X-Collection", "admins") }, "collection:admins"},
+			{"ip", func(r *http.Request) { r.RemoteAddr = "10.0.0.1:1234" }, "ip:10.0.0.1"},
+			{"header", func(r *http.Request) { r.Header.Set("X-Debug-Session", "abc") }, "header:X-Debug-Session=abc"},
+			{"none", func(r *http.Request) {}, ""},
+		}
+
+		for _, s := range scenarios {
+			tracer := &mockTracer{enabled: true}
+			middleware := TraceMiddleware(tracer, &MiddlewareConfig{
+				Mode:     ModeConditional,
+				DyeStore: store,
+				GetAuthCollection: func(r *http.Request) string {
+					return r.Header.Get("X-Collection")
+				},
+			})
+
+			req := httptest.NewRequest("GET", "/api/test", nil)
+			s.setup(req)
+			middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			if tracer.spanRecorded != (s.target != "") {
+				t.Errorf("%s: expected span recorded %v, got %v", s.name, s.target != "", tracer.spanRecorded)
+				continue
+			}
+			if s.target != "" && tracer.lastSpan.Attributes["trace.dye_target"] != s.target {
+				t.Errorf("%s: expected dye target %s, got %v", s.name, s.target, tracer.lastSpan.Attributes["trace.dye_target"])
+			}
+		}
+	})
+
 	t.Run("custom span name function", func(t *testing.T) {
 		tracer := &mockTracer{enabled: true}
 		middleware := TraceMiddleware(tracer, &MiddlewareConfig{
@@ -400,6 +447,9 @@ type mockSpanBuilder struct{}
 func (b *mockSpanBuilder) SetAttribute(key string, value any) SpanBuilder { return b }
 func (b *mockSpanBuilder) SetStatus(status SpanStatus, msg string) SpanBuilder { return b }
 func (b *mockSpanBuilder) SetKind(kind SpanKind) SpanBuilder { return b }
+func (b *mockSpanBuilder) AddEvent(name string, attrs map[string]any) SpanBuilder { return b }
+func (b *mockSpanBuilder) RecordError(err error) SpanBuilder { return b }
+func (b *mockSpanBuilder) AddLink(traceID, spanID string, attrs map[string]any) SpanBuilder { return b }
 func (b *mockSpanBuilder) End() {}
 
 // mockDyeStore 用于测试的 mock dye store
@@ -55,6 +55,21 @@ func (n *NoopSpanBuilder) SetKind(kind SpanKind) SpanBuilder {
 	return n
 }
 
+// AddEvent 返回自身
+func (n *NoopSpanBuilder) AddEvent(name string, attributes map[string]any) SpanBuilder {
+	return n
+}
+
+// RecordError 返回自身
+func (n *NoopSpanBuilder) RecordError(err error) SpanBuilder {
+	return n
+}
+
+// AddLink 返回自身
+func (n *NoopSpanBuilder) AddLink(traceID, spanID string, attributes map[string]any) SpanBuilder {
+	return n
+}
+
 // End 不执行任何操作
 func (n *NoopSpanBuilder) End() {}
 
@@ -0,0 +1,258 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// OTLP/HTTP 传输协议
+const (
+	OTLPProtocolProtobuf = "http/protobuf"
+	OTLPProtocolJSON     = "http/json"
+)
+
+// otlpTracesPath OTLP/HTTP traces 的默认路径
+const otlpTracesPath = "/v1/traces"
+
+// OTLPConfig 定义 OTLP/HTTP 导出配置
+type OTLPConfig struct {
+	// Endpoint Collector 地址，如 "http://otel-collector:4318"
+	// 未指定路径时自动追加 /v1/traces
+	Endpoint string
+	// Protocol 传输协议（http/protobuf 或 http/json），默认 http/protobuf
+	Protocol string
+	// Headers 附加请求头（如认证信息）
+	Headers map[string]string
+	// Timeout 单次请求超时，默认 10s
+	Timeout time.Duration
+
+	// MaxRetries 可重试错误的最大重试次数，默认 3
+	MaxRetries int
+	// InitialBackoff 首次重试等待时间，之后按指数增长，默认 500ms
+	InitialBackoff time.Duration
+	// MaxBackoff 单次重试等待时间上限，默认 10s
+	MaxBackoff time.Duration
+
+	// ServiceName 资源属性 service.name，默认 "pocketbase"
+	ServiceName string
+	// ServiceVersion 资源属性 service.version
+	ServiceVersion string
+	// NodeID 资源属性 service.instance.id，默认为主机名
+	NodeID string
+	// ResourceAttributes 附加资源属性（如 deployment.environment）
+	ResourceAttributes map[string]any
+}
+
+// applyOTLPDefaults 应用 OTLP 默认值
+func applyOTLPDefaults(c OTLPConfig) OTLPConfig {
+	if c.Protocol == "" {
+		c.Protocol = OTLPProtocolProtobuf
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = "pocketbase"
+	}
+	if c.NodeID == "" {
+		c.NodeID, _ = os.Hostname()
+	}
+	return c
+}
+
+// OTLPExporter 通过 OTLP/HTTP 将 Span 导出到 OpenTelemetry Collector
+type OTLPExporter struct {
+	config   OTLPConfig
+	url      string
+	client   *http.Client
+	resource []otlpKeyValue
+
+	exported atomic.Int64
+	failed   atomic.Int64
+}
+
+// NewOTLPExporter 创建 OTLP/HTTP 导出器
+func NewOTLPExporter(config OTLPConfig) (*OTLPExporter, error) {
+	config = applyOTLPDefaults(config)
+
+	if config.Protocol != OTLPProtocolProtobuf && config.Protocol != OTLPProtocolJSON {
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", config.Protocol)
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", config.Endpoint)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = otlpTracesPath
+	}
+
+	attrs := make(map[string]any, len(config.ResourceAttributes)+3)
+	for k, v := range config.ResourceAttributes {
+		attrs[k] = v
+	}
+	attrs["service.name"] = config.ServiceName
+	if config.ServiceVersion != "" {
+		attrs["service.version"] = config.ServiceVersion
+	}
+	if config.NodeID != "" {
+		attrs["service.instance.id"] = config.NodeID
+	}
+
+	return &OTLPExporter{
+		config:   config,
+		url:      endpoint.String(),
+		client:   &http.Client{Timeout: config.Timeout},
+		resource: toOTLPAttributes(attrs),
+	}, nil
+}
+
+// ExportedCount 返回成功导出的 Span 数量
+func (e *OTLPExporter) ExportedCount() int64 {
+	return e.exported.Load()
+}
+
+// FailedCount 返回导出失败（重试耗尽或不可重试）的 Span 数量
+func (e *OTLPExporter) FailedCount() int64 {
+	return e.failed.Load()
+}
+
+// Export 编码并发送一批 Span，可重试的错误按指数退避重试
+func (e *OTLPExporter) Export(ctx context.Context, spans []*Span) error {
+	request := newOTLPTraceRequest(e.resource, spans)
+	if request == nil {
+		return nil
+	}
+	count := int64(len(request.ResourceSpans[0].ScopeSpans[0].Spans))
+
+	var body []byte
+	var contentType string
+	if e.config.Protocol == OTLPProtocolJSON {
+		body, contentType = request.marshalJSON(), "application/json"
+	} else {
+		body, contentType = request.marshalProto(), "application/x-protobuf"
+	}
+
+	backoff := e.config.InitialBackoff
+	var lastErr error
+	for attempt := 0; attempt <= e.config.MaxRetries; attempt++ {
+		retryAfter, err := e.send(ctx, body, contentType)
+		if err == nil {
+			e.exported.Add(count)
+			return nil
+		}
+		lastErr = err
+
+		var permanent *otlpPermanentError
+		if errors.As(err, &permanent) || attempt == e.config.MaxRetries {
+			break
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		wait = min(wait, e.config.MaxBackoff)
+		backoff = min(backoff*2, e.config.MaxBackoff)
+
+		select {
+		case <-ctx.Done():
+			e.failed.Add(count)
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	e.failed.Add(count)
+	return lastErr
+}
+
+// Shutdown 关闭空闲连接
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlpPermanentError 表示不可重试的导出错误（如 400 Bad Request）
+type otlpPermanentError struct {
+	status int
+	body   string
+}
+
+func (e *otlpPermanentError) Error() string {
+	return fmt.Sprintf("OTLP export rejected with status %d: %s", e.status, e.body)
+}
+
+// send 发送一次请求，返回服务端要求的重试等待时间（Retry-After）
+func (e *OTLPExporter) send(ctx context.Context, body []byte, contentType string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, &otlpPermanentError{body: err.Error()}
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return 0, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return retryAfter, fmt.Errorf("OTLP export failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	default:
+		return 0, &otlpPermanentError{status: resp.StatusCode, body: strings.TrimSpace(string(msg))}
+	}
+}
+
+// parseOTLPHeaders 解析 "key1=value1,key2=value2" 格式的请求头
+func parseOTLPHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		headers[key] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// 确保实现了接口
+var _ SpanExporter = (*OTLPExporter)(nil)
@@ -0,0 +1,498 @@
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// otlpScopeName 导出 Span 的 InstrumentationScope 名称
+const otlpScopeName = "github.com/pocketbase/pocketbase/plugins/trace"
+
+// 以下结构对应 opentelemetry-proto 中 ExportTraceServiceRequest 的子集，
+// JSON 编码遵循 OTLP/JSON 规范（ID 使用 hex，64 位整数使用字符串），
+// Protobuf 编码由 marshalProto 手工完成，避免引入 protobuf 依赖。
+
+type otlpTraceRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64         `json:"endTimeUnixNano,string"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano uint64         `json:"timeUnixNano,string"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue 对应 AnyValue，同一时间只有一个字段有效
+type otlpAnyValue struct {
+	String *string
+	Bool   *bool
+	Int    *int64
+	Double *float64
+	Array  []otlpAnyValue
+	KvList []otlpKeyValue
+	isList bool // Array/KvList 可能为空，需要单独标记类型
+	isKv   bool
+}
+
+// MarshalJSON 按 OTLP/JSON 规范编码 AnyValue
+func (v otlpAnyValue) MarshalJSON() ([]byte, error) {
+	switch {
+	case v.String != nil:
+		return json.Marshal(map[string]string{"stringValue": *v.String})
+	case v.Bool != nil:
+		return json.Marshal(map[string]bool{"boolValue": *v.Bool})
+	case v.Int != nil:
+		return json.Marshal(map[string]string{"intValue": strconv.FormatInt(*v.Int, 10)})
+	case v.Double != nil:
+		return json.Marshal(map[string]float64{"doubleValue": *v.Double})
+	case v.isList:
+		return json.Marshal(map[string]any{"arrayValue": map[string]any{"values": nonNil(v.Array)}})
+	case v.isKv:
+		return json.Marshal(map[string]any{"kvlistValue": map[string]any{"values": nonNil(v.KvList)}})
+	default:
+		return []byte("{}"), nil
+	}
+}
+
+// nonNil 避免空切片被编码为 null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// otlpSpanKinds Span 类型到 OTLP SpanKind 枚举值
+var otlpSpanKinds = map[SpanKind]int{
+	SpanKindInternal: 1,
+	SpanKindServer:   2,
+	SpanKindClient:   3,
+	SpanKindProducer: 4,
+	SpanKindConsumer: 5,
+}
+
+// otlpStatusCodes Span 状态到 OTLP StatusCode 枚举值
+var otlpStatusCodes = map[SpanStatus]int{
+	SpanStatusUnset: 0,
+	SpanStatusOK:    1,
+	SpanStatusError: 2,
+}
+
+// newOTLPTraceRequest 将 Span 转换为导出请求，TraceID/SpanID 无效的 Span 会被跳过。
+// 没有可导出的 Span 时返回 nil。
+func newOTLPTraceRequest(resource []otlpKeyValue, spans []*Span) *otlpTraceRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		if span == nil || !isHexID(span.TraceID, 16) || !isHexID(span.SpanID, 8) {
+			continue
+		}
+
+		parentID := span.ParentID
+		if !isHexID(parentID, 8) {
+			parentID = ""
+		}
+
+		start := uint64(max(span.StartTime, 0)) * 1000
+		otlpSpans = append(otlpSpans, otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      parentID,
+			Name:              span.Name,
+			Kind:              otlpSpanKinds[span.Kind],
+			StartTimeUnixNano: start,
+			EndTimeUnixNano:   start + uint64(max(span.Duration, 0))*1000,
+			Attributes:        toOTLPAttributes(span.Attributes),
+			Events:            toOTLPEvents(span.Events),
+			Links:             toOTLPLinks(span.Links),
+			Status:            otlpStatus{Message: span.StatusMessage, Code: otlpStatusCodes[span.Status]},
+		})
+	}
+
+	if len(otlpSpans) == 0 {
+		return nil
+	}
+
+	return &otlpTraceRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: nonNil(resource)},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: otlpScopeName},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
+
+// isHexID 检查 ID 是否为指定字节长度且非全零的 hex 字符串
+func isHexID(id string, size int) bool {
+	if len(id) != size*2 {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// toOTLPEvents 转换 Span 事件
+func toOTLPEvents(events []SpanEvent) []otlpEvent {
+	if len(events) == 0 {
+		return nil
+	}
+
+	result := make([]otlpEvent, 0, len(events))
+	for _, event := range events {
+		result = append(result, otlpEvent{
+			TimeUnixNano: uint64(max(event.Time, 0)) * 1000,
+			Name:         event.Name,
+			Attributes:   toOTLPAttributes(event.Attributes),
+		})
+	}
+	return result
+}
+
+// toOTLPLinks 转换 Span 链接，ID 无效的链接会被跳过
+func toOTLPLinks(links []SpanLink) []otlpLink {
+	var result []otlpLink
+	for _, link := range links {
+		if !isHexID(link.TraceID, 16) || !isHexID(link.SpanID, 8) {
+			continue
+		}
+		result = append(result, otlpLink{
+			TraceID:    link.TraceID,
+			SpanID:     link.SpanID,
+			Attributes: toOTLPAttributes(link.Attributes),
+		})
+	}
+	return result
+}
+
+// toOTLPAttributes 将属性 map 转换为按 key 排序的 KeyValue 列表
+func toOTLPAttributes(attrs map[string]any) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, otlpKeyValue{Key: k, Value: toOTLPAnyValue(attrs[k])})
+	}
+	return result
+}
+
+// toOTLPAnyValue 将任意值转换为 AnyValue，不支持的类型按字符串处理
+func toOTLPAnyValue(value any) otlpAnyValue {
+	switch v := value.(type) {
+	case nil:
+		return otlpAnyValue{}
+	case string:
+		return otlpAnyValue{String: &v}
+	case bool:
+		return otlpAnyValue{Bool: &v}
+	case float64:
+		return otlpAnyValue{Double: &v}
+	case float32:
+		f := float64(v)
+		return otlpAnyValue{Double: &f}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return otlpAnyValue{Int: &i}
+		}
+		f, _ := v.Float64()
+		return otlpAnyValue{Double: &f}
+	case map[string]any:
+		return otlpAnyValue{KvList: toOTLPAttributes(v), isKv: true}
+	case []any:
+		values := make([]otlpAnyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, toOTLPAnyValue(item))
+		}
+		return otlpAnyValue{Array: values, isList: true}
+	case fmt.Stringer:
+		s := v.String()
+		return otlpAnyValue{String: &s}
+	case error:
+		s := v.Error()
+		return otlpAnyValue{String: &s}
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		return otlpAnyValue{Int: &i}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i := int64(min(rv.Uint(), math.MaxInt64))
+		return otlpAnyValue{Int: &i}
+	case reflect.Slice, reflect.Array:
+		values := make([]otlpAnyValue, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, toOTLPAnyValue(rv.Index(i).Interface()))
+		}
+		return otlpAnyValue{Array: values, isList: true}
+	}
+
+	s := fmt.Sprint(value)
+	return otlpAnyValue{String: &s}
+}
+
+// marshalJSON 编码为 OTLP/JSON
+func (r *otlpTraceRequest) marshalJSON() []byte {
+	data, _ := json.Marshal(r)
+	return data
+}
+
+// marshalProto 编码为 OTLP Protobuf（ExportTraceServiceRequest）
+func (r *otlpTraceRequest) marshalProto() []byte {
+	var b protoBuffer
+	for _, rs := range r.ResourceSpans {
+		b.message(1, func(b *protoBuffer) {
+			b.message(1, func(b *protoBuffer) {
+				for _, kv := range rs.Resource.Attributes {
+					b.message(1, kv.marshalProto)
+				}
+			})
+			for _, ss := range rs.ScopeSpans {
+				b.message(2, func(b *protoBuffer) {
+					b.message(1, func(b *protoBuffer) {
+						b.string(1, ss.Scope.Name)
+					})
+					for i := range ss.Spans {
+						b.message(2, ss.Spans[i].marshalProto)
+					}
+				})
+			}
+		})
+	}
+	return b
+}
+
+func (s *otlpSpan) marshalProto(b *protoBuffer) {
+	traceID, _ := hex.DecodeString(s.TraceID)
+	spanID, _ := hex.DecodeString(s.SpanID)
+	parentID, _ := hex.DecodeString(s.ParentSpanID)
+
+	b.bytes(1, traceID)
+	b.bytes(2, spanID)
+	b.bytes(4, parentID)
+	b.string(5, s.Name)
+	b.varint(6, uint64(s.Kind))
+	b.fixed64(7, s.StartTimeUnixNano)
+	b.fixed64(8, s.EndTimeUnixNano)
+	for _, kv := range s.Attributes {
+		b.message(9, kv.marshalProto)
+	}
+	for i := range s.Events {
+		b.forceMessage(11, s.Events[i].marshalProto)
+	}
+	for i := range s.Links {
+		b.forceMessage(13, s.Links[i].marshalProto)
+	}
+	b.message(15, func(b *protoBuffer) {
+		b.string(2, s.Status.Message)
+		b.varint(3, uint64(s.Status.Code))
+	})
+}
+
+func (e *otlpEvent) marshalProto(b *protoBuffer) {
+	b.fixed64(1, e.TimeUnixNano)
+	b.string(2, e.Name)
+	for _, kv := range e.Attributes {
+		b.message(3, kv.marshalProto)
+	}
+}
+
+func (l *otlpLink) marshalProto(b *protoBuffer) {
+	traceID, _ := hex.DecodeString(l.TraceID)
+	spanID, _ := hex.DecodeString(l.SpanID)
+
+	b.bytes(1, traceID)
+	b.bytes(2, spanID)
+	for _, kv := range l.Attributes {
+		b.message(4, kv.marshalProto)
+	}
+}
+
+func (kv otlpKeyValue) marshalProto(b *protoBuffer) {
+	b.string(1, kv.Key)
+	b.message(2, kv.Value.marshalProto)
+}
+
+func (v otlpAnyValue) marshalProto(b *protoBuffer) {
+	switch {
+	case v.String != nil:
+		b.forceString(1, *v.String)
+	case v.Bool != nil:
+		b.tag(2, protoWireVarint)
+		b.appendVarint(boolToUint(*v.Bool))
+	case v.Int != nil:
+		b.tag(3, protoWireVarint)
+		b.appendVarint(uint64(*v.Int))
+	case v.Double != nil:
+		b.tag(4, protoWireFixed64)
+		*b = binary.LittleEndian.AppendUint64(*b, math.Float64bits(*v.Double))
+	case v.isList:
+		b.forceMessage(5, func(b *protoBuffer) {
+			for _, item := range v.Array {
+				b.forceMessage(1, item.marshalProto)
+			}
+		})
+	case v.isKv:
+		b.forceMessage(6, func(b *protoBuffer) {
+			for _, kv := range v.KvList {
+				b.message(1, kv.marshalProto)
+			}
+		})
+	}
+}
+
+func boolToUint(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// Protobuf wire types
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+// protoBuffer 是一个最小的 Protobuf 编码器，零值字段按 proto3 规则省略
+type protoBuffer []byte
+
+func (b *protoBuffer) tag(field, wireType int) {
+	b.appendVarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) appendVarint(v uint64) {
+	*b = binary.AppendUvarint(*b, v)
+}
+
+func (b *protoBuffer) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, protoWireVarint)
+	b.appendVarint(v)
+}
+
+func (b *protoBuffer) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.tag(field, protoWireFixed64)
+	*b = binary.LittleEndian.AppendUint64(*b, v)
+}
+
+func (b *protoBuffer) bytes(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	b.tag(field, protoWireBytes)
+	b.appendVarint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *protoBuffer) string(field int, v string) {
+	if v == "" {
+		return
+	}
+	b.forceString(field, v)
+}
+
+// forceString 即使为空也写入（oneof 字段需要保留类型信息）
+func (b *protoBuffer) forceString(field int, v string) {
+	b.tag(field, protoWireBytes)
+	b.appendVarint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// message 写入嵌套消息，空消息省略
+func (b *protoBuffer) message(field int, encode func(*protoBuffer)) {
+	var nested protoBuffer
+	encode(&nested)
+	if len(nested) == 0 {
+		return
+	}
+	b.tag(field, protoWireBytes)
+	b.appendVarint(uint64(len(nested)))
+	*b = append(*b, nested...)
+}
+
+// forceMessage 写入嵌套消息，空消息也保留
+func (b *protoBuffer) forceMessage(field int, encode func(*protoBuffer)) {
+	var nested protoBuffer
+	encode(&nested)
+	b.tag(field, protoWireBytes)
+	b.appendVarint(uint64(len(nested)))
+	*b = append(*b, nested...)
+}
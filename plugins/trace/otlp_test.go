@@ -0,0 +1,476 @@
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// testCollector 本地模拟的 OTLP/HTTP Collector
+type testCollector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+
+	// statuses 按请求顺序返回的状态码，用完后返回 200
+	statuses []int
+	calls    atomic.Int32
+}
+
+func newTestCollector(t *testing.T, statuses ...int) *testCollector {
+	c := &testCollector{statuses: statuses}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		c.mu.Lock()
+		c.requests = append(c.requests, r)
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+
+		n := int(c.calls.Add(1)) - 1
+		if n < len(c.statuses) {
+			w.WriteHeader(c.statuses[n])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *testCollector) lastBody() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.bodies) == 0 {
+		return nil
+	}
+	return c.bodies[len(c.bodies)-1]
+}
+
+func testExportSpan() *Span {
+	return &Span{
+		ID:            "1",
+		TraceID:       "0af7651916cd43dd8448eb211c80319c",
+		SpanID:        "b7ad6b7169203331",
+		ParentID:      "00f067aa0ba902b7",
+		Name:          "GET /api/collections",
+		Kind:          SpanKindServer,
+		StartTime:     1700000000000000,
+		Duration:      1500,
+		Status:        SpanStatusError,
+		StatusMessage: "internal error",
+		Events: []SpanEvent{
+			{Name: "cache miss", Time: 1700000000000100, Attributes: map[string]any{"cache.key": "users"}},
+		},
+		Links: []SpanLink{
+			{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b8"},
+			{TraceID: "invalid", SpanID: "invalid"},
+		},
+		Attributes: map[string]any{
+			"http.method":      "GET",
+			"http.status_code": 500,
+			"trace.dyed":       true,
+			"db.ratio":         0.5,
+			"tags":             []any{"a", "b"},
+		},
+		Created: types.NowDateTime(),
+	}
+}
+
+func TestNewOTLPExporter(t *testing.T) {
+	t.Run("appends default path", func(t *testing.T) {
+		exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: "http://collector:4318"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exporter.url != "http://collector:4318/v1/traces" {
+			t.Errorf("url = %s", exporter.url)
+		}
+	})
+
+	t.Run("keeps custom path", func(t *testing.T) {
+		exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: "https://otlp.example.com/custom/traces"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exporter.url != "https://otlp.example.com/custom/traces" {
+			t.Errorf("url = %s", exporter.url)
+		}
+	})
+
+	for _, config := range []OTLPConfig{
+		{},
+		{Endpoint: "collector:4318"},
+		{Endpoint: "ftp://collector"},
+		{Endpoint: "http://collector:4318", Protocol: "grpc"},
+	} {
+		if _, err := NewOTLPExporter(config); err == nil {
+			t.Errorf("expected error for %+v", config)
+		}
+	}
+}
+
+func TestOTLPExporter_JSON(t *testing.T) {
+	collector := newTestCollector(t)
+
+	exporter, err := NewOTLPExporter(OTLPConfig{
+		Endpoint:           collector.URL,
+		Protocol:           OTLPProtocolJSON,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ServiceName:        "api",
+		ServiceVersion:     "1.2.3",
+		NodeID:             "node-1",
+		ResourceAttributes: map[string]any{"deployment.environment": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := &Span{TraceID: "not-hex", SpanID: "b7ad6b7169203331"}
+	if err := exporter.Export(t.Context(), []*Span{testExportSpan(), invalid, nil}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if exporter.ExportedCount() != 1 {
+		t.Errorf("ExportedCount = %d", exporter.ExportedCount())
+	}
+
+	req := collector.requests[0]
+	if req.URL.Path != "/v1/traces" || req.Header.Get("Content-Type") != "application/json" || req.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected request %s %v", req.URL.Path, req.Header)
+	}
+
+	var payload struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string         `json:"key"`
+					Value map[string]any `json:"value"`
+				} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Scope struct {
+					Name string `json:"name"`
+				} `json:"scope"`
+				Spans []map[string]any `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(collector.lastBody(), &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	resource := map[string]any{}
+	for _, attr := range payload.ResourceSpans[0].Resource.Attributes {
+		resource[attr.Key] = attr.Value["stringValue"]
+	}
+	expectedResource := map[string]any{
+		"service.name":           "api",
+		"service.version":        "1.2.3",
+		"service.instance.id":    "node-1",
+		"deployment.environment": "prod",
+	}
+	for k, v := range expectedResource {
+		if resource[k] != v {
+			t.Errorf("resource[%s] = %v, want %v", k, resource[k], v)
+		}
+	}
+
+	scope := payload.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != otlpScopeName || len(scope.Spans) != 1 {
+		t.Fatalf("unexpected scope spans %+v", scope)
+	}
+
+	span := scope.Spans[0]
+	expected := map[string]any{
+		"traceId":           "0af7651916cd43dd8448eb211c80319c",
+		"spanId":            "b7ad6b7169203331",
+		"parentSpanId":      "00f067aa0ba902b7",
+		"name":              "GET /api/collections",
+		"kind":              float64(2),
+		"startTimeUnixNano": "1700000000000000000",
+		"endTimeUnixNano":   "1700000000001500000",
+	}
+	for k, v := range expected {
+		if span[k] != v {
+			t.Errorf("span[%s] = %v, want %v", k, span[k], v)
+		}
+	}
+	if status := span["status"].(map[string]any); status["code"] != float64(2) || status["message"] != "internal error" {
+		t.Errorf("status = %v", status)
+	}
+	events := span["events"].([]any)
+	if event := events[0].(map[string]any); len(events) != 1 || event["name"] != "cache miss" ||
+		event["timeUnixNano"] != "1700000000000100000" || len(event["attributes"].([]any)) != 1 {
+		t.Errorf("events = %v", events)
+	}
+	links := span["links"].([]any)
+	if link := links[0].(map[string]any); len(links) != 1 || link["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		link["spanId"] != "00f067aa0ba902b8" {
+		t.Errorf("links = %v", links)
+	}
+
+	attrs := map[string]map[string]any{}
+	for _, attr := range span["attributes"].([]any) {
+		kv := attr.(map[string]any)
+		attrs[kv["key"].(string)] = kv["value"].(map[string]any)
+	}
+	if attrs["http.status_code"]["intValue"] != "500" || attrs["trace.dyed"]["boolValue"] != true ||
+		attrs["db.ratio"]["doubleValue"] != 0.5 || attrs["http.method"]["stringValue"] != "GET" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if values := attrs["tags"]["arrayValue"].(map[string]any)["values"].([]any); len(values) != 2 {
+		t.Errorf("tags = %v", attrs["tags"])
+	}
+}
+
+func TestOTLPExporter_Protobuf(t *testing.T) {
+	collector := newTestCollector(t)
+
+	exporter, err := NewOTLPExporter(OTLPConfig{Endpoint: collector.URL, NodeID: "node-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(t.Context(), []*Span{testExportSpan()}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if ct := collector.requests[0].Header.Get("Content-Type"); ct != "application/x-protobuf" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+
+	request := decodeTestProto(t, collector.lastBody())
+	resourceSpans := decodeTestProto(t, request[1][0].([]byte))
+
+	resource := decodeTestProto(t, resourceSpans[1][0].([]byte))
+	resourceAttrs := map[string]string{}
+	for _, raw := range resource[1] {
+		kv := decodeTestProto(t, raw.([]byte))
+		value := decodeTestProto(t, kv[2][0].([]byte))
+		resourceAttrs[string(kv[1][0].([]byte))] = string(value[1][0].([]byte))
+	}
+	if resourceAttrs["service.name"] != "pocketbase" || resourceAttrs["service.instance.id"] != "node-1" {
+		t.Errorf("resource = %v", resourceAttrs)
+	}
+
+	scopeSpans := decodeTestProto(t, resourceSpans[2][0].([]byte))
+	scope := decodeTestProto(t, scopeSpans[1][0].([]byte))
+	if string(scope[1][0].([]byte)) != otlpScopeName {
+		t.Errorf("scope = %s", scope[1][0])
+	}
+
+	span := decodeTestProto(t, scopeSpans[2][0].([]byte))
+	if got := span[1][0].([]byte); len(got) != 16 || got[0] != 0x0a || got[15] != 0x9c {
+		t.Errorf("trace_id = %x", got)
+	}
+	if got := span[2][0].([]byte); len(got) != 8 || got[0] != 0xb7 {
+		t.Errorf("span_id = %x", got)
+	}
+	if got := span[4][0].([]byte); len(got) != 8 || got[0] != 0x00 || got[1] != 0xf0 {
+		t.Errorf("parent_span_id = %x", got)
+	}
+	if string(span[5][0].([]byte)) != "GET /api/collections" || span[6][0] != uint64(2) {
+		t.Errorf("name/kind = %s/%v", span[5][0], span[6][0])
+	}
+	if span[7][0] != uint64(1700000000000000000) || span[8][0] != uint64(1700000000001500000) {
+		t.Errorf("times = %v/%v", span[7][0], span[8][0])
+	}
+	if len(span[9]) != 5 {
+		t.Errorf("expected 5 attributes, got %d", len(span[9]))
+	}
+	status := decodeTestProto(t, span[15][0].([]byte))
+	if status[3][0] != uint64(2) || string(status[2][0].([]byte)) != "internal error" {
+		t.Errorf("status = %v", status)
+	}
+	if len(span[11]) != 1 || len(span[13]) != 1 {
+		t.Fatalf("expected 1 event and 1 link, got %d/%d", len(span[11]), len(span[13]))
+	}
+	event := decodeTestProto(t, span[11][0].([]byte))
+	if event[1][0] != uint64(1700000000000100000) || string(event[2][0].([]byte)) != "cache miss" || len(event[3]) != 1 {
+		t.Errorf("event = %v", event)
+	}
+	link := decodeTestProto(t, span[13][0].([]byte))
+	if got := link[1][0].([]byte); len(got) != 16 || got[0] != 0x4b {
+		t.Errorf("link trace_id = %x", got)
+	}
+	if got := link[2][0].([]byte); len(got) != 8 || got[7] != 0xb8 {
+		t.Errorf("link span_id = %x", got)
+	}
+
+	// http.status_code 为 int_value（字段 3）
+	for _, raw := range span[9] {
+		kv := decodeTestProto(t, raw.([]byte))
+		if string(kv[1][0].([]byte)) != "http.status_code" {
+			continue
+		}
+		if value := decodeTestProto(t, kv[2][0].([]byte)); value[3][0] != uint64(500) {
+			t.Errorf("http.status_code = %v", value)
+		}
+	}
+}
+
+func TestOTLPExporter_Retry(t *testing.T) {
+	fast := func(url string) OTLPConfig {
+		return OTLPConfig{Endpoint: url, MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	}
+
+	t.Run("retries transient errors", func(t *testing.T) {
+		collector := newTestCollector(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+		exporter, _ := NewOTLPExporter(fast(collector.URL))
+
+		if err := exporter.Export(t.Context(), []*Span{testExportSpan()}); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if calls := collector.calls.Load(); calls != 3 {
+			t.Errorf("calls = %d, want 3", calls)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		collector := newTestCollector(t, 502, 502, 502, 502, 502)
+		exporter, _ := NewOTLPExporter(fast(collector.URL))
+
+		if err := exporter.Export(t.Context(), []*Span{testExportSpan()}); err == nil {
+			t.Fatal("expected error")
+		}
+		if calls := collector.calls.Load(); calls != 4 {
+			t.Errorf("calls = %d, want 4", calls)
+		}
+		if exporter.FailedCount() != 1 {
+			t.Errorf("FailedCount = %d", exporter.FailedCount())
+		}
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		collector := newTestCollector(t, http.StatusBadRequest)
+		exporter, _ := NewOTLPExporter(fast(collector.URL))
+
+		err := exporter.Export(t.Context(), []*Span{testExportSpan()})
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+		if calls := collector.calls.Load(); calls != 1 {
+			t.Errorf("calls = %d, want 1", calls)
+		}
+	})
+}
+
+// recordingRepository 记录保存的 Span
+type recordingRepository struct {
+	mockRepository
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *recordingRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return BatchSaveResult{Total: len(spans), Success: len(spans)}, nil
+}
+
+func TestTracerExportModes(t *testing.T) {
+	tests := []struct {
+		mode     ExportMode
+		stored   int
+		exported int32
+	}{
+		{ExportModeStore, 3, 0},
+		{ExportModeOTLP, 0, 2},
+		{ExportModeBoth, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			collector := newTestCollector(t)
+			repo := &recordingRepository{}
+
+			config := applyDefaults(Config{
+				Mode:          ModeFull,
+				BatchSize:     2,
+				FlushInterval: time.Hour,
+				ExportMode:    tt.mode,
+				Repository:    repo,
+				OTLP:          OTLPConfig{Endpoint: collector.URL},
+			})
+			tracer, err := newTracer(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 3; i++ {
+				tracer.RecordSpan(testExportSpan())
+			}
+			if err := tracer.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if len(repo.spans) != tt.stored {
+				t.Errorf("stored = %d, want %d", len(repo.spans), tt.stored)
+			}
+			if calls := collector.calls.Load(); calls != tt.exported {
+				t.Errorf("export calls = %d, want %d", calls, tt.exported)
+			}
+		})
+	}
+}
+
+func TestRegister_InvalidExportConfig(t *testing.T) {
+	for _, config := range []Config{
+		{ExportMode: "kafka"},
+		{ExportMode: ExportModeOTLP},
+		{ExportMode: ExportModeBoth, OTLP: OTLPConfig{Endpoint: "not a url"}},
+	} {
+		if err := Register(&mockRegisterApp{}, config); err == nil {
+			t.Errorf("expected Register error for %+v", config)
+		}
+	}
+}
+
+// decodeTestProto 解析一层 Protobuf 消息：varint/fixed64 为 uint64，length-delimited 为 []byte
+func decodeTestProto(t *testing.T, b []byte) map[int][]any {
+	t.Helper()
+
+	fields := map[int][]any{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid tag")
+		}
+		b = b[n:]
+		field, wireType := int(key>>3), int(key&7)
+
+		switch wireType {
+		case protoWireVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("invalid varint")
+			}
+			fields[field] = append(fields[field], v)
+			b = b[n:]
+		case protoWireFixed64:
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case protoWireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || int(size) > len(b)-n {
+				t.Fatalf("invalid length")
+			}
+			fields[field] = append(fields[field], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", wireType)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,366 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// MaxRequestChildSpans 单个请求最多收集的子 Span 数，超出部分被丢弃并记录在
+// 请求 Span 的 trace.dropped_spans 属性中
+const MaxRequestChildSpans = 512
+
+// spanRecorder 收集一次请求内产生的子 Span。
+//
+// 条件采集模式下是否记录请求要到请求结束后才能确定，因此子 Span 先暂存在这里，
+// 由 TraceMiddleware 在请求被采集时与请求 Span 一起记录，否则整体丢弃。
+//
+// stack 记录正在执行的 hook 处理器 Span，处理器内部产生的数据库/文件/HTTP Span
+// 以栈顶为父 Span。请求内的处理器按顺序执行，请求内自行启动的并发 goroutine
+// 产生的 Span 可能被挂到当时正在执行的处理器下。
+type spanRecorder struct {
+	traceID string
+	rootID  string
+
+	mu       sync.Mutex
+	spans    []*Span
+	stack    []string
+	dropped  int
+	finished bool
+}
+
+// newSpanRecorder 创建请求级 Span 收集器，rootID 为请求 Span 的 SpanID
+func newSpanRecorder(traceID, rootID string) *spanRecorder {
+	return &spanRecorder{
+		traceID: traceID,
+		rootID:  rootID,
+	}
+}
+
+// current 返回新子 Span 的默认父 Span ID
+func (r *spanRecorder) current() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n := len(r.stack); n > 0 {
+		return r.stack[n-1]
+	}
+	return r.rootID
+}
+
+// push 将正在执行的处理器 Span 压栈
+func (r *spanRecorder) push(spanID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stack = append(r.stack, spanID)
+}
+
+// pop 将处理器 Span 出栈
+func (r *spanRecorder) pop(spanID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.stack) - 1; i >= 0; i-- {
+		if r.stack[i] == spanID {
+			r.stack = append(r.stack[:i], r.stack[i+1:]...)
+			return
+		}
+	}
+}
+
+// add 暂存一个已完成的子 Span，请求结束后产生的 Span 被忽略
+func (r *spanRecorder) add(span *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.finished {
+		return
+	}
+	if len(r.spans) >= MaxRequestChildSpans {
+		r.dropped++
+		return
+	}
+	r.spans = append(r.spans, span)
+}
+
+// finish 结束收集，返回暂存的子 Span 与丢弃数
+func (r *spanRecorder) finish() ([]*Span, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.finished = true
+	spans := r.spans
+	r.spans = nil
+	return spans, r.dropped
+}
+
+// contextWithRecorder 将请求级 Span 收集器存入 context
+func contextWithRecorder(ctx context.Context, r *spanRecorder) context.Context {
+	return context.WithValue(ctx, recorderContextKey, r)
+}
+
+// recorderFromContext 从 context 获取请求级 Span 收集器
+func recorderFromContext(ctx context.Context) *spanRecorder {
+	if ctx == nil {
+		return nil
+	}
+	if r, ok := ctx.Value(recorderContextKey).(*spanRecorder); ok {
+		return r
+	}
+	return nil
+}
+
+// StartChildSpan 在 ctx 所属请求的追踪下开始一个子 Span。
+//
+// 父 Span 为 ctx 中属于同一追踪的 Span，否则为当前正在执行的 hook 处理器 Span 或请求 Span。
+// ctx 不在被追踪的请求中时返回原 ctx 和 NoopSpanBuilder，调用方无需额外判断。
+func StartChildSpan(ctx context.Context, name string) (context.Context, SpanBuilder) {
+	r := recorderFromContext(ctx)
+	if r == nil {
+		return ctx, &NoopSpanBuilder{}
+	}
+
+	builder := r.start(ctx, name)
+	return ContextWithSpan(ctx, builder.span), builder
+}
+
+// start 创建子 Span，结束时暂存到收集器
+func (r *spanRecorder) start(ctx context.Context, name string) *spanBuilder {
+	parentID := ""
+	if parent := SpanFromContext(ctx); parent != nil && parent.TraceID == r.traceID {
+		parentID = parent.SpanID
+	} else {
+		parentID = r.current()
+	}
+
+	span := &Span{
+		ID:       GenerateSpanID(),
+		TraceID:  r.traceID,
+		SpanID:   GenerateSpanID(),
+		ParentID: parentID,
+		Name:     name,
+		Kind:     SpanKindInternal,
+		Status:   SpanStatusUnset,
+	}
+
+	return newSpanBuilder(span, time.Now(), r.add)
+}
+
+// InjectTraceparent 将 ctx 中当前 Span 的 W3C traceparent 写入出站请求头，
+// ctx 中没有 Span 时不做任何修改。
+func InjectTraceparent(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil || span.TraceID == "" || span.SpanID == "" {
+		return
+	}
+
+	header.Set("traceparent", FormatTraceparent(&TraceContext{
+		TraceID:  span.TraceID,
+		ParentID: span.SpanID,
+		Sampled:  true,
+	}))
+}
+
+// spanBuilder 是 SpanBuilder 的实现，End 时计算耗时并交给 record
+type spanBuilder struct {
+	mu     sync.Mutex
+	span   *Span
+	start  time.Time
+	record func(*Span)
+	ended  bool
+}
+
+// newSpanBuilder 创建 spanBuilder
+func newSpanBuilder(span *Span, start time.Time, record func(*Span)) *spanBuilder {
+	span.StartTime = start.UnixMicro()
+	return &spanBuilder{
+		span:   span,
+		start:  start,
+		record: record,
+	}
+}
+
+// SetAttribute 设置 Span 属性
+func (b *spanBuilder) SetAttribute(key string, value any) SpanBuilder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.span.Attributes == nil {
+		b.span.Attributes = make(map[string]any)
+	}
+	b.span.Attributes[key] = value
+	return b
+}
+
+// SetStatus 设置 Span 状态及状态描述
+func (b *spanBuilder) SetStatus(status SpanStatus, message string) SpanBuilder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.span.Status = status
+	b.span.StatusMessage = message
+	return b
+}
+
+// SetKind 设置 Span 类型
+func (b *spanBuilder) SetKind(kind SpanKind) SpanBuilder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.span.Kind = kind
+	return b
+}
+
+// AddEvent 添加一个以当前时间为时间戳的事件，超过 MaxSpanEvents 的事件被丢弃
+func (b *spanBuilder) AddEvent(name string, attributes map[string]any) SpanBuilder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.span.Events) < MaxSpanEvents {
+		b.span.Events = append(b.span.Events, SpanEvent{
+			Name:       name,
+			Time:       time.Now().UnixMicro(),
+			Attributes: attributes,
+		})
+	}
+	return b
+}
+
+// RecordError 记录异常事件并将状态设为 error
+func (b *spanBuilder) RecordError(err error) SpanBuilder {
+	if err == nil {
+		return b
+	}
+
+	b.AddEvent(SpanEventException, exceptionAttributes(err))
+	return b.SetStatus(SpanStatusError, err.Error())
+}
+
+// AddLink 添加指向另一个 Span 的链接，ID 为空或超过 MaxSpanLinks 的链接被丢弃
+func (b *spanBuilder) AddLink(traceID, spanID string, attributes map[string]any) SpanBuilder {
+	if traceID == "" || spanID == "" {
+		return b
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.span.Links) < MaxSpanLinks {
+		b.span.Links = append(b.span.Links, SpanLink{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			Attributes: attributes,
+		})
+	}
+	return b
+}
+
+// maxStacktraceLength exception.stacktrace 属性的最大长度
+const maxStacktraceLength = 8192
+
+// exceptionAttributes 返回异常事件的属性：错误类型、消息与当前 goroutine 的堆栈
+func exceptionAttributes(err error) map[string]any {
+	stack := string(debug.Stack())
+	if len(stack) > maxStacktraceLength {
+		stack = stack[:maxStacktraceLength]
+	}
+
+	return map[string]any{
+		"exception.type":       fmt.Sprintf("%T", err),
+		"exception.message":    err.Error(),
+		"exception.stacktrace": stack,
+	}
+}
+
+// End 结束 Span，重复调用无效
+func (b *spanBuilder) End() {
+	b.mu.Lock()
+	if b.ended {
+		b.mu.Unlock()
+		return
+	}
+	b.ended = true
+	b.span.Duration = time.Since(b.start).Microseconds()
+	b.span.Created = types.NowDateTime()
+	b.mu.Unlock()
+
+	if b.record != nil {
+		b.record(b.span)
+	}
+}
+
+// endAt 以指定耗时结束 Span（用于事后才得知耗时的场景，如数据库查询日志回调）
+func (b *spanBuilder) endAt(duration time.Duration) {
+	b.mu.Lock()
+	b.ended = true
+	b.span.StartTime = time.Now().Add(-duration).UnixMicro()
+	b.span.Duration = duration.Microseconds()
+	b.span.Created = types.NowDateTime()
+	b.mu.Unlock()
+
+	if b.record != nil {
+		b.record(b.span)
+	}
+}
+
+var (
+	contextType     = reflect.TypeOf((*context.Context)(nil)).Elem()
+	requestType     = reflect.TypeOf((*http.Request)(nil))
+	eventContextMap sync.Map // reflect.Type -> eventContextField
+)
+
+// eventContextField 事件类型中携带 context 的字段
+type eventContextField struct {
+	index     []int
+	isRequest bool
+	found     bool
+}
+
+// EventContext 返回 hook 事件携带的 context：
+// 模型事件的 Context 字段，或请求事件的 Request.Context()。
+// 事件不携带 context 时返回 nil。
+func EventContext(event any) context.Context {
+	v := reflect.ValueOf(event)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := lookupEventContextField(v.Type())
+	if !field.found {
+		return nil
+	}
+
+	fv, err := v.Elem().FieldByIndexErr(field.index)
+	if err != nil || fv.IsNil() {
+		return nil
+	}
+
+	if field.isRequest {
+		return fv.Interface().(*http.Request).Context()
+	}
+	return fv.Interface().(context.Context)
+}
+
+// lookupEventContextField 查找并缓存事件类型的 context 字段
+func lookupEventContextField(t reflect.Type) eventContextField {
+	if cached, ok := eventContextMap.Load(t); ok {
+		return cached.(eventContextField)
+	}
+
+	var field eventContextField
+	st := t.Elem()
+	if f, ok := st.FieldByName("Context"); ok && f.Type == contextType {
+		field = eventContextField{index: f.Index, found: true}
+	} else if f, ok := st.FieldByName("Request"); ok && f.Type == requestType {
+		field = eventContextField{index: f.Index, isRequest: true, found: true}
+	}
+
+	eventContextMap.Store(t, field)
+	return field
+}
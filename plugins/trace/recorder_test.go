@@ -0,0 +1,263 @@
+package trace
+
+import (
+	"context"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// collectingTracer 记录所有 Span 的 mock tracer
+type collectingTracer struct {
+	mockTracer
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (c *collectingTracer) RecordSpan(span *Span) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, span)
+}
+
+func (c *collectingTracer) find(name string) *Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestStartChildSpan(t *testing.T) {
+	t.Run("no recorder", func(t *testing.T) {
+		ctx := context.Background()
+		newCtx, builder := StartChildSpan(ctx, "child")
+		if newCtx != ctx {
+			t.Error("expected same context")
+		}
+		if _, ok := builder.(*NoopSpanBuilder); !ok {
+			t.Error("expected NoopSpanBuilder")
+		}
+	})
+
+	t.Run("parent resolution", func(t *testing.T) {
+		traceID, rootID := GenerateTraceID(), GenerateSpanID()
+		r := newSpanRecorder(traceID, rootID)
+		ctx := contextWithRecorder(context.Background(), r)
+
+		// 默认挂在请求 Span 下
+		childCtx, child := StartChildSpan(ctx, "child")
+
+		// ctx 中的 Span 优先
+		_, nested := StartChildSpan(childCtx, "nested")
+
+		// 正在执行的处理器 Span 优先于请求 Span
+		r.push("handler")
+		_, inHandler := StartChildSpan(ctx, "in-handler")
+		r.pop("handler")
+
+		inHandler.End()
+		nested.End()
+		child.End()
+
+		spans, dropped := r.finish()
+		if dropped != 0 || len(spans) != 3 {
+			t.Fatalf("expected 3 spans and 0 dropped, got %d/%d", len(spans), dropped)
+		}
+
+		childSpan := SpanFromContext(childCtx)
+		expectedParents := map[string]string{
+			"child":      rootID,
+			"nested":     childSpan.SpanID,
+			"in-handler": "handler",
+		}
+		for _, span := range spans {
+			if span.TraceID != traceID {
+				t.Errorf("%s: expected trace %s, got %s", span.Name, traceID, span.TraceID)
+			}
+			if span.ParentID != expectedParents[span.Name] {
+				t.Errorf("%s: expected parent %s, got %s", span.Name, expectedParents[span.Name], span.ParentID)
+			}
+		}
+
+		// 请求结束后的 Span 被忽略
+		_, late := StartChildSpan(ctx, "late")
+		late.End()
+		if spans, _ := r.finish(); len(spans) != 0 {
+			t.Errorf("expected late span to be ignored, got %d", len(spans))
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		r := newSpanRecorder(GenerateTraceID(), GenerateSpanID())
+		ctx := contextWithRecorder(context.Background(), r)
+
+		for i := 0; i < MaxRequestChildSpans+3; i++ {
+			_, span := StartChildSpan(ctx, "child")
+			span.End()
+		}
+
+		spans, dropped := r.finish()
+		if len(spans) != MaxRequestChildSpans || dropped != 3 {
+			t.Errorf("expected %d spans and 3 dropped, got %d/%d", MaxRequestChildSpans, len(spans), dropped)
+		}
+	})
+}
+
+func TestTraceMiddlewareChildSpans(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc := TraceContextFromContext(r.Context())
+		if tc == nil {
+			t.Error("expected TraceContext in request context")
+		}
+
+		_, span := StartChildSpan(r.Context(), "child")
+		span.End()
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("records children with request span", func(t *testing.T) {
+		tracer := &collectingTracer{mockTracer: mockTracer{enabled: true}}
+		middleware := TraceMiddleware(tracer, &MiddlewareConfig{Mode: ModeFull})
+
+		req := httptest.NewRequest("GET", "/api/test", nil)
+		req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		middleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		requestSpan := tracer.find("GET /api/test")
+		child := tracer.find("child")
+		if requestSpan == nil || child == nil {
+			t.Fatalf("expected request and child spans, got %d spans", len(tracer.spans))
+		}
+		if requestSpan.ParentID != "b7ad6b7169203331" {
+			t.Errorf("expected request span parent from traceparent, got %s", requestSpan.ParentID)
+		}
+		if child.TraceID != "0af7651916cd43dd8448eb211c80319c" || child.ParentID != requestSpan.SpanID {
+			t.Errorf("expected child under request span, got %+v", child)
+		}
+	})
+
+	t.Run("drops children of untraced request", func(t *testing.T) {
+		tracer := &collectingTracer{mockTracer: mockTracer{enabled: true}}
+		middleware := TraceMiddleware(tracer, &MiddlewareConfig{Mode: ModeConditional})
+
+		middleware(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/test", nil))
+
+		if len(tracer.spans) != 0 {
+			t.Errorf("expected no spans, got %d", len(tracer.spans))
+		}
+	})
+}
+
+func TestSpanBuilderDetails(t *testing.T) {
+	var recorded *Span
+	builder := newSpanBuilder(&Span{Name: "job"}, time.Now(), func(span *Span) { recorded = span })
+
+	builder.AddEvent("retry #2", map[string]any{"attempt": 2})
+	builder.RecordError(nil)
+	builder.RecordError(fs.ErrNotExist)
+	builder.AddLink("", "", nil)
+	builder.AddLink("trace-1", "span-1", map[string]any{"job.id": "1"})
+	for i := 0; i < MaxSpanLinks; i++ {
+		builder.AddLink("trace-x", "span-x", nil)
+	}
+	for i := 0; i < MaxSpanEvents; i++ {
+		builder.AddEvent("overflow", nil)
+	}
+	builder.End()
+
+	if recorded == nil {
+		t.Fatal("expected span to be recorded")
+	}
+	if recorded.Status != SpanStatusError || recorded.StatusMessage != fs.ErrNotExist.Error() {
+		t.Errorf("expected error status from RecordError, got %s %q", recorded.Status, recorded.StatusMessage)
+	}
+	if len(recorded.Events) != MaxSpanEvents || len(recorded.Links) != MaxSpanLinks {
+		t.Fatalf("expected events/links to be capped, got %d/%d", len(recorded.Events), len(recorded.Links))
+	}
+
+	retry, exception := recorded.Events[0], recorded.Events[1]
+	if retry.Name != "retry #2" || retry.Attributes["attempt"] != 2 || retry.Time < recorded.StartTime {
+		t.Errorf("unexpected event %+v", retry)
+	}
+	if exception.Name != SpanEventException ||
+		exception.Attributes["exception.type"] != "*errors.errorString" ||
+		exception.Attributes["exception.message"] != fs.ErrNotExist.Error() ||
+		!strings.Contains(exception.Attributes["exception.stacktrace"].(string), "TestSpanBuilderDetails") {
+		t.Errorf("unexpected exception event %+v", exception.Attributes)
+	}
+
+	if link := recorded.Links[0]; link.TraceID != "trace-1" || link.SpanID != "span-1" || link.Attributes["job.id"] != "1" {
+		t.Errorf("unexpected link %+v", link)
+	}
+}
+
+func TestInjectTraceparent(t *testing.T) {
+	header := http.Header{}
+	InjectTraceparent(context.Background(), header)
+	if header.Get("traceparent") != "" {
+		t.Error("expected no traceparent without span")
+	}
+
+	span := &Span{TraceID: GenerateTraceID(), SpanID: GenerateSpanID()}
+	InjectTraceparent(ContextWithSpan(context.Background(), span), header)
+
+	expected := "00-" + span.TraceID + "-" + span.SpanID + "-01"
+	if got := header.Get("traceparent"); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestEventContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "test")
+
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	requestEvent := &core.RequestEvent{}
+	requestEvent.Request = req
+
+	modelEvent := &core.ModelEvent{}
+	modelEvent.Context = ctx
+
+	recordRequestEvent := &core.RecordRequestEvent{RequestEvent: requestEvent}
+
+	scenarios := []struct {
+		name     string
+		event    any
+		expected bool
+	}{
+		{"nil", nil, false},
+		{"non pointer", core.ModelEvent{}, false},
+		{"model event", modelEvent, true},
+		{"model event without context", &core.ModelEvent{}, false},
+		{"request event", requestEvent, true},
+		{"embedded request event", recordRequestEvent, true},
+		{"nil embedded request event", &core.RecordRequestEvent{}, false},
+		{"event without context", &core.BootstrapEvent{}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result := EventContext(s.event)
+			if !s.expected {
+				if result != nil {
+					t.Errorf("expected nil context, got %v", result)
+				}
+				return
+			}
+			if result == nil || result.Value(ctxKey{}) != "test" {
+				t.Errorf("expected event context, got %v", result)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package trace
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/plugins/trace/dye"
@@ -30,6 +33,13 @@ func Register(app core.App, config Config) error {
 	// 应用默认值
 	config = applyDefaults(config)
 
+	if !config.ExportMode.IsValid() {
+		return fmt.Errorf("trace: invalid export mode %q", config.ExportMode)
+	}
+	if !config.DyeStorage.IsValid() {
+		return fmt.Errorf("trace: invalid dye storage %q", config.DyeStorage)
+	}
+
 	var tracer Tracer
 
 	// 如果模式为 Off，注册 NoopTracer
@@ -37,7 +47,30 @@ func Register(app core.App, config Config) error {
 		tracer = NewNoopTrace()
 	} else {
 		// 创建 Tracer 实例
-		tracer = newTracer(config)
+		impl, err := newTracer(config)
+		if err != nil {
+			return err
+		}
+		if config.DyeStorage == DyeStorageDB {
+			if err := registerDBDyeStore(app, impl, config); err != nil {
+				_ = impl.Close()
+				return err
+			}
+		}
+		tracer = impl
+	}
+
+	// 自动子 Span：hook 处理器、文件上传与数据库查询
+	if config.Mode != ModeOff && !config.DisableAutoSpans {
+		installObservers()
+
+		app.OnBootstrap().BindFunc(func(e *core.BootstrapEvent) error {
+			if err := e.Next(); err != nil {
+				return err
+			}
+			instrumentDB(e.App)
+			return nil
+		})
 	}
 
 	// 注册到全局 registry
@@ -70,31 +103,112 @@ func GetTracer(app core.App) Tracer {
 	return NewNoopTrace()
 }
 
+// finalFlushTimeout 关闭时最后一次刷新的超时时间
+const finalFlushTimeout = 10 * time.Second
+
 // newTracer 创建一个新的 Tracer 实例
-func newTracer(config Config) *traceImpl {
+func newTracer(config Config) (*traceImpl, error) {
 	tracer := &traceImpl{
 		config: config,
+		buffer: NewRingBuffer(config.BufferSize),
+	}
+
+	// 初始化输出：本地存储与 OTLP 导出
+	if config.ExportMode.Stores() {
+		tracer.repo = config.Repository
+	}
+	if config.ExportMode.Exports() {
+		exporter, err := NewOTLPExporter(config.OTLP)
+		if err != nil {
+			return nil, fmt.Errorf("trace: %w", err)
+		}
+		tracer.exporter = exporter
+	}
+
+	// 初始化尾部采样，保留的追踪再进入缓冲区
+	if config.TailSampling.Enabled {
+		tracer.tail = newTailSampler(config.TailSampling, func(span *Span) {
+			tracer.buffer.Push(span)
+		})
+	}
+
+	// 初始化聚合统计，随缓冲区刷新累计
+	if !config.Stats.Disabled {
+		tracer.stats = NewSpanStats(config.OTLP.ServiceName, config.Stats)
 	}
 
-	// 初始化 DyeStore
-	if config.DyeMaxUsers > 0 {
+	// 初始化 DyeStore（db 存储需要 App 数据库，由 Register 创建）
+	if config.DyeMaxUsers > 0 && config.DyeStorage != DyeStorageDB {
 		tracer.dyeStore = dye.NewMemoryDyeStore(config.DyeMaxUsers, config.DyeDefaultTTL)
+		presetDyeUsers(tracer.dyeStore, config)
+	}
 
-		// 预设染色用户
-		for _, userID := range config.DyeUsers {
-			if strings.TrimSpace(userID) != "" {
-				_ = tracer.dyeStore.Add(userID, config.DyeDefaultTTL, "config", "preset")
-			}
+	// 有输出目标时启动定时刷新
+	if tracer.repo != nil || tracer.exporter != nil {
+		tracer.stopCh = make(chan struct{})
+		tracer.doneCh = make(chan struct{})
+		go tracer.run()
+	}
+
+	return tracer, nil
+}
+
+// registerDBDyeStore 为 tracer 创建数据库染色存储，在 App 引导完成（数据库初始化）后启动
+func registerDBDyeStore(app core.App, tracer *traceImpl, config Config) error {
+	store := dye.NewDBDyeStore(app, config.DyeMaxUsers, config.DyeDefaultTTL, config.DyeSyncInterval)
+	tracer.dyeStore = store
+
+	start := func() error {
+		if err := store.Start(); err != nil {
+			return fmt.Errorf("trace: failed to start dye store: %w", err)
 		}
+		presetDyeUsers(store, config)
+		return nil
+	}
+
+	if app.IsBootstrapped() {
+		return start()
 	}
 
-	return tracer
+	app.OnBootstrap().BindFunc(func(e *core.BootstrapEvent) error {
+		if err := e.Next(); err != nil {
+			return err
+		}
+		return start()
+	})
+
+	return nil
+}
+
+// presetDyeUsers 添加配置中预设的染色用户
+func presetDyeUsers(store dye.DyeStore, config Config) {
+	for _, userID := range config.DyeUsers {
+		if strings.TrimSpace(userID) != "" {
+			_ = store.Add(userID, config.DyeDefaultTTL, "config", "preset")
+		}
+	}
 }
 
 // traceImpl 是 Tracer 接口的实现
 type traceImpl struct {
 	config   Config
 	dyeStore dye.DyeStore
+
+	// tail 启用尾部采样时按追踪暂存 Span，决策保留后再写入 buffer
+	tail *tailSampler
+
+	// stats 刷新时聚合的 RED 指标与服务依赖图
+	stats *SpanStats
+
+	// buffer 暂存已完成的 Span，定时批量写入 repo 和/或 exporter
+	buffer   *RingBuffer
+	repo     TraceRepository
+	exporter SpanExporter
+
+	flushMu   sync.Mutex
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	closeOnce sync.Once
 }
 
 // DyeStore 返回染色存储
@@ -102,13 +216,61 @@ func (t *traceImpl) DyeStore() dye.DyeStore {
 	return t.dyeStore
 }
 
+// Stats 返回 Span 聚合统计，未启用时返回 nil
+func (t *traceImpl) Stats() *SpanStats {
+	return t.stats
+}
+
+// StartSpan 创建并开始一个新的 Span。
+// ctx 属于被追踪的请求时作为请求的子 Span 随请求一起记录，
+// 否则以 ctx 中的 Span 或 TraceContext 为父（都没有时开始新的 trace），End 时直接记录。
 func (t *traceImpl) StartSpan(ctx context.Context, name string) (context.Context, SpanBuilder) {
-	// TODO: Phase 2 实现完整的 Span 创建
-	return ctx, &NoopSpanBuilder{}
+	if !t.IsEnabled() {
+		return ctx, &NoopSpanBuilder{}
+	}
+
+	if r := recorderFromContext(ctx); r != nil {
+		builder := r.start(ctx, name)
+		return ContextWithSpan(ctx, builder.span), builder
+	}
+
+	span := &Span{
+		ID:     GenerateSpanID(),
+		SpanID: GenerateSpanID(),
+		Name:   name,
+		Kind:   SpanKindInternal,
+		Status: SpanStatusUnset,
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil && parent.TraceID != "" {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else if tc := TraceContextFromContext(ctx); tc != nil && tc.TraceID != "" {
+		span.TraceID = tc.TraceID
+		span.ParentID = tc.ParentID
+	} else {
+		span.TraceID = GenerateTraceID()
+	}
+
+	builder := newSpanBuilder(span, time.Now(), t.RecordSpan)
+	return ContextWithSpan(ctx, builder.span), builder
 }
 
 func (t *traceImpl) RecordSpan(span *Span) {
-	// TODO: Phase 2 实现 Span 记录
+	if span == nil || t.buffer == nil {
+		return
+	}
+	if t.tail != nil {
+		t.tail.add(span)
+		return
+	}
+	t.buffer.Push(span)
+}
+
+// TailSamplingEnabled 返回是否启用了尾部采样。
+// 启用时 TraceMiddleware 记录所有请求，由尾部采样决定保留哪些追踪。
+func (t *traceImpl) TailSamplingEnabled() bool {
+	return t.tail != nil
 }
 
 func (t *traceImpl) IsEnabled() bool {
@@ -116,7 +278,66 @@ func (t *traceImpl) IsEnabled() bool {
 }
 
 func (t *traceImpl) Flush() {
-	// TODO: Phase 2 实现缓冲区刷新
+	_ = t.flush(context.Background())
+}
+
+// flush 将缓冲区中的 Span 按 BatchSize 分批写入本地存储并导出。
+// 单个批次失败不影响其他批次，返回所有错误的合集。
+func (t *traceImpl) flush(ctx context.Context) error {
+	if t.buffer == nil {
+		return nil
+	}
+
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	// 等待超时的追踪先完成尾部采样决策
+	if t.tail != nil {
+		t.tail.sweep(time.Now())
+	}
+
+	var errs []error
+	for {
+		batch := t.buffer.Flush(t.config.BatchSize)
+		if len(batch) == 0 {
+			break
+		}
+
+		if t.stats != nil {
+			t.stats.Record(batch)
+		}
+
+		if t.repo != nil {
+			if _, err := t.repo.SaveBatch(batch); err != nil {
+				errs = append(errs, fmt.Errorf("failed to store spans: %w", err))
+			}
+		}
+
+		if t.exporter != nil {
+			if err := t.exporter.Export(ctx, batch); err != nil {
+				errs = append(errs, fmt.Errorf("failed to export spans: %w", err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// run 定时刷新缓冲区，直到 Close 被调用
+func (t *traceImpl) run() {
+	defer close(t.doneCh)
+
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stopCh:
+			return
+		case <-ticker.C:
+			t.Flush()
+		}
+	}
 }
 
 func (t *traceImpl) Prune() (int64, error) {
@@ -125,10 +346,40 @@ func (t *traceImpl) Prune() (int64, error) {
 }
 
 func (t *traceImpl) Close() error {
-	if t.dyeStore != nil {
-		return t.dyeStore.Close()
-	}
-	return nil
+	var errs []error
+
+	t.closeOnce.Do(func() {
+		if t.stopCh != nil {
+			close(t.stopCh)
+			<-t.doneCh
+		}
+
+		// 等待中的追踪立即决策后刷新剩余的 Span
+		if t.tail != nil {
+			t.tail.drain()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), finalFlushTimeout)
+		defer cancel()
+
+		if err := t.flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		if t.exporter != nil {
+			if err := t.exporter.Shutdown(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if t.dyeStore != nil {
+			if err := t.dyeStore.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+
+	return errors.Join(errs...)
 }
 
 // 确保实现了 Tracer 接口
@@ -110,6 +110,14 @@ func TestGetTracer(t *testing.T) {
 type mockRegisterApp struct {
 	core.App
 	onTerminate *hook.Hook[*core.TerminateEvent]
+	onBootstrap *hook.Hook[*core.BootstrapEvent]
+}
+
+func (m *mockRegisterApp) OnBootstrap() *hook.Hook[*core.BootstrapEvent] {
+	if m.onBootstrap == nil {
+		m.onBootstrap = &hook.Hook[*core.BootstrapEvent]{}
+	}
+	return m.onBootstrap
 }
 
 func (m *mockRegisterApp) OnTerminate() *hook.Hook[*core.TerminateEvent] {
@@ -2,6 +2,7 @@
 package trace
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -66,6 +67,12 @@ type TraceQueryOptions struct {
 	// AttributeFilters 属性过滤条件（key=value 形式）
 	AttributeFilters map[string]any
 
+	// EventName 只返回包含指定名称事件的 Span（如 "exception"）
+	EventName string
+
+	// LinkedTraceID 只返回链接到指定 TraceID 的 Span
+	LinkedTraceID string
+
 	// OrderBy 排序字段，默认按开始时间降序
 	OrderBy string
 
@@ -93,3 +100,23 @@ type BatchSaveResult struct {
 	// Errors 具体的错误信息列表
 	Errors []error
 }
+
+// spanColumns 查询 Span 时选择的列，顺序与各存储实现的 scanSpan 一致
+const spanColumns = "id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, status_message, attributes, events, links, created"
+
+// rowScanner 是 *sql.Row 与 *sql.Rows 的公共接口
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// nullableJSON 将非空切片编码为 JSON 字符串，空切片返回 nil 以存储为 NULL
+func nullableJSON[T any](items []T) any {
+	if len(items) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil
+	}
+	return string(data)
+}
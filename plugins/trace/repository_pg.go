@@ -64,16 +64,25 @@ func (r *PostgresRepository) initSchema() error {
 			start_time  BIGINT NOT NULL,
 			duration    BIGINT NOT NULL,
 			status      TEXT NOT NULL,
+			status_message TEXT NOT NULL DEFAULT '',
 			attributes  JSONB,
+			events      JSONB,
+			links       JSONB,
 			created     TIMESTAMP WITH TIME ZONE NOT NULL
 		);
 
+		-- 补充旧版本表结构中缺少的列
+		ALTER TABLE _trace_spans ADD COLUMN IF NOT EXISTS status_message TEXT NOT NULL DEFAULT '';
+		ALTER TABLE _trace_spans ADD COLUMN IF NOT EXISTS events JSONB;
+		ALTER TABLE _trace_spans ADD COLUMN IF NOT EXISTS links JSONB;
+
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_trace_id ON _trace_spans(trace_id);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_span_id ON _trace_spans(span_id);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_start_time ON _trace_spans(start_time);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_status ON _trace_spans(status);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_created ON _trace_spans(created);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_attributes ON _trace_spans USING GIN (attributes);
+		CREATE INDEX IF NOT EXISTS idx_trace_spans_links ON _trace_spans USING GIN (links);
 	`
 	_, err := r.db.Exec(schema)
 	return err
@@ -90,8 +99,8 @@ func (r *PostgresRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 	// PostgreSQL 支持 ON CONFLICT，可以安全处理冲突
 	stmt, err := r.db.Prepare(`
 		INSERT INTO _trace_spans 
-		(id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		(`+spanColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		ON CONFLICT (id) DO UPDATE SET
 			trace_id = EXCLUDED.trace_id,
 			span_id = EXCLUDED.span_id,
@@ -101,7 +110,10 @@ func (r *PostgresRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 			start_time = EXCLUDED.start_time,
 			duration = EXCLUDED.duration,
 			status = EXCLUDED.status,
+			status_message = EXCLUDED.status_message,
 			attributes = EXCLUDED.attributes,
+			events = EXCLUDED.events,
+			links = EXCLUDED.links,
 			created = EXCLUDED.created
 	`)
 	if err != nil {
@@ -131,7 +143,10 @@ func (r *PostgresRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 			span.StartTime,
 			span.Duration,
 			string(span.Status),
+			span.StatusMessage,
 			attrsJSON,
+			nullableJSON(span.Events),
+			nullableJSON(span.Links),
 			span.Created.Time().UTC(),
 		)
 		if err != nil {
@@ -148,7 +163,7 @@ func (r *PostgresRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 // FindByTraceID 根据 TraceID 查找所有相关 Span
 func (r *PostgresRepository) FindByTraceID(traceID string) ([]*Span, error) {
 	rows, err := r.db.Query(`
-		SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created
+		SELECT `+spanColumns+`
 		FROM _trace_spans
 		WHERE trace_id = $1
 		ORDER BY start_time ASC
@@ -164,7 +179,7 @@ func (r *PostgresRepository) FindByTraceID(traceID string) ([]*Span, error) {
 // FindBySpanID 根据 SpanID 查找单个 Span
 func (r *PostgresRepository) FindBySpanID(spanID string) (*Span, error) {
 	row := r.db.QueryRow(`
-		SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created
+		SELECT `+spanColumns+`
 		FROM _trace_spans
 		WHERE span_id = $1
 		LIMIT 1
@@ -238,7 +253,7 @@ func (r *PostgresRepository) buildQuery(opts TraceQueryOptions, countOnly bool)
 	if countOnly {
 		sb.WriteString("SELECT COUNT(*) FROM _trace_spans WHERE 1=1")
 	} else {
-		sb.WriteString("SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created FROM _trace_spans WHERE 1=1")
+		sb.WriteString("SELECT " + spanColumns + " FROM _trace_spans WHERE 1=1")
 	}
 
 	if opts.TraceID != "" {
@@ -310,6 +325,19 @@ func (r *PostgresRepository) buildQuery(opts TraceQueryOptions, countOnly bool)
 		argIndex++
 	}
 
+	// 事件与链接使用 JSONB 包含运算匹配数组中的元素
+	if opts.EventName != "" {
+		sb.WriteString(fmt.Sprintf(" AND events @> $%d::jsonb", argIndex))
+		args = append(args, nullableJSON([]map[string]string{{"name": opts.EventName}}))
+		argIndex++
+	}
+
+	if opts.LinkedTraceID != "" {
+		sb.WriteString(fmt.Sprintf(" AND links @> $%d::jsonb", argIndex))
+		args = append(args, nullableJSON([]map[string]string{{"traceId": opts.LinkedTraceID}}))
+		argIndex++
+	}
+
 	if !countOnly {
 		// 排序
 		orderBy := "start_time"
@@ -341,7 +369,7 @@ func (r *PostgresRepository) buildQuery(opts TraceQueryOptions, countOnly bool)
 func (r *PostgresRepository) scanSpans(rows *sql.Rows) ([]*Span, error) {
 	var spans []*Span
 	for rows.Next() {
-		span, err := r.scanSpanFromRows(rows)
+		span, err := r.scanSpan(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -351,13 +379,15 @@ func (r *PostgresRepository) scanSpans(rows *sql.Rows) ([]*Span, error) {
 }
 
 // scanSpan 扫描单行结果
-func (r *PostgresRepository) scanSpan(row *sql.Row) (*Span, error) {
+func (r *PostgresRepository) scanSpan(row rowScanner) (*Span, error) {
 	var (
-		span      Span
-		kind      string
-		status    string
-		attrsJSON []byte
-		created   time.Time
+		span       Span
+		kind       string
+		status     string
+		attrsJSON  []byte
+		eventsJSON []byte
+		linksJSON  []byte
+		created    time.Time
 	)
 
 	err := row.Scan(
@@ -370,7 +400,10 @@ func (r *PostgresRepository) scanSpan(row *sql.Row) (*Span, error) {
 		&span.StartTime,
 		&span.Duration,
 		&status,
+		&span.StatusMessage,
 		&attrsJSON,
+		&eventsJSON,
+		&linksJSON,
 		&created,
 	)
 	if err != nil {
@@ -383,44 +416,11 @@ func (r *PostgresRepository) scanSpan(row *sql.Row) (*Span, error) {
 	if len(attrsJSON) > 0 {
 		json.Unmarshal(attrsJSON, &span.Attributes)
 	}
-
-	span.Created, _ = types.ParseDateTime(created)
-
-	return &span, nil
-}
-
-// scanSpanFromRows 从 Rows 扫描单个 Span
-func (r *PostgresRepository) scanSpanFromRows(rows *sql.Rows) (*Span, error) {
-	var (
-		span      Span
-		kind      string
-		status    string
-		attrsJSON []byte
-		created   time.Time
-	)
-
-	err := rows.Scan(
-		&span.ID,
-		&span.TraceID,
-		&span.SpanID,
-		&span.ParentID,
-		&span.Name,
-		&kind,
-		&span.StartTime,
-		&span.Duration,
-		&status,
-		&attrsJSON,
-		&created,
-	)
-	if err != nil {
-		return nil, err
+	if len(eventsJSON) > 0 {
+		json.Unmarshal(eventsJSON, &span.Events)
 	}
-
-	span.Kind = SpanKind(kind)
-	span.Status = SpanStatus(status)
-
-	if len(attrsJSON) > 0 {
-		json.Unmarshal(attrsJSON, &span.Attributes)
+	if len(linksJSON) > 0 {
+		json.Unmarshal(linksJSON, &span.Links)
 	}
 
 	span.Created, _ = types.ParseDateTime(created)
@@ -1,4 +1,4 @@
-package trace
+package trace_test
 
 import (
 	"os"
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pocketbase/pocketbase/plugins/trace"
 	"github.com/pocketbase/pocketbase/tests"
 	"github.com/pocketbase/pocketbase/tools/types"
 )
@@ -58,7 +59,7 @@ func TestNewPostgresRepository(t *testing.T) {
 	dsn := skipIfNoPostgres(t)
 
 	t.Run("create with valid DSN", func(t *testing.T) {
-		repo, err := NewPostgresRepository(dsn)
+		repo, err := trace.NewPostgresRepository(dsn)
 		if err != nil {
 			t.Fatalf("NewPostgresRepository() error = %v", err)
 		}
@@ -70,7 +71,7 @@ func TestNewPostgresRepository(t *testing.T) {
 	})
 
 	t.Run("create with empty DSN", func(t *testing.T) {
-		_, err := NewPostgresRepository("")
+		_, err := trace.NewPostgresRepository("")
 		if err == nil {
 			t.Error("expected error for empty DSN")
 		}
@@ -97,20 +98,20 @@ func TestPostgresRepositorySaveBatch(t *testing.T) {
 
 	t.Run("save single span", func(t *testing.T) {
 		// 使用唯一 ID 避免冲突
-		spanID := GenerateSpanID()
-		span := &Span{
+		spanID := trace.GenerateSpanID()
+		span := &trace.Span{
 			ID:        "pg-span-" + spanID,
 			TraceID:   "pg-trace-" + spanID,
 			SpanID:    spanID,
 			Name:      "test-operation",
-			Kind:      SpanKindServer,
+			Kind:      trace.SpanKindServer,
 			StartTime: time.Now().UnixMicro(),
 			Duration:  1000,
-			Status:    SpanStatusOK,
+			Status:    trace.SpanStatusOK,
 			Created:   types.NowDateTime(),
 		}
 
-		result, err := repo.SaveBatch([]*Span{span})
+		result, err := repo.SaveBatch([]*trace.Span{span})
 		if err != nil {
 			t.Errorf("SaveBatch() error = %v", err)
 		}
@@ -121,18 +122,18 @@ func TestPostgresRepositorySaveBatch(t *testing.T) {
 	})
 
 	t.Run("save multiple spans", func(t *testing.T) {
-		traceID := "pg-trace-batch-" + GenerateSpanID()
-		spans := make([]*Span, 10)
+		traceID := "pg-trace-batch-" + trace.GenerateSpanID()
+		spans := make([]*trace.Span, 10)
 		for i := 0; i < 10; i++ {
-			spans[i] = &Span{
-				ID:        GenerateSpanID(),
+			spans[i] = &trace.Span{
+				ID:        trace.GenerateSpanID(),
 				TraceID:   traceID,
-				SpanID:    GenerateSpanID(),
+				SpanID:    trace.GenerateSpanID(),
 				Name:      "batch-operation",
-				Kind:      SpanKindInternal,
+				Kind:      trace.SpanKindInternal,
 				StartTime: time.Now().UnixMicro(),
 				Duration:  int64(i * 100),
-				Status:    SpanStatusOK,
+				Status:    trace.SpanStatusOK,
 				Created:   types.NowDateTime(),
 			}
 		}
@@ -147,16 +148,16 @@ func TestPostgresRepositorySaveBatch(t *testing.T) {
 	})
 
 	t.Run("save span with attributes", func(t *testing.T) {
-		spanID := GenerateSpanID()
-		span := &Span{
+		spanID := trace.GenerateSpanID()
+		span := &trace.Span{
 			ID:        "pg-span-attrs-" + spanID,
 			TraceID:   "pg-trace-attrs-" + spanID,
 			SpanID:    spanID,
 			Name:      "with-attrs",
-			Kind:      SpanKindServer,
+			Kind:      trace.SpanKindServer,
 			StartTime: time.Now().UnixMicro(),
 			Duration:  500,
-			Status:    SpanStatusOK,
+			Status:    trace.SpanStatusOK,
 			Attributes: map[string]any{
 				"http.method":      "GET",
 				"http.status_code": 200,
@@ -165,7 +166,7 @@ func TestPostgresRepositorySaveBatch(t *testing.T) {
 			Created: types.NowDateTime(),
 		}
 
-		result, err := repo.SaveBatch([]*Span{span})
+		result, err := repo.SaveBatch([]*trace.Span{span})
 		if err != nil {
 			t.Errorf("SaveBatch() error = %v", err)
 		}
@@ -184,11 +185,11 @@ func TestPostgresRepositoryFindByTraceID(t *testing.T) {
 	defer repo.Close()
 
 	// 准备测试数据 - 使用唯一 ID
-	traceID := "pg-test-trace-find-" + GenerateSpanID()
-	spans := []*Span{
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "op1", Kind: SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "op2", Kind: SpanKindInternal, StartTime: time.Now().UnixMicro(), Duration: 50, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: "pg-other-trace-" + GenerateSpanID(), SpanID: GenerateSpanID(), Name: "op3", Kind: SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 200, Status: SpanStatusOK, Created: types.NowDateTime()},
+	traceID := "pg-test-trace-find-" + trace.GenerateSpanID()
+	spans := []*trace.Span{
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "op1", Kind: trace.SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "op2", Kind: trace.SpanKindInternal, StartTime: time.Now().UnixMicro(), Duration: 50, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: "pg-other-trace-" + trace.GenerateSpanID(), SpanID: trace.GenerateSpanID(), Name: "op3", Kind: trace.SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 200, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
 	}
 	repo.SaveBatch(spans)
 
@@ -203,7 +204,7 @@ func TestPostgresRepositoryFindByTraceID(t *testing.T) {
 	})
 
 	t.Run("find non-existing trace", func(t *testing.T) {
-		found, err := repo.FindByTraceID("non-existent-" + GenerateSpanID())
+		found, err := repo.FindByTraceID("non-existent-" + trace.GenerateSpanID())
 		if err != nil {
 			t.Errorf("FindByTraceID() error = %v", err)
 		}
@@ -223,16 +224,16 @@ func TestPostgresRepositoryQuery(t *testing.T) {
 
 	// 准备测试数据
 	now := time.Now()
-	traceID := "pg-trace-q-" + GenerateSpanID()
-	spans := []*Span{
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "fast-op", Kind: SpanKindServer, StartTime: now.UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "slow-op", Kind: SpanKindServer, StartTime: now.Add(-1 * time.Minute).UnixMicro(), Duration: 5000000, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "error-op", Kind: SpanKindServer, StartTime: now.Add(-2 * time.Minute).UnixMicro(), Duration: 200, Status: SpanStatusError, Created: types.NowDateTime()},
+	traceID := "pg-trace-q-" + trace.GenerateSpanID()
+	spans := []*trace.Span{
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "fast-op", Kind: trace.SpanKindServer, StartTime: now.UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "slow-op", Kind: trace.SpanKindServer, StartTime: now.Add(-1 * time.Minute).UnixMicro(), Duration: 5000000, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "error-op", Kind: trace.SpanKindServer, StartTime: now.Add(-2 * time.Minute).UnixMicro(), Duration: 200, Status: trace.SpanStatusError, Created: types.NowDateTime()},
 	}
 	repo.SaveBatch(spans)
 
 	t.Run("query by traceID with limit", func(t *testing.T) {
-		found, err := repo.Query(TraceQueryOptions{TraceID: traceID, Limit: 2})
+		found, err := repo.Query(trace.TraceQueryOptions{TraceID: traceID, Limit: 2})
 		if err != nil {
 			t.Errorf("Query() error = %v", err)
 		}
@@ -242,7 +243,7 @@ func TestPostgresRepositoryQuery(t *testing.T) {
 	})
 
 	t.Run("query by status", func(t *testing.T) {
-		found, err := repo.Query(TraceQueryOptions{TraceID: traceID, StatusFilter: []SpanStatus{SpanStatusError}})
+		found, err := repo.Query(trace.TraceQueryOptions{TraceID: traceID, StatusFilter: []trace.SpanStatus{trace.SpanStatusError}})
 		if err != nil {
 			t.Errorf("Query() error = %v", err)
 		}
@@ -252,7 +253,7 @@ func TestPostgresRepositoryQuery(t *testing.T) {
 	})
 
 	t.Run("query by min duration", func(t *testing.T) {
-		found, err := repo.Query(TraceQueryOptions{TraceID: traceID, MinDuration: 1 * time.Second})
+		found, err := repo.Query(trace.TraceQueryOptions{TraceID: traceID, MinDuration: 1 * time.Second})
 		if err != nil {
 			t.Errorf("Query() error = %v", err)
 		}
@@ -271,19 +272,19 @@ func TestPostgresRepositoryCount(t *testing.T) {
 	defer repo.Close()
 
 	// 准备测试数据
-	traceID := "pg-trace-count-" + GenerateSpanID()
-	spans := make([]*Span, 5)
+	traceID := "pg-trace-count-" + trace.GenerateSpanID()
+	spans := make([]*trace.Span, 5)
 	for i := 0; i < 5; i++ {
-		status := SpanStatusOK
+		status := trace.SpanStatusOK
 		if i%2 == 0 {
-			status = SpanStatusError
+			status = trace.SpanStatusError
 		}
-		spans[i] = &Span{
-			ID:        GenerateSpanID(),
+		spans[i] = &trace.Span{
+			ID:        trace.GenerateSpanID(),
 			TraceID:   traceID,
-			SpanID:    GenerateSpanID(),
+			SpanID:    trace.GenerateSpanID(),
 			Name:      "count-op",
-			Kind:      SpanKindServer,
+			Kind:      trace.SpanKindServer,
 			StartTime: time.Now().UnixMicro(),
 			Duration:  int64(i * 100),
 			Status:    status,
@@ -293,7 +294,7 @@ func TestPostgresRepositoryCount(t *testing.T) {
 	repo.SaveBatch(spans)
 
 	t.Run("count all", func(t *testing.T) {
-		count, err := repo.Count(TraceQueryOptions{TraceID: traceID})
+		count, err := repo.Count(trace.TraceQueryOptions{TraceID: traceID})
 		if err != nil {
 			t.Errorf("Count() error = %v", err)
 		}
@@ -303,7 +304,7 @@ func TestPostgresRepositoryCount(t *testing.T) {
 	})
 
 	t.Run("count by status", func(t *testing.T) {
-		count, err := repo.Count(TraceQueryOptions{TraceID: traceID, StatusFilter: []SpanStatus{SpanStatusError}})
+		count, err := repo.Count(trace.TraceQueryOptions{TraceID: traceID, StatusFilter: []trace.SpanStatus{trace.SpanStatusError}})
 		if err != nil {
 			t.Errorf("Count() error = %v", err)
 		}
@@ -326,14 +327,14 @@ func TestPostgresRepositoryPrune(t *testing.T) {
 	oldTime := now.Add(-48 * time.Hour)
 	recentTime := now.Add(-1 * time.Hour)
 
-	traceID := "pg-trace-prune-" + GenerateSpanID()
+	traceID := "pg-trace-prune-" + trace.GenerateSpanID()
 	oldCreated, _ := types.ParseDateTime(oldTime)
 	recentCreated, _ := types.ParseDateTime(recentTime)
 
-	spans := []*Span{
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "old-op", Kind: SpanKindServer, StartTime: oldTime.UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: oldCreated},
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "old-op", Kind: SpanKindServer, StartTime: oldTime.UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: oldCreated},
-		{ID: GenerateSpanID(), TraceID: traceID, SpanID: GenerateSpanID(), Name: "recent-op", Kind: SpanKindServer, StartTime: recentTime.UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: recentCreated},
+	spans := []*trace.Span{
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "old-op", Kind: trace.SpanKindServer, StartTime: oldTime.UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: oldCreated},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "old-op", Kind: trace.SpanKindServer, StartTime: oldTime.UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: oldCreated},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "recent-op", Kind: trace.SpanKindServer, StartTime: recentTime.UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: recentCreated},
 	}
 	repo.SaveBatch(spans)
 
@@ -347,7 +348,7 @@ func TestPostgresRepositoryPrune(t *testing.T) {
 	}
 
 	// 验证 recent 数据仍在
-	remaining, _ := repo.Query(TraceQueryOptions{TraceID: traceID})
+	remaining, _ := repo.Query(trace.TraceQueryOptions{TraceID: traceID})
 	if len(remaining) < 1 {
 		t.Errorf("expected at least 1 remaining span, got %d", len(remaining))
 	}
@@ -361,13 +362,13 @@ func TestPostgresRepositoryDeleteByTraceID(t *testing.T) {
 	}
 	defer repo.Close()
 
-	traceDelete := "pg-trace-delete-" + GenerateSpanID()
-	traceKeep := "pg-trace-keep-" + GenerateSpanID()
+	traceDelete := "pg-trace-delete-" + trace.GenerateSpanID()
+	traceKeep := "pg-trace-keep-" + trace.GenerateSpanID()
 
-	spans := []*Span{
-		{ID: GenerateSpanID(), TraceID: traceDelete, SpanID: GenerateSpanID(), Name: "delete-op", Kind: SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: traceDelete, SpanID: GenerateSpanID(), Name: "delete-op", Kind: SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: types.NowDateTime()},
-		{ID: GenerateSpanID(), TraceID: traceKeep, SpanID: GenerateSpanID(), Name: "keep-op", Kind: SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: SpanStatusOK, Created: types.NowDateTime()},
+	spans := []*trace.Span{
+		{ID: trace.GenerateSpanID(), TraceID: traceDelete, SpanID: trace.GenerateSpanID(), Name: "delete-op", Kind: trace.SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: traceDelete, SpanID: trace.GenerateSpanID(), Name: "delete-op", Kind: trace.SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+		{ID: trace.GenerateSpanID(), TraceID: traceKeep, SpanID: trace.GenerateSpanID(), Name: "keep-op", Kind: trace.SpanKindServer, StartTime: time.Now().UnixMicro(), Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
 	}
 	repo.SaveBatch(spans)
 
@@ -389,13 +390,80 @@ func TestPostgresRepositoryDeleteByTraceID(t *testing.T) {
 	}
 }
 
+// TestPostgresRepositorySpanDetails 测试状态描述、事件与链接的存取和过滤
+func TestPostgresRepositorySpanDetails(t *testing.T) {
+	repo := setupTestPostgresRepo(t)
+	if repo == nil {
+		return
+	}
+	defer repo.Close()
+
+	now := time.Now().UnixMicro()
+	traceID := "pg-trace-details-" + trace.GenerateSpanID()
+	linkedTraceID := "pg-trace-linked-" + trace.GenerateSpanID()
+	spanID := trace.GenerateSpanID()
+	spans := []*trace.Span{
+		{
+			ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: spanID, Name: "job run",
+			Kind: trace.SpanKindConsumer, StartTime: now, Duration: 100,
+			Status: trace.SpanStatusError, StatusMessage: "boom",
+			Events: []trace.SpanEvent{
+				{Name: "retry #2", Time: now + 10},
+				{Name: trace.SpanEventException, Time: now + 20, Attributes: map[string]any{"exception.message": "boom"}},
+			},
+			Links:   []trace.SpanLink{{TraceID: linkedTraceID, SpanID: "sp-request"}},
+			Created: types.NowDateTime(),
+		},
+		{ID: trace.GenerateSpanID(), TraceID: traceID, SpanID: trace.GenerateSpanID(), Name: "plain", Kind: trace.SpanKindServer, StartTime: now, Duration: 100, Status: trace.SpanStatusOK, Created: types.NowDateTime()},
+	}
+	if result, err := repo.SaveBatch(spans); err != nil || result.Success != 2 {
+		t.Fatalf("SaveBatch() = %+v, %v", result, err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		span, err := repo.FindBySpanID(spanID)
+		if err != nil || span == nil {
+			t.Fatalf("FindBySpanID() = %v, %v", span, err)
+		}
+		if span.StatusMessage != "boom" {
+			t.Errorf("expected status message boom, got %q", span.StatusMessage)
+		}
+		if len(span.Events) != 2 || span.Events[1].Name != trace.SpanEventException || span.Events[1].Time != now+20 {
+			t.Errorf("unexpected events %+v", span.Events)
+		}
+		if len(span.Links) != 1 || span.Links[0].TraceID != linkedTraceID || span.Links[0].SpanID != "sp-request" {
+			t.Errorf("unexpected links %+v", span.Links)
+		}
+	})
+
+	t.Run("query by event name", func(t *testing.T) {
+		found, err := repo.Query(trace.TraceQueryOptions{TraceID: traceID, EventName: trace.SpanEventException})
+		if err != nil {
+			t.Errorf("Query() error = %v", err)
+		}
+		if len(found) != 1 {
+			t.Errorf("expected 1 span, got %d", len(found))
+		}
+	})
+
+	t.Run("query by linked trace", func(t *testing.T) {
+		count, err := repo.Count(trace.TraceQueryOptions{LinkedTraceID: linkedTraceID})
+		if err != nil {
+			t.Errorf("Count() error = %v", err)
+		}
+		if count != 1 {
+			t.Errorf("expected 1 span, got %d", count)
+		}
+	})
+}
+
 // setupTestPostgresRepo 创建测试用的 PostgreSQL Repository
-func setupTestPostgresRepo(t *testing.T) TraceRepository {
+func setupTestPostgresRepo(t *testing.T) trace.TraceRepository {
 	t.Helper()
 
 	dsn := skipIfNoPostgres(t)
 
-	repo, err := NewPostgresRepository(dsn)
+	repo, err := trace.NewPostgresRepository(dsn)
 	if err != nil {
 		t.Fatalf("NewPostgresRepository() error = %v", err)
 	}
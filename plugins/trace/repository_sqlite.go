@@ -57,7 +57,10 @@ func (r *SQLiteRepository) initSchema() error {
 			start_time  INTEGER NOT NULL,
 			duration    INTEGER NOT NULL,
 			status      TEXT NOT NULL,
+			status_message TEXT NOT NULL DEFAULT '',
 			attributes  TEXT,
+			events      TEXT,
+			links       TEXT,
 			created     TEXT NOT NULL
 		);
 
@@ -67,8 +70,54 @@ func (r *SQLiteRepository) initSchema() error {
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_status ON _trace_spans(status);
 		CREATE INDEX IF NOT EXISTS idx_trace_spans_created ON _trace_spans(created);
 	`
-	_, err := r.db.Exec(schema)
-	return err
+	if _, err := r.db.Exec(schema); err != nil {
+		return err
+	}
+
+	return r.migrateSchema()
+}
+
+// sqliteSpanColumnMigrations 旧版表结构中缺少的列
+var sqliteSpanColumnMigrations = []struct {
+	name       string
+	definition string
+}{
+	{"status_message", "TEXT NOT NULL DEFAULT ''"},
+	{"events", "TEXT"},
+	{"links", "TEXT"},
+}
+
+// migrateSchema 为旧版本创建的表补充新增的列
+func (r *SQLiteRepository) migrateSchema() error {
+	rows, err := r.db.Query(`SELECT name FROM pragma_table_info('_trace_spans')`)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range sqliteSpanColumnMigrations {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := r.db.Exec("ALTER TABLE _trace_spans ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.name, err)
+		}
+	}
+
+	return nil
 }
 
 // SaveBatch 批量保存 Span 数据
@@ -86,8 +135,8 @@ func (r *SQLiteRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 
 	stmt, err := tx.Prepare(`
 		INSERT OR REPLACE INTO _trace_spans 
-		(id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(` + spanColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return result, fmt.Errorf("failed to prepare statement: %w", err)
@@ -114,7 +163,10 @@ func (r *SQLiteRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 			span.StartTime,
 			span.Duration,
 			string(span.Status),
+			span.StatusMessage,
 			string(attrsJSON),
+			nullableJSON(span.Events),
+			nullableJSON(span.Links),
 			span.Created.String(),
 		)
 		if err != nil {
@@ -135,7 +187,7 @@ func (r *SQLiteRepository) SaveBatch(spans []*Span) (BatchSaveResult, error) {
 // FindByTraceID 根据 TraceID 查找所有相关 Span
 func (r *SQLiteRepository) FindByTraceID(traceID string) ([]*Span, error) {
 	rows, err := r.db.Query(`
-		SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created
+		SELECT `+spanColumns+`
 		FROM _trace_spans
 		WHERE trace_id = ?
 		ORDER BY start_time ASC
@@ -151,7 +203,7 @@ func (r *SQLiteRepository) FindByTraceID(traceID string) ([]*Span, error) {
 // FindBySpanID 根据 SpanID 查找单个 Span
 func (r *SQLiteRepository) FindBySpanID(spanID string) (*Span, error) {
 	row := r.db.QueryRow(`
-		SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created
+		SELECT `+spanColumns+`
 		FROM _trace_spans
 		WHERE span_id = ?
 		LIMIT 1
@@ -226,7 +278,7 @@ func (r *SQLiteRepository) buildQuery(opts TraceQueryOptions, countOnly bool) (s
 	if countOnly {
 		sb.WriteString("SELECT COUNT(*) FROM _trace_spans WHERE 1=1")
 	} else {
-		sb.WriteString("SELECT id, trace_id, span_id, parent_id, name, kind, start_time, duration, status, attributes, created FROM _trace_spans WHERE 1=1")
+		sb.WriteString("SELECT " + spanColumns + " FROM _trace_spans WHERE 1=1")
 	}
 
 	if opts.TraceID != "" {
@@ -291,6 +343,16 @@ func (r *SQLiteRepository) buildQuery(opts TraceQueryOptions, countOnly bool) (s
 		args = append(args, value)
 	}
 
+	if opts.EventName != "" {
+		sb.WriteString(" AND EXISTS (SELECT 1 FROM json_each(_trace_spans.events) WHERE json_extract(value, '$.name') = ?)")
+		args = append(args, opts.EventName)
+	}
+
+	if opts.LinkedTraceID != "" {
+		sb.WriteString(" AND EXISTS (SELECT 1 FROM json_each(_trace_spans.links) WHERE json_extract(value, '$.traceId') = ?)")
+		args = append(args, opts.LinkedTraceID)
+	}
+
 	if !countOnly {
 		// 排序
 		orderBy := "start_time"
@@ -322,7 +384,7 @@ func (r *SQLiteRepository) buildQuery(opts TraceQueryOptions, countOnly bool) (s
 func (r *SQLiteRepository) scanSpans(rows *sql.Rows) ([]*Span, error) {
 	var spans []*Span
 	for rows.Next() {
-		span, err := r.scanSpanFromRows(rows)
+		span, err := r.scanSpan(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -332,12 +394,14 @@ func (r *SQLiteRepository) scanSpans(rows *sql.Rows) ([]*Span, error) {
 }
 
 // scanSpan 扫描单行结果
-func (r *SQLiteRepository) scanSpan(row *sql.Row) (*Span, error) {
+func (r *SQLiteRepository) scanSpan(row rowScanner) (*Span, error) {
 	var (
 		span       Span
 		kind       string
 		status     string
 		attrsJSON  sql.NullString
+		eventsJSON sql.NullString
+		linksJSON  sql.NullString
 		createdStr string
 	)
 
@@ -351,7 +415,10 @@ func (r *SQLiteRepository) scanSpan(row *sql.Row) (*Span, error) {
 		&span.StartTime,
 		&span.Duration,
 		&status,
+		&span.StatusMessage,
 		&attrsJSON,
+		&eventsJSON,
+		&linksJSON,
 		&createdStr,
 	)
 	if err != nil {
@@ -364,44 +431,11 @@ func (r *SQLiteRepository) scanSpan(row *sql.Row) (*Span, error) {
 	if attrsJSON.Valid && attrsJSON.String != "" {
 		json.Unmarshal([]byte(attrsJSON.String), &span.Attributes)
 	}
-
-	span.Created, _ = types.ParseDateTime(createdStr)
-
-	return &span, nil
-}
-
-// scanSpanFromRows 从 Rows 扫描单个 Span
-func (r *SQLiteRepository) scanSpanFromRows(rows *sql.Rows) (*Span, error) {
-	var (
-		span       Span
-		kind       string
-		status     string
-		attrsJSON  sql.NullString
-		createdStr string
-	)
-
-	err := rows.Scan(
-		&span.ID,
-		&span.TraceID,
-		&span.SpanID,
-		&span.ParentID,
-		&span.Name,
-		&kind,
-		&span.StartTime,
-		&span.Duration,
-		&status,
-		&attrsJSON,
-		&createdStr,
-	)
-	if err != nil {
-		return nil, err
+	if eventsJSON.Valid && eventsJSON.String != "" {
+		json.Unmarshal([]byte(eventsJSON.String), &span.Events)
 	}
-
-	span.Kind = SpanKind(kind)
-	span.Status = SpanStatus(status)
-
-	if attrsJSON.Valid && attrsJSON.String != "" {
-		json.Unmarshal([]byte(attrsJSON.String), &span.Attributes)
+	if linksJSON.Valid && linksJSON.String != "" {
+		json.Unmarshal([]byte(linksJSON.String), &span.Links)
 	}
 
 	span.Created, _ = types.ParseDateTime(createdStr)
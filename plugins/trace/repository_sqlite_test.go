@@ -1,7 +1,9 @@
 package trace
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -529,6 +531,139 @@ func TestSQLiteRepositorySaveNilSpan(t *testing.T) {
 	}
 }
 
+// TestSQLiteRepositorySpanDetails 测试状态描述、事件与链接的存取和过滤
+func TestSQLiteRepositorySpanDetails(t *testing.T) {
+	repo := setupTestSQLiteRepo(t)
+	defer repo.Close()
+
+	now := time.Now().UnixMicro()
+	spans := []*Span{
+		{
+			ID: "job-1", TraceID: "trace-job", SpanID: "sp-job", Name: "job run",
+			Kind: SpanKindConsumer, StartTime: now, Duration: 100,
+			Status: SpanStatusError, StatusMessage: "boom",
+			Events: []SpanEvent{
+				{Name: "retry #2", Time: now + 10},
+				{Name: SpanEventException, Time: now + 20, Attributes: map[string]any{"exception.message": "boom"}},
+			},
+			Links: []SpanLink{
+				{TraceID: "trace-request", SpanID: "sp-request", Attributes: map[string]any{"job.id": "1"}},
+			},
+			Created: types.NowDateTime(),
+		},
+		{
+			ID: "plain-1", TraceID: "trace-plain", SpanID: "sp-plain", Name: "plain",
+			Kind: SpanKindServer, StartTime: now, Duration: 100, Status: SpanStatusOK,
+			Created: types.NowDateTime(),
+		},
+	}
+	if result, err := repo.SaveBatch(spans); err != nil || result.Success != 2 {
+		t.Fatalf("SaveBatch() = %+v, %v", result, err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		span, err := repo.FindBySpanID("sp-job")
+		if err != nil || span == nil {
+			t.Fatalf("FindBySpanID() = %v, %v", span, err)
+		}
+		if span.StatusMessage != "boom" {
+			t.Errorf("expected status message boom, got %q", span.StatusMessage)
+		}
+		if len(span.Events) != 2 || span.Events[1].Name != SpanEventException || span.Events[1].Time != now+20 ||
+			span.Events[1].Attributes["exception.message"] != "boom" {
+			t.Errorf("unexpected events %+v", span.Events)
+		}
+		if len(span.Links) != 1 || span.Links[0].TraceID != "trace-request" || span.Links[0].SpanID != "sp-request" ||
+			span.Links[0].Attributes["job.id"] != "1" {
+			t.Errorf("unexpected links %+v", span.Links)
+		}
+
+		plain, _ := repo.FindBySpanID("sp-plain")
+		if plain == nil || plain.StatusMessage != "" || plain.Events != nil || plain.Links != nil {
+			t.Errorf("expected span without details, got %+v", plain)
+		}
+	})
+
+	scenarios := []struct {
+		name     string
+		opts     TraceQueryOptions
+		expected int
+	}{
+		{"event name", TraceQueryOptions{EventName: SpanEventException}, 1},
+		{"missing event name", TraceQueryOptions{EventName: "cache miss"}, 0},
+		{"linked trace", TraceQueryOptions{LinkedTraceID: "trace-request"}, 1},
+		{"missing linked trace", TraceQueryOptions{LinkedTraceID: "trace-plain"}, 0},
+	}
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			found, err := repo.Query(s.opts)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if len(found) != s.expected {
+				t.Errorf("expected %d spans, got %d", s.expected, len(found))
+			}
+			count, err := repo.Count(s.opts)
+			if err != nil || count != int64(s.expected) {
+				t.Errorf("expected count %d, got %d (%v)", s.expected, count, err)
+			}
+		})
+	}
+}
+
+// TestSQLiteRepositoryMigrateSchema 测试为旧版表结构补充新增列
+func TestSQLiteRepositoryMigrateSchema(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "trace.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE _trace_spans (
+			id TEXT PRIMARY KEY, trace_id TEXT NOT NULL, span_id TEXT NOT NULL, parent_id TEXT,
+			name TEXT NOT NULL, kind TEXT NOT NULL, start_time INTEGER NOT NULL, duration INTEGER NOT NULL,
+			status TEXT NOT NULL, attributes TEXT, created TEXT NOT NULL
+		);
+		INSERT INTO _trace_spans VALUES ('old-1', 'trace-old', 'sp-old', '', 'old', 'server', 1, 1, 'ok', '', '2024-01-01 00:00:00.000Z');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := NewSQLiteRepository(dsn)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository() error = %v", err)
+	}
+	defer repo.Close()
+
+	old, err := repo.FindBySpanID("sp-old")
+	if err != nil || old == nil || old.Name != "old" {
+		t.Fatalf("expected existing span to be readable, got %+v, %v", old, err)
+	}
+
+	span := &Span{
+		ID: "new-1", TraceID: "trace-old", SpanID: "sp-new", Name: "new", Kind: SpanKindInternal,
+		Status: SpanStatusError, StatusMessage: "failed", Events: []SpanEvent{{Name: "e", Time: 1}},
+		Created: types.NowDateTime(),
+	}
+	if result, err := repo.SaveBatch([]*Span{span}); err != nil || result.Success != 1 {
+		t.Fatalf("SaveBatch() = %+v, %v", result, err)
+	}
+	found, _ := repo.FindBySpanID("sp-new")
+	if found == nil || found.StatusMessage != "failed" || len(found.Events) != 1 {
+		t.Errorf("unexpected migrated span %+v", found)
+	}
+
+	// 重复打开不应重复添加列
+	repo2, err := NewSQLiteRepository(dsn)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	repo2.Close()
+}
+
 // setupTestSQLiteRepo 创建测试用的 SQLite Repository
 func setupTestSQLiteRepo(t *testing.T) TraceRepository {
 	t.Helper()
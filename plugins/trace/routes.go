@@ -17,9 +17,24 @@ type SpanListResponse struct {
 	PerPage    int     `json:"perPage"`
 }
 
+// StatsResponse 表示 RED 指标响应
+type StatsResponse struct {
+	From  time.Time       `json:"from"`
+	To    time.Time       `json:"to"`
+	Items []SpanStatsItem `json:"items"`
+}
+
+// ServiceMapResponse 表示服务依赖图响应
+type ServiceMapResponse struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	*ServiceMap
+}
+
 // TraceAPIHandler 处理追踪相关的 HTTP API
 type TraceAPIHandler struct {
-	repo TraceRepository
+	repo  TraceRepository
+	stats *SpanStats
 }
 
 // NewTraceAPIHandler 创建新的 API 处理器
@@ -27,6 +42,12 @@ func NewTraceAPIHandler(repo TraceRepository) *TraceAPIHandler {
 	return &TraceAPIHandler{repo: repo}
 }
 
+// WithStats 设置 /stats 与 /service-map 使用的聚合统计（通常为 TracerStats(tracer)）
+func (h *TraceAPIHandler) WithStats(stats *SpanStats) *TraceAPIHandler {
+	h.stats = stats
+	return h
+}
+
 // RegisterRoutes 注册追踪 API 路由
 func (h *TraceAPIHandler) RegisterRoutes(mux *http.ServeMux, prefix string) {
 	// 确保 prefix 不以 / 结尾
@@ -42,6 +63,26 @@ func (h *TraceAPIHandler) RegisterRoutes(mux *http.ServeMux, prefix string) {
 		}
 	})
 
+	// GET /api/_/trace/stats - RED 指标
+	mux.HandleFunc(prefix+"/stats", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetStats(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	// GET /api/_/trace/service-map - 服务依赖图
+	mux.HandleFunc(prefix+"/service-map", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetServiceMap(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	// GET/DELETE /api/_/trace/spans/{traceId} - 按 TraceID 操作
 	mux.HandleFunc(prefix+"/spans/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, prefix+"/spans/")
@@ -145,16 +186,68 @@ func (h *TraceAPIHandler) DeleteByTraceID(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GetStats 处理 GET /api/_/trace/stats
+func (h *TraceAPIHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		writeError(w, http.StatusNotFound, "Span stats are not enabled", nil)
+		return
+	}
+
+	q := r.URL.Query()
+	from, to := parseTimeRange(r)
+	series, _ := strconv.ParseBool(q.Get("series"))
+
+	items := h.stats.Query(StatsQuery{
+		From:   from,
+		To:     to,
+		Name:   q.Get("name"),
+		Kind:   SpanKind(q.Get("kind")),
+		Series: series,
+	})
+
+	writeJSON(w, http.StatusOK, StatsResponse{From: from, To: to, Items: items})
+}
+
+// GetServiceMap 处理 GET /api/_/trace/service-map
+func (h *TraceAPIHandler) GetServiceMap(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		writeError(w, http.StatusNotFound, "Span stats are not enabled", nil)
+		return
+	}
+
+	from, to := parseTimeRange(r)
+	writeJSON(w, http.StatusOK, ServiceMapResponse{From: from, To: to, ServiceMap: h.stats.ServiceMap(from, to)})
+}
+
+// parseTimeRange 解析 from/to 参数（RFC3339），默认为最近 1 小时
+func parseTimeRange(r *http.Request) (time.Time, time.Time) {
+	q := r.URL.Query()
+
+	to := time.Now().UTC()
+	if t, err := time.Parse(time.RFC3339, q.Get("to")); err == nil {
+		to = t
+	}
+
+	from := to.Add(-time.Hour)
+	if t, err := time.Parse(time.RFC3339, q.Get("from")); err == nil && t.Before(to) {
+		from = t
+	}
+
+	return from, to
+}
+
 // parseQueryOptions 从请求参数解析查询选项
 func parseQueryOptions(r *http.Request) TraceQueryOptions {
 	q := r.URL.Query()
 
 	opts := TraceQueryOptions{
-		TraceID:      q.Get("traceId"),
-		ParentSpanID: q.Get("parentSpanId"),
-		SpanName:     q.Get("name"),
-		OrderBy:      q.Get("orderBy"),
-		OrderDesc:    q.Get("orderDir") != "asc",
+		TraceID:       q.Get("traceId"),
+		ParentSpanID:  q.Get("parentSpanId"),
+		SpanName:      q.Get("name"),
+		EventName:     q.Get("event"),
+		LinkedTraceID: q.Get("linkedTraceId"),
+		OrderBy:       q.Get("orderBy"),
+		OrderDesc:     q.Get("orderDir") != "asc",
 	}
 
 	// 解析分页
@@ -282,7 +282,7 @@ func TestTraceAPIHandler_GetSpanError(t *testing.T) {
 // TestParseQueryOptions 测试查询参数解析
 func TestParseQueryOptions(t *testing.T) {
 	t.Run("parses all query parameters", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/api/_/trace/spans?traceId=t1&parentSpanId=p1&name=test&orderBy=duration&orderDir=asc&limit=50&offset=10&minDuration=100&maxDuration=5000&status=error,ok&startTimeFrom=2024-01-01T00:00:00Z&startTimeTo=2024-12-31T23:59:59Z", nil)
+		req := httptest.NewRequest("GET", "/api/_/trace/spans?traceId=t1&parentSpanId=p1&name=test&event=exception&linkedTraceId=t0&orderBy=duration&orderDir=asc&limit=50&offset=10&minDuration=100&maxDuration=5000&status=error,ok&startTimeFrom=2024-01-01T00:00:00Z&startTimeTo=2024-12-31T23:59:59Z", nil)
 
 		opts := parseQueryOptions(req)
 
@@ -295,6 +295,12 @@ func TestParseQueryOptions(t *testing.T) {
 		if opts.SpanName != "test" {
 			t.Errorf("expected name test, got %s", opts.SpanName)
 		}
+		if opts.EventName != "exception" {
+			t.Errorf("expected event exception, got %s", opts.EventName)
+		}
+		if opts.LinkedTraceID != "t0" {
+			t.Errorf("expected linkedTraceId t0, got %s", opts.LinkedTraceID)
+		}
 		if opts.OrderBy != "duration" {
 			t.Errorf("expected orderBy duration, got %s", opts.OrderBy)
 		}
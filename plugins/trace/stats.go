@@ -0,0 +1,443 @@
+package trace
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StatsConfig Span 聚合统计配置
+type StatsConfig struct {
+	// Disabled 关闭 RED 指标与服务依赖图的聚合
+	Disabled bool
+	// BucketSize 时间桶大小，默认 1m
+	BucketSize time.Duration
+	// Retention 内存中保留的时长，默认 24h
+	Retention time.Duration
+	// MaxSeries 每个时间桶最多的 Span 名称/类型组合数（服务依赖图的边数同样受此限制），
+	// 超出的部分计入名称为 StatsOtherName 的组合，默认 1000
+	MaxSeries int
+}
+
+// StatsOtherName 超出 MaxSeries 的 Span 聚合到的名称
+const StatsOtherName = "~other"
+
+// REDMetrics 一组 Span 在时间范围内的请求速率、错误与耗时分布
+type REDMetrics struct {
+	Count     uint64  `json:"count"`
+	Errors    uint64  `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	// Rate 每秒的 Span 数
+	Rate  float64 `json:"rate"`
+	AvgMs float64 `json:"avgMs"`
+	MinMs float64 `json:"minMs"`
+	MaxMs float64 `json:"maxMs"`
+	P50Ms float64 `json:"p50Ms"`
+	P95Ms float64 `json:"p95Ms"`
+	P99Ms float64 `json:"p99Ms"`
+}
+
+// SpanStatsItem 按 Span 名称与类型聚合的 RED 指标
+type SpanStatsItem struct {
+	Name string   `json:"name"`
+	Kind SpanKind `json:"kind"`
+	REDMetrics
+	// Series 每个时间桶的指标，仅在 StatsQuery.Series 为 true 时返回
+	Series []StatsPoint `json:"series,omitempty"`
+}
+
+// StatsPoint 单个时间桶的指标
+type StatsPoint struct {
+	Time time.Time `json:"time"`
+	REDMetrics
+}
+
+// StatsQuery RED 指标查询条件
+type StatsQuery struct {
+	// From/To 时间范围，为零值时分别为 To 前 1 小时与当前时间
+	From time.Time
+	To   time.Time
+	// Name 只返回指定名称的 Span（可选）
+	Name string
+	// Kind 只返回指定类型的 Span（可选）
+	Kind SpanKind
+	// Series 是否返回每个时间桶的指标
+	Series bool
+}
+
+// ServiceMap 由客户端 Span 构建的服务依赖图
+type ServiceMap struct {
+	Nodes []ServiceMapNode `json:"nodes"`
+	Edges []ServiceMapEdge `json:"edges"`
+}
+
+// ServiceMapNode 服务依赖图的节点
+type ServiceMapNode struct {
+	ID string `json:"id"`
+	// Type 节点类型：service（本服务）、database、http、gateway
+	Type string `json:"type"`
+}
+
+// ServiceMapEdge 服务依赖图的边：本服务对下游的调用
+type ServiceMapEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	REDMetrics
+}
+
+// 服务依赖图的节点类型
+const (
+	ServiceMapNodeService  = "service"
+	ServiceMapNodeDatabase = "database"
+	ServiceMapNodeHTTP     = "http"
+	ServiceMapNodeGateway  = "gateway"
+)
+
+// SpanStats 在 Span 刷新时按时间桶聚合 RED 指标（速率、错误、耗时分布），
+// 并根据客户端 Span 构建服务依赖图。
+//
+// 统计只保存在当前进程的内存中，只包含被采集并刷新的 Span：
+// 条件采集或尾部采样模式下反映的是被保留的请求。
+type SpanStats struct {
+	service    string
+	bucketSize time.Duration
+	retention  time.Duration
+	maxSeries  int
+
+	mu      sync.RWMutex
+	buckets map[int64]*statsBucket // 时间桶开始时间（Unix 秒）-> 时间桶
+}
+
+// statsBucket 单个时间桶内的聚合
+type statsBucket struct {
+	series map[statsSeriesKey]*redAggregate
+	edges  map[statsEdgeKey]*redAggregate
+}
+
+type statsSeriesKey struct {
+	name string
+	kind SpanKind
+}
+
+type statsEdgeKey struct {
+	target     string
+	targetType string
+}
+
+// redAggregate 一组 Span 的计数、耗时（微秒）与分布草图
+type redAggregate struct {
+	count  uint64
+	errors uint64
+	sum    int64
+	min    int64
+	max    int64
+	sketch durationSketch
+}
+
+// NewSpanStats 创建 Span 聚合统计，service 为服务依赖图中本服务的节点名称
+func NewSpanStats(service string, config StatsConfig) *SpanStats {
+	if service == "" {
+		service = "pocketbase"
+	}
+	if config.BucketSize <= 0 {
+		config.BucketSize = time.Minute
+	}
+	if config.Retention <= 0 {
+		config.Retention = 24 * time.Hour
+	}
+	if config.MaxSeries <= 0 {
+		config.MaxSeries = 1000
+	}
+
+	return &SpanStats{
+		service:    service,
+		bucketSize: config.BucketSize,
+		retention:  config.Retention,
+		maxSeries:  config.MaxSeries,
+		buckets:    make(map[int64]*statsBucket),
+	}
+}
+
+// Record 将一批 Span 计入其开始时间所在的时间桶，超出保留时长的 Span 被忽略
+func (s *SpanStats) Record(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+
+	now := time.Now()
+	oldest := s.bucketStart(now.Add(-s.retention))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(oldest)
+
+	for _, span := range spans {
+		start := s.bucketStart(time.UnixMicro(span.StartTime))
+		if start < oldest {
+			continue
+		}
+
+		bucket, ok := s.buckets[start]
+		if !ok {
+			bucket = &statsBucket{
+				series: make(map[statsSeriesKey]*redAggregate),
+				edges:  make(map[statsEdgeKey]*redAggregate),
+			}
+			s.buckets[start] = bucket
+		}
+
+		key := statsSeriesKey{name: span.Name, kind: span.Kind}
+		if _, ok := bucket.series[key]; !ok && len(bucket.series) >= s.maxSeries {
+			key.name = StatsOtherName
+		}
+		addToAggregate(bucket.series, key, span)
+
+		if target, targetType, ok := spanPeer(span); ok {
+			edge := statsEdgeKey{target: target, targetType: targetType}
+			if _, ok := bucket.edges[edge]; !ok && len(bucket.edges) >= s.maxSeries {
+				edge = statsEdgeKey{target: StatsOtherName, targetType: targetType}
+			}
+			addToAggregate(bucket.edges, edge, span)
+		}
+	}
+}
+
+// Query 返回时间范围内按 Span 名称与类型聚合的 RED 指标，按 Span 数从多到少排序
+func (s *SpanStats) Query(query StatsQuery) []SpanStatsItem {
+	from, to := s.queryRange(query.From, query.To)
+	seconds := to.Sub(from).Seconds()
+
+	totals := make(map[statsSeriesKey]*redAggregate)
+	series := make(map[statsSeriesKey][]StatsPoint)
+
+	s.mu.RLock()
+	for _, start := range s.bucketStarts(from, to) {
+		for key, agg := range s.buckets[start].series {
+			if (query.Name != "" && key.name != query.Name) || (query.Kind != "" && key.kind != query.Kind) {
+				continue
+			}
+			mergeAggregate(totals, key, agg)
+			if query.Series {
+				series[key] = append(series[key], StatsPoint{
+					Time:       time.Unix(start, 0).UTC(),
+					REDMetrics: agg.metrics(s.bucketSize.Seconds()),
+				})
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	items := make([]SpanStatsItem, 0, len(totals))
+	for key, agg := range totals {
+		items = append(items, SpanStatsItem{
+			Name:       key.name,
+			Kind:       key.kind,
+			REDMetrics: agg.metrics(seconds),
+			Series:     series[key],
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].Kind < items[j].Kind
+	})
+
+	return items
+}
+
+// ServiceMap 返回时间范围内的服务依赖图，边按调用数从多到少排序
+func (s *SpanStats) ServiceMap(from, to time.Time) *ServiceMap {
+	from, to = s.queryRange(from, to)
+
+	totals := make(map[statsEdgeKey]*redAggregate)
+
+	s.mu.RLock()
+	for _, start := range s.bucketStarts(from, to) {
+		for key, agg := range s.buckets[start].edges {
+			mergeAggregate(totals, key, agg)
+		}
+	}
+	s.mu.RUnlock()
+
+	result := &ServiceMap{
+		Nodes: []ServiceMapNode{{ID: s.service, Type: ServiceMapNodeService}},
+		Edges: make([]ServiceMapEdge, 0, len(totals)),
+	}
+
+	seen := map[string]bool{s.service: true}
+	for key, agg := range totals {
+		if !seen[key.target] {
+			seen[key.target] = true
+			result.Nodes = append(result.Nodes, ServiceMapNode{ID: key.target, Type: key.targetType})
+		}
+		result.Edges = append(result.Edges, ServiceMapEdge{
+			Source:     s.service,
+			Target:     key.target,
+			REDMetrics: agg.metrics(to.Sub(from).Seconds()),
+		})
+	}
+
+	peers := result.Nodes[1:]
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ID < peers[j].ID
+	})
+	sort.Slice(result.Edges, func(i, j int) bool {
+		if result.Edges[i].Count != result.Edges[j].Count {
+			return result.Edges[i].Count > result.Edges[j].Count
+		}
+		return result.Edges[i].Target < result.Edges[j].Target
+	})
+
+	return result
+}
+
+// queryRange 补全查询的时间范围：默认为最近 1 小时
+func (s *SpanStats) queryRange(from, to time.Time) (time.Time, time.Time) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() || !from.Before(to) {
+		from = to.Add(-time.Hour)
+	}
+	return from, to
+}
+
+// bucketStarts 返回与时间范围有交集的时间桶，按时间排序，调用方需持有读锁
+func (s *SpanStats) bucketStarts(from, to time.Time) []int64 {
+	first, last := s.bucketStart(from), to.Unix()
+
+	starts := make([]int64, 0, len(s.buckets))
+	for start := range s.buckets {
+		if start >= first && start < last {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	return starts
+}
+
+// bucketStart 返回 t 所在时间桶的开始时间（Unix 秒）
+func (s *SpanStats) bucketStart(t time.Time) int64 {
+	return t.Truncate(s.bucketSize).Unix()
+}
+
+// prune 删除早于 oldest 的时间桶，调用方需持有写锁
+func (s *SpanStats) prune(oldest int64) {
+	for start := range s.buckets {
+		if start < oldest {
+			delete(s.buckets, start)
+		}
+	}
+}
+
+// addToAggregate 将 Span 计入 key 对应的聚合
+func addToAggregate[K comparable](aggs map[K]*redAggregate, key K, span *Span) {
+	agg, ok := aggs[key]
+	if !ok {
+		agg = &redAggregate{min: span.Duration, max: span.Duration}
+		aggs[key] = agg
+	}
+
+	agg.count++
+	if span.Status == SpanStatusError {
+		agg.errors++
+	}
+	agg.sum += span.Duration
+	agg.min = min(agg.min, span.Duration)
+	agg.max = max(agg.max, span.Duration)
+	agg.sketch.add(span.Duration)
+}
+
+// mergeAggregate 将 agg 合并到 key 对应的聚合
+func mergeAggregate[K comparable](aggs map[K]*redAggregate, key K, agg *redAggregate) {
+	total, ok := aggs[key]
+	if !ok {
+		total = &redAggregate{min: agg.min, max: agg.max}
+		aggs[key] = total
+	}
+
+	total.count += agg.count
+	total.errors += agg.errors
+	total.sum += agg.sum
+	total.min = min(total.min, agg.min)
+	total.max = max(total.max, agg.max)
+	total.sketch.merge(&agg.sketch)
+}
+
+// metrics 将聚合转换为 RED 指标，seconds 为计算速率的时长
+func (a *redAggregate) metrics(seconds float64) REDMetrics {
+	m := REDMetrics{
+		Count:  a.count,
+		Errors: a.errors,
+		MinMs:  float64(a.min) / 1000,
+		MaxMs:  float64(a.max) / 1000,
+		P50Ms:  a.sketch.quantile(0.50) / 1000,
+		P95Ms:  a.sketch.quantile(0.95) / 1000,
+		P99Ms:  a.sketch.quantile(0.99) / 1000,
+	}
+	if a.count > 0 {
+		m.ErrorRate = float64(a.errors) / float64(a.count)
+		m.AvgMs = float64(a.sum) / float64(a.count) / 1000
+	}
+	if seconds > 0 {
+		m.Rate = float64(a.count) / seconds
+	}
+	return m
+}
+
+// spanPeer 返回客户端 Span 调用的下游节点：
+// 显式的 peer.service、数据库系统、网关上游主机或出站 HTTP 请求的主机
+func spanPeer(span *Span) (string, string, bool) {
+	if span.Kind != SpanKindClient {
+		return "", "", false
+	}
+
+	attr := func(key string) string {
+		if v, ok := span.Attributes[key].(string); ok {
+			return v
+		}
+		return ""
+	}
+
+	if peer := attr("peer.service"); peer != "" {
+		return peer, ServiceMapNodeService, true
+	}
+	if system := attr("db.system"); system != "" {
+		return system, ServiceMapNodeDatabase, true
+	}
+	if upstream := attr("gateway.upstream"); upstream != "" {
+		if u, err := url.Parse(upstream); err == nil && u.Host != "" {
+			upstream = u.Host
+		}
+		return upstream, ServiceMapNodeGateway, true
+	}
+	if proxy := attr("gateway.proxy"); proxy != "" {
+		return proxy, ServiceMapNodeGateway, true
+	}
+	if rawURL := attr("http.url"); rawURL != "" {
+		if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+			return u.Host, ServiceMapNodeHTTP, true
+		}
+	}
+	return span.Name, ServiceMapNodeService, true
+}
+
+// StatsProvider 是提供 Span 聚合统计的接口
+type StatsProvider interface {
+	Stats() *SpanStats
+}
+
+// TracerStats 返回 tracer 的 Span 聚合统计，未启用时返回 nil
+func TracerStats(tracer Tracer) *SpanStats {
+	if provider, ok := tracer.(StatsProvider); ok {
+		return provider.Stats()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// sketchRelativeAccuracy 分位数估计的相对误差
+	sketchRelativeAccuracy = 0.01
+	// sketchMaxBins 单个草图最多的分桶数，超出时合并最小的分桶（只影响最低的分位数）
+	sketchMaxBins = 2048
+)
+
+var (
+	sketchGamma    = (1 + sketchRelativeAccuracy) / (1 - sketchRelativeAccuracy)
+	sketchLogGamma = math.Log(sketchGamma)
+)
+
+// durationSketch 是按对数分桶的流式分位数草图（DDSketch），记录以微秒为单位的耗时。
+//
+// 每个值落入 [γ^(i-1), γ^i) 分桶，分位数的相对误差不超过 sketchRelativeAccuracy。
+// 草图可以合并，多个时间桶的草图合并后即为整个时间范围的分布。
+type durationSketch struct {
+	bins  map[int]uint64
+	zeros uint64
+	count uint64
+}
+
+// add 记录一个耗时（微秒），小于等于 0 的值单独计数
+func (s *durationSketch) add(value int64) {
+	s.count++
+	if value <= 0 {
+		s.zeros++
+		return
+	}
+
+	if s.bins == nil {
+		s.bins = make(map[int]uint64)
+	}
+	s.bins[int(math.Ceil(math.Log(float64(value))/sketchLogGamma))]++
+	s.collapse()
+}
+
+// merge 将另一个草图合并到当前草图
+func (s *durationSketch) merge(other *durationSketch) {
+	if other.count == 0 {
+		return
+	}
+
+	if s.bins == nil {
+		s.bins = make(map[int]uint64, len(other.bins))
+	}
+	for index, n := range other.bins {
+		s.bins[index] += n
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+	s.collapse()
+}
+
+// quantile 返回分位数 q（0-1）的估计值（微秒），草图为空时返回 0
+func (s *durationSketch) quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Max(0, math.Min(1, q)) * float64(s.count-1))
+	if rank < s.zeros {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(s.bins))
+	for index := range s.bins {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	seen := s.zeros
+	for _, index := range indexes {
+		seen += s.bins[index]
+		if seen > rank {
+			return 2 * math.Pow(sketchGamma, float64(index)) / (sketchGamma + 1)
+		}
+	}
+	return 2 * math.Pow(sketchGamma, float64(indexes[len(indexes)-1])) / (sketchGamma + 1)
+}
+
+// collapse 分桶数超出上限时将最小的分桶合并到次小的分桶
+func (s *durationSketch) collapse() {
+	for len(s.bins) > sketchMaxBins {
+		lowest, second := math.MaxInt, math.MaxInt
+		for index := range s.bins {
+			if index < lowest {
+				lowest, second = index, lowest
+			} else if index < second {
+				second = index
+			}
+		}
+		s.bins[second] += s.bins[lowest]
+		delete(s.bins, lowest)
+	}
+}
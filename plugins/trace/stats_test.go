@@ -0,0 +1,273 @@
+package trace
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+func statsTestSpan(name string, kind SpanKind, start time.Time, duration time.Duration, status SpanStatus, attrs map[string]any) *Span {
+	return &Span{
+		TraceID:    GenerateTraceID(),
+		SpanID:     GenerateSpanID(),
+		Name:       name,
+		Kind:       kind,
+		StartTime:  start.UnixMicro(),
+		Duration:   duration.Microseconds(),
+		Status:     status,
+		Attributes: attrs,
+	}
+}
+
+func TestDurationSketch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	values := make([]int64, 10000)
+	var first, second durationSketch
+	for i := range values {
+		// 1µs - 10s 的对数均匀分布
+		values[i] = int64(math.Exp(rng.Float64() * math.Log(1e7)))
+		if i%2 == 0 {
+			first.add(values[i])
+		} else {
+			second.add(values[i])
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	first.merge(&second)
+	if first.count != uint64(len(values)) {
+		t.Fatalf("expected %d values after merge, got %d", len(values), first.count)
+	}
+
+	for _, q := range []float64{0, 0.5, 0.95, 0.99, 1} {
+		expected := float64(values[int(q*float64(len(values)-1))])
+		if got := first.quantile(q); math.Abs(got-expected) > expected*sketchRelativeAccuracy*1.01 {
+			t.Errorf("q%v: expected %v within 1%%, got %v", q, expected, got)
+		}
+	}
+
+	var empty durationSketch
+	empty.add(0)
+	if empty.quantile(0.5) != 0 {
+		t.Error("expected zero durations to be counted as 0")
+	}
+}
+
+func TestSpanStats(t *testing.T) {
+	stats := NewSpanStats("api", StatsConfig{MaxSeries: 3})
+
+	now := time.Now()
+	spans := []*Span{
+		statsTestSpan("GET /a", SpanKindServer, now, 10*time.Millisecond, SpanStatusOK, nil),
+		statsTestSpan("GET /a", SpanKindServer, now, 30*time.Millisecond, SpanStatusError, nil),
+		statsTestSpan("GET /a", SpanKindServer, now.Add(-5*time.Minute), 20*time.Millisecond, SpanStatusOK, nil),
+		statsTestSpan("db SELECT", SpanKindClient, now, time.Millisecond, SpanStatusOK, map[string]any{"db.system": "sqlite"}),
+		statsTestSpan("POST upstream", SpanKindClient, now, 100*time.Millisecond, SpanStatusError, map[string]any{"gateway.upstream": "https://api.example.com/v1"}),
+		statsTestSpan("GET api.github.com", SpanKindClient, now, 50*time.Millisecond, SpanStatusOK, map[string]any{"http.url": "https://api.github.com/repos"}),
+		// 超出保留时长
+		statsTestSpan("GET /a", SpanKindServer, now.Add(-25*time.Hour), time.Second, SpanStatusOK, nil),
+	}
+	stats.Record(spans)
+
+	t.Run("query", func(t *testing.T) {
+		items := stats.Query(StatsQuery{From: now.Add(-10 * time.Minute), To: now.Add(time.Minute)})
+
+		// GET /a, db SELECT, POST upstream 占满 MaxSeries，后续的组合计入 ~other
+		names := map[string]SpanStatsItem{}
+		for _, item := range items {
+			names[item.Name] = item
+		}
+		if len(items) != 4 || names[StatsOtherName].Count != 1 {
+			t.Fatalf("expected 3 series and an overflow series, got %+v", items)
+		}
+
+		a := items[0]
+		if a.Name != "GET /a" || a.Kind != SpanKindServer || a.Count != 3 || a.Errors != 1 {
+			t.Fatalf("unexpected first item %+v", a)
+		}
+		if math.Abs(a.ErrorRate-1.0/3) > 1e-9 || a.AvgMs != 20 || a.MinMs != 10 || a.MaxMs != 30 {
+			t.Errorf("unexpected RED metrics %+v", a.REDMetrics)
+		}
+		// 样本量很小时分位数取下侧秩（rank = q*(n-1) 向下取整）
+		if math.Abs(a.P50Ms-20) > 0.2 || math.Abs(a.P99Ms-20) > 0.2 {
+			t.Errorf("unexpected percentiles p50=%v p99=%v", a.P50Ms, a.P99Ms)
+		}
+		if math.Abs(a.Rate-3.0/660) > 1e-9 {
+			t.Errorf("expected rate over the 11m range, got %v", a.Rate)
+		}
+	})
+
+	t.Run("filters and series", func(t *testing.T) {
+		items := stats.Query(StatsQuery{
+			From:   now.Add(-10 * time.Minute),
+			To:     now.Add(time.Minute),
+			Name:   "GET /a",
+			Series: true,
+		})
+		if len(items) != 1 || len(items[0].Series) != 2 {
+			t.Fatalf("expected GET /a with 2 buckets, got %+v", items)
+		}
+		if !items[0].Series[0].Time.Before(items[0].Series[1].Time) || items[0].Series[1].Count != 2 {
+			t.Errorf("unexpected series %+v", items[0].Series)
+		}
+
+		if items := stats.Query(StatsQuery{Kind: SpanKindClient, From: now.Add(-time.Minute), To: now.Add(time.Minute)}); len(items) != 3 {
+			t.Errorf("expected 3 client series (including overflow), got %d", len(items))
+		}
+		if items := stats.Query(StatsQuery{From: now.Add(-10 * time.Minute), To: now.Add(-4 * time.Minute)}); len(items) != 1 || items[0].Count != 1 {
+			t.Errorf("expected only the older span in range, got %+v", items)
+		}
+	})
+
+	t.Run("service map", func(t *testing.T) {
+		serviceMap := stats.ServiceMap(now.Add(-time.Minute), now.Add(time.Minute))
+
+		expectedNodes := []ServiceMapNode{
+			{ID: "api", Type: ServiceMapNodeService},
+			{ID: "api.example.com", Type: ServiceMapNodeGateway},
+			{ID: "api.github.com", Type: ServiceMapNodeHTTP},
+			{ID: "sqlite", Type: ServiceMapNodeDatabase},
+		}
+		if len(serviceMap.Nodes) != len(expectedNodes) {
+			t.Fatalf("expected %d nodes, got %+v", len(expectedNodes), serviceMap.Nodes)
+		}
+		for i, node := range expectedNodes {
+			if serviceMap.Nodes[i] != node {
+				t.Errorf("(%d) expected node %+v, got %+v", i, node, serviceMap.Nodes[i])
+			}
+		}
+
+		if len(serviceMap.Edges) != 3 {
+			t.Fatalf("expected 3 edges, got %+v", serviceMap.Edges)
+		}
+		for _, edge := range serviceMap.Edges {
+			if edge.Source != "api" || edge.Count != 1 {
+				t.Errorf("unexpected edge %+v", edge)
+			}
+			if edge.Target == "api.example.com" && edge.Errors != 1 {
+				t.Errorf("expected upstream error to be counted, got %+v", edge)
+			}
+		}
+	})
+}
+
+func TestSpanPeer(t *testing.T) {
+	scenarios := []struct {
+		span       *Span
+		target     string
+		targetType string
+		ok         bool
+	}{
+		{&Span{Kind: SpanKindServer, Attributes: map[string]any{"db.system": "sqlite"}}, "", "", false},
+		{&Span{Kind: SpanKindClient, Attributes: map[string]any{"peer.service": "billing", "http.url": "http://x"}}, "billing", ServiceMapNodeService, true},
+		{&Span{Kind: SpanKindClient, Attributes: map[string]any{"db.system": "postgresql"}}, "postgresql", ServiceMapNodeDatabase, true},
+		{&Span{Kind: SpanKindClient, Attributes: map[string]any{"gateway.upstream": "svc-a"}}, "svc-a", ServiceMapNodeGateway, true},
+		{&Span{Kind: SpanKindClient, Attributes: map[string]any{"http.url": "http://localhost:8080/x"}}, "localhost:8080", ServiceMapNodeHTTP, true},
+		{&Span{Kind: SpanKindClient, Name: "rpc"}, "rpc", ServiceMapNodeService, true},
+	}
+
+	for i, s := range scenarios {
+		target, targetType, ok := spanPeer(s.span)
+		if target != s.target || targetType != s.targetType || ok != s.ok {
+			t.Errorf("(%d) expected %s/%s/%v, got %s/%s/%v", i, s.target, s.targetType, s.ok, target, targetType, ok)
+		}
+	}
+}
+
+func TestTraceAPIHandler_Stats(t *testing.T) {
+	stats := NewSpanStats("", StatsConfig{})
+	now := time.Now()
+	stats.Record([]*Span{
+		statsTestSpan("GET /a", SpanKindServer, now, 10*time.Millisecond, SpanStatusOK, nil),
+		statsTestSpan("db SELECT", SpanKindClient, now, time.Millisecond, SpanStatusOK, map[string]any{"db.system": "sqlite"}),
+	})
+
+	mux := http.NewServeMux()
+	NewTraceAPIHandler(&mockRepository{}).WithStats(stats).RegisterRoutes(mux, "/api/_/trace")
+
+	t.Run("stats", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/_/trace/stats?kind=server&series=true", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var response StatsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Items) != 1 || response.Items[0].Name != "GET /a" || len(response.Items[0].Series) != 1 {
+			t.Errorf("unexpected items %+v", response.Items)
+		}
+		if response.To.Sub(response.From) != time.Hour {
+			t.Errorf("expected default 1h range, got %v - %v", response.From, response.To)
+		}
+	})
+
+	t.Run("service map with time range", func(t *testing.T) {
+		from := now.Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+		to := now.Add(-time.Hour).UTC().Format(time.RFC3339)
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/_/trace/service-map?from="+from+"&to="+to, nil))
+
+		var response ServiceMapResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Nodes) != 1 || len(response.Edges) != 0 {
+			t.Errorf("expected only the service node outside the range, got %+v", response.ServiceMap)
+		}
+
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/_/trace/service-map", nil))
+		response = ServiceMapResponse{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		if len(response.Edges) != 1 || response.Edges[0].Source != "pocketbase" || response.Edges[0].Target != "sqlite" {
+			t.Errorf("unexpected service map %+v", response.ServiceMap)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		mux := http.NewServeMux()
+		NewTraceAPIHandler(&mockRepository{}).RegisterRoutes(mux, "/api/_/trace")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/_/trace/stats", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rec.Code)
+		}
+	})
+}
+
+func TestTraceImplStats(t *testing.T) {
+	repo := &recordingRepository{}
+	tracer, err := newTracer(applyDefaults(Config{Mode: ModeFull, Repository: repo}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Close()
+
+	tracer.RecordSpan(statsTestSpan("GET /a", SpanKindServer, time.Now(), time.Millisecond, SpanStatusOK, nil))
+	tracer.Flush()
+
+	items := TracerStats(tracer).Query(StatsQuery{})
+	if len(items) != 1 || items[0].Count != 1 {
+		t.Errorf("expected flushed span to be aggregated, got %+v", items)
+	}
+
+	disabled, err := newTracer(applyDefaults(Config{Mode: ModeFull, Stats: StatsConfig{Disabled: true}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disabled.Close()
+	if TracerStats(disabled) != nil || TracerStats(NewNoopTrace()) != nil {
+		t.Error("expected no stats when disabled")
+	}
+}
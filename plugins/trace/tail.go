@@ -0,0 +1,255 @@
+package trace
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// AttrSamplingPriority 大于 0 时尾部采样无条件保留所在追踪。
+// 启用尾部采样时，TraceMiddleware 会为染色用户及过滤器命中的请求设置该属性，
+// 业务代码也可以通过 SetAttribute 主动标记需要保留的追踪。
+const AttrSamplingPriority = "sampling.priority"
+
+// TailSamplingConfig 尾部采样配置
+type TailSamplingConfig struct {
+	// Enabled 是否启用尾部采样
+	Enabled bool
+	// MaxWait 等待根 Span 结束的最长时间，超时后以已收到的 Span 做决策，默认 30s
+	MaxWait time.Duration
+	// MaxSpans 内存中最多暂存的 Span 数，超出时最早的追踪被提前决策，默认 50000
+	MaxSpans int
+	// DecisionTTL 决策的缓存时间，决策之后才到达的同一追踪的 Span 沿用该决策，默认 1m
+	DecisionTTL time.Duration
+	// Policies 保留策略，任一策略命中即保留整个追踪；为空时使用 DefaultTailPolicies
+	Policies []TailPolicy
+}
+
+// TailTrace 是尾部采样决策时一个追踪在本进程内的全部 Span
+type TailTrace struct {
+	TraceID string
+	Spans   []*Span
+	// Root 本进程内的根 Span（请求 Span 或没有父 Span 的 Span），
+	// 等待超时或因内存上限被提前决策时可能为 nil
+	Root *Span
+}
+
+// Duration 返回追踪的耗时：根 Span 的耗时，没有根 Span 时为所有 Span 覆盖的时间范围
+func (t *TailTrace) Duration() time.Duration {
+	if t.Root != nil {
+		return time.Duration(t.Root.Duration) * time.Microsecond
+	}
+
+	var start, end int64
+	for i, span := range t.Spans {
+		if i == 0 || span.StartTime < start {
+			start = span.StartTime
+		}
+		if spanEnd := span.StartTime + span.Duration; i == 0 || spanEnd > end {
+			end = spanEnd
+		}
+	}
+	return time.Duration(end-start) * time.Microsecond
+}
+
+// HasError 返回追踪中是否有状态为 error 的 Span
+func (t *TailTrace) HasError() bool {
+	for _, span := range t.Spans {
+		if span.Status == SpanStatusError {
+			return true
+		}
+	}
+	return false
+}
+
+// TailPolicy 尾部采样策略，在追踪结束后决定是否保留整个追踪
+type TailPolicy interface {
+	// Name 返回策略名称
+	Name() string
+	// ShouldKeep 判断是否保留追踪
+	ShouldKeep(trace *TailTrace) bool
+}
+
+// tailSampler 按 TraceID 暂存 Span，在根 Span 结束（或等待超时、超出内存上限）时
+// 根据策略保留或丢弃整个追踪，保留的 Span 交给 next（通常是 RingBuffer）。
+type tailSampler struct {
+	maxWait     time.Duration
+	maxSpans    int
+	decisionTTL time.Duration
+	policies    []TailPolicy
+	next        func(*Span)
+
+	mu        sync.Mutex
+	pending   map[string]*list.Element // traceID -> *tailEntry
+	order     *list.List               // 按首个 Span 到达时间排序的 *tailEntry
+	spanCount int
+	decisions map[string]*list.Element // traceID -> *tailDecision
+	expiry    *list.List               // 按决策时间排序的 *tailDecision
+}
+
+// tailEntry 等待决策的追踪
+type tailEntry struct {
+	trace     TailTrace
+	firstSeen time.Time
+}
+
+// tailDecision 已做出的决策
+type tailDecision struct {
+	traceID string
+	keep    bool
+	expires time.Time
+}
+
+// newTailSampler 创建尾部采样器，config 应已应用默认值
+func newTailSampler(config TailSamplingConfig, next func(*Span)) *tailSampler {
+	policies := config.Policies
+	if len(policies) == 0 {
+		policies = DefaultTailPolicies()
+	}
+
+	return &tailSampler{
+		maxWait:     config.MaxWait,
+		maxSpans:    config.MaxSpans,
+		decisionTTL: config.DecisionTTL,
+		policies:    policies,
+		next:        next,
+		pending:     make(map[string]*list.Element),
+		order:       list.New(),
+		decisions:   make(map[string]*list.Element),
+		expiry:      list.New(),
+	}
+}
+
+// add 暂存一个已完成的 Span，根 Span 到达时立即对所在追踪做出决策
+func (s *tailSampler) add(span *Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 已决策的追踪沿用之前的决策
+	if el, ok := s.decisions[span.TraceID]; ok {
+		if el.Value.(*tailDecision).keep {
+			s.next(span)
+		}
+		return
+	}
+
+	el, ok := s.pending[span.TraceID]
+	if !ok {
+		el = s.order.PushBack(&tailEntry{
+			trace:     TailTrace{TraceID: span.TraceID},
+			firstSeen: time.Now(),
+		})
+		s.pending[span.TraceID] = el
+	}
+
+	entry := el.Value.(*tailEntry)
+	entry.trace.Spans = append(entry.trace.Spans, span)
+	s.spanCount++
+
+	if isTailRoot(span) {
+		entry.trace.Root = span
+		s.decide(el)
+	}
+
+	// 超出内存上限时提前决策最早的追踪
+	for s.spanCount > s.maxSpans && s.order.Len() > 0 {
+		s.decide(s.order.Front())
+	}
+}
+
+// sweep 对等待超过 MaxWait 的追踪做出决策，并清理过期的决策缓存
+func (s *tailSampler) sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for el := s.order.Front(); el != nil; el = s.order.Front() {
+		if now.Sub(el.Value.(*tailEntry).firstSeen) < s.maxWait {
+			break
+		}
+		s.decide(el)
+	}
+
+	for el := s.expiry.Front(); el != nil; el = s.expiry.Front() {
+		decision := el.Value.(*tailDecision)
+		if now.Before(decision.expires) {
+			break
+		}
+		s.expiry.Remove(el)
+		delete(s.decisions, decision.traceID)
+	}
+}
+
+// drain 立即对所有等待中的追踪做出决策（关闭时调用）
+func (s *tailSampler) drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.order.Len() > 0 {
+		s.decide(s.order.Front())
+	}
+}
+
+// decide 对追踪做出决策并输出保留的 Span，调用方需持有锁
+func (s *tailSampler) decide(el *list.Element) {
+	entry := s.order.Remove(el).(*tailEntry)
+	delete(s.pending, entry.trace.TraceID)
+	s.spanCount -= len(entry.trace.Spans)
+
+	keep := s.shouldKeep(&entry.trace)
+	if keep {
+		for _, span := range entry.trace.Spans {
+			s.next(span)
+		}
+	}
+
+	s.decisions[entry.trace.TraceID] = s.expiry.PushBack(&tailDecision{
+		traceID: entry.trace.TraceID,
+		keep:    keep,
+		expires: time.Now().Add(s.decisionTTL),
+	})
+}
+
+// shouldKeep 判断是否保留追踪：标记了 sampling.priority 或任一策略命中
+func (s *tailSampler) shouldKeep(trace *TailTrace) bool {
+	for _, span := range trace.Spans {
+		if priority, ok := span.Attributes[AttrSamplingPriority]; ok && isPositiveNumber(priority) {
+			return true
+		}
+	}
+
+	for _, policy := range s.policies {
+		if policy.ShouldKeep(trace) {
+			return true
+		}
+	}
+	return false
+}
+
+// stats 返回等待决策的追踪数与 Span 数
+func (s *tailSampler) stats() (traces int, spans int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len(), s.spanCount
+}
+
+// isTailRoot 判断 Span 是否为本进程内的根 Span：
+// 服务端请求 Span（父 Span 可能在上游服务）或没有父 Span 的 Span
+func isTailRoot(span *Span) bool {
+	return span.ParentID == "" || span.Kind == SpanKindServer
+}
+
+// isPositiveNumber 判断属性值是否为大于 0 的数字或 true
+func isPositiveNumber(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v > 0
+	case int64:
+		return v > 0
+	case float64:
+		return v > 0
+	default:
+		return false
+	}
+}
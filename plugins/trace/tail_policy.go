@@ -0,0 +1,207 @@
+package trace
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultTailPolicies 返回默认的尾部采样策略：保留出错的追踪和耗时超过 1s 的追踪
+func DefaultTailPolicies() []TailPolicy {
+	return []TailPolicy{
+		ErrorTailPolicy(),
+		LatencyTailPolicy(time.Second),
+	}
+}
+
+// ============================================================================
+// 错误策略
+// ============================================================================
+
+type errorTailPolicy struct{}
+
+// ErrorTailPolicy 保留任一 Span 状态为 error 的追踪
+func ErrorTailPolicy() TailPolicy {
+	return errorTailPolicy{}
+}
+
+func (errorTailPolicy) Name() string {
+	return "error"
+}
+
+func (errorTailPolicy) ShouldKeep(trace *TailTrace) bool {
+	return trace.HasError()
+}
+
+// ============================================================================
+// 耗时策略
+// ============================================================================
+
+type latencyTailPolicy struct {
+	threshold time.Duration
+}
+
+// LatencyTailPolicy 保留总耗时不低于 threshold 的追踪
+func LatencyTailPolicy(threshold time.Duration) TailPolicy {
+	return latencyTailPolicy{threshold: threshold}
+}
+
+func (p latencyTailPolicy) Name() string {
+	return "latency"
+}
+
+func (p latencyTailPolicy) ShouldKeep(trace *TailTrace) bool {
+	return trace.Duration() >= p.threshold
+}
+
+// ============================================================================
+// 属性策略
+// ============================================================================
+
+type attributeTailPolicy struct {
+	key    string
+	values map[string]struct{}
+}
+
+// AttributeTailPolicy 保留任一 Span 的属性 key 等于 values 之一的追踪，
+// 未指定 values 时只要存在该属性即保留。属性值按 fmt.Sprint 的结果比较。
+func AttributeTailPolicy(key string, values ...any) TailPolicy {
+	p := attributeTailPolicy{key: key}
+	if len(values) > 0 {
+		p.values = make(map[string]struct{}, len(values))
+		for _, v := range values {
+			p.values[fmt.Sprint(v)] = struct{}{}
+		}
+	}
+	return p
+}
+
+func (p attributeTailPolicy) Name() string {
+	return "attribute"
+}
+
+func (p attributeTailPolicy) ShouldKeep(trace *TailTrace) bool {
+	for _, span := range trace.Spans {
+		value, ok := span.Attributes[p.key]
+		if !ok {
+			continue
+		}
+		if p.values == nil {
+			return true
+		}
+		if _, ok := p.values[fmt.Sprint(value)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ============================================================================
+// 概率策略
+// ============================================================================
+
+type probabilisticTailPolicy struct {
+	rate float64
+}
+
+// ProbabilisticTailPolicy 按比例保留追踪，作为未命中其他策略时的基线采样。
+// 结果由 TraceID 决定，同一追踪在不同服务中的决策一致。
+// rate 应该在 [0.0, 1.0] 范围内。
+func ProbabilisticTailPolicy(rate float64) TailPolicy {
+	return probabilisticTailPolicy{rate: math.Max(0, math.Min(1, rate))}
+}
+
+func (p probabilisticTailPolicy) Name() string {
+	return "probabilistic"
+}
+
+func (p probabilisticTailPolicy) ShouldKeep(trace *TailTrace) bool {
+	if p.rate >= 1 {
+		return true
+	}
+	if p.rate <= 0 {
+		return false
+	}
+
+	// 取 TraceID 低 8 字节作为均匀分布的随机数
+	id, err := hex.DecodeString(trace.TraceID)
+	if err != nil || len(id) < 8 {
+		return false
+	}
+	n := binary.BigEndian.Uint64(id[len(id)-8:])
+	return float64(n) < p.rate*math.MaxUint64
+}
+
+// ============================================================================
+// 按路由限流策略
+// ============================================================================
+
+// maxRateLimitRoutes 限流策略最多单独计数的路由数，超出的路由共用一个计数器
+const maxRateLimitRoutes = 1024
+
+type rateLimitTailPolicy struct {
+	perSecond float64
+	burst     float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitTailPolicy 每个路由（根 Span 名称）每秒最多保留 perSecond 个追踪，
+// 保证低流量路由也有样本，同时限制高流量路由的存储量。
+// 默认的请求 Span 名称包含原始路径，可通过 MiddlewareConfig.SpanNameFunc 使用路由模式。
+func RateLimitTailPolicy(perSecond float64) TailPolicy {
+	return &rateLimitTailPolicy{
+		perSecond: perSecond,
+		burst:     math.Max(1, perSecond),
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+func (p *rateLimitTailPolicy) Name() string {
+	return "rate_limit"
+}
+
+func (p *rateLimitTailPolicy) ShouldKeep(trace *TailTrace) bool {
+	if p.perSecond <= 0 {
+		return false
+	}
+
+	route := ""
+	if trace.Root != nil {
+		route = trace.Root.Name
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bucket, ok := p.buckets[route]
+	if !ok {
+		if len(p.buckets) >= maxRateLimitRoutes {
+			route = "*"
+			bucket = p.buckets[route]
+		}
+		if bucket == nil {
+			bucket = &tokenBucket{tokens: p.burst, last: time.Now()}
+			p.buckets[route] = bucket
+		}
+	}
+
+	now := time.Now()
+	bucket.tokens = math.Min(p.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*p.perSecond)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
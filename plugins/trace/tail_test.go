@@ -0,0 +1,299 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTailSampler 创建输出到切片的尾部采样器
+func newTestTailSampler(config TailSamplingConfig) (*tailSampler, *[]*Span) {
+	var kept []*Span
+	config = applyDefaults(Config{TailSampling: config}).TailSampling
+	return newTailSampler(config, func(span *Span) { kept = append(kept, span) }), &kept
+}
+
+func tailTestSpan(traceID, name, parentID string, status SpanStatus) *Span {
+	return &Span{
+		TraceID:  traceID,
+		SpanID:   GenerateSpanID(),
+		ParentID: parentID,
+		Name:     name,
+		Kind:     SpanKindInternal,
+		Status:   status,
+	}
+}
+
+func tailTestRoot(traceID string, duration time.Duration) *Span {
+	return &Span{
+		TraceID:   traceID,
+		SpanID:    GenerateSpanID(),
+		ParentID:  "remote",
+		Name:      "GET /api/test",
+		Kind:      SpanKindServer,
+		Duration:  duration.Microseconds(),
+		Status:    SpanStatusOK,
+		StartTime: time.Now().UnixMicro(),
+	}
+}
+
+// staticFilter 总是返回固定结果的前置过滤器
+type staticFilter bool
+
+func (f staticFilter) Name() string                        { return "static" }
+func (f staticFilter) Phase() FilterPhase                  { return PreExecution }
+func (f staticFilter) ShouldTrace(ctx *FilterContext) bool { return bool(f) }
+
+func TestTailSampler(t *testing.T) {
+	t.Run("keeps whole trace when a child errored", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{})
+
+		errored, ok := GenerateTraceID(), GenerateTraceID()
+		s.add(tailTestSpan(errored, "db SELECT", "root", SpanStatusError))
+		s.add(tailTestSpan(errored, "hook", "root", SpanStatusOK))
+		s.add(tailTestSpan(ok, "db SELECT", "root", SpanStatusOK))
+
+		if len(*kept) != 0 {
+			t.Fatalf("expected spans to be buffered until the root ends, got %d", len(*kept))
+		}
+
+		s.add(tailTestRoot(errored, time.Millisecond))
+		s.add(tailTestRoot(ok, time.Millisecond))
+
+		if len(*kept) != 3 {
+			t.Fatalf("expected 3 spans of the errored trace, got %d", len(*kept))
+		}
+		for _, span := range *kept {
+			if span.TraceID != errored {
+				t.Errorf("unexpected span from trace %s", span.TraceID)
+			}
+		}
+		if traces, spans := s.stats(); traces != 0 || spans != 0 {
+			t.Errorf("expected no pending traces, got %d/%d", traces, spans)
+		}
+	})
+
+	t.Run("late spans follow the decision", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{})
+
+		slow, fast := GenerateTraceID(), GenerateTraceID()
+		s.add(tailTestRoot(slow, 2*time.Second))
+		s.add(tailTestRoot(fast, time.Millisecond))
+		s.add(tailTestSpan(slow, "late", "root", SpanStatusOK))
+		s.add(tailTestSpan(fast, "late", "root", SpanStatusOK))
+
+		if len(*kept) != 2 || (*kept)[1].Name != "late" || (*kept)[1].TraceID != slow {
+			t.Errorf("expected root and late span of the slow trace, got %d spans", len(*kept))
+		}
+
+		// 决策过期后的 Span 重新等待决策
+		s.sweep(time.Now().Add(2 * time.Minute))
+		s.add(tailTestSpan(fast, "after ttl", "root", SpanStatusOK))
+		if traces, _ := s.stats(); traces != 1 {
+			t.Errorf("expected span to be pending after decision expiry, got %d traces", traces)
+		}
+	})
+
+	t.Run("max wait", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{MaxWait: time.Minute})
+
+		traceID := GenerateTraceID()
+		s.add(tailTestSpan(traceID, "orphan", "root", SpanStatusError))
+
+		s.sweep(time.Now())
+		if len(*kept) != 0 {
+			t.Fatal("expected trace to wait for its root")
+		}
+
+		s.sweep(time.Now().Add(time.Minute))
+		if len(*kept) != 1 {
+			t.Errorf("expected trace to be decided after max wait, got %d spans", len(*kept))
+		}
+	})
+
+	t.Run("max spans", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{MaxSpans: 3})
+
+		first, second := GenerateTraceID(), GenerateTraceID()
+		s.add(tailTestSpan(first, "a", "root", SpanStatusError))
+		s.add(tailTestSpan(first, "b", "root", SpanStatusOK))
+		s.add(tailTestSpan(second, "c", "root", SpanStatusOK))
+		s.add(tailTestSpan(second, "d", "root", SpanStatusOK))
+
+		if len(*kept) != 2 || (*kept)[0].TraceID != first {
+			t.Errorf("expected the oldest trace to be decided early, got %d spans", len(*kept))
+		}
+		if traces, spans := s.stats(); traces != 1 || spans != 2 {
+			t.Errorf("expected 1 pending trace with 2 spans, got %d/%d", traces, spans)
+		}
+	})
+
+	t.Run("sampling priority", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{Policies: []TailPolicy{ProbabilisticTailPolicy(0)}})
+
+		root := tailTestRoot(GenerateTraceID(), time.Millisecond)
+		root.Attributes = map[string]any{AttrSamplingPriority: 1}
+		s.add(root)
+		s.add(tailTestRoot(GenerateTraceID(), time.Millisecond))
+
+		if len(*kept) != 1 || (*kept)[0] != root {
+			t.Errorf("expected only the prioritized trace, got %d spans", len(*kept))
+		}
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		s, kept := newTestTailSampler(TailSamplingConfig{})
+
+		s.add(tailTestSpan(GenerateTraceID(), "pending", "root", SpanStatusError))
+		s.drain()
+
+		if len(*kept) != 1 {
+			t.Errorf("expected pending trace to be decided, got %d spans", len(*kept))
+		}
+	})
+}
+
+func TestTailPolicies(t *testing.T) {
+	traceWith := func(spans ...*Span) *TailTrace {
+		trace := &TailTrace{TraceID: "0af7651916cd43dd8448eb211c80319c", Spans: spans}
+		for _, span := range spans {
+			if span.Kind == SpanKindServer {
+				trace.Root = span
+			}
+		}
+		return trace
+	}
+
+	t.Run("latency without root uses span range", func(t *testing.T) {
+		trace := traceWith(
+			&Span{StartTime: 1000, Duration: 500},
+			&Span{StartTime: 1200, Duration: 1000000},
+		)
+		if trace.Duration() != 1000200*time.Microsecond {
+			t.Errorf("unexpected duration %v", trace.Duration())
+		}
+		if !LatencyTailPolicy(time.Second).ShouldKeep(trace) || LatencyTailPolicy(2*time.Second).ShouldKeep(trace) {
+			t.Error("unexpected latency policy result")
+		}
+	})
+
+	t.Run("attribute", func(t *testing.T) {
+		trace := traceWith(&Span{Attributes: map[string]any{"user.id": "u1", "http.status_code": 429}})
+
+		scenarios := []struct {
+			policy   TailPolicy
+			expected bool
+		}{
+			{AttributeTailPolicy("user.id"), true},
+			{AttributeTailPolicy("user.id", "u2"), false},
+			{AttributeTailPolicy("http.status_code", 429, 503), true},
+			{AttributeTailPolicy("http.status_code", "429"), true},
+			{AttributeTailPolicy("missing"), false},
+		}
+		for i, s := range scenarios {
+			if result := s.policy.ShouldKeep(trace); result != s.expected {
+				t.Errorf("(%d) expected %v, got %v", i, s.expected, result)
+			}
+		}
+	})
+
+	t.Run("probabilistic", func(t *testing.T) {
+		if !ProbabilisticTailPolicy(1).ShouldKeep(traceWith()) || ProbabilisticTailPolicy(0).ShouldKeep(traceWith()) {
+			t.Fatal("unexpected result for rate 0/1")
+		}
+
+		policy := ProbabilisticTailPolicy(0.25)
+		kept := 0
+		for i := 0; i < 4000; i++ {
+			trace := &TailTrace{TraceID: GenerateTraceID()}
+			result := policy.ShouldKeep(trace)
+			if result != policy.ShouldKeep(trace) {
+				t.Fatal("expected deterministic decision for the same trace")
+			}
+			if result {
+				kept++
+			}
+		}
+		if kept < 800 || kept > 1200 {
+			t.Errorf("expected about 1000 kept traces, got %d", kept)
+		}
+	})
+
+	t.Run("rate limit per route", func(t *testing.T) {
+		policy := RateLimitTailPolicy(2)
+
+		route := func(name string) *TailTrace {
+			return &TailTrace{Root: &Span{Name: name}}
+		}
+
+		keptA := 0
+		for i := 0; i < 10; i++ {
+			if policy.ShouldKeep(route("GET /a")) {
+				keptA++
+			}
+		}
+		if keptA != 2 {
+			t.Errorf("expected 2 traces of /a within the burst, got %d", keptA)
+		}
+		if !policy.ShouldKeep(route("GET /b")) {
+			t.Error("expected /b to have its own budget")
+		}
+
+		if RateLimitTailPolicy(0).ShouldKeep(route("GET /a")) {
+			t.Error("expected zero rate to keep nothing")
+		}
+	})
+}
+
+func TestTraceImplTailSampling(t *testing.T) {
+	config := applyDefaults(Config{Mode: ModeConditional, TailSampling: TailSamplingConfig{Enabled: true}})
+	tracer, err := newTracer(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tracer.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartChildSpan(r.Context(), "child")
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			span.SetStatus(SpanStatusError, "failed")
+		}
+		span.End()
+		w.WriteHeader(http.StatusOK)
+	})
+	middleware := TraceMiddleware(tracer, &MiddlewareConfig{Mode: ModeConditional})(handler)
+
+	// 请求本身成功但子 Span 出错的追踪被完整保留，正常的快速请求被丢弃
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/ok", nil))
+	middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/fail", nil))
+
+	spans := tracer.buffer.Flush(10)
+	if len(spans) != 2 {
+		t.Fatalf("expected request and child span of the failed request, got %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.TraceID != spans[0].TraceID {
+			t.Error("expected spans from a single trace")
+		}
+		if span.Kind == SpanKindServer && span.Name != "GET /api/fail" {
+			t.Errorf("unexpected request span %s", span.Name)
+		}
+	}
+
+	// 前置过滤器命中的请求标记为必须保留
+	forced := TraceMiddleware(tracer, &MiddlewareConfig{
+		Mode:    ModeConditional,
+		Filters: []Filter{staticFilter(true)},
+	})(handler)
+	forced.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/ok", nil))
+
+	spans = tracer.buffer.Flush(10)
+	if len(spans) != 2 {
+		t.Fatalf("expected forced trace to be kept, got %d spans", len(spans))
+	}
+	if root := spans[1]; root.Attributes[AttrSamplingPriority] != 1 {
+		t.Errorf("expected %s on the request span, got %v", AttrSamplingPriority, root.Attributes)
+	}
+}
@@ -29,18 +29,59 @@ func TestTraceImplIsEnabled(t *testing.T) {
 
 // TestTraceImplStartSpan 测试 traceImpl.StartSpan 方法
 func TestTraceImplStartSpan(t *testing.T) {
-	tracer := &traceImpl{config: DefaultConfig()}
-	ctx := context.Background()
+	t.Run("disabled tracer returns NoopSpanBuilder", func(t *testing.T) {
+		tracer := &traceImpl{config: Config{Mode: ModeOff}}
+		ctx := context.Background()
 
-	newCtx, builder := tracer.StartSpan(ctx, "test-span")
+		newCtx, builder := tracer.StartSpan(ctx, "test-span")
+		if newCtx != ctx {
+			t.Error("StartSpan should return same context when disabled")
+		}
+		if _, ok := builder.(*NoopSpanBuilder); !ok {
+			t.Error("StartSpan should return NoopSpanBuilder when disabled")
+		}
+	})
 
-	// 现在返回 NoopSpanBuilder（TODO 实现后会更新）
-	if newCtx != ctx {
-		t.Error("StartSpan should return same context for now")
-	}
-	if _, ok := builder.(*NoopSpanBuilder); !ok {
-		t.Error("StartSpan should return NoopSpanBuilder for now")
-	}
+	t.Run("root and child spans", func(t *testing.T) {
+		tracer := &traceImpl{config: DefaultConfig(), buffer: NewRingBuffer(10)}
+
+		ctx, root := tracer.StartSpan(context.Background(), "root")
+		rootSpan := SpanFromContext(ctx)
+		if rootSpan == nil || rootSpan.TraceID == "" || rootSpan.ParentID != "" {
+			t.Fatalf("expected new root span, got %+v", rootSpan)
+		}
+
+		_, child := tracer.StartSpan(ctx, "child")
+		child.SetKind(SpanKindClient).SetAttribute("k", "v").SetStatus(SpanStatusError, "boom")
+		child.End()
+		child.End() // 重复调用无效
+		root.End()
+
+		spans := tracer.buffer.Flush(10)
+		if len(spans) != 2 {
+			t.Fatalf("expected 2 recorded spans, got %d", len(spans))
+		}
+
+		childSpan := spans[0]
+		if childSpan.Name != "child" || childSpan.TraceID != rootSpan.TraceID || childSpan.ParentID != rootSpan.SpanID {
+			t.Errorf("unexpected child span %+v", childSpan)
+		}
+		if childSpan.Kind != SpanKindClient || childSpan.Status != SpanStatusError ||
+			childSpan.Attributes["k"] != "v" || childSpan.StatusMessage != "boom" {
+			t.Errorf("unexpected child span fields %+v", childSpan)
+		}
+	})
+
+	t.Run("continues remote trace context", func(t *testing.T) {
+		tracer := &traceImpl{config: DefaultConfig(), buffer: NewRingBuffer(10)}
+		tc := &TraceContext{TraceID: GenerateTraceID(), ParentID: GenerateSpanID(), Sampled: true}
+
+		ctx, _ := tracer.StartSpan(ContextWithTraceContext(context.Background(), tc), "remote")
+		span := SpanFromContext(ctx)
+		if span.TraceID != tc.TraceID || span.ParentID != tc.ParentID {
+			t.Errorf("expected span to continue %+v, got %+v", tc, span)
+		}
+	})
 }
 
 // TestTraceImplRecordSpan 测试 traceImpl.RecordSpan 方法
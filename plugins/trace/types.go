@@ -32,27 +32,61 @@ const (
 
 // Span 表示一个追踪 Span
 type Span struct {
-	ID         string            `json:"id"`
-	TraceID    string            `json:"traceId"`
-	SpanID     string            `json:"spanId"`
-	ParentID   string            `json:"parentId,omitempty"`
-	Name       string            `json:"name"`
-	Kind       SpanKind          `json:"kind"`
-	StartTime  int64             `json:"startTime"` // 微秒时间戳
-	Duration   int64             `json:"duration"`  // 微秒
-	Status     SpanStatus        `json:"status"`
-	Attributes map[string]any    `json:"attributes,omitempty"`
-	Created    types.DateTime    `json:"created"`
+	ID            string         `json:"id"`
+	TraceID       string         `json:"traceId"`
+	SpanID        string         `json:"spanId"`
+	ParentID      string         `json:"parentId,omitempty"`
+	Name          string         `json:"name"`
+	Kind          SpanKind       `json:"kind"`
+	StartTime     int64          `json:"startTime"` // 微秒时间戳
+	Duration      int64          `json:"duration"`  // 微秒
+	Status        SpanStatus     `json:"status"`
+	StatusMessage string         `json:"statusMessage,omitempty"`
+	Attributes    map[string]any `json:"attributes,omitempty"`
+	Events        []SpanEvent    `json:"events,omitempty"`
+	Links         []SpanLink     `json:"links,omitempty"`
+	Created       types.DateTime `json:"created"`
 }
 
+// SpanEvent 表示 Span 执行期间发生的一个带时间戳的事件，如 "cache miss"、"retry #2" 或异常
+type SpanEvent struct {
+	Name       string         `json:"name"`
+	Time       int64          `json:"time"` // 微秒时间戳
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanLink 表示指向另一个 Span 的因果关联（不构成父子关系），
+// 如任务执行 Span 指向将其入队的请求 Span
+type SpanLink struct {
+	TraceID    string         `json:"traceId"`
+	SpanID     string         `json:"spanId"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// SpanEventException 是 RecordError 记录的异常事件名称
+const SpanEventException = "exception"
+
+const (
+	// MaxSpanEvents 单个 Span 最多记录的事件数，超出部分被丢弃
+	MaxSpanEvents = 128
+	// MaxSpanLinks 单个 Span 最多记录的链接数，超出部分被丢弃
+	MaxSpanLinks = 32
+)
+
 // SpanBuilder 用于构建和完成 Span
 type SpanBuilder interface {
 	// SetAttribute 设置 Span 属性
 	SetAttribute(key string, value any) SpanBuilder
-	// SetStatus 设置 Span 状态
+	// SetStatus 设置 Span 状态及状态描述
 	SetStatus(status SpanStatus, message string) SpanBuilder
 	// SetKind 设置 Span 类型
 	SetKind(kind SpanKind) SpanBuilder
+	// AddEvent 添加一个以当前时间为时间戳的事件
+	AddEvent(name string, attributes map[string]any) SpanBuilder
+	// RecordError 记录异常事件（类型、消息与堆栈）并将状态设为 error，err 为 nil 时不做任何操作
+	RecordError(err error) SpanBuilder
+	// AddLink 添加指向另一个 Span 的链接
+	AddLink(traceID, spanID string, attributes map[string]any) SpanBuilder
 	// End 结束 Span
 	End()
 }
@@ -0,0 +1,128 @@
+package dbutils
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// NormalizeSQL 将语句中的字符串、数字与十六进制字面量（以及 dbx 内联的 nil 参数 <nil>）替换为 ?，
+// 去掉 dbx 的 {{table}} / [[column]] 引用标记，带引号的标识符原样保留。
+//
+// 返回的语句最长 maxLength 字节（<= 0 时不限制）；同时按出现顺序返回各字面量的类型与长度
+// （text(n)、integer、real、blob(n)、null），最多 maxParams 个，超出时末尾追加 "..."，
+// maxParams <= 0 时不收集。
+//
+// dbx 在查询回调中传入的是内联了参数值的语句，因此绑定参数与语句中原有的字面量无法区分，两者都按字面量处理。
+func NormalizeSQL(statement string, maxLength, maxParams int) (string, []string) {
+	if maxLength <= 0 {
+		maxLength = len(statement)
+	}
+
+	var sb strings.Builder
+	sb.Grow(min(len(statement), maxLength))
+
+	var params []string
+	if maxParams > 0 {
+		params = []string{}
+	}
+	addParam := func(shape func() string) {
+		sb.WriteByte('?')
+		switch {
+		case maxParams <= 0:
+		case len(params) < maxParams:
+			params = append(params, shape())
+		case len(params) == maxParams:
+			params = append(params, "...")
+		}
+	}
+
+	for i := 0; i < len(statement) && sb.Len() < maxLength; i++ {
+		c := statement[i]
+
+		switch {
+		case c == '\'':
+			// 字符串字面量，'' 为转义的单引号
+			start := i + 1
+			escaped := 0
+			i++
+			for i < len(statement) {
+				if statement[i] == '\'' {
+					if i+1 < len(statement) && statement[i+1] == '\'' {
+						escaped++
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			end := min(i, len(statement))
+			addParam(func() string {
+				return "text(" + strconv.Itoa(utf8.RuneCountInString(statement[start:end])-escaped) + ")"
+			})
+		case c == '`' || c == '"':
+			// 带引号的标识符原样保留
+			end := strings.IndexByte(statement[i+1:], c)
+			if end < 0 {
+				sb.WriteString(statement[i:])
+				i = len(statement)
+				break
+			}
+			sb.WriteString(statement[i : i+end+2])
+			i += end + 1
+		case c == '0' && i+1 < len(statement) && (statement[i+1] == 'x' || statement[i+1] == 'X') && (i == 0 || !isSQLIdentChar(statement[i-1])):
+			// 十六进制字面量（dbx 将 []byte 参数内联为 0x...）
+			start := i + 2
+			i++
+			for i+1 < len(statement) && isSQLIdentChar(statement[i+1]) {
+				i++
+			}
+			size := (i + 1 - start) / 2
+			addParam(func() string { return "blob(" + strconv.Itoa(size) + ")" })
+		case c >= '0' && c <= '9' && (i == 0 || !isSQLIdentChar(statement[i-1])):
+			isReal := false
+			for i+1 < len(statement) && (isSQLIdentChar(statement[i+1]) || statement[i+1] == '.') {
+				i++
+				if statement[i] == '.' || statement[i] == 'e' || statement[i] == 'E' {
+					isReal = true
+				}
+			}
+			if isReal {
+				addParam(func() string { return "real" })
+			} else {
+				addParam(func() string { return "integer" })
+			}
+		case c == '<' && strings.HasPrefix(statement[i:], "<nil>"):
+			i += len("<nil>") - 1
+			addParam(func() string { return "null" })
+		case (c == '{' || c == '}' || c == '[' || c == ']') && i+1 < len(statement) && statement[i+1] == c:
+			// dbx 的 {{table}} 与 [[column]] 引用标记
+			i++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	result := sb.String()
+	if len(result) > maxLength {
+		result = result[:maxLength]
+	}
+
+	return result, params
+}
+
+// SQLOperation 返回 SQL 语句的操作类型（首个关键字的大写形式），空语句返回空字符串
+func SQLOperation(statement string) string {
+	statement = strings.TrimLeft(statement, " \t\r\n(")
+	end := strings.IndexAny(statement, " \t\r\n(")
+	if end < 0 {
+		end = len(statement)
+	}
+	return strings.ToUpper(statement[:end])
+}
+
+// isSQLIdentChar 判断字符是否可以出现在未加引号的标识符中
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
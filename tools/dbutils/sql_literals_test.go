@@ -0,0 +1,82 @@
+package dbutils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		sql      string
+		expected string
+		params   string
+	}{
+		{
+			"dbx quote markers",
+			"SELECT {{users}}.* FROM {{users}} WHERE [[users.email]] = 'john@example.com' LIMIT 1",
+			"SELECT users.* FROM users WHERE users.email = ? LIMIT ?",
+			"text(16),integer",
+		},
+		{
+			"escaped quotes and numbers",
+			"UPDATE `posts` SET `title`='it''s secret', `views`=42, `rating`=4.5 WHERE `id`='abc'",
+			"UPDATE `posts` SET `title`=?, `views`=?, `rating`=? WHERE `id`=?",
+			"text(11),integer,real,text(3)",
+		},
+		{
+			"hex and nil",
+			"INSERT INTO t1 (col2, data, note) VALUES (-7, 0x68656c6c6f, <nil>)",
+			"INSERT INTO t1 (col2, data, note) VALUES (-?, ?, ?)",
+			"integer,blob(5),null",
+		},
+		{
+			"quoted identifiers and digits in names",
+			"SELECT \"2024 total\", `col'1` FROM _analytics_geo2",
+			"SELECT \"2024 total\", `col'1` FROM _analytics_geo2",
+			"",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result, params := dbutils.NormalizeSQL(s.sql, 2048, 10)
+			if result != s.expected {
+				t.Errorf("expected\n%s\ngot\n%s", s.expected, result)
+			}
+			if strings.Join(params, ",") != s.params {
+				t.Errorf("expected params %q, got %v", s.params, params)
+			}
+		})
+	}
+}
+
+func TestNormalizeSQLLimits(t *testing.T) {
+	result, params := dbutils.NormalizeSQL("SELECT "+strings.Repeat("a", 100), 10, 0)
+	if len(result) != 10 || params != nil {
+		t.Errorf("expected a truncated statement without params, got %q %v", result, params)
+	}
+
+	_, params = dbutils.NormalizeSQL("SELECT 1, 2, 3", 0, 2)
+	if strings.Join(params, ",") != "integer,integer,..." {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestSQLOperation(t *testing.T) {
+	scenarios := map[string]string{
+		"select * from users":           "SELECT",
+		"  (SELECT 1) UNION (SELECT 2)": "SELECT",
+		"WITH cte AS (SELECT 1)":        "WITH",
+		"DELETE FROM users":             "DELETE",
+		"":                              "",
+	}
+
+	for sql, expected := range scenarios {
+		if result := dbutils.SQLOperation(sql); result != expected {
+			t.Errorf("SQLOperation(%q) = %q, want %q", sql, result, expected)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 
 	"github.com/disintegration/imaging"
 	"github.com/fatih/color"
@@ -33,6 +34,31 @@ var ErrNotFound = blob.ErrNotFound
 
 const metadataOriginalName = "original-filename"
 
+// UploadObserver defines an optional function that is invoked right
+// before each file upload (eg. for tracing).
+//
+// ctx is the filesystem context assigned with [System.SetContext] and size
+// is the size in bytes of the uploaded content.
+//
+// The returned function, if not nil, is invoked right after the upload
+// completes with the upload error.
+type UploadObserver func(ctx context.Context, fileKey string, size int64) func(err error)
+
+var uploadObserver atomic.Pointer[UploadObserver]
+
+// SetUploadObserver registers a global observer that is notified
+// for every [System.Upload], [System.UploadFile] and [System.UploadMultipart] call.
+//
+// Pass nil to remove the current observer.
+func SetUploadObserver(observer UploadObserver) {
+	if observer == nil {
+		uploadObserver.Store(nil)
+		return
+	}
+
+	uploadObserver.Store(&observer)
+}
+
 type System struct {
 	ctx    context.Context
 	bucket *blob.Bucket
@@ -94,6 +120,16 @@ func (s *System) SetContext(ctx context.Context) {
 	s.ctx = ctx
 }
 
+// observeUpload notifies the registered UploadObserver (if any).
+func (s *System) observeUpload(fileKey string, size int64) func(err error) {
+	observer := uploadObserver.Load()
+	if observer == nil {
+		return nil
+	}
+
+	return (*observer)(s.ctx, fileKey, size)
+}
+
 // Close releases any resources used for the related filesystem.
 func (s *System) Close() error {
 	return s.bucket.Close()
@@ -197,7 +233,11 @@ func (s *System) List(prefix string) ([]*blob.ListObject, error) {
 }
 
 // Upload writes content into the fileKey location.
-func (s *System) Upload(content []byte, fileKey string) error {
+func (s *System) Upload(content []byte, fileKey string) (err error) {
+	if done := s.observeUpload(fileKey, int64(len(content))); done != nil {
+		defer func() { done(err) }()
+	}
+
 	opts := &blob.WriterOptions{
 		ContentType: mimetype.Detect(content).String(),
 	}
@@ -215,7 +255,11 @@ func (s *System) Upload(content []byte, fileKey string) error {
 }
 
 // UploadFile uploads the provided File to the fileKey location.
-func (s *System) UploadFile(file *File, fileKey string) error {
+func (s *System) UploadFile(file *File, fileKey string) (err error) {
+	if done := s.observeUpload(fileKey, file.Size); done != nil {
+		defer func() { done(err) }()
+	}
+
 	f, err := file.Reader.Open()
 	if err != nil {
 		return err
@@ -257,7 +301,11 @@ func (s *System) UploadFile(file *File, fileKey string) error {
 }
 
 // UploadMultipart uploads the provided multipart file to the fileKey location.
-func (s *System) UploadMultipart(fh *multipart.FileHeader, fileKey string) error {
+func (s *System) UploadMultipart(fh *multipart.FileHeader, fileKey string) (err error) {
+	if done := s.observeUpload(fileKey, fh.Size); done != nil {
+		defer func() { done(err) }()
+	}
+
 	f, err := fh.Open()
 	if err != nil {
 		return err
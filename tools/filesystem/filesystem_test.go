@@ -2,7 +2,9 @@ package filesystem_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
@@ -327,6 +329,46 @@ func TestFileSystemUpload(t *testing.T) {
 	}
 }
 
+func TestFileSystemUploadObserver(t *testing.T) {
+	dir := createTestDir(t)
+	defer os.RemoveAll(dir)
+
+	fsys, err := filesystem.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	type ctxKey struct{}
+	fsys.SetContext(context.WithValue(context.Background(), ctxKey{}, "test"))
+
+	var calls []string
+	filesystem.SetUploadObserver(func(ctx context.Context, fileKey string, size int64) func(err error) {
+		calls = append(calls, fmt.Sprintf("start:%s:%d:%v", fileKey, size, ctx.Value(ctxKey{})))
+		return func(err error) {
+			calls = append(calls, fmt.Sprintf("end:%s:%v", fileKey, err))
+		}
+	})
+	defer filesystem.SetUploadObserver(nil)
+
+	if err := fsys.Upload([]byte("demo"), "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := filesystem.NewFileFromBytes([]byte("file"), "b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.UploadFile(file, "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"start:a.txt:4:test", "end:a.txt:<nil>", "start:b.txt:4:test", "end:b.txt:<nil>"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+}
+
 func TestFileSystemServe(t *testing.T) {
 	dir := createTestDir(t)
 	defer os.RemoveAll(dir)
@@ -3,6 +3,7 @@ package hook
 import (
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/pocketbase/pocketbase/tools/security"
 )
@@ -31,6 +32,31 @@ type Handler[T Resolver] struct {
 	Priority int
 }
 
+// TriggerObserver defines an optional function that is invoked right
+// before each hook handler execution (eg. for tracing).
+//
+// handlerId is the id of the executed handler and it is empty for the
+// one off handler funcs passed to [Hook.Trigger].
+//
+// The returned function, if not nil, is invoked right after the handler
+// returns with the handler error.
+type TriggerObserver func(event Resolver, handlerId string) func(err error)
+
+var triggerObserver atomic.Pointer[TriggerObserver]
+
+// SetTriggerObserver registers a global observer that is notified
+// for every handler executed by [Hook.Trigger] of all hooks.
+//
+// Pass nil to remove the current observer.
+func SetTriggerObserver(observer TriggerObserver) {
+	if observer == nil {
+		triggerObserver.Store(nil)
+		return
+	}
+
+	triggerObserver.Store(&observer)
+}
+
 // Hook defines a generic concurrent safe structure for managing event hooks.
 //
 // When using custom event it must embed the base [hook.Event].
@@ -151,10 +177,19 @@ func (h *Hook[T]) Length() int {
 //
 // NB! Each hook handler must call event.Next() in order the hook chain to proceed.
 func (h *Hook[T]) Trigger(event T, oneOffHandlerFuncs ...func(T) error) error {
+	observer := triggerObserver.Load()
+
 	h.mu.RLock()
 	handlers := make([]func(T) error, 0, len(h.handlers)+len(oneOffHandlerFuncs))
-	for _, handler := range h.handlers {
+	var ids []string
+	if observer != nil {
+		ids = make([]string, len(h.handlers), len(h.handlers)+len(oneOffHandlerFuncs))
+	}
+	for i, handler := range h.handlers {
 		handlers = append(handlers, handler.Func)
+		if observer != nil {
+			ids[i] = handler.Id
+		}
 	}
 	handlers = append(handlers, oneOffHandlerFuncs...)
 	h.mu.RUnlock()
@@ -166,6 +201,19 @@ func (h *Hook[T]) Trigger(event T, oneOffHandlerFuncs ...func(T) error) error {
 		old := event.nextFunc()
 		event.setNextFunc(func() error {
 			event.setNextFunc(old)
+
+			if observer != nil {
+				var id string
+				if i < len(ids) {
+					id = ids[i]
+				}
+				if done := (*observer)(event, id); done != nil {
+					err := handlers[i](event)
+					done(err)
+					return err
+				}
+			}
+
 			return handlers[i](event)
 		})
 	}
@@ -2,6 +2,8 @@ package hook
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -160,3 +162,38 @@ func TestHookTriggerErrorPropagation(t *testing.T) {
 		})
 	}
 }
+
+func TestHookTriggerObserver(t *testing.T) {
+	testErr := errors.New("test")
+
+	var calls []string
+	SetTriggerObserver(func(event Resolver, handlerId string) func(err error) {
+		calls = append(calls, "start:"+handlerId)
+		return func(err error) {
+			calls = append(calls, "end:"+handlerId+":"+fmt.Sprint(err))
+		}
+	})
+	defer SetTriggerObserver(nil)
+
+	h := Hook[*Event]{}
+	h.Bind(&Handler[*Event]{Id: "a", Func: func(e *Event) error { return e.Next() }})
+	h.Bind(&Handler[*Event]{Id: "b", Func: func(e *Event) error { e.Next(); return testErr }})
+
+	result := h.Trigger(&Event{}, func(e *Event) error { return e.Next() })
+	if result != testErr {
+		t.Fatalf("Expected %v, got %v", testErr, result)
+	}
+
+	expected := []string{"start:a", "start:b", "start:", "end::<nil>", "end:b:test", "end:a:test"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+
+	// removed observer
+	SetTriggerObserver(nil)
+	calls = nil
+	h.Trigger(&Event{})
+	if len(calls) != 0 {
+		t.Fatalf("Expected no observer calls, got %v", calls)
+	}
+}